@@ -21,6 +21,7 @@ import (
 	"github.com/digi604/swarmmarket/backend/internal/task"
 	"github.com/digi604/swarmmarket/backend/internal/transaction"
 	"github.com/digi604/swarmmarket/backend/internal/user"
+	"github.com/digi604/swarmmarket/backend/internal/wallet"
 	"github.com/digi604/swarmmarket/backend/internal/worker"
 	"github.com/digi604/swarmmarket/backend/pkg/api"
 	"github.com/digi604/swarmmarket/backend/pkg/logger"
@@ -96,6 +97,8 @@ func main() {
 	// Initialize transaction service
 	transactionRepo := transaction.NewRepository(db.Pool)
 	transactionService := transaction.NewService(transactionRepo, notificationService)
+	transactionService.SetDisputeConfig(cfg.Dispute.ResponseWindow, cfg.Dispute.Arbitrators())
+	transactionService.SetInspectionConfig(cfg.Escrow.InspectionWindow, cfg.Escrow.InspectionWarning, cfg.Escrow.MaxInspectionWindow)
 
 	// Wire transaction creator to marketplace and task services (avoids circular dependency)
 	marketplaceService.SetTransactionCreator(transactionService)
@@ -105,6 +108,9 @@ func main() {
 	// Initialize auction service
 	auctionRepo := auction.NewRepository(db.Pool)
 	auctionService := auction.NewService(auctionRepo, notificationService)
+	auctionService.SetTransactionCreator(transactionService)
+	auctionService.SetSettlementWindows(cfg.Auction.PaymentWindow, cfg.Auction.SecondChanceWindow)
+	auctionService.SetCancellationPenalty(cfg.Auction.CancelPenaltyPercent)
 
 	// webhookRepo uses the same notification repository for webhook management
 	webhookRepo := notificationRepo
@@ -113,6 +119,7 @@ func main() {
 	wsHub := websocket.NewHub()
 	go wsHub.Run(context.Background())
 	go websocket.BridgeRedisToHub(context.Background(), redis.Client, wsHub)
+	go websocket.BridgeAuctionEvents(context.Background(), redis.Client, websocket.NewAuctionFeed(wsHub, auctionService))
 	log.Println("WebSocket hub started")
 
 
@@ -120,15 +127,64 @@ func main() {
 	matchingEngine := matching.NewEngine(func(ctx context.Context, trade matching.Trade) {
 		// Publish trade events
 		notificationService.Publish(ctx, "match.found", map[string]any{
-			"trade_id":   trade.ID,
-			"product_id": trade.ProductID,
-			"buyer_id":   trade.BuyerID,
-			"seller_id":  trade.SellerID,
-			"price":      trade.Price,
-			"quantity":   trade.Quantity,
+			"trade_id":       trade.ID,
+			"product_id":     trade.ProductID,
+			"buyer_id":       trade.BuyerID,
+			"seller_id":      trade.SellerID,
+			"price":          trade.Price,
+			"quantity":       trade.Quantity,
+			"transaction_id": trade.TransactionID,
 		})
 	})
-	log.Println("Matching engine initialized")
+	matchingEngine.SetExpiryHandler(func(ctx context.Context, order matching.Order) {
+		notificationService.Publish(ctx, "order.expired", map[string]any{
+			"order_id":      order.ID,
+			"agent_id":      order.AgentID,
+			"product_id":    order.ProductID,
+			"side":          order.Side,
+			"price":         order.Price,
+			"remaining_qty": order.RemainingQty,
+			"expires_at":    order.ExpiresAt,
+		})
+	})
+	matchingEngine.SetTriggerHandler(func(ctx context.Context, order matching.Order) {
+		notificationService.Publish(ctx, "order.triggered", map[string]any{
+			"order_id":   order.ID,
+			"agent_id":   order.AgentID,
+			"product_id": order.ProductID,
+			"side":       order.Side,
+			"type":       order.Type,
+			"stop_price": order.StopPrice,
+			"status":     order.Status,
+		})
+	})
+	if err := matchingEngine.SetSelfTradePrevention(matching.STPMode(cfg.OrderBook.STPMode), cfg.OrderBook.STPSameOwner); err != nil {
+		log.Fatalf("Invalid ORDERBOOK_STP_MODE %q: %v", cfg.OrderBook.STPMode, err)
+	}
+	matchingEngine.SetSelfTradeHandler(func(ctx context.Context, order matching.Order) {
+		notificationService.Publish(ctx, "order.self_trade_prevented", map[string]any{
+			"order_id":      order.ID,
+			"agent_id":      order.AgentID,
+			"product_id":    order.ProductID,
+			"side":          order.Side,
+			"status":        order.Status,
+			"remaining_qty": order.RemainingQty,
+		})
+	})
+	matchingEngine.SetRequireInstruments(cfg.OrderBook.RequireInstruments)
+	matchingEngine.SetInstrumentAdmins(cfg.OrderBook.InstrumentAdmins())
+	matchingEngine.SetBookHandler(websocket.NewMarketFeed(wsHub).HandleBookUpdate)
+	matchingEngine.SetRepository(matching.NewRepository(db.Pool))
+	matchingEngine.SetTransactionCreator(transactionService)
+	restored, err := matchingEngine.Recover(ctx)
+	if err != nil {
+		log.Fatalf("Failed to recover order book: %v", err)
+	}
+	settled, err := matchingEngine.SettlePendingTrades(ctx)
+	if err != nil {
+		log.Fatalf("Failed to settle pending trades: %v", err)
+	}
+	log.Printf("Matching engine initialized (%d resting orders recovered, %d pending trades settled)", restored, settled)
 
 	// Initialize user repository and service (for human dashboard + Connect)
 	var userService *user.Service
@@ -151,6 +207,14 @@ func main() {
 	auctionService.SetSpendingChecker(spendingService)
 	log.Println("Spending checker wired to marketplace and auction services")
 
+	// Initialize wallet ledger and record escrow in it
+	walletRepo := wallet.NewRepository(db.Pool)
+	walletService := wallet.NewService(walletRepo)
+	walletService.SetFeePercent(cfg.Stripe.PlatformFeePercent)
+	transactionService.SetLedger(walletService)
+	auctionService.SetPenaltyCharger(walletService)
+	log.Println("Wallet ledger initialized")
+
 	// Initialize payment service (Stripe)
 	var paymentService *payment.Service
 	var connectService *payment.ConnectService
@@ -169,6 +233,7 @@ func main() {
 		paymentAdapter.SetSpendingChecker(spendingService)
 		transactionService.SetPaymentService(paymentAdapter)
 		marketplaceService.SetPaymentCreator(paymentAdapter)
+		walletService.SetPaymentProvider(paymentAdapter)
 		log.Println("Stripe payment service initialized with off-session support")
 
 		connectService = payment.NewConnectService()
@@ -240,11 +305,14 @@ func main() {
 		WebhookRepo:         webhookRepo,
 		AuctionService:      auctionService,
 		AuctionRepo:         auctionRepo,
+		TransactionService:  transactionService,
 		EmailService:        emailService,
+		MatchingEngine:      matchingEngine,
+		WalletService:       walletService,
 		RedisClient:         redis.Client,
 	})
 	go bgWorker.Run(context.Background())
-	log.Println("Background worker started (webhook delivery, auction scheduler)")
+	log.Println("Background worker started (webhook delivery, auction scheduler, order expiry, dispute and inspection deadlines, ledger reconciliation)")
 
 	// Create router
 	router := api.NewRouter(api.RouterConfig{
@@ -257,6 +325,7 @@ func main() {
 		MatchingEngine:      matchingEngine,
 		PaymentService:      paymentService,
 		SpendingService:     spendingService,
+		WalletService:       walletService,
 		TaskService:         taskService,
 		MessagingService:    messagingService,
 		WebhookRepo:         webhookRepo,
@@ -13,6 +13,10 @@ import (
 	"github.com/digi604/swarmmarket/backend/internal/database"
 	"github.com/digi604/swarmmarket/backend/internal/email"
 	"github.com/digi604/swarmmarket/backend/internal/notification"
+	"github.com/digi604/swarmmarket/backend/internal/payment"
+	"github.com/digi604/swarmmarket/backend/internal/transaction"
+	"github.com/digi604/swarmmarket/backend/internal/user"
+	"github.com/digi604/swarmmarket/backend/internal/wallet"
 	"github.com/digi604/swarmmarket/backend/internal/worker"
 )
 
@@ -52,8 +56,39 @@ func main() {
 	// Initialize services
 	notificationService := notification.NewService(redis.Client)
 	webhookRepo := notification.NewRepository(db.Pool)
+	transactionService := transaction.NewService(transaction.NewRepository(db.Pool), notificationService)
+	transactionService.SetDisputeConfig(cfg.Dispute.ResponseWindow, cfg.Dispute.Arbitrators())
+	transactionService.SetInspectionConfig(cfg.Escrow.InspectionWindow, cfg.Escrow.InspectionWarning, cfg.Escrow.MaxInspectionWindow)
 	auctionRepo := auction.NewRepository(db.Pool)
 	auctionService := auction.NewService(auctionRepo, notificationService)
+	auctionService.SetTransactionCreator(transactionService)
+	auctionService.SetSettlementWindows(cfg.Auction.PaymentWindow, cfg.Auction.SecondChanceWindow)
+	auctionService.SetCancellationPenalty(cfg.Auction.CancelPenaltyPercent)
+
+	// Initialize wallet ledger so settled escrow is recorded and reconciled
+	walletService := wallet.NewService(wallet.NewRepository(db.Pool))
+	walletService.SetFeePercent(cfg.Stripe.PlatformFeePercent)
+	transactionService.SetLedger(walletService)
+
+	// Initialize payment service (Stripe) so deadlines that settle escrow move the funds
+	if cfg.Stripe.SecretKey != "" {
+		paymentAdapter := payment.NewAdapter(payment.NewService(payment.Config{
+			SecretKey:          cfg.Stripe.SecretKey,
+			WebhookSecret:      cfg.Stripe.WebhookSecret,
+			PlatformFeePercent: cfg.Stripe.PlatformFeePercent,
+			DefaultReturnURL:   cfg.Stripe.DefaultReturnURL,
+		}))
+		if cfg.Clerk.SecretKey != "" {
+			userRepo := user.NewRepository(db.Pool)
+			paymentAdapter.SetConnectAccountResolver(userRepo)
+			paymentAdapter.SetPaymentMethodResolver(userRepo)
+		}
+		transactionService.SetPaymentService(paymentAdapter)
+		walletService.SetPaymentProvider(paymentAdapter)
+		log.Println("Worker: Stripe payment service initialized")
+	} else {
+		log.Println("Worker: Stripe not configured - disputed and inspected escrow is settled without moving funds")
+	}
 
 	// Initialize email service (SendGrid)
 	var emailService *email.Service
@@ -78,7 +113,9 @@ func main() {
 		WebhookRepo:         webhookRepo,
 		AuctionService:      auctionService,
 		AuctionRepo:         auctionRepo,
+		TransactionService:  transactionService,
 		EmailService:        emailService,
+		WalletService:       walletService,
 		RedisClient:         redis.Client,
 	})
 
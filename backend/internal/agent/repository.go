@@ -238,6 +238,16 @@ func (r *Repository) GetReputation(ctx context.Context, agentID uuid.UUID) (*Rep
 		AverageRating:     agent.AverageRating,
 	}
 
+	// Count resolved disputes the agent won and lost
+	disputesQuery := `
+		SELECT COUNT(*) FILTER (WHERE winner_id = $1), COUNT(*) FILTER (WHERE loser_id = $1)
+		FROM disputes
+		WHERE status = 'resolved' AND (winner_id = $1 OR loser_id = $1)
+	`
+	if err := r.pool.QueryRow(ctx, disputesQuery, agentID).Scan(&rep.DisputesWon, &rep.DisputesLost); err != nil {
+		return nil, fmt.Errorf("failed to count disputes: %w", err)
+	}
+
 	// Get recent ratings
 	ratingsQuery := `
 		SELECT transaction_id, rater_id, score, comment, created_at
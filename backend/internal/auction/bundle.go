@@ -0,0 +1,305 @@
+package auction
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidBundle = errors.New("bid must name at least one item of the bundle auction, each once")
+
+const (
+	// maxBundleItems is the most items a bundle auction can have.
+	maxBundleItems = 64
+	// exactBundleItems is the most items for which the allocation is searched
+	// exhaustively. Larger auctions are allocated greedily.
+	exactBundleItems = 12
+)
+
+// validateBundleItems checks the items of a bundle auction being created.
+func validateBundleItems(items []BundleItemRequest) error {
+	if len(items) < 2 || len(items) > maxBundleItems {
+		return errors.New("bundle auctions must have between 2 and 64 items")
+	}
+	for _, item := range items {
+		if item.Title == "" {
+			return errors.New("every bundle item needs a title")
+		}
+	}
+	return nil
+}
+
+// attachItems loads the items of a bundle auction into it.
+func (s *Service) attachItems(ctx context.Context, auction *Auction) error {
+	if auction.AuctionType != AuctionTypeBundle {
+		return nil
+	}
+	items, err := s.repo.GetBundleItems(ctx, auction.ID)
+	if err != nil {
+		return err
+	}
+	auction.Items = items
+	return nil
+}
+
+// placeBundleBid records a sealed bid of an amount for a subset of the items.
+func (s *Service) placeBundleBid(ctx context.Context, auction *Auction, bidderID uuid.UUID, req *PlaceBidRequest) (*Bid, error) {
+	if req.Amount < auction.StartingPrice {
+		return nil, ErrBidTooLow
+	}
+
+	items, err := s.repo.GetBundleItems(ctx, auction.ID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[uuid.UUID]bool, len(items))
+	for _, item := range items {
+		known[item.ID] = true
+	}
+	named := make(map[uuid.UUID]bool, len(req.Items))
+	for _, id := range req.Items {
+		if !known[id] || named[id] {
+			return nil, ErrInvalidBundle
+		}
+		named[id] = true
+	}
+	if len(named) == 0 {
+		return nil, ErrInvalidBundle
+	}
+
+	bid := &Bid{
+		ID:        uuid.New(),
+		AuctionID: auction.ID,
+		BidderID:  bidderID,
+		Amount:    req.Amount,
+		Currency:  auction.Currency,
+		IsSealed:  true,
+		Status:    BidStatusActive,
+		Quantity:  1,
+		Items:     req.Items,
+		Metadata:  make(map[string]any),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.repo.CreateBid(ctx, bid); err != nil {
+		return nil, err
+	}
+
+	s.publishEvent(ctx, "bid.placed", map[string]any{
+		"auction_id": auction.ID,
+		"bidder_id":  bidderID,
+		"seller_id":  auction.SellerID,
+	})
+
+	return bid, nil
+}
+
+// bundleAward is what one bidder won in a bundle auction.
+type bundleAward struct {
+	bid    *Bid // The bidder's first winning bid
+	items  []uuid.UUID
+	amount float64
+}
+
+// settleBundleAuction ends a bundle auction with the allocation of items to
+// bids that raises the most revenue. Every winning bid pays its amount and a
+// bidder can win several bids on disjoint items. If the revenue is below the
+// reserve price nothing is sold. Every winning bidder gets one transaction for
+// all their bids.
+func (s *Service) settleBundleAuction(ctx context.Context, auction *Auction) error {
+	items, err := s.repo.GetBundleItems(ctx, auction.ID)
+	if err != nil {
+		return err
+	}
+	bids, err := s.repo.GetBidsByAuctionID(ctx, auction.ID)
+	if err != nil {
+		return err
+	}
+
+	var eligible []*Bid
+	for _, b := range bids {
+		if b.Status == BidStatusActive {
+			eligible = append(eligible, b)
+		}
+	}
+	// Earlier bids win ties
+	sort.SliceStable(eligible, func(i, j int) bool {
+		return eligible[i].CreatedAt.Before(eligible[j].CreatedAt)
+	})
+
+	winners := allocateBundles(items, eligible)
+	revenue := 0.0
+	for _, b := range winners {
+		revenue += b.Amount
+	}
+	if auction.ReservePrice != nil && revenue < *auction.ReservePrice {
+		winners = nil
+	}
+
+	won := make(map[uuid.UUID]bool, len(winners))
+	for _, b := range winners {
+		won[b.ID] = true
+	}
+	for _, b := range eligible {
+		status := BidStatusLost
+		if won[b.ID] {
+			status = BidStatusWon
+		}
+		s.repo.UpdateBidStatus(ctx, b.ID, status)
+	}
+
+	if len(winners) == 0 {
+		if err := s.repo.UpdateAuctionStatus(ctx, auction.ID, AuctionStatusEnded); err != nil {
+			return err
+		}
+		payload := map[string]any{"auction_id": auction.ID, "no_bids": true}
+		if len(eligible) > 0 {
+			payload = map[string]any{"auction_id": auction.ID, "met_reserve": false}
+		}
+		s.publishEvent(ctx, "auction.ended", payload)
+		return nil
+	}
+
+	var awards []*bundleAward
+	byBidder := make(map[uuid.UUID]*bundleAward)
+	for _, b := range winners {
+		award := byBidder[b.BidderID]
+		if award == nil {
+			award = &bundleAward{bid: b}
+			byBidder[b.BidderID] = award
+			awards = append(awards, award)
+		}
+		award.items = append(award.items, b.Items...)
+		award.amount += b.Amount
+	}
+
+	// Only the call that ends the auction settles it
+	ended, err := s.repo.EndAuction(ctx, auction.ID)
+	if err != nil {
+		return err
+	}
+	if !ended {
+		return ErrAuctionNotActive
+	}
+	if err := s.repo.UpdateAuctionPrice(ctx, auction.ID, revenue); err != nil {
+		return err
+	}
+
+	results := make([]map[string]any, 0, len(awards))
+	for _, award := range awards {
+		result := map[string]any{
+			"bidder_id": award.bid.BidderID,
+			"item_ids":  award.items,
+			"amount":    award.amount,
+		}
+		if settlement := s.settleWinner(ctx, auction, award.bid, award.amount); settlement != nil {
+			result["transaction_id"] = *settlement.TransactionID
+			result["payment_due_at"] = settlement.DueAt
+		}
+		results = append(results, result)
+	}
+
+	s.publishEvent(ctx, "auction.ended", map[string]any{
+		"auction_id":  auction.ID,
+		"revenue":     revenue,
+		"winners":     results,
+		"met_reserve": true,
+	})
+	return nil
+}
+
+// allocateBundles returns the bids on pairwise disjoint sets of items with the
+// highest total amount. Up to exactBundleItems items every allocation is
+// considered; beyond that bids are taken greedily by amount per square root of
+// their item count, which favors bids that leave more items to others. Bids
+// must be in order of precedence, earlier bids winning ties.
+func allocateBundles(items []*BundleItem, bids []*Bid) []*Bid {
+	index := make(map[uuid.UUID]uint, len(items))
+	for i, item := range items {
+		index[item.ID] = uint(i)
+	}
+	masks := make([]uint64, len(bids))
+	for i, b := range bids {
+		for _, id := range b.Items {
+			masks[i] |= 1 << index[id]
+		}
+	}
+
+	if len(items) <= exactBundleItems {
+		return allocateBundlesExact(len(items), bids, masks)
+	}
+	return allocateBundlesGreedy(bids, masks)
+}
+
+// allocateBundlesExact finds the best allocation over every subset of items.
+// The best allocation of a subset either leaves its lowest item unsold or
+// sells it with one of the bids containing it, plus the best allocation of
+// the items that bid leaves.
+func allocateBundlesExact(n int, bids []*Bid, masks []uint64) []*Bid {
+	byLowest := make([][]int, n)
+	for i, mask := range masks {
+		if mask != 0 {
+			low := bits.TrailingZeros64(mask)
+			byLowest[low] = append(byLowest[low], i)
+		}
+	}
+
+	full := uint64(1)<<n - 1
+	best := make([]float64, full+1)
+	choice := make([]int, full+1)
+	for set := uint64(1); set <= full; set++ {
+		low := bits.TrailingZeros64(set)
+		best[set] = best[set&^(1<<low)]
+		choice[set] = -1
+		for _, i := range byLowest[low] {
+			if masks[i]&^set != 0 {
+				continue
+			}
+			if revenue := bids[i].Amount + best[set&^masks[i]]; revenue > best[set] {
+				best[set] = revenue
+				choice[set] = i
+			}
+		}
+	}
+
+	var winners []*Bid
+	for set := full; set != 0; {
+		if i := choice[set]; i >= 0 {
+			winners = append(winners, bids[i])
+			set &^= masks[i]
+		} else {
+			set &^= 1 << bits.TrailingZeros64(set)
+		}
+	}
+	return winners
+}
+
+// allocateBundlesGreedy takes bids in order of amount per square root of item
+// count while their items are still unsold.
+func allocateBundlesGreedy(bids []*Bid, masks []uint64) []*Bid {
+	order := make([]int, len(bids))
+	for i := range order {
+		order[i] = i
+	}
+	score := func(i int) float64 {
+		return bids[i].Amount / math.Sqrt(float64(bits.OnesCount64(masks[i])))
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return score(order[a]) > score(order[b])
+	})
+
+	var sold uint64
+	var winners []*Bid
+	for _, i := range order {
+		if masks[i] == 0 || masks[i]&sold != 0 {
+			continue
+		}
+		sold |= masks[i]
+		winners = append(winners, bids[i])
+	}
+	return winners
+}
@@ -0,0 +1,154 @@
+package auction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrBuyNowNotAvailable = errors.New("buy-now is not available on this auction")
+
+// supportsBuyNow reports whether auctions of a type can have a buy-now price.
+// Dutch auctions already sell at their asking price and continuous ones never close.
+func supportsBuyNow(auctionType AuctionType) bool {
+	return auctionType == AuctionTypeEnglish || auctionType == AuctionTypeSealed || auctionType == AuctionTypeVickrey
+}
+
+// buyNowAvailable reports whether the auction can still be bought at its
+// buy-now price.
+func (a *Auction) buyNowAvailable() bool {
+	if a.BuyNowPrice == nil || !supportsBuyNow(a.AuctionType) {
+		return false
+	}
+	return !a.BuyNowUntilFirstBid || a.BidCount == 0
+}
+
+// BuyNow ends an auction right away, selling it to the buyer at its buy-now price.
+func (s *Service) BuyNow(ctx context.Context, auctionID, buyerID uuid.UUID) (*Bid, error) {
+	auction, err := s.repo.GetAuctionByID(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if auction.Status != AuctionStatusActive {
+		return nil, ErrAuctionNotActive
+	}
+	if time.Now().UTC().After(auction.EndsAt) {
+		return nil, ErrAuctionEnded
+	}
+	if auction.SellerID == buyerID {
+		return nil, ErrCannotBidOnOwnAuction
+	}
+	if !auction.buyNowAvailable() {
+		return nil, ErrBuyNowNotAvailable
+	}
+
+	if s.spendingChecker != nil {
+		if err := s.spendingChecker.CheckSpendingLimit(ctx, buyerID, *auction.BuyNowPrice); err != nil {
+			return nil, fmt.Errorf("spending limit check failed: %w", err)
+		}
+	}
+
+	return s.buyNow(ctx, auctionID, buyerID)
+}
+
+// buyNow records a bid at the buy-now price and awards the auction to it while
+// holding the auction's row lock, so only one buyer or bid takes the auction.
+// Other bidders are told they were outbid once it is committed.
+func (s *Service) buyNow(ctx context.Context, auctionID, buyerID uuid.UUID) (*Bid, error) {
+	var auction *Auction
+	var bid *Bid
+	var events []bidEvent
+	err := s.repo.WithAuctionLock(ctx, auctionID, func(repo RepositoryInterface) error {
+		var err error
+		auction, bid, events, err = takeBuyNow(ctx, repo, auctionID, buyerID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range events {
+		s.publishEvent(ctx, e.eventType, e.payload)
+	}
+	s.announceAward(ctx, auction, bid, bid.Amount, map[string]any{"buy_now": true})
+	return bid, nil
+}
+
+// takeBuyNow awards the auction to a bid at its buy-now price if it can still
+// be bought, returning the events to publish once it is committed.
+func takeBuyNow(ctx context.Context, repo RepositoryInterface, auctionID, buyerID uuid.UUID) (*Auction, *Bid, []bidEvent, error) {
+	// The auction may have been bought, bid on or ended since it was checked
+	auction, err := repo.GetAuctionByID(ctx, auctionID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	now := time.Now().UTC()
+	if auction.Status != AuctionStatusActive {
+		return nil, nil, nil, ErrAuctionNotActive
+	}
+	if now.After(auction.EndsAt) {
+		return nil, nil, nil, ErrAuctionEnded
+	}
+	if !auction.buyNowAvailable() {
+		return nil, nil, nil, ErrBuyNowNotAvailable
+	}
+
+	price := *auction.BuyNowPrice
+	bid := &Bid{
+		ID:        uuid.New(),
+		AuctionID: auction.ID,
+		BidderID:  buyerID,
+		Amount:    price,
+		Currency:  auction.Currency,
+		IsSealed:  auction.isSealedBid(),
+		Status:    BidStatusActive,
+		Quantity:  1,
+		Metadata:  map[string]any{"buy_now": true},
+		CreatedAt: now,
+	}
+
+	// Read before the new bid so it is not among them
+	others, err := repo.GetBidsByAuctionID(ctx, auction.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := repo.CreateBid(ctx, bid); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var events []bidEvent
+	notified := map[uuid.UUID]bool{buyerID: true}
+	for _, other := range others {
+		if other.Status != BidStatusActive && other.Status != BidStatusWinning && other.Status != BidStatusCommitted {
+			continue
+		}
+		if err := repo.UpdateBidStatus(ctx, other.ID, BidStatusOutbid); err != nil {
+			return nil, nil, nil, err
+		}
+		if notified[other.BidderID] {
+			continue
+		}
+		notified[other.BidderID] = true
+
+		payload := map[string]any{
+			"auction_id": auction.ID,
+			"bidder_id":  other.BidderID,
+			"new_amount": price,
+			"buy_now":    true,
+		}
+		// Sealed amounts are never published
+		if !auction.isSealedBid() {
+			payload["old_amount"] = other.Amount
+		}
+		events = append(events, bidEvent{"bid.outbid", payload})
+	}
+
+	if err := awardBid(ctx, repo, auction.ID, bid, price); err != nil {
+		return nil, nil, nil, err
+	}
+	return auction, bid, events, nil
+}
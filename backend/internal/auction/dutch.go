@@ -0,0 +1,126 @@
+package auction
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// hasPriceSchedule reports whether the auction is a Dutch auction that lowers its price over time.
+func (a *Auction) hasPriceSchedule() bool {
+	return a.AuctionType == AuctionTypeDutch &&
+		a.PriceDecrement != nil && *a.PriceDecrement > 0 &&
+		a.DecrementIntervalSecs != nil && *a.DecrementIntervalSecs > 0
+}
+
+// DutchPriceAt returns the price of a Dutch auction at a time and when it next
+// drops (nil once it has reached its floor). The price starts at the starting
+// price and falls by the decrement every interval after the start, down to the
+// reserve price, or to the lowest positive step without a reserve.
+func (a *Auction) DutchPriceAt(now time.Time) (float64, *time.Time) {
+	if !a.hasPriceSchedule() {
+		if a.CurrentPrice != nil {
+			return *a.CurrentPrice, nil
+		}
+		return a.StartingPrice, nil
+	}
+
+	decrement := *a.PriceDecrement
+	interval := time.Duration(*a.DecrementIntervalSecs) * time.Second
+
+	// The floor is the reserve price, or the lowest positive step without one.
+	// The tolerance keeps float error from adding a step.
+	var floor float64
+	var maxSteps int64
+	if a.ReservePrice != nil && *a.ReservePrice > 0 {
+		floor = math.Min(*a.ReservePrice, a.StartingPrice)
+		maxSteps = int64(math.Ceil((a.StartingPrice-floor)/decrement - 1e-9))
+	} else {
+		maxSteps = int64(math.Ceil(a.StartingPrice/decrement-1e-9)) - 1
+		floor = a.StartingPrice - float64(maxSteps)*decrement
+	}
+
+	steps := int64(0)
+	if now.After(a.StartsAt) {
+		steps = int64(now.Sub(a.StartsAt) / interval)
+	}
+	if steps >= maxSteps {
+		return floor, nil
+	}
+
+	next := a.StartsAt.Add(time.Duration(steps+1) * interval)
+	return a.StartingPrice - float64(steps)*decrement, &next
+}
+
+// applyDutchPrice sets the current price of an active Dutch auction from the clock,
+// so readers never see a price the worker hasn't stepped down yet.
+func (a *Auction) applyDutchPrice(now time.Time) {
+	if a.Status != AuctionStatusActive || !a.hasPriceSchedule() {
+		return
+	}
+	price, next := a.DutchPriceAt(now)
+	a.CurrentPrice = &price
+	a.NextPriceAt = next
+}
+
+// TickDutchAuctions lowers the stored price of active Dutch auctions that are due
+// and publishes auction.price_changed for each. It returns the number of auctions
+// whose price changed.
+func (s *Service) TickDutchAuctions(ctx context.Context, now time.Time) (int, error) {
+	auctionType := AuctionTypeDutch
+	status := AuctionStatusActive
+
+	changed := 0
+	for offset := 0; ; offset += 100 {
+		result, err := s.repo.SearchAuctions(ctx, SearchAuctionsParams{
+			AuctionType: &auctionType,
+			Status:      &status,
+			Limit:       100,
+			Offset:      offset,
+		})
+		if err != nil {
+			return changed, fmt.Errorf("failed to search dutch auctions: %w", err)
+		}
+
+		for _, auction := range result.Auctions {
+			if !auction.hasPriceSchedule() {
+				continue
+			}
+			oldPrice := auction.StartingPrice
+			if auction.CurrentPrice != nil {
+				oldPrice = *auction.CurrentPrice
+			}
+			price, next := auction.DutchPriceAt(now)
+			if price >= oldPrice {
+				continue
+			}
+
+			// Only lowers an active auction's price, so a sale or a concurrent tick wins
+			lowered, err := s.repo.LowerAuctionPrice(ctx, auction.ID, price)
+			if err != nil {
+				return changed, fmt.Errorf("failed to lower price of auction %s: %w", auction.ID, err)
+			}
+			if !lowered {
+				continue
+			}
+			changed++
+
+			payload := map[string]any{
+				"auction_id": auction.ID,
+				"seller_id":  auction.SellerID,
+				"old_price":  oldPrice,
+				"new_price":  price,
+				"currency":   auction.Currency,
+			}
+			if next != nil {
+				payload["next_price_at"] = *next
+			}
+			s.publishEvent(ctx, "auction.price_changed", payload)
+		}
+
+		if len(result.Auctions) < 100 || offset+100 >= result.Total {
+			return changed, nil
+		}
+	}
+}
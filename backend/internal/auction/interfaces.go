@@ -16,16 +16,53 @@ type RepositoryInterface interface {
 	GetAuctionBySlug(ctx context.Context, slug string) (*Auction, error)
 	SearchAuctions(ctx context.Context, params SearchAuctionsParams) (*AuctionListResult, error)
 	UpdateAuctionPrice(ctx context.Context, auctionID uuid.UUID, price float64) error
+	LowerAuctionPrice(ctx context.Context, auctionID uuid.UUID, price float64) (bool, error)
 	UpdateAuctionStatus(ctx context.Context, auctionID uuid.UUID, status AuctionStatus) error
-	SetAuctionWinner(ctx context.Context, auctionID, winningBidID, winnerID uuid.UUID) error
+	SetAuctionWinner(ctx context.Context, auctionID, winningBidID, winnerID uuid.UUID) (bool, error)
+	ReplaceAuctionWinner(ctx context.Context, auctionID, winningBidID, winnerID uuid.UUID) error
+	EndAuction(ctx context.Context, auctionID uuid.UUID) (bool, error)
 	ExtendAuction(ctx context.Context, auctionID uuid.UUID, newEndTime time.Time) error
+	StartAuctionReveal(ctx context.Context, auctionID uuid.UUID, endsAt, revealEndsAt time.Time) error
+	UpdateScheduledAuction(ctx context.Context, auction *Auction) (bool, error)
+	CancelAuction(ctx context.Context, auctionID uuid.UUID) (bool, error)
+	WithAuctionLock(ctx context.Context, auctionID uuid.UUID, fn func(repo RepositoryInterface) error) error
 
 	// Bid Operations
 	CreateBid(ctx context.Context, bid *Bid) error
 	GetBidsByAuctionID(ctx context.Context, auctionID uuid.UUID) ([]*Bid, error)
 	GetHighestBid(ctx context.Context, auctionID uuid.UUID) (*Bid, error)
 	UpdateBidStatus(ctx context.Context, bidID uuid.UUID, status BidStatus) error
+	AllocateBid(ctx context.Context, bidID uuid.UUID, status BidStatus, allocated int) error
+	UpdateBidAmount(ctx context.Context, bidID uuid.UUID, amount float64) error
+	UpdateBidMaxAmount(ctx context.Context, bidID uuid.UUID, maxAmount float64) error
+	RevealBid(ctx context.Context, bidID uuid.UUID, amount float64, revealedAt time.Time) error
 	MarkPreviousBidsOutbid(ctx context.Context, auctionID uuid.UUID, exceptBidID uuid.UUID) error
+
+	// Bundle Operations
+	CreateBundleItems(ctx context.Context, items []*BundleItem) error
+	GetBundleItems(ctx context.Context, auctionID uuid.UUID) ([]*BundleItem, error)
+
+	// Settlement Operations
+	CreateSettlement(ctx context.Context, settlement *Settlement) error
+	UpdateSettlement(ctx context.Context, settlement *Settlement) error
+	MoveSettlement(ctx context.Context, settlementID uuid.UUID, from, to SettlementStatus, dueAt time.Time) (bool, error)
+	GetSettlementsByAuctionID(ctx context.Context, auctionID uuid.UUID) ([]*Settlement, error)
+	GetDueSettlements(ctx context.Context, now time.Time, limit int) ([]*Settlement, error)
+
+	// History Operations
+	CreateHistoryEntry(ctx context.Context, entry *HistoryEntry) error
+	GetAuctionHistory(ctx context.Context, auctionID uuid.UUID) ([]*HistoryEntry, error)
+
+	// Watchlist Operations
+	WatchAuction(ctx context.Context, agentID, auctionID uuid.UUID) error
+	UnwatchAuction(ctx context.Context, agentID, auctionID uuid.UUID) (bool, error)
+	GetAuctionWatcherIDs(ctx context.Context, auctionID uuid.UUID) ([]uuid.UUID, error)
+
+	// Saved Search Operations
+	CreateSavedSearch(ctx context.Context, search *SavedSearch) error
+	GetSavedSearches(ctx context.Context, agentID uuid.UUID) ([]*SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, id, agentID uuid.UUID) (bool, error)
+	GetMatchingSavedSearches(ctx context.Context, auction *Auction) ([]*SavedSearch, error)
 }
 
 // Verify that Repository implements RepositoryInterface
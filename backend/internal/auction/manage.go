@@ -0,0 +1,390 @@
+package auction
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/digi604/swarmmarket/backend/pkg/logger"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAuctionNotScheduled = errors.New("only scheduled auctions can be edited")
+	ErrAuctionHasBids      = errors.New("auction has bids, cancelling it requires accepting the penalty")
+	ErrBidNotRetractable   = errors.New("bid cannot be retracted once it has outbid another bid")
+)
+
+// defaultCancelPenaltyRate is the share of the highest bid a seller is charged
+// for cancelling an auction that has bids.
+const defaultCancelPenaltyRate = 0.10
+
+// UpdateAuction edits an auction that has not started yet. Every changed field
+// is recorded in the auction's history.
+func (s *Service) UpdateAuction(ctx context.Context, auctionID, sellerID uuid.UUID, req *UpdateAuctionRequest) (*Auction, error) {
+	auction, err := s.repo.GetAuctionByID(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if auction.SellerID != sellerID {
+		return nil, ErrNotAuthorized
+	}
+	if auction.Status != AuctionStatusScheduled {
+		return nil, ErrAuctionNotScheduled
+	}
+
+	changes := make(map[string]any)
+	if req.Title != nil {
+		recordChange(changes, "title", &auction.Title, *req.Title)
+		auction.Title = *req.Title
+	}
+	if req.Description != nil {
+		recordChange(changes, "description", &auction.Description, *req.Description)
+		auction.Description = *req.Description
+	}
+	if req.StartingPrice != nil {
+		recordChange(changes, "starting_price", &auction.StartingPrice, *req.StartingPrice)
+		auction.StartingPrice = *req.StartingPrice
+		auction.CurrentPrice = req.StartingPrice
+	}
+	if req.ReservePrice != nil {
+		recordChange(changes, "reserve_price", auction.ReservePrice, *req.ReservePrice)
+		auction.ReservePrice = req.ReservePrice
+	}
+	if req.BuyNowPrice != nil {
+		recordChange(changes, "buy_now_price", auction.BuyNowPrice, *req.BuyNowPrice)
+		auction.BuyNowPrice = req.BuyNowPrice
+	}
+	if req.MinIncrement != nil {
+		recordChange(changes, "min_increment", auction.MinIncrement, *req.MinIncrement)
+		auction.MinIncrement = req.MinIncrement
+	}
+	if req.PriceDecrement != nil {
+		recordChange(changes, "price_decrement", auction.PriceDecrement, *req.PriceDecrement)
+		auction.PriceDecrement = req.PriceDecrement
+	}
+	if req.DecrementIntervalSecs != nil {
+		recordChange(changes, "decrement_interval_seconds", auction.DecrementIntervalSecs, *req.DecrementIntervalSecs)
+		auction.DecrementIntervalSecs = req.DecrementIntervalSecs
+	}
+	if req.ExtensionSeconds != nil {
+		recordChange(changes, "extension_seconds", &auction.ExtensionSeconds, *req.ExtensionSeconds)
+		auction.ExtensionSeconds = *req.ExtensionSeconds
+	}
+	if req.StartsAt != nil && !req.StartsAt.Equal(auction.StartsAt) {
+		changes["starts_at"] = map[string]any{"from": auction.StartsAt, "to": req.StartsAt.UTC()}
+		auction.StartsAt = req.StartsAt.UTC()
+	}
+	if req.EndsAt != nil && !req.EndsAt.Equal(auction.EndsAt) {
+		changes["ends_at"] = map[string]any{"from": auction.EndsAt, "to": req.EndsAt.UTC()}
+		// The reveal window keeps its length
+		if auction.RevealEndsAt != nil {
+			revealEndsAt := auction.RevealEndsAt.Add(req.EndsAt.Sub(auction.EndsAt))
+			auction.RevealEndsAt = &revealEndsAt
+		}
+		auction.EndsAt = req.EndsAt.UTC()
+	}
+
+	if err := auction.validateSchedule(time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return auction, nil
+	}
+
+	updated, err := s.repo.UpdateScheduledAuction(ctx, auction)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrAuctionNotScheduled
+	}
+
+	s.recordHistory(ctx, auctionID, sellerID, HistoryActionUpdated, changes)
+	return auction, nil
+}
+
+// validateSchedule checks the prices and times of an edited auction.
+func (a *Auction) validateSchedule(now time.Time) error {
+	if a.StartingPrice <= 0 {
+		return errors.New("starting price must be positive")
+	}
+	if !a.StartsAt.After(now) {
+		return errors.New("start time must be in the future")
+	}
+	if !a.EndsAt.After(a.StartsAt) {
+		return errors.New("end time must be after the start time")
+	}
+	if (a.PriceDecrement == nil) != (a.DecrementIntervalSecs == nil) {
+		return errors.New("price decrement and decrement interval must be set together")
+	}
+	if a.PriceDecrement != nil && (*a.PriceDecrement <= 0 || *a.DecrementIntervalSecs <= 0) {
+		return errors.New("price decrement and decrement interval must be positive")
+	}
+	if a.BuyNowPrice != nil {
+		if !supportsBuyNow(a.AuctionType) || a.isMultiUnit() {
+			return ErrBuyNowNotAvailable
+		}
+		if *a.BuyNowPrice < a.StartingPrice || a.ReservePrice != nil && *a.BuyNowPrice < *a.ReservePrice {
+			return errors.New("buy-now price must be at least the starting and reserve prices")
+		}
+	}
+	return nil
+}
+
+// recordChange adds a field to the changes if its new value differs from the
+// old one, which is nil for a field that was not set.
+func recordChange[T comparable](changes map[string]any, field string, from *T, to T) {
+	if from != nil && *from == to {
+		return
+	}
+	var old any
+	if from != nil {
+		old = *from
+	}
+	changes[field] = map[string]any{"from": old, "to": to}
+}
+
+// CancelAuction cancels a scheduled or active auction. Once it has bids the
+// seller must accept a penalty, a share of the highest bid, which is charged
+// to the seller's wallet and recorded with the cancellation. The penalty is
+// charged and the auction cancelled while holding its row lock, so it can't
+// end in between; a charge whose cancellation fails anyway is reversed. Open
+// bids are cancelled with the auction.
+func (s *Service) CancelAuction(ctx context.Context, auctionID, sellerID uuid.UUID, req *CancelAuctionRequest) (*Auction, error) {
+	auction, err := s.repo.GetAuctionByID(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if auction.SellerID != sellerID {
+		return nil, ErrNotAuthorized
+	}
+
+	var cancellation *auctionCancellation
+	var chargeID *uuid.UUID
+	err = s.repo.WithAuctionLock(ctx, auctionID, func(repo RepositoryInterface) error {
+		var err error
+		cancellation, err = s.prepareCancellation(ctx, repo, auctionID, req)
+		if err != nil {
+			return err
+		}
+
+		if cancellation.penalty > 0 && s.penaltyCharger != nil {
+			id, err := s.penaltyCharger.ChargeCancellationPenalty(ctx, auctionID, sellerID, cancellation.penalty, cancellation.currency)
+			if err != nil {
+				return err
+			}
+			chargeID = &id
+		}
+
+		cancelled, err := repo.CancelAuction(ctx, auctionID)
+		if err != nil {
+			return err
+		}
+		if !cancelled {
+			return ErrAuctionNotActive
+		}
+		return nil
+	})
+	if err != nil {
+		if chargeID != nil {
+			s.reversePenalty(ctx, *chargeID, auctionID, sellerID, cancellation)
+		}
+		return nil, err
+	}
+
+	changes := map[string]any{"bidder_count": len(cancellation.bidderIDs)}
+	if req.Reason != "" {
+		changes["reason"] = req.Reason
+	}
+	payload := map[string]any{
+		"auction_id": auctionID,
+		"seller_id":  sellerID,
+		"bidder_ids": cancellation.bidderIDs,
+		"reason":     req.Reason,
+	}
+	if cancellation.penalty > 0 {
+		changes["penalty"] = cancellation.penalty
+		changes["currency"] = cancellation.currency
+		payload["penalty"] = cancellation.penalty
+		payload["currency"] = cancellation.currency
+	}
+	s.recordHistory(ctx, auctionID, sellerID, HistoryActionCancelled, changes)
+	s.publishEvent(ctx, "auction.cancelled", payload)
+
+	return s.repo.GetAuctionByID(ctx, auctionID)
+}
+
+// auctionCancellation is what cancelling an auction affects: its bidders and
+// the penalty for cancelling it.
+type auctionCancellation struct {
+	bidderIDs []uuid.UUID
+	penalty   float64
+	currency  string
+}
+
+// prepareCancellation checks that an auction can be cancelled and works out
+// its bidders and penalty from its current bids.
+func (s *Service) prepareCancellation(ctx context.Context, repo RepositoryInterface, auctionID uuid.UUID, req *CancelAuctionRequest) (*auctionCancellation, error) {
+	auction, err := repo.GetAuctionByID(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if auction.Status != AuctionStatusScheduled && auction.Status != AuctionStatusActive {
+		return nil, ErrAuctionNotActive
+	}
+
+	bids, err := repo.GetBidsByAuctionID(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	highest := auction.StartingPrice
+	if auction.CurrentPrice != nil && *auction.CurrentPrice > highest {
+		highest = *auction.CurrentPrice
+	}
+	cancellation := &auctionCancellation{currency: auction.Currency}
+	bidders := make(map[uuid.UUID]bool)
+	for _, bid := range bids {
+		if bid.Status == BidStatusRetracted {
+			continue
+		}
+		if bid.Amount > highest {
+			highest = bid.Amount
+		}
+		if !bidders[bid.BidderID] {
+			bidders[bid.BidderID] = true
+			cancellation.bidderIDs = append(cancellation.bidderIDs, bid.BidderID)
+		}
+	}
+
+	if len(cancellation.bidderIDs) > 0 {
+		if !req.AcceptPenalty {
+			return nil, ErrAuctionHasBids
+		}
+		cancellation.penalty = math.Round(highest*s.cancelPenaltyRate*100) / 100
+	}
+	return cancellation, nil
+}
+
+// reversePenalty gives back a cancellation penalty charged for a cancellation
+// that did not go through.
+func (s *Service) reversePenalty(ctx context.Context, chargeID, auctionID, sellerID uuid.UUID, cancellation *auctionCancellation) {
+	err := s.penaltyCharger.ReverseCancellationPenalty(ctx, chargeID, sellerID, cancellation.penalty, cancellation.currency)
+	if err != nil {
+		logger.Error("auction_penalty_reversal_failed", map[string]interface{}{
+			"auction_id": auctionID.String(),
+			"seller_id":  sellerID.String(),
+			"charge_id":  chargeID.String(),
+			"penalty":    cancellation.penalty,
+			"error":      err.Error(),
+		})
+	}
+}
+
+// RetractBid withdraws a bid from an active auction. Only a bid that has not
+// outbid another bid can be retracted, so retracting never takes a lead away
+// from a bidder that was pushed out by it. A retracted lead returns the price
+// to the highest remaining bid, or the starting price.
+func (s *Service) RetractBid(ctx context.Context, auctionID, bidID, bidderID uuid.UUID) (*Bid, error) {
+	auction, err := s.repo.GetAuctionByID(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if auction.Status != AuctionStatusActive {
+		return nil, ErrAuctionNotActive
+	}
+	if time.Now().UTC().After(auction.EndsAt) {
+		return nil, ErrAuctionEnded
+	}
+
+	bids, err := s.repo.GetBidsByAuctionID(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	var bid *Bid
+	for _, b := range bids {
+		if b.ID == bidID {
+			bid = b
+		}
+	}
+	if bid == nil {
+		return nil, ErrBidNotFound
+	}
+	if bid.BidderID != bidderID {
+		return nil, ErrNotAuthorized
+	}
+	switch bid.Status {
+	case BidStatusActive, BidStatusWinning, BidStatusOutbid, BidStatusCommitted:
+	default:
+		return nil, ErrBidNotRetractable
+	}
+	for _, other := range bids {
+		if other.ID != bid.ID && other.Status == BidStatusOutbid && other.Amount <= bid.Amount {
+			return nil, ErrBidNotRetractable
+		}
+	}
+
+	if err := s.repo.UpdateBidStatus(ctx, bid.ID, BidStatusRetracted); err != nil {
+		return nil, err
+	}
+	bid.Status = BidStatusRetracted
+
+	if !auction.isSealedBid() {
+		price := auction.StartingPrice
+		highest, err := s.repo.GetHighestBid(ctx, auctionID)
+		if err != nil {
+			return nil, err
+		}
+		if highest != nil {
+			price = highest.Amount
+		}
+		if err := s.repo.UpdateAuctionPrice(ctx, auctionID, price); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := map[string]any{"bid_id": bid.ID}
+	// Sealed amounts are never published
+	if !auction.isSealedBid() {
+		changes["amount"] = bid.Amount
+	}
+	s.recordHistory(ctx, auctionID, bidderID, HistoryActionBidRetracted, changes)
+
+	s.publishEvent(ctx, "bid.retracted", map[string]any{
+		"auction_id": auctionID,
+		"bid_id":     bid.ID,
+		"bidder_id":  bidderID,
+		"seller_id":  auction.SellerID,
+	})
+
+	return bid, nil
+}
+
+// GetAuctionHistory retrieves the recorded changes to an auction.
+func (s *Service) GetAuctionHistory(ctx context.Context, auctionID uuid.UUID) ([]*HistoryEntry, error) {
+	if _, err := s.repo.GetAuctionByID(ctx, auctionID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAuctionHistory(ctx, auctionID)
+}
+
+// recordHistory records a change to an auction. Failures are logged rather
+// than returned: the change itself has already been made.
+func (s *Service) recordHistory(ctx context.Context, auctionID, actorID uuid.UUID, action HistoryAction, changes map[string]any) {
+	entry := &HistoryEntry{
+		ID:        uuid.New(),
+		AuctionID: auctionID,
+		ActorID:   actorID,
+		Action:    action,
+		Changes:   changes,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.repo.CreateHistoryEntry(ctx, entry); err != nil {
+		logger.Error("auction_history_failed", map[string]interface{}{
+			"auction_id": auctionID.String(),
+			"action":     string(action),
+			"error":      err.Error(),
+		})
+	}
+}
@@ -13,7 +13,9 @@ const (
 	AuctionTypeEnglish    AuctionType = "english"    // Ascending price, highest bidder wins
 	AuctionTypeDutch      AuctionType = "dutch"      // Descending price, first bidder wins
 	AuctionTypeSealed     AuctionType = "sealed"     // Sealed-bid, highest bid wins
+	AuctionTypeVickrey    AuctionType = "vickrey"    // Sealed-bid, highest bid wins at the second-highest price
 	AuctionTypeContinuous AuctionType = "continuous" // Ongoing, like a limit order book
+	AuctionTypeBundle     AuctionType = "bundle"     // Sealed bids on subsets of items, revenue-maximizing allocation
 )
 
 // AuctionStatus represents the status of an auction.
@@ -22,6 +24,7 @@ type AuctionStatus string
 const (
 	AuctionStatusScheduled AuctionStatus = "scheduled"
 	AuctionStatusActive    AuctionStatus = "active"
+	AuctionStatusRevealing AuctionStatus = "revealing" // Commit-reveal bidding closed, bids being revealed
 	AuctionStatusEnded     AuctionStatus = "ended"
 	AuctionStatusCancelled AuctionStatus = "cancelled"
 )
@@ -30,10 +33,23 @@ const (
 type BidStatus string
 
 const (
-	BidStatusActive  BidStatus = "active"
-	BidStatusOutbid  BidStatus = "outbid"
-	BidStatusWinning BidStatus = "winning"
-	BidStatusWon     BidStatus = "won"
+	BidStatusActive    BidStatus = "active"
+	BidStatusOutbid    BidStatus = "outbid"
+	BidStatusWinning   BidStatus = "winning"
+	BidStatusWon       BidStatus = "won"
+	BidStatusCommitted BidStatus = "committed" // Commit-reveal bid not revealed yet
+	BidStatusForfeited BidStatus = "forfeited" // Commit-reveal bid not revealed in time, or winner that did not pay
+	BidStatusLost      BidStatus = "lost"      // Multi-unit bid allocated no units
+	BidStatusRetracted BidStatus = "retracted" // Withdrawn by its bidder
+	BidStatusCancelled BidStatus = "cancelled" // Its auction was cancelled
+)
+
+// PricingRule is how the winners of a multi-unit auction pay.
+type PricingRule string
+
+const (
+	PricingUniform  PricingRule = "uniform"    // Every winner pays the lowest winning bid
+	PricingPayAsBid PricingRule = "pay_as_bid" // Every winner pays their own bid
 )
 
 // Auction represents an auction.
@@ -49,20 +65,27 @@ type Auction struct {
 	CurrentPrice            *float64       `json:"current_price,omitempty"`
 	ReservePrice            *float64       `json:"reserve_price,omitempty"`
 	BuyNowPrice             *float64       `json:"buy_now_price,omitempty"`
+	BuyNowUntilFirstBid     bool           `json:"buy_now_until_first_bid"` // Buy-now is withdrawn once there is a bid
+	Quantity                int            `json:"quantity"`                // Identical units for sale
+	Pricing                 PricingRule    `json:"pricing,omitempty"`       // For multi-unit auctions
 	Currency                string         `json:"currency"`
 	MinIncrement            *float64       `json:"min_increment,omitempty"`             // For English auctions
 	PriceDecrement          *float64       `json:"price_decrement,omitempty"`           // For Dutch auctions
 	DecrementIntervalSecs   *int           `json:"decrement_interval_seconds,omitempty"` // For Dutch auctions
+	NextPriceAt             *time.Time     `json:"next_price_at,omitempty"`              // When a Dutch auction's price next drops
 	Status                  AuctionStatus  `json:"status"`
 	StartsAt                time.Time      `json:"starts_at"`
 	EndsAt                  time.Time      `json:"ends_at"`
 	ExtensionSeconds        int            `json:"extension_seconds"` // Anti-sniping
+	CommitReveal            bool           `json:"commit_reveal"`            // Sealed bids are committed as hashes and revealed after close
+	RevealEndsAt            *time.Time     `json:"reveal_ends_at,omitempty"` // End of the reveal window of commit-reveal auctions
 	WinningBidID            *uuid.UUID     `json:"winning_bid_id,omitempty"`
 	WinnerID                *uuid.UUID     `json:"winner_id,omitempty"`
 	BidCount                int            `json:"bid_count"`
 	Metadata                map[string]any `json:"metadata,omitempty"`
 	CreatedAt               time.Time      `json:"created_at"`
 	UpdatedAt               time.Time      `json:"updated_at"`
+	Items                   []*BundleItem  `json:"items,omitempty"` // Items of bundle auctions
 
 	// Enriched fields (from agent join)
 	SellerName        string  `json:"seller_name,omitempty"`
@@ -73,38 +96,168 @@ type Auction struct {
 
 // Bid represents a bid on an auction.
 type Bid struct {
+	ID         uuid.UUID      `json:"id"`
+	AuctionID  uuid.UUID      `json:"auction_id"`
+	BidderID   uuid.UUID      `json:"bidder_id"`
+	Amount     float64        `json:"amount"`
+	MaxAmount  *float64       `json:"max_amount,omitempty"` // Proxy ceiling, only ever shown to its bidder
+	Commitment *string        `json:"commitment,omitempty"` // Commit-reveal: SHA-256 of "<amount>:<nonce>"
+	RevealedAt *time.Time     `json:"revealed_at,omitempty"`
+	Currency   string         `json:"currency"`
+	IsSealed   bool           `json:"is_sealed"`
+	Status     BidStatus      `json:"status"`
+	Quantity   int            `json:"quantity"`                     // Units wanted, at Amount each
+	Allocated  *int           `json:"allocated_quantity,omitempty"` // Units won, once a multi-unit auction closes
+	Items      []uuid.UUID    `json:"item_ids,omitempty"`           // Bundle auctions: the items the bid is for
+	Metadata   map[string]any `json:"metadata,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// BundleItem is one of the items sold in a bundle auction, optionally one of
+// the seller's listings.
+type BundleItem struct {
+	ID          uuid.UUID  `json:"id"`
+	AuctionID   uuid.UUID  `json:"auction_id"`
+	ListingID   *uuid.UUID `json:"listing_id,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Position    int        `json:"position"`
+}
+
+// SettlementStatus represents the status of an auction settlement.
+type SettlementStatus string
+
+const (
+	SettlementStatusOffered         SettlementStatus = "offered"          // Second-chance offer awaiting the bidder
+	SettlementStatusPendingCreation SettlementStatus = "pending_creation" // Won or accepted, transaction not created yet
+	SettlementStatusPending         SettlementStatus = "pending"          // Transaction created, awaiting escrow funding
+	SettlementStatusFunded          SettlementStatus = "funded"           // Escrow funded before the deadline
+	SettlementStatusDefaulted       SettlementStatus = "defaulted"        // Not funded in time, transaction cancelled
+	SettlementStatusDeclined        SettlementStatus = "declined"         // Second-chance offer declined or expired
+)
+
+// Settlement is the sale of a won auction to one bidder: the winner, or a
+// runner-up given a second chance after the winner failed to pay.
+type Settlement struct {
+	ID            uuid.UUID        `json:"id"`
+	AuctionID     uuid.UUID        `json:"auction_id"`
+	BidID         uuid.UUID        `json:"bid_id"`
+	BidderID      uuid.UUID        `json:"bidder_id"`
+	Amount        float64          `json:"amount"`
+	Currency      string           `json:"currency"`
+	SecondChance  bool             `json:"second_chance"`
+	Status        SettlementStatus `json:"status"`
+	TransactionID *uuid.UUID       `json:"transaction_id,omitempty"`
+	DueAt         time.Time        `json:"due_at"` // Payment deadline, when an offer expires, or when creating the transaction is retried
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+// HistoryAction is a kind of change recorded in an auction's history.
+type HistoryAction string
+
+const (
+	HistoryActionUpdated      HistoryAction = "updated"
+	HistoryActionCancelled    HistoryAction = "cancelled"
+	HistoryActionBidRetracted HistoryAction = "bid_retracted"
+)
+
+// HistoryEntry is a change to an auction, recorded for auditing.
+type HistoryEntry struct {
 	ID        uuid.UUID      `json:"id"`
 	AuctionID uuid.UUID      `json:"auction_id"`
-	BidderID  uuid.UUID      `json:"bidder_id"`
-	Amount    float64        `json:"amount"`
-	Currency  string         `json:"currency"`
-	IsSealed  bool           `json:"is_sealed"`
-	Status    BidStatus      `json:"status"`
-	Metadata  map[string]any `json:"metadata,omitempty"`
+	ActorID   uuid.UUID      `json:"actor_id"`
+	Action    HistoryAction  `json:"action"`
+	Changes   map[string]any `json:"changes,omitempty"` // Updates: {"field": {"from": old, "to": new}}
 	CreatedAt time.Time      `json:"created_at"`
 }
 
+// SavedSearch is an agent's search for auctions, alerting it whenever a
+// matching auction starts. Fields left empty match every auction.
+type SavedSearch struct {
+	ID          uuid.UUID    `json:"id"`
+	AgentID     uuid.UUID    `json:"agent_id"`
+	Name        string       `json:"name,omitempty"`
+	AuctionType *AuctionType `json:"auction_type,omitempty"`
+	Query       string       `json:"query,omitempty"` // Matches the title or description
+	SellerID    *uuid.UUID   `json:"seller_id,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
 // CreateAuctionRequest is the request for creating an auction.
 type CreateAuctionRequest struct {
-	ListingID             *uuid.UUID `json:"listing_id,omitempty"`
-	AuctionType           string     `json:"auction_type"`
-	Title                 string     `json:"title"`
-	Description           string     `json:"description,omitempty"`
-	StartingPrice         float64    `json:"starting_price"`
+	ListingID             *uuid.UUID          `json:"listing_id,omitempty"`
+	AuctionType           string              `json:"auction_type"`
+	Title                 string              `json:"title"`
+	Description           string              `json:"description,omitempty"`
+	StartingPrice         float64             `json:"starting_price"`
+	ReservePrice          *float64            `json:"reserve_price,omitempty"`
+	BuyNowPrice           *float64            `json:"buy_now_price,omitempty"`
+	Currency              string              `json:"currency,omitempty"`
+	MinIncrement          *float64            `json:"min_increment,omitempty"`
+	PriceDecrement        *float64            `json:"price_decrement,omitempty"`
+	DecrementIntervalSecs *int                `json:"decrement_interval_seconds,omitempty"`
+	StartsAt              *time.Time          `json:"starts_at,omitempty"`
+	EndsAt                time.Time           `json:"ends_at"`
+	ExtensionSeconds      *int                `json:"extension_seconds,omitempty"`
+	BuyNowUntilFirstBid   bool                `json:"buy_now_until_first_bid,omitempty"`
+	CommitReveal          bool                `json:"commit_reveal,omitempty"`         // Sealed and Vickrey auctions only
+	RevealWindowSecs      *int                `json:"reveal_window_seconds,omitempty"` // Defaults to an hour
+	Quantity              int                 `json:"quantity,omitempty"`              // Units for sale, sealed auctions only above 1
+	Pricing               string              `json:"pricing,omitempty"`               // Multi-unit: uniform (default) or pay_as_bid
+	Items                 []BundleItemRequest `json:"items,omitempty"`                 // Bundle auctions: the items for sale
+}
+
+// BundleItemRequest is an item of a bundle auction being created.
+type BundleItemRequest struct {
+	ListingID   *uuid.UUID `json:"listing_id,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+}
+
+// UpdateAuctionRequest is the request for editing a scheduled auction. Fields
+// left out are kept.
+type UpdateAuctionRequest struct {
+	Title                 *string    `json:"title,omitempty"`
+	Description           *string    `json:"description,omitempty"`
+	StartingPrice         *float64   `json:"starting_price,omitempty"`
 	ReservePrice          *float64   `json:"reserve_price,omitempty"`
 	BuyNowPrice           *float64   `json:"buy_now_price,omitempty"`
-	Currency              string     `json:"currency,omitempty"`
 	MinIncrement          *float64   `json:"min_increment,omitempty"`
 	PriceDecrement        *float64   `json:"price_decrement,omitempty"`
 	DecrementIntervalSecs *int       `json:"decrement_interval_seconds,omitempty"`
 	StartsAt              *time.Time `json:"starts_at,omitempty"`
-	EndsAt                time.Time  `json:"ends_at"`
+	EndsAt                *time.Time `json:"ends_at,omitempty"`
 	ExtensionSeconds      *int       `json:"extension_seconds,omitempty"`
 }
 
+// CancelAuctionRequest is the request for cancelling an auction.
+type CancelAuctionRequest struct {
+	Reason        string `json:"reason,omitempty"`
+	AcceptPenalty bool   `json:"accept_penalty,omitempty"` // Required once the auction has bids
+}
+
 // PlaceBidRequest is the request for placing a bid.
 type PlaceBidRequest struct {
+	Amount     float64     `json:"amount"`
+	MaxAmount  *float64    `json:"max_amount,omitempty"` // English auctions: bid automatically up to this, amount is ignored
+	Commitment *string     `json:"commitment,omitempty"` // Commit-reveal auctions: hex SHA-256 of "<amount>:<nonce>", sent instead of the amount
+	Quantity   int         `json:"quantity,omitempty"`   // Multi-unit auctions: units wanted at amount each, defaults to 1
+	Items      []uuid.UUID `json:"item_ids,omitempty"`   // Bundle auctions: the items the amount is for
+}
+
+// CreateSavedSearchRequest is the request for saving an auction search.
+type CreateSavedSearchRequest struct {
+	Name        string     `json:"name,omitempty"`
+	AuctionType string     `json:"auction_type,omitempty"`
+	Query       string     `json:"query,omitempty"`
+	SellerID    *uuid.UUID `json:"seller_id,omitempty"`
+}
+
+// RevealBidRequest reveals a commit-reveal bid.
+type RevealBidRequest struct {
 	Amount float64 `json:"amount"`
+	Nonce  string  `json:"nonce"`
 }
 
 // AuctionListResult is the result of listing auctions.
@@ -121,6 +274,7 @@ type SearchAuctionsParams struct {
 	AuctionType *AuctionType
 	Status      *AuctionStatus
 	Query       string
+	WatcherID   *uuid.UUID // Only auctions on this agent's watchlist
 	Limit       int
 	Offset      int
 }
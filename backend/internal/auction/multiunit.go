@@ -0,0 +1,138 @@
+package auction
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidQuantity = errors.New("quantity must be between 1 and the units for sale")
+
+// units returns the number of identical units for sale.
+func (a *Auction) units() int {
+	if a.Quantity > 1 {
+		return a.Quantity
+	}
+	return 1
+}
+
+// isMultiUnit reports whether the auction sells more than one unit.
+func (a *Auction) isMultiUnit() bool {
+	return a.Quantity > 1
+}
+
+// units returns the number of units the bid is for.
+func (r *PlaceBidRequest) units() int {
+	if r.Quantity > 0 {
+		return r.Quantity
+	}
+	return 1
+}
+
+// units returns the number of units the bid is for.
+func (b *Bid) units() int {
+	if b.Quantity > 0 {
+		return b.Quantity
+	}
+	return 1
+}
+
+// unitAward is what one bidder won in a multi-unit auction.
+type unitAward struct {
+	bid    *Bid // The bidder's highest winning bid
+	units  int
+	amount float64
+}
+
+// allocateUnits awards the units of a multi-unit auction to its bids, highest
+// first, partly filling the last bid that gets any. Under uniform pricing every
+// winner pays the lowest winning bid, under pay-as-bid their own bids. Bids
+// below the reserve price get nothing. Every winning bidder gets one
+// transaction for all their units.
+func (s *Service) allocateUnits(ctx context.Context, auction *Auction, bids []*Bid) error {
+	remaining := auction.units()
+	allocated := make(map[uuid.UUID]int)
+	var winners []*Bid
+	for _, b := range bids {
+		units := 0
+		if auction.ReservePrice == nil || b.Amount >= *auction.ReservePrice {
+			units = min(b.units(), remaining)
+		}
+		remaining -= units
+
+		status := BidStatusLost
+		if units > 0 {
+			status = BidStatusWon
+			allocated[b.ID] = units
+			winners = append(winners, b)
+		}
+		s.repo.AllocateBid(ctx, b.ID, status, units)
+	}
+
+	if len(winners) == 0 {
+		if err := s.repo.UpdateAuctionStatus(ctx, auction.ID, AuctionStatusEnded); err != nil {
+			return err
+		}
+		payload := map[string]any{"auction_id": auction.ID, "no_bids": true}
+		if len(bids) > 0 {
+			payload = map[string]any{"auction_id": auction.ID, "met_reserve": false}
+		}
+		s.publishEvent(ctx, "auction.ended", payload)
+		return nil
+	}
+
+	clearingPrice := winners[len(winners)-1].Amount
+	var awards []*unitAward
+	byBidder := make(map[uuid.UUID]*unitAward)
+	for _, b := range winners {
+		price := clearingPrice
+		if auction.Pricing == PricingPayAsBid {
+			price = b.Amount
+		}
+		award := byBidder[b.BidderID]
+		if award == nil {
+			award = &unitAward{bid: b}
+			byBidder[b.BidderID] = award
+			awards = append(awards, award)
+		}
+		award.units += allocated[b.ID]
+		award.amount += price * float64(allocated[b.ID])
+	}
+
+	// Only the call that ends the auction settles it
+	ended, err := s.repo.EndAuction(ctx, auction.ID)
+	if err != nil {
+		return err
+	}
+	if !ended {
+		return ErrAuctionNotActive
+	}
+	if err := s.repo.UpdateAuctionPrice(ctx, auction.ID, clearingPrice); err != nil {
+		return err
+	}
+
+	results := make([]map[string]any, 0, len(awards))
+	for _, award := range awards {
+		result := map[string]any{
+			"bidder_id": award.bid.BidderID,
+			"quantity":  award.units,
+			"amount":    award.amount,
+		}
+		if settlement := s.settleWinner(ctx, auction, award.bid, award.amount); settlement != nil {
+			result["transaction_id"] = *settlement.TransactionID
+			result["payment_due_at"] = settlement.DueAt
+		}
+		results = append(results, result)
+	}
+
+	s.publishEvent(ctx, "auction.ended", map[string]any{
+		"auction_id":     auction.ID,
+		"pricing":        auction.Pricing,
+		"clearing_price": clearingPrice,
+		"quantity_sold":  auction.units() - remaining,
+		"winners":        results,
+		"met_reserve":    true,
+	})
+	return nil
+}
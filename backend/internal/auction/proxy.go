@@ -0,0 +1,203 @@
+package auction
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// bidIncrement returns the minimum step between English auction bids.
+func (a *Auction) bidIncrement() float64 {
+	if a.MinIncrement != nil {
+		return *a.MinIncrement
+	}
+	return 1 // Default increment
+}
+
+// proxyAmount returns what a proxy bid with a ceiling shows when it needs to beat
+// an amount: one increment more, capped at the ceiling, but at least the reserve
+// price if the ceiling covers it.
+func (a *Auction) proxyAmount(beat, ceiling float64) float64 {
+	amount := math.Min(beat+a.bidIncrement(), ceiling)
+	if a.ReservePrice != nil && amount < *a.ReservePrice && ceiling >= *a.ReservePrice {
+		amount = *a.ReservePrice
+	}
+	return amount
+}
+
+// ceiling returns the most a bid may be raised to on its bidder's behalf.
+func (b *Bid) ceiling() float64 {
+	if b.MaxAmount != nil {
+		return *b.MaxAmount
+	}
+	return b.Amount
+}
+
+// ceiling returns the most the bidder is willing to pay.
+func (r *PlaceBidRequest) ceiling() float64 {
+	if r.MaxAmount != nil {
+		return *r.MaxAmount
+	}
+	return r.Amount
+}
+
+// bidEvent is an event to publish once a bid is committed.
+type bidEvent struct {
+	eventType string
+	payload   map[string]any
+}
+
+// placeEnglishBid places a bid on an English auction, resolving proxy bids the
+// way eBay does: the leading bid shows one increment over the best competing
+// ceiling, up to its own, and of two equal ceilings the earlier one leads.
+// The leader is read, the bid resolved and the result written while holding
+// the auction's row lock, so concurrent bids are resolved one at a time.
+func (s *Service) placeEnglishBid(ctx context.Context, auctionID, bidderID uuid.UUID, req *PlaceBidRequest) (*Bid, error) {
+	var bid *Bid
+	var events []bidEvent
+	err := s.repo.WithAuctionLock(ctx, auctionID, func(repo RepositoryInterface) error {
+		var err error
+		bid, events, err = resolveEnglishBid(ctx, repo, auctionID, bidderID, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range events {
+		s.publishEvent(ctx, e.eventType, e.payload)
+	}
+	return bid, nil
+}
+
+// resolveEnglishBid resolves a bid against the auction's current leader and
+// writes the result, returning the events to publish once it is committed.
+func resolveEnglishBid(ctx context.Context, repo RepositoryInterface, auctionID, bidderID uuid.UUID, req *PlaceBidRequest) (*Bid, []bidEvent, error) {
+	// The auction may have ended or been cancelled since the bid was checked
+	auction, err := repo.GetAuctionByID(ctx, auctionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now().UTC()
+	if auction.Status != AuctionStatusActive {
+		return nil, nil, ErrAuctionNotActive
+	}
+	if now.After(auction.EndsAt) {
+		return nil, nil, ErrAuctionEnded
+	}
+
+	highestBid, err := repo.GetHighestBid(ctx, auctionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	minBid := auction.StartingPrice
+	if highestBid != nil {
+		minBid = highestBid.Amount + auction.bidIncrement()
+	}
+	ceiling := req.ceiling()
+	if ceiling < minBid {
+		return nil, nil, ErrBidTooLow
+	}
+
+	// The leader raising their maximum keeps their bid and the current price
+	if req.MaxAmount != nil && highestBid != nil && highestBid.BidderID == bidderID {
+		if ceiling <= highestBid.ceiling() {
+			return nil, nil, ErrBidTooLow
+		}
+		if err := repo.UpdateBidMaxAmount(ctx, highestBid.ID, ceiling); err != nil {
+			return nil, nil, err
+		}
+		highestBid.MaxAmount = &ceiling
+		return highestBid, nil, nil
+	}
+
+	bid := &Bid{
+		ID:        uuid.New(),
+		AuctionID: auction.ID,
+		BidderID:  bidderID,
+		Amount:    req.Amount,
+		MaxAmount: req.MaxAmount,
+		Currency:  auction.Currency,
+		Status:    BidStatusActive,
+		Quantity:  1,
+		Metadata:  make(map[string]any),
+		CreatedAt: now,
+	}
+
+	var events []bidEvent
+	var price float64
+	if highestBid != nil && highestBid.BidderID != bidderID && ceiling <= highestBid.ceiling() {
+		// The leader's proxy covers the bid: it is outbid at once and the
+		// leader's bid rises to beat it
+		bid.Amount = ceiling
+		bid.Status = BidStatusOutbid
+		if err := repo.CreateBid(ctx, bid); err != nil {
+			return nil, nil, err
+		}
+
+		price = auction.proxyAmount(ceiling, highestBid.ceiling())
+		if err := repo.UpdateBidAmount(ctx, highestBid.ID, price); err != nil {
+			return nil, nil, err
+		}
+
+		events = append(events, bidEvent{"bid.outbid", map[string]any{
+			"auction_id": auction.ID,
+			"bidder_id":  bidderID,
+			"old_amount": bid.Amount,
+			"new_amount": price,
+		}})
+	} else {
+		if req.MaxAmount != nil {
+			bid.Amount = minBid
+			if highestBid != nil {
+				bid.Amount = auction.proxyAmount(highestBid.ceiling(), ceiling)
+			} else if auction.ReservePrice != nil && ceiling >= *auction.ReservePrice {
+				bid.Amount = math.Max(minBid, *auction.ReservePrice)
+			}
+		}
+		if err := repo.CreateBid(ctx, bid); err != nil {
+			return nil, nil, err
+		}
+		price = bid.Amount
+
+		// Mark previous bids as outbid
+		if highestBid != nil {
+			if err := repo.MarkPreviousBidsOutbid(ctx, auction.ID, bid.ID); err != nil {
+				return nil, nil, err
+			}
+
+			// Notify outbid bidder
+			events = append(events, bidEvent{"bid.outbid", map[string]any{
+				"auction_id": auction.ID,
+				"bidder_id":  highestBid.BidderID,
+				"old_amount": highestBid.Amount,
+				"new_amount": bid.Amount,
+			}})
+		}
+	}
+
+	// Update current price
+	if err := repo.UpdateAuctionPrice(ctx, auction.ID, price); err != nil {
+		return nil, nil, err
+	}
+
+	// Anti-sniping: extend auction if bid is close to end
+	if auction.EndsAt.Sub(now) < time.Duration(auction.ExtensionSeconds)*time.Second {
+		newEndTime := now.Add(time.Duration(auction.ExtensionSeconds) * time.Second)
+		if err := repo.ExtendAuction(ctx, auction.ID, newEndTime); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	events = append(events, bidEvent{"bid.placed", map[string]any{
+		"auction_id": auction.ID,
+		"bidder_id":  bidderID,
+		"amount":     bid.Amount,
+		"seller_id":  auction.SellerID,
+	}})
+
+	return bid, events, nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/digi604/swarmmarket/backend/internal/common"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -18,14 +19,48 @@ var (
 	ErrBidNotFound     = errors.New("bid not found")
 )
 
+// querier runs queries on the pool, or within a database transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 // Repository handles auction persistence.
 type Repository struct {
 	pool *pgxpool.Pool
+	db   querier
 }
 
 // NewRepository creates a new auction repository.
 func NewRepository(pool *pgxpool.Pool) *Repository {
-	return &Repository{pool: pool}
+	return &Repository{pool: pool, db: pool}
+}
+
+// WithAuctionLock runs fn in a database transaction holding the auction's row
+// lock, so concurrent bids on the auction are resolved one at a time. The
+// repository passed to fn works within the transaction, which commits if fn
+// returns nil.
+func (r *Repository) WithAuctionLock(ctx context.Context, auctionID uuid.UUID, fn func(repo RepositoryInterface) error) error {
+	dbTx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback(ctx)
+
+	var locked uuid.UUID
+	err = dbTx.QueryRow(ctx, `SELECT id FROM auctions WHERE id = $1 FOR UPDATE`, auctionID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAuctionNotFound
+		}
+		return err
+	}
+
+	if err := fn(&Repository{pool: r.pool, db: dbTx}); err != nil {
+		return err
+	}
+	return dbTx.Commit(ctx)
 }
 
 // generateUniqueSlug generates a unique slug from title + short UUID.
@@ -47,16 +82,18 @@ func (r *Repository) CreateAuction(ctx context.Context, auction *Auction) error
 			id, slug, listing_id, seller_id, auction_type, title, description,
 			starting_price, current_price, reserve_price, buy_now_price, price_currency,
 			min_increment, price_decrement, decrement_interval_seconds,
-			status, starts_at, ends_at, extension_seconds, metadata, created_at, updated_at
+			status, starts_at, ends_at, extension_seconds, commit_reveal, reveal_ends_at,
+			buy_now_until_first_bid, quantity, pricing, metadata, created_at, updated_at
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7,
 			$8, $9, $10, $11, $12,
 			$13, $14, $15,
-			$16, $17, $18, $19, $20, $21, $22
+			$16, $17, $18, $19, $20, $21,
+			$22, $23, $24, $25, $26, $27
 		)
 	`
 
-	_, err := r.pool.Exec(ctx, query,
+	_, err := r.db.Exec(ctx, query,
 		auction.ID,
 		auction.Slug,
 		auction.ListingID,
@@ -76,6 +113,11 @@ func (r *Repository) CreateAuction(ctx context.Context, auction *Auction) error
 		auction.StartsAt,
 		auction.EndsAt,
 		auction.ExtensionSeconds,
+		auction.CommitReveal,
+		auction.RevealEndsAt,
+		auction.BuyNowUntilFirstBid,
+		auction.Quantity,
+		auction.Pricing,
 		auction.Metadata,
 		auction.CreatedAt,
 		auction.UpdatedAt,
@@ -91,7 +133,8 @@ func (r *Repository) GetAuctionByID(ctx context.Context, id uuid.UUID) (*Auction
 			a.id, COALESCE(a.slug, ''), a.listing_id, a.seller_id, a.auction_type, a.title, a.description,
 			a.starting_price, a.current_price, a.reserve_price, a.buy_now_price, a.price_currency,
 			a.min_increment, a.price_decrement, a.decrement_interval_seconds,
-			a.status, a.starts_at, a.ends_at, a.extension_seconds,
+			a.status, a.starts_at, a.ends_at, a.extension_seconds, a.commit_reveal, a.reveal_ends_at,
+			a.buy_now_until_first_bid, a.quantity, a.pricing,
 			a.winning_bid_id, a.winner_id, a.metadata, a.created_at, a.updated_at,
 			(SELECT COUNT(*) FROM bids WHERE auction_id = a.id) as bid_count,
 			COALESCE(ag.name, '') as seller_name,
@@ -103,7 +146,7 @@ func (r *Repository) GetAuctionByID(ctx context.Context, id uuid.UUID) (*Auction
 	`
 
 	var auction Auction
-	err := r.pool.QueryRow(ctx, query, id).Scan(
+	err := r.db.QueryRow(ctx, query, id).Scan(
 		&auction.ID,
 		&auction.Slug,
 		&auction.ListingID,
@@ -123,6 +166,11 @@ func (r *Repository) GetAuctionByID(ctx context.Context, id uuid.UUID) (*Auction
 		&auction.StartsAt,
 		&auction.EndsAt,
 		&auction.ExtensionSeconds,
+		&auction.CommitReveal,
+		&auction.RevealEndsAt,
+		&auction.BuyNowUntilFirstBid,
+		&auction.Quantity,
+		&auction.Pricing,
 		&auction.WinningBidID,
 		&auction.WinnerID,
 		&auction.Metadata,
@@ -160,7 +208,8 @@ func (r *Repository) GetAuctionBySlug(ctx context.Context, slug string) (*Auctio
 			a.id, COALESCE(a.slug, ''), a.listing_id, a.seller_id, a.auction_type, a.title, a.description,
 			a.starting_price, a.current_price, a.reserve_price, a.buy_now_price, a.price_currency,
 			a.min_increment, a.price_decrement, a.decrement_interval_seconds,
-			a.status, a.starts_at, a.ends_at, a.extension_seconds,
+			a.status, a.starts_at, a.ends_at, a.extension_seconds, a.commit_reveal, a.reveal_ends_at,
+			a.buy_now_until_first_bid, a.quantity, a.pricing,
 			a.winning_bid_id, a.winner_id, a.metadata, a.created_at, a.updated_at,
 			(SELECT COUNT(*) FROM bids WHERE auction_id = a.id) as bid_count,
 			COALESCE(ag.name, '') as seller_name,
@@ -172,7 +221,7 @@ func (r *Repository) GetAuctionBySlug(ctx context.Context, slug string) (*Auctio
 	`
 
 	var auction Auction
-	err := r.pool.QueryRow(ctx, query, uuidPrefix+"%").Scan(
+	err := r.db.QueryRow(ctx, query, uuidPrefix+"%").Scan(
 		&auction.ID,
 		&auction.Slug,
 		&auction.ListingID,
@@ -192,6 +241,11 @@ func (r *Repository) GetAuctionBySlug(ctx context.Context, slug string) (*Auctio
 		&auction.StartsAt,
 		&auction.EndsAt,
 		&auction.ExtensionSeconds,
+		&auction.CommitReveal,
+		&auction.RevealEndsAt,
+		&auction.BuyNowUntilFirstBid,
+		&auction.Quantity,
+		&auction.Pricing,
 		&auction.WinningBidID,
 		&auction.WinnerID,
 		&auction.Metadata,
@@ -220,7 +274,8 @@ func (r *Repository) SearchAuctions(ctx context.Context, params SearchAuctionsPa
 			a.id, COALESCE(a.slug, ''), a.listing_id, a.seller_id, a.auction_type, a.title, a.description,
 			a.starting_price, a.current_price, a.reserve_price, a.buy_now_price, a.price_currency,
 			a.min_increment, a.price_decrement, a.decrement_interval_seconds,
-			a.status, a.starts_at, a.ends_at, a.extension_seconds,
+			a.status, a.starts_at, a.ends_at, a.extension_seconds, a.commit_reveal, a.reveal_ends_at,
+			a.buy_now_until_first_bid, a.quantity, a.pricing,
 			a.winning_bid_id, a.winner_id, a.metadata, a.created_at, a.updated_at,
 			(SELECT COUNT(*) FROM bids WHERE auction_id = a.id) as bid_count,
 			COALESCE(ag.name, '') as seller_name,
@@ -263,9 +318,16 @@ func (r *Repository) SearchAuctions(ctx context.Context, params SearchAuctionsPa
 		argNum++
 	}
 
+	if params.WatcherID != nil {
+		baseQuery += ` AND a.id IN (SELECT auction_id FROM auction_watches WHERE agent_id = $` + string(rune('0'+argNum)) + `)`
+		countQuery += ` AND a.id IN (SELECT auction_id FROM auction_watches WHERE agent_id = $` + string(rune('0'+argNum)) + `)`
+		args = append(args, *params.WatcherID)
+		argNum++
+	}
+
 	// Get total count
 	var total int
-	err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
+	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, err
 	}
@@ -282,7 +344,7 @@ func (r *Repository) SearchAuctions(ctx context.Context, params SearchAuctionsPa
 	baseQuery += ` LIMIT $` + string(rune('0'+argNum)) + ` OFFSET $` + string(rune('0'+argNum+1))
 	args = append(args, params.Limit, params.Offset)
 
-	rows, err := r.pool.Query(ctx, baseQuery, args...)
+	rows, err := r.db.Query(ctx, baseQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -311,6 +373,11 @@ func (r *Repository) SearchAuctions(ctx context.Context, params SearchAuctionsPa
 			&auction.StartsAt,
 			&auction.EndsAt,
 			&auction.ExtensionSeconds,
+			&auction.CommitReveal,
+			&auction.RevealEndsAt,
+			&auction.BuyNowUntilFirstBid,
+			&auction.Quantity,
+			&auction.Pricing,
 			&auction.WinningBidID,
 			&auction.WinnerID,
 			&auction.Metadata,
@@ -337,18 +404,22 @@ func (r *Repository) SearchAuctions(ctx context.Context, params SearchAuctionsPa
 // CreateBid creates a new bid.
 func (r *Repository) CreateBid(ctx context.Context, bid *Bid) error {
 	query := `
-		INSERT INTO bids (id, auction_id, bidder_id, amount, currency, is_sealed, status, metadata, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO bids (id, auction_id, bidder_id, amount, max_amount, commitment, currency, is_sealed, status, quantity, item_ids, metadata, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
-	_, err := r.pool.Exec(ctx, query,
+	_, err := r.db.Exec(ctx, query,
 		bid.ID,
 		bid.AuctionID,
 		bid.BidderID,
 		bid.Amount,
+		bid.MaxAmount,
+		bid.Commitment,
 		bid.Currency,
 		bid.IsSealed,
 		bid.Status,
+		bid.Quantity,
+		bid.Items,
 		bid.Metadata,
 		bid.CreatedAt,
 	)
@@ -359,13 +430,14 @@ func (r *Repository) CreateBid(ctx context.Context, bid *Bid) error {
 // GetBidsByAuctionID retrieves all bids for an auction.
 func (r *Repository) GetBidsByAuctionID(ctx context.Context, auctionID uuid.UUID) ([]*Bid, error) {
 	query := `
-		SELECT id, auction_id, bidder_id, amount, currency, is_sealed, status, metadata, created_at
+		SELECT id, auction_id, bidder_id, amount, max_amount, commitment, revealed_at, currency, is_sealed, status,
+			quantity, allocated_quantity, item_ids, metadata, created_at
 		FROM bids
 		WHERE auction_id = $1
 		ORDER BY amount DESC, created_at ASC
 	`
 
-	rows, err := r.pool.Query(ctx, query, auctionID)
+	rows, err := r.db.Query(ctx, query, auctionID)
 	if err != nil {
 		return nil, err
 	}
@@ -379,9 +451,15 @@ func (r *Repository) GetBidsByAuctionID(ctx context.Context, auctionID uuid.UUID
 			&bid.AuctionID,
 			&bid.BidderID,
 			&bid.Amount,
+			&bid.MaxAmount,
+			&bid.Commitment,
+			&bid.RevealedAt,
 			&bid.Currency,
 			&bid.IsSealed,
 			&bid.Status,
+			&bid.Quantity,
+			&bid.Allocated,
+			&bid.Items,
 			&bid.Metadata,
 			&bid.CreatedAt,
 		); err != nil {
@@ -396,22 +474,29 @@ func (r *Repository) GetBidsByAuctionID(ctx context.Context, auctionID uuid.UUID
 // GetHighestBid retrieves the highest bid for an auction.
 func (r *Repository) GetHighestBid(ctx context.Context, auctionID uuid.UUID) (*Bid, error) {
 	query := `
-		SELECT id, auction_id, bidder_id, amount, currency, is_sealed, status, metadata, created_at
+		SELECT id, auction_id, bidder_id, amount, max_amount, commitment, revealed_at, currency, is_sealed, status,
+			quantity, allocated_quantity, item_ids, metadata, created_at
 		FROM bids
 		WHERE auction_id = $1 AND status IN ('active', 'winning')
-		ORDER BY amount DESC
+		ORDER BY amount DESC, created_at ASC
 		LIMIT 1
 	`
 
 	var bid Bid
-	err := r.pool.QueryRow(ctx, query, auctionID).Scan(
+	err := r.db.QueryRow(ctx, query, auctionID).Scan(
 		&bid.ID,
 		&bid.AuctionID,
 		&bid.BidderID,
 		&bid.Amount,
+		&bid.MaxAmount,
+		&bid.Commitment,
+		&bid.RevealedAt,
 		&bid.Currency,
 		&bid.IsSealed,
 		&bid.Status,
+		&bid.Quantity,
+		&bid.Allocated,
+		&bid.Items,
 		&bid.Metadata,
 		&bid.CreatedAt,
 	)
@@ -426,48 +511,486 @@ func (r *Repository) GetHighestBid(ctx context.Context, auctionID uuid.UUID) (*B
 	return &bid, nil
 }
 
+// CreateBundleItems creates the items of a bundle auction.
+func (r *Repository) CreateBundleItems(ctx context.Context, items []*BundleItem) error {
+	query := `
+		INSERT INTO auction_items (id, auction_id, listing_id, title, description, position)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	for _, item := range items {
+		if _, err := r.db.Exec(ctx, query, item.ID, item.AuctionID, item.ListingID, item.Title, item.Description, item.Position); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetBundleItems retrieves the items of a bundle auction in order.
+func (r *Repository) GetBundleItems(ctx context.Context, auctionID uuid.UUID) ([]*BundleItem, error) {
+	query := `
+		SELECT id, auction_id, listing_id, title, COALESCE(description, ''), position
+		FROM auction_items
+		WHERE auction_id = $1
+		ORDER BY position ASC
+	`
+	rows, err := r.db.Query(ctx, query, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*BundleItem
+	for rows.Next() {
+		var item BundleItem
+		if err := rows.Scan(&item.ID, &item.AuctionID, &item.ListingID, &item.Title, &item.Description, &item.Position); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+
+	return items, rows.Err()
+}
+
 // UpdateBidStatus updates the status of a bid.
 func (r *Repository) UpdateBidStatus(ctx context.Context, bidID uuid.UUID, status BidStatus) error {
 	query := `UPDATE bids SET status = $2 WHERE id = $1`
-	_, err := r.pool.Exec(ctx, query, bidID, status)
+	_, err := r.db.Exec(ctx, query, bidID, status)
+	return err
+}
+
+// AllocateBid records the units a bid was allocated when its multi-unit auction closed.
+func (r *Repository) AllocateBid(ctx context.Context, bidID uuid.UUID, status BidStatus, allocated int) error {
+	query := `UPDATE bids SET status = $2, allocated_quantity = $3 WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, bidID, status, allocated)
+	return err
+}
+
+// UpdateBidAmount updates the amount of a bid.
+func (r *Repository) UpdateBidAmount(ctx context.Context, bidID uuid.UUID, amount float64) error {
+	query := `UPDATE bids SET amount = $2 WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, bidID, amount)
+	return err
+}
+
+// UpdateBidMaxAmount updates the proxy ceiling of a bid.
+func (r *Repository) UpdateBidMaxAmount(ctx context.Context, bidID uuid.UUID, maxAmount float64) error {
+	query := `UPDATE bids SET max_amount = $2 WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, bidID, maxAmount)
+	return err
+}
+
+// RevealBid records the revealed amount of a committed bid.
+func (r *Repository) RevealBid(ctx context.Context, bidID uuid.UUID, amount float64, revealedAt time.Time) error {
+	query := `UPDATE bids SET amount = $2, revealed_at = $3, status = 'active' WHERE id = $1 AND status = 'committed'`
+	_, err := r.db.Exec(ctx, query, bidID, amount, revealedAt)
 	return err
 }
 
 // UpdateAuctionPrice updates the current price of an auction.
 func (r *Repository) UpdateAuctionPrice(ctx context.Context, auctionID uuid.UUID, price float64) error {
 	query := `UPDATE auctions SET current_price = $2, updated_at = NOW() WHERE id = $1`
-	_, err := r.pool.Exec(ctx, query, auctionID, price)
+	_, err := r.db.Exec(ctx, query, auctionID, price)
 	return err
 }
 
+// LowerAuctionPrice sets the current price of an active auction if it is below
+// the stored one, reporting whether it did.
+func (r *Repository) LowerAuctionPrice(ctx context.Context, auctionID uuid.UUID, price float64) (bool, error) {
+	query := `
+		UPDATE auctions SET current_price = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'active' AND (current_price IS NULL OR current_price > $2)
+	`
+	result, err := r.db.Exec(ctx, query, auctionID, price)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
 // UpdateAuctionStatus updates the status of an auction.
 func (r *Repository) UpdateAuctionStatus(ctx context.Context, auctionID uuid.UUID, status AuctionStatus) error {
 	query := `UPDATE auctions SET status = $2, updated_at = NOW() WHERE id = $1`
-	_, err := r.pool.Exec(ctx, query, auctionID, status)
+	_, err := r.db.Exec(ctx, query, auctionID, status)
 	return err
 }
 
-// SetAuctionWinner sets the winning bid and winner of an auction.
-func (r *Repository) SetAuctionWinner(ctx context.Context, auctionID, winningBidID, winnerID uuid.UUID) error {
+// StartAuctionReveal closes bidding on a commit-reveal auction and opens its reveal window.
+func (r *Repository) StartAuctionReveal(ctx context.Context, auctionID uuid.UUID, endsAt, revealEndsAt time.Time) error {
+	query := `
+		UPDATE auctions
+		SET status = 'revealing', ends_at = $2, reveal_ends_at = $3, updated_at = NOW()
+		WHERE id = $1 AND status = 'active'
+	`
+	_, err := r.db.Exec(ctx, query, auctionID, endsAt, revealEndsAt)
+	return err
+}
+
+// SetAuctionWinner ends an auction that is still open with the winning bid and
+// winner, reporting whether it did.
+func (r *Repository) SetAuctionWinner(ctx context.Context, auctionID, winningBidID, winnerID uuid.UUID) (bool, error) {
 	query := `
 		UPDATE auctions
 		SET winning_bid_id = $2, winner_id = $3, status = 'ended', updated_at = NOW()
-		WHERE id = $1
+		WHERE id = $1 AND status IN ('active', 'revealing')
+	`
+	result, err := r.db.Exec(ctx, query, auctionID, winningBidID, winnerID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// ReplaceAuctionWinner moves an ended auction to a new winning bid and winner.
+func (r *Repository) ReplaceAuctionWinner(ctx context.Context, auctionID, winningBidID, winnerID uuid.UUID) error {
+	query := `
+		UPDATE auctions
+		SET winning_bid_id = $2, winner_id = $3, updated_at = NOW()
+		WHERE id = $1 AND status = 'ended'
 	`
-	_, err := r.pool.Exec(ctx, query, auctionID, winningBidID, winnerID)
+	_, err := r.db.Exec(ctx, query, auctionID, winningBidID, winnerID)
 	return err
 }
 
+// EndAuction ends an auction that is still open, reporting whether it did.
+func (r *Repository) EndAuction(ctx context.Context, auctionID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE auctions SET status = 'ended', updated_at = NOW()
+		WHERE id = $1 AND status IN ('active', 'revealing')
+	`
+	result, err := r.db.Exec(ctx, query, auctionID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
 // ExtendAuction extends the end time of an auction (anti-sniping).
 func (r *Repository) ExtendAuction(ctx context.Context, auctionID uuid.UUID, newEndTime time.Time) error {
 	query := `UPDATE auctions SET ends_at = $2, updated_at = NOW() WHERE id = $1`
-	_, err := r.pool.Exec(ctx, query, auctionID, newEndTime)
+	_, err := r.db.Exec(ctx, query, auctionID, newEndTime)
 	return err
 }
 
 // MarkPreviousBidsOutbid marks all previous bids as outbid.
 func (r *Repository) MarkPreviousBidsOutbid(ctx context.Context, auctionID uuid.UUID, exceptBidID uuid.UUID) error {
 	query := `UPDATE bids SET status = 'outbid' WHERE auction_id = $1 AND id != $2 AND status = 'active'`
-	_, err := r.pool.Exec(ctx, query, auctionID, exceptBidID)
+	_, err := r.db.Exec(ctx, query, auctionID, exceptBidID)
 	return err
 }
+
+// UpdateScheduledAuction saves the editable fields of an auction if it has not
+// started yet, reporting whether it did.
+func (r *Repository) UpdateScheduledAuction(ctx context.Context, auction *Auction) (bool, error) {
+	query := `
+		UPDATE auctions
+		SET title = $2, description = $3, starting_price = $4, current_price = $5, reserve_price = $6,
+			buy_now_price = $7, min_increment = $8, price_decrement = $9, decrement_interval_seconds = $10,
+			starts_at = $11, ends_at = $12, extension_seconds = $13, reveal_ends_at = $14, updated_at = NOW()
+		WHERE id = $1 AND status = 'scheduled'
+	`
+	result, err := r.db.Exec(ctx, query,
+		auction.ID,
+		auction.Title,
+		auction.Description,
+		auction.StartingPrice,
+		auction.CurrentPrice,
+		auction.ReservePrice,
+		auction.BuyNowPrice,
+		auction.MinIncrement,
+		auction.PriceDecrement,
+		auction.DecrementIntervalSecs,
+		auction.StartsAt,
+		auction.EndsAt,
+		auction.ExtensionSeconds,
+		auction.RevealEndsAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// CancelAuction cancels a scheduled or active auction and its open bids,
+// reporting whether it did.
+func (r *Repository) CancelAuction(ctx context.Context, auctionID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE auctions SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND status IN ('scheduled', 'active')
+	`
+	result, err := r.db.Exec(ctx, query, auctionID)
+	if err != nil {
+		return false, err
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	query = `UPDATE bids SET status = 'cancelled' WHERE auction_id = $1 AND status IN ('active', 'winning', 'outbid', 'committed')`
+	_, err = r.db.Exec(ctx, query, auctionID)
+	return true, err
+}
+
+// CreateHistoryEntry records a change to an auction.
+func (r *Repository) CreateHistoryEntry(ctx context.Context, entry *HistoryEntry) error {
+	query := `
+		INSERT INTO auction_history (id, auction_id, actor_id, action, changes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.Exec(ctx, query, entry.ID, entry.AuctionID, entry.ActorID, entry.Action, entry.Changes, entry.CreatedAt)
+	return err
+}
+
+// GetAuctionHistory retrieves the recorded changes to an auction, oldest first.
+func (r *Repository) GetAuctionHistory(ctx context.Context, auctionID uuid.UUID) ([]*HistoryEntry, error) {
+	query := `
+		SELECT id, auction_id, actor_id, action, changes, created_at
+		FROM auction_history
+		WHERE auction_id = $1
+		ORDER BY created_at ASC
+	`
+	rows, err := r.db.Query(ctx, query, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*HistoryEntry
+	for rows.Next() {
+		var entry HistoryEntry
+		if err := rows.Scan(&entry.ID, &entry.AuctionID, &entry.ActorID, &entry.Action, &entry.Changes, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// WatchAuction adds an auction to an agent's watchlist.
+func (r *Repository) WatchAuction(ctx context.Context, agentID, auctionID uuid.UUID) error {
+	query := `
+		INSERT INTO auction_watches (agent_id, auction_id)
+		VALUES ($1, $2)
+		ON CONFLICT (agent_id, auction_id) DO NOTHING
+	`
+	_, err := r.db.Exec(ctx, query, agentID, auctionID)
+	return err
+}
+
+// UnwatchAuction removes an auction from an agent's watchlist, reporting
+// whether it was on it.
+func (r *Repository) UnwatchAuction(ctx context.Context, agentID, auctionID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM auction_watches WHERE agent_id = $1 AND auction_id = $2`, agentID, auctionID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// GetAuctionWatcherIDs retrieves the agents watching an auction.
+func (r *Repository) GetAuctionWatcherIDs(ctx context.Context, auctionID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `SELECT agent_id FROM auction_watches WHERE auction_id = $1`, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// CreateSavedSearch saves an agent's auction search.
+func (r *Repository) CreateSavedSearch(ctx context.Context, search *SavedSearch) error {
+	query := `
+		INSERT INTO auction_saved_searches (id, agent_id, name, auction_type, query, seller_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.Exec(ctx, query,
+		search.ID,
+		search.AgentID,
+		search.Name,
+		search.AuctionType,
+		search.Query,
+		search.SellerID,
+		search.CreatedAt,
+	)
+	return err
+}
+
+// GetSavedSearches retrieves an agent's saved auction searches, oldest first.
+func (r *Repository) GetSavedSearches(ctx context.Context, agentID uuid.UUID) ([]*SavedSearch, error) {
+	query := `
+		SELECT id, agent_id, name, auction_type, query, seller_id, created_at
+		FROM auction_saved_searches
+		WHERE agent_id = $1
+		ORDER BY created_at ASC
+	`
+	return r.querySavedSearches(ctx, query, agentID)
+}
+
+// DeleteSavedSearch deletes an agent's saved search, reporting whether it existed.
+func (r *Repository) DeleteSavedSearch(ctx context.Context, id, agentID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM auction_saved_searches WHERE id = $1 AND agent_id = $2`, id, agentID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// GetMatchingSavedSearches retrieves the saved searches an auction matches.
+func (r *Repository) GetMatchingSavedSearches(ctx context.Context, auction *Auction) ([]*SavedSearch, error) {
+	query := `
+		SELECT id, agent_id, name, auction_type, query, seller_id, created_at
+		FROM auction_saved_searches
+		WHERE (auction_type IS NULL OR auction_type = $1)
+			AND (seller_id IS NULL OR seller_id = $2)
+			AND (query = '' OR $3 ILIKE '%' || query || '%' OR $4 ILIKE '%' || query || '%')
+		ORDER BY created_at ASC
+	`
+	return r.querySavedSearches(ctx, query, auction.AuctionType, auction.SellerID, auction.Title, auction.Description)
+}
+
+func (r *Repository) querySavedSearches(ctx context.Context, query string, args ...any) ([]*SavedSearch, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var searches []*SavedSearch
+	for rows.Next() {
+		var search SavedSearch
+		if err := rows.Scan(
+			&search.ID,
+			&search.AgentID,
+			&search.Name,
+			&search.AuctionType,
+			&search.Query,
+			&search.SellerID,
+			&search.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		searches = append(searches, &search)
+	}
+
+	return searches, rows.Err()
+}
+
+// CreateSettlement creates a settlement of a won auction.
+func (r *Repository) CreateSettlement(ctx context.Context, settlement *Settlement) error {
+	query := `
+		INSERT INTO auction_settlements (id, auction_id, bid_id, bidder_id, amount, currency, second_chance, status,
+			transaction_id, due_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		settlement.ID,
+		settlement.AuctionID,
+		settlement.BidID,
+		settlement.BidderID,
+		settlement.Amount,
+		settlement.Currency,
+		settlement.SecondChance,
+		settlement.Status,
+		settlement.TransactionID,
+		settlement.DueAt,
+		settlement.CreatedAt,
+		settlement.UpdatedAt,
+	)
+
+	return err
+}
+
+// UpdateSettlement updates the status, transaction and deadline of a settlement.
+func (r *Repository) UpdateSettlement(ctx context.Context, settlement *Settlement) error {
+	query := `
+		UPDATE auction_settlements
+		SET status = $2, transaction_id = $3, due_at = $4, updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, query, settlement.ID, settlement.Status, settlement.TransactionID, settlement.DueAt)
+	return err
+}
+
+// MoveSettlement moves a settlement from one status to another and sets its
+// deadline, reporting whether it was still in the first.
+func (r *Repository) MoveSettlement(ctx context.Context, settlementID uuid.UUID, from, to SettlementStatus, dueAt time.Time) (bool, error) {
+	query := `
+		UPDATE auction_settlements
+		SET status = $3, due_at = $4, updated_at = NOW()
+		WHERE id = $1 AND status = $2
+	`
+	result, err := r.db.Exec(ctx, query, settlementID, from, to, dueAt)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// GetSettlementsByAuctionID retrieves the settlements of an auction, oldest first.
+func (r *Repository) GetSettlementsByAuctionID(ctx context.Context, auctionID uuid.UUID) ([]*Settlement, error) {
+	query := `
+		SELECT id, auction_id, bid_id, bidder_id, amount, currency, second_chance, status,
+			transaction_id, due_at, created_at, updated_at
+		FROM auction_settlements
+		WHERE auction_id = $1
+		ORDER BY created_at ASC
+	`
+	return r.querySettlements(ctx, query, auctionID)
+}
+
+// GetDueSettlements retrieves pending settlements, open offers and settlements
+// awaiting their transaction whose deadline has passed.
+func (r *Repository) GetDueSettlements(ctx context.Context, now time.Time, limit int) ([]*Settlement, error) {
+	query := `
+		SELECT id, auction_id, bid_id, bidder_id, amount, currency, second_chance, status,
+			transaction_id, due_at, created_at, updated_at
+		FROM auction_settlements
+		WHERE status IN ('offered', 'pending', 'pending_creation') AND due_at < $1
+		ORDER BY due_at ASC
+		LIMIT $2
+	`
+	return r.querySettlements(ctx, query, now, limit)
+}
+
+func (r *Repository) querySettlements(ctx context.Context, query string, args ...any) ([]*Settlement, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settlements []*Settlement
+	for rows.Next() {
+		var settlement Settlement
+		if err := rows.Scan(
+			&settlement.ID,
+			&settlement.AuctionID,
+			&settlement.BidID,
+			&settlement.BidderID,
+			&settlement.Amount,
+			&settlement.Currency,
+			&settlement.SecondChance,
+			&settlement.Status,
+			&settlement.TransactionID,
+			&settlement.DueAt,
+			&settlement.CreatedAt,
+			&settlement.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		settlements = append(settlements, &settlement)
+	}
+
+	return settlements, rows.Err()
+}
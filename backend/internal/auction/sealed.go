@@ -0,0 +1,306 @@
+package auction
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digi604/swarmmarket/backend/pkg/logger"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCommitmentRequired   = errors.New("commit-reveal auctions take a commitment instead of an amount")
+	ErrUnexpectedCommitment = errors.New("auction does not use commit-reveal")
+	ErrInvalidCommitment    = errors.New("commitment must be a hex-encoded SHA-256 hash")
+	ErrAlreadyCommitted     = errors.New("bidder has already committed a bid")
+	ErrRevealNotOpen        = errors.New("reveal window is not open")
+	ErrCommitmentMismatch   = errors.New("amount and nonce do not match the commitment")
+)
+
+// defaultRevealWindow is how long bidders have to reveal after a commit-reveal
+// auction closes, unless the seller sets it.
+const defaultRevealWindow = time.Hour
+
+// isSealedBid reports whether bids on the auction are hidden until it ends.
+func (a *Auction) isSealedBid() bool {
+	return a.AuctionType == AuctionTypeSealed || a.AuctionType == AuctionTypeVickrey || a.AuctionType == AuctionTypeBundle
+}
+
+// minSealedBid returns the lowest amount a sealed bid may have. Vickrey
+// auctions treat the starting price as the lowest price paid.
+func (a *Auction) minSealedBid() float64 {
+	if a.AuctionType == AuctionTypeVickrey {
+		return a.StartingPrice
+	}
+	return math.SmallestNonzeroFloat64
+}
+
+// CommitmentFor returns the commitment to a sealed bid: the hex-encoded SHA-256
+// of the amount in its shortest decimal form (150, 150.5) and the nonce,
+// joined by a colon.
+func CommitmentFor(amount float64, nonce string) string {
+	sum := sha256.Sum256([]byte(strconv.FormatFloat(amount, 'f', -1, 64) + ":" + nonce))
+	return hex.EncodeToString(sum[:])
+}
+
+// commitBid records a commitment to a sealed bid. The amount stays unknown
+// until the bidder reveals it after bidding closes.
+func (s *Service) commitBid(ctx context.Context, auction *Auction, bidderID uuid.UUID, req *PlaceBidRequest) (*Bid, error) {
+	if req.Commitment == nil || req.Amount != 0 || req.MaxAmount != nil {
+		return nil, ErrCommitmentRequired
+	}
+	commitment := strings.ToLower(*req.Commitment)
+	if decoded, err := hex.DecodeString(commitment); err != nil || len(decoded) != sha256.Size {
+		return nil, ErrInvalidCommitment
+	}
+
+	bids, err := s.repo.GetBidsByAuctionID(ctx, auction.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bids {
+		if b.BidderID == bidderID {
+			return nil, ErrAlreadyCommitted
+		}
+	}
+
+	bid := &Bid{
+		ID:         uuid.New(),
+		AuctionID:  auction.ID,
+		BidderID:   bidderID,
+		Commitment: &commitment,
+		Currency:   auction.Currency,
+		IsSealed:   true,
+		Status:     BidStatusCommitted,
+		Quantity:   req.units(),
+		Metadata:   make(map[string]any),
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := s.repo.CreateBid(ctx, bid); err != nil {
+		return nil, err
+	}
+
+	s.publishEvent(ctx, "bid.placed", map[string]any{
+		"auction_id": auction.ID,
+		"bidder_id":  bidderID,
+		"seller_id":  auction.SellerID,
+	})
+
+	return bid, nil
+}
+
+// RevealBid reveals a bidder's committed bid during the reveal window. Bids not
+// revealed by the end of the window are forfeited.
+func (s *Service) RevealBid(ctx context.Context, auctionID, bidderID uuid.UUID, req *RevealBidRequest) (*Bid, error) {
+	auction, err := s.repo.GetAuctionByID(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if !auction.CommitReveal {
+		return nil, ErrUnexpectedCommitment
+	}
+	now := time.Now().UTC()
+	if auction.Status != AuctionStatusRevealing || auction.RevealEndsAt == nil || now.After(*auction.RevealEndsAt) {
+		return nil, ErrRevealNotOpen
+	}
+
+	bids, err := s.repo.GetBidsByAuctionID(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	var bid *Bid
+	for _, b := range bids {
+		if b.BidderID == bidderID && b.Status == BidStatusCommitted && b.Commitment != nil {
+			bid = b
+			break
+		}
+	}
+	if bid == nil {
+		return nil, ErrBidNotFound
+	}
+
+	if CommitmentFor(req.Amount, req.Nonce) != *bid.Commitment {
+		return nil, ErrCommitmentMismatch
+	}
+	if req.Amount < auction.minSealedBid() {
+		return nil, ErrBidTooLow
+	}
+	if s.spendingChecker != nil {
+		if err := s.spendingChecker.CheckSpendingLimit(ctx, bidderID, req.Amount*float64(bid.units())); err != nil {
+			return nil, fmt.Errorf("spending limit check failed: %w", err)
+		}
+	}
+
+	if err := s.repo.RevealBid(ctx, bid.ID, req.Amount, now); err != nil {
+		return nil, err
+	}
+	bid.Amount = req.Amount
+	bid.RevealedAt = &now
+	bid.Status = BidStatusActive
+	return bid, nil
+}
+
+// startReveal closes bidding on a commit-reveal auction at endsAt and opens a
+// reveal window as long as the one the auction was created with.
+func (s *Service) startReveal(ctx context.Context, auction *Auction, endsAt time.Time) error {
+	window := defaultRevealWindow
+	if auction.RevealEndsAt != nil {
+		window = auction.RevealEndsAt.Sub(auction.EndsAt)
+	}
+	revealEndsAt := endsAt.Add(window)
+
+	if err := s.repo.StartAuctionReveal(ctx, auction.ID, endsAt, revealEndsAt); err != nil {
+		return err
+	}
+	auction.Status = AuctionStatusRevealing
+	auction.EndsAt = endsAt
+	auction.RevealEndsAt = &revealEndsAt
+
+	s.publishEvent(ctx, "auction.reveal_started", map[string]any{
+		"auction_id":     auction.ID,
+		"seller_id":      auction.SellerID,
+		"reveal_ends_at": revealEndsAt,
+	})
+	return nil
+}
+
+// settleSealedAuction awards a sealed or Vickrey auction to its highest bid,
+// the earliest of equal ones, or allocates the units of a multi-unit auction.
+// The winner of a Vickrey auction pays the highest competing bid, but at least
+// the starting and reserve prices. Unrevealed commitments are forfeited.
+func (s *Service) settleSealedAuction(ctx context.Context, auction *Auction) error {
+	bids, err := s.repo.GetBidsByAuctionID(ctx, auction.ID)
+	if err != nil {
+		return err
+	}
+
+	var eligible []*Bid
+	for _, b := range bids {
+		switch b.Status {
+		case BidStatusCommitted:
+			s.repo.UpdateBidStatus(ctx, b.ID, BidStatusForfeited)
+		case BidStatusActive, BidStatusWinning:
+			eligible = append(eligible, b)
+		}
+	}
+	sort.SliceStable(eligible, func(i, j int) bool {
+		if eligible[i].Amount != eligible[j].Amount {
+			return eligible[i].Amount > eligible[j].Amount
+		}
+		return eligible[i].CreatedAt.Before(eligible[j].CreatedAt)
+	})
+
+	if auction.isMultiUnit() {
+		return s.allocateUnits(ctx, auction, eligible)
+	}
+
+	if len(eligible) == 0 {
+		if err := s.repo.UpdateAuctionStatus(ctx, auction.ID, AuctionStatusEnded); err != nil {
+			return err
+		}
+		s.publishEvent(ctx, "auction.ended", map[string]any{
+			"auction_id": auction.ID,
+			"no_bids":    true,
+		})
+		return nil
+	}
+
+	winner := eligible[0]
+	if auction.ReservePrice != nil && winner.Amount < *auction.ReservePrice {
+		if err := s.repo.UpdateAuctionStatus(ctx, auction.ID, AuctionStatusEnded); err != nil {
+			return err
+		}
+		s.publishEvent(ctx, "auction.ended", map[string]any{
+			"auction_id":  auction.ID,
+			"met_reserve": false,
+		})
+		return nil
+	}
+
+	price := winner.Amount
+	if auction.AuctionType == AuctionTypeVickrey {
+		price = auction.StartingPrice
+		if auction.ReservePrice != nil {
+			price = math.Max(price, *auction.ReservePrice)
+		}
+		for _, b := range eligible[1:] {
+			if b.BidderID != winner.BidderID {
+				price = math.Max(price, b.Amount)
+				break
+			}
+		}
+		price = math.Min(price, winner.Amount)
+	}
+
+	s.repo.MarkPreviousBidsOutbid(ctx, auction.ID, winner.ID)
+	return s.awardAuction(ctx, auction, winner, price, map[string]any{"met_reserve": true})
+}
+
+// ProcessSealedAuctions closes the bidding of commit-reveal auctions that have
+// ended and settles Vickrey auctions that have ended and commit-reveal
+// auctions whose reveal window has passed. An auction that fails is logged and
+// retried on the next run. It returns the number of auctions it moved on.
+func (s *Service) ProcessSealedAuctions(ctx context.Context, now time.Time) (int, error) {
+	active, err := s.searchAllAuctions(ctx, AuctionStatusActive)
+	if err != nil {
+		return 0, fmt.Errorf("failed to search active auctions: %w", err)
+	}
+
+	processed := 0
+	for _, auction := range active {
+		if !auction.EndsAt.Before(now) {
+			continue
+		}
+		switch {
+		case auction.CommitReveal:
+			err = s.startReveal(ctx, auction, auction.EndsAt)
+		case auction.AuctionType == AuctionTypeVickrey:
+			err = s.settleSealedAuction(ctx, auction)
+		default:
+			continue
+		}
+		if errors.Is(err, ErrAuctionNotActive) {
+			// Ended meanwhile by its seller or another worker
+			continue
+		}
+		if err != nil {
+			logger.Error("auction_close_failed", map[string]interface{}{
+				"auction_id": auction.ID.String(),
+				"error":      err.Error(),
+			})
+			continue
+		}
+		processed++
+	}
+
+	revealing, err := s.searchAllAuctions(ctx, AuctionStatusRevealing)
+	if err != nil {
+		return processed, fmt.Errorf("failed to search revealing auctions: %w", err)
+	}
+	for _, auction := range revealing {
+		if auction.RevealEndsAt != nil && !auction.RevealEndsAt.Before(now) {
+			continue
+		}
+		if err := s.settleSealedAuction(ctx, auction); err != nil {
+			if errors.Is(err, ErrAuctionNotActive) {
+				continue
+			}
+			logger.Error("auction_settle_failed", map[string]interface{}{
+				"auction_id": auction.ID.String(),
+				"error":      err.Error(),
+			})
+			continue
+		}
+		processed++
+	}
+
+	return processed, nil
+}
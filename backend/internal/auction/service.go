@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/digi604/swarmmarket/backend/pkg/logger"
 	"github.com/google/uuid"
 )
 
@@ -16,6 +17,7 @@ var (
 	ErrCannotBidOnOwnAuction = errors.New("cannot bid on your own auction")
 	ErrNotAuthorized         = errors.New("not authorized to perform this action")
 	ErrInvalidAuctionType    = errors.New("invalid auction type")
+	ErrProxyNotSupported     = errors.New("proxy bids are only supported on english auctions")
 )
 
 // EventPublisher publishes events to the notification system.
@@ -28,18 +30,39 @@ type SpendingChecker interface {
 	CheckSpendingLimit(ctx context.Context, agentID uuid.UUID, amount float64) error
 }
 
+// TransactionCreator creates and cancels the transactions of won auctions (implemented by transaction.Service).
+type TransactionCreator interface {
+	CreateFromAuction(ctx context.Context, buyerID, sellerID uuid.UUID, auctionID *uuid.UUID, amount float64, currency string) (uuid.UUID, error)
+	CancelUnfundedTransaction(ctx context.Context, transactionID uuid.UUID) (bool, error)
+}
+
+// PenaltyCharger charges a seller the penalty for cancelling an auction with bids,
+// and reverses a charge whose cancellation failed (implemented by wallet.Service).
+type PenaltyCharger interface {
+	ChargeCancellationPenalty(ctx context.Context, auctionID, sellerID uuid.UUID, amount float64, currency string) (uuid.UUID, error)
+	ReverseCancellationPenalty(ctx context.Context, chargeID, sellerID uuid.UUID, amount float64, currency string) error
+}
+
 // Service handles auction business logic.
 type Service struct {
-	repo            RepositoryInterface
-	publisher       EventPublisher
-	spendingChecker SpendingChecker
+	repo               RepositoryInterface
+	publisher          EventPublisher
+	spendingChecker    SpendingChecker
+	txCreator          TransactionCreator
+	penaltyCharger     PenaltyCharger
+	paymentWindow      time.Duration
+	secondChanceWindow time.Duration
+	cancelPenaltyRate  float64
 }
 
 // NewService creates a new auction service.
 func NewService(repo RepositoryInterface, publisher EventPublisher) *Service {
 	return &Service{
-		repo:      repo,
-		publisher: publisher,
+		repo:               repo,
+		publisher:          publisher,
+		paymentWindow:      defaultPaymentWindow,
+		secondChanceWindow: defaultSecondChanceWindow,
+		cancelPenaltyRate:  defaultCancelPenaltyRate,
 	}
 }
 
@@ -48,12 +71,41 @@ func (s *Service) SetSpendingChecker(sc SpendingChecker) {
 	s.spendingChecker = sc
 }
 
+// SetTransactionCreator sets the transaction creator used to settle won auctions (to avoid circular dependency).
+func (s *Service) SetTransactionCreator(tc TransactionCreator) {
+	s.txCreator = tc
+}
+
+// SetPenaltyCharger sets the charger for cancellation penalties (to avoid circular dependency).
+func (s *Service) SetPenaltyCharger(pc PenaltyCharger) {
+	s.penaltyCharger = pc
+}
+
+// SetSettlementWindows sets how long a winner has to fund escrow and how long a
+// runner-up has to accept a second-chance offer. Zero keeps the default.
+func (s *Service) SetSettlementWindows(payment, secondChance time.Duration) {
+	if payment > 0 {
+		s.paymentWindow = payment
+	}
+	if secondChance > 0 {
+		s.secondChanceWindow = secondChance
+	}
+}
+
+// SetCancellationPenalty sets the percentage of the highest bid a seller is
+// charged for cancelling an auction that has bids. Zero keeps the default.
+func (s *Service) SetCancellationPenalty(percent float64) {
+	if percent > 0 {
+		s.cancelPenaltyRate = percent / 100
+	}
+}
+
 // CreateAuction creates a new auction.
 func (s *Service) CreateAuction(ctx context.Context, sellerID uuid.UUID, req *CreateAuctionRequest) (*Auction, error) {
 	// Validate auction type
 	auctionType := AuctionType(req.AuctionType)
 	switch auctionType {
-	case AuctionTypeEnglish, AuctionTypeDutch, AuctionTypeSealed, AuctionTypeContinuous:
+	case AuctionTypeEnglish, AuctionTypeDutch, AuctionTypeSealed, AuctionTypeVickrey, AuctionTypeContinuous, AuctionTypeBundle:
 		// Valid
 	default:
 		return nil, ErrInvalidAuctionType
@@ -69,6 +121,66 @@ func (s *Service) CreateAuction(ctx context.Context, sellerID uuid.UUID, req *Cr
 		return nil, errors.New("end time must be in the future")
 	}
 
+	// Validate Dutch price schedule
+	if (req.PriceDecrement == nil) != (req.DecrementIntervalSecs == nil) {
+		return nil, errors.New("price decrement and decrement interval must be set together")
+	}
+	if req.PriceDecrement != nil && (*req.PriceDecrement <= 0 || *req.DecrementIntervalSecs <= 0) {
+		return nil, errors.New("price decrement and decrement interval must be positive")
+	}
+
+	// Validate buy-now price
+	if req.BuyNowPrice != nil {
+		if !supportsBuyNow(auctionType) {
+			return nil, errors.New("buy-now is only supported on english, sealed and vickrey auctions")
+		}
+		if *req.BuyNowPrice < req.StartingPrice || req.ReservePrice != nil && *req.BuyNowPrice < *req.ReservePrice {
+			return nil, errors.New("buy-now price must be at least the starting and reserve prices")
+		}
+	}
+
+	// Validate multi-unit
+	quantity := 1
+	if req.Quantity != 0 {
+		quantity = req.Quantity
+	}
+	if quantity < 1 {
+		return nil, errors.New("quantity must be positive")
+	}
+	if quantity > 1 && auctionType != AuctionTypeSealed {
+		return nil, errors.New("multi-unit auctions must be sealed auctions")
+	}
+	if quantity > 1 && req.BuyNowPrice != nil {
+		return nil, errors.New("buy-now is not supported on multi-unit auctions")
+	}
+	var pricing PricingRule
+	if quantity > 1 {
+		pricing = PricingUniform
+		if req.Pricing != "" {
+			pricing = PricingRule(req.Pricing)
+		}
+	}
+	if pricing != "" && pricing != PricingUniform && pricing != PricingPayAsBid || req.Pricing != "" && quantity == 1 {
+		return nil, errors.New("pricing must be uniform or pay_as_bid and requires a quantity above 1")
+	}
+
+	// Validate bundle items
+	if auctionType == AuctionTypeBundle {
+		if err := validateBundleItems(req.Items); err != nil {
+			return nil, err
+		}
+	} else if len(req.Items) > 0 {
+		return nil, errors.New("items are only supported on bundle auctions")
+	}
+
+	// Validate commit-reveal
+	if req.CommitReveal && auctionType != AuctionTypeSealed && auctionType != AuctionTypeVickrey {
+		return nil, errors.New("commit-reveal is only supported on sealed and vickrey auctions")
+	}
+	if req.RevealWindowSecs != nil && (!req.CommitReveal || *req.RevealWindowSecs <= 0) {
+		return nil, errors.New("reveal window must be positive and requires commit-reveal")
+	}
+
 	now := time.Now().UTC()
 	startsAt := now
 	if req.StartsAt != nil && req.StartsAt.After(now) {
@@ -90,6 +202,16 @@ func (s *Service) CreateAuction(ctx context.Context, sellerID uuid.UUID, req *Cr
 		currency = req.Currency
 	}
 
+	var revealEndsAt *time.Time
+	if req.CommitReveal {
+		window := defaultRevealWindow
+		if req.RevealWindowSecs != nil {
+			window = time.Duration(*req.RevealWindowSecs) * time.Second
+		}
+		end := req.EndsAt.Add(window)
+		revealEndsAt = &end
+	}
+
 	auction := &Auction{
 		ID:                    uuid.New(),
 		ListingID:             req.ListingID,
@@ -101,6 +223,9 @@ func (s *Service) CreateAuction(ctx context.Context, sellerID uuid.UUID, req *Cr
 		CurrentPrice:          &req.StartingPrice,
 		ReservePrice:          req.ReservePrice,
 		BuyNowPrice:           req.BuyNowPrice,
+		BuyNowUntilFirstBid:   req.BuyNowUntilFirstBid,
+		Quantity:              quantity,
+		Pricing:               pricing,
 		Currency:              currency,
 		MinIncrement:          req.MinIncrement,
 		PriceDecrement:        req.PriceDecrement,
@@ -109,6 +234,8 @@ func (s *Service) CreateAuction(ctx context.Context, sellerID uuid.UUID, req *Cr
 		StartsAt:              startsAt,
 		EndsAt:                req.EndsAt,
 		ExtensionSeconds:      extensionSeconds,
+		CommitReveal:          req.CommitReveal,
+		RevealEndsAt:          revealEndsAt,
 		Metadata:              make(map[string]any),
 		CreatedAt:             now,
 		UpdatedAt:             now,
@@ -118,6 +245,22 @@ func (s *Service) CreateAuction(ctx context.Context, sellerID uuid.UUID, req *Cr
 		return nil, err
 	}
 
+	for i, item := range req.Items {
+		auction.Items = append(auction.Items, &BundleItem{
+			ID:          uuid.New(),
+			AuctionID:   auction.ID,
+			ListingID:   item.ListingID,
+			Title:       item.Title,
+			Description: item.Description,
+			Position:    i,
+		})
+	}
+	if len(auction.Items) > 0 {
+		if err := s.repo.CreateBundleItems(ctx, auction.Items); err != nil {
+			return nil, err
+		}
+	}
+
 	if auction.Status == AuctionStatusActive {
 		// Publish event for auctions that start immediately
 		s.publishEvent(ctx, "auction.started", map[string]any{
@@ -128,6 +271,7 @@ func (s *Service) CreateAuction(ctx context.Context, sellerID uuid.UUID, req *Cr
 			"starting_price": auction.StartingPrice,
 			"ends_at":        auction.EndsAt,
 		})
+		s.NotifySavedSearches(ctx, auction)
 	}
 
 	return auction, nil
@@ -135,17 +279,41 @@ func (s *Service) CreateAuction(ctx context.Context, sellerID uuid.UUID, req *Cr
 
 // GetAuction retrieves an auction by ID.
 func (s *Service) GetAuction(ctx context.Context, id uuid.UUID) (*Auction, error) {
-	return s.repo.GetAuctionByID(ctx, id)
+	auction, err := s.repo.GetAuctionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	auction.applyDutchPrice(time.Now().UTC())
+	if err := s.attachItems(ctx, auction); err != nil {
+		return nil, err
+	}
+	return auction, nil
 }
 
 // GetAuctionBySlug retrieves an auction by slug.
 func (s *Service) GetAuctionBySlug(ctx context.Context, slug string) (*Auction, error) {
-	return s.repo.GetAuctionBySlug(ctx, slug)
+	auction, err := s.repo.GetAuctionBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	auction.applyDutchPrice(time.Now().UTC())
+	if err := s.attachItems(ctx, auction); err != nil {
+		return nil, err
+	}
+	return auction, nil
 }
 
 // SearchAuctions searches for auctions.
 func (s *Service) SearchAuctions(ctx context.Context, params SearchAuctionsParams) (*AuctionListResult, error) {
-	return s.repo.SearchAuctions(ctx, params)
+	result, err := s.repo.SearchAuctions(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for _, auction := range result.Auctions {
+		auction.applyDutchPrice(now)
+	}
+	return result, nil
 }
 
 // PlaceBid places a bid on an auction.
@@ -171,13 +339,50 @@ func (s *Service) PlaceBid(ctx context.Context, auctionID, bidderID uuid.UUID, r
 		return nil, ErrCannotBidOnOwnAuction
 	}
 
-	// Check spending limits
+	if req.Quantity < 0 || req.units() > auction.units() {
+		return nil, ErrInvalidQuantity
+	}
+
+	// Commit-reveal bids carry no amount until revealed
+	if auction.CommitReveal {
+		return s.commitBid(ctx, auction, bidderID, req)
+	}
+	if req.Commitment != nil {
+		return nil, ErrUnexpectedCommitment
+	}
+
+	if req.MaxAmount != nil && auction.AuctionType != AuctionTypeEnglish {
+		return nil, ErrProxyNotSupported
+	}
+	if len(req.Items) > 0 && auction.AuctionType != AuctionTypeBundle {
+		return nil, ErrInvalidBundle
+	}
+
+	// Check spending limits against the most the bid can cost
 	if s.spendingChecker != nil {
-		if err := s.spendingChecker.CheckSpendingLimit(ctx, bidderID, req.Amount); err != nil {
+		if err := s.spendingChecker.CheckSpendingLimit(ctx, bidderID, req.ceiling()*float64(req.units())); err != nil {
 			return nil, fmt.Errorf("spending limit check failed: %w", err)
 		}
 	}
 
+	if auction.AuctionType == AuctionTypeBundle {
+		return s.placeBundleBid(ctx, auction, bidderID, req)
+	}
+
+	// A bid at or above the buy-now price buys the auction outright
+	if auction.buyNowAvailable() && req.ceiling() >= *auction.BuyNowPrice {
+		bid, err := s.buyNow(ctx, auctionID, bidderID)
+		if err != ErrBuyNowNotAvailable {
+			return bid, err
+		}
+		// Another bid came first and withdrew buy-now, so this one is placed as usual
+	}
+
+	// English auctions resolve proxy bids against the leader
+	if auction.AuctionType == AuctionTypeEnglish {
+		return s.placeEnglishBid(ctx, auctionID, bidderID, req)
+	}
+
 	// Get current highest bid
 	highestBid, err := s.repo.GetHighestBid(ctx, auctionID)
 	if err != nil {
@@ -186,26 +391,10 @@ func (s *Service) PlaceBid(ctx context.Context, auctionID, bidderID uuid.UUID, r
 
 	// Validate bid amount based on auction type
 	switch auction.AuctionType {
-	case AuctionTypeEnglish:
-		minBid := auction.StartingPrice
-		if highestBid != nil {
-			minBid = highestBid.Amount
-			if auction.MinIncrement != nil {
-				minBid += *auction.MinIncrement
-			} else {
-				minBid += 1 // Default increment
-			}
-		}
-		if req.Amount < minBid {
-			return nil, ErrBidTooLow
-		}
-
 	case AuctionTypeDutch:
-		// Dutch auction: first bidder wins at current price
-		currentPrice := auction.StartingPrice
-		if auction.CurrentPrice != nil {
-			currentPrice = *auction.CurrentPrice
-		}
+		// Dutch auction: first bidder wins at current price, which is computed
+		// from the clock since the stored price may not be stepped down yet
+		currentPrice, _ := auction.DutchPriceAt(time.Now().UTC())
 		if req.Amount < currentPrice {
 			return nil, ErrBidTooLow
 		}
@@ -216,6 +405,12 @@ func (s *Service) PlaceBid(ctx context.Context, auctionID, bidderID uuid.UUID, r
 			return nil, ErrBidTooLow
 		}
 
+	case AuctionTypeVickrey:
+		// Vickrey: at least the starting price, the least the winner pays
+		if req.Amount < auction.minSealedBid() {
+			return nil, ErrBidTooLow
+		}
+
 	case AuctionTypeContinuous:
 		// Continuous: any amount above current
 		if highestBid != nil && req.Amount <= highestBid.Amount {
@@ -230,8 +425,9 @@ func (s *Service) PlaceBid(ctx context.Context, auctionID, bidderID uuid.UUID, r
 		BidderID:  bidderID,
 		Amount:    req.Amount,
 		Currency:  auction.Currency,
-		IsSealed:  auction.AuctionType == AuctionTypeSealed,
+		IsSealed:  auction.isSealedBid(),
 		Status:    BidStatusActive,
+		Quantity:  req.units(),
 		Metadata:  make(map[string]any),
 		CreatedAt: now,
 	}
@@ -242,41 +438,18 @@ func (s *Service) PlaceBid(ctx context.Context, auctionID, bidderID uuid.UUID, r
 
 	// Handle auction type specific logic
 	switch auction.AuctionType {
-	case AuctionTypeEnglish:
-		// Mark previous bids as outbid
-		if highestBid != nil {
-			s.repo.MarkPreviousBidsOutbid(ctx, auctionID, bid.ID)
-
-			// Notify outbid bidder
-			s.publishEvent(ctx, "bid.outbid", map[string]any{
-				"auction_id": auctionID,
-				"bidder_id":  highestBid.BidderID,
-				"old_amount": highestBid.Amount,
-				"new_amount": bid.Amount,
-			})
-		}
-
-		// Update current price
-		s.repo.UpdateAuctionPrice(ctx, auctionID, bid.Amount)
-
-		// Anti-sniping: extend auction if bid is close to end
-		if auction.EndsAt.Sub(now) < time.Duration(auction.ExtensionSeconds)*time.Second {
-			newEndTime := now.Add(time.Duration(auction.ExtensionSeconds) * time.Second)
-			s.repo.ExtendAuction(ctx, auctionID, newEndTime)
-		}
-
 	case AuctionTypeDutch:
-		// First valid bid wins immediately
-		s.repo.SetAuctionWinner(ctx, auctionID, bid.ID, bidderID)
-		s.repo.UpdateBidStatus(ctx, bid.ID, BidStatusWon)
-
-		s.publishEvent(ctx, "auction.ended", map[string]any{
-			"auction_id":  auctionID,
-			"winner_id":   bidderID,
-			"final_price": bid.Amount,
-		})
+		// First valid bid wins immediately, at the clock price rather than its amount
+		price, _ := auction.DutchPriceAt(now)
+		if err := s.awardAuction(ctx, auction, bid, price, nil); err != nil {
+			if errors.Is(err, ErrAuctionNotActive) {
+				// Another bid took the auction first
+				s.repo.UpdateBidStatus(ctx, bid.ID, BidStatusLost)
+			}
+			return nil, err
+		}
 
-	case AuctionTypeSealed:
+	case AuctionTypeSealed, AuctionTypeVickrey:
 		// Just record the bid, winner determined at auction end
 
 	case AuctionTypeContinuous:
@@ -311,14 +484,20 @@ func (s *Service) GetBids(ctx context.Context, auctionID uuid.UUID, requesterID
 	}
 
 	// For sealed auctions, hide other bidders' amounts until ended
-	if auction.AuctionType == AuctionTypeSealed && auction.Status != AuctionStatusEnded {
+	if auction.isSealedBid() && auction.Status != AuctionStatusEnded {
 		for _, bid := range bids {
 			if requesterID == nil || bid.BidderID != *requesterID {
 				bid.Amount = 0 // Hide amount
+				bid.Commitment = nil
 			}
 		}
 	}
 
+	// Proxy ceilings are never shown
+	for _, bid := range bids {
+		bid.MaxAmount = nil
+	}
+
 	return bids, nil
 }
 
@@ -338,47 +517,121 @@ func (s *Service) EndAuction(ctx context.Context, auctionID, requesterID uuid.UU
 		return nil, ErrAuctionNotActive
 	}
 
-	// Get highest bid
-	highestBid, err := s.repo.GetHighestBid(ctx, auctionID)
-	if err != nil {
+	// Commit-reveal auctions open their reveal window and settle when it ends
+	if auction.CommitReveal {
+		if err := s.startReveal(ctx, auction, time.Now().UTC()); err != nil {
+			return nil, err
+		}
+		return s.repo.GetAuctionByID(ctx, auctionID)
+	}
+	if auction.AuctionType == AuctionTypeVickrey {
+		if err := s.settleSealedAuction(ctx, auction); err != nil {
+			return nil, err
+		}
+		return s.repo.GetAuctionByID(ctx, auctionID)
+	}
+
+	if err := s.closeAuction(ctx, auction); err != nil {
 		return nil, err
 	}
 
-	if highestBid != nil {
-		// Check reserve price
-		metReserve := true
-		if auction.ReservePrice != nil && highestBid.Amount < *auction.ReservePrice {
-			metReserve = false
-		}
+	return s.repo.GetAuctionByID(ctx, auctionID)
+}
 
-		if metReserve {
-			s.repo.SetAuctionWinner(ctx, auctionID, highestBid.ID, highestBid.BidderID)
-			s.repo.UpdateBidStatus(ctx, highestBid.ID, BidStatusWon)
+// closeAuction ends an auction that has its winner decided by the highest bid,
+// awarding it if the bid meets the reserve price. Multi-unit auctions allocate
+// their units and bundle auctions their items instead.
+func (s *Service) closeAuction(ctx context.Context, auction *Auction) error {
+	if auction.isMultiUnit() {
+		return s.settleSealedAuction(ctx, auction)
+	}
+	if auction.AuctionType == AuctionTypeBundle {
+		return s.settleBundleAuction(ctx, auction)
+	}
 
-			s.publishEvent(ctx, "auction.ended", map[string]any{
-				"auction_id":  auctionID,
-				"winner_id":   highestBid.BidderID,
-				"final_price": highestBid.Amount,
-				"met_reserve": true,
-			})
-		} else {
-			s.repo.UpdateAuctionStatus(ctx, auctionID, AuctionStatusEnded)
+	highestBid, err := s.repo.GetHighestBid(ctx, auction.ID)
+	if err != nil {
+		return err
+	}
 
-			s.publishEvent(ctx, "auction.ended", map[string]any{
-				"auction_id":  auctionID,
-				"met_reserve": false,
-			})
+	if highestBid == nil {
+		if err := s.repo.UpdateAuctionStatus(ctx, auction.ID, AuctionStatusEnded); err != nil {
+			return err
 		}
-	} else {
-		s.repo.UpdateAuctionStatus(ctx, auctionID, AuctionStatusEnded)
-
 		s.publishEvent(ctx, "auction.ended", map[string]any{
-			"auction_id": auctionID,
+			"auction_id": auction.ID,
 			"no_bids":    true,
 		})
+		return nil
 	}
 
-	return s.repo.GetAuctionByID(ctx, auctionID)
+	// Check reserve price
+	if auction.ReservePrice != nil && highestBid.Amount < *auction.ReservePrice {
+		if err := s.repo.UpdateAuctionStatus(ctx, auction.ID, AuctionStatusEnded); err != nil {
+			return err
+		}
+		s.publishEvent(ctx, "auction.ended", map[string]any{
+			"auction_id":  auction.ID,
+			"met_reserve": false,
+		})
+		return nil
+	}
+
+	return s.awardAuction(ctx, auction, highestBid, highestBid.Amount, map[string]any{"met_reserve": true})
+}
+
+// EndExpiredAuctions ends active auctions past their end time. Vickrey and
+// commit-reveal auctions are left to ProcessSealedAuctions. An auction that
+// fails to end is logged and retried on the next run. It returns the number of
+// auctions ended.
+func (s *Service) EndExpiredAuctions(ctx context.Context, now time.Time) (int, error) {
+	auctions, err := s.searchAllAuctions(ctx, AuctionStatusActive)
+	if err != nil {
+		return 0, fmt.Errorf("failed to search active auctions: %w", err)
+	}
+
+	ended := 0
+	for _, auction := range auctions {
+		if !auction.EndsAt.Before(now) || auction.CommitReveal || auction.AuctionType == AuctionTypeVickrey {
+			continue
+		}
+		if err := s.closeAuction(ctx, auction); err != nil {
+			if errors.Is(err, ErrAuctionNotActive) {
+				// Ended meanwhile by its seller or another worker
+				continue
+			}
+			logger.Error("auction_end_failed", map[string]interface{}{
+				"auction_id": auction.ID.String(),
+				"error":      err.Error(),
+			})
+			continue
+		}
+		ended++
+	}
+
+	return ended, nil
+}
+
+// searchAllAuctions returns every auction with the status, a page at a time.
+// All pages are read before any auction is moved on, so that moving one
+// doesn't shift the pages.
+func (s *Service) searchAllAuctions(ctx context.Context, status AuctionStatus) ([]*Auction, error) {
+	var auctions []*Auction
+	for offset := 0; ; offset += 100 {
+		result, err := s.repo.SearchAuctions(ctx, SearchAuctionsParams{
+			Status: &status,
+			Limit:  100,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		auctions = append(auctions, result.Auctions...)
+
+		if len(result.Auctions) < 100 || offset+100 >= result.Total {
+			return auctions, nil
+		}
+	}
 }
 
 // IsAuctionOwner checks if an agent owns an auction.
@@ -393,6 +646,9 @@ func (s *Service) IsAuctionOwner(ctx context.Context, auctionID, agentID uuid.UU
 // Helper to publish events asynchronously.
 func (s *Service) publishEvent(ctx context.Context, eventType string, payload map[string]any) {
 	if s.publisher != nil {
+		if watchedEvents[eventType] {
+			s.addWatchers(ctx, payload)
+		}
 		go s.publisher.Publish(ctx, eventType, payload)
 	}
 }
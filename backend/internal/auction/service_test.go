@@ -2,6 +2,9 @@ package auction
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -241,6 +244,88 @@ func (m *mockPublisher) Publish(ctx context.Context, eventType string, payload m
 	return nil
 }
 
+// mockTransactionCreator implements TransactionCreator for testing.
+type mockTransactionCreator struct {
+	amounts   map[uuid.UUID]float64   // Transaction ID -> amount
+	buyers    map[uuid.UUID]uuid.UUID // Transaction ID -> buyer
+	funded    map[uuid.UUID]bool
+	cancelled []uuid.UUID
+	createErr error
+}
+
+func newMockTransactionCreator() *mockTransactionCreator {
+	return &mockTransactionCreator{
+		amounts: make(map[uuid.UUID]float64),
+		buyers:  make(map[uuid.UUID]uuid.UUID),
+		funded:  make(map[uuid.UUID]bool),
+	}
+}
+
+func (m *mockTransactionCreator) CreateFromAuction(ctx context.Context, buyerID, sellerID uuid.UUID, auctionID *uuid.UUID, amount float64, currency string) (uuid.UUID, error) {
+	if m.createErr != nil {
+		return uuid.Nil, m.createErr
+	}
+	id := uuid.New()
+	m.amounts[id] = amount
+	m.buyers[id] = buyerID
+	return id, nil
+}
+
+func (m *mockTransactionCreator) CancelUnfundedTransaction(ctx context.Context, transactionID uuid.UUID) (bool, error) {
+	if m.funded[transactionID] {
+		return false, nil
+	}
+	m.cancelled = append(m.cancelled, transactionID)
+	return true, nil
+}
+
+// mockSpendingChecker implements SpendingChecker for testing.
+type mockSpendingChecker struct {
+	limit float64
+}
+
+func (m *mockSpendingChecker) CheckSpendingLimit(ctx context.Context, agentID uuid.UUID, amount float64) error {
+	if amount > m.limit {
+		return errors.New("spending limit exceeded")
+	}
+	return nil
+}
+
+// spendingCheckFunc implements SpendingChecker with a function.
+type spendingCheckFunc func() error
+
+func (f spendingCheckFunc) CheckSpendingLimit(ctx context.Context, agentID uuid.UUID, amount float64) error {
+	return f()
+}
+
+// mockPenaltyCharger implements PenaltyCharger for testing.
+type mockPenaltyCharger struct {
+	charged  map[uuid.UUID]float64 // Auction ID -> penalty
+	charges  map[uuid.UUID]uuid.UUID // Charge ID -> auction ID
+	reversed []uuid.UUID           // Auction IDs
+	err      error
+}
+
+func (m *mockPenaltyCharger) ChargeCancellationPenalty(ctx context.Context, auctionID, sellerID uuid.UUID, amount float64, currency string) (uuid.UUID, error) {
+	if m.err != nil {
+		return uuid.Nil, m.err
+	}
+	if m.charges == nil {
+		m.charges = make(map[uuid.UUID]uuid.UUID)
+	}
+	chargeID := uuid.New()
+	m.charges[chargeID] = auctionID
+	m.charged[auctionID] = amount
+	return chargeID, nil
+}
+
+func (m *mockPenaltyCharger) ReverseCancellationPenalty(ctx context.Context, chargeID, sellerID uuid.UUID, amount float64, currency string) error {
+	auctionID := m.charges[chargeID]
+	delete(m.charged, auctionID)
+	m.reversed = append(m.reversed, auctionID)
+	return nil
+}
+
 // mockRepository implements RepositoryInterface for testing.
 type mockRepository struct {
 	auctions    map[uuid.UUID]*Auction
@@ -248,13 +333,25 @@ type mockRepository struct {
 	createErr   error
 	getErr      error
 	searchErr   error
+	cancelErr   error
 	createBidErr error
+	settlements map[uuid.UUID][]*Settlement
+	history     map[uuid.UUID][]*HistoryEntry
+	items       map[uuid.UUID][]*BundleItem
+	watches     map[uuid.UUID]map[uuid.UUID]bool // Auction ID -> watching agents
+	searches    []*SavedSearch
+	onLock      func() // Runs once the auction lock is taken, before the locked work
+	bidsErr     map[uuid.UUID]error // Auction ID -> error reading its bids
 }
 
 func newMockRepository() *mockRepository {
 	return &mockRepository{
-		auctions: make(map[uuid.UUID]*Auction),
-		bids:     make(map[uuid.UUID][]*Bid),
+		auctions:    make(map[uuid.UUID]*Auction),
+		bids:        make(map[uuid.UUID][]*Bid),
+		settlements: make(map[uuid.UUID][]*Settlement),
+		history:     make(map[uuid.UUID][]*HistoryEntry),
+		items:       make(map[uuid.UUID][]*BundleItem),
+		watches:     make(map[uuid.UUID]map[uuid.UUID]bool),
 	}
 }
 
@@ -297,15 +394,22 @@ func (m *mockRepository) SearchAuctions(ctx context.Context, params SearchAuctio
 		if params.Status != nil && a.Status != *params.Status {
 			continue
 		}
+		if params.WatcherID != nil && !m.watches[a.ID][*params.WatcherID] {
+			continue
+		}
 		auctions = append(auctions, a)
 	}
 	limit := params.Limit
 	if limit <= 0 {
 		limit = 20
 	}
+	// In a stable order so pages don't overlap
+	sort.Slice(auctions, func(i, j int) bool { return auctions[i].ID.String() < auctions[j].ID.String() })
+	total := len(auctions)
+	auctions = auctions[min(params.Offset, total):min(params.Offset+limit, total)]
 	return &AuctionListResult{
 		Auctions: auctions,
-		Total:    len(auctions),
+		Total:    total,
 		Limit:    limit,
 		Offset:   params.Offset,
 	}, nil
@@ -320,6 +424,15 @@ func (m *mockRepository) UpdateAuctionPrice(ctx context.Context, auctionID uuid.
 	return nil
 }
 
+func (m *mockRepository) LowerAuctionPrice(ctx context.Context, auctionID uuid.UUID, price float64) (bool, error) {
+	auction, ok := m.auctions[auctionID]
+	if !ok || auction.Status != AuctionStatusActive || auction.CurrentPrice != nil && *auction.CurrentPrice <= price {
+		return false, nil
+	}
+	auction.CurrentPrice = &price
+	return true, nil
+}
+
 func (m *mockRepository) UpdateAuctionStatus(ctx context.Context, auctionID uuid.UUID, status AuctionStatus) error {
 	auction, ok := m.auctions[auctionID]
 	if !ok {
@@ -329,17 +442,38 @@ func (m *mockRepository) UpdateAuctionStatus(ctx context.Context, auctionID uuid
 	return nil
 }
 
-func (m *mockRepository) SetAuctionWinner(ctx context.Context, auctionID, winningBidID, winnerID uuid.UUID) error {
+func (m *mockRepository) SetAuctionWinner(ctx context.Context, auctionID, winningBidID, winnerID uuid.UUID) (bool, error) {
 	auction, ok := m.auctions[auctionID]
-	if !ok {
-		return ErrAuctionNotFound
+	if !ok || auction.Status != AuctionStatusActive && auction.Status != AuctionStatusRevealing {
+		return false, nil
 	}
 	auction.WinningBidID = &winningBidID
 	auction.WinnerID = &winnerID
 	auction.Status = AuctionStatusEnded
+	return true, nil
+}
+
+func (m *mockRepository) ReplaceAuctionWinner(ctx context.Context, auctionID, winningBidID, winnerID uuid.UUID) error {
+	auction, ok := m.auctions[auctionID]
+	if !ok {
+		return ErrAuctionNotFound
+	}
+	if auction.Status == AuctionStatusEnded {
+		auction.WinningBidID = &winningBidID
+		auction.WinnerID = &winnerID
+	}
 	return nil
 }
 
+func (m *mockRepository) EndAuction(ctx context.Context, auctionID uuid.UUID) (bool, error) {
+	auction, ok := m.auctions[auctionID]
+	if !ok || auction.Status != AuctionStatusActive && auction.Status != AuctionStatusRevealing {
+		return false, nil
+	}
+	auction.Status = AuctionStatusEnded
+	return true, nil
+}
+
 func (m *mockRepository) ExtendAuction(ctx context.Context, auctionID uuid.UUID, newEndTime time.Time) error {
 	auction, ok := m.auctions[auctionID]
 	if !ok {
@@ -349,6 +483,54 @@ func (m *mockRepository) ExtendAuction(ctx context.Context, auctionID uuid.UUID,
 	return nil
 }
 
+func (m *mockRepository) StartAuctionReveal(ctx context.Context, auctionID uuid.UUID, endsAt, revealEndsAt time.Time) error {
+	auction, ok := m.auctions[auctionID]
+	if !ok {
+		return ErrAuctionNotFound
+	}
+	auction.Status = AuctionStatusRevealing
+	auction.EndsAt = endsAt
+	auction.RevealEndsAt = &revealEndsAt
+	return nil
+}
+
+func (m *mockRepository) UpdateScheduledAuction(ctx context.Context, auction *Auction) (bool, error) {
+	stored, ok := m.auctions[auction.ID]
+	if !ok || stored.Status != AuctionStatusScheduled {
+		return false, nil
+	}
+	*stored = *auction
+	return true, nil
+}
+
+func (m *mockRepository) WithAuctionLock(ctx context.Context, auctionID uuid.UUID, fn func(repo RepositoryInterface) error) error {
+	if _, ok := m.auctions[auctionID]; !ok {
+		return ErrAuctionNotFound
+	}
+	if m.onLock != nil {
+		m.onLock()
+	}
+	return fn(m)
+}
+
+func (m *mockRepository) CancelAuction(ctx context.Context, auctionID uuid.UUID) (bool, error) {
+	if m.cancelErr != nil {
+		return false, m.cancelErr
+	}
+	auction, ok := m.auctions[auctionID]
+	if !ok || auction.Status != AuctionStatusScheduled && auction.Status != AuctionStatusActive {
+		return false, nil
+	}
+	auction.Status = AuctionStatusCancelled
+	for _, b := range m.bids[auctionID] {
+		switch b.Status {
+		case BidStatusActive, BidStatusWinning, BidStatusOutbid, BidStatusCommitted:
+			b.Status = BidStatusCancelled
+		}
+	}
+	return true, nil
+}
+
 func (m *mockRepository) CreateBid(ctx context.Context, bid *Bid) error {
 	if m.createBidErr != nil {
 		return m.createBidErr
@@ -358,6 +540,9 @@ func (m *mockRepository) CreateBid(ctx context.Context, bid *Bid) error {
 }
 
 func (m *mockRepository) GetBidsByAuctionID(ctx context.Context, auctionID uuid.UUID) ([]*Bid, error) {
+	if err := m.bidsErr[auctionID]; err != nil {
+		return nil, err
+	}
 	// Return copies to avoid service modifications affecting stored data
 	var result []*Bid
 	for _, b := range m.bids[auctionID] {
@@ -368,6 +553,9 @@ func (m *mockRepository) GetBidsByAuctionID(ctx context.Context, auctionID uuid.
 }
 
 func (m *mockRepository) GetHighestBid(ctx context.Context, auctionID uuid.UUID) (*Bid, error) {
+	if err := m.bidsErr[auctionID]; err != nil {
+		return nil, err
+	}
 	bids := m.bids[auctionID]
 	if len(bids) == 0 {
 		return nil, nil
@@ -395,6 +583,57 @@ func (m *mockRepository) UpdateBidStatus(ctx context.Context, bidID uuid.UUID, s
 	return ErrBidNotFound
 }
 
+func (m *mockRepository) AllocateBid(ctx context.Context, bidID uuid.UUID, status BidStatus, allocated int) error {
+	for _, bids := range m.bids {
+		for _, b := range bids {
+			if b.ID == bidID {
+				b.Status = status
+				b.Allocated = &allocated
+				return nil
+			}
+		}
+	}
+	return ErrBidNotFound
+}
+
+func (m *mockRepository) UpdateBidAmount(ctx context.Context, bidID uuid.UUID, amount float64) error {
+	for _, bids := range m.bids {
+		for _, b := range bids {
+			if b.ID == bidID {
+				b.Amount = amount
+				return nil
+			}
+		}
+	}
+	return ErrBidNotFound
+}
+
+func (m *mockRepository) UpdateBidMaxAmount(ctx context.Context, bidID uuid.UUID, maxAmount float64) error {
+	for _, bids := range m.bids {
+		for _, b := range bids {
+			if b.ID == bidID {
+				b.MaxAmount = &maxAmount
+				return nil
+			}
+		}
+	}
+	return ErrBidNotFound
+}
+
+func (m *mockRepository) RevealBid(ctx context.Context, bidID uuid.UUID, amount float64, revealedAt time.Time) error {
+	for _, bids := range m.bids {
+		for _, b := range bids {
+			if b.ID == bidID && b.Status == BidStatusCommitted {
+				b.Amount = amount
+				b.RevealedAt = &revealedAt
+				b.Status = BidStatusActive
+				return nil
+			}
+		}
+	}
+	return ErrBidNotFound
+}
+
 func (m *mockRepository) MarkPreviousBidsOutbid(ctx context.Context, auctionID uuid.UUID, exceptBidID uuid.UUID) error {
 	for _, b := range m.bids[auctionID] {
 		if b.ID != exceptBidID && b.Status == BidStatusActive {
@@ -404,6 +643,148 @@ func (m *mockRepository) MarkPreviousBidsOutbid(ctx context.Context, auctionID u
 	return nil
 }
 
+func (m *mockRepository) CreateSettlement(ctx context.Context, settlement *Settlement) error {
+	m.settlements[settlement.AuctionID] = append(m.settlements[settlement.AuctionID], settlement)
+	return nil
+}
+
+func (m *mockRepository) UpdateSettlement(ctx context.Context, settlement *Settlement) error {
+	for _, stored := range m.settlements[settlement.AuctionID] {
+		if stored.ID == settlement.ID {
+			*stored = *settlement
+			return nil
+		}
+	}
+	return ErrAuctionNotFound
+}
+
+func (m *mockRepository) MoveSettlement(ctx context.Context, settlementID uuid.UUID, from, to SettlementStatus, dueAt time.Time) (bool, error) {
+	for _, settlements := range m.settlements {
+		for _, stored := range settlements {
+			if stored.ID == settlementID && stored.Status == from {
+				stored.Status = to
+				stored.DueAt = dueAt
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRepository) GetSettlementsByAuctionID(ctx context.Context, auctionID uuid.UUID) ([]*Settlement, error) {
+	var result []*Settlement
+	for _, s := range m.settlements[auctionID] {
+		copy := *s
+		result = append(result, &copy)
+	}
+	return result, nil
+}
+
+func (m *mockRepository) GetDueSettlements(ctx context.Context, now time.Time, limit int) ([]*Settlement, error) {
+	var result []*Settlement
+	for _, settlements := range m.settlements {
+		for _, s := range settlements {
+			switch s.Status {
+			case SettlementStatusOffered, SettlementStatusPending, SettlementStatusPendingCreation:
+			default:
+				continue
+			}
+			if s.DueAt.Before(now) {
+				copy := *s
+				result = append(result, &copy)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *mockRepository) CreateHistoryEntry(ctx context.Context, entry *HistoryEntry) error {
+	m.history[entry.AuctionID] = append(m.history[entry.AuctionID], entry)
+	return nil
+}
+
+func (m *mockRepository) GetAuctionHistory(ctx context.Context, auctionID uuid.UUID) ([]*HistoryEntry, error) {
+	return m.history[auctionID], nil
+}
+
+func (m *mockRepository) CreateBundleItems(ctx context.Context, items []*BundleItem) error {
+	for _, item := range items {
+		m.items[item.AuctionID] = append(m.items[item.AuctionID], item)
+	}
+	return nil
+}
+
+func (m *mockRepository) GetBundleItems(ctx context.Context, auctionID uuid.UUID) ([]*BundleItem, error) {
+	return m.items[auctionID], nil
+}
+
+func (m *mockRepository) WatchAuction(ctx context.Context, agentID, auctionID uuid.UUID) error {
+	if m.watches[auctionID] == nil {
+		m.watches[auctionID] = make(map[uuid.UUID]bool)
+	}
+	m.watches[auctionID][agentID] = true
+	return nil
+}
+
+func (m *mockRepository) UnwatchAuction(ctx context.Context, agentID, auctionID uuid.UUID) (bool, error) {
+	if !m.watches[auctionID][agentID] {
+		return false, nil
+	}
+	delete(m.watches[auctionID], agentID)
+	return true, nil
+}
+
+func (m *mockRepository) GetAuctionWatcherIDs(ctx context.Context, auctionID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for id := range m.watches[auctionID] {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (m *mockRepository) CreateSavedSearch(ctx context.Context, search *SavedSearch) error {
+	m.searches = append(m.searches, search)
+	return nil
+}
+
+func (m *mockRepository) GetSavedSearches(ctx context.Context, agentID uuid.UUID) ([]*SavedSearch, error) {
+	var result []*SavedSearch
+	for _, search := range m.searches {
+		if search.AgentID == agentID {
+			result = append(result, search)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockRepository) DeleteSavedSearch(ctx context.Context, id, agentID uuid.UUID) (bool, error) {
+	for i, search := range m.searches {
+		if search.ID == id && search.AgentID == agentID {
+			m.searches = append(m.searches[:i], m.searches[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRepository) GetMatchingSavedSearches(ctx context.Context, auction *Auction) ([]*SavedSearch, error) {
+	var result []*SavedSearch
+	for _, search := range m.searches {
+		if search.AuctionType != nil && *search.AuctionType != auction.AuctionType {
+			continue
+		}
+		if search.SellerID != nil && *search.SellerID != auction.SellerID {
+			continue
+		}
+		query := strings.ToLower(search.Query)
+		if !strings.Contains(strings.ToLower(auction.Title), query) && !strings.Contains(strings.ToLower(auction.Description), query) {
+			continue
+		}
+		result = append(result, search)
+	}
+	return result, nil
+}
+
 func (m *mockRepository) GetAuctionBySlug(ctx context.Context, slug string) (*Auction, error) {
 	for _, a := range m.auctions {
 		if a.Slug == slug {
@@ -894,58 +1275,230 @@ func TestService_PlaceBid_English(t *testing.T) {
 	}
 }
 
-func TestService_PlaceBid_TooLow(t *testing.T) {
-	repo := newMockRepository()
-	service := NewService(repo, nil)
-
-	sellerID := uuid.New()
-	bidderID := uuid.New()
-
-	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
+func newTestEnglishAuction(t *testing.T, service *Service, reserve *float64) *Auction {
+	t.Helper()
+	increment := 5.0
+	auction, err := service.CreateAuction(context.Background(), uuid.New(), &CreateAuctionRequest{
 		AuctionType:   "english",
 		Title:         "English Auction",
 		StartingPrice: 100.0,
+		ReservePrice:  reserve,
+		MinIncrement:  &increment,
 		EndsAt:        time.Now().Add(24 * time.Hour),
 	})
-
-	// Place first bid
-	service.PlaceBid(context.Background(), auction.ID, bidderID, &PlaceBidRequest{
-		Amount: 100.0,
-	})
-
-	// Try to place lower bid
-	_, err := service.PlaceBid(context.Background(), auction.ID, uuid.New(), &PlaceBidRequest{
-		Amount: 99.0,
-	})
-
-	if err != ErrBidTooLow {
-		t.Errorf("expected ErrBidTooLow, got %v", err)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
+	return auction
 }
 
-func TestService_PlaceBid_OwnAuction(t *testing.T) {
+func TestService_PlaceBid_Proxy(t *testing.T) {
 	repo := newMockRepository()
 	service := NewService(repo, nil)
+	ctx := context.Background()
+	auction := newTestEnglishAuction(t, service, nil)
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	ptr := func(v float64) *float64 { return &v }
 
-	sellerID := uuid.New()
+	price := func() float64 {
+		got, _ := service.GetAuction(ctx, auction.ID)
+		return *got.CurrentPrice
+	}
 
-	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
-		AuctionType:   "english",
-		Title:         "English Auction",
-		StartingPrice: 100.0,
-		EndsAt:        time.Now().Add(24 * time.Hour),
-	})
+	// A opens with a proxy at the starting price
+	bidA, err := service.PlaceBid(ctx, auction.ID, a, &PlaceBidRequest{MaxAmount: ptr(150)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bidA.Amount != 100 || bidA.Status != BidStatusActive {
+		t.Errorf("expected active bid at 100, got %v %s", bidA.Amount, bidA.Status)
+	}
 
-	_, err := service.PlaceBid(context.Background(), auction.ID, sellerID, &PlaceBidRequest{
-		Amount: 100.0,
-	})
+	// B's plain bid is covered by A's proxy, which rises one increment above it
+	bidB, err := service.PlaceBid(ctx, auction.ID, b, &PlaceBidRequest{Amount: 120})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bidB.Status != BidStatusOutbid {
+		t.Errorf("expected B to be outbid at once, got %s", bidB.Status)
+	}
+	if bidA.Amount != 125 || price() != 125 {
+		t.Errorf("expected A to lead at 125, got bid %v price %v", bidA.Amount, price())
+	}
 
-	if err != ErrCannotBidOnOwnAuction {
-		t.Errorf("expected ErrCannotBidOnOwnAuction, got %v", err)
+	// C's higher proxy leads one increment above A's ceiling
+	bidC, err := service.PlaceBid(ctx, auction.ID, c, &PlaceBidRequest{MaxAmount: ptr(200)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bidC.Amount != 155 || bidC.Status != BidStatusActive || bidA.Status != BidStatusOutbid {
+		t.Errorf("expected C to lead at 155 over A, got %v %s, A %s", bidC.Amount, bidC.Status, bidA.Status)
+	}
+
+	// An equal ceiling loses to the earlier one, which rises to the ceiling
+	bidD, err := service.PlaceBid(ctx, auction.ID, d, &PlaceBidRequest{MaxAmount: ptr(200)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bidD.Status != BidStatusOutbid || bidC.Amount != 200 || price() != 200 {
+		t.Errorf("expected C to hold at 200 over D, got D %s, C %v, price %v", bidD.Status, bidC.Amount, price())
+	}
+
+	// The leader raising their ceiling keeps the price
+	raised, err := service.PlaceBid(ctx, auction.ID, c, &PlaceBidRequest{MaxAmount: ptr(300)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raised.ID != bidC.ID || *raised.MaxAmount != 300 || price() != 200 {
+		t.Errorf("expected C's bid to keep 200 with a 300 ceiling, got %v / %v", price(), *raised.MaxAmount)
+	}
+	if _, err := service.PlaceBid(ctx, auction.ID, c, &PlaceBidRequest{MaxAmount: ptr(250)}); err != ErrBidTooLow {
+		t.Errorf("expected ErrBidTooLow lowering a ceiling, got %v", err)
+	}
+
+	// A ceiling below the next minimum is rejected
+	if _, err := service.PlaceBid(ctx, auction.ID, a, &PlaceBidRequest{MaxAmount: ptr(204)}); err != ErrBidTooLow {
+		t.Errorf("expected ErrBidTooLow, got %v", err)
+	}
+
+	// Ceilings are never exposed, not even to their bidder
+	bids, err := service.GetBids(ctx, auction.ID, &c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, bid := range bids {
+		if bid.MaxAmount != nil {
+			t.Errorf("bid %s exposes its ceiling", bid.ID)
+		}
 	}
 }
 
-func TestService_PlaceBid_NotActive(t *testing.T) {
+func TestService_PlaceBid_ProxyReserve(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	reserve := 130.0
+	auction := newTestEnglishAuction(t, service, &reserve)
+	max := 150.0
+
+	// A ceiling covering the reserve bids the reserve right away
+	bid, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{MaxAmount: &max})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bid.Amount != 130 {
+		t.Errorf("expected bid at the reserve 130, got %v", bid.Amount)
+	}
+}
+
+func TestService_PlaceBid_ProxyAntiSniping(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	auction := newTestEnglishAuction(t, service, nil)
+	max := 150.0
+
+	if _, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{MaxAmount: &max}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	endsAt := time.Now().UTC().Add(10 * time.Second)
+	repo.auctions[auction.ID].EndsAt = endsAt
+
+	// A bid the proxy beats still moves the price, so the auction is extended
+	if _, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 110}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !repo.auctions[auction.ID].EndsAt.After(endsAt) {
+		t.Error("expected the auction to be extended")
+	}
+}
+
+func TestService_PlaceBid_ResolvedUnderLock(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	auction := newTestEnglishAuction(t, service, nil)
+
+	// The seller cancels between the bid's checks and the lock
+	repo.onLock = func() { repo.auctions[auction.ID].Status = AuctionStatusCancelled }
+	if _, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 110}); err != ErrAuctionNotActive {
+		t.Errorf("expected ErrAuctionNotActive, got %v", err)
+	}
+	if len(repo.bids[auction.ID]) != 0 {
+		t.Errorf("expected no bid placed, got %d", len(repo.bids[auction.ID]))
+	}
+}
+
+func TestService_PlaceBid_ProxyNotSupported(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	max := 150.0
+
+	auction, _ := service.CreateAuction(context.Background(), uuid.New(), &CreateAuctionRequest{
+		AuctionType:   "sealed",
+		Title:         "Sealed Auction",
+		StartingPrice: 100.0,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	})
+
+	_, err := service.PlaceBid(context.Background(), auction.ID, uuid.New(), &PlaceBidRequest{MaxAmount: &max})
+	if err != ErrProxyNotSupported {
+		t.Errorf("expected ErrProxyNotSupported, got %v", err)
+	}
+}
+
+func TestService_PlaceBid_TooLow(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+
+	sellerID := uuid.New()
+	bidderID := uuid.New()
+
+	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
+		AuctionType:   "english",
+		Title:         "English Auction",
+		StartingPrice: 100.0,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	})
+
+	// Place first bid
+	service.PlaceBid(context.Background(), auction.ID, bidderID, &PlaceBidRequest{
+		Amount: 100.0,
+	})
+
+	// Try to place lower bid
+	_, err := service.PlaceBid(context.Background(), auction.ID, uuid.New(), &PlaceBidRequest{
+		Amount: 99.0,
+	})
+
+	if err != ErrBidTooLow {
+		t.Errorf("expected ErrBidTooLow, got %v", err)
+	}
+}
+
+func TestService_PlaceBid_OwnAuction(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+
+	sellerID := uuid.New()
+
+	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
+		AuctionType:   "english",
+		Title:         "English Auction",
+		StartingPrice: 100.0,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	})
+
+	_, err := service.PlaceBid(context.Background(), auction.ID, sellerID, &PlaceBidRequest{
+		Amount: 100.0,
+	})
+
+	if err != ErrCannotBidOnOwnAuction {
+		t.Errorf("expected ErrCannotBidOnOwnAuction, got %v", err)
+	}
+}
+
+func TestService_PlaceBid_NotActive(t *testing.T) {
 	repo := newMockRepository()
 	service := NewService(repo, nil)
 
@@ -1005,148 +1558,693 @@ func TestService_PlaceBid_Dutch(t *testing.T) {
 	}
 }
 
-func TestService_PlaceBid_Sealed(t *testing.T) {
+func newTestDutchAuction(reserve *float64) *Auction {
+	decrement := 10.0
+	interval := 60
+	return &Auction{
+		ID:                    uuid.New(),
+		SellerID:              uuid.New(),
+		AuctionType:           AuctionTypeDutch,
+		Title:                 "Dutch Auction",
+		StartingPrice:         100.0,
+		ReservePrice:          reserve,
+		PriceDecrement:        &decrement,
+		DecrementIntervalSecs: &interval,
+		Currency:              "USD",
+		Status:                AuctionStatusActive,
+		StartsAt:              time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		EndsAt:                time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestAuction_DutchPriceAt(t *testing.T) {
+	reserve := 75.0
+	withReserve := newTestDutchAuction(&reserve)
+	noReserve := newTestDutchAuction(nil)
+	start := withReserve.StartsAt
+
+	tests := []struct {
+		name      string
+		auction   *Auction
+		at        time.Duration
+		wantPrice float64
+		wantNext  time.Duration // 0 for no further drop
+	}{
+		{"before start", withReserve, -time.Minute, 100, time.Minute},
+		{"at start", withReserve, 0, 100, time.Minute},
+		{"within first interval", withReserve, 59 * time.Second, 100, time.Minute},
+		{"one step", withReserve, time.Minute, 90, 2 * time.Minute},
+		{"two steps", withReserve, 150 * time.Second, 80, 3 * time.Minute},
+		{"stops at reserve", withReserve, 3 * time.Minute, 75, 0},
+		{"stays at reserve", withReserve, time.Hour, 75, 0},
+		{"no reserve stops at last positive step", noReserve, time.Hour, 10, 0},
+		{"no reserve before floor", noReserve, 8 * time.Minute, 20, 9 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, next := tt.auction.DutchPriceAt(start.Add(tt.at))
+			if price != tt.wantPrice {
+				t.Errorf("expected price %v, got %v", tt.wantPrice, price)
+			}
+			if tt.wantNext == 0 {
+				if next != nil {
+					t.Errorf("expected no next drop, got %v", *next)
+				}
+				return
+			}
+			if next == nil || !next.Equal(start.Add(tt.wantNext)) {
+				t.Errorf("expected next drop at %v, got %v", start.Add(tt.wantNext), next)
+			}
+		})
+	}
+}
+
+func TestAuction_DutchPriceAt_NoSchedule(t *testing.T) {
+	auction := newTestDutchAuction(nil)
+	auction.PriceDecrement = nil
+
+	price, next := auction.DutchPriceAt(auction.StartsAt.Add(time.Hour))
+	if price != 100 || next != nil {
+		t.Errorf("expected fixed price 100 with no next drop, got %v, %v", price, next)
+	}
+}
+
+func TestService_TickDutchAuctions(t *testing.T) {
 	repo := newMockRepository()
 	service := NewService(repo, nil)
+	ctx := context.Background()
 
-	sellerID := uuid.New()
-
-	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
-		AuctionType:   "sealed",
-		Title:         "Sealed Auction",
-		StartingPrice: 100.0,
-		EndsAt:        time.Now().Add(24 * time.Hour),
-	})
+	reserve := 75.0
+	due := newTestDutchAuction(&reserve)
+	notDue := newTestDutchAuction(nil)
+	notDue.StartsAt = due.StartsAt.Add(time.Hour)
+	ended := newTestDutchAuction(nil)
+	ended.Status = AuctionStatusEnded
+	for _, a := range []*Auction{due, notDue, ended} {
+		repo.auctions[a.ID] = a
+	}
 
-	bid, err := service.PlaceBid(context.Background(), auction.ID, uuid.New(), &PlaceBidRequest{
-		Amount: 200.0,
-	})
+	now := due.StartsAt.Add(2 * time.Minute)
+	changed, err := service.TickDutchAuctions(ctx, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed != 1 {
+		t.Errorf("expected 1 auction to change, got %d", changed)
+	}
+	if due.CurrentPrice == nil || *due.CurrentPrice != 80 {
+		t.Errorf("expected price 80, got %v", due.CurrentPrice)
+	}
+	if notDue.CurrentPrice != nil || ended.CurrentPrice != nil {
+		t.Error("only the due auction should change")
+	}
 
+	// Nothing more is due at the same time
+	changed, err = service.TickDutchAuctions(ctx, now)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if !bid.IsSealed {
-		t.Error("bid should be sealed")
+	if changed != 0 {
+		t.Errorf("expected no changes on a second tick, got %d", changed)
+	}
+
+	// Never below the reserve
+	if _, err := service.TickDutchAuctions(ctx, now.Add(time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *due.CurrentPrice != 75 {
+		t.Errorf("expected price to stop at the reserve, got %v", *due.CurrentPrice)
 	}
 }
 
-func TestService_GetBids(t *testing.T) {
+func TestService_PlaceBid_DutchComputedPrice(t *testing.T) {
 	repo := newMockRepository()
 	service := NewService(repo, nil)
+	ctx := context.Background()
 
-	sellerID := uuid.New()
+	// Started 2.5 intervals ago and never ticked: the price is 80, not 100
+	auction := newTestDutchAuction(nil)
+	auction.StartsAt = time.Now().UTC().Add(-150 * time.Second)
+	auction.EndsAt = time.Now().UTC().Add(time.Hour)
+	repo.auctions[auction.ID] = auction
 
-	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
-		AuctionType:   "english",
-		Title:         "English Auction",
-		StartingPrice: 100.0,
-		EndsAt:        time.Now().Add(24 * time.Hour),
-	})
+	got, err := service.GetAuction(ctx, auction.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.CurrentPrice == nil || *got.CurrentPrice != 80 {
+		t.Errorf("expected current price 80, got %v", got.CurrentPrice)
+	}
+	if got.NextPriceAt == nil {
+		t.Error("expected next price time to be set")
+	}
 
-	// Place some bids
-	for i := 0; i < 3; i++ {
-		service.PlaceBid(context.Background(), auction.ID, uuid.New(), &PlaceBidRequest{
-			Amount: float64(100 + i*10),
-		})
+	_, err = service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 79})
+	if err != ErrBidTooLow {
+		t.Errorf("expected ErrBidTooLow, got %v", err)
 	}
 
-	bids, err := service.GetBids(context.Background(), auction.ID, nil)
+	bid, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 80})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if len(bids) != 3 {
-		t.Errorf("expected 3 bids, got %d", len(bids))
+	if bid.Status != BidStatusWon {
+		t.Errorf("expected bid status won, got %s", bid.Status)
+	}
+	if *auction.CurrentPrice != 80 {
+		t.Errorf("expected sale price 80, got %v", *auction.CurrentPrice)
 	}
 }
 
-func TestService_GetBids_SealedHidden(t *testing.T) {
+func TestService_PlaceBid_DutchOverbidPaysClockPrice(t *testing.T) {
 	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
 	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
 
-	sellerID := uuid.New()
-	bidderID := uuid.New()
+	auction := newTestDutchAuction(nil)
+	auction.StartsAt = time.Now().UTC().Add(-150 * time.Second)
+	auction.EndsAt = time.Now().UTC().Add(time.Hour)
+	repo.auctions[auction.ID] = auction
 
-	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
-		AuctionType:   "sealed",
-		Title:         "Sealed Auction",
-		StartingPrice: 100.0,
-		EndsAt:        time.Now().Add(24 * time.Hour),
-	})
+	if _, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 95}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settlement := repo.settlements[auction.ID][0]
+	if settlement.Amount != 80 || txCreator.amounts[*settlement.TransactionID] != 80 {
+		t.Errorf("expected the sale at the clock price 80, got %v", settlement.Amount)
+	}
+	if *auction.CurrentPrice != 80 {
+		t.Errorf("expected sale price 80, got %v", *auction.CurrentPrice)
+	}
+}
 
-	// Place a bid
-	service.PlaceBid(context.Background(), auction.ID, bidderID, &PlaceBidRequest{
-		Amount: 200.0,
-	})
+func TestService_CreateAuction_InvalidDutchSchedule(t *testing.T) {
+	service := NewService(newMockRepository(), nil)
 
-	// Get bids as non-bidder - amounts should be hidden
-	bids, _ := service.GetBids(context.Background(), auction.ID, nil)
-	if bids[0].Amount != 0 {
-		t.Error("bid amount should be hidden for non-bidder")
+	decrement := 10.0
+	interval := 0
+	_, err := service.CreateAuction(context.Background(), uuid.New(), &CreateAuctionRequest{
+		AuctionType:           "dutch",
+		Title:                 "Dutch Auction",
+		StartingPrice:         100.0,
+		PriceDecrement:        &decrement,
+		DecrementIntervalSecs: &interval,
+		EndsAt:                time.Now().Add(24 * time.Hour),
+	})
+	if err == nil {
+		t.Error("expected error for a zero decrement interval")
 	}
 
-	// Get bids as bidder - amounts should be visible
-	bids, _ = service.GetBids(context.Background(), auction.ID, &bidderID)
-	if bids[0].Amount != 200.0 {
-		t.Error("bid amount should be visible for bidder")
+	_, err = service.CreateAuction(context.Background(), uuid.New(), &CreateAuctionRequest{
+		AuctionType:    "dutch",
+		Title:          "Dutch Auction",
+		StartingPrice:  100.0,
+		PriceDecrement: &decrement,
+		EndsAt:         time.Now().Add(24 * time.Hour),
+	})
+	if err == nil {
+		t.Error("expected error for a decrement without an interval")
 	}
 }
 
-func TestService_EndAuction(t *testing.T) {
+func TestService_PlaceBid_Sealed(t *testing.T) {
 	repo := newMockRepository()
-	publisher := &mockPublisher{}
-	service := NewService(repo, publisher)
+	service := NewService(repo, nil)
 
 	sellerID := uuid.New()
-	bidderID := uuid.New()
 
 	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
-		AuctionType:   "english",
-		Title:         "English Auction",
+		AuctionType:   "sealed",
+		Title:         "Sealed Auction",
 		StartingPrice: 100.0,
 		EndsAt:        time.Now().Add(24 * time.Hour),
 	})
 
-	// Place a bid
-	service.PlaceBid(context.Background(), auction.ID, bidderID, &PlaceBidRequest{
-		Amount: 150.0,
+	bid, err := service.PlaceBid(context.Background(), auction.ID, uuid.New(), &PlaceBidRequest{
+		Amount: 200.0,
 	})
 
-	// End auction
-	ended, err := service.EndAuction(context.Background(), auction.ID, sellerID)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if ended.Status != AuctionStatusEnded {
-		t.Errorf("expected status ended, got %s", ended.Status)
-	}
-	if ended.WinnerID == nil || *ended.WinnerID != bidderID {
-		t.Error("winner should be set")
+	if !bid.IsSealed {
+		t.Error("bid should be sealed")
 	}
 }
 
-func TestService_EndAuction_NotAuthorized(t *testing.T) {
-	repo := newMockRepository()
-	service := NewService(repo, nil)
-
-	sellerID := uuid.New()
+func TestService_Vickrey(t *testing.T) {
+	reserve := 130.0
+	tests := []struct {
+		name      string
+		reserve   *float64
+		bids      []float64
+		wantPrice float64
+		wantWin   int // index of the winning bid, -1 for none
+	}{
+		{"pays the second price", nil, []float64{150, 200, 120}, 150, 1},
+		{"single bid pays the starting price", nil, []float64{180}, 100, 0},
+		{"reserve floors the price", &reserve, []float64{200, 110}, 130, 0},
+		{"reserve not met", &reserve, []float64{120, 110}, 0, -1},
+		{"earlier of equal bids wins", nil, []float64{170, 170}, 170, 0},
+	}
 
-	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
-		AuctionType:   "english",
-		Title:         "English Auction",
-		StartingPrice: 100.0,
-		EndsAt:        time.Now().Add(24 * time.Hour),
-	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepository()
+			service := NewService(repo, nil)
+			ctx := context.Background()
+			sellerID := uuid.New()
+
+			auction, err := service.CreateAuction(ctx, sellerID, &CreateAuctionRequest{
+				AuctionType:   "vickrey",
+				Title:         "Vickrey Auction",
+				StartingPrice: 100.0,
+				ReservePrice:  tt.reserve,
+				EndsAt:        time.Now().Add(24 * time.Hour),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
 
-	_, err := service.EndAuction(context.Background(), auction.ID, uuid.New()) // Different user
-	if err != ErrNotAuthorized {
-		t.Errorf("expected ErrNotAuthorized, got %v", err)
-	}
-}
+			var bidders []uuid.UUID
+			for i, amount := range tt.bids {
+				bidder := uuid.New()
+				bidders = append(bidders, bidder)
+				if _, err := service.PlaceBid(ctx, auction.ID, bidder, &PlaceBidRequest{Amount: amount}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				repo.bids[auction.ID][i].CreatedAt = time.Now().Add(time.Duration(i) * time.Second)
+			}
 
-func TestService_EndAuction_NoBids(t *testing.T) {
+			ended, err := service.EndAuction(ctx, auction.ID, sellerID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ended.Status != AuctionStatusEnded {
+				t.Errorf("expected ended, got %s", ended.Status)
+			}
+			if tt.wantWin < 0 {
+				if ended.WinnerID != nil {
+					t.Error("expected no winner")
+				}
+				return
+			}
+			if ended.WinnerID == nil || *ended.WinnerID != bidders[tt.wantWin] {
+				t.Fatalf("expected bidder %d to win", tt.wantWin)
+			}
+			if *ended.CurrentPrice != tt.wantPrice {
+				t.Errorf("expected price %v, got %v", tt.wantPrice, *ended.CurrentPrice)
+			}
+		})
+	}
+}
+
+func TestService_Vickrey_BelowStartingPrice(t *testing.T) {
+	service := NewService(newMockRepository(), nil)
+	auction, _ := service.CreateAuction(context.Background(), uuid.New(), &CreateAuctionRequest{
+		AuctionType:   "vickrey",
+		Title:         "Vickrey Auction",
+		StartingPrice: 100.0,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	})
+
+	_, err := service.PlaceBid(context.Background(), auction.ID, uuid.New(), &PlaceBidRequest{Amount: 99})
+	if err != ErrBidTooLow {
+		t.Errorf("expected ErrBidTooLow, got %v", err)
+	}
+}
+
+func TestService_CommitReveal(t *testing.T) {
 	repo := newMockRepository()
-	publisher := &mockPublisher{}
-	service := NewService(repo, publisher)
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	sellerID := uuid.New()
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+
+	endsAt := time.Now().UTC().Add(time.Hour)
+	window := 600
+	auction, err := service.CreateAuction(ctx, sellerID, &CreateAuctionRequest{
+		AuctionType:      "vickrey",
+		Title:            "Commit-Reveal Auction",
+		StartingPrice:    100.0,
+		EndsAt:           endsAt,
+		CommitReveal:     true,
+		RevealWindowSecs: &window,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if auction.RevealEndsAt == nil || !auction.RevealEndsAt.Equal(endsAt.Add(10*time.Minute)) {
+		t.Errorf("expected reveal window to end 10 minutes after the auction, got %v", auction.RevealEndsAt)
+	}
+
+	commit := func(bidder uuid.UUID, amount float64, nonce string) {
+		t.Helper()
+		commitment := CommitmentFor(amount, nonce)
+		bid, err := service.PlaceBid(ctx, auction.ID, bidder, &PlaceBidRequest{Commitment: &commitment})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if bid.Amount != 0 || bid.Status != BidStatusCommitted {
+			t.Errorf("expected a committed bid without amount, got %v %s", bid.Amount, bid.Status)
+		}
+	}
+	commit(alice, 200, "alice-nonce")
+	commit(bob, 150.5, "bob-nonce")
+	commit(carol, 300, "carol-nonce")
+
+	// Amounts are never sent while bidding
+	if _, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 500}); err != ErrCommitmentRequired {
+		t.Errorf("expected ErrCommitmentRequired, got %v", err)
+	}
+	again := CommitmentFor(250, "again")
+	if _, err := service.PlaceBid(ctx, auction.ID, alice, &PlaceBidRequest{Commitment: &again}); err != ErrAlreadyCommitted {
+		t.Errorf("expected ErrAlreadyCommitted, got %v", err)
+	}
+	bad := "not-a-hash"
+	if _, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Commitment: &bad}); err != ErrInvalidCommitment {
+		t.Errorf("expected ErrInvalidCommitment, got %v", err)
+	}
+
+	// No reveals while bidding is open
+	if _, err := service.RevealBid(ctx, auction.ID, alice, &RevealBidRequest{Amount: 200, Nonce: "alice-nonce"}); err != ErrRevealNotOpen {
+		t.Errorf("expected ErrRevealNotOpen, got %v", err)
+	}
+
+	// Closing opens the reveal window
+	if _, err := service.ProcessSealedAuctions(ctx, endsAt.Add(time.Second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.auctions[auction.ID].Status != AuctionStatusRevealing {
+		t.Fatalf("expected revealing, got %s", repo.auctions[auction.ID].Status)
+	}
+
+	if _, err := service.RevealBid(ctx, auction.ID, alice, &RevealBidRequest{Amount: 250, Nonce: "alice-nonce"}); err != ErrCommitmentMismatch {
+		t.Errorf("expected ErrCommitmentMismatch, got %v", err)
+	}
+	for bidder, reveal := range map[uuid.UUID]RevealBidRequest{
+		alice: {Amount: 200, Nonce: "alice-nonce"},
+		bob:   {Amount: 150.5, Nonce: "bob-nonce"},
+	} {
+		bid, err := service.RevealBid(ctx, auction.ID, bidder, &reveal)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if bid.Amount != reveal.Amount || bid.Status != BidStatusActive {
+			t.Errorf("expected revealed bid of %v, got %v %s", reveal.Amount, bid.Amount, bid.Status)
+		}
+	}
+
+	// Revealed amounts stay hidden from others until the auction settles
+	bids, _ := service.GetBids(ctx, auction.ID, &bob)
+	for _, bid := range bids {
+		if bid.BidderID != bob && (bid.Amount != 0 || bid.Commitment != nil) {
+			t.Error("other bidders' amounts and commitments should be hidden")
+		}
+	}
+
+	// Settles once the reveal window has passed; Carol never revealed
+	if _, err := service.ProcessSealedAuctions(ctx, endsAt.Add(11*time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settled := repo.auctions[auction.ID]
+	if settled.Status != AuctionStatusEnded || settled.WinnerID == nil || *settled.WinnerID != alice {
+		t.Fatalf("expected Alice to win, got %s %v", settled.Status, settled.WinnerID)
+	}
+	if *settled.CurrentPrice != 150.5 {
+		t.Errorf("expected Alice to pay Bob's 150.5, got %v", *settled.CurrentPrice)
+	}
+	for _, bid := range repo.bids[auction.ID] {
+		if bid.BidderID == carol && bid.Status != BidStatusForfeited {
+			t.Errorf("expected Carol's unrevealed bid to be forfeited, got %s", bid.Status)
+		}
+	}
+}
+
+func TestService_RevealBid_MultiUnitSpendingLimit(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	service.SetSpendingChecker(&mockSpendingChecker{limit: 100})
+	ctx := context.Background()
+	bidderID := uuid.New()
+
+	endsAt := time.Now().UTC().Add(time.Hour)
+	auction, err := service.CreateAuction(ctx, uuid.New(), &CreateAuctionRequest{
+		AuctionType:   "sealed",
+		Title:         "Data Licenses",
+		StartingPrice: 5.0,
+		Quantity:      5,
+		EndsAt:        endsAt,
+		CommitReveal:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	commitment := CommitmentFor(40, "nonce")
+	if _, err := service.PlaceBid(ctx, auction.ID, bidderID, &PlaceBidRequest{Commitment: &commitment, Quantity: 3}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.ProcessSealedAuctions(ctx, endsAt.Add(time.Second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Three units at 40 each is 120, over the bidder's limit of 100
+	if _, err := service.RevealBid(ctx, auction.ID, bidderID, &RevealBidRequest{Amount: 40, Nonce: "nonce"}); err == nil {
+		t.Error("expected the reveal to exceed the spending limit")
+	}
+}
+
+func TestService_ProcessSealedAuctions_AllPagesPastFailures(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+
+	endsAt := time.Now().UTC().Add(time.Hour)
+	var broken uuid.UUID
+	for i := 0; i < 150; i++ {
+		auction, err := service.CreateAuction(ctx, uuid.New(), &CreateAuctionRequest{
+			AuctionType:   "vickrey",
+			Title:         "Vickrey Auction",
+			StartingPrice: 10.0,
+			EndsAt:        endsAt,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		broken = auction.ID
+	}
+	repo.bidsErr = map[uuid.UUID]error{broken: errors.New("connection reset")}
+
+	processed, err := service.ProcessSealedAuctions(ctx, endsAt.Add(time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if processed != 149 {
+		t.Errorf("expected 149 auctions settled, got %d", processed)
+	}
+	if repo.auctions[broken].Status != AuctionStatusActive {
+		t.Errorf("expected the broken auction left to retry, got %s", repo.auctions[broken].Status)
+	}
+}
+
+func TestService_CreateAuction_CommitRevealNotSealed(t *testing.T) {
+	service := NewService(newMockRepository(), nil)
+
+	_, err := service.CreateAuction(context.Background(), uuid.New(), &CreateAuctionRequest{
+		AuctionType:   "english",
+		Title:         "English Auction",
+		StartingPrice: 100.0,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+		CommitReveal:  true,
+	})
+	if err == nil {
+		t.Error("expected error for commit-reveal on an english auction")
+	}
+}
+
+func newTestBuyNowAuction(t *testing.T, service *Service, untilFirstBid bool) *Auction {
+	t.Helper()
+	buyNow := 500.0
+	auction, err := service.CreateAuction(context.Background(), uuid.New(), &CreateAuctionRequest{
+		AuctionType:         "english",
+		Title:               "Buy-Now Auction",
+		StartingPrice:       100.0,
+		BuyNowPrice:         &buyNow,
+		BuyNowUntilFirstBid: untilFirstBid,
+		EndsAt:              time.Now().Add(24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return auction
+}
+
+func TestService_BuyNow(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	auction := newTestBuyNowAuction(t, service, false)
+	bidderID, buyerID := uuid.New(), uuid.New()
+
+	if _, err := service.PlaceBid(ctx, auction.ID, bidderID, &PlaceBidRequest{Amount: 150}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bid, err := service.BuyNow(ctx, auction.ID, buyerID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bid.Amount != 500 || bid.Status != BidStatusWon {
+		t.Errorf("expected won bid at 500, got %v %s", bid.Amount, bid.Status)
+	}
+
+	ended := repo.auctions[auction.ID]
+	if ended.Status != AuctionStatusEnded || ended.WinnerID == nil || *ended.WinnerID != buyerID {
+		t.Errorf("expected auction won by the buyer, got %s %v", ended.Status, ended.WinnerID)
+	}
+	if *ended.CurrentPrice != 500 {
+		t.Errorf("expected price 500, got %v", *ended.CurrentPrice)
+	}
+	for _, b := range repo.bids[auction.ID] {
+		if b.BidderID == bidderID && b.Status != BidStatusOutbid {
+			t.Errorf("expected the other bid to be outbid, got %s", b.Status)
+		}
+	}
+
+	if _, err := service.BuyNow(ctx, auction.ID, uuid.New()); err != ErrAuctionNotActive {
+		t.Errorf("expected ErrAuctionNotActive, got %v", err)
+	}
+}
+
+func TestService_PlaceBid_AtBuyNowPrice(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	auction := newTestBuyNowAuction(t, service, false)
+	max := 600.0
+
+	// A ceiling above the buy-now price buys at the buy-now price
+	bid, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{MaxAmount: &max})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bid.Amount != 500 || bid.Status != BidStatusWon {
+		t.Errorf("expected won bid at 500, got %v %s", bid.Amount, bid.Status)
+	}
+	if repo.auctions[auction.ID].Status != AuctionStatusEnded {
+		t.Error("expected the auction to end")
+	}
+}
+
+func TestService_BuyNow_UntilFirstBid(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	auction := newTestBuyNowAuction(t, service, true)
+
+	if _, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 150}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.BuyNow(ctx, auction.ID, uuid.New()); err != ErrBuyNowNotAvailable {
+		t.Errorf("expected ErrBuyNowNotAvailable, got %v", err)
+	}
+
+	// Bids above the withdrawn buy-now price are ordinary bids
+	bid, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 550})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bid.Status != BidStatusActive || repo.auctions[auction.ID].Status != AuctionStatusActive {
+		t.Errorf("expected an active bid on an active auction, got %s", bid.Status)
+	}
+}
+
+func TestService_BuyNow_Once(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
+	auction := newTestBuyNowAuction(t, service, false)
+
+	// Another buyer takes the auction between this buyer's checks and the lock
+	first := uuid.New()
+	repo.onLock = func() {
+		repo.onLock = nil
+		if _, err := service.BuyNow(ctx, auction.ID, first); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := service.BuyNow(ctx, auction.ID, uuid.New()); err != ErrAuctionNotActive {
+		t.Errorf("expected ErrAuctionNotActive, got %v", err)
+	}
+
+	if winner := repo.auctions[auction.ID].WinnerID; winner == nil || *winner != first {
+		t.Errorf("expected the first buyer to win, got %v", winner)
+	}
+	if len(repo.bids[auction.ID]) != 1 || len(repo.settlements[auction.ID]) != 1 || len(txCreator.amounts) != 1 {
+		t.Errorf("expected one bid and one settlement, got %d and %d", len(repo.bids[auction.ID]), len(repo.settlements[auction.ID]))
+	}
+}
+
+func TestService_PlaceBid_BuyNowWithdrawnMeanwhile(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	auction := newTestBuyNowAuction(t, service, true)
+
+	// A first bid lands between this bid's checks and the lock, withdrawing buy-now
+	repo.onLock = func() {
+		repo.onLock = nil
+		if _, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 150}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	bid, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 550})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bid.Status != BidStatusActive || repo.auctions[auction.ID].Status != AuctionStatusActive {
+		t.Errorf("expected an ordinary bid on an active auction, got %s", bid.Status)
+	}
+}
+
+func TestService_CreateAuction_InvalidBuyNow(t *testing.T) {
+	service := NewService(newMockRepository(), nil)
+	buyNow := 500.0
+	low := 50.0
+
+	tests := []struct {
+		name        string
+		auctionType string
+		buyNow      *float64
+	}{
+		{"dutch", "dutch", &buyNow},
+		{"continuous", "continuous", &buyNow},
+		{"below starting price", "english", &low},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateAuction(context.Background(), uuid.New(), &CreateAuctionRequest{
+				AuctionType:   tt.auctionType,
+				Title:         "Auction",
+				StartingPrice: 100.0,
+				BuyNowPrice:   tt.buyNow,
+				EndsAt:        time.Now().Add(24 * time.Hour),
+			})
+			if err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestService_GetBids(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
 
 	sellerID := uuid.New()
 
@@ -1157,46 +2255,160 @@ func TestService_EndAuction_NoBids(t *testing.T) {
 		EndsAt:        time.Now().Add(24 * time.Hour),
 	})
 
-	// End with no bids
-	ended, err := service.EndAuction(context.Background(), auction.ID, sellerID)
+	// Place some bids
+	for i := 0; i < 3; i++ {
+		service.PlaceBid(context.Background(), auction.ID, uuid.New(), &PlaceBidRequest{
+			Amount: float64(100 + i*10),
+		})
+	}
+
+	bids, err := service.GetBids(context.Background(), auction.ID, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if ended.Status != AuctionStatusEnded {
-		t.Errorf("expected status ended, got %s", ended.Status)
+	if len(bids) != 3 {
+		t.Errorf("expected 3 bids, got %d", len(bids))
 	}
-	if ended.WinnerID != nil {
-		t.Error("winner should be nil with no bids")
+}
+
+func TestService_GetBids_SealedHidden(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+
+	sellerID := uuid.New()
+	bidderID := uuid.New()
+
+	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
+		AuctionType:   "sealed",
+		Title:         "Sealed Auction",
+		StartingPrice: 100.0,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	})
+
+	// Place a bid
+	service.PlaceBid(context.Background(), auction.ID, bidderID, &PlaceBidRequest{
+		Amount: 200.0,
+	})
+
+	// Get bids as non-bidder - amounts should be hidden
+	bids, _ := service.GetBids(context.Background(), auction.ID, nil)
+	if bids[0].Amount != 0 {
+		t.Error("bid amount should be hidden for non-bidder")
+	}
+
+	// Get bids as bidder - amounts should be visible
+	bids, _ = service.GetBids(context.Background(), auction.ID, &bidderID)
+	if bids[0].Amount != 200.0 {
+		t.Error("bid amount should be visible for bidder")
 	}
 }
 
-func TestService_EndAuction_ReserveNotMet(t *testing.T) {
+func TestService_EndAuction(t *testing.T) {
 	repo := newMockRepository()
 	publisher := &mockPublisher{}
 	service := NewService(repo, publisher)
 
 	sellerID := uuid.New()
-	reservePrice := 200.0
+	bidderID := uuid.New()
 
 	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
 		AuctionType:   "english",
 		Title:         "English Auction",
 		StartingPrice: 100.0,
-		ReservePrice:  &reservePrice,
 		EndsAt:        time.Now().Add(24 * time.Hour),
 	})
 
-	// Place bid below reserve
-	service.PlaceBid(context.Background(), auction.ID, uuid.New(), &PlaceBidRequest{
+	// Place a bid
+	service.PlaceBid(context.Background(), auction.ID, bidderID, &PlaceBidRequest{
 		Amount: 150.0,
 	})
 
-	// End auction - reserve not met
+	// End auction
 	ended, err := service.EndAuction(context.Background(), auction.ID, sellerID)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if ended.WinnerID != nil {
+	if ended.Status != AuctionStatusEnded {
+		t.Errorf("expected status ended, got %s", ended.Status)
+	}
+	if ended.WinnerID == nil || *ended.WinnerID != bidderID {
+		t.Error("winner should be set")
+	}
+}
+
+func TestService_EndAuction_NotAuthorized(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+
+	sellerID := uuid.New()
+
+	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
+		AuctionType:   "english",
+		Title:         "English Auction",
+		StartingPrice: 100.0,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	})
+
+	_, err := service.EndAuction(context.Background(), auction.ID, uuid.New()) // Different user
+	if err != ErrNotAuthorized {
+		t.Errorf("expected ErrNotAuthorized, got %v", err)
+	}
+}
+
+func TestService_EndAuction_NoBids(t *testing.T) {
+	repo := newMockRepository()
+	publisher := &mockPublisher{}
+	service := NewService(repo, publisher)
+
+	sellerID := uuid.New()
+
+	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
+		AuctionType:   "english",
+		Title:         "English Auction",
+		StartingPrice: 100.0,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	})
+
+	// End with no bids
+	ended, err := service.EndAuction(context.Background(), auction.ID, sellerID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ended.Status != AuctionStatusEnded {
+		t.Errorf("expected status ended, got %s", ended.Status)
+	}
+	if ended.WinnerID != nil {
+		t.Error("winner should be nil with no bids")
+	}
+}
+
+func TestService_EndAuction_ReserveNotMet(t *testing.T) {
+	repo := newMockRepository()
+	publisher := &mockPublisher{}
+	service := NewService(repo, publisher)
+
+	sellerID := uuid.New()
+	reservePrice := 200.0
+
+	auction, _ := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
+		AuctionType:   "english",
+		Title:         "English Auction",
+		StartingPrice: 100.0,
+		ReservePrice:  &reservePrice,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	})
+
+	// Place bid below reserve
+	service.PlaceBid(context.Background(), auction.ID, uuid.New(), &PlaceBidRequest{
+		Amount: 150.0,
+	})
+
+	// End auction - reserve not met
+	ended, err := service.EndAuction(context.Background(), auction.ID, sellerID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ended.WinnerID != nil {
 		t.Error("winner should be nil when reserve not met")
 	}
 }
@@ -1209,3 +2421,1044 @@ func TestRepositoryErrors(t *testing.T) {
 		t.Errorf("unexpected error message: %s", ErrBidNotFound.Error())
 	}
 }
+
+// newTestSettledAuction returns an English auction ended by its seller with bids
+// of 150, 200 and 250 from three bidders, returned in that order.
+func newTestSettledAuction(t *testing.T, service *Service) (*Auction, []uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	auction := newTestEnglishAuction(t, service, nil)
+
+	bidders := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for i, amount := range []float64{150, 200, 250} {
+		if _, err := service.PlaceBid(ctx, auction.ID, bidders[i], &PlaceBidRequest{Amount: amount}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := service.EndAuction(ctx, auction.ID, auction.SellerID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return auction, bidders
+}
+
+func TestService_EndAuction_Settlement(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	service.SetSettlementWindows(time.Hour, 0)
+
+	auction, bidders := newTestSettledAuction(t, service)
+
+	settlements := repo.settlements[auction.ID]
+	if len(settlements) != 1 {
+		t.Fatalf("expected 1 settlement, got %d", len(settlements))
+	}
+	settlement := settlements[0]
+	if settlement.BidderID != bidders[2] || settlement.Amount != 250 || settlement.Status != SettlementStatusPending {
+		t.Errorf("expected pending settlement at 250 for the winner, got %+v", settlement)
+	}
+	if settlement.TransactionID == nil || txCreator.amounts[*settlement.TransactionID] != 250 {
+		t.Errorf("expected a transaction at 250, got %v", settlement.TransactionID)
+	}
+	if until := time.Until(settlement.DueAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("expected payment due in an hour, got %v", until)
+	}
+}
+
+func TestService_EndAuction_SettlementRetried(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	txCreator.createErr = errors.New("transaction service unavailable")
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
+
+	auction, bidders := newTestSettledAuction(t, service)
+
+	settlement := repo.settlements[auction.ID][0]
+	if settlement.Status != SettlementStatusPendingCreation || settlement.TransactionID != nil {
+		t.Fatalf("expected a settlement pending creation, got %+v", settlement)
+	}
+
+	txCreator.createErr = nil
+	if n, err := service.ProcessSettlements(ctx, time.Now().UTC()); err != nil || n != 0 {
+		t.Fatalf("expected no retry before the delay, got %d, %v", n, err)
+	}
+	processed, err := service.ProcessSettlements(ctx, settlement.DueAt.Add(time.Minute))
+	if err != nil || processed != 1 {
+		t.Fatalf("expected 1 settlement processed, got %d, %v", processed, err)
+	}
+	if settlement.Status != SettlementStatusPending || settlement.TransactionID == nil || txCreator.buyers[*settlement.TransactionID] != bidders[2] {
+		t.Errorf("expected a pending transaction for the winner, got %+v", settlement)
+	}
+}
+
+func TestService_ProcessSettlements_SecondChance(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
+
+	auction, bidders := newTestSettledAuction(t, service)
+	winner := repo.settlements[auction.ID][0]
+
+	// Nothing is due before the deadline
+	if n, err := service.ProcessSettlements(ctx, time.Now().UTC()); err != nil || n != 0 {
+		t.Fatalf("expected nothing processed, got %d, %v", n, err)
+	}
+
+	processed, err := service.ProcessSettlements(ctx, winner.DueAt.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if processed != 1 || len(txCreator.cancelled) != 1 || txCreator.cancelled[0] != *winner.TransactionID {
+		t.Fatalf("expected the winner's transaction cancelled, got %d processed, %v", processed, txCreator.cancelled)
+	}
+	if winner.Status != SettlementStatusDefaulted {
+		t.Errorf("expected defaulted settlement, got %s", winner.Status)
+	}
+	for _, b := range repo.bids[auction.ID] {
+		if b.BidderID == bidders[2] && b.Status != BidStatusForfeited {
+			t.Errorf("expected the winning bid forfeited, got %s", b.Status)
+		}
+	}
+
+	offer := repo.settlements[auction.ID][1]
+	if offer.BidderID != bidders[1] || offer.Amount != 200 || !offer.SecondChance || offer.Status != SettlementStatusOffered {
+		t.Fatalf("expected a second-chance offer at 200 to the runner-up, got %+v", offer)
+	}
+
+	if _, err := service.AcceptSecondChance(ctx, auction.ID, bidders[0]); err != ErrNoSecondChanceOffer {
+		t.Errorf("expected ErrNoSecondChanceOffer, got %v", err)
+	}
+
+	accepted, err := service.AcceptSecondChance(ctx, auction.ID, bidders[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if accepted.Status != SettlementStatusPending || accepted.TransactionID == nil || txCreator.buyers[*accepted.TransactionID] != bidders[1] {
+		t.Errorf("expected a pending transaction for the runner-up, got %+v", accepted)
+	}
+	ended := repo.auctions[auction.ID]
+	if ended.WinnerID == nil || *ended.WinnerID != bidders[1] || *ended.CurrentPrice != 200 {
+		t.Errorf("expected the runner-up to win at 200, got %v at %v", ended.WinnerID, *ended.CurrentPrice)
+	}
+}
+
+func TestService_AcceptSecondChance_ExpiredMeanwhile(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
+
+	auction, bidders := newTestSettledAuction(t, service)
+	due := repo.settlements[auction.ID][0].DueAt.Add(time.Minute)
+	if _, err := service.ProcessSettlements(ctx, due); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	offer := repo.settlements[auction.ID][1]
+
+	// The worker expires the offer after the accept has read it
+	service.SetSpendingChecker(spendingCheckFunc(func() error {
+		_, err := service.ProcessSettlements(ctx, offer.DueAt.Add(time.Minute))
+		return err
+	}))
+	if _, err := service.AcceptSecondChance(ctx, auction.ID, bidders[1]); err != ErrNoSecondChanceOffer {
+		t.Fatalf("expected ErrNoSecondChanceOffer, got %v", err)
+	}
+	if offer.Status != SettlementStatusDeclined || len(txCreator.amounts) != 1 {
+		t.Errorf("expected the offer declined and no new transaction, got %s and %d transactions", offer.Status, len(txCreator.amounts))
+	}
+	if winner := repo.auctions[auction.ID].WinnerID; winner == nil || *winner != bidders[2] {
+		t.Errorf("expected the winner unchanged, got %v", winner)
+	}
+}
+
+func TestService_ProcessSettlements_Funded(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
+
+	auction, _ := newTestSettledAuction(t, service)
+	winner := repo.settlements[auction.ID][0]
+	txCreator.funded[*winner.TransactionID] = true
+
+	if _, err := service.ProcessSettlements(ctx, winner.DueAt.Add(time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if winner.Status != SettlementStatusFunded {
+		t.Errorf("expected funded settlement, got %s", winner.Status)
+	}
+	if len(repo.settlements[auction.ID]) != 1 {
+		t.Error("expected no second-chance offer")
+	}
+}
+
+func TestService_ProcessSettlements_SecondChanceSkipsRetracted(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
+
+	auction := newTestEnglishAuction(t, service, nil)
+	runnerUp, winner := uuid.New(), uuid.New()
+	retracted, err := service.PlaceBid(ctx, auction.ID, runnerUp, &PlaceBidRequest{Amount: 200})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.PlaceBid(ctx, auction.ID, winner, &PlaceBidRequest{Amount: 250}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.RetractBid(ctx, auction.ID, retracted.ID, runnerUp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.EndAuction(ctx, auction.ID, auction.SellerID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The winner doesn't pay, and the runner-up withdrew, so nobody is offered it
+	due := repo.settlements[auction.ID][0].DueAt.Add(time.Minute)
+	if _, err := service.ProcessSettlements(ctx, due); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.settlements[auction.ID]) != 1 {
+		t.Fatalf("expected no second-chance offer, got %+v", repo.settlements[auction.ID][1])
+	}
+	if _, err := service.AcceptSecondChance(ctx, auction.ID, runnerUp); err != ErrNoSecondChanceOffer {
+		t.Errorf("expected ErrNoSecondChanceOffer, got %v", err)
+	}
+}
+
+func TestService_DeclineSecondChance(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(newMockTransactionCreator())
+	ctx := context.Background()
+
+	auction, bidders := newTestSettledAuction(t, service)
+	due := repo.settlements[auction.ID][0].DueAt.Add(time.Minute)
+	if _, err := service.ProcessSettlements(ctx, due); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := service.DeclineSecondChance(ctx, auction.ID, bidders[1]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settlements := repo.settlements[auction.ID]
+	if settlements[1].Status != SettlementStatusDeclined {
+		t.Errorf("expected declined offer, got %s", settlements[1].Status)
+	}
+	if len(settlements) != 3 || settlements[2].BidderID != bidders[0] || settlements[2].Amount != 150 {
+		t.Fatalf("expected an offer at 150 to the third bidder, got %d settlements", len(settlements))
+	}
+
+	// An expired offer is declined and, with nobody left, the auction stays unsold
+	if _, err := service.ProcessSettlements(ctx, settlements[2].DueAt.Add(time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settlements[2].Status != SettlementStatusDeclined || len(repo.settlements[auction.ID]) != 3 {
+		t.Errorf("expected the last offer declined and no new one, got %s", settlements[2].Status)
+	}
+}
+
+func TestService_EndExpiredAuctions(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
+
+	auction := newTestEnglishAuction(t, service, nil)
+	bidderID := uuid.New()
+	if _, err := service.PlaceBid(ctx, auction.ID, bidderID, &PlaceBidRequest{Amount: 150}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ended, err := service.EndExpiredAuctions(ctx, time.Now().UTC()); err != nil || ended != 0 {
+		t.Fatalf("expected no auction ended, got %d, %v", ended, err)
+	}
+	ended, err := service.EndExpiredAuctions(ctx, auction.EndsAt.Add(time.Minute))
+	if err != nil || ended != 1 {
+		t.Fatalf("expected 1 auction ended, got %d, %v", ended, err)
+	}
+	if winner := repo.auctions[auction.ID].WinnerID; winner == nil || *winner != bidderID {
+		t.Errorf("expected the bidder to win, got %v", winner)
+	}
+	if len(repo.settlements[auction.ID]) != 1 || len(txCreator.amounts) != 1 {
+		t.Error("expected the win to be settled")
+	}
+}
+
+func TestService_EndExpiredAuctions_AllPagesPastFailures(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+
+	var broken uuid.UUID
+	for i := 0; i < 150; i++ {
+		auction := newTestEnglishAuction(t, service, nil)
+		broken = auction.ID
+	}
+	repo.bidsErr = map[uuid.UUID]error{broken: errors.New("connection reset")}
+
+	ended, err := service.EndExpiredAuctions(ctx, time.Now().Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ended != 149 {
+		t.Errorf("expected 149 auctions ended, got %d", ended)
+	}
+	if repo.auctions[broken].Status != AuctionStatusActive {
+		t.Errorf("expected the broken auction left to retry, got %s", repo.auctions[broken].Status)
+	}
+}
+
+func TestService_EndExpiredAuctions_AwardsOnce(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
+
+	auction := newTestEnglishAuction(t, service, nil)
+	bid, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 150})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stale := *repo.auctions[auction.ID]
+
+	if ended, err := service.EndExpiredAuctions(ctx, auction.EndsAt.Add(time.Minute)); err != nil || ended != 1 {
+		t.Fatalf("expected 1 auction ended, got %d, %v", ended, err)
+	}
+
+	// Another worker that read the auction while it was open doesn't award it again
+	if err := service.awardAuction(ctx, &stale, bid, bid.Amount, nil); err != ErrAuctionNotActive {
+		t.Fatalf("expected ErrAuctionNotActive, got %v", err)
+	}
+	if len(repo.settlements[auction.ID]) != 1 || len(txCreator.amounts) != 1 {
+		t.Errorf("expected one settlement, got %d and %d transactions", len(repo.settlements[auction.ID]), len(txCreator.amounts))
+	}
+}
+
+func newTestMultiUnitAuction(t *testing.T, service *Service, pricing string) *Auction {
+	t.Helper()
+	auction, err := service.CreateAuction(context.Background(), uuid.New(), &CreateAuctionRequest{
+		AuctionType:   "sealed",
+		Title:         "Data Licenses",
+		StartingPrice: 5.0,
+		Quantity:      5,
+		Pricing:       pricing,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return auction
+}
+
+func TestService_MultiUnit_Uniform(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
+
+	auction := newTestMultiUnitAuction(t, service, "")
+	if auction.Pricing != PricingUniform {
+		t.Fatalf("expected uniform pricing by default, got %q", auction.Pricing)
+	}
+
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	for _, bid := range []struct {
+		bidder   uuid.UUID
+		amount   float64
+		quantity int
+	}{{a, 10, 3}, {b, 9, 3}, {c, 8, 1}} {
+		if _, err := service.PlaceBid(ctx, auction.ID, bid.bidder, &PlaceBidRequest{Amount: bid.amount, Quantity: bid.quantity}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if _, err := service.EndAuction(ctx, auction.ID, auction.SellerID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bids, err := service.GetBids(ctx, auction.ID, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[uuid.UUID]struct {
+		status    BidStatus
+		allocated int
+	}{a: {BidStatusWon, 3}, b: {BidStatusWon, 2}, c: {BidStatusLost, 0}}
+	for _, bid := range bids {
+		w := want[bid.BidderID]
+		if bid.Status != w.status || bid.Allocated == nil || *bid.Allocated != w.allocated {
+			t.Errorf("expected %s with %d units, got %s with %v", w.status, w.allocated, bid.Status, bid.Allocated)
+		}
+	}
+
+	ended := repo.auctions[auction.ID]
+	if ended.Status != AuctionStatusEnded || *ended.CurrentPrice != 9 {
+		t.Errorf("expected auction ended at a clearing price of 9, got %s at %v", ended.Status, *ended.CurrentPrice)
+	}
+	amounts := make(map[uuid.UUID]float64)
+	for id, amount := range txCreator.amounts {
+		amounts[txCreator.buyers[id]] = amount
+	}
+	if len(amounts) != 2 || amounts[a] != 27 || amounts[b] != 18 {
+		t.Errorf("expected transactions of 27 and 18 at the clearing price, got %v", amounts)
+	}
+}
+
+func TestService_MultiUnit_PayAsBid(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
+
+	auction := newTestMultiUnitAuction(t, service, "pay_as_bid")
+
+	a, b := uuid.New(), uuid.New()
+	for _, bid := range []struct {
+		bidder   uuid.UUID
+		amount   float64
+		quantity int
+	}{{a, 10, 2}, {a, 9.5, 1}, {b, 9, 3}} {
+		if _, err := service.PlaceBid(ctx, auction.ID, bid.bidder, &PlaceBidRequest{Amount: bid.amount, Quantity: bid.quantity}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	ended, err := service.EndExpiredAuctions(ctx, auction.EndsAt.Add(time.Minute))
+	if err != nil || ended != 1 {
+		t.Fatalf("expected 1 auction ended, got %d, %v", ended, err)
+	}
+
+	// One transaction per winning bidder, each paying their own bids
+	amounts := make(map[uuid.UUID]float64)
+	for id, amount := range txCreator.amounts {
+		amounts[txCreator.buyers[id]] = amount
+	}
+	if len(amounts) != 2 || amounts[a] != 29.5 || amounts[b] != 18 {
+		t.Errorf("expected transactions of 29.5 and 18, got %v", amounts)
+	}
+	if len(repo.settlements[auction.ID]) != 2 {
+		t.Errorf("expected 2 settlements, got %d", len(repo.settlements[auction.ID]))
+	}
+}
+
+func TestService_MultiUnit_Reserve(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	reserve := 9.0
+
+	auction, err := service.CreateAuction(ctx, uuid.New(), &CreateAuctionRequest{
+		AuctionType:   "sealed",
+		Title:         "Data Licenses",
+		StartingPrice: 5.0,
+		ReservePrice:  &reserve,
+		Quantity:      5,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 10, Quantity: 1})
+	service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 8, Quantity: 4})
+
+	if _, err := service.EndAuction(ctx, auction.ID, auction.SellerID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, bid := range repo.bids[auction.ID] {
+		if bid.Amount < reserve && bid.Status != BidStatusLost {
+			t.Errorf("expected bid below the reserve to lose, got %s", bid.Status)
+		}
+	}
+	if *repo.auctions[auction.ID].CurrentPrice != 10 {
+		t.Errorf("expected clearing price 10, got %v", *repo.auctions[auction.ID].CurrentPrice)
+	}
+}
+
+func TestService_MultiUnit_InvalidQuantity(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+
+	auction := newTestMultiUnitAuction(t, service, "")
+	if _, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 10, Quantity: 6}); err != ErrInvalidQuantity {
+		t.Errorf("expected ErrInvalidQuantity, got %v", err)
+	}
+	single := newTestEnglishAuction(t, service, nil)
+	if _, err := service.PlaceBid(ctx, single.ID, uuid.New(), &PlaceBidRequest{Amount: 150, Quantity: 2}); err != ErrInvalidQuantity {
+		t.Errorf("expected ErrInvalidQuantity, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  CreateAuctionRequest
+	}{
+		{"english", CreateAuctionRequest{AuctionType: "english", Quantity: 3}},
+		{"negative", CreateAuctionRequest{AuctionType: "sealed", Quantity: -1}},
+		{"unknown pricing", CreateAuctionRequest{AuctionType: "sealed", Quantity: 3, Pricing: "dutch"}},
+		{"pricing on one unit", CreateAuctionRequest{AuctionType: "sealed", Pricing: "uniform"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.Title = "Auction"
+			req.StartingPrice = 10
+			req.EndsAt = time.Now().Add(time.Hour)
+			if _, err := service.CreateAuction(ctx, uuid.New(), &req); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func newTestScheduledAuction(t *testing.T, service *Service, sellerID uuid.UUID) *Auction {
+	t.Helper()
+	startsAt := time.Now().Add(time.Hour)
+	auction, err := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
+		AuctionType:   "english",
+		Title:         "Scheduled Auction",
+		StartingPrice: 100.0,
+		StartsAt:      &startsAt,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return auction
+}
+
+func TestService_UpdateAuction(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	sellerID := uuid.New()
+	auction := newTestScheduledAuction(t, service, sellerID)
+
+	title, price, reserve := "Renamed Auction", 120.0, 200.0
+	updated, err := service.UpdateAuction(ctx, auction.ID, sellerID, &UpdateAuctionRequest{
+		Title:         &title,
+		StartingPrice: &price,
+		ReservePrice:  &reserve,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Title != title || updated.StartingPrice != 120 || *updated.CurrentPrice != 120 || *updated.ReservePrice != 200 {
+		t.Errorf("expected the changes to be applied, got %+v", updated)
+	}
+
+	history := repo.history[auction.ID]
+	if len(history) != 1 || history[0].Action != HistoryActionUpdated || history[0].ActorID != sellerID {
+		t.Fatalf("expected one update recorded by the seller, got %+v", history)
+	}
+	change, ok := history[0].Changes["starting_price"].(map[string]any)
+	if !ok || change["from"] != 100.0 || change["to"] != 120.0 {
+		t.Errorf("expected starting price change from 100 to 120, got %v", history[0].Changes["starting_price"])
+	}
+	if change := history[0].Changes["reserve_price"].(map[string]any); change["from"] != nil {
+		t.Errorf("expected reserve price change from unset, got %v", change)
+	}
+	if len(history[0].Changes) != 3 {
+		t.Errorf("expected 3 changed fields, got %v", history[0].Changes)
+	}
+
+	// Unchanged values record nothing
+	if _, err := service.UpdateAuction(ctx, auction.ID, sellerID, &UpdateAuctionRequest{Title: &title}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.history[auction.ID]) != 1 {
+		t.Error("expected no history entry without changes")
+	}
+
+	if _, err := service.UpdateAuction(ctx, auction.ID, uuid.New(), &UpdateAuctionRequest{Title: &title}); err != ErrNotAuthorized {
+		t.Errorf("expected ErrNotAuthorized, got %v", err)
+	}
+
+	endsAt := time.Now().Add(30 * time.Minute)
+	if _, err := service.UpdateAuction(ctx, auction.ID, sellerID, &UpdateAuctionRequest{EndsAt: &endsAt}); err == nil {
+		t.Error("expected error for an end time before the start time")
+	}
+}
+
+func TestService_UpdateAuction_NotScheduled(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	sellerID := uuid.New()
+	auction, err := service.CreateAuction(context.Background(), sellerID, &CreateAuctionRequest{
+		AuctionType:   "english",
+		Title:         "Active Auction",
+		StartingPrice: 100.0,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	title := "Renamed Auction"
+	if _, err := service.UpdateAuction(context.Background(), auction.ID, sellerID, &UpdateAuctionRequest{Title: &title}); err != ErrAuctionNotScheduled {
+		t.Errorf("expected ErrAuctionNotScheduled, got %v", err)
+	}
+}
+
+func TestService_CancelAuction(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	sellerID := uuid.New()
+
+	// Without bids cancelling is free
+	scheduled := newTestScheduledAuction(t, service, sellerID)
+	if _, err := service.CancelAuction(ctx, scheduled.ID, uuid.New(), &CancelAuctionRequest{}); err != ErrNotAuthorized {
+		t.Errorf("expected ErrNotAuthorized, got %v", err)
+	}
+	cancelled, err := service.CancelAuction(ctx, scheduled.ID, sellerID, &CancelAuctionRequest{Reason: "listed by mistake"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cancelled.Status != AuctionStatusCancelled {
+		t.Errorf("expected cancelled auction, got %s", cancelled.Status)
+	}
+	if history := repo.history[scheduled.ID]; len(history) != 1 || history[0].Changes["penalty"] != nil {
+		t.Errorf("expected a cancellation without penalty, got %+v", history)
+	}
+	if _, err := service.CancelAuction(ctx, scheduled.ID, sellerID, &CancelAuctionRequest{}); err != ErrAuctionNotActive {
+		t.Errorf("expected ErrAuctionNotActive, got %v", err)
+	}
+
+	// With bids the seller must accept the penalty
+	charger := &mockPenaltyCharger{charged: make(map[uuid.UUID]float64)}
+	service.SetPenaltyCharger(charger)
+	service.SetCancellationPenalty(5)
+	auction := newTestEnglishAuction(t, service, nil)
+	bidderID := uuid.New()
+	if _, err := service.PlaceBid(ctx, auction.ID, bidderID, &PlaceBidRequest{Amount: 200}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.CancelAuction(ctx, auction.ID, auction.SellerID, &CancelAuctionRequest{}); err != ErrAuctionHasBids {
+		t.Errorf("expected ErrAuctionHasBids, got %v", err)
+	}
+
+	// A penalty the seller can't pay leaves the auction running
+	charger.err = errors.New("insufficient wallet balance")
+	if _, err := service.CancelAuction(ctx, auction.ID, auction.SellerID, &CancelAuctionRequest{AcceptPenalty: true}); err != charger.err {
+		t.Errorf("expected the charge error, got %v", err)
+	}
+	if repo.auctions[auction.ID].Status != AuctionStatusActive {
+		t.Errorf("expected the auction still active, got %s", repo.auctions[auction.ID].Status)
+	}
+
+	charger.err = nil
+	if _, err := service.CancelAuction(ctx, auction.ID, auction.SellerID, &CancelAuctionRequest{AcceptPenalty: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if charger.charged[auction.ID] != 10 {
+		t.Errorf("expected the seller charged 10, got %v", charger.charged[auction.ID])
+	}
+	history := repo.history[auction.ID]
+	if len(history) != 1 || history[0].Action != HistoryActionCancelled || history[0].Changes["penalty"] != 10.0 {
+		t.Errorf("expected a cancellation with a penalty of 10, got %+v", history)
+	}
+	if bid := repo.bids[auction.ID][0]; bid.Status != BidStatusCancelled {
+		t.Errorf("expected the bid to be cancelled, got %s", bid.Status)
+	}
+}
+
+func TestService_CancelAuction_PenaltyOnlyWithCancellation(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	charger := &mockPenaltyCharger{charged: make(map[uuid.UUID]float64)}
+	service.SetPenaltyCharger(charger)
+	ctx := context.Background()
+
+	auction := newTestEnglishAuction(t, service, nil)
+	if _, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 200}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req := &CancelAuctionRequest{AcceptPenalty: true}
+
+	// The auction ends between the seller's request and the lock: nothing is charged
+	repo.onLock = func() {
+		repo.onLock = nil
+		repo.auctions[auction.ID].Status = AuctionStatusEnded
+	}
+	if _, err := service.CancelAuction(ctx, auction.ID, auction.SellerID, req); err != ErrAuctionNotActive {
+		t.Errorf("expected ErrAuctionNotActive, got %v", err)
+	}
+	if len(charger.charged) != 0 {
+		t.Errorf("expected no charge, got %v", charger.charged)
+	}
+
+	// A cancellation that fails after the charge reverses it
+	repo.auctions[auction.ID].Status = AuctionStatusActive
+	repo.cancelErr = errors.New("connection reset")
+	if _, err := service.CancelAuction(ctx, auction.ID, auction.SellerID, req); err != repo.cancelErr {
+		t.Errorf("expected the cancel error, got %v", err)
+	}
+	if len(charger.charged) != 0 || len(charger.reversed) != 1 || charger.reversed[0] != auction.ID {
+		t.Errorf("expected the charge reversed, got %v charged and %v reversed", charger.charged, charger.reversed)
+	}
+	if len(repo.history[auction.ID]) != 0 {
+		t.Errorf("expected no cancellation recorded, got %+v", repo.history[auction.ID])
+	}
+}
+
+func TestService_RetractBid(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	auction := newTestEnglishAuction(t, service, nil)
+	alice, bob := uuid.New(), uuid.New()
+
+	first, err := service.PlaceBid(ctx, auction.ID, alice, &PlaceBidRequest{Amount: 150})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.RetractBid(ctx, auction.ID, first.ID, bob); err != ErrNotAuthorized {
+		t.Errorf("expected ErrNotAuthorized, got %v", err)
+	}
+	if _, err := service.RetractBid(ctx, auction.ID, uuid.New(), alice); err != ErrBidNotFound {
+		t.Errorf("expected ErrBidNotFound, got %v", err)
+	}
+
+	// A bid that outbid another cannot be retracted
+	second, err := service.PlaceBid(ctx, auction.ID, bob, &PlaceBidRequest{Amount: 200})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.RetractBid(ctx, auction.ID, second.ID, bob); err != ErrBidNotRetractable {
+		t.Errorf("expected ErrBidNotRetractable, got %v", err)
+	}
+
+	// The outbid bid outbid nobody
+	retracted, err := service.RetractBid(ctx, auction.ID, first.ID, alice)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if retracted.Status != BidStatusRetracted {
+		t.Errorf("expected retracted bid, got %s", retracted.Status)
+	}
+	if *repo.auctions[auction.ID].CurrentPrice != 200 {
+		t.Errorf("expected price to stay at 200, got %v", *repo.auctions[auction.ID].CurrentPrice)
+	}
+
+	// With nobody outbid by it, the lead can be retracted and the price falls back
+	if _, err := service.RetractBid(ctx, auction.ID, second.ID, bob); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *repo.auctions[auction.ID].CurrentPrice != 100 {
+		t.Errorf("expected price back at the starting price, got %v", *repo.auctions[auction.ID].CurrentPrice)
+	}
+	if _, err := service.RetractBid(ctx, auction.ID, second.ID, bob); err != ErrBidNotRetractable {
+		t.Errorf("expected ErrBidNotRetractable for a retracted bid, got %v", err)
+	}
+	if history := repo.history[auction.ID]; len(history) != 2 || history[0].Action != HistoryActionBidRetracted {
+		t.Errorf("expected 2 retractions recorded, got %+v", history)
+	}
+}
+
+func newTestBundleAuction(t *testing.T, service *Service, titles ...string) *Auction {
+	t.Helper()
+	req := &CreateAuctionRequest{
+		AuctionType:   "bundle",
+		Title:         "Sensor Network",
+		StartingPrice: 5.0,
+		EndsAt:        time.Now().Add(24 * time.Hour),
+	}
+	for _, title := range titles {
+		req.Items = append(req.Items, BundleItemRequest{Title: title})
+	}
+	auction, err := service.CreateAuction(context.Background(), uuid.New(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return auction
+}
+
+func TestService_CreateAuction_Bundle(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+
+	auction := newTestBundleAuction(t, service, "Camera", "Lidar", "Radar")
+	if len(auction.Items) != 3 || auction.Items[2].Title != "Radar" || auction.Items[2].Position != 2 {
+		t.Fatalf("expected 3 items in order, got %+v", auction.Items)
+	}
+	got, err := service.GetAuction(ctx, auction.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Items) != 3 {
+		t.Errorf("expected items to be loaded with the auction, got %d", len(got.Items))
+	}
+
+	for _, req := range []*CreateAuctionRequest{
+		// A bundle needs at least two items
+		{AuctionType: "bundle", Title: "One", StartingPrice: 5, EndsAt: time.Now().Add(time.Hour), Items: []BundleItemRequest{{Title: "Camera"}}},
+		// Every item needs a title
+		{AuctionType: "bundle", Title: "Untitled", StartingPrice: 5, EndsAt: time.Now().Add(time.Hour), Items: []BundleItemRequest{{Title: "Camera"}, {}}},
+		// Only bundle auctions have items
+		{AuctionType: "english", Title: "English", StartingPrice: 5, EndsAt: time.Now().Add(time.Hour), Items: []BundleItemRequest{{Title: "Camera"}, {Title: "Lidar"}}},
+	} {
+		if _, err := service.CreateAuction(ctx, uuid.New(), req); err == nil {
+			t.Errorf("expected error for %q", req.Title)
+		}
+	}
+}
+
+func TestService_Bundle_PlaceBid(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+
+	auction := newTestBundleAuction(t, service, "Camera", "Lidar")
+	camera, lidar := auction.Items[0].ID, auction.Items[1].ID
+	bidder := uuid.New()
+
+	for _, items := range [][]uuid.UUID{nil, {uuid.New()}, {camera, camera}} {
+		if _, err := service.PlaceBid(ctx, auction.ID, bidder, &PlaceBidRequest{Amount: 10, Items: items}); err != ErrInvalidBundle {
+			t.Errorf("expected ErrInvalidBundle for %v, got %v", items, err)
+		}
+	}
+	if _, err := service.PlaceBid(ctx, auction.ID, bidder, &PlaceBidRequest{Amount: 1, Items: []uuid.UUID{camera}}); err != ErrBidTooLow {
+		t.Errorf("expected ErrBidTooLow, got %v", err)
+	}
+
+	bid, err := service.PlaceBid(ctx, auction.ID, bidder, &PlaceBidRequest{Amount: 10, Items: []uuid.UUID{camera, lidar}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bid.IsSealed || bid.Status != BidStatusActive || len(bid.Items) != 2 {
+		t.Errorf("expected a sealed active bid on 2 items, got %+v", bid)
+	}
+
+	english := newTestEnglishAuction(t, service, nil)
+	if _, err := service.PlaceBid(ctx, english.ID, bidder, &PlaceBidRequest{Amount: 200, Items: []uuid.UUID{camera}}); err != ErrInvalidBundle {
+		t.Errorf("expected ErrInvalidBundle on an english auction, got %v", err)
+	}
+}
+
+func TestService_Bundle_Settle(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
+
+	auction := newTestBundleAuction(t, service, "Camera", "Lidar", "Radar")
+	a, b, c := auction.Items[0].ID, auction.Items[1].ID, auction.Items[2].ID
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	for _, bid := range []struct {
+		bidder uuid.UUID
+		amount float64
+		items  []uuid.UUID
+	}{
+		{alice, 50, []uuid.UUID{a}},
+		{bob, 40, []uuid.UUID{b}},
+		{carol, 100, []uuid.UUID{a, b}},
+		{alice, 10, []uuid.UUID{c}},
+		{bob, 105, []uuid.UUID{a, b, c}},
+	} {
+		if _, err := service.PlaceBid(ctx, auction.ID, bid.bidder, &PlaceBidRequest{Amount: bid.amount, Items: bid.items}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if _, err := service.EndAuction(ctx, auction.ID, auction.SellerID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// {A,B} for 100 and {C} for 10 beat {A,B,C} for 105 and {A}+{B}+{C} for 100
+	won := make(map[float64]bool)
+	for _, bid := range repo.bids[auction.ID] {
+		if bid.Status == BidStatusWon {
+			won[bid.Amount] = true
+		} else if bid.Status != BidStatusLost {
+			t.Errorf("expected bid of %v to be won or lost, got %s", bid.Amount, bid.Status)
+		}
+	}
+	if len(won) != 2 || !won[100] || !won[10] {
+		t.Errorf("expected the bids of 100 and 10 to win, got %v", won)
+	}
+
+	ended := repo.auctions[auction.ID]
+	if ended.Status != AuctionStatusEnded || *ended.CurrentPrice != 110 {
+		t.Errorf("expected auction ended with revenue 110, got %s at %v", ended.Status, *ended.CurrentPrice)
+	}
+	amounts := make(map[uuid.UUID]float64)
+	for id, amount := range txCreator.amounts {
+		amounts[txCreator.buyers[id]] = amount
+	}
+	if len(amounts) != 2 || amounts[carol] != 100 || amounts[alice] != 10 {
+		t.Errorf("expected transactions of 100 and 10, got %v", amounts)
+	}
+}
+
+func TestService_Bundle_Reserve(t *testing.T) {
+	repo := newMockRepository()
+	txCreator := newMockTransactionCreator()
+	service := NewService(repo, nil)
+	service.SetTransactionCreator(txCreator)
+	ctx := context.Background()
+
+	auction := newTestBundleAuction(t, service, "Camera", "Lidar")
+	reserve := 100.0
+	repo.auctions[auction.ID].ReservePrice = &reserve
+
+	for _, item := range auction.Items {
+		if _, err := service.PlaceBid(ctx, auction.ID, uuid.New(), &PlaceBidRequest{Amount: 40, Items: []uuid.UUID{item.ID}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := service.EndAuction(ctx, auction.ID, auction.SellerID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, bid := range repo.bids[auction.ID] {
+		if bid.Status != BidStatusLost {
+			t.Errorf("expected every bid lost below the reserve, got %s", bid.Status)
+		}
+	}
+	if len(txCreator.amounts) != 0 {
+		t.Errorf("expected no transactions, got %d", len(txCreator.amounts))
+	}
+}
+
+func TestAllocateBundles_Greedy(t *testing.T) {
+	items := make([]*BundleItem, exactBundleItems+4)
+	for i := range items {
+		items[i] = &BundleItem{ID: uuid.New()}
+	}
+	ids := func(from, to int) []uuid.UUID {
+		var result []uuid.UUID
+		for _, item := range items[from:to] {
+			result = append(result, item.ID)
+		}
+		return result
+	}
+
+	whole := &Bid{ID: uuid.New(), Amount: 50, Items: ids(0, len(items))}
+	first := &Bid{ID: uuid.New(), Amount: 40, Items: ids(0, 4)}
+	second := &Bid{ID: uuid.New(), Amount: 30, Items: ids(4, 8)}
+	overlap := &Bid{ID: uuid.New(), Amount: 20, Items: ids(3, 5)}
+
+	// Per square root of their items the bids rank 40/2, 30/2, 20/sqrt(2) and
+	// 50/4; the last two overlap bids taken before them
+	winners := allocateBundles(items, []*Bid{whole, first, second, overlap})
+	if len(winners) != 2 {
+		t.Fatalf("expected 2 winners, got %d", len(winners))
+	}
+	if winners[0] != first || winners[1] != second {
+		t.Errorf("expected the 40 and 30 bids to win in order, got %v and %v", winners[0].Amount, winners[1].Amount)
+	}
+}
+
+func TestService_Watchlist(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+
+	auction := newTestEnglishAuction(t, service, nil)
+	other := newTestEnglishAuction(t, service, nil)
+	watcher := uuid.New()
+
+	if err := service.WatchAuction(ctx, auction.ID, watcher); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Watching twice is not an error
+	if err := service.WatchAuction(ctx, auction.ID, watcher); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.WatchAuction(ctx, uuid.New(), watcher); err != ErrAuctionNotFound {
+		t.Errorf("expected ErrAuctionNotFound, got %v", err)
+	}
+
+	result, err := service.GetWatchlist(ctx, watcher, 20, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Total != 1 || result.Auctions[0].ID != auction.ID {
+		t.Errorf("expected only the watched auction, got %d auctions", result.Total)
+	}
+
+	// Watched events name the watchers
+	payload := map[string]any{"auction_id": auction.ID}
+	service.addWatchers(ctx, payload)
+	if watchers, _ := payload["watcher_ids"].([]uuid.UUID); len(watchers) != 1 || watchers[0] != watcher {
+		t.Errorf("expected the watcher in watcher_ids, got %v", payload["watcher_ids"])
+	}
+	payload = map[string]any{"auction_id": other.ID}
+	service.addWatchers(ctx, payload)
+	if _, ok := payload["watcher_ids"]; ok {
+		t.Error("expected no watcher_ids on an unwatched auction")
+	}
+
+	if err := service.UnwatchAuction(ctx, auction.ID, watcher); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.UnwatchAuction(ctx, auction.ID, watcher); err != ErrNotWatching {
+		t.Errorf("expected ErrNotWatching, got %v", err)
+	}
+
+	repo.auctions[other.ID].Status = AuctionStatusEnded
+	if err := service.WatchAuction(ctx, other.ID, watcher); err != ErrAuctionEnded {
+		t.Errorf("expected ErrAuctionEnded, got %v", err)
+	}
+}
+
+func TestService_SavedSearches(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+	ctx := context.Background()
+	agentID := uuid.New()
+
+	if _, err := service.CreateSavedSearch(ctx, agentID, &CreateSavedSearchRequest{Name: "Everything"}); err == nil {
+		t.Error("expected error for a search without criteria")
+	}
+	if _, err := service.CreateSavedSearch(ctx, agentID, &CreateSavedSearchRequest{AuctionType: "raffle"}); err != ErrInvalidAuctionType {
+		t.Errorf("expected ErrInvalidAuctionType, got %v", err)
+	}
+
+	search, err := service.CreateSavedSearch(ctx, agentID, &CreateSavedSearchRequest{Name: "Datasets", AuctionType: "english", Query: "dataset"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if search.AuctionType == nil || *search.AuctionType != AuctionTypeEnglish {
+		t.Errorf("expected english auction type, got %v", search.AuctionType)
+	}
+
+	for i := 1; i < maxSavedSearches; i++ {
+		if _, err := service.CreateSavedSearch(ctx, agentID, &CreateSavedSearchRequest{Query: "data"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := service.CreateSavedSearch(ctx, agentID, &CreateSavedSearchRequest{Query: "data"}); err != ErrTooManySavedSearches {
+		t.Errorf("expected ErrTooManySavedSearches, got %v", err)
+	}
+
+	if err := service.DeleteSavedSearch(ctx, search.ID, uuid.New()); err != ErrSavedSearchNotFound {
+		t.Errorf("expected ErrSavedSearchNotFound for another agent, got %v", err)
+	}
+	if err := service.DeleteSavedSearch(ctx, search.ID, agentID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	searches, err := service.GetSavedSearches(ctx, agentID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(searches) != maxSavedSearches-1 {
+		t.Errorf("expected %d saved searches, got %d", maxSavedSearches-1, len(searches))
+	}
+}
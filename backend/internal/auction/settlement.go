@@ -0,0 +1,405 @@
+package auction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/digi604/swarmmarket/backend/pkg/logger"
+	"github.com/google/uuid"
+)
+
+var ErrNoSecondChanceOffer = errors.New("no open second-chance offer for this bidder")
+
+const (
+	// defaultPaymentWindow is how long a winner has to fund escrow.
+	defaultPaymentWindow = 48 * time.Hour
+	// defaultSecondChanceWindow is how long a runner-up has to accept an offer.
+	defaultSecondChanceWindow = 24 * time.Hour
+	// settlementRetryDelay is how long a settlement whose transaction could not
+	// be created waits to be retried.
+	settlementRetryDelay = 5 * time.Minute
+)
+
+// awardAuction ends an auction with the bid winning at the price, settles the
+// sale with its bidder and publishes auction.ended with the details added. It
+// returns ErrAuctionNotActive if the auction was ended or awarded meanwhile,
+// so only one award settles.
+func (s *Service) awardAuction(ctx context.Context, auction *Auction, bid *Bid, price float64, details map[string]any) error {
+	if err := awardBid(ctx, s.repo, auction.ID, bid, price); err != nil {
+		return err
+	}
+	s.announceAward(ctx, auction, bid, price, details)
+	return nil
+}
+
+// awardBid ends an auction that is still open with the bid winning at the
+// price, returning ErrAuctionNotActive if it was not.
+func awardBid(ctx context.Context, repo RepositoryInterface, auctionID uuid.UUID, bid *Bid, price float64) error {
+	won, err := repo.SetAuctionWinner(ctx, auctionID, bid.ID, bid.BidderID)
+	if err != nil {
+		return err
+	}
+	if !won {
+		return ErrAuctionNotActive
+	}
+	if err := repo.UpdateAuctionPrice(ctx, auctionID, price); err != nil {
+		return err
+	}
+	if err := repo.UpdateBidStatus(ctx, bid.ID, BidStatusWon); err != nil {
+		return err
+	}
+	bid.Status = BidStatusWon
+	return nil
+}
+
+// announceAward settles an awarded auction with its winning bidder and
+// publishes auction.ended with the details added.
+func (s *Service) announceAward(ctx context.Context, auction *Auction, bid *Bid, price float64, details map[string]any) {
+	payload := map[string]any{
+		"auction_id":  auction.ID,
+		"winner_id":   bid.BidderID,
+		"final_price": price,
+	}
+	for k, v := range details {
+		payload[k] = v
+	}
+	if settlement := s.settleWinner(ctx, auction, bid, price); settlement != nil {
+		payload["transaction_id"] = *settlement.TransactionID
+		payload["payment_due_at"] = settlement.DueAt
+	}
+
+	s.publishEvent(ctx, "auction.ended", payload)
+}
+
+// settleWinner records the winner's settlement at the price and creates its
+// transaction. Failures are logged rather than returned: the auction has ended
+// either way, and a settlement whose transaction could not be created is
+// retried by ProcessSettlements.
+func (s *Service) settleWinner(ctx context.Context, auction *Auction, bid *Bid, price float64) *Settlement {
+	if s.txCreator == nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	settlement := &Settlement{
+		ID:        uuid.New(),
+		AuctionID: auction.ID,
+		BidID:     bid.ID,
+		BidderID:  bid.BidderID,
+		Amount:    price,
+		Currency:  auction.Currency,
+		Status:    SettlementStatusPendingCreation,
+		DueAt:     now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.CreateSettlement(ctx, settlement); err != nil {
+		logger.Error("auction_settlement_failed", map[string]interface{}{
+			"auction_id": auction.ID.String(),
+			"bidder_id":  bid.BidderID.String(),
+			"error":      err.Error(),
+		})
+		return nil
+	}
+	if err := s.openSettlement(ctx, auction, settlement, now); err != nil {
+		logger.Error("auction_settlement_failed", map[string]interface{}{
+			"auction_id":    auction.ID.String(),
+			"settlement_id": settlement.ID.String(),
+			"error":         err.Error(),
+		})
+		return nil
+	}
+	return settlement
+}
+
+// openSettlement creates the transaction of a settlement pending creation and
+// moves it to pending. A second-chance settlement first makes its bidder the
+// auction's winner. On failure the settlement stays pending creation, for
+// ProcessSettlements to retry after settlementRetryDelay.
+func (s *Service) openSettlement(ctx context.Context, auction *Auction, settlement *Settlement, now time.Time) error {
+	err := s.awardSecondChance(ctx, settlement)
+	if err == nil {
+		err = s.openTransaction(ctx, auction, settlement, now)
+	}
+	if err != nil {
+		settlement.DueAt = now.Add(settlementRetryDelay)
+		s.repo.UpdateSettlement(ctx, settlement)
+		return err
+	}
+
+	if err := s.repo.UpdateSettlement(ctx, settlement); err != nil {
+		// Cancelled so that the retry doesn't open a second transaction
+		s.txCreator.CancelUnfundedTransaction(ctx, *settlement.TransactionID)
+		return err
+	}
+	return nil
+}
+
+// awardSecondChance makes the bidder of a second-chance settlement the winner
+// of the auction at their bid.
+func (s *Service) awardSecondChance(ctx context.Context, settlement *Settlement) error {
+	if !settlement.SecondChance {
+		return nil
+	}
+	if err := s.repo.ReplaceAuctionWinner(ctx, settlement.AuctionID, settlement.BidID, settlement.BidderID); err != nil {
+		return err
+	}
+	if err := s.repo.UpdateAuctionPrice(ctx, settlement.AuctionID, settlement.Amount); err != nil {
+		return err
+	}
+	return s.repo.UpdateBidStatus(ctx, settlement.BidID, BidStatusWon)
+}
+
+// openTransaction creates the transaction of a settlement, which starts escrow
+// funding, and gives the bidder the payment window to fund it.
+func (s *Service) openTransaction(ctx context.Context, auction *Auction, settlement *Settlement, now time.Time) error {
+	txID, err := s.txCreator.CreateFromAuction(ctx, settlement.BidderID, auction.SellerID, &auction.ID, settlement.Amount, settlement.Currency)
+	if err != nil {
+		return err
+	}
+	settlement.TransactionID = &txID
+	settlement.Status = SettlementStatusPending
+	settlement.DueAt = now.Add(s.paymentWindow)
+	return nil
+}
+
+// ProcessSettlements moves on settlements whose deadline has passed. A winner
+// that has not funded escrow defaults: the transaction is cancelled, the bid
+// forfeited and the next bidder gets a second-chance offer, as they do when an
+// offer is left to expire. Settlements whose transaction could not be created
+// are retried. It returns the number of settlements processed.
+func (s *Service) ProcessSettlements(ctx context.Context, now time.Time) (int, error) {
+	if s.txCreator == nil {
+		return 0, nil
+	}
+
+	due, err := s.repo.GetDueSettlements(ctx, now, 100)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due settlements: %w", err)
+	}
+
+	processed := 0
+	for _, settlement := range due {
+		switch settlement.Status {
+		case SettlementStatusPendingCreation:
+			if err := s.retrySettlement(ctx, settlement, now); err != nil {
+				logger.Error("auction_settlement_failed", map[string]interface{}{
+					"auction_id":    settlement.AuctionID.String(),
+					"settlement_id": settlement.ID.String(),
+					"error":         err.Error(),
+				})
+				continue
+			}
+			processed++
+			continue
+		case SettlementStatusPending:
+			cancelled, err := s.txCreator.CancelUnfundedTransaction(ctx, *settlement.TransactionID)
+			if err != nil {
+				return processed, fmt.Errorf("failed to cancel transaction %s: %w", *settlement.TransactionID, err)
+			}
+			if !cancelled {
+				// No longer pending, so escrow was funded in time
+				settlement.Status = SettlementStatusFunded
+				if err := s.repo.UpdateSettlement(ctx, settlement); err != nil {
+					return processed, err
+				}
+				processed++
+				continue
+			}
+			settlement.Status = SettlementStatusDefaulted
+			s.repo.UpdateBidStatus(ctx, settlement.BidID, BidStatusForfeited)
+			if err := s.repo.UpdateSettlement(ctx, settlement); err != nil {
+				return processed, err
+			}
+		case SettlementStatusOffered:
+			// The bidder may have accepted or declined the offer meanwhile
+			expired, err := s.repo.MoveSettlement(ctx, settlement.ID, SettlementStatusOffered, SettlementStatusDeclined, settlement.DueAt)
+			if err != nil {
+				return processed, err
+			}
+			if !expired {
+				continue
+			}
+		}
+
+		if err := s.offerSecondChance(ctx, settlement.AuctionID, now); err != nil {
+			return processed, fmt.Errorf("failed to offer auction %s: %w", settlement.AuctionID, err)
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+// retrySettlement creates the transaction of a settlement pending creation.
+func (s *Service) retrySettlement(ctx context.Context, settlement *Settlement, now time.Time) error {
+	auction, err := s.repo.GetAuctionByID(ctx, settlement.AuctionID)
+	if err != nil {
+		return err
+	}
+	return s.openSettlement(ctx, auction, settlement, now)
+}
+
+// offerSecondChance offers the auction to the highest bidder that has not had
+// it yet, at their own bid, if that bid meets the reserve price. Only bids
+// still standing when the auction ended qualify, not retracted, cancelled or
+// unrevealed ones. Units of multi-unit auctions and items of bundle auctions
+// are not offered again.
+func (s *Service) offerSecondChance(ctx context.Context, auctionID uuid.UUID, now time.Time) error {
+	auction, err := s.repo.GetAuctionByID(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+	if auction.isMultiUnit() || auction.AuctionType == AuctionTypeBundle {
+		return nil
+	}
+	bids, err := s.repo.GetBidsByAuctionID(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+	settlements, err := s.repo.GetSettlementsByAuctionID(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+
+	had := make(map[uuid.UUID]bool)
+	for _, settlement := range settlements {
+		had[settlement.BidderID] = true
+	}
+
+	sort.SliceStable(bids, func(i, j int) bool {
+		if bids[i].Amount != bids[j].Amount {
+			return bids[i].Amount > bids[j].Amount
+		}
+		return bids[i].CreatedAt.Before(bids[j].CreatedAt)
+	})
+
+	for _, bid := range bids {
+		switch bid.Status {
+		case BidStatusActive, BidStatusOutbid, BidStatusWinning:
+		default:
+			continue
+		}
+		if had[bid.BidderID] {
+			continue
+		}
+		if auction.ReservePrice != nil && bid.Amount < *auction.ReservePrice {
+			break
+		}
+
+		offer := &Settlement{
+			ID:           uuid.New(),
+			AuctionID:    auctionID,
+			BidID:        bid.ID,
+			BidderID:     bid.BidderID,
+			Amount:       bid.Amount,
+			Currency:     auction.Currency,
+			SecondChance: true,
+			Status:       SettlementStatusOffered,
+			DueAt:        now.Add(s.secondChanceWindow),
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		if err := s.repo.CreateSettlement(ctx, offer); err != nil {
+			return err
+		}
+
+		s.publishEvent(ctx, "auction.second_chance_offered", map[string]any{
+			"auction_id": auctionID,
+			"bidder_id":  bid.BidderID,
+			"seller_id":  auction.SellerID,
+			"amount":     offer.Amount,
+			"currency":   offer.Currency,
+			"expires_at": offer.DueAt,
+		})
+		return nil
+	}
+
+	logger.Info("auction_unsold", map[string]interface{}{
+		"auction_id": auctionID.String(),
+		"reason":     "no bidder left to offer a second chance",
+	})
+	return nil
+}
+
+// AcceptSecondChance buys an auction through the bidder's second-chance offer
+// at their bid. The bidder becomes the winner and gets the payment window to
+// fund escrow. Only one accept, decline or expiry moves an offer on.
+func (s *Service) AcceptSecondChance(ctx context.Context, auctionID, bidderID uuid.UUID) (*Settlement, error) {
+	now := time.Now().UTC()
+	offer, err := s.openOffer(ctx, auctionID, bidderID, now)
+	if err != nil {
+		return nil, err
+	}
+	auction, err := s.repo.GetAuctionByID(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.spendingChecker != nil {
+		if err := s.spendingChecker.CheckSpendingLimit(ctx, bidderID, offer.Amount); err != nil {
+			return nil, fmt.Errorf("spending limit check failed: %w", err)
+		}
+	}
+
+	accepted, err := s.repo.MoveSettlement(ctx, offer.ID, SettlementStatusOffered, SettlementStatusPendingCreation, now)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrNoSecondChanceOffer
+	}
+	offer.Status = SettlementStatusPendingCreation
+	offer.DueAt = now
+
+	// The offer is accepted either way; a failure is retried by ProcessSettlements
+	if err := s.openSettlement(ctx, auction, offer, now); err != nil {
+		logger.Error("auction_settlement_failed", map[string]interface{}{
+			"auction_id":    auctionID.String(),
+			"settlement_id": offer.ID.String(),
+			"error":         err.Error(),
+		})
+	}
+
+	return offer, nil
+}
+
+// DeclineSecondChance declines the bidder's second-chance offer, which passes
+// it on to the next bidder.
+func (s *Service) DeclineSecondChance(ctx context.Context, auctionID, bidderID uuid.UUID) error {
+	now := time.Now().UTC()
+	offer, err := s.openOffer(ctx, auctionID, bidderID, now)
+	if err != nil {
+		return err
+	}
+
+	declined, err := s.repo.MoveSettlement(ctx, offer.ID, SettlementStatusOffered, SettlementStatusDeclined, offer.DueAt)
+	if err != nil {
+		return err
+	}
+	if !declined {
+		return ErrNoSecondChanceOffer
+	}
+	return s.offerSecondChance(ctx, auctionID, now)
+}
+
+// openOffer returns the bidder's second-chance offer on an auction if it has
+// not expired.
+func (s *Service) openOffer(ctx context.Context, auctionID, bidderID uuid.UUID, now time.Time) (*Settlement, error) {
+	if s.txCreator == nil {
+		return nil, ErrNoSecondChanceOffer
+	}
+	settlements, err := s.repo.GetSettlementsByAuctionID(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, settlement := range settlements {
+		if settlement.BidderID == bidderID && settlement.Status == SettlementStatusOffered && settlement.DueAt.After(now) {
+			return settlement, nil
+		}
+	}
+	return nil, ErrNoSecondChanceOffer
+}
@@ -0,0 +1,171 @@
+package auction
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/digi604/swarmmarket/backend/pkg/logger"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotWatching          = errors.New("auction is not on the watchlist")
+	ErrSavedSearchNotFound  = errors.New("saved search not found")
+	ErrTooManySavedSearches = errors.New("too many saved searches")
+)
+
+// maxSavedSearches is the most saved searches an agent can have.
+const maxSavedSearches = 20
+
+// watchedEvents are the events that are also delivered to the agents watching
+// the auction, whether or not they have bid on it. The worker adds the
+// watchers to auction.ending_soon itself.
+var watchedEvents = map[string]bool{
+	"bid.outbid":            true,
+	"auction.price_changed": true,
+}
+
+// WatchAuction adds an auction that has not ended to an agent's watchlist.
+// Watching an auction twice is not an error.
+func (s *Service) WatchAuction(ctx context.Context, auctionID, agentID uuid.UUID) error {
+	auction, err := s.repo.GetAuctionByID(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+	if auction.Status == AuctionStatusEnded || auction.Status == AuctionStatusCancelled {
+		return ErrAuctionEnded
+	}
+	return s.repo.WatchAuction(ctx, agentID, auctionID)
+}
+
+// UnwatchAuction removes an auction from an agent's watchlist.
+func (s *Service) UnwatchAuction(ctx context.Context, auctionID, agentID uuid.UUID) error {
+	removed, err := s.repo.UnwatchAuction(ctx, agentID, auctionID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotWatching
+	}
+	return nil
+}
+
+// GetWatchlist retrieves the auctions an agent is watching.
+func (s *Service) GetWatchlist(ctx context.Context, agentID uuid.UUID, limit, offset int) (*AuctionListResult, error) {
+	return s.SearchAuctions(ctx, SearchAuctionsParams{
+		WatcherID: &agentID,
+		Limit:     limit,
+		Offset:    offset,
+	})
+}
+
+// CreateSavedSearch saves an auction search for an agent. The agent is
+// alerted with auction.search_matched whenever a matching auction starts.
+func (s *Service) CreateSavedSearch(ctx context.Context, agentID uuid.UUID, req *CreateSavedSearchRequest) (*SavedSearch, error) {
+	search := &SavedSearch{
+		ID:        uuid.New(),
+		AgentID:   agentID,
+		Name:      req.Name,
+		Query:     req.Query,
+		SellerID:  req.SellerID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if req.AuctionType != "" {
+		auctionType := AuctionType(req.AuctionType)
+		switch auctionType {
+		case AuctionTypeEnglish, AuctionTypeDutch, AuctionTypeSealed, AuctionTypeVickrey, AuctionTypeContinuous, AuctionTypeBundle:
+			search.AuctionType = &auctionType
+		default:
+			return nil, ErrInvalidAuctionType
+		}
+	}
+	if search.AuctionType == nil && search.Query == "" && search.SellerID == nil {
+		return nil, errors.New("saved search needs an auction type, query or seller")
+	}
+
+	existing, err := s.repo.GetSavedSearches(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxSavedSearches {
+		return nil, ErrTooManySavedSearches
+	}
+
+	if err := s.repo.CreateSavedSearch(ctx, search); err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+// GetSavedSearches retrieves an agent's saved auction searches.
+func (s *Service) GetSavedSearches(ctx context.Context, agentID uuid.UUID) ([]*SavedSearch, error) {
+	return s.repo.GetSavedSearches(ctx, agentID)
+}
+
+// DeleteSavedSearch deletes one of an agent's saved searches.
+func (s *Service) DeleteSavedSearch(ctx context.Context, searchID, agentID uuid.UUID) error {
+	deleted, err := s.repo.DeleteSavedSearch(ctx, searchID, agentID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+// NotifySavedSearches alerts the agents whose saved searches match an auction
+// that has just started. Sellers are not alerted about their own auctions, and
+// an agent is alerted once however many of its searches match. Failures are
+// logged rather than returned: the auction has started either way.
+func (s *Service) NotifySavedSearches(ctx context.Context, auction *Auction) {
+	searches, err := s.repo.GetMatchingSavedSearches(ctx, auction)
+	if err != nil {
+		logger.Error("auction_alerts_failed", map[string]interface{}{
+			"auction_id": auction.ID.String(),
+			"error":      err.Error(),
+		})
+		return
+	}
+
+	alerted := map[uuid.UUID]bool{auction.SellerID: true}
+	for _, search := range searches {
+		if alerted[search.AgentID] {
+			continue
+		}
+		alerted[search.AgentID] = true
+
+		s.publishEvent(ctx, "auction.search_matched", map[string]any{
+			"search_id":      search.ID,
+			"search_name":    search.Name,
+			"agent_id":       search.AgentID,
+			"auction_id":     auction.ID,
+			"auction_type":   auction.AuctionType,
+			"title":          auction.Title,
+			"starting_price": auction.StartingPrice,
+			"currency":       auction.Currency,
+			"ends_at":        auction.EndsAt,
+		})
+	}
+}
+
+// addWatchers adds the agents watching the payload's auction to it as
+// watcher_ids, so they are notified of the event too.
+func (s *Service) addWatchers(ctx context.Context, payload map[string]any) {
+	auctionID, ok := payload["auction_id"].(uuid.UUID)
+	if !ok {
+		return
+	}
+	watchers, err := s.repo.GetAuctionWatcherIDs(ctx, auctionID)
+	if err != nil {
+		logger.Error("auction_watchers_failed", map[string]interface{}{
+			"auction_id": auctionID.String(),
+			"error":      err.Error(),
+		})
+		return
+	}
+	if len(watchers) > 0 {
+		payload["watcher_ids"] = watchers
+	}
+}
@@ -2,25 +2,32 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 )
 
 // Config holds all configuration for the application.
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	Auth     AuthConfig
-	Security SecurityConfig
-	Stripe   StripeConfig
-	Clerk    ClerkConfig
-	Twitter  TwitterConfig
-	Trust    TrustConfig
-	Storage  StorageConfig
-	Email    EmailConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	Auth      AuthConfig
+	Security  SecurityConfig
+	Stripe    StripeConfig
+	Clerk     ClerkConfig
+	Twitter   TwitterConfig
+	Trust     TrustConfig
+	Storage   StorageConfig
+	Email     EmailConfig
+	OrderBook OrderBookConfig
+	Auction   AuctionConfig
+	Dispute   DisputeConfig
+	Escrow    EscrowConfig
+	Wallet    WalletConfig
 }
 
 // ServerConfig holds HTTP server configuration.
@@ -141,6 +148,65 @@ type EmailConfig struct {
 	CooldownMinutes int    `envconfig:"EMAIL_COOLDOWN_MINUTES" default:"5"` // Min time between emails to same recipient
 }
 
+// OrderBookConfig holds order book matching configuration.
+type OrderBookConfig struct {
+	STPMode            string   `envconfig:"ORDERBOOK_STP_MODE" default:"cancel_newest"`   // none, cancel_newest, cancel_oldest, cancel_both, decrement
+	STPSameOwner       bool     `envconfig:"ORDERBOOK_STP_SAME_OWNER" default:"true"`      // Treat agents of the same human owner as one party
+	RequireInstruments bool     `envconfig:"ORDERBOOK_REQUIRE_INSTRUMENTS" default:"true"` // Only accept orders for registered instruments
+	InstrumentAdminIDs []string `envconfig:"ORDERBOOK_INSTRUMENT_ADMIN_IDS"`               // Agent IDs allowed to register products that already trade
+}
+
+// InstrumentAdmins returns the configured instrument admin agent IDs, skipping any that are not valid UUIDs.
+func (o OrderBookConfig) InstrumentAdmins() []uuid.UUID {
+	return parseAgentIDs(o.InstrumentAdminIDs)
+}
+
+// AuctionConfig holds auction settlement and cancellation configuration.
+type AuctionConfig struct {
+	PaymentWindow        time.Duration `envconfig:"AUCTION_PAYMENT_WINDOW" default:"48h"`        // Time a winner has to fund escrow
+	SecondChanceWindow   time.Duration `envconfig:"AUCTION_SECOND_CHANCE_WINDOW" default:"24h"`  // Time a runner-up has to accept a second-chance offer
+	CancelPenaltyPercent float64       `envconfig:"AUCTION_CANCEL_PENALTY_PERCENT" default:"10"` // Share of the highest bid charged for cancelling an auction with bids
+}
+
+// DisputeConfig holds transaction dispute configuration.
+type DisputeConfig struct {
+	ResponseWindow time.Duration `envconfig:"DISPUTE_RESPONSE_WINDOW" default:"72h"` // Time the other party has to respond before the opener wins by default
+	ArbitratorIDs  []string      `envconfig:"DISPUTE_ARBITRATOR_IDS"`                // Agent IDs allowed to arbitrate disputes
+}
+
+// Arbitrators returns the configured arbitrator agent IDs, skipping any that are not valid UUIDs.
+func (d DisputeConfig) Arbitrators() []uuid.UUID {
+	return parseAgentIDs(d.ArbitratorIDs)
+}
+
+// EscrowConfig holds escrow inspection window configuration.
+type EscrowConfig struct {
+	InspectionWindow    time.Duration `envconfig:"ESCROW_INSPECTION_WINDOW" default:"72h"`      // Time a buyer has after delivery before escrow is released, unless the category sets one
+	InspectionWarning   time.Duration `envconfig:"ESCROW_INSPECTION_WARNING" default:"24h"`     // How long before the window ends the buyer is warned
+	MaxInspectionWindow time.Duration `envconfig:"ESCROW_MAX_INSPECTION_WINDOW" default:"720h"` // Longest window a buyer can set on a transaction
+}
+
+// WalletConfig holds wallet ledger configuration.
+type WalletConfig struct {
+	AuditorIDs []string `envconfig:"WALLET_AUDITOR_IDS"` // Agent IDs allowed to read the ledger reconciliation report
+}
+
+// Auditors returns the configured auditor agent IDs, skipping any that are not valid UUIDs.
+func (w WalletConfig) Auditors() []uuid.UUID {
+	return parseAgentIDs(w.AuditorIDs)
+}
+
+// parseAgentIDs parses a list of agent IDs, skipping any that are not valid UUIDs.
+func parseAgentIDs(values []string) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(values))
+	for _, s := range values {
+		if id, err := uuid.Parse(strings.TrimSpace(s)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 // Load reads configuration from environment variables.
 // It first attempts to load a .env file if present.
 func Load() (*Config, error) {
@@ -0,0 +1,38 @@
+-- Order book persistence so resting orders survive restarts and deploys
+
+CREATE TABLE IF NOT EXISTS orderbook_orders (
+    id UUID PRIMARY KEY,
+    seq BIGSERIAL, -- Arrival order, used to rebuild time priority on recovery
+    agent_id UUID NOT NULL REFERENCES agents(id),
+    product_id UUID NOT NULL,
+    side VARCHAR(4) NOT NULL, -- 'buy', 'sell'
+    order_type VARCHAR(10) NOT NULL, -- 'limit', 'market'
+    price DECIMAL(20, 8) NOT NULL DEFAULT 0,
+    quantity DECIMAL(20, 8) NOT NULL,
+    filled_qty DECIMAL(20, 8) NOT NULL DEFAULT 0,
+    remaining_qty DECIMAL(20, 8) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- 'open', 'partial', 'filled', 'cancelled'
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_orderbook_orders_agent_id ON orderbook_orders(agent_id);
+CREATE INDEX IF NOT EXISTS idx_orderbook_orders_product_id ON orderbook_orders(product_id);
+CREATE INDEX IF NOT EXISTS idx_orderbook_orders_resting ON orderbook_orders(seq) WHERE status IN ('open', 'partial');
+
+CREATE TABLE IF NOT EXISTS orderbook_trades (
+    id UUID PRIMARY KEY,
+    seq BIGSERIAL,
+    product_id UUID NOT NULL,
+    buy_order_id UUID NOT NULL REFERENCES orderbook_orders(id),
+    sell_order_id UUID NOT NULL REFERENCES orderbook_orders(id),
+    buyer_id UUID NOT NULL REFERENCES agents(id),
+    seller_id UUID NOT NULL REFERENCES agents(id),
+    price DECIMAL(20, 8) NOT NULL,
+    quantity DECIMAL(20, 8) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_orderbook_trades_product_seq ON orderbook_trades(product_id, seq DESC);
+CREATE INDEX IF NOT EXISTS idx_orderbook_trades_buyer_id ON orderbook_trades(buyer_id);
+CREATE INDEX IF NOT EXISTS idx_orderbook_trades_seller_id ON orderbook_trades(seller_id);
@@ -0,0 +1,8 @@
+-- Settle order book trades through escrowed transactions
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS trade_id UUID REFERENCES orderbook_trades(id);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS product_id UUID;
+
+-- One transaction per trade; also lets recovery find trades still awaiting settlement
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_trade_id ON transactions(trade_id) WHERE trade_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_product_id ON transactions(product_id) WHERE product_id IS NOT NULL;
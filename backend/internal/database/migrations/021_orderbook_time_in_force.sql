@@ -0,0 +1,7 @@
+-- Time in force for order book orders (GTC, GTD, IOC, FOK)
+
+ALTER TABLE orderbook_orders ADD COLUMN IF NOT EXISTS time_in_force VARCHAR(3) NOT NULL DEFAULT 'GTC';
+ALTER TABLE orderbook_orders ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_orderbook_orders_expires_at ON orderbook_orders(expires_at)
+    WHERE expires_at IS NOT NULL AND status IN ('open', 'partial');
@@ -0,0 +1,8 @@
+-- Stop and stop-limit orders for the order book
+
+ALTER TABLE orderbook_orders ADD COLUMN IF NOT EXISTS stop_price DECIMAL(20, 8) NOT NULL DEFAULT 0;
+ALTER TABLE orderbook_orders ADD COLUMN IF NOT EXISTS triggered_at TIMESTAMP WITH TIME ZONE;
+
+-- Untriggered stop orders are recovered along with resting orders
+DROP INDEX IF EXISTS idx_orderbook_orders_resting;
+CREATE INDEX IF NOT EXISTS idx_orderbook_orders_resting ON orderbook_orders(seq) WHERE status IN ('pending', 'open', 'partial');
@@ -0,0 +1,4 @@
+-- Time range lookups of trades for candles and 24h statistics
+
+CREATE INDEX IF NOT EXISTS idx_orderbook_trades_product_created_at ON orderbook_trades(product_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_orderbook_trades_created_at ON orderbook_trades(created_at);
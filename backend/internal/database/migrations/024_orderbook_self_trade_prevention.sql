@@ -0,0 +1,4 @@
+-- Self-trade prevention: per-order mode and the agent's owner for same-owner checks
+
+ALTER TABLE orderbook_orders ADD COLUMN IF NOT EXISTS stp_mode VARCHAR(20) NOT NULL DEFAULT 'none';
+ALTER TABLE orderbook_orders ADD COLUMN IF NOT EXISTS owner_id UUID;
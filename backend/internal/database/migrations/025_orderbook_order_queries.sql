@@ -0,0 +1,5 @@
+-- Indexes for listing an agent's orders and an order's fills
+
+CREATE INDEX IF NOT EXISTS idx_orderbook_orders_agent_created_at ON orderbook_orders(agent_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_orderbook_trades_buy_order_id ON orderbook_trades(buy_order_id);
+CREATE INDEX IF NOT EXISTS idx_orderbook_trades_sell_order_id ON orderbook_trades(sell_order_id);
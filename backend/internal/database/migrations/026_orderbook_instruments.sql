@@ -0,0 +1,22 @@
+-- Registry of tradable order book instruments; an instrument's id is the product_id of its orders
+
+CREATE TABLE IF NOT EXISTS orderbook_instruments (
+    id UUID PRIMARY KEY,
+    symbol VARCHAR(32) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    category_id UUID REFERENCES categories(id),
+    listing_id UUID REFERENCES listings(id),
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    tick_size DECIMAL(20, 8) NOT NULL,
+    lot_size DECIMAL(20, 8) NOT NULL,
+    min_price DECIMAL(20, 8) NOT NULL DEFAULT 0,
+    max_price DECIMAL(20, 8) NOT NULL DEFAULT 0, -- 0 for no upper bound
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- 'open', 'halted'
+    created_by UUID NOT NULL REFERENCES agents(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (category_id IS NOT NULL OR listing_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_orderbook_instruments_category_id ON orderbook_instruments(category_id);
+CREATE INDEX IF NOT EXISTS idx_orderbook_instruments_listing_id ON orderbook_instruments(listing_id);
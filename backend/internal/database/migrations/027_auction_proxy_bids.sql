@@ -0,0 +1,3 @@
+-- Proxy bidding: the hidden maximum an English auction bidder is auto-bid up to
+
+ALTER TABLE bids ADD COLUMN IF NOT EXISTS max_amount DECIMAL(20, 8);
@@ -0,0 +1,9 @@
+-- Sealed-bid commit-reveal: bids are stored as hashes until revealed after bidding closes
+
+ALTER TABLE auctions ADD COLUMN IF NOT EXISTS commit_reveal BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE auctions ADD COLUMN IF NOT EXISTS reveal_ends_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE bids ADD COLUMN IF NOT EXISTS commitment VARCHAR(64);
+ALTER TABLE bids ADD COLUMN IF NOT EXISTS revealed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_auctions_reveal_ends_at ON auctions(reveal_ends_at) WHERE status = 'revealing';
@@ -0,0 +1,3 @@
+-- Buy-it-now: whether the buy-now price is withdrawn once the auction has a bid
+
+ALTER TABLE auctions ADD COLUMN IF NOT EXISTS buy_now_until_first_bid BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,19 @@
+-- Settlement of won auctions: the winner's transaction and payment deadline, and second-chance offers to runners-up
+
+CREATE TABLE IF NOT EXISTS auction_settlements (
+    id UUID PRIMARY KEY,
+    auction_id UUID NOT NULL REFERENCES auctions(id),
+    bid_id UUID NOT NULL REFERENCES bids(id),
+    bidder_id UUID NOT NULL REFERENCES agents(id),
+    amount DECIMAL(20, 8) NOT NULL,
+    currency VARCHAR(10) NOT NULL DEFAULT 'USD',
+    second_chance BOOLEAN NOT NULL DEFAULT false,
+    status VARCHAR(20) NOT NULL, -- 'offered', 'pending_creation', 'pending', 'funded', 'defaulted', 'declined'
+    transaction_id UUID REFERENCES transactions(id),
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL, -- Payment deadline, when an offer expires, or when creating the transaction is retried
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auction_settlements_auction_id ON auction_settlements(auction_id);
+CREATE INDEX IF NOT EXISTS idx_auction_settlements_due ON auction_settlements(due_at) WHERE status IN ('offered', 'pending', 'pending_creation');
@@ -0,0 +1,7 @@
+-- Multi-unit auctions: units for sale and pricing rule, units wanted per bid and units allocated at close
+
+ALTER TABLE auctions ADD COLUMN IF NOT EXISTS quantity INT NOT NULL DEFAULT 1;
+ALTER TABLE auctions ADD COLUMN IF NOT EXISTS pricing VARCHAR(20) NOT NULL DEFAULT ''; -- 'uniform', 'pay_as_bid' for multi-unit auctions
+
+ALTER TABLE bids ADD COLUMN IF NOT EXISTS quantity INT NOT NULL DEFAULT 1;
+ALTER TABLE bids ADD COLUMN IF NOT EXISTS allocated_quantity INT; -- Set when a multi-unit auction closes
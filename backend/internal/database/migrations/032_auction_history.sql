@@ -0,0 +1,12 @@
+-- Audit history of auctions: edits by the seller, cancellations and bid retractions
+
+CREATE TABLE IF NOT EXISTS auction_history (
+    id UUID PRIMARY KEY,
+    auction_id UUID NOT NULL REFERENCES auctions(id),
+    actor_id UUID NOT NULL REFERENCES agents(id),
+    action VARCHAR(20) NOT NULL, -- 'updated', 'cancelled', 'bid_retracted'
+    changes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auction_history_auction_id ON auction_history(auction_id, created_at);
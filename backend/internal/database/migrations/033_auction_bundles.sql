@@ -0,0 +1,14 @@
+-- Bundle auctions: the items for sale, and the subset of them each bid is for
+
+CREATE TABLE IF NOT EXISTS auction_items (
+    id UUID PRIMARY KEY,
+    auction_id UUID NOT NULL REFERENCES auctions(id),
+    listing_id UUID REFERENCES listings(id),
+    title VARCHAR(500) NOT NULL,
+    description TEXT,
+    position INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_auction_items_auction_id ON auction_items(auction_id, position);
+
+ALTER TABLE bids ADD COLUMN IF NOT EXISTS item_ids UUID[]; -- Bundle auctions: the items the bid is for
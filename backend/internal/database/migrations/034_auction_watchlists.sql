@@ -0,0 +1,22 @@
+-- Auction watchlists and saved auction searches that alert agents when a matching auction starts
+
+CREATE TABLE IF NOT EXISTS auction_watches (
+    agent_id UUID NOT NULL REFERENCES agents(id),
+    auction_id UUID NOT NULL REFERENCES auctions(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (agent_id, auction_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auction_watches_auction_id ON auction_watches(auction_id);
+
+CREATE TABLE IF NOT EXISTS auction_saved_searches (
+    id UUID PRIMARY KEY,
+    agent_id UUID NOT NULL REFERENCES agents(id),
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    auction_type VARCHAR(20), -- NULL matches every type
+    query VARCHAR(255) NOT NULL DEFAULT '',
+    seller_id UUID REFERENCES agents(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auction_saved_searches_agent_id ON auction_saved_searches(agent_id);
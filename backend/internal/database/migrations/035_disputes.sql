@@ -0,0 +1,40 @@
+-- Transaction disputes with an evidence thread, a response deadline and arbitrated outcomes
+
+CREATE TABLE IF NOT EXISTS disputes (
+    id UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL UNIQUE REFERENCES transactions(id),
+    opened_by UUID NOT NULL REFERENCES agents(id),
+    respondent_id UUID NOT NULL REFERENCES agents(id),
+    reason VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- 'open', 'arbitration', 'resolving', 'resolved'
+    response_due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    responded_at TIMESTAMP WITH TIME ZONE,
+    outcome VARCHAR(20), -- 'release', 'refund', 'split'
+    seller_percent DECIMAL(5, 2),
+    seller_amount DECIMAL(20, 8),
+    refund_amount DECIMAL(20, 8),
+    winner_id UUID REFERENCES agents(id), -- NULL for an even split
+    loser_id UUID REFERENCES agents(id),
+    resolved_by UUID REFERENCES agents(id), -- NULL when resolved by default
+    resolution_note TEXT NOT NULL DEFAULT '',
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_disputes_status_response_due_at ON disputes(status, response_due_at);
+CREATE INDEX IF NOT EXISTS idx_disputes_winner_id ON disputes(winner_id);
+CREATE INDEX IF NOT EXISTS idx_disputes_loser_id ON disputes(loser_id);
+
+CREATE TABLE IF NOT EXISTS dispute_evidence (
+    id UUID PRIMARY KEY,
+    dispute_id UUID NOT NULL REFERENCES disputes(id),
+    author_id UUID NOT NULL REFERENCES agents(id),
+    role VARCHAR(20) NOT NULL, -- 'buyer', 'seller', 'arbitrator'
+    body TEXT NOT NULL DEFAULT '',
+    attachments TEXT[] NOT NULL DEFAULT '{}', -- URLs of supporting files
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispute_evidence_dispute_id ON dispute_evidence(dispute_id, created_at);
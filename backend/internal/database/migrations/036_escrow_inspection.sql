@@ -0,0 +1,15 @@
+-- Inspection windows: escrow is released automatically if the buyer neither confirms
+-- delivery nor opens a dispute before the window after delivery ends
+
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS inspection_window_hours INTEGER; -- NULL uses the parent's or the platform default
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS inspection_window_hours INTEGER; -- NULL uses the category default
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS inspection_ends_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS inspection_warned_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_inspection_ends_at ON transactions(inspection_ends_at) WHERE status = 'delivered';
+
+-- Physical goods take longer to check than digital deliveries
+UPDATE categories SET inspection_window_hours = 168 WHERE slug = 'goods-physical' AND inspection_window_hours IS NULL;
+UPDATE categories SET inspection_window_hours = 24 WHERE slug = 'data-realtime' AND inspection_window_hours IS NULL;
@@ -0,0 +1,44 @@
+-- Milestone escrow: a deal paid in ordered stages, each delivered, confirmed and
+-- released on its own out of a single escrow
+
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS milestones JSONB NOT NULL DEFAULT '[]'; -- [{title, description, amount}]
+
+CREATE TABLE IF NOT EXISTS transaction_milestones (
+    id UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    position INTEGER NOT NULL, -- 1-based, milestones are delivered in order
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    amount DECIMAL(20, 8) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'delivered', 'disputed', 'releasing', 'released', 'refunded'
+    delivery_proof TEXT NOT NULL DEFAULT '',
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    inspection_ends_at TIMESTAMP WITH TIME ZONE,
+    released_amount DECIMAL(20, 8) NOT NULL DEFAULT 0, -- Less than amount after a split dispute
+    settled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (transaction_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_milestones_inspection_ends_at ON transaction_milestones(inspection_ends_at) WHERE status = 'delivered';
+
+-- Escrow tracks how much of the funded amount has been paid out and how much is still held
+ALTER TABLE escrow_accounts ADD COLUMN IF NOT EXISTS funded_amount DECIMAL(20, 8) NOT NULL DEFAULT 0;
+ALTER TABLE escrow_accounts ADD COLUMN IF NOT EXISTS released_amount DECIMAL(20, 8) NOT NULL DEFAULT 0;
+ALTER TABLE escrow_accounts ADD COLUMN IF NOT EXISTS remaining_amount DECIMAL(20, 8) NOT NULL DEFAULT 0;
+
+UPDATE escrow_accounts SET funded_amount = amount, remaining_amount = amount
+WHERE funded_at IS NOT NULL AND status IN ('funded', 'disputed');
+UPDATE escrow_accounts SET funded_amount = amount, released_amount = amount
+WHERE funded_at IS NOT NULL AND status = 'released';
+UPDATE escrow_accounts SET funded_amount = amount
+WHERE funded_at IS NOT NULL AND status = 'refunded';
+
+-- A milestone can be disputed on its own; a transaction still has at most one
+-- dispute of its own and each milestone at most one
+ALTER TABLE disputes ADD COLUMN IF NOT EXISTS milestone_id UUID REFERENCES transaction_milestones(id);
+ALTER TABLE disputes DROP CONSTRAINT IF EXISTS disputes_transaction_id_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_disputes_transaction_id ON disputes(transaction_id) WHERE milestone_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_disputes_milestone_id ON disputes(milestone_id) WHERE milestone_id IS NOT NULL;
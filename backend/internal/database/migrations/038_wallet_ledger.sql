@@ -0,0 +1,123 @@
+-- Wallet ledger: double-entry accounts for agents and owners, an immutable
+-- journal, escrow holds, and withdrawals paid out via Stripe Connect
+
+-- An account holds money for an agent or user wallet, or is one of the
+-- platform's own accounts: 'escrow' (held for open transactions), 'fees'
+-- (platform fees earned) and 'stripe' (the counterparty for money moving in
+-- and out through Stripe). Balances are the sum of an account's postings.
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id UUID PRIMARY KEY,
+    owner_type VARCHAR(20) NOT NULL, -- 'agent', 'user', 'platform'
+    owner_id UUID, -- NULL for platform accounts
+    kind VARCHAR(20) NOT NULL, -- 'wallet', 'escrow', 'fees', 'stripe'
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_accounts_owner ON ledger_accounts(owner_type, COALESCE(owner_id, '00000000-0000-0000-0000-000000000000'), kind, currency);
+
+-- Deposits fund wallets through Stripe. Migration 013 created this table and
+-- 018 dropped it before anything used it; same shape as in 013.
+CREATE TABLE IF NOT EXISTS wallet_deposits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    agent_id UUID REFERENCES agents(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    stripe_payment_intent_id VARCHAR(255),
+    stripe_client_secret VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'completed', 'failed'
+    failure_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT wallet_deposits_owner_check CHECK (
+        (user_id IS NOT NULL AND agent_id IS NULL) OR
+        (user_id IS NULL AND agent_id IS NOT NULL)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_deposits_user_id ON wallet_deposits(user_id) WHERE user_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_wallet_deposits_agent_id ON wallet_deposits(agent_id) WHERE agent_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_wallet_deposits_stripe_pi ON wallet_deposits(stripe_payment_intent_id);
+
+-- Withdrawals pay wallet balances out to the owner's Stripe Connect account
+CREATE TABLE IF NOT EXISTS wallet_withdrawals (
+    id UUID PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    agent_id UUID REFERENCES agents(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    stripe_transfer_id VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'completed', 'failed'
+    failure_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT wallet_withdrawals_owner_check CHECK (
+        (user_id IS NOT NULL AND agent_id IS NULL) OR
+        (user_id IS NULL AND agent_id IS NOT NULL)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_withdrawals_user_id ON wallet_withdrawals(user_id) WHERE user_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_wallet_withdrawals_agent_id ON wallet_withdrawals(agent_id) WHERE agent_id IS NOT NULL;
+
+-- A journal entry groups postings that sum to zero
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(30) NOT NULL, -- 'deposit', 'withdrawal', 'withdrawal_reversal', 'escrow_hold', 'escrow_release', 'payout'
+    description TEXT NOT NULL DEFAULT '',
+    transaction_id UUID REFERENCES transactions(id),
+    deposit_id UUID REFERENCES wallet_deposits(id),
+    withdrawal_id UUID REFERENCES wallet_withdrawals(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON ledger_entries(transaction_id) WHERE transaction_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS ledger_postings (
+    id UUID PRIMARY KEY,
+    entry_id UUID NOT NULL REFERENCES ledger_entries(id),
+    account_id UUID NOT NULL REFERENCES ledger_accounts(id),
+    amount DECIMAL(20, 2) NOT NULL, -- Positive credits the account, negative debits it
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_entry_id ON ledger_postings(entry_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_account_id ON ledger_postings(account_id, created_at DESC);
+
+-- The journal is append-only: corrections are new, reversing entries
+CREATE OR REPLACE FUNCTION prevent_ledger_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger entries are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_immutable ON ledger_entries;
+CREATE TRIGGER ledger_entries_immutable
+    BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION prevent_ledger_changes();
+
+DROP TRIGGER IF EXISTS ledger_postings_immutable ON ledger_postings;
+CREATE TRIGGER ledger_postings_immutable
+    BEFORE UPDATE OR DELETE ON ledger_postings
+    FOR EACH ROW EXECUTE FUNCTION prevent_ledger_changes();
+
+-- A hold is the money a transaction has in the escrow account, and where it
+-- came from: the buyer's wallet, or the 'stripe' account for card payments
+CREATE TABLE IF NOT EXISTS ledger_holds (
+    id UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL UNIQUE REFERENCES transactions(id),
+    source_account_id UUID NOT NULL REFERENCES ledger_accounts(id),
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    amount DECIMAL(20, 2) NOT NULL,
+    remaining DECIMAL(20, 2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'held', -- 'held', 'settled'
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    settled_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_holds_source ON ledger_holds(source_account_id) WHERE status = 'held';
@@ -0,0 +1,26 @@
+-- Refunds on a transaction after its escrow was paid out: partial refunds
+-- issued by the seller, and refund requests from the buyer that the seller
+-- accepts or rejects. Completed refunds never add up to more than was paid out.
+
+CREATE TABLE IF NOT EXISTS transaction_refunds (
+    id UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    requested_by UUID NOT NULL REFERENCES agents(id),
+    amount DECIMAL(20, 8) NOT NULL,
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL, -- 'requested', 'processing', 'completed', 'rejected', 'failed'
+    stripe_refund_id VARCHAR(255),
+    response_note TEXT NOT NULL DEFAULT '', -- Seller's note when rejecting a request
+    responded_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_refunds_transaction_id ON transaction_refunds(transaction_id, created_at);
+
+-- A buyer has at most one refund request waiting on the seller per transaction
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transaction_refunds_open_request ON transaction_refunds(transaction_id) WHERE status = 'requested';
+
+-- Refunds of escrow already paid out are posted to the wallet ledger as
+-- ledger_entries of kind 'refund'
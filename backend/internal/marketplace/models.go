@@ -138,10 +138,20 @@ type Offer struct {
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 
+	// Payment in stages; the accepted offer's milestones carry over to the transaction
+	Milestones []OfferMilestone `json:"milestones,omitempty"`
+
 	// Enriched fields (from agent join)
 	OffererName string `json:"offerer_name,omitempty"`
 }
 
+// OfferMilestone is one stage of an offer paid in milestones.
+type OfferMilestone struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description,omitempty"`
+	Amount      float64 `json:"amount"`
+}
+
 // Category represents a category in the taxonomy.
 type Category struct {
 	ID          uuid.UUID      `json:"id"`
@@ -192,12 +202,13 @@ type CreateRequestRequest struct {
 
 // CreateOfferRequest is the request body for submitting an offer.
 type CreateOfferRequest struct {
-	ListingID     *uuid.UUID `json:"listing_id,omitempty"`
-	PriceAmount   float64    `json:"price_amount"`
-	PriceCurrency string     `json:"price_currency,omitempty"`
-	Description   string     `json:"description,omitempty"`
-	DeliveryTerms string     `json:"delivery_terms,omitempty"`
-	ValidUntil    *time.Time `json:"valid_until,omitempty"`
+	ListingID     *uuid.UUID       `json:"listing_id,omitempty"`
+	PriceAmount   float64          `json:"price_amount"`
+	PriceCurrency string           `json:"price_currency,omitempty"`
+	Description   string           `json:"description,omitempty"`
+	DeliveryTerms string           `json:"delivery_terms,omitempty"`
+	ValidUntil    *time.Time       `json:"valid_until,omitempty"`
+	Milestones    []OfferMilestone `json:"milestones,omitempty"` // Amounts must add up to price_amount
 }
 
 // SearchListingsParams contains search/filter parameters for listings.
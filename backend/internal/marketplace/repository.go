@@ -605,16 +605,20 @@ func (r *Repository) CreateOffer(ctx context.Context, offer *Offer) error {
 	query := `
 		INSERT INTO offers (
 			id, request_id, offerer_id, listing_id, price_amount, price_currency,
-			description, delivery_terms, valid_until, status, metadata,
+			description, delivery_terms, valid_until, status, metadata, milestones,
 			created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
 		)
 	`
+	milestones := offer.Milestones
+	if milestones == nil {
+		milestones = []OfferMilestone{}
+	}
 	_, err := r.pool.Exec(ctx, query,
 		offer.ID, offer.RequestID, offer.OffererID, offer.ListingID,
 		offer.PriceAmount, offer.PriceCurrency, offer.Description,
-		offer.DeliveryTerms, offer.ValidUntil, offer.Status, offer.Metadata,
+		offer.DeliveryTerms, offer.ValidUntil, offer.Status, offer.Metadata, milestones,
 		offer.CreatedAt, offer.UpdatedAt,
 	)
 	return err
@@ -624,7 +628,7 @@ func (r *Repository) CreateOffer(ctx context.Context, offer *Offer) error {
 func (r *Repository) GetOfferByID(ctx context.Context, id uuid.UUID) (*Offer, error) {
 	query := `
 		SELECT id, request_id, offerer_id, listing_id, price_amount, price_currency,
-			description, delivery_terms, valid_until, status, metadata,
+			description, delivery_terms, valid_until, status, metadata, milestones,
 			created_at, updated_at
 		FROM offers WHERE id = $1
 	`
@@ -633,7 +637,7 @@ func (r *Repository) GetOfferByID(ctx context.Context, id uuid.UUID) (*Offer, er
 		&offer.ID, &offer.RequestID, &offer.OffererID, &offer.ListingID,
 		&offer.PriceAmount, &offer.PriceCurrency, &offer.Description,
 		&offer.DeliveryTerms, &offer.ValidUntil, &offer.Status, &offer.Metadata,
-		&offer.CreatedAt, &offer.UpdatedAt,
+		&offer.Milestones, &offer.CreatedAt, &offer.UpdatedAt,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrOfferNotFound
@@ -645,7 +649,7 @@ func (r *Repository) GetOfferByID(ctx context.Context, id uuid.UUID) (*Offer, er
 func (r *Repository) GetOffersByRequestID(ctx context.Context, requestID uuid.UUID) ([]Offer, error) {
 	query := `
 		SELECT o.id, o.request_id, o.offerer_id, o.listing_id, o.price_amount, o.price_currency,
-			o.description, o.delivery_terms, o.valid_until, o.status, o.metadata,
+			o.description, o.delivery_terms, o.valid_until, o.status, o.metadata, o.milestones,
 			o.created_at, o.updated_at,
 			COALESCE(a.name, '') AS offerer_name
 		FROM offers o
@@ -665,7 +669,7 @@ func (r *Repository) GetOffersByRequestID(ctx context.Context, requestID uuid.UU
 		if err := rows.Scan(
 			&o.ID, &o.RequestID, &o.OffererID, &o.ListingID, &o.PriceAmount,
 			&o.PriceCurrency, &o.Description, &o.DeliveryTerms, &o.ValidUntil,
-			&o.Status, &o.Metadata, &o.CreatedAt, &o.UpdatedAt,
+			&o.Status, &o.Metadata, &o.Milestones, &o.CreatedAt, &o.UpdatedAt,
 			&o.OffererName,
 		); err != nil {
 			return nil, err
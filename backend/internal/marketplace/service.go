@@ -3,6 +3,7 @@ package marketplace
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -360,6 +361,9 @@ func (s *Service) SubmitOffer(ctx context.Context, offererID uuid.UUID, requestI
 	if req.PriceAmount <= 0 {
 		return nil, fmt.Errorf("price_amount must be positive")
 	}
+	if err := validateOfferMilestones(req.Milestones, req.PriceAmount); err != nil {
+		return nil, err
+	}
 
 	now := time.Now().UTC()
 	offer := &Offer{
@@ -373,6 +377,7 @@ func (s *Service) SubmitOffer(ctx context.Context, offererID uuid.UUID, requestI
 		DeliveryTerms: req.DeliveryTerms,
 		ValidUntil:    req.ValidUntil,
 		Status:        OfferStatusPending,
+		Milestones:    req.Milestones,
 		CreatedAt:     now,
 		UpdatedAt:     now,
 	}
@@ -395,6 +400,35 @@ func (s *Service) SubmitOffer(ctx context.Context, offererID uuid.UUID, requestI
 	return offer, nil
 }
 
+// maxOfferMilestones is the most milestones an offer can be split into.
+const maxOfferMilestones = 20
+
+// validateOfferMilestones checks that an offer's milestones each have a title
+// and a positive amount and add up to the offer price.
+func validateOfferMilestones(milestones []OfferMilestone, price float64) error {
+	if len(milestones) == 0 {
+		return nil
+	}
+	if len(milestones) > maxOfferMilestones {
+		return fmt.Errorf("an offer can have at most %d milestones", maxOfferMilestones)
+	}
+
+	var total float64
+	for _, m := range milestones {
+		if m.Title == "" {
+			return fmt.Errorf("milestones need a title")
+		}
+		if m.Amount <= 0 {
+			return fmt.Errorf("milestone amounts must be positive")
+		}
+		total += m.Amount
+	}
+	if math.Abs(total-price) >= 0.005 {
+		return fmt.Errorf("milestone amounts must add up to price_amount")
+	}
+	return nil
+}
+
 // GetOffersByRequest retrieves all offers for a request.
 func (s *Service) GetOffersByRequest(ctx context.Context, requestID uuid.UUID) ([]Offer, error) {
 	return s.repo.GetOffersByRequestID(ctx, requestID)
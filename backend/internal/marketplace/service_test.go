@@ -1276,6 +1276,47 @@ func TestService_SubmitOffer_InvalidPrice(t *testing.T) {
 	}
 }
 
+func TestService_SubmitOffer_Milestones(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+
+	request, _ := service.CreateRequest(context.Background(), uuid.New(), &CreateRequestRequest{
+		Title:       "Build a scraper",
+		RequestType: ListingTypeServices,
+	})
+
+	tests := []struct {
+		name       string
+		milestones []OfferMilestone
+		wantErr    bool
+	}{
+		{"adds up", []OfferMilestone{{Title: "Prototype", Amount: 40}, {Title: "Production", Amount: 60}}, false},
+		{"short of price", []OfferMilestone{{Title: "Prototype", Amount: 40}, {Title: "Production", Amount: 50}}, true},
+		{"missing title", []OfferMilestone{{Amount: 40}, {Title: "Production", Amount: 60}}, true},
+		{"zero amount", []OfferMilestone{{Title: "Prototype", Amount: 0}, {Title: "Production", Amount: 100}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offer, err := service.SubmitOffer(context.Background(), uuid.New(), request.ID, &CreateOfferRequest{
+				PriceAmount: 100.0,
+				Milestones:  tt.milestones,
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(offer.Milestones) != 2 {
+				t.Errorf("expected 2 milestones, got %d", len(offer.Milestones))
+			}
+		})
+	}
+}
+
 func TestService_GetOffersByRequest(t *testing.T) {
 	repo := newMockRepository()
 	service := NewService(repo, nil)
@@ -0,0 +1,445 @@
+package matching
+
+import (
+	"container/list"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// maxSkipHeight bounds the height of price level skip lists (enough for millions of levels).
+const maxSkipHeight = 24
+
+// priceLevel is a FIFO queue of resting orders at a single price.
+type priceLevel struct {
+	price    float64
+	quantity float64    // Sum of remaining quantity of all orders
+	orders   *list.List // *Order values in time priority
+}
+
+// front returns the order with time priority at this level.
+func (l *priceLevel) front() *Order {
+	return l.orders.Front().Value.(*Order)
+}
+
+// skipNode is a node of a price level skip list.
+type skipNode struct {
+	level *priceLevel
+	next  []*skipNode
+}
+
+// levelList keeps the price levels of one book side ordered best price first.
+// Insert, remove and best-price lookups are O(log n) / O(1) in the number of
+// levels, and iterating the top N levels is O(N).
+type levelList struct {
+	head   *skipNode
+	height int
+	better func(a, b float64) bool // Reports whether price a sorts before price b
+	rnd    *rand.Rand
+}
+
+func newLevelList(better func(a, b float64) bool) *levelList {
+	return &levelList{
+		head:   &skipNode{next: make([]*skipNode, maxSkipHeight)},
+		height: 1,
+		better: better,
+		rnd:    rand.New(rand.NewSource(1)), // Fixed seed: structure only, never affects ordering
+	}
+}
+
+// randomHeight picks a node height with P(h) = 1/2^h.
+func (s *levelList) randomHeight() int {
+	h := 1
+	for h < maxSkipHeight && s.rnd.Int63()&1 == 1 {
+		h++
+	}
+	return h
+}
+
+// insert adds a price level. The price must not already be in the list.
+func (s *levelList) insert(level *priceLevel) {
+	var update [maxSkipHeight]*skipNode
+	node := s.head
+	for i := s.height - 1; i >= 0; i-- {
+		for node.next[i] != nil && s.better(node.next[i].level.price, level.price) {
+			node = node.next[i]
+		}
+		update[i] = node
+	}
+
+	h := s.randomHeight()
+	if h > s.height {
+		for i := s.height; i < h; i++ {
+			update[i] = s.head
+		}
+		s.height = h
+	}
+
+	n := &skipNode{level: level, next: make([]*skipNode, h)}
+	for i := 0; i < h; i++ {
+		n.next[i] = update[i].next[i]
+		update[i].next[i] = n
+	}
+}
+
+// remove deletes the level at a price, if present.
+func (s *levelList) remove(price float64) {
+	var update [maxSkipHeight]*skipNode
+	node := s.head
+	for i := s.height - 1; i >= 0; i-- {
+		for node.next[i] != nil && s.better(node.next[i].level.price, price) {
+			node = node.next[i]
+		}
+		update[i] = node
+	}
+
+	target := node.next[0]
+	if target == nil || target.level.price != price {
+		return
+	}
+	for i := 0; i < len(target.next); i++ {
+		update[i].next[i] = target.next[i]
+	}
+	for s.height > 1 && s.head.next[s.height-1] == nil {
+		s.height--
+	}
+}
+
+// best returns the level with the best price, or nil if the side is empty.
+func (s *levelList) best() *priceLevel {
+	if n := s.head.next[0]; n != nil {
+		return n.level
+	}
+	return nil
+}
+
+// each calls fn for every level, best price first, until fn returns false.
+func (s *levelList) each(fn func(*priceLevel) bool) {
+	for n := s.head.next[0]; n != nil; n = n.next[0] {
+		if !fn(n.level) {
+			return
+		}
+	}
+}
+
+// bookSide is one side (bids or asks) of a product's book.
+type bookSide struct {
+	levels map[float64]*priceLevel
+	sorted *levelList
+	dirty  map[float64]struct{} // Prices changed since the last flush
+}
+
+func newBookSide(better func(a, b float64) bool) *bookSide {
+	return &bookSide{
+		levels: make(map[float64]*priceLevel),
+		sorted: newLevelList(better),
+		dirty:  make(map[float64]struct{}),
+	}
+}
+
+// bookEntry locates a resting order within its book.
+type bookEntry struct {
+	order *Order
+	level *priceLevel   // nil for untriggered stop orders
+	elem  *list.Element // Position within level.orders
+}
+
+// book is the order book of a single product. Every book has its own lock,
+// so products are matched independently of each other.
+//
+// Mutations made while a match is journaled (between begin and commit) record
+// their inverse, so a match that fails to persist can be rolled back exactly.
+// Order field changes are not journaled; callers restore those from fills.
+type book struct {
+	mu        sync.RWMutex
+	productID uuid.UUID
+	bids      *bookSide                // Highest price first
+	asks      *bookSide                // Lowest price first
+	stops     []*Order                 // Untriggered stop orders, arrival order
+	orders    map[uuid.UUID]*bookEntry // Resting and stop orders by ID
+	expiring  map[uuid.UUID]*Order     // GTD orders, resting or stop
+	lastPrice float64
+	hasPrice  bool
+	stats     *tradeStats // Rolling 24h statistics, nil until the first trade
+	index     *sync.Map   // Engine-wide OrderID -> *book
+	stp       *stpPolicy  // Engine-wide self-trade prevention settings
+
+	journaling bool
+	undo       []func()
+}
+
+func newBook(productID uuid.UUID, index *sync.Map, stp *stpPolicy) *book {
+	return &book{
+		productID: productID,
+		bids:      newBookSide(func(a, b float64) bool { return a > b }),
+		asks:      newBookSide(func(a, b float64) bool { return a < b }),
+		orders:    make(map[uuid.UUID]*bookEntry),
+		expiring:  make(map[uuid.UUID]*Order),
+		index:     index,
+		stp:       stp,
+	}
+}
+
+// side returns the side an order of the given direction rests on.
+func (b *book) side(side OrderSide) *bookSide {
+	if side == OrderSideBuy {
+		return b.bids
+	}
+	return b.asks
+}
+
+// opposite returns the side an order of the given direction matches against.
+func (b *book) opposite(side OrderSide) *bookSide {
+	if side == OrderSideBuy {
+		return b.asks
+	}
+	return b.bids
+}
+
+// touch marks a price level as changed for the next flush.
+func (s *bookSide) touch(price float64) {
+	s.dirty[price] = struct{}{}
+}
+
+// changes returns the current state of every level touched since the last
+// flush, best price first. Levels that no longer exist have zero quantity.
+func (s *bookSide) changes() []PriceLevel {
+	var levels []PriceLevel
+	for price := range s.dirty {
+		change := PriceLevel{Price: price}
+		if level, ok := s.levels[price]; ok {
+			change.Quantity = level.quantity
+			change.Orders = level.orders.Len()
+		}
+		levels = append(levels, change)
+		delete(s.dirty, price)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return s.sorted.better(levels[i].Price, levels[j].Price)
+	})
+	return levels
+}
+
+// best returns the aggregated best level of a side, or nil if it is empty.
+func (s *bookSide) best() *PriceLevel {
+	level := s.sorted.best()
+	if level == nil {
+		return nil
+	}
+	return &PriceLevel{Price: level.price, Quantity: level.quantity, Orders: level.orders.Len()}
+}
+
+// flush collects the level changes since the last flush into an update.
+func (b *book) flush(trades []Trade) BookUpdate {
+	return BookUpdate{
+		ProductID: b.productID,
+		Bids:      b.bids.changes(),
+		Asks:      b.asks.changes(),
+		Trades:    trades,
+		BestBid:   b.bids.best(),
+		BestAsk:   b.asks.best(),
+	}
+}
+
+// discard forgets level changes that were rolled back.
+func (b *book) discard() {
+	clear(b.bids.dirty)
+	clear(b.asks.dirty)
+}
+
+// begin starts journaling mutations.
+func (b *book) begin() {
+	b.journaling = true
+	b.undo = b.undo[:0]
+}
+
+// commit stops journaling and keeps all mutations.
+func (b *book) commit() {
+	b.journaling = false
+	b.undo = b.undo[:0]
+}
+
+// rollback reverts every journaled mutation, newest first.
+func (b *book) rollback() {
+	for i := len(b.undo) - 1; i >= 0; i-- {
+		b.undo[i]()
+	}
+	b.commit()
+}
+
+// journal records the inverse of a mutation while journaling.
+func (b *book) journal(undo func()) {
+	if b.journaling {
+		b.undo = append(b.undo, undo)
+	}
+}
+
+// track indexes an order so it can be found by ID.
+func (b *book) track(entry *bookEntry) {
+	b.orders[entry.order.ID] = entry
+	b.index.Store(entry.order.ID, b)
+	if entry.order.TimeInForce == TimeInForceGTD {
+		b.expiring[entry.order.ID] = entry.order
+	}
+}
+
+// untrack removes an order from the indexes.
+func (b *book) untrack(order *Order) {
+	delete(b.orders, order.ID)
+	delete(b.expiring, order.ID)
+	b.index.Delete(order.ID)
+}
+
+// add rests an order at the back of its price level.
+func (b *book) add(order *Order) {
+	s := b.side(order.Side)
+	level, ok := s.levels[order.Price]
+	if !ok {
+		level = &priceLevel{price: order.Price, orders: list.New()}
+		s.levels[order.Price] = level
+		s.sorted.insert(level)
+	}
+	elem := level.orders.PushBack(order)
+	level.quantity += order.RemainingQty
+	s.touch(order.Price)
+	b.track(&bookEntry{order: order, level: level, elem: elem})
+
+	qty := order.RemainingQty
+	b.journal(func() { b.removeQty(order, qty) })
+}
+
+// remove takes a resting order off the book wherever it is in its level.
+// It is not journaled: cancels and expiries persist before they touch the book.
+func (b *book) remove(order *Order) {
+	b.removeQty(order, order.RemainingQty)
+}
+
+// take removes a resting order from the book as a journaled step, so a
+// rollback puts it back at the same position in its level.
+func (b *book) take(order *Order) {
+	entry, ok := b.orders[order.ID]
+	if !ok || entry.level == nil {
+		return
+	}
+	level, s := entry.level, b.side(order.Side)
+	pos := 0
+	for el := level.orders.Front(); el != entry.elem; el = el.Next() {
+		pos++
+	}
+	qty := order.RemainingQty
+	b.removeQty(order, qty)
+	dropped := s.levels[level.price] != level
+
+	b.journal(func() {
+		if dropped {
+			s.levels[level.price] = level
+			s.sorted.insert(level)
+		}
+		// Elements after it may have been replaced since, so find the spot by position
+		var elem *list.Element
+		if pos == 0 {
+			elem = level.orders.PushFront(order)
+		} else {
+			mark := level.orders.Front()
+			for i := 1; i < pos && mark.Next() != nil; i++ {
+				mark = mark.Next()
+			}
+			elem = level.orders.InsertAfter(order, mark)
+		}
+		level.quantity += qty
+		s.touch(level.price)
+		b.track(&bookEntry{order: order, level: level, elem: elem})
+	})
+}
+
+// removeQty removes a resting order that contributes qty to its level.
+func (b *book) removeQty(order *Order, qty float64) {
+	entry, ok := b.orders[order.ID]
+	if !ok || entry.level == nil {
+		return
+	}
+	level := entry.level
+	level.orders.Remove(entry.elem)
+	level.quantity -= qty
+	b.side(order.Side).touch(level.price)
+	b.untrack(order)
+	b.dropIfEmpty(b.side(order.Side), level)
+}
+
+// popFront removes the first order of a level during matching.
+func (b *book) popFront(s *bookSide, level *priceLevel) *Order {
+	order := level.orders.Remove(level.orders.Front()).(*Order)
+	qty := order.RemainingQty
+	level.quantity -= qty
+	s.touch(level.price)
+	b.untrack(order)
+	dropped := b.dropIfEmpty(s, level)
+
+	b.journal(func() {
+		if dropped {
+			s.levels[level.price] = level
+			s.sorted.insert(level)
+		}
+		elem := level.orders.PushFront(order)
+		level.quantity += qty
+		b.track(&bookEntry{order: order, level: level, elem: elem})
+	})
+	return order
+}
+
+// reduceLevel lowers a level's aggregate quantity after a fill.
+func (b *book) reduceLevel(s *bookSide, level *priceLevel, qty float64) {
+	level.quantity -= qty
+	s.touch(level.price)
+	b.journal(func() { level.quantity += qty })
+}
+
+// dropIfEmpty removes a level with no orders left, reporting whether it did.
+func (b *book) dropIfEmpty(s *bookSide, level *priceLevel) bool {
+	if level.orders.Len() > 0 {
+		return false
+	}
+	delete(s.levels, level.price)
+	s.sorted.remove(level.price)
+	return true
+}
+
+// addStop parks an untriggered stop order.
+func (b *book) addStop(order *Order) {
+	b.stops = append(b.stops, order)
+	b.track(&bookEntry{order: order})
+
+	b.journal(func() {
+		b.stops = b.stops[:len(b.stops)-1]
+		b.untrack(order)
+	})
+}
+
+// removeStop takes a stop order off the book, returning whether it was there.
+func (b *book) removeStop(order *Order) bool {
+	for i, stop := range b.stops {
+		if stop != order {
+			continue
+		}
+		b.stops = append(b.stops[:i], b.stops[i+1:]...)
+		b.untrack(order)
+
+		b.journal(func() {
+			b.stops = append(b.stops, nil)
+			copy(b.stops[i+1:], b.stops[i:])
+			b.stops[i] = order
+			b.track(&bookEntry{order: order})
+		})
+		return true
+	}
+	return false
+}
+
+// setLastPrice records the price of the latest trade.
+func (b *book) setLastPrice(price float64) {
+	prev, hadPrice := b.lastPrice, b.hasPrice
+	b.lastPrice, b.hasPrice = price, true
+	b.journal(func() { b.lastPrice, b.hasPrice = prev, hadPrice })
+}
@@ -0,0 +1,328 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestLevelListOrdering(t *testing.T) {
+	bids := newLevelList(func(a, b float64) bool { return a > b })
+	for _, price := range []float64{101, 99, 105, 100, 103} {
+		bids.insert(&priceLevel{price: price})
+	}
+	bids.remove(100)
+	bids.remove(42) // Not present
+
+	var got []float64
+	bids.each(func(level *priceLevel) bool {
+		got = append(got, level.price)
+		return true
+	})
+
+	want := []float64{105, 103, 101, 99}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("levels = %v, want %v", got, want)
+	}
+	if best := bids.best(); best == nil || best.price != 105 {
+		t.Errorf("best = %v, want 105", best)
+	}
+}
+
+func TestLevelListManyLevels(t *testing.T) {
+	asks := newLevelList(func(a, b float64) bool { return a < b })
+	for i := 5000; i > 0; i-- {
+		asks.insert(&priceLevel{price: float64(i)})
+	}
+	for i := 1; i <= 5000; i += 2 {
+		asks.remove(float64(i))
+	}
+
+	prev, count := 0.0, 0
+	asks.each(func(level *priceLevel) bool {
+		if level.price <= prev {
+			t.Fatalf("level %v after %v", level.price, prev)
+		}
+		prev = level.price
+		count++
+		return true
+	})
+	if count != 2500 {
+		t.Errorf("count = %d, want 2500", count)
+	}
+	if best := asks.best(); best.price != 2 {
+		t.Errorf("best = %v, want 2", best.price)
+	}
+}
+
+func TestOrderBookAggregatesLevels(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+
+	for _, price := range []float64{100, 100, 99} {
+		engine.PlaceOrder(context.Background(), &Order{
+			AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy,
+			Type: OrderTypeLimit, Price: price, Quantity: 5,
+		})
+	}
+	engine.PlaceOrder(context.Background(), &Order{
+		AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell,
+		Type: OrderTypeLimit, Price: 100, Quantity: 7,
+	})
+
+	book := engine.GetOrderBook(productID, 10)
+	if len(book.Bids) != 2 {
+		t.Fatalf("len(Bids) = %d, want 2", len(book.Bids))
+	}
+	if book.Bids[0].Price != 100 || book.Bids[0].Quantity != 3 || book.Bids[0].Orders != 1 {
+		t.Errorf("Bids[0] = %+v, want {100 3 1}", book.Bids[0])
+	}
+	if book.Bids[1].Price != 99 || book.Bids[1].Quantity != 5 {
+		t.Errorf("Bids[1] = %+v, want {99 5 1}", book.Bids[1])
+	}
+}
+
+func TestGetOrderBookUnknownProduct(t *testing.T) {
+	engine := NewEngine(nil)
+
+	book := engine.GetOrderBook(uuid.New(), 10)
+	if len(book.Bids) != 0 || len(book.Asks) != 0 || book.LastPrice != nil {
+		t.Errorf("book = %+v, want empty", book)
+	}
+	if len(engine.books) != 0 {
+		t.Error("GetOrderBook should not create a book")
+	}
+}
+
+func TestRollbackRestoresBook(t *testing.T) {
+	repo := newMockRepository()
+	engine := NewEngine(nil)
+	engine.SetRepository(repo)
+	productID := uuid.New()
+
+	for _, price := range []float64{100, 101, 101} {
+		engine.PlaceOrder(context.Background(), &Order{
+			AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell,
+			Type: OrderTypeLimit, Price: price, Quantity: 2,
+		})
+	}
+	before := engine.GetOrderBook(productID, 0)
+
+	// Sweeps every level and rests the remainder at 102
+	repo.saveErr = errors.New("db down")
+	_, err := engine.PlaceOrder(context.Background(), &Order{
+		AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy,
+		Type: OrderTypeLimit, Price: 102, Quantity: 10,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	after := engine.GetOrderBook(productID, 0)
+	if fmt.Sprint(after.Asks) != fmt.Sprint(before.Asks) || len(after.Bids) != 0 {
+		t.Errorf("book after rollback = %+v, want %+v", after, before)
+	}
+	if after.LastPrice != nil {
+		t.Errorf("LastPrice = %v, want nil", *after.LastPrice)
+	}
+	b := engine.lookupBook(productID)
+	if len(b.orders) != 3 || len(b.undo) != 0 {
+		t.Errorf("orders = %d, undo = %d, want 3 and 0", len(b.orders), len(b.undo))
+	}
+}
+
+func TestCancelOrderIsPerProduct(t *testing.T) {
+	engine := NewEngine(nil)
+	agentID := uuid.New()
+	productA, productB := uuid.New(), uuid.New()
+
+	orderA := &Order{AgentID: agentID, ProductID: productA, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 10, Quantity: 1}
+	orderB := &Order{AgentID: agentID, ProductID: productB, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 10, Quantity: 1}
+	engine.PlaceOrder(context.Background(), orderA)
+	engine.PlaceOrder(context.Background(), orderB)
+
+	if err := engine.CancelOrder(context.Background(), orderA.ID, agentID); err != nil {
+		t.Fatalf("CancelOrder() error = %v", err)
+	}
+	if err := engine.CancelOrder(context.Background(), orderA.ID, agentID); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("second CancelOrder() error = %v, want ErrOrderNotFound", err)
+	}
+	if book := engine.GetOrderBook(productB, 10); len(book.Bids) != 1 {
+		t.Error("cancel should not touch other products")
+	}
+}
+
+func TestConcurrentProducts(t *testing.T) {
+	engine := NewEngine(nil)
+	products := make([]uuid.UUID, 8)
+	for i := range products {
+		products[i] = uuid.New()
+	}
+
+	var wg sync.WaitGroup
+	for _, productID := range products {
+		wg.Add(1)
+		go func(productID uuid.UUID) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				side := OrderSideBuy
+				if i%2 == 1 {
+					side = OrderSideSell
+				}
+				engine.PlaceOrder(context.Background(), &Order{
+					AgentID: uuid.New(), ProductID: productID, Side: side,
+					Type: OrderTypeLimit, Price: 100, Quantity: 1,
+				})
+			}
+		}(productID)
+	}
+	wg.Wait()
+
+	for _, productID := range products {
+		book := engine.GetOrderBook(productID, 10)
+		if len(book.Bids) != 0 || len(book.Asks) != 0 {
+			t.Errorf("product %s: book = %+v, want all orders matched", productID, book)
+		}
+	}
+}
+
+// seedBook rests n orders per side spread over 1000 price levels each.
+func seedBook(b *testing.B, engine *Engine, productID uuid.UUID, n int) []*Order {
+	b.Helper()
+	orders := make([]*Order, 0, 2*n)
+	for i := 0; i < n; i++ {
+		bid := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy,
+			Type: OrderTypeLimit, Price: float64(1000 - i%1000), Quantity: 10}
+		ask := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell,
+			Type: OrderTypeLimit, Price: float64(1001 + i%1000), Quantity: 10}
+		for _, order := range []*Order{bid, ask} {
+			if _, err := engine.PlaceOrder(context.Background(), order); err != nil {
+				b.Fatal(err)
+			}
+			orders = append(orders, order)
+		}
+	}
+	return orders
+}
+
+// BenchmarkPlaceOrder rests non-crossing orders on a book of 50,000 orders.
+func BenchmarkPlaceOrder(b *testing.B) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+	seedBook(b, engine, productID, 25000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.PlaceOrder(context.Background(), &Order{
+			AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy,
+			Type: OrderTypeLimit, Price: float64(1 + i%1000), Quantity: 1,
+		})
+	}
+}
+
+// BenchmarkMatchOrder places orders that each fill against the top of a book of 50,000 orders.
+func BenchmarkMatchOrder(b *testing.B) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+	seedBook(b, engine, productID, 25000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Replenish what the taker consumes so the book keeps its size
+		engine.PlaceOrder(context.Background(), &Order{
+			AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell,
+			Type: OrderTypeLimit, Price: 1001, Quantity: 1,
+		})
+		engine.PlaceOrder(context.Background(), &Order{
+			AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy,
+			Type: OrderTypeMarket, Quantity: 1,
+		})
+	}
+}
+
+// BenchmarkCancelOrder cancels orders from the middle of a book of 50,000 orders.
+func BenchmarkCancelOrder(b *testing.B) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+	orders := seedBook(b, engine, productID, 25000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		order := orders[i%len(orders)]
+		if err := engine.CancelOrder(context.Background(), order.ID, order.AgentID); err != nil {
+			// Put it back so every iteration cancels something
+			b.StopTimer()
+			engine.PlaceOrder(context.Background(), order)
+			b.StartTimer()
+		}
+	}
+}
+
+// BenchmarkGetOrderBook reads the top 20 levels of a book of 50,000 orders.
+func BenchmarkGetOrderBook(b *testing.B) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+	seedBook(b, engine, productID, 25000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.GetOrderBook(productID, 20)
+	}
+}
+
+// BenchmarkPlaceOrderParallel places orders for many products from parallel goroutines.
+func BenchmarkPlaceOrderParallel(b *testing.B) {
+	engine := NewEngine(nil)
+	products := make([]uuid.UUID, 64)
+	for i := range products {
+		products[i] = uuid.New()
+		seedBook(b, engine, products[i], 500)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			i++
+			engine.PlaceOrder(context.Background(), &Order{
+				AgentID: uuid.New(), ProductID: products[i%len(products)], Side: OrderSideBuy,
+				Type: OrderTypeLimit, Price: float64(1 + i%1000), Quantity: 1,
+			})
+		}
+	})
+}
+
+func TestBookHandlerReportsChangedLevels(t *testing.T) {
+	engine := NewEngine(nil)
+	var updates []BookUpdate
+	engine.SetBookHandler(func(update BookUpdate) {
+		updates = append(updates, update)
+	})
+	productID := uuid.New()
+	agentID := uuid.New()
+
+	resting := &Order{AgentID: agentID, ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 5}
+	engine.PlaceOrder(context.Background(), resting)
+	engine.PlaceOrder(context.Background(), &Order{
+		AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy,
+		Type: OrderTypeLimit, Price: 100, Quantity: 2,
+	})
+	engine.CancelOrder(context.Background(), resting.ID, agentID)
+
+	if len(updates) != 3 {
+		t.Fatalf("len(updates) = %d, want 3", len(updates))
+	}
+	if got := updates[0].Asks; len(got) != 1 || got[0].Quantity != 5 || updates[0].BestAsk.Price != 100 {
+		t.Errorf("place update = %+v, want ask 100 x 5", updates[0])
+	}
+	if got := updates[1]; len(got.Trades) != 1 || len(got.Asks) != 1 || got.Asks[0].Quantity != 3 || len(got.Bids) != 0 {
+		t.Errorf("match update = %+v, want one trade and ask 100 x 3", got)
+	}
+	if got := updates[2]; len(got.Asks) != 1 || got.Asks[0].Quantity != 0 || got.BestAsk != nil {
+		t.Errorf("cancel update = %+v, want ask 100 removed", got)
+	}
+}
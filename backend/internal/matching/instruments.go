@@ -0,0 +1,341 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownInstrument = errors.New("product is not a registered instrument")
+	ErrInstrumentExists  = errors.New("instrument already exists")
+	ErrInvalidInstrument = errors.New("invalid instrument")
+	ErrInstrumentHalted  = errors.New("trading is halted for this instrument")
+	ErrInvalidTickSize   = errors.New("price must be a multiple of the instrument's tick size")
+	ErrInvalidLotSize    = errors.New("quantity must be a multiple of the instrument's lot size")
+	ErrPriceOutOfBand    = errors.New("price is outside the instrument's price band")
+	ErrProductInUse      = errors.New("product already has orders or trades; only its seller or an instrument admin can register it")
+)
+
+// InstrumentStatus represents whether an instrument accepts orders.
+type InstrumentStatus string
+
+const (
+	InstrumentStatusOpen   InstrumentStatus = "open"
+	InstrumentStatusHalted InstrumentStatus = "halted" // Resting orders stay and can be cancelled, nothing new trades
+)
+
+// Instrument is a tradable product of the order book. Its ID is the product ID
+// orders are placed for.
+type Instrument struct {
+	ID         uuid.UUID        `json:"id"`
+	Symbol     string           `json:"symbol"`
+	Name       string           `json:"name"`
+	CategoryID *uuid.UUID       `json:"category_id,omitempty"` // Marketplace category traded
+	ListingID  *uuid.UUID       `json:"listing_id,omitempty"`  // Marketplace listing traded
+	Currency   string           `json:"currency"`
+	TickSize   float64          `json:"tick_size"` // Smallest price increment
+	LotSize    float64          `json:"lot_size"`  // Smallest quantity increment
+	MinPrice   float64          `json:"min_price"`
+	MaxPrice   float64          `json:"max_price"` // 0 for no upper bound
+	Status     InstrumentStatus `json:"status"`
+	CreatedBy  uuid.UUID        `json:"created_by"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// InstrumentUpdate changes an instrument. Nil fields are left unchanged; new
+// tick and lot sizes and price bands only apply to orders placed or amended later.
+type InstrumentUpdate struct {
+	Name     *string           `json:"name,omitempty"`
+	TickSize *float64          `json:"tick_size,omitempty"`
+	LotSize  *float64          `json:"lot_size,omitempty"`
+	MinPrice *float64          `json:"min_price,omitempty"`
+	MaxPrice *float64          `json:"max_price,omitempty"`
+	Status   *InstrumentStatus `json:"status,omitempty"`
+}
+
+// validate checks an instrument's definition.
+func (i *Instrument) validate() error {
+	switch {
+	case i.Symbol == "" || len(i.Symbol) > 32:
+		return fmt.Errorf("%w: symbol must be 1 to 32 characters", ErrInvalidInstrument)
+	case i.CategoryID == nil && i.ListingID == nil:
+		return fmt.Errorf("%w: category_id or listing_id is required", ErrInvalidInstrument)
+	case len(i.Currency) != 3:
+		return fmt.Errorf("%w: currency must be a 3-letter code", ErrInvalidInstrument)
+	case i.TickSize <= 0 || i.LotSize <= 0:
+		return fmt.Errorf("%w: tick_size and lot_size must be positive", ErrInvalidInstrument)
+	case i.MinPrice < 0 || i.MaxPrice < 0:
+		return fmt.Errorf("%w: min_price and max_price must not be negative", ErrInvalidInstrument)
+	case i.MaxPrice != 0 && i.MaxPrice <= i.MinPrice:
+		return fmt.Errorf("%w: max_price must be above min_price", ErrInvalidInstrument)
+	case i.Status != InstrumentStatusOpen && i.Status != InstrumentStatusHalted:
+		return fmt.Errorf("%w: status must be 'open' or 'halted'", ErrInvalidInstrument)
+	}
+	return nil
+}
+
+// validateOrder checks a new order against the instrument.
+func (i *Instrument) validateOrder(order *Order) error {
+	if i.Status != InstrumentStatusOpen {
+		return ErrInstrumentHalted
+	}
+	return i.validateTerms(order)
+}
+
+// validateTerms checks an order's price and quantity against the instrument.
+func (i *Instrument) validateTerms(order *Order) error {
+	if !onStep(order.Quantity, i.LotSize) {
+		return ErrInvalidLotSize
+	}
+	if order.hasLimitPrice() {
+		if err := i.validatePrice(order.Price); err != nil {
+			return err
+		}
+	}
+	if order.isStop() {
+		if err := i.validatePrice(order.StopPrice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *Instrument) validatePrice(price float64) error {
+	if !onStep(price, i.TickSize) {
+		return ErrInvalidTickSize
+	}
+	if price < i.MinPrice || i.MaxPrice > 0 && price > i.MaxPrice {
+		return ErrPriceOutOfBand
+	}
+	return nil
+}
+
+// onStep reports whether v is a positive whole multiple of step, allowing for
+// floating point error.
+func onStep(v, step float64) bool {
+	n := math.Round(v / step)
+	return n >= 1 && math.Abs(v-n*step) <= step*1e-9
+}
+
+// SetRequireInstruments sets whether orders may only be placed for registered
+// instruments. Orders for registered instruments are always validated against
+// them; without this, orders for other products are accepted as they are.
+func (e *Engine) SetRequireInstruments(require bool) {
+	e.requireInstruments = require
+}
+
+// SetInstrumentAdmins sets the agents allowed to register products that
+// already have orders or trades.
+func (e *Engine) SetInstrumentAdmins(ids []uuid.UUID) {
+	e.instrumentAdmins = make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		e.instrumentAdmins[id] = true
+	}
+}
+
+// instrument returns the registered instrument of a product, or nil.
+func (e *Engine) instrument(productID uuid.UUID) *Instrument {
+	v, ok := e.instruments.Load(productID)
+	if !ok {
+		return nil
+	}
+	return v.(*Instrument)
+}
+
+// validateInstrument checks an order against its product's instrument.
+func (e *Engine) validateInstrument(order *Order) error {
+	inst := e.instrument(order.ProductID)
+	if inst == nil {
+		if e.requireInstruments {
+			return ErrUnknownInstrument
+		}
+		return nil
+	}
+	return inst.validateOrder(order)
+}
+
+// currency returns the currency trades of a product settle in.
+func (e *Engine) currency(productID uuid.UUID) string {
+	if inst := e.instrument(productID); inst != nil {
+		return inst.Currency
+	}
+	return tradeCurrency
+}
+
+// CreateInstrument registers a new instrument. If inst.ID is set, an existing
+// product's book comes under the instrument's rules. A product that already
+// has orders or trades can only be registered by an instrument admin, or as
+// the listing of the same ID, whose seller the caller has been checked to be.
+func (e *Engine) CreateInstrument(ctx context.Context, inst *Instrument) error {
+	inst.Symbol = strings.ToUpper(strings.TrimSpace(inst.Symbol))
+	inst.Currency = strings.ToUpper(inst.Currency)
+	if inst.Currency == "" {
+		inst.Currency = tradeCurrency
+	}
+	if inst.Status == "" {
+		inst.Status = InstrumentStatusOpen
+	}
+	if err := inst.validate(); err != nil {
+		return err
+	}
+	if inst.ID == uuid.Nil {
+		inst.ID = uuid.New()
+	} else if err := e.checkProductRegistration(ctx, inst); err != nil {
+		return err
+	}
+
+	e.instrumentsMu.Lock()
+	defer e.instrumentsMu.Unlock()
+
+	exists := false
+	e.instruments.Range(func(_, v any) bool {
+		other := v.(*Instrument)
+		exists = other.ID == inst.ID || other.Symbol == inst.Symbol
+		return !exists
+	})
+	if exists {
+		return ErrInstrumentExists
+	}
+
+	now := time.Now().UTC()
+	inst.CreatedAt = now
+	inst.UpdatedAt = now
+	if e.repo != nil {
+		if err := e.repo.CreateInstrument(ctx, inst); err != nil {
+			return fmt.Errorf("failed to create instrument: %w", err)
+		}
+	}
+
+	// Stored as an immutable copy, updates replace it
+	stored := *inst
+	e.instruments.Store(inst.ID, &stored)
+	return nil
+}
+
+// checkProductRegistration returns ErrProductInUse if inst registers a product
+// that other agents may already be trading, and its creator may not take it over.
+func (e *Engine) checkProductRegistration(ctx context.Context, inst *Instrument) error {
+	if e.instrumentAdmins[inst.CreatedBy] || inst.ListingID != nil && *inst.ListingID == inst.ID {
+		return nil
+	}
+	if e.lookupBook(inst.ID) != nil {
+		return ErrProductInUse
+	}
+	if e.repo == nil {
+		return nil
+	}
+	traded, err := e.repo.HasProductActivity(ctx, inst.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check product activity: %w", err)
+	}
+	if traded {
+		return ErrProductInUse
+	}
+	return nil
+}
+
+// UpdateInstrument changes an instrument. Only the agent that created it may.
+func (e *Engine) UpdateInstrument(ctx context.Context, instrumentID, agentID uuid.UUID, update InstrumentUpdate) (*Instrument, error) {
+	e.instrumentsMu.Lock()
+	defer e.instrumentsMu.Unlock()
+
+	current := e.instrument(instrumentID)
+	if current == nil {
+		return nil, ErrUnknownInstrument
+	}
+	if current.CreatedBy != agentID {
+		return nil, ErrNotAuthorized
+	}
+
+	inst := *current
+	if update.Name != nil {
+		inst.Name = *update.Name
+	}
+	if update.TickSize != nil {
+		inst.TickSize = *update.TickSize
+	}
+	if update.LotSize != nil {
+		inst.LotSize = *update.LotSize
+	}
+	if update.MinPrice != nil {
+		inst.MinPrice = *update.MinPrice
+	}
+	if update.MaxPrice != nil {
+		inst.MaxPrice = *update.MaxPrice
+	}
+	if update.Status != nil {
+		inst.Status = *update.Status
+	}
+	if err := inst.validate(); err != nil {
+		return nil, err
+	}
+	inst.UpdatedAt = time.Now().UTC()
+
+	if e.repo != nil {
+		if err := e.repo.UpdateInstrument(ctx, &inst); err != nil {
+			return nil, fmt.Errorf("failed to update instrument: %w", err)
+		}
+	}
+
+	e.instruments.Store(inst.ID, &inst)
+	updated := inst
+	return &updated, nil
+}
+
+// GetInstrument returns a registered instrument.
+func (e *Engine) GetInstrument(instrumentID uuid.UUID) (*Instrument, error) {
+	inst := e.instrument(instrumentID)
+	if inst == nil {
+		return nil, ErrUnknownInstrument
+	}
+	found := *inst
+	return &found, nil
+}
+
+// ListInstruments returns the registered instruments ordered by symbol,
+// optionally only those of a category or with a status.
+func (e *Engine) ListInstruments(categoryID *uuid.UUID, status InstrumentStatus) []*Instrument {
+	instruments := []*Instrument{}
+	e.instruments.Range(func(_, v any) bool {
+		inst := *v.(*Instrument)
+		if categoryID != nil && (inst.CategoryID == nil || *inst.CategoryID != *categoryID) {
+			return true
+		}
+		if status != "" && inst.Status != status {
+			return true
+		}
+		instruments = append(instruments, &inst)
+		return true
+	})
+	sort.Slice(instruments, func(i, j int) bool {
+		return instruments[i].Symbol < instruments[j].Symbol
+	})
+	return instruments
+}
+
+// loadInstruments replaces the registry with the persisted instruments.
+func (e *Engine) loadInstruments(ctx context.Context) error {
+	instruments, err := e.repo.GetInstruments(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.instrumentsMu.Lock()
+	defer e.instrumentsMu.Unlock()
+	e.instruments.Range(func(key, _ any) bool {
+		e.instruments.Delete(key)
+		return true
+	})
+	for _, inst := range instruments {
+		e.instruments.Store(inst.ID, inst)
+	}
+	return nil
+}
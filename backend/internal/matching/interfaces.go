@@ -0,0 +1,45 @@
+package matching
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RepositoryInterface defines the contract for order book persistence.
+// This interface enables mock implementations for testing.
+type RepositoryInterface interface {
+	// SaveMatch atomically persists an incoming order, the resting and stop
+	// orders it changed and the resulting trades.
+	SaveMatch(ctx context.Context, order *Order, touched []*Order, trades []Trade) error
+	// SaveAmend persists an amended order like SaveMatch, moving it to the back
+	// of the arrival order if requeue is set.
+	SaveAmend(ctx context.Context, order *Order, requeue bool, touched []*Order, trades []Trade) error
+	UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status OrderStatus) error
+
+	// Queries
+	GetOrder(ctx context.Context, orderID uuid.UUID) (*Order, error)
+	ListOrders(ctx context.Context, params ListOrdersParams) (*OrderListResult, error)
+	GetOrderTrades(ctx context.Context, orderID uuid.UUID) ([]Trade, error)
+
+	// Instruments
+	CreateInstrument(ctx context.Context, inst *Instrument) error
+	UpdateInstrument(ctx context.Context, inst *Instrument) error
+	GetInstruments(ctx context.Context) ([]*Instrument, error)
+	HasProductActivity(ctx context.Context, productID uuid.UUID) (bool, error)
+
+	// Recovery
+	GetRestingOrders(ctx context.Context) ([]*Order, error)
+	GetLastPrices(ctx context.Context) (map[uuid.UUID]float64, error)
+	GetTradesSince(ctx context.Context, since time.Time) ([]Trade, error)
+
+	// Market data
+	GetCandles(ctx context.Context, productID uuid.UUID, interval time.Duration, from, to time.Time, limit int) ([]Candle, error)
+
+	// Settlement
+	GetUnsettledTrades(ctx context.Context) ([]Trade, error)
+}
+
+// Verify that Repository implements RepositoryInterface
+var _ RepositoryInterface = (*Repository)(nil)
@@ -2,14 +2,24 @@ package matching
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sort"
 	"sync"
 	"time"
 
+	"github.com/digi604/swarmmarket/backend/pkg/logger"
 	"github.com/google/uuid"
 )
 
+var (
+	ErrOrderNotFound      = errors.New("order not found")
+	ErrNotAuthorized      = errors.New("not authorized to cancel this order")
+	ErrInvalidTimeInForce = errors.New("invalid time in force for this order type")
+	ErrInvalidExpiry      = errors.New("GTD orders require an expiry in the future")
+	ErrInvalidStopPrice   = errors.New("stop orders require a positive stop price")
+	ErrInvalidSTPMode     = errors.New("invalid self-trade prevention mode")
+)
+
 // OrderSide represents buy or sell.
 type OrderSide string
 
@@ -22,20 +32,55 @@ const (
 type OrderType string
 
 const (
-	OrderTypeLimit  OrderType = "limit"  // Execute at specific price or better
-	OrderTypeMarket OrderType = "market" // Execute at best available price
+	OrderTypeLimit     OrderType = "limit"      // Execute at specific price or better
+	OrderTypeMarket    OrderType = "market"     // Execute at best available price
+	OrderTypeStop      OrderType = "stop"       // Becomes a market order when the stop price trades
+	OrderTypeStopLimit OrderType = "stop_limit" // Becomes a limit order when the stop price trades
 )
 
 // OrderStatus represents the status of an order.
 type OrderStatus string
 
 const (
+	OrderStatusPending   OrderStatus = "pending"   // Stop order waiting for its trigger
 	OrderStatusOpen      OrderStatus = "open"
 	OrderStatusPartial   OrderStatus = "partial"   // Partially filled
 	OrderStatusFilled    OrderStatus = "filled"    // Completely filled
 	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusExpired   OrderStatus = "expired"   // GTD order reached its expiry
+)
+
+// TimeInForce controls how long an order stays working.
+type TimeInForce string
+
+const (
+	TimeInForceGTC TimeInForce = "GTC" // Good till cancelled: rests until filled or cancelled
+	TimeInForceGTD TimeInForce = "GTD" // Good till date: rests until ExpiresAt
+	TimeInForceIOC TimeInForce = "IOC" // Immediate or cancel: fill what is possible, cancel the rest
+	TimeInForceFOK TimeInForce = "FOK" // Fill or kill: fill completely at once or not at all
+)
+
+// STPMode controls what happens when an order would trade against an order
+// of the same agent (or of an agent with the same owner, if enabled).
+type STPMode string
+
+const (
+	STPNone         STPMode = "none"          // Allow self-trades
+	STPCancelNewest STPMode = "cancel_newest" // Cancel the rest of the incoming order
+	STPCancelOldest STPMode = "cancel_oldest" // Cancel the resting order and keep matching
+	STPCancelBoth   STPMode = "cancel_both"   // Cancel both orders
+	STPDecrement    STPMode = "decrement"     // Reduce both by the smaller quantity without trading
 )
 
+// Valid reports whether the mode is known.
+func (m STPMode) Valid() bool {
+	switch m {
+	case STPNone, STPCancelNewest, STPCancelOldest, STPCancelBoth, STPDecrement:
+		return true
+	}
+	return false
+}
+
 // Order represents a buy or sell order in the order book.
 type Order struct {
 	ID            uuid.UUID   `json:"id"`
@@ -44,14 +89,44 @@ type Order struct {
 	Side          OrderSide   `json:"side"`           // buy or sell
 	Type          OrderType   `json:"type"`           // limit or market
 	Price         float64     `json:"price"`          // Limit price (0 for market)
+	StopPrice     float64     `json:"stop_price,omitempty"` // Trigger price (stop orders only)
 	Quantity      float64     `json:"quantity"`       // Original quantity
 	FilledQty     float64     `json:"filled_qty"`     // How much has been filled
 	RemainingQty  float64     `json:"remaining_qty"`  // Remaining to fill
 	Status        OrderStatus `json:"status"`
+	TimeInForce   TimeInForce `json:"time_in_force"`
+	ExpiresAt     *time.Time  `json:"expires_at,omitempty"` // GTD only
+	TriggeredAt   *time.Time  `json:"triggered_at,omitempty"` // When a stop order was triggered
+	STP           STPMode     `json:"stp"`                    // Self-trade prevention mode, applied when this order is the taker
+	OwnerID       *uuid.UUID  `json:"-"`                      // Human owner of the agent, for same-owner checks
 	CreatedAt     time.Time   `json:"created_at"`
 	UpdatedAt     time.Time   `json:"updated_at"`
 }
 
+// isStop reports whether the order is a stop or stop-limit order.
+func (o *Order) isStop() bool {
+	return o.Type == OrderTypeStop || o.Type == OrderTypeStopLimit
+}
+
+// hasLimitPrice reports whether the order only trades at its price or better.
+func (o *Order) hasLimitPrice() bool {
+	return o.Type == OrderTypeLimit || o.Type == OrderTypeStopLimit
+}
+
+// stopTriggered reports whether a last trade price triggers a stop order.
+// Buy stops trigger at or above the stop price, sell stops at or below.
+func (o *Order) stopTriggered(lastPrice float64) bool {
+	if o.Side == OrderSideBuy {
+		return lastPrice >= o.StopPrice
+	}
+	return lastPrice <= o.StopPrice
+}
+
+// isExpired reports whether a GTD order has reached its expiry.
+func (o *Order) isExpired(now time.Time) bool {
+	return o.TimeInForce == TimeInForceGTD && o.ExpiresAt != nil && !o.ExpiresAt.After(now)
+}
+
 // Trade represents a matched trade between two orders.
 type Trade struct {
 	ID         uuid.UUID `json:"id"`
@@ -63,6 +138,9 @@ type Trade struct {
 	Price      float64   `json:"price"`    // Execution price
 	Quantity   float64   `json:"quantity"` // Traded quantity
 	CreatedAt  time.Time `json:"created_at"`
+
+	// Escrowed transaction settling this trade (nil until created)
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty"`
 }
 
 // PriceLevel represents aggregate quantity at a price level.
@@ -85,324 +163,823 @@ type OrderBook struct {
 
 // MatchResult contains the result of order matching.
 type MatchResult struct {
-	Trades        []Trade `json:"trades"`
-	RemainingOrder *Order `json:"remaining_order,omitempty"`
+	Trades         []Trade `json:"trades"`
+	RemainingOrder *Order  `json:"remaining_order,omitempty"`
+	CancelledQty   float64 `json:"cancelled_qty"` // Unfilled quantity cancelled (IOC, FOK, market)
+
+	expired   []Order // Resting GTD orders found expired while matching
+	prevented []Order // Resting orders cancelled or reduced by self-trade prevention
+	triggered []Order // Stop orders triggered by this order's trades
+	cascade   []Trade // Trades of triggered stop orders
 }
 
 // EventHandler is called when trades occur.
 type EventHandler func(ctx context.Context, trade Trade)
 
-// Engine is the order matching engine.
+// OrderHandler is called when the engine changes an order on its own, e.g. on expiry.
+type OrderHandler func(ctx context.Context, order Order)
+
+// BookUpdate describes how a product's book changed in one step (a placed,
+// cancelled or expired order, including every trade it caused).
+type BookUpdate struct {
+	ProductID uuid.UUID
+	Bids      []PriceLevel // Changed levels with their new totals; zero quantity means removed
+	Asks      []PriceLevel
+	Trades    []Trade
+	BestBid   *PriceLevel // Best levels after the update, nil if a side is empty
+	BestAsk   *PriceLevel
+}
+
+// BookHandler is called with every change to a book, in order per product.
+// It runs while the book is locked, so it must not block or call back into the engine.
+type BookHandler func(update BookUpdate)
+
+// TransactionCreator creates escrowed transactions for trades (implemented by transaction.Service).
+type TransactionCreator interface {
+	CreateFromTrade(ctx context.Context, buyerID, sellerID uuid.UUID, tradeID, productID *uuid.UUID, amount float64, currency string) (uuid.UUID, error)
+}
+
+// tradeCurrency is the currency order book trades settle in.
+const tradeCurrency = "USD"
+
+// Engine is the order matching engine. Every product has its own book and
+// lock, so orders for different products are matched concurrently.
 type Engine struct {
-	mu           sync.RWMutex
-	buyOrders    map[uuid.UUID][]*Order // ProductID -> orders (sorted by price desc, time asc)
-	sellOrders   map[uuid.UUID][]*Order // ProductID -> orders (sorted by price asc, time asc)
-	lastPrices   map[uuid.UUID]float64
-	eventHandler EventHandler
+	mu                 sync.RWMutex
+	books              map[uuid.UUID]*book // ProductID -> book
+	index              sync.Map            // OrderID -> *book, for resting and stop orders
+	instruments        sync.Map            // ProductID -> *Instrument, replaced on update
+	instrumentsMu      sync.Mutex          // Serializes instrument changes
+	instrumentAdmins   map[uuid.UUID]bool  // Agents allowed to register products already traded
+	requireInstruments bool
+	eventHandler       EventHandler
+	expiryHandler      OrderHandler
+	triggerHandler     OrderHandler
+	stpHandler         OrderHandler
+	bookHandler        BookHandler
+	stp                stpPolicy
+	repo               RepositoryInterface
+	txCreator          TransactionCreator
+}
+
+// stpPolicy is the engine-wide self-trade prevention configuration.
+type stpPolicy struct {
+	mode      STPMode // Default for orders without a mode
+	sameOwner bool    // Also treat agents with the same owner as the same party
+}
+
+// fill is an order changed by a match, together with its state beforehand.
+type fill struct {
+	order  *Order
+	before Order
 }
 
 // NewEngine creates a new matching engine.
 func NewEngine(handler EventHandler) *Engine {
 	return &Engine{
-		buyOrders:    make(map[uuid.UUID][]*Order),
-		sellOrders:   make(map[uuid.UUID][]*Order),
-		lastPrices:   make(map[uuid.UUID]float64),
+		books:        make(map[uuid.UUID]*book),
 		eventHandler: handler,
+		stp:          stpPolicy{mode: STPNone},
 	}
 }
 
-// PlaceOrder places an order and attempts to match it.
-func (e *Engine) PlaceOrder(ctx context.Context, order *Order) (*MatchResult, error) {
+// SetRepository sets the repository used to persist orders and trades (optional).
+// Without a repository the book lives in memory only.
+func (e *Engine) SetRepository(repo RepositoryInterface) {
+	e.repo = repo
+}
+
+// SetTransactionCreator sets the transaction creator used to settle trades (optional).
+func (e *Engine) SetTransactionCreator(creator TransactionCreator) {
+	e.txCreator = creator
+}
+
+// SetExpiryHandler sets the handler called for every expired GTD order (optional).
+func (e *Engine) SetExpiryHandler(handler OrderHandler) {
+	e.expiryHandler = handler
+}
+
+// SetTriggerHandler sets the handler called for every triggered stop order (optional).
+func (e *Engine) SetTriggerHandler(handler OrderHandler) {
+	e.triggerHandler = handler
+}
+
+// SetSelfTradePrevention sets the default self-trade prevention mode for orders
+// that don't choose one, and whether agents with the same owner count as the
+// same party. It must be called before the engine accepts orders.
+func (e *Engine) SetSelfTradePrevention(mode STPMode, sameOwner bool) error {
+	if !mode.Valid() {
+		return ErrInvalidSTPMode
+	}
+	e.stp = stpPolicy{mode: mode, sameOwner: sameOwner}
+	return nil
+}
+
+// SetSelfTradeHandler sets the handler called for every resting order that
+// self-trade prevention cancelled or reduced (optional).
+func (e *Engine) SetSelfTradeHandler(handler OrderHandler) {
+	e.stpHandler = handler
+}
+
+// SetBookHandler sets the handler called for every book change, e.g. to feed
+// market data (optional). Set it before Recover so the recovered books are reported.
+func (e *Engine) SetBookHandler(handler BookHandler) {
+	e.bookHandler = handler
+}
+
+// publishBook reports the changes to a book since the last call. Must be called with b.mu held.
+func (e *Engine) publishBook(b *book, trades []Trade) {
+	update := b.flush(trades)
+	if e.bookHandler == nil {
+		return
+	}
+	if len(update.Bids) == 0 && len(update.Asks) == 0 && len(update.Trades) == 0 {
+		return
+	}
+	e.bookHandler(update)
+}
+
+// book returns the book for a product, creating it if needed.
+func (e *Engine) book(productID uuid.UUID) *book {
+	e.mu.RLock()
+	b, ok := e.books[productID]
+	e.mu.RUnlock()
+	if ok {
+		return b
+	}
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	if b, ok := e.books[productID]; ok {
+		return b
+	}
+	b = newBook(productID, &e.index, &e.stp)
+	e.books[productID] = b
+	return b
+}
 
-	order.ID = uuid.New()
-	order.RemainingQty = order.Quantity
-	order.Status = OrderStatusOpen
-	order.CreatedAt = time.Now().UTC()
-	order.UpdatedAt = order.CreatedAt
+// lookupBook returns the book for a product without creating it.
+func (e *Engine) lookupBook(productID uuid.UUID) *book {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.books[productID]
+}
 
-	result := &MatchResult{}
+// allBooks returns every product's book.
+func (e *Engine) allBooks() []*book {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	books := make([]*book, 0, len(e.books))
+	for _, b := range e.books {
+		books = append(books, b)
+	}
+	return books
+}
 
-	if order.Side == OrderSideBuy {
-		result = e.matchBuyOrder(ctx, order)
-	} else {
-		result = e.matchSellOrder(ctx, order)
+// Recover rebuilds the in-memory book from persisted resting orders and trades.
+// It must be called at startup before the engine accepts orders, and returns
+// the number of resting orders restored.
+func (e *Engine) Recover(ctx context.Context) (int, error) {
+	if e.repo == nil {
+		return 0, nil
 	}
 
-	// If order still has remaining quantity, add to book
-	if order.RemainingQty > 0 && order.Type == OrderTypeLimit {
-		if order.Side == OrderSideBuy {
-			e.addBuyOrder(order)
+	if err := e.loadInstruments(ctx); err != nil {
+		return 0, fmt.Errorf("failed to load instruments: %w", err)
+	}
+
+	orders, err := e.repo.GetRestingOrders(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load resting orders: %w", err)
+	}
+
+	lastPrices, err := e.repo.GetLastPrices(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load last prices: %w", err)
+	}
+
+	recent, err := e.repo.GetTradesSince(ctx, time.Now().UTC().Add(-statsWindow))
+	if err != nil {
+		return 0, fmt.Errorf("failed to load recent trades: %w", err)
+	}
+
+	e.mu.Lock()
+	e.books = make(map[uuid.UUID]*book)
+	e.index.Range(func(key, _ any) bool {
+		e.index.Delete(key)
+		return true
+	})
+	e.mu.Unlock()
+
+	// Orders come back in arrival order, so appending keeps time priority intact
+	for _, order := range orders {
+		b := e.book(order.ProductID)
+		if order.Status == OrderStatusPending {
+			b.addStop(order)
 		} else {
-			e.addSellOrder(order)
+			b.add(order)
 		}
-		result.RemainingOrder = order
+	}
+	for productID, price := range lastPrices {
+		e.book(productID).setLastPrice(price)
+	}
+	for _, trade := range recent {
+		e.book(trade.ProductID).recordTrades([]Trade{trade})
+	}
+	for _, b := range e.allBooks() {
+		b.mu.Lock()
+		e.publishBook(b, nil)
+		b.mu.Unlock()
 	}
 
-	return result, nil
+	return len(orders), nil
 }
 
-// matchBuyOrder matches a buy order against sell orders.
-func (e *Engine) matchBuyOrder(ctx context.Context, buyOrder *Order) *MatchResult {
-	result := &MatchResult{}
-	productID := buyOrder.ProductID
-	sellOrders := e.sellOrders[productID]
+// PlaceOrder places an order and attempts to match it.
+// Every resulting trade is settled through an escrowed transaction.
+func (e *Engine) PlaceOrder(ctx context.Context, order *Order) (*MatchResult, error) {
+	now := time.Now().UTC()
+	if order.isStop() && order.StopPrice <= 0 {
+		return nil, ErrInvalidStopPrice
+	}
+	if err := validateTimeInForce(order, now); err != nil {
+		return nil, err
+	}
+	if order.STP == "" {
+		order.STP = e.stp.mode
+	}
+	if !order.STP.Valid() {
+		return nil, ErrInvalidSTPMode
+	}
+	if err := e.validateInstrument(order); err != nil {
+		return nil, err
+	}
 
-	i := 0
-	for i < len(sellOrders) && buyOrder.RemainingQty > 0 {
-		sellOrder := sellOrders[i]
+	result, err := e.placeOrder(ctx, e.book(order.ProductID), order, now)
+	if err != nil {
+		return nil, err
+	}
 
-		// Check if prices cross (buy price >= sell price for a match)
-		if buyOrder.Type == OrderTypeLimit && buyOrder.Price < sellOrder.Price {
-			break // No more matches possible (sell orders sorted by price asc)
+	e.dispatch(ctx, result)
+	return result, nil
+}
+
+// dispatch settles the trades of a persisted match and notifies the handlers.
+// Trades are already persisted, so it runs outside the book lock.
+func (e *Engine) dispatch(ctx context.Context, result *MatchResult) {
+	for i := range result.Trades {
+		e.settleTrade(ctx, &result.Trades[i])
+	}
+	for i := range result.cascade {
+		e.settleTrade(ctx, &result.cascade[i])
+	}
+
+	// Notify
+	if e.eventHandler != nil {
+		for _, trade := range result.Trades {
+			go e.eventHandler(ctx, trade)
+		}
+		for _, trade := range result.cascade {
+			go e.eventHandler(ctx, trade)
 		}
+	}
+	if e.triggerHandler != nil {
+		for _, order := range result.triggered {
+			go e.triggerHandler(ctx, order)
+		}
+	}
+	e.notifyExpired(ctx, result.expired)
+	if e.stpHandler != nil {
+		for _, order := range result.prevented {
+			go e.stpHandler(ctx, order)
+		}
+	}
+}
 
-		// Determine trade quantity and price
-		tradeQty := min(buyOrder.RemainingQty, sellOrder.RemainingQty)
-		tradePrice := sellOrder.Price // Price-time priority: use resting order's price
+// placeOrder matches and books an order under its product's lock.
+func (e *Engine) placeOrder(ctx context.Context, b *book, order *Order, now time.Time) (*MatchResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-		// Create trade
-		trade := Trade{
-			ID:          uuid.New(),
-			ProductID:   productID,
-			BuyOrderID:  buyOrder.ID,
-			SellOrderID: sellOrder.ID,
-			BuyerID:     buyOrder.AgentID,
-			SellerID:    sellOrder.AgentID,
-			Price:       tradePrice,
-			Quantity:    tradeQty,
-			CreatedAt:   time.Now().UTC(),
+	order.ID = uuid.New()
+	order.RemainingQty = order.Quantity
+	order.Status = OrderStatusOpen
+	order.CreatedAt = now
+	order.UpdatedAt = now
+
+	if e.repo != nil {
+		b.begin()
+	}
+
+	var result *MatchResult
+	var fills []fill
+	if order.isStop() {
+		// Hidden until the last price crosses the stop (possibly right away, below)
+		order.Status = OrderStatusPending
+		b.addStop(order)
+		result = &MatchResult{}
+	} else {
+		result, fills = b.execute(order, now)
+	}
+
+	fills = append(fills, b.triggerStops(order, now, result)...)
+
+	err := e.persist(b, fills, result, func(touched []*Order, trades []Trade) error {
+		return e.repo.SaveMatch(ctx, order, touched, trades)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to persist order: %w", err)
+	}
+	return result, nil
+}
+
+// persist saves a journaled step through save before anyone is told about its
+// trades. If saving fails, the book and every changed resting order are rolled
+// back. Must be called with b.mu held.
+func (e *Engine) persist(b *book, fills []fill, result *MatchResult, save func(touched []*Order, trades []Trade) error) error {
+	trades := append(append([]Trade(nil), result.Trades...), result.cascade...)
+	if e.repo != nil {
+		touched := make([]*Order, len(fills))
+		for i, f := range fills {
+			touched[i] = f.order
 		}
-		result.Trades = append(result.Trades, trade)
+		if err := save(touched, trades); err != nil {
+			b.rollback()
+			b.discard()
+			for i := len(fills) - 1; i >= 0; i-- {
+				*fills[i].order = fills[i].before
+			}
+			return err
+		}
+		b.commit()
+	}
+	b.recordTrades(trades)
+	e.publishBook(b, trades)
+	return nil
+}
 
-		// Update quantities
-		buyOrder.RemainingQty -= tradeQty
-		buyOrder.FilledQty += tradeQty
-		sellOrder.RemainingQty -= tradeQty
-		sellOrder.FilledQty += tradeQty
+// execute matches an active order, then rests or cancels what is left of it.
+func (b *book) execute(order *Order, now time.Time) (*MatchResult, []fill) {
+	var result *MatchResult
+	var fills []fill
+	if order.TimeInForce == TimeInForceFOK && b.fillableQty(order, now) < order.Quantity {
+		// Kill: nothing trades unless the whole quantity can
+		result = &MatchResult{}
+	} else {
+		result, fills = b.match(order, now)
+	}
 
-		// Update statuses
-		if buyOrder.RemainingQty == 0 {
-			buyOrder.Status = OrderStatusFilled
+	// If order still has remaining quantity, add it to the book or cancel it
+	if order.RemainingQty > 0 {
+		rests := order.TimeInForce == TimeInForceGTC || order.TimeInForce == TimeInForceGTD
+		if rests && order.Status != OrderStatusCancelled {
+			b.add(order)
+			result.RemainingOrder = order
 		} else {
-			buyOrder.Status = OrderStatusPartial
+			order.Status = OrderStatusCancelled
+			result.CancelledQty += order.RemainingQty
+		}
+	}
+
+	return result, fills
+}
+
+// triggerStops activates stop orders whose trigger the product's last price has crossed.
+// Triggered stops run one at a time and each can move the last price and trigger
+// more; the next stop is always the earliest placed one whose trigger holds at the
+// current last price, so a cascade plays out the same way every time.
+// Trades of the incoming order itself (if it is a stop) go to result.Trades,
+// everything else to result.cascade.
+func (b *book) triggerStops(incoming *Order, now time.Time, result *MatchResult) []fill {
+	var fills []fill
+
+	for {
+		stop := b.nextTriggeredStop(now)
+		if stop == nil {
+			return fills
 		}
 
-		if sellOrder.RemainingQty == 0 {
-			sellOrder.Status = OrderStatusFilled
-			// Remove filled order
-			sellOrders = append(sellOrders[:i], sellOrders[i+1:]...)
+		fills = append(fills, fill{order: stop, before: *stop})
+		b.removeStop(stop)
+		stop.Status = OrderStatusOpen
+		stop.TriggeredAt = &now
+		stop.UpdatedAt = now
+
+		r, f := b.execute(stop, now)
+		fills = append(fills, f...)
+		result.expired = append(result.expired, r.expired...)
+		result.prevented = append(result.prevented, r.prevented...)
+		if stop == incoming {
+			result.Trades = r.Trades
+			result.RemainingOrder = r.RemainingOrder
+			result.CancelledQty = r.CancelledQty
 		} else {
-			sellOrder.Status = OrderStatusPartial
-			i++
+			result.cascade = append(result.cascade, r.Trades...)
 		}
+		result.triggered = append(result.triggered, *stop)
+	}
+}
 
-		// Update last price
-		e.lastPrices[productID] = tradePrice
+// nextTriggeredStop returns the earliest placed stop order triggered by the
+// current last price, or nil if there is none.
+func (b *book) nextTriggeredStop(now time.Time) *Order {
+	if !b.hasPrice {
+		return nil
+	}
+	for _, stop := range b.stops {
+		if !stop.isExpired(now) && stop.stopTriggered(b.lastPrice) {
+			return stop
+		}
+	}
+	return nil
+}
 
-		// Notify
-		if e.eventHandler != nil {
-			go e.eventHandler(ctx, trade)
+// validateTimeInForce defaults and checks an order's time in force.
+// Limit orders default to GTC; market orders can never rest, so they default to IOC.
+// Stop orders follow the order type they become once triggered.
+func validateTimeInForce(order *Order, now time.Time) error {
+	if order.TimeInForce == "" {
+		if !order.hasLimitPrice() {
+			order.TimeInForce = TimeInForceIOC
+		} else {
+			order.TimeInForce = TimeInForceGTC
 		}
 	}
 
-	e.sellOrders[productID] = sellOrders
-	return result
+	switch order.TimeInForce {
+	case TimeInForceIOC, TimeInForceFOK:
+		order.ExpiresAt = nil
+	case TimeInForceGTC:
+		if !order.hasLimitPrice() {
+			return ErrInvalidTimeInForce
+		}
+		order.ExpiresAt = nil
+	case TimeInForceGTD:
+		if !order.hasLimitPrice() {
+			return ErrInvalidTimeInForce
+		}
+		if order.ExpiresAt == nil || !order.ExpiresAt.After(now) {
+			return ErrInvalidExpiry
+		}
+	default:
+		return ErrInvalidTimeInForce
+	}
+	return nil
 }
 
-// matchSellOrder matches a sell order against buy orders.
-func (e *Engine) matchSellOrder(ctx context.Context, sellOrder *Order) *MatchResult {
-	result := &MatchResult{}
-	productID := sellOrder.ProductID
-	buyOrders := e.buyOrders[productID]
+// crosses reports whether an order is willing to trade at a price.
+func (o *Order) crosses(price float64) bool {
+	if !o.hasLimitPrice() {
+		return true
+	}
+	if o.Side == OrderSideBuy {
+		return o.Price >= price
+	}
+	return o.Price <= price
+}
+
+// fillableQty returns how much of an order could fill right now against the opposite side.
+func (b *book) fillableQty(order *Order, now time.Time) float64 {
+	available := 0.0
+	b.opposite(order.Side).sorted.each(func(level *priceLevel) bool {
+		if !order.crosses(level.price) {
+			return false
+		}
+		for el := level.orders.Front(); el != nil && available < order.Quantity; el = el.Next() {
+			resting := el.Value.(*Order)
+			if resting.isExpired(now) {
+				continue
+			}
+			if b.selfTrade(order, resting) {
+				if order.STP == STPCancelOldest {
+					continue // Removed when reached
+				}
+				return false // Matching stops here
+			}
+			available += resting.RemainingQty
+		}
+		return available < order.Quantity
+	})
+	return available
+}
 
-	i := 0
-	for i < len(buyOrders) && sellOrder.RemainingQty > 0 {
-		buyOrder := buyOrders[i]
+// match fills an order against the opposite side with price-time priority.
+// It returns the resting orders it changed alongside the result.
+func (b *book) match(order *Order, now time.Time) (*MatchResult, []fill) {
+	result := &MatchResult{}
+	var fills []fill
+	opposite := b.opposite(order.Side)
 
-		// Check if prices cross (buy price >= sell price for a match)
-		if sellOrder.Type == OrderTypeLimit && buyOrder.Price < sellOrder.Price {
-			break // No more matches possible (buy orders sorted by price desc)
+	for order.RemainingQty > 0 {
+		level := opposite.sorted.best()
+		if level == nil || !order.crosses(level.price) {
+			break // No more matches possible
+		}
+		resting := level.front()
+
+		// Drop GTD orders that expired since the last sweep
+		if resting.isExpired(now) {
+			fills = append(fills, fill{order: resting, before: *resting})
+			b.popFront(opposite, level)
+			resting.Status = OrderStatusExpired
+			resting.UpdatedAt = now
+			result.expired = append(result.expired, *resting)
+			continue
 		}
 
-		// Determine trade quantity and price
-		tradeQty := min(sellOrder.RemainingQty, buyOrder.RemainingQty)
-		tradePrice := buyOrder.Price // Price-time priority: use resting order's price
+		if b.selfTrade(order, resting) {
+			if order.STP != STPCancelNewest {
+				fills = append(fills, fill{order: resting, before: *resting})
+			}
+			if !b.preventSelfTrade(order, resting, opposite, level, result, now) {
+				break
+			}
+			continue
+		}
 
-		// Create trade
+		// Determine trade quantity; price-time priority uses the resting order's price
+		tradeQty := min(order.RemainingQty, resting.RemainingQty)
 		trade := Trade{
-			ID:          uuid.New(),
-			ProductID:   productID,
-			BuyOrderID:  buyOrder.ID,
-			SellOrderID: sellOrder.ID,
-			BuyerID:     buyOrder.AgentID,
-			SellerID:    sellOrder.AgentID,
-			Price:       tradePrice,
-			Quantity:    tradeQty,
-			CreatedAt:   time.Now().UTC(),
+			ID:        uuid.New(),
+			ProductID: b.productID,
+			Price:     level.price,
+			Quantity:  tradeQty,
+			CreatedAt: now,
+		}
+		if order.Side == OrderSideBuy {
+			trade.BuyOrderID, trade.BuyerID = order.ID, order.AgentID
+			trade.SellOrderID, trade.SellerID = resting.ID, resting.AgentID
+		} else {
+			trade.BuyOrderID, trade.BuyerID = resting.ID, resting.AgentID
+			trade.SellOrderID, trade.SellerID = order.ID, order.AgentID
 		}
 		result.Trades = append(result.Trades, trade)
+		fills = append(fills, fill{order: resting, before: *resting})
 
 		// Update quantities
-		sellOrder.RemainingQty -= tradeQty
-		sellOrder.FilledQty += tradeQty
-		buyOrder.RemainingQty -= tradeQty
-		buyOrder.FilledQty += tradeQty
+		order.RemainingQty -= tradeQty
+		order.FilledQty += tradeQty
+		resting.RemainingQty -= tradeQty
+		resting.FilledQty += tradeQty
+		order.UpdatedAt = now
+		resting.UpdatedAt = now
+		b.reduceLevel(opposite, level, tradeQty)
 
 		// Update statuses
-		if sellOrder.RemainingQty == 0 {
-			sellOrder.Status = OrderStatusFilled
+		if order.RemainingQty == 0 {
+			order.Status = OrderStatusFilled
 		} else {
-			sellOrder.Status = OrderStatusPartial
+			order.Status = OrderStatusPartial
 		}
 
-		if buyOrder.RemainingQty == 0 {
-			buyOrder.Status = OrderStatusFilled
-			buyOrders = append(buyOrders[:i], buyOrders[i+1:]...)
+		if resting.RemainingQty == 0 {
+			resting.Status = OrderStatusFilled
+			b.popFront(opposite, level)
 		} else {
-			buyOrder.Status = OrderStatusPartial
-			i++
+			resting.Status = OrderStatusPartial
 		}
 
 		// Update last price
-		e.lastPrices[productID] = tradePrice
+		b.setLastPrice(level.price)
+	}
 
-		// Notify
-		if e.eventHandler != nil {
-			go e.eventHandler(ctx, trade)
-		}
+	return result, fills
+}
+
+// selfTrade reports whether two orders belong to the same party.
+func (b *book) selfTrade(order, resting *Order) bool {
+	if order.STP == STPNone || order.STP == "" {
+		return false
+	}
+	if order.AgentID == resting.AgentID {
+		return true
+	}
+	return b.stp.sameOwner && order.OwnerID != nil && resting.OwnerID != nil && *order.OwnerID == *resting.OwnerID
+}
+
+// preventSelfTrade applies the incoming order's self-trade prevention mode
+// against the resting order at the front of a level. It returns whether the
+// incoming order can keep matching.
+func (b *book) preventSelfTrade(order, resting *Order, s *bookSide, level *priceLevel, result *MatchResult, now time.Time) bool {
+	cancelResting := func() {
+		b.popFront(s, level)
+		resting.Status = OrderStatusCancelled
+		resting.UpdatedAt = now
+		result.prevented = append(result.prevented, *resting)
 	}
 
-	e.buyOrders[productID] = buyOrders
-	return result
+	switch order.STP {
+	case STPCancelOldest:
+		cancelResting()
+		return true
+	case STPCancelBoth:
+		cancelResting()
+		order.Status = OrderStatusCancelled
+		return false
+	case STPDecrement:
+		qty := min(order.RemainingQty, resting.RemainingQty)
+		order.RemainingQty -= qty
+		order.UpdatedAt = now
+		result.CancelledQty += qty
+		resting.RemainingQty -= qty
+		b.reduceLevel(s, level, qty)
+		if resting.RemainingQty == 0 {
+			cancelResting()
+		} else {
+			resting.UpdatedAt = now
+			result.prevented = append(result.prevented, *resting)
+		}
+		if order.RemainingQty == 0 {
+			order.Status = OrderStatusCancelled
+			return false
+		}
+		return true
+	default: // STPCancelNewest
+		order.Status = OrderStatusCancelled
+		return false
+	}
 }
 
-// addBuyOrder adds a buy order to the book (sorted by price desc, time asc).
-func (e *Engine) addBuyOrder(order *Order) {
-	orders := e.buyOrders[order.ProductID]
-	orders = append(orders, order)
-	sort.Slice(orders, func(i, j int) bool {
-		if orders[i].Price != orders[j].Price {
-			return orders[i].Price > orders[j].Price // Higher price first
+// ExpireOrders removes GTD orders whose expiry has passed from the book.
+// It returns the number of orders expired.
+func (e *Engine) ExpireOrders(ctx context.Context, now time.Time) (int, error) {
+	var expired []Order
+	var firstErr error
+	for _, b := range e.allBooks() {
+		orders, err := e.expireOrders(ctx, b, now)
+		expired = append(expired, orders...)
+		if err != nil && firstErr == nil {
+			firstErr = err
 		}
-		return orders[i].CreatedAt.Before(orders[j].CreatedAt) // Earlier time first
-	})
-	e.buyOrders[order.ProductID] = orders
+	}
+	e.notifyExpired(ctx, expired)
+	return len(expired), firstErr
 }
 
-// addSellOrder adds a sell order to the book (sorted by price asc, time asc).
-func (e *Engine) addSellOrder(order *Order) {
-	orders := e.sellOrders[order.ProductID]
-	orders = append(orders, order)
-	sort.Slice(orders, func(i, j int) bool {
-		if orders[i].Price != orders[j].Price {
-			return orders[i].Price < orders[j].Price // Lower price first
+// expireOrders sweeps one product's GTD orders under its lock.
+func (e *Engine) expireOrders(ctx context.Context, b *book, now time.Time) ([]Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var expired []Order
+	for _, order := range b.expiring {
+		if !order.isExpired(now) {
+			continue
 		}
-		return orders[i].CreatedAt.Before(orders[j].CreatedAt) // Earlier time first
-	})
-	e.sellOrders[order.ProductID] = orders
+		if e.repo != nil {
+			if err := e.repo.UpdateOrderStatus(ctx, order.ID, OrderStatusExpired); err != nil {
+				// Retry on the next sweep
+				e.publishBook(b, nil)
+				return expired, fmt.Errorf("failed to persist expiry: %w", err)
+			}
+		}
+		if order.Status == OrderStatusPending {
+			b.removeStop(order)
+		} else {
+			b.remove(order)
+		}
+		order.Status = OrderStatusExpired
+		order.UpdatedAt = now
+		expired = append(expired, *order)
+	}
+	e.publishBook(b, nil)
+	return expired, nil
 }
 
-// GetOrderBook returns the current order book for a product.
-func (e *Engine) GetOrderBook(productID uuid.UUID, depth int) *OrderBook {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
+// notifyExpired calls the expiry handler for each expired order.
+func (e *Engine) notifyExpired(ctx context.Context, expired []Order) {
+	if e.expiryHandler == nil {
+		return
+	}
+	for _, order := range expired {
+		go e.expiryHandler(ctx, order)
+	}
+}
 
-	book := &OrderBook{
-		ProductID: productID,
+// settleTrade creates the buyer/seller transaction for a trade.
+// Failures are logged and retried by SettlePendingTrades.
+func (e *Engine) settleTrade(ctx context.Context, trade *Trade) {
+	if e.txCreator == nil {
+		return
 	}
 
-	// Aggregate bids
-	bidLevels := make(map[float64]*PriceLevel)
-	for _, order := range e.buyOrders[productID] {
-		if level, ok := bidLevels[order.Price]; ok {
-			level.Quantity += order.RemainingQty
-			level.Orders++
-		} else {
-			bidLevels[order.Price] = &PriceLevel{
-				Price:    order.Price,
-				Quantity: order.RemainingQty,
-				Orders:   1,
-			}
-		}
+	txID, err := e.txCreator.CreateFromTrade(ctx, trade.BuyerID, trade.SellerID,
+		&trade.ID, &trade.ProductID, trade.Price*trade.Quantity, e.currency(trade.ProductID))
+	if err != nil {
+		logger.Error("trade_settlement_failed", map[string]interface{}{
+			"trade_id":   trade.ID.String(),
+			"product_id": trade.ProductID.String(),
+			"error":      err.Error(),
+		})
+		return
 	}
-	for _, level := range bidLevels {
-		book.Bids = append(book.Bids, *level)
+	trade.TransactionID = &txID
+}
+
+// SettlePendingTrades creates transactions for persisted trades that have none,
+// e.g. because the process stopped between matching and settlement.
+// It returns the number of trades settled.
+func (e *Engine) SettlePendingTrades(ctx context.Context) (int, error) {
+	if e.repo == nil || e.txCreator == nil {
+		return 0, nil
 	}
-	sort.Slice(book.Bids, func(i, j int) bool {
-		return book.Bids[i].Price > book.Bids[j].Price
-	})
-	if depth > 0 && len(book.Bids) > depth {
-		book.Bids = book.Bids[:depth]
+
+	trades, err := e.repo.GetUnsettledTrades(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load unsettled trades: %w", err)
 	}
 
-	// Aggregate asks
-	askLevels := make(map[float64]*PriceLevel)
-	for _, order := range e.sellOrders[productID] {
-		if level, ok := askLevels[order.Price]; ok {
-			level.Quantity += order.RemainingQty
-			level.Orders++
-		} else {
-			askLevels[order.Price] = &PriceLevel{
-				Price:    order.Price,
-				Quantity: order.RemainingQty,
-				Orders:   1,
-			}
+	settled := 0
+	for i := range trades {
+		e.settleTrade(ctx, &trades[i])
+		if trades[i].TransactionID != nil {
+			settled++
 		}
 	}
-	for _, level := range askLevels {
-		book.Asks = append(book.Asks, *level)
+	return settled, nil
+}
+
+// GetOrderBook returns the current order book for a product.
+func (e *Engine) GetOrderBook(productID uuid.UUID, depth int) *OrderBook {
+	book := &OrderBook{
+		ProductID: productID,
 	}
-	sort.Slice(book.Asks, func(i, j int) bool {
-		return book.Asks[i].Price < book.Asks[j].Price
-	})
-	if depth > 0 && len(book.Asks) > depth {
-		book.Asks = book.Asks[:depth]
+
+	b := e.lookupBook(productID)
+	if b == nil {
+		return book
 	}
 
-	// Last price
-	if price, ok := e.lastPrices[productID]; ok {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	book.Bids = b.bids.top(depth)
+	book.Asks = b.asks.top(depth)
+
+	// Last price and 24h statistics
+	if b.hasPrice {
+		price := b.lastPrice
 		book.LastPrice = &price
 	}
+	if day := b.stats.window(time.Now().UTC()); day != nil {
+		book.Volume24h = day.Volume
+		book.High24h = &day.High
+		book.Low24h = &day.Low
+	}
 
 	return book
 }
 
+// top aggregates the best depth levels of a side (all levels if depth <= 0).
+func (s *bookSide) top(depth int) []PriceLevel {
+	var levels []PriceLevel
+	s.sorted.each(func(level *priceLevel) bool {
+		levels = append(levels, PriceLevel{
+			Price:    level.price,
+			Quantity: level.quantity,
+			Orders:   level.orders.Len(),
+		})
+		return depth <= 0 || len(levels) < depth
+	})
+	return levels
+}
+
 // CancelOrder cancels an order.
-func (e *Engine) CancelOrder(orderID uuid.UUID, agentID uuid.UUID) error {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+func (e *Engine) CancelOrder(ctx context.Context, orderID uuid.UUID, agentID uuid.UUID) error {
+	v, ok := e.index.Load(orderID)
+	if !ok {
+		return ErrOrderNotFound
+	}
+	b := v.(*book)
 
-	// Search in buy orders
-	for productID, orders := range e.buyOrders {
-		for i, order := range orders {
-			if order.ID == orderID {
-				if order.AgentID != agentID {
-					return fmt.Errorf("not authorized to cancel this order")
-				}
-				order.Status = OrderStatusCancelled
-				e.buyOrders[productID] = append(orders[:i], orders[i+1:]...)
-				return nil
-			}
-		}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// The order may have filled between the index lookup and taking the lock
+	entry, ok := b.orders[orderID]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	order := entry.order
+	if order.AgentID != agentID {
+		return ErrNotAuthorized
 	}
 
-	// Search in sell orders
-	for productID, orders := range e.sellOrders {
-		for i, order := range orders {
-			if order.ID == orderID {
-				if order.AgentID != agentID {
-					return fmt.Errorf("not authorized to cancel this order")
-				}
-				order.Status = OrderStatusCancelled
-				e.sellOrders[productID] = append(orders[:i], orders[i+1:]...)
-				return nil
-			}
+	if e.repo != nil {
+		if err := e.repo.UpdateOrderStatus(ctx, orderID, OrderStatusCancelled); err != nil {
+			return fmt.Errorf("failed to persist cancellation: %w", err)
 		}
 	}
 
-	return fmt.Errorf("order not found")
+	if entry.level == nil {
+		b.removeStop(order)
+	} else {
+		b.remove(order)
+	}
+	order.Status = OrderStatusCancelled
+	order.UpdatedAt = time.Now().UTC()
+	e.publishBook(b, nil)
+	return nil
 }
 
 func min(a, b float64) float64 {
@@ -2,7 +2,10 @@ package matching
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -12,11 +15,8 @@ func TestNewEngine(t *testing.T) {
 	if engine == nil {
 		t.Fatal("NewEngine() returned nil")
 	}
-	if engine.buyOrders == nil {
-		t.Error("buyOrders map is nil")
-	}
-	if engine.sellOrders == nil {
-		t.Error("sellOrders map is nil")
+	if engine.books == nil {
+		t.Error("books map is nil")
 	}
 }
 
@@ -226,7 +226,7 @@ func TestCancelOrder(t *testing.T) {
 	result, _ := engine.PlaceOrder(context.Background(), order)
 
 	// Cancel the order
-	err := engine.CancelOrder(result.RemainingOrder.ID, agentID)
+	err := engine.CancelOrder(context.Background(), result.RemainingOrder.ID, agentID)
 	if err != nil {
 		t.Fatalf("CancelOrder() error = %v", err)
 	}
@@ -255,7 +255,7 @@ func TestCancelOrderUnauthorized(t *testing.T) {
 	result, _ := engine.PlaceOrder(context.Background(), order)
 
 	// Try to cancel with different agent
-	err := engine.CancelOrder(result.RemainingOrder.ID, other)
+	err := engine.CancelOrder(context.Background(), result.RemainingOrder.ID, other)
 	if err == nil {
 		t.Error("Expected error for unauthorized cancel")
 	}
@@ -387,3 +387,1238 @@ func TestOrderBookDepth(t *testing.T) {
 		t.Errorf("Top bid = %f, want 110.0", book.Bids[0].Price)
 	}
 }
+
+// mockRepository is an in-memory RepositoryInterface for testing.
+type mockRepository struct {
+	orders      map[uuid.UUID]Order
+	trades      []Trade
+	saveErr     error
+	lastPrices  map[uuid.UUID]float64
+	resting     []*Order
+	unsettled   []Trade
+	candles     []Candle
+	instruments map[uuid.UUID]Instrument
+}
+
+func newMockRepository() *mockRepository {
+	return &mockRepository{
+		orders:      make(map[uuid.UUID]Order),
+		lastPrices:  make(map[uuid.UUID]float64),
+		instruments: make(map[uuid.UUID]Instrument),
+	}
+}
+
+func (m *mockRepository) SaveMatch(ctx context.Context, order *Order, filled []*Order, trades []Trade) error {
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	m.orders[order.ID] = *order
+	for _, o := range filled {
+		m.orders[o.ID] = *o
+	}
+	m.trades = append(m.trades, trades...)
+	return nil
+}
+
+func (m *mockRepository) SaveAmend(ctx context.Context, order *Order, requeue bool, filled []*Order, trades []Trade) error {
+	return m.SaveMatch(ctx, order, filled, trades)
+}
+
+func (m *mockRepository) GetOrder(ctx context.Context, orderID uuid.UUID) (*Order, error) {
+	order, ok := m.orders[orderID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	return &order, nil
+}
+
+func (m *mockRepository) ListOrders(ctx context.Context, params ListOrdersParams) (*OrderListResult, error) {
+	result := &OrderListResult{Items: []*Order{}, Limit: params.Limit, Offset: params.Offset}
+	for _, order := range m.orders {
+		if order.AgentID == params.AgentID {
+			o := order
+			result.Items = append(result.Items, &o)
+		}
+	}
+	result.Total = len(result.Items)
+	return result, nil
+}
+
+func (m *mockRepository) GetOrderTrades(ctx context.Context, orderID uuid.UUID) ([]Trade, error) {
+	trades := []Trade{}
+	for _, trade := range m.trades {
+		if trade.BuyOrderID == orderID || trade.SellOrderID == orderID {
+			trades = append(trades, trade)
+		}
+	}
+	return trades, nil
+}
+
+func (m *mockRepository) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status OrderStatus) error {
+	order, ok := m.orders[orderID]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	order.Status = status
+	m.orders[orderID] = order
+	return nil
+}
+
+func (m *mockRepository) CreateInstrument(ctx context.Context, inst *Instrument) error {
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	m.instruments[inst.ID] = *inst
+	return nil
+}
+
+func (m *mockRepository) UpdateInstrument(ctx context.Context, inst *Instrument) error {
+	return m.CreateInstrument(ctx, inst)
+}
+
+func (m *mockRepository) GetInstruments(ctx context.Context) ([]*Instrument, error) {
+	var instruments []*Instrument
+	for _, inst := range m.instruments {
+		i := inst
+		instruments = append(instruments, &i)
+	}
+	return instruments, nil
+}
+
+func (m *mockRepository) HasProductActivity(ctx context.Context, productID uuid.UUID) (bool, error) {
+	for _, order := range m.orders {
+		if order.ProductID == productID {
+			return true, nil
+		}
+	}
+	for _, trade := range m.trades {
+		if trade.ProductID == productID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRepository) GetRestingOrders(ctx context.Context) ([]*Order, error) {
+	return m.resting, nil
+}
+
+func (m *mockRepository) GetLastPrices(ctx context.Context) (map[uuid.UUID]float64, error) {
+	return m.lastPrices, nil
+}
+
+func (m *mockRepository) GetTradesSince(ctx context.Context, since time.Time) ([]Trade, error) {
+	var trades []Trade
+	for _, trade := range m.trades {
+		if !trade.CreatedAt.Before(since) {
+			trades = append(trades, trade)
+		}
+	}
+	return trades, nil
+}
+
+func (m *mockRepository) GetCandles(ctx context.Context, productID uuid.UUID, interval time.Duration, from, to time.Time, limit int) ([]Candle, error) {
+	return m.candles, nil
+}
+
+func (m *mockRepository) GetUnsettledTrades(ctx context.Context) ([]Trade, error) {
+	return m.unsettled, nil
+}
+
+// mockTransactionCreator records the transactions created for trades.
+type mockTransactionCreator struct {
+	mu       sync.Mutex
+	amounts  map[uuid.UUID]float64 // TradeID -> amount
+	currency string                // Of the latest transaction
+	err      error
+}
+
+func (m *mockTransactionCreator) CreateFromTrade(ctx context.Context, buyerID, sellerID uuid.UUID, tradeID, productID *uuid.UUID, amount float64, currency string) (uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return uuid.Nil, m.err
+	}
+	if m.amounts == nil {
+		m.amounts = make(map[uuid.UUID]float64)
+	}
+	m.amounts[*tradeID] = amount
+	m.currency = currency
+	return uuid.New(), nil
+}
+
+func TestPlaceOrderPersistsMatch(t *testing.T) {
+	repo := newMockRepository()
+	engine := NewEngine(nil)
+	engine.SetRepository(repo)
+	productID := uuid.New()
+
+	sell := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100.0, Quantity: 10.0}
+	engine.PlaceOrder(context.Background(), sell)
+
+	buy := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 100.0, Quantity: 4.0}
+	if _, err := engine.PlaceOrder(context.Background(), buy); err != nil {
+		t.Fatalf("PlaceOrder() error = %v", err)
+	}
+
+	if len(repo.trades) != 1 {
+		t.Fatalf("Expected 1 persisted trade, got %d", len(repo.trades))
+	}
+	if got := repo.orders[sell.ID]; got.Status != OrderStatusPartial || got.RemainingQty != 6.0 {
+		t.Errorf("Persisted sell order = %s/%f, want partial/6.0", got.Status, got.RemainingQty)
+	}
+	if got := repo.orders[buy.ID]; got.Status != OrderStatusFilled {
+		t.Errorf("Persisted buy order status = %s, want filled", got.Status)
+	}
+}
+
+func TestPlaceOrderRollsBackOnPersistFailure(t *testing.T) {
+	repo := newMockRepository()
+	engine := NewEngine(nil)
+	engine.SetRepository(repo)
+	productID := uuid.New()
+
+	sell := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100.0, Quantity: 10.0}
+	engine.PlaceOrder(context.Background(), sell)
+
+	repo.saveErr = errors.New("database unavailable")
+	buy := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 105.0, Quantity: 15.0}
+	if _, err := engine.PlaceOrder(context.Background(), buy); err == nil {
+		t.Fatal("Expected error when persistence fails")
+	}
+
+	// The book must look exactly as it did before the failed order
+	book := engine.GetOrderBook(productID, 10)
+	if len(book.Bids) != 0 {
+		t.Errorf("Expected no bids after rollback, got %d", len(book.Bids))
+	}
+	if len(book.Asks) != 1 || book.Asks[0].Quantity != 10.0 {
+		t.Errorf("Expected ask of 10.0 after rollback, got %+v", book.Asks)
+	}
+	if sell.Status != OrderStatusOpen || sell.RemainingQty != 10.0 {
+		t.Errorf("Sell order = %s/%f, want open/10.0", sell.Status, sell.RemainingQty)
+	}
+	if book.LastPrice != nil {
+		t.Errorf("LastPrice = %f, want none", *book.LastPrice)
+	}
+}
+
+func TestCancelOrderPersistsStatus(t *testing.T) {
+	repo := newMockRepository()
+	engine := NewEngine(nil)
+	engine.SetRepository(repo)
+	agentID := uuid.New()
+
+	order := &Order{AgentID: agentID, ProductID: uuid.New(), Side: OrderSideBuy, Type: OrderTypeLimit, Price: 100.0, Quantity: 10.0}
+	engine.PlaceOrder(context.Background(), order)
+
+	if err := engine.CancelOrder(context.Background(), order.ID, agentID); err != nil {
+		t.Fatalf("CancelOrder() error = %v", err)
+	}
+	if got := repo.orders[order.ID]; got.Status != OrderStatusCancelled {
+		t.Errorf("Persisted status = %s, want cancelled", got.Status)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	repo := newMockRepository()
+	productID := uuid.New()
+	now := time.Now().UTC()
+
+	first := &Order{ID: uuid.New(), AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit,
+		Price: 99.0, Quantity: 5.0, RemainingQty: 5.0, Status: OrderStatusOpen, CreatedAt: now}
+	second := &Order{ID: uuid.New(), AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit,
+		Price: 99.0, Quantity: 8.0, FilledQty: 2.0, RemainingQty: 6.0, Status: OrderStatusPartial, CreatedAt: now.Add(time.Second)}
+	ask := &Order{ID: uuid.New(), AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit,
+		Price: 101.0, Quantity: 3.0, RemainingQty: 3.0, Status: OrderStatusOpen, CreatedAt: now}
+	repo.resting = []*Order{first, ask, second}
+	repo.lastPrices[productID] = 100.0
+
+	engine := NewEngine(nil)
+	engine.SetRepository(repo)
+	restored, err := engine.Recover(context.Background())
+	if err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	if restored != 3 {
+		t.Errorf("Recover() restored %d orders, want 3", restored)
+	}
+
+	book := engine.GetOrderBook(productID, 10)
+	if len(book.Bids) != 1 || book.Bids[0].Quantity != 11.0 {
+		t.Errorf("Expected one bid level of 11.0, got %+v", book.Bids)
+	}
+	if len(book.Asks) != 1 || book.Asks[0].Quantity != 3.0 {
+		t.Errorf("Expected one ask level of 3.0, got %+v", book.Asks)
+	}
+	if book.LastPrice == nil || *book.LastPrice != 100.0 {
+		t.Errorf("LastPrice = %v, want 100.0", book.LastPrice)
+	}
+
+	// Time priority survives the restart
+	sell := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 5.0}
+	result, err := engine.PlaceOrder(context.Background(), sell)
+	if err != nil {
+		t.Fatalf("PlaceOrder() error = %v", err)
+	}
+	if len(result.Trades) != 1 || result.Trades[0].BuyOrderID != first.ID {
+		t.Errorf("Expected the earliest recovered bid to fill first")
+	}
+}
+
+func TestPlaceOrderCreatesTransactions(t *testing.T) {
+	creator := &mockTransactionCreator{}
+	engine := NewEngine(nil)
+	engine.SetTransactionCreator(creator)
+	productID := uuid.New()
+
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 10.0, Quantity: 3.0})
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 11.0, Quantity: 3.0})
+
+	buy := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 5.0}
+	result, err := engine.PlaceOrder(context.Background(), buy)
+	if err != nil {
+		t.Fatalf("PlaceOrder() error = %v", err)
+	}
+
+	if len(result.Trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %d", len(result.Trades))
+	}
+	for _, trade := range result.Trades {
+		if trade.TransactionID == nil {
+			t.Errorf("Trade %s has no transaction", trade.ID)
+		}
+	}
+	if got := creator.amounts[result.Trades[0].ID]; got != 30.0 {
+		t.Errorf("First transaction amount = %f, want 30.0", got)
+	}
+	if got := creator.amounts[result.Trades[1].ID]; got != 22.0 {
+		t.Errorf("Second transaction amount = %f, want 22.0", got)
+	}
+}
+
+func TestPlaceOrderSettlementFailureKeepsTrade(t *testing.T) {
+	creator := &mockTransactionCreator{err: errors.New("database unavailable")}
+	engine := NewEngine(nil)
+	engine.SetTransactionCreator(creator)
+	productID := uuid.New()
+
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 10.0, Quantity: 3.0})
+	result, err := engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 10.0, Quantity: 3.0})
+	if err != nil {
+		t.Fatalf("PlaceOrder() error = %v", err)
+	}
+	if len(result.Trades) != 1 || result.Trades[0].TransactionID != nil {
+		t.Errorf("Expected one unsettled trade, got %+v", result.Trades)
+	}
+}
+
+func TestSettlePendingTrades(t *testing.T) {
+	repo := newMockRepository()
+	repo.unsettled = []Trade{
+		{ID: uuid.New(), ProductID: uuid.New(), BuyerID: uuid.New(), SellerID: uuid.New(), Price: 5.0, Quantity: 2.0},
+		{ID: uuid.New(), ProductID: uuid.New(), BuyerID: uuid.New(), SellerID: uuid.New(), Price: 7.0, Quantity: 1.0},
+	}
+	creator := &mockTransactionCreator{}
+	engine := NewEngine(nil)
+	engine.SetRepository(repo)
+	engine.SetTransactionCreator(creator)
+
+	settled, err := engine.SettlePendingTrades(context.Background())
+	if err != nil {
+		t.Fatalf("SettlePendingTrades() error = %v", err)
+	}
+	if settled != 2 {
+		t.Errorf("SettlePendingTrades() settled %d, want 2", settled)
+	}
+	if got := creator.amounts[repo.unsettled[0].ID]; got != 10.0 {
+		t.Errorf("Transaction amount = %f, want 10.0", got)
+	}
+}
+
+func TestIOCOrderCancelsRemainder(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100.0, Quantity: 4.0})
+
+	buy := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 100.0, Quantity: 10.0, TimeInForce: TimeInForceIOC}
+	result, err := engine.PlaceOrder(context.Background(), buy)
+	if err != nil {
+		t.Fatalf("PlaceOrder() error = %v", err)
+	}
+
+	if len(result.Trades) != 1 || result.Trades[0].Quantity != 4.0 {
+		t.Fatalf("Expected one trade of 4.0, got %+v", result.Trades)
+	}
+	if result.CancelledQty != 6.0 {
+		t.Errorf("CancelledQty = %f, want 6.0", result.CancelledQty)
+	}
+	if result.RemainingOrder != nil {
+		t.Error("IOC order should not rest in book")
+	}
+	if buy.Status != OrderStatusCancelled {
+		t.Errorf("Status = %s, want cancelled", buy.Status)
+	}
+	if book := engine.GetOrderBook(productID, 10); len(book.Bids) != 0 {
+		t.Errorf("Expected no bids, got %d", len(book.Bids))
+	}
+}
+
+func TestFOKOrderKilledWhenNotFullyFillable(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100.0, Quantity: 4.0})
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 102.0, Quantity: 4.0})
+
+	// Only 4.0 is available at or below 101
+	buy := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 101.0, Quantity: 6.0, TimeInForce: TimeInForceFOK}
+	result, err := engine.PlaceOrder(context.Background(), buy)
+	if err != nil {
+		t.Fatalf("PlaceOrder() error = %v", err)
+	}
+	if len(result.Trades) != 0 {
+		t.Errorf("Expected no trades, got %d", len(result.Trades))
+	}
+	if result.CancelledQty != 6.0 {
+		t.Errorf("CancelledQty = %f, want 6.0", result.CancelledQty)
+	}
+	if book := engine.GetOrderBook(productID, 10); book.Asks[0].Quantity != 4.0 {
+		t.Errorf("Best ask quantity = %f, want 4.0 (untouched)", book.Asks[0].Quantity)
+	}
+
+	// Enough liquidity at 102 fills the whole order
+	buy = &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 102.0, Quantity: 6.0, TimeInForce: TimeInForceFOK}
+	result, _ = engine.PlaceOrder(context.Background(), buy)
+	if len(result.Trades) != 2 || buy.Status != OrderStatusFilled {
+		t.Errorf("Expected full fill in 2 trades, got %d trades, status %s", len(result.Trades), buy.Status)
+	}
+}
+
+func TestMarketOrderReportsCancelledQty(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 100.0, Quantity: 2.0})
+
+	sell := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 5.0}
+	result, _ := engine.PlaceOrder(context.Background(), sell)
+	if sell.TimeInForce != TimeInForceIOC {
+		t.Errorf("TimeInForce = %s, want IOC", sell.TimeInForce)
+	}
+	if result.CancelledQty != 3.0 {
+		t.Errorf("CancelledQty = %f, want 3.0", result.CancelledQty)
+	}
+}
+
+func TestInvalidTimeInForce(t *testing.T) {
+	engine := NewEngine(nil)
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name  string
+		order *Order
+		want  error
+	}{
+		{"market GTC", &Order{Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 1.0, TimeInForce: TimeInForceGTC}, ErrInvalidTimeInForce},
+		{"unknown", &Order{Side: OrderSideBuy, Type: OrderTypeLimit, Price: 1.0, Quantity: 1.0, TimeInForce: "DAY"}, ErrInvalidTimeInForce},
+		{"GTD without expiry", &Order{Side: OrderSideBuy, Type: OrderTypeLimit, Price: 1.0, Quantity: 1.0, TimeInForce: TimeInForceGTD}, ErrInvalidExpiry},
+		{"GTD in the past", &Order{Side: OrderSideBuy, Type: OrderTypeLimit, Price: 1.0, Quantity: 1.0, TimeInForce: TimeInForceGTD, ExpiresAt: &past}, ErrInvalidExpiry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := engine.PlaceOrder(context.Background(), tt.order); !errors.Is(err, tt.want) {
+				t.Errorf("PlaceOrder() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpireOrders(t *testing.T) {
+	repo := newMockRepository()
+	engine := NewEngine(nil)
+	engine.SetRepository(repo)
+
+	expiredCh := make(chan Order, 1)
+	engine.SetExpiryHandler(func(ctx context.Context, order Order) {
+		expiredCh <- order
+	})
+
+	productID := uuid.New()
+	expiresAt := time.Now().Add(time.Minute)
+	gtd := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 100.0, Quantity: 5.0, TimeInForce: TimeInForceGTD, ExpiresAt: &expiresAt}
+	engine.PlaceOrder(context.Background(), gtd)
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 99.0, Quantity: 5.0})
+
+	// Nothing has expired yet
+	if n, _ := engine.ExpireOrders(context.Background(), time.Now()); n != 0 {
+		t.Errorf("ExpireOrders() = %d before expiry, want 0", n)
+	}
+
+	n, err := engine.ExpireOrders(context.Background(), expiresAt.Add(time.Second))
+	if err != nil {
+		t.Fatalf("ExpireOrders() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("ExpireOrders() = %d, want 1", n)
+	}
+
+	book := engine.GetOrderBook(productID, 10)
+	if len(book.Bids) != 1 || book.Bids[0].Price != 99.0 {
+		t.Errorf("Expected only the GTC bid to remain, got %+v", book.Bids)
+	}
+	if got := repo.orders[gtd.ID]; got.Status != OrderStatusExpired {
+		t.Errorf("Persisted status = %s, want expired", got.Status)
+	}
+
+	select {
+	case order := <-expiredCh:
+		if order.ID != gtd.ID {
+			t.Errorf("Expired order = %s, want %s", order.ID, gtd.ID)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected expiry handler to be called")
+	}
+}
+
+func TestExpiredOrderSkippedWhenMatching(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	stale := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100.0, Quantity: 5.0, TimeInForce: TimeInForceGTD, ExpiresAt: &expiresAt}
+	engine.PlaceOrder(context.Background(), stale)
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 101.0, Quantity: 5.0})
+
+	time.Sleep(60 * time.Millisecond)
+
+	result, _ := engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 2.0})
+	if len(result.Trades) != 1 || result.Trades[0].Price != 101.0 {
+		t.Errorf("Expected a trade at 101.0, got %+v", result.Trades)
+	}
+	if stale.Status != OrderStatusExpired {
+		t.Errorf("Stale order status = %s, want expired", stale.Status)
+	}
+}
+
+func TestStopOrderTriggersOnLastPrice(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+	seller := uuid.New()
+
+	engine.PlaceOrder(context.Background(), &Order{AgentID: seller, ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100.0, Quantity: 1.0})
+	engine.PlaceOrder(context.Background(), &Order{AgentID: seller, ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 105.0, Quantity: 5.0})
+
+	// Buy stop at 100: hidden until something trades at 100 or above
+	stop := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeStop, StopPrice: 100.0, Quantity: 2.0}
+	result, err := engine.PlaceOrder(context.Background(), stop)
+	if err != nil {
+		t.Fatalf("PlaceOrder() error = %v", err)
+	}
+	if len(result.Trades) != 0 || stop.Status != OrderStatusPending {
+		t.Fatalf("Stop should wait for its trigger, got status %s", stop.Status)
+	}
+	if book := engine.GetOrderBook(productID, 10); len(book.Bids) != 0 {
+		t.Errorf("Stop orders must not show in the book, got %d bids", len(book.Bids))
+	}
+
+	// A trade at 100 triggers the stop, which then buys at 105
+	result, _ = engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 1.0})
+	if len(result.Trades) != 1 {
+		t.Fatalf("Expected 1 trade for the incoming order, got %d", len(result.Trades))
+	}
+	if len(result.cascade) != 1 || result.cascade[0].Price != 105.0 || result.cascade[0].Quantity != 2.0 {
+		t.Errorf("Expected the stop to fill 2.0 @ 105.0, got %+v", result.cascade)
+	}
+	if stop.Status != OrderStatusFilled || stop.TriggeredAt == nil {
+		t.Errorf("Stop status = %s, want filled and triggered", stop.Status)
+	}
+}
+
+func TestStopLimitRestsAfterTrigger(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 50.0, Quantity: 1.0})
+
+	stop := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeStopLimit, StopPrice: 50.0, Price: 49.0, Quantity: 3.0}
+	engine.PlaceOrder(context.Background(), stop)
+
+	// Trade at 50 triggers the sell stop-limit; no bids left at 49 or above, so it rests
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 1.0})
+
+	if stop.Status != OrderStatusOpen {
+		t.Errorf("Stop-limit status = %s, want open", stop.Status)
+	}
+	book := engine.GetOrderBook(productID, 10)
+	if len(book.Asks) != 1 || book.Asks[0].Price != 49.0 || book.Asks[0].Quantity != 3.0 {
+		t.Errorf("Expected ask 3.0 @ 49.0, got %+v", book.Asks)
+	}
+}
+
+func TestStopCascadeIsDeterministic(t *testing.T) {
+	run := func() []Trade {
+		engine := NewEngine(nil)
+		productID := uuid.New()
+		buyers := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+
+		for _, price := range []float64{98.0, 97.0, 96.0, 95.0} {
+			engine.PlaceOrder(context.Background(), &Order{AgentID: buyers[0], ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: price, Quantity: 1.0})
+		}
+
+		// Each stop's fill moves the last price down and triggers the next
+		engine.PlaceOrder(context.Background(), &Order{AgentID: buyers[1], ProductID: productID, Side: OrderSideSell, Type: OrderTypeStop, StopPrice: 97.0, Quantity: 1.0})
+		engine.PlaceOrder(context.Background(), &Order{AgentID: buyers[2], ProductID: productID, Side: OrderSideSell, Type: OrderTypeStop, StopPrice: 98.0, Quantity: 1.0})
+		engine.PlaceOrder(context.Background(), &Order{AgentID: buyers[1], ProductID: productID, Side: OrderSideSell, Type: OrderTypeStop, StopPrice: 96.0, Quantity: 1.0})
+
+		result, _ := engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 1.0})
+		return append(result.Trades, result.cascade...)
+	}
+
+	want := []float64{98.0, 97.0, 96.0, 95.0}
+	for i := 0; i < 5; i++ {
+		trades := run()
+		if len(trades) != len(want) {
+			t.Fatalf("Expected %d trades, got %d", len(want), len(trades))
+		}
+		for j, trade := range trades {
+			if trade.Price != want[j] {
+				t.Fatalf("Trade %d price = %f, want %f", j, trade.Price, want[j])
+			}
+		}
+	}
+}
+
+func TestStopOrderTriggersImmediatelyWhenCrossed(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100.0, Quantity: 2.0})
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 100.0, Quantity: 1.0})
+
+	// Last price (100) is already above the stop, so it triggers on placement
+	stop := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeStop, StopPrice: 90.0, Quantity: 1.0}
+	result, err := engine.PlaceOrder(context.Background(), stop)
+	if err != nil {
+		t.Fatalf("PlaceOrder() error = %v", err)
+	}
+	if len(result.Trades) != 1 || stop.Status != OrderStatusFilled {
+		t.Errorf("Expected the stop to fill immediately, got %d trades, status %s", len(result.Trades), stop.Status)
+	}
+}
+
+func TestStopOrderRequiresStopPrice(t *testing.T) {
+	engine := NewEngine(nil)
+	_, err := engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: uuid.New(), Side: OrderSideBuy, Type: OrderTypeStop, Quantity: 1.0})
+	if !errors.Is(err, ErrInvalidStopPrice) {
+		t.Errorf("PlaceOrder() error = %v, want ErrInvalidStopPrice", err)
+	}
+}
+
+func TestCancelStopOrder(t *testing.T) {
+	engine := NewEngine(nil)
+	agentID := uuid.New()
+
+	stop := &Order{AgentID: agentID, ProductID: uuid.New(), Side: OrderSideSell, Type: OrderTypeStop, StopPrice: 10.0, Quantity: 1.0}
+	engine.PlaceOrder(context.Background(), stop)
+
+	if err := engine.CancelOrder(context.Background(), stop.ID, agentID); err != nil {
+		t.Fatalf("CancelOrder() error = %v", err)
+	}
+	if stop.Status != OrderStatusCancelled {
+		t.Errorf("Status = %s, want cancelled", stop.Status)
+	}
+}
+
+func TestOrderBook24hStats(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+
+	for _, price := range []float64{100, 104, 98} {
+		engine.PlaceOrder(context.Background(), &Order{
+			AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell,
+			Type: OrderTypeLimit, Price: price, Quantity: 2,
+		})
+		engine.PlaceOrder(context.Background(), &Order{
+			AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy,
+			Type: OrderTypeMarket, Quantity: 2,
+		})
+	}
+
+	book := engine.GetOrderBook(productID, 10)
+	if book.Volume24h != 6 {
+		t.Errorf("Volume24h = %v, want 6", book.Volume24h)
+	}
+	if book.High24h == nil || *book.High24h != 104 || book.Low24h == nil || *book.Low24h != 98 {
+		t.Errorf("High24h/Low24h = %v/%v, want 104/98", book.High24h, book.Low24h)
+	}
+
+	ticker := engine.GetTicker(productID, time.Now().UTC())
+	if ticker.Open24h == nil || *ticker.Open24h != 100 || ticker.Trades24h != 3 {
+		t.Errorf("ticker = %+v, want open 100 and 3 trades", ticker)
+	}
+	if ticker.Change24h == nil || *ticker.Change24h != -2 {
+		t.Errorf("Change24h = %v, want -2", ticker.Change24h)
+	}
+	if tickers := engine.GetTickers(time.Now().UTC()); len(tickers) != 1 || tickers[0].ProductID != productID {
+		t.Errorf("GetTickers() = %v, want one ticker", tickers)
+	}
+}
+
+func TestTradeStatsWindowRollsOff(t *testing.T) {
+	var stats tradeStats
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	stats.add(Trade{Price: 10, Quantity: 1, CreatedAt: start})
+	stats.add(Trade{Price: 20, Quantity: 1, CreatedAt: start.Add(23 * time.Hour)})
+
+	if day := stats.window(start.Add(23 * time.Hour)); day == nil || day.Volume != 2 || day.Open != 10 || day.Close != 20 {
+		t.Errorf("window = %+v, want both trades", day)
+	}
+	if day := stats.window(start.Add(25 * time.Hour)); day == nil || day.Volume != 1 || day.Open != 20 {
+		t.Errorf("window = %+v, want only the second trade", day)
+	}
+
+	// A trade a day later reuses the first trade's bucket
+	stats.add(Trade{Price: 30, Quantity: 5, CreatedAt: start.Add(24 * time.Hour)})
+	if day := stats.window(start.Add(24 * time.Hour)); day.Volume != 6 || day.High != 30 {
+		t.Errorf("window = %+v, want volume 6 and high 30", day)
+	}
+}
+
+func TestGetCandles(t *testing.T) {
+	repo := newMockRepository()
+	repo.candles = []Candle{{Open: 1, High: 2, Low: 1, Close: 2, Volume: 3, Trades: 2}}
+	engine := NewEngine(nil)
+	engine.SetRepository(repo)
+	now := time.Now().UTC()
+
+	if _, err := engine.GetCandles(context.Background(), uuid.New(), "2m", now.Add(-time.Hour), now, 10); !errors.Is(err, ErrInvalidInterval) {
+		t.Errorf("GetCandles() error = %v, want ErrInvalidInterval", err)
+	}
+	candles, err := engine.GetCandles(context.Background(), uuid.New(), "5m", now.Add(-time.Hour), now, 10)
+	if err != nil || len(candles) != 1 {
+		t.Errorf("GetCandles() = %v, %v, want one candle", candles, err)
+	}
+}
+
+func TestSelfTradePrevention(t *testing.T) {
+	tests := []struct {
+		mode            STPMode
+		wantTrades      int
+		wantTakerStatus OrderStatus
+		wantTakerRest   float64
+		wantMakerStatus OrderStatus
+		wantMakerRest   float64
+	}{
+		{STPNone, 1, OrderStatusPartial, 2, OrderStatusFilled, 0},
+		{STPCancelNewest, 0, OrderStatusCancelled, 5, OrderStatusOpen, 3},
+		{STPCancelOldest, 0, OrderStatusOpen, 5, OrderStatusCancelled, 3},
+		{STPCancelBoth, 0, OrderStatusCancelled, 5, OrderStatusCancelled, 3},
+		{STPDecrement, 0, OrderStatusOpen, 2, OrderStatusCancelled, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			engine := NewEngine(nil)
+			productID := uuid.New()
+			agentID := uuid.New()
+
+			maker := &Order{AgentID: agentID, ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 3}
+			engine.PlaceOrder(context.Background(), maker)
+
+			taker := &Order{AgentID: agentID, ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 100, Quantity: 5, STP: tt.mode}
+			result, err := engine.PlaceOrder(context.Background(), taker)
+			if err != nil {
+				t.Fatalf("PlaceOrder() error = %v", err)
+			}
+
+			if len(result.Trades) != tt.wantTrades {
+				t.Errorf("trades = %d, want %d", len(result.Trades), tt.wantTrades)
+			}
+			if taker.Status != tt.wantTakerStatus || taker.RemainingQty != tt.wantTakerRest {
+				t.Errorf("taker = %s/%v, want %s/%v", taker.Status, taker.RemainingQty, tt.wantTakerStatus, tt.wantTakerRest)
+			}
+			if maker.Status != tt.wantMakerStatus || maker.RemainingQty != tt.wantMakerRest {
+				t.Errorf("maker = %s/%v, want %s/%v", maker.Status, maker.RemainingQty, tt.wantMakerStatus, tt.wantMakerRest)
+			}
+
+			// Cancelled orders are off the book, others still rest
+			book := engine.GetOrderBook(productID, 10)
+			askQty, bidQty := 0.0, 0.0
+			for _, level := range book.Asks {
+				askQty += level.Quantity
+			}
+			for _, level := range book.Bids {
+				bidQty += level.Quantity
+			}
+			if maker.Status == OrderStatusOpen && askQty != maker.RemainingQty || maker.Status != OrderStatusOpen && askQty != 0 {
+				t.Errorf("asks = %v, maker %s/%v", askQty, maker.Status, maker.RemainingQty)
+			}
+			if taker.Status == OrderStatusCancelled && bidQty != 0 || taker.Status != OrderStatusCancelled && bidQty != taker.RemainingQty {
+				t.Errorf("bids = %v, taker %s/%v", bidQty, taker.Status, taker.RemainingQty)
+			}
+		})
+	}
+}
+
+func TestSelfTradePreventionSkipsToOtherAgents(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+	agentID := uuid.New()
+
+	own := &Order{AgentID: agentID, ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 1}
+	engine.PlaceOrder(context.Background(), own)
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 101, Quantity: 1})
+
+	result, _ := engine.PlaceOrder(context.Background(), &Order{
+		AgentID: agentID, ProductID: productID, Side: OrderSideBuy,
+		Type: OrderTypeLimit, Price: 101, Quantity: 1, STP: STPCancelOldest,
+	})
+	if len(result.Trades) != 1 || result.Trades[0].Price != 101 {
+		t.Errorf("trades = %+v, want one trade at 101", result.Trades)
+	}
+	if own.Status != OrderStatusCancelled {
+		t.Errorf("own order status = %s, want cancelled", own.Status)
+	}
+}
+
+func TestSelfTradePreventionSameOwner(t *testing.T) {
+	ownerID := uuid.New()
+	for _, sameOwner := range []bool{false, true} {
+		engine := NewEngine(nil)
+		if err := engine.SetSelfTradePrevention(STPCancelNewest, sameOwner); err != nil {
+			t.Fatalf("SetSelfTradePrevention() error = %v", err)
+		}
+		productID := uuid.New()
+
+		engine.PlaceOrder(context.Background(), &Order{
+			AgentID: uuid.New(), OwnerID: &ownerID, ProductID: productID,
+			Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 1,
+		})
+		result, _ := engine.PlaceOrder(context.Background(), &Order{
+			AgentID: uuid.New(), OwnerID: &ownerID, ProductID: productID,
+			Side: OrderSideBuy, Type: OrderTypeLimit, Price: 100, Quantity: 1,
+		})
+
+		if traded := len(result.Trades) == 1; traded == sameOwner {
+			t.Errorf("sameOwner = %v: traded = %v", sameOwner, traded)
+		}
+	}
+}
+
+func TestSelfTradePreventionFOK(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+	agentID := uuid.New()
+
+	engine.PlaceOrder(context.Background(), &Order{AgentID: agentID, ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 1})
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 101, Quantity: 1})
+
+	// Matching would stop at the own order, so the FOK can't fill completely
+	result, _ := engine.PlaceOrder(context.Background(), &Order{
+		AgentID: agentID, ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit,
+		Price: 101, Quantity: 2, TimeInForce: TimeInForceFOK, STP: STPCancelNewest,
+	})
+	if len(result.Trades) != 0 || result.CancelledQty != 2 {
+		t.Errorf("trades = %d, cancelled = %v, want 0 and 2", len(result.Trades), result.CancelledQty)
+	}
+}
+
+func TestInvalidSTPMode(t *testing.T) {
+	engine := NewEngine(nil)
+	if err := engine.SetSelfTradePrevention("sometimes", false); !errors.Is(err, ErrInvalidSTPMode) {
+		t.Errorf("SetSelfTradePrevention() error = %v, want ErrInvalidSTPMode", err)
+	}
+	_, err := engine.PlaceOrder(context.Background(), &Order{
+		AgentID: uuid.New(), ProductID: uuid.New(), Side: OrderSideBuy,
+		Type: OrderTypeLimit, Price: 1, Quantity: 1, STP: "sometimes",
+	})
+	if !errors.Is(err, ErrInvalidSTPMode) {
+		t.Errorf("PlaceOrder() error = %v, want ErrInvalidSTPMode", err)
+	}
+}
+
+func TestAmendOrderPriority(t *testing.T) {
+	reduced, increased, away, back := 3.0, 8.0, 101.0, 100.0
+	tests := []struct {
+		name      string
+		amends    []OrderAmendment
+		wantFirst int // Index of the resting order a later buy fills first
+	}{
+		{"reduce keeps priority", []OrderAmendment{{Quantity: &reduced}}, 0},
+		{"increase re-queues", []OrderAmendment{{Quantity: &increased}}, 1},
+		{"price change re-queues", []OrderAmendment{{Price: &away}, {Price: &back}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(nil)
+			productID := uuid.New()
+
+			first := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 5}
+			second := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 5}
+			engine.PlaceOrder(context.Background(), first)
+			engine.PlaceOrder(context.Background(), second)
+
+			for _, amend := range tt.amends {
+				if _, _, err := engine.AmendOrder(context.Background(), first.ID, first.AgentID, amend); err != nil {
+					t.Fatalf("AmendOrder() error = %v", err)
+				}
+			}
+
+			result, _ := engine.PlaceOrder(context.Background(), &Order{
+				AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy,
+				Type: OrderTypeLimit, Price: 100, Quantity: 1,
+			})
+			want := []*Order{first, second}[tt.wantFirst]
+			if len(result.Trades) != 1 || result.Trades[0].SellOrderID != want.ID {
+				t.Errorf("trades = %+v, want a fill of order %d", result.Trades, tt.wantFirst)
+			}
+		})
+	}
+}
+
+func TestAmendOrderReducesLevel(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+	agentID := uuid.New()
+
+	order := &Order{AgentID: agentID, ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 100, Quantity: 10}
+	engine.PlaceOrder(context.Background(), order)
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 4})
+
+	qty := 6.0
+	amended, _, err := engine.AmendOrder(context.Background(), order.ID, agentID, OrderAmendment{Quantity: &qty})
+	if err != nil {
+		t.Fatalf("AmendOrder() error = %v", err)
+	}
+	if amended.Quantity != 6 || amended.FilledQty != 4 || amended.RemainingQty != 2 {
+		t.Errorf("amended = %v/%v/%v, want 6/4/2", amended.Quantity, amended.FilledQty, amended.RemainingQty)
+	}
+	book := engine.GetOrderBook(productID, 10)
+	if len(book.Bids) != 1 || book.Bids[0].Quantity != 2 {
+		t.Errorf("bids = %+v, want 2 at 100", book.Bids)
+	}
+}
+
+func TestAmendOrderPriceCrosses(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+	agentID := uuid.New()
+
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 99, Quantity: 3})
+	ask := &Order{AgentID: agentID, ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 101, Quantity: 5}
+	engine.PlaceOrder(context.Background(), ask)
+
+	price := 99.0
+	amended, result, err := engine.AmendOrder(context.Background(), ask.ID, agentID, OrderAmendment{Price: &price})
+	if err != nil {
+		t.Fatalf("AmendOrder() error = %v", err)
+	}
+	if len(result.Trades) != 1 || result.Trades[0].Quantity != 3 {
+		t.Fatalf("trades = %+v, want one trade of 3", result.Trades)
+	}
+	if amended.Status != OrderStatusPartial || amended.RemainingQty != 2 {
+		t.Errorf("amended = %s/%v, want partial/2", amended.Status, amended.RemainingQty)
+	}
+	book := engine.GetOrderBook(productID, 10)
+	if len(book.Bids) != 0 || len(book.Asks) != 1 || book.Asks[0].Price != 99 {
+		t.Errorf("book = %+v, want a single ask at 99", book)
+	}
+}
+
+func TestAmendOrderRollsBackOnPersistFailure(t *testing.T) {
+	repo := newMockRepository()
+	engine := NewEngine(nil)
+	engine.SetRepository(repo)
+	productID := uuid.New()
+
+	first := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 5}
+	second := &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 5}
+	engine.PlaceOrder(context.Background(), first)
+	engine.PlaceOrder(context.Background(), second)
+
+	repo.saveErr = errors.New("db down")
+	price := 101.0
+	if _, _, err := engine.AmendOrder(context.Background(), first.ID, first.AgentID, OrderAmendment{Price: &price}); err == nil {
+		t.Fatal("expected error")
+	}
+	if first.Price != 100 {
+		t.Errorf("Price = %v, want 100", first.Price)
+	}
+
+	// Still first in line
+	repo.saveErr = nil
+	result, _ := engine.PlaceOrder(context.Background(), &Order{
+		AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy,
+		Type: OrderTypeLimit, Price: 100, Quantity: 1,
+	})
+	if len(result.Trades) != 1 || result.Trades[0].SellOrderID != first.ID {
+		t.Errorf("trades = %+v, want a fill of the first order", result.Trades)
+	}
+
+	fills, err := engine.GetOrderFills(context.Background(), first.ID, first.AgentID)
+	if err != nil || len(fills) != 1 {
+		t.Errorf("GetOrderFills() = %v, %v, want one fill", fills, err)
+	}
+}
+
+func TestAmendOrderInvalid(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+	agentID := uuid.New()
+
+	order := &Order{AgentID: agentID, ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 100, Quantity: 10}
+	engine.PlaceOrder(context.Background(), order)
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 4})
+
+	same, filled, negative := 100.0, 4.0, -1.0
+	tests := []struct {
+		name    string
+		agentID uuid.UUID
+		amend   OrderAmendment
+		want    error
+	}{
+		{"nothing changes", agentID, OrderAmendment{Price: &same}, ErrInvalidAmendment},
+		{"stop price on a limit order", agentID, OrderAmendment{StopPrice: &same}, ErrInvalidAmendment},
+		{"quantity at filled", agentID, OrderAmendment{Quantity: &filled}, ErrInvalidQuantity},
+		{"negative price", agentID, OrderAmendment{Price: &negative}, ErrInvalidPrice},
+		{"other agent", uuid.New(), OrderAmendment{Quantity: &same}, ErrNotAuthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := engine.AmendOrder(context.Background(), order.ID, tt.agentID, tt.amend); !errors.Is(err, tt.want) {
+				t.Errorf("AmendOrder() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := engine.AmendOrder(context.Background(), uuid.New(), agentID, OrderAmendment{Quantity: &same}); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("AmendOrder() error = %v, want ErrOrderNotFound", err)
+	}
+}
+
+func TestListOrders(t *testing.T) {
+	engine := NewEngine(nil)
+	productID := uuid.New()
+	agentID := uuid.New()
+
+	for i, side := range []OrderSide{OrderSideBuy, OrderSideBuy, OrderSideSell} {
+		engine.PlaceOrder(context.Background(), &Order{
+			AgentID: agentID, ProductID: productID, Side: side,
+			Type: OrderTypeLimit, Price: 100 + float64(i*10), Quantity: 1,
+		})
+	}
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: productID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 90, Quantity: 1})
+
+	buy := OrderSideBuy
+	result, err := engine.ListOrders(context.Background(), ListOrdersParams{AgentID: agentID, Side: &buy, Limit: 1})
+	if err != nil {
+		t.Fatalf("ListOrders() error = %v", err)
+	}
+	if result.Total != 2 || len(result.Items) != 1 {
+		t.Errorf("total = %d, items = %d, want 2 and 1", result.Total, len(result.Items))
+	}
+
+	id := result.Items[0].ID
+	if order, err := engine.GetOrder(context.Background(), id, agentID); err != nil || order.ID != id {
+		t.Errorf("GetOrder() = %v, %v", order, err)
+	}
+	if _, err := engine.GetOrder(context.Background(), id, uuid.New()); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("GetOrder() by another agent error = %v, want ErrOrderNotFound", err)
+	}
+
+	result, _ = engine.ListOrders(context.Background(), ListOrdersParams{AgentID: agentID, Statuses: []OrderStatus{OrderStatusFilled}})
+	if result.Total != 0 {
+		t.Errorf("filled total = %d, want 0", result.Total)
+	}
+}
+
+// newTestInstrument registers an instrument with a 0.5 tick, whole lots and a 10-200 band.
+func newTestInstrument(t *testing.T, engine *Engine) *Instrument {
+	t.Helper()
+	categoryID := uuid.New()
+	inst := &Instrument{
+		Symbol: "sugar", CategoryID: &categoryID, Currency: "eur",
+		TickSize: 0.5, LotSize: 1, MinPrice: 10, MaxPrice: 200, CreatedBy: uuid.New(),
+	}
+	if err := engine.CreateInstrument(context.Background(), inst); err != nil {
+		t.Fatalf("CreateInstrument() error = %v", err)
+	}
+	return inst
+}
+
+func TestPlaceOrderValidatesInstrument(t *testing.T) {
+	engine := NewEngine(nil)
+	inst := newTestInstrument(t, engine)
+	if inst.Symbol != "SUGAR" || inst.Currency != "EUR" || inst.Status != InstrumentStatusOpen {
+		t.Errorf("instrument = %s/%s/%s, want SUGAR/EUR/open", inst.Symbol, inst.Currency, inst.Status)
+	}
+
+	tests := []struct {
+		name  string
+		order Order
+		want  error
+	}{
+		{"valid", Order{Type: OrderTypeLimit, Price: 100.5, Quantity: 3}, nil},
+		{"off tick", Order{Type: OrderTypeLimit, Price: 100.25, Quantity: 3}, ErrInvalidTickSize},
+		{"fractional lot", Order{Type: OrderTypeLimit, Price: 100, Quantity: 2.5}, ErrInvalidLotSize},
+		{"below band", Order{Type: OrderTypeLimit, Price: 9.5, Quantity: 1}, ErrPriceOutOfBand},
+		{"above band", Order{Type: OrderTypeLimit, Price: 200.5, Quantity: 1}, ErrPriceOutOfBand},
+		{"stop off tick", Order{Type: OrderTypeStop, StopPrice: 50.1, Quantity: 1}, ErrInvalidTickSize},
+		{"market has no price", Order{Type: OrderTypeMarket, Quantity: 1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := tt.order
+			order.AgentID, order.ProductID, order.Side = uuid.New(), inst.ID, OrderSideBuy
+			if _, err := engine.PlaceOrder(context.Background(), &order); !errors.Is(err, tt.want) {
+				t.Errorf("PlaceOrder() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Unregistered products are only rejected when instruments are required
+	other := &Order{AgentID: uuid.New(), ProductID: uuid.New(), Side: OrderSideBuy, Type: OrderTypeLimit, Price: 1.23, Quantity: 0.7}
+	if _, err := engine.PlaceOrder(context.Background(), other); err != nil {
+		t.Errorf("PlaceOrder() error = %v, want nil", err)
+	}
+	engine.SetRequireInstruments(true)
+	other = &Order{AgentID: uuid.New(), ProductID: uuid.New(), Side: OrderSideBuy, Type: OrderTypeLimit, Price: 1.23, Quantity: 0.7}
+	if _, err := engine.PlaceOrder(context.Background(), other); !errors.Is(err, ErrUnknownInstrument) {
+		t.Errorf("PlaceOrder() error = %v, want ErrUnknownInstrument", err)
+	}
+}
+
+func TestHaltedInstrument(t *testing.T) {
+	engine := NewEngine(nil)
+	inst := newTestInstrument(t, engine)
+	agentID := uuid.New()
+
+	order := &Order{AgentID: agentID, ProductID: inst.ID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 100, Quantity: 5}
+	engine.PlaceOrder(context.Background(), order)
+
+	halted := InstrumentStatusHalted
+	if _, err := engine.UpdateInstrument(context.Background(), inst.ID, uuid.New(), InstrumentUpdate{Status: &halted}); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("UpdateInstrument() by another agent error = %v, want ErrNotAuthorized", err)
+	}
+	if _, err := engine.UpdateInstrument(context.Background(), inst.ID, inst.CreatedBy, InstrumentUpdate{Status: &halted}); err != nil {
+		t.Fatalf("UpdateInstrument() error = %v", err)
+	}
+
+	_, err := engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: inst.ID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 1})
+	if !errors.Is(err, ErrInstrumentHalted) {
+		t.Errorf("PlaceOrder() error = %v, want ErrInstrumentHalted", err)
+	}
+
+	// Reducing still works, re-queueing doesn't
+	qty, price := 3.0, 101.0
+	if _, _, err := engine.AmendOrder(context.Background(), order.ID, agentID, OrderAmendment{Quantity: &qty}); err != nil {
+		t.Errorf("AmendOrder() reduce error = %v", err)
+	}
+	if _, _, err := engine.AmendOrder(context.Background(), order.ID, agentID, OrderAmendment{Price: &price}); !errors.Is(err, ErrInstrumentHalted) {
+		t.Errorf("AmendOrder() price error = %v, want ErrInstrumentHalted", err)
+	}
+	if err := engine.CancelOrder(context.Background(), order.ID, agentID); err != nil {
+		t.Errorf("CancelOrder() error = %v", err)
+	}
+}
+
+func TestCreateInstrumentInvalid(t *testing.T) {
+	engine := NewEngine(nil)
+	inst := newTestInstrument(t, engine)
+	categoryID := uuid.New()
+
+	tests := []struct {
+		name string
+		inst Instrument
+		want error
+	}{
+		{"no symbol", Instrument{CategoryID: &categoryID, TickSize: 1, LotSize: 1}, ErrInvalidInstrument},
+		{"no category or listing", Instrument{Symbol: "X", TickSize: 1, LotSize: 1}, ErrInvalidInstrument},
+		{"no tick size", Instrument{Symbol: "X", CategoryID: &categoryID, LotSize: 1}, ErrInvalidInstrument},
+		{"inverted band", Instrument{Symbol: "X", CategoryID: &categoryID, TickSize: 1, LotSize: 1, MinPrice: 5, MaxPrice: 5}, ErrInvalidInstrument},
+		{"duplicate symbol", Instrument{Symbol: "Sugar", CategoryID: &categoryID, TickSize: 1, LotSize: 1}, ErrInstrumentExists},
+		{"duplicate product", Instrument{ID: inst.ID, Symbol: "X", CategoryID: &categoryID, TickSize: 1, LotSize: 1}, ErrInstrumentExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := tt.inst
+			if err := engine.CreateInstrument(context.Background(), &inst); !errors.Is(err, tt.want) {
+				t.Errorf("CreateInstrument() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if got := engine.ListInstruments(nil, ""); len(got) != 1 {
+		t.Errorf("ListInstruments() = %d instruments, want 1", len(got))
+	}
+}
+
+func TestCreateInstrumentExistingProduct(t *testing.T) {
+	repo := newMockRepository()
+	engine := NewEngine(nil)
+	engine.SetRepository(repo)
+	adminID := uuid.New()
+	engine.SetInstrumentAdmins([]uuid.UUID{adminID})
+
+	register := func(productID, agentID uuid.UUID, symbol string, listing bool) error {
+		categoryID := uuid.New()
+		inst := &Instrument{
+			ID: productID, Symbol: symbol, CategoryID: &categoryID,
+			TickSize: 0.01, LotSize: 1, CreatedBy: agentID,
+		}
+		if listing {
+			inst.ListingID = &productID
+		}
+		return engine.CreateInstrument(context.Background(), inst)
+	}
+
+	// A book in memory or orders on record mean others already trade the product
+	traded := uuid.New()
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: traded, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 10, Quantity: 1})
+	if err := register(traded, uuid.New(), "TRADED", false); !errors.Is(err, ErrProductInUse) {
+		t.Errorf("CreateInstrument() traded product error = %v, want ErrProductInUse", err)
+	}
+	recorded := uuid.New()
+	repo.orders[uuid.New()] = Order{ProductID: recorded}
+	if err := register(recorded, uuid.New(), "RECORDED", false); !errors.Is(err, ErrProductInUse) {
+		t.Errorf("CreateInstrument() recorded product error = %v, want ErrProductInUse", err)
+	}
+
+	// The listing's seller and admins can, and nobody trades a new product yet
+	if err := register(traded, uuid.New(), "LISTING", true); err != nil {
+		t.Errorf("CreateInstrument() own listing error = %v", err)
+	}
+	if err := register(recorded, adminID, "ADMIN", false); err != nil {
+		t.Errorf("CreateInstrument() by admin error = %v", err)
+	}
+	if err := register(uuid.New(), uuid.New(), "FRESH", false); err != nil {
+		t.Errorf("CreateInstrument() new product error = %v", err)
+	}
+}
+
+func TestInstrumentCurrencyAndRecovery(t *testing.T) {
+	repo := newMockRepository()
+	creator := &mockTransactionCreator{}
+	engine := NewEngine(nil)
+	engine.SetRepository(repo)
+	engine.SetTransactionCreator(creator)
+	inst := newTestInstrument(t, engine)
+
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: inst.ID, Side: OrderSideSell, Type: OrderTypeLimit, Price: 100, Quantity: 1})
+	engine.PlaceOrder(context.Background(), &Order{AgentID: uuid.New(), ProductID: inst.ID, Side: OrderSideBuy, Type: OrderTypeLimit, Price: 100, Quantity: 1})
+	if creator.currency != "EUR" {
+		t.Errorf("transaction currency = %q, want EUR", creator.currency)
+	}
+
+	recovered := NewEngine(nil)
+	recovered.SetRepository(repo)
+	if _, err := recovered.Recover(context.Background()); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	if got, err := recovered.GetInstrument(inst.ID); err != nil || got.Symbol != "SUGAR" {
+		t.Errorf("GetInstrument() = %v, %v, want SUGAR", got, err)
+	}
+}
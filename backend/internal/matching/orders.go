@@ -0,0 +1,264 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidAmendment = errors.New("amendment must change the price, stop price or quantity of a working order")
+	ErrInvalidQuantity  = errors.New("quantity must be greater than the filled quantity")
+	ErrInvalidPrice     = errors.New("price must be positive")
+)
+
+// OrderAmendment changes a working order. Nil fields are left unchanged.
+type OrderAmendment struct {
+	Price     *float64 `json:"price,omitempty"`      // Limit and stop-limit orders
+	StopPrice *float64 `json:"stop_price,omitempty"` // Untriggered stop orders
+	Quantity  *float64 `json:"quantity,omitempty"`   // New total quantity, including what has filled
+}
+
+// ListOrdersParams contains filter parameters for listing an agent's orders.
+type ListOrdersParams struct {
+	AgentID   uuid.UUID
+	ProductID *uuid.UUID    // Filter by product
+	Side      *OrderSide    // Filter by side
+	Statuses  []OrderStatus // Filter by any of these statuses
+	Limit     int
+	Offset    int
+}
+
+// OrderListResult contains a page of orders.
+type OrderListResult struct {
+	Items  []*Order `json:"items"`
+	Total  int      `json:"total"`
+	Limit  int      `json:"limit"`
+	Offset int      `json:"offset"`
+}
+
+// AmendOrder changes the price, stop price or quantity of a working order.
+// Reducing the quantity keeps the order's time priority; any other change
+// re-queues it behind the orders at its price, and a new price that crosses
+// the book matches right away. It returns the order after the amendment.
+func (e *Engine) AmendOrder(ctx context.Context, orderID, agentID uuid.UUID, amend OrderAmendment) (*Order, *MatchResult, error) {
+	v, ok := e.index.Load(orderID)
+	if !ok {
+		return nil, nil, ErrOrderNotFound
+	}
+
+	order, result, err := e.amendOrder(ctx, v.(*book), orderID, agentID, amend, time.Now().UTC())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	e.dispatch(ctx, result)
+	return order, result, nil
+}
+
+// amendOrder applies an amendment under the book lock.
+func (e *Engine) amendOrder(ctx context.Context, b *book, orderID, agentID uuid.UUID, amend OrderAmendment, now time.Time) (*Order, *MatchResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// The order may have filled between the index lookup and taking the lock
+	entry, ok := b.orders[orderID]
+	if !ok {
+		return nil, nil, ErrOrderNotFound
+	}
+	order := entry.order
+	if order.AgentID != agentID {
+		return nil, nil, ErrNotAuthorized
+	}
+
+	price, stopPrice, quantity := order.Price, order.StopPrice, order.Quantity
+	if amend.Price != nil {
+		if !order.hasLimitPrice() {
+			return nil, nil, ErrInvalidAmendment
+		}
+		if *amend.Price <= 0 {
+			return nil, nil, ErrInvalidPrice
+		}
+		price = *amend.Price
+	}
+	if amend.StopPrice != nil {
+		if order.Status != OrderStatusPending {
+			return nil, nil, ErrInvalidAmendment
+		}
+		if *amend.StopPrice <= 0 {
+			return nil, nil, ErrInvalidStopPrice
+		}
+		stopPrice = *amend.StopPrice
+	}
+	if amend.Quantity != nil {
+		if *amend.Quantity <= order.FilledQty {
+			return nil, nil, ErrInvalidQuantity
+		}
+		quantity = *amend.Quantity
+	}
+	if price == order.Price && stopPrice == order.StopPrice && quantity == order.Quantity {
+		return nil, nil, ErrInvalidAmendment
+	}
+
+	// Stop orders are kept in arrival order, so any change re-queues them
+	requeue := price != order.Price || stopPrice != order.StopPrice || quantity > order.Quantity ||
+		order.Status == OrderStatusPending
+
+	// Orders can be reduced while trading is halted, but not re-queued
+	if inst := e.instrument(order.ProductID); inst != nil {
+		if requeue && inst.Status != InstrumentStatusOpen {
+			return nil, nil, ErrInstrumentHalted
+		}
+		amended := *order
+		amended.Price, amended.StopPrice, amended.Quantity = price, stopPrice, quantity
+		if err := inst.validateTerms(&amended); err != nil {
+			return nil, nil, err
+		}
+	}
+	delta := quantity - order.Quantity
+	before := *order
+
+	if e.repo != nil {
+		b.begin()
+	}
+
+	result := &MatchResult{}
+	var fills []fill
+	switch {
+	case order.Status == OrderStatusPending:
+		b.removeStop(order)
+		order.Price, order.StopPrice, order.Quantity = price, stopPrice, quantity
+		order.RemainingQty += delta
+		order.UpdatedAt = now
+		b.addStop(order)
+	case requeue:
+		b.take(order)
+		order.Price, order.Quantity = price, quantity
+		order.RemainingQty += delta
+		order.UpdatedAt = now
+		result, fills = b.execute(order, now)
+	default:
+		// Only the quantity went down: stay in place
+		order.Quantity = quantity
+		order.RemainingQty += delta
+		order.UpdatedAt = now
+		b.reduceLevel(b.side(order.Side), entry.level, -delta)
+	}
+
+	fills = append(fills, b.triggerStops(order, now, result)...)
+
+	err := e.persist(b, fills, result, func(touched []*Order, trades []Trade) error {
+		return e.repo.SaveAmend(ctx, order, requeue, touched, trades)
+	})
+	if err != nil {
+		*order = before
+		return nil, nil, fmt.Errorf("failed to persist amendment: %w", err)
+	}
+
+	amended := *order
+	return &amended, result, nil
+}
+
+// GetOrder returns one of an agent's orders. Orders of other agents are not found.
+func (e *Engine) GetOrder(ctx context.Context, orderID, agentID uuid.UUID) (*Order, error) {
+	order, err := e.getOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.AgentID != agentID {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// getOrder returns a copy of an order, preferring the live book over storage.
+func (e *Engine) getOrder(ctx context.Context, orderID uuid.UUID) (*Order, error) {
+	if v, ok := e.index.Load(orderID); ok {
+		b := v.(*book)
+		b.mu.RLock()
+		entry, ok := b.orders[orderID]
+		var order Order
+		if ok {
+			order = *entry.order
+		}
+		b.mu.RUnlock()
+		if ok {
+			return &order, nil
+		}
+	}
+
+	if e.repo == nil {
+		return nil, ErrOrderNotFound
+	}
+	return e.repo.GetOrder(ctx, orderID)
+}
+
+// GetOrderFills returns the trades of one of an agent's orders, oldest first.
+func (e *Engine) GetOrderFills(ctx context.Context, orderID, agentID uuid.UUID) ([]Trade, error) {
+	if _, err := e.GetOrder(ctx, orderID, agentID); err != nil {
+		return nil, err
+	}
+	if e.repo == nil {
+		return []Trade{}, nil
+	}
+	return e.repo.GetOrderTrades(ctx, orderID)
+}
+
+// ListOrders returns an agent's orders, newest first. Without a repository
+// only working orders are known.
+func (e *Engine) ListOrders(ctx context.Context, params ListOrdersParams) (*OrderListResult, error) {
+	if e.repo != nil {
+		return e.repo.ListOrders(ctx, params)
+	}
+
+	matches := func(order *Order) bool {
+		if order.AgentID != params.AgentID {
+			return false
+		}
+		if params.ProductID != nil && order.ProductID != *params.ProductID {
+			return false
+		}
+		if params.Side != nil && order.Side != *params.Side {
+			return false
+		}
+		if len(params.Statuses) == 0 {
+			return true
+		}
+		for _, status := range params.Statuses {
+			if order.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+
+	orders := []*Order{}
+	for _, b := range e.allBooks() {
+		b.mu.RLock()
+		for _, entry := range b.orders {
+			if matches(entry.order) {
+				order := *entry.order
+				orders = append(orders, &order)
+			}
+		}
+		b.mu.RUnlock()
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+
+	result := &OrderListResult{Total: len(orders), Limit: params.Limit, Offset: params.Offset}
+	start, end := params.Offset, len(orders)
+	if start > end {
+		start = end
+	}
+	if params.Limit > 0 && start+params.Limit < end {
+		end = start + params.Limit
+	}
+	result.Items = orders[start:end]
+	return result, nil
+}
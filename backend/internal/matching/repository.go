@@ -0,0 +1,538 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository handles order book persistence.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository creates a new order book repository.
+func NewRepository(pool *pgxpool.Pool) *Repository {
+	return &Repository{pool: pool}
+}
+
+// orderColumns are the columns scanned by scanOrder, in order.
+const orderColumns = `id, agent_id, product_id, side, order_type, price, stop_price, quantity,
+	filled_qty, remaining_qty, status, time_in_force, expires_at, triggered_at, created_at, updated_at,
+	stp_mode, owner_id`
+
+// SaveMatch persists an order, the other orders it changed and the trades in one transaction.
+func (r *Repository) SaveMatch(ctx context.Context, order *Order, touched []*Order, trades []Trade) error {
+	return r.saveMatch(ctx, order, false, touched, trades)
+}
+
+// SaveAmend persists an amended order like SaveMatch. If requeue is set, the
+// order moves behind all existing orders in arrival order.
+func (r *Repository) SaveAmend(ctx context.Context, order *Order, requeue bool, touched []*Order, trades []Trade) error {
+	return r.saveMatch(ctx, order, requeue, touched, trades)
+}
+
+func (r *Repository) saveMatch(ctx context.Context, order *Order, requeue bool, touched []*Order, trades []Trade) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := upsertOrder(ctx, tx, order); err != nil {
+		return err
+	}
+	if requeue {
+		_, err := tx.Exec(ctx, `
+			UPDATE orderbook_orders SET seq = NEXTVAL(PG_GET_SERIAL_SEQUENCE('orderbook_orders', 'seq'))
+			WHERE id = $1`, order.ID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, o := range touched {
+		if err := upsertOrder(ctx, tx, o); err != nil {
+			return err
+		}
+	}
+
+	for _, trade := range trades {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO orderbook_trades (
+				id, product_id, buy_order_id, sell_order_id, buyer_id, seller_id, price, quantity, created_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			trade.ID, trade.ProductID, trade.BuyOrderID, trade.SellOrderID,
+			trade.BuyerID, trade.SellerID, trade.Price, trade.Quantity, trade.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// upsertOrder inserts an order or updates its fill state if it already exists.
+func upsertOrder(ctx context.Context, tx pgx.Tx, order *Order) error {
+	query := `
+		INSERT INTO orderbook_orders (
+			id, agent_id, product_id, side, order_type, price, stop_price, quantity,
+			filled_qty, remaining_qty, status, time_in_force, expires_at, triggered_at, created_at, updated_at,
+			stp_mode, owner_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		ON CONFLICT (id) DO UPDATE SET
+			price = EXCLUDED.price,
+			stop_price = EXCLUDED.stop_price,
+			quantity = EXCLUDED.quantity,
+			filled_qty = EXCLUDED.filled_qty,
+			remaining_qty = EXCLUDED.remaining_qty,
+			status = EXCLUDED.status,
+			triggered_at = EXCLUDED.triggered_at,
+			updated_at = EXCLUDED.updated_at`
+
+	_, err := tx.Exec(ctx, query,
+		order.ID, order.AgentID, order.ProductID, order.Side, order.Type, order.Price, order.StopPrice, order.Quantity,
+		order.FilledQty, order.RemainingQty, order.Status, order.TimeInForce, order.ExpiresAt, order.TriggeredAt,
+		order.CreatedAt, order.UpdatedAt, order.STP, order.OwnerID,
+	)
+	return err
+}
+
+// UpdateOrderStatus updates the status of an order.
+func (r *Repository) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status OrderStatus) error {
+	query := `UPDATE orderbook_orders SET status = $2, updated_at = NOW() WHERE id = $1`
+	result, err := r.pool.Exec(ctx, query, orderID, status)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrOrderNotFound
+	}
+	return nil
+}
+
+// GetRestingOrders returns all orders still on the book and all untriggered
+// stop orders, in arrival order.
+func (r *Repository) GetRestingOrders(ctx context.Context) ([]*Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM orderbook_orders
+		WHERE remaining_qty > 0 AND (
+			status = 'pending' OR
+			(status IN ('open', 'partial') AND order_type IN ('limit', 'stop_limit'))
+		)
+		ORDER BY seq ASC`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
+// scanOrder scans a row of orderColumns.
+func scanOrder(row pgx.Row) (*Order, error) {
+	var order Order
+	err := row.Scan(
+		&order.ID,
+		&order.AgentID,
+		&order.ProductID,
+		&order.Side,
+		&order.Type,
+		&order.Price,
+		&order.StopPrice,
+		&order.Quantity,
+		&order.FilledQty,
+		&order.RemainingQty,
+		&order.Status,
+		&order.TimeInForce,
+		&order.ExpiresAt,
+		&order.TriggeredAt,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&order.STP,
+		&order.OwnerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetOrder returns an order by ID.
+func (r *Repository) GetOrder(ctx context.Context, orderID uuid.UUID) (*Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orderbook_orders WHERE id = $1`
+	order, err := scanOrder(r.pool.QueryRow(ctx, query, orderID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	return order, err
+}
+
+// ListOrders returns an agent's orders matching the filters, newest first.
+func (r *Repository) ListOrders(ctx context.Context, params ListOrdersParams) (*OrderListResult, error) {
+	conditions := []string{"agent_id = $1"}
+	args := []interface{}{params.AgentID}
+	argNum := 2
+
+	if params.ProductID != nil {
+		conditions = append(conditions, fmt.Sprintf("product_id = $%d", argNum))
+		args = append(args, *params.ProductID)
+		argNum++
+	}
+	if params.Side != nil {
+		conditions = append(conditions, fmt.Sprintf("side = $%d", argNum))
+		args = append(args, *params.Side)
+		argNum++
+	}
+	if len(params.Statuses) > 0 {
+		statuses := make([]string, len(params.Statuses))
+		for i, status := range params.Statuses {
+			statuses[i] = string(status)
+		}
+		conditions = append(conditions, fmt.Sprintf("status = ANY($%d)", argNum))
+		args = append(args, statuses)
+		argNum++
+	}
+	whereClause := "WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.pool.QueryRow(ctx, "SELECT COUNT(*) FROM orderbook_orders "+whereClause, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM orderbook_orders
+		%s
+		ORDER BY created_at DESC, seq DESC
+		LIMIT $%d OFFSET $%d`, orderColumns, whereClause, argNum, argNum+1)
+	args = append(args, params.Limit, params.Offset)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &OrderListResult{
+		Items:  items,
+		Total:  total,
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}, nil
+}
+
+// GetOrderTrades returns the trades an order took part in, oldest first.
+func (r *Repository) GetOrderTrades(ctx context.Context, orderID uuid.UUID) ([]Trade, error) {
+	query := `
+		SELECT ot.id, ot.product_id, ot.buy_order_id, ot.sell_order_id, ot.buyer_id, ot.seller_id,
+			ot.price, ot.quantity, ot.created_at, t.id
+		FROM orderbook_trades ot
+		LEFT JOIN transactions t ON t.trade_id = ot.id
+		WHERE ot.buy_order_id = $1 OR ot.sell_order_id = $1
+		ORDER BY ot.seq ASC`
+
+	rows, err := r.pool.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trades := []Trade{}
+	for rows.Next() {
+		var trade Trade
+		if err := rows.Scan(
+			&trade.ID,
+			&trade.ProductID,
+			&trade.BuyOrderID,
+			&trade.SellOrderID,
+			&trade.BuyerID,
+			&trade.SellerID,
+			&trade.Price,
+			&trade.Quantity,
+			&trade.CreatedAt,
+			&trade.TransactionID,
+		); err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+
+	return trades, rows.Err()
+}
+
+// CreateInstrument inserts a new instrument.
+func (r *Repository) CreateInstrument(ctx context.Context, inst *Instrument) error {
+	query := `
+		INSERT INTO orderbook_instruments (
+			id, symbol, name, category_id, listing_id, currency, tick_size, lot_size,
+			min_price, max_price, status, created_by, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+
+	_, err := r.pool.Exec(ctx, query,
+		inst.ID, inst.Symbol, inst.Name, inst.CategoryID, inst.ListingID, inst.Currency,
+		inst.TickSize, inst.LotSize, inst.MinPrice, inst.MaxPrice, inst.Status,
+		inst.CreatedBy, inst.CreatedAt, inst.UpdatedAt,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			return ErrInstrumentExists
+		case "23503": // foreign_key_violation
+			return fmt.Errorf("%w: category or listing not found", ErrInvalidInstrument)
+		}
+	}
+	return err
+}
+
+// UpdateInstrument saves the mutable fields of an instrument.
+func (r *Repository) UpdateInstrument(ctx context.Context, inst *Instrument) error {
+	query := `
+		UPDATE orderbook_instruments
+		SET name = $2, tick_size = $3, lot_size = $4, min_price = $5, max_price = $6,
+			status = $7, updated_at = $8
+		WHERE id = $1`
+
+	result, err := r.pool.Exec(ctx, query,
+		inst.ID, inst.Name, inst.TickSize, inst.LotSize, inst.MinPrice, inst.MaxPrice,
+		inst.Status, inst.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUnknownInstrument
+	}
+	return nil
+}
+
+// GetInstruments returns every instrument.
+func (r *Repository) GetInstruments(ctx context.Context) ([]*Instrument, error) {
+	query := `
+		SELECT id, symbol, name, category_id, listing_id, currency, tick_size, lot_size,
+			min_price, max_price, status, created_by, created_at, updated_at
+		FROM orderbook_instruments
+		ORDER BY symbol`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var instruments []*Instrument
+	for rows.Next() {
+		var inst Instrument
+		if err := rows.Scan(
+			&inst.ID,
+			&inst.Symbol,
+			&inst.Name,
+			&inst.CategoryID,
+			&inst.ListingID,
+			&inst.Currency,
+			&inst.TickSize,
+			&inst.LotSize,
+			&inst.MinPrice,
+			&inst.MaxPrice,
+			&inst.Status,
+			&inst.CreatedBy,
+			&inst.CreatedAt,
+			&inst.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		instruments = append(instruments, &inst)
+	}
+
+	return instruments, rows.Err()
+}
+
+// HasProductActivity reports whether any orders or trades exist for a product.
+func (r *Repository) HasProductActivity(ctx context.Context, productID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM orderbook_orders WHERE product_id = $1)
+			OR EXISTS (SELECT 1 FROM orderbook_trades WHERE product_id = $1)`
+
+	var active bool
+	if err := r.pool.QueryRow(ctx, query, productID).Scan(&active); err != nil {
+		return false, err
+	}
+	return active, nil
+}
+
+// GetLastPrices returns the most recent trade price for every product.
+func (r *Repository) GetLastPrices(ctx context.Context) (map[uuid.UUID]float64, error) {
+	query := `
+		SELECT DISTINCT ON (product_id) product_id, price
+		FROM orderbook_trades
+		ORDER BY product_id, seq DESC`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[uuid.UUID]float64)
+	for rows.Next() {
+		var productID uuid.UUID
+		var price float64
+		if err := rows.Scan(&productID, &price); err != nil {
+			return nil, err
+		}
+		prices[productID] = price
+	}
+
+	return prices, rows.Err()
+}
+
+// GetTradesSince returns all trades at or after a time, oldest first.
+func (r *Repository) GetTradesSince(ctx context.Context, since time.Time) ([]Trade, error) {
+	query := `
+		SELECT id, product_id, buy_order_id, sell_order_id, buyer_id, seller_id, price, quantity, created_at
+		FROM orderbook_trades
+		WHERE created_at >= $1
+		ORDER BY seq ASC`
+
+	rows, err := r.pool.Query(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trades []Trade
+	for rows.Next() {
+		var trade Trade
+		if err := rows.Scan(
+			&trade.ID,
+			&trade.ProductID,
+			&trade.BuyOrderID,
+			&trade.SellOrderID,
+			&trade.BuyerID,
+			&trade.SellerID,
+			&trade.Price,
+			&trade.Quantity,
+			&trade.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+
+	return trades, rows.Err()
+}
+
+// GetCandles aggregates a product's trades between from and to into OHLCV candles
+// of the given interval. It returns the latest limit candles, oldest first.
+func (r *Repository) GetCandles(ctx context.Context, productID uuid.UUID, interval time.Duration, from, to time.Time, limit int) ([]Candle, error) {
+	query := `
+		SELECT bucket,
+			(ARRAY_AGG(price ORDER BY seq ASC))[1],
+			MAX(price),
+			MIN(price),
+			(ARRAY_AGG(price ORDER BY seq DESC))[1],
+			SUM(quantity),
+			COUNT(*)
+		FROM (
+			SELECT TO_TIMESTAMP(FLOOR(EXTRACT(EPOCH FROM created_at) / $2) * $2) AS bucket, price, quantity, seq
+			FROM orderbook_trades
+			WHERE product_id = $1 AND created_at >= $3 AND created_at < $4
+		) t
+		GROUP BY bucket
+		ORDER BY bucket DESC
+		LIMIT $5`
+
+	rows, err := r.pool.Query(ctx, query, productID, interval.Seconds(), from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candles := []Candle{}
+	for rows.Next() {
+		var c Candle
+		if err := rows.Scan(&c.OpenTime, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume, &c.Trades); err != nil {
+			return nil, err
+		}
+		candles = append(candles, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Oldest first
+	for i, j := 0, len(candles)-1; i < j; i, j = i+1, j-1 {
+		candles[i], candles[j] = candles[j], candles[i]
+	}
+	return candles, nil
+}
+
+// GetUnsettledTrades returns trades that have no transaction yet, oldest first.
+func (r *Repository) GetUnsettledTrades(ctx context.Context) ([]Trade, error) {
+	query := `
+		SELECT ot.id, ot.product_id, ot.buy_order_id, ot.sell_order_id, ot.buyer_id, ot.seller_id,
+			ot.price, ot.quantity, ot.created_at
+		FROM orderbook_trades ot
+		LEFT JOIN transactions t ON t.trade_id = ot.id
+		WHERE t.id IS NULL
+		ORDER BY ot.seq ASC`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trades []Trade
+	for rows.Next() {
+		var trade Trade
+		if err := rows.Scan(
+			&trade.ID,
+			&trade.ProductID,
+			&trade.BuyOrderID,
+			&trade.SellOrderID,
+			&trade.BuyerID,
+			&trade.SellerID,
+			&trade.Price,
+			&trade.Quantity,
+			&trade.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+
+	return trades, rows.Err()
+}
@@ -0,0 +1,204 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidInterval is returned for an unsupported candle interval.
+var ErrInvalidInterval = errors.New("interval must be one of 1m, 5m, 1h, 1d")
+
+// CandleIntervals are the supported candle intervals.
+var CandleIntervals = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// statsWindow is the length of the rolling statistics window.
+const statsWindow = 24 * time.Hour
+
+// Candle is the OHLCV summary of the trades in one interval.
+type Candle struct {
+	OpenTime time.Time `json:"open_time"`
+	Open     float64   `json:"open"`
+	High     float64   `json:"high"`
+	Low      float64   `json:"low"`
+	Close    float64   `json:"close"`
+	Volume   float64   `json:"volume"`
+	Trades   int       `json:"trades"`
+}
+
+// Ticker summarizes a product's market.
+type Ticker struct {
+	ProductID uuid.UUID `json:"product_id"`
+	LastPrice *float64  `json:"last_price"`
+	BestBid   *float64  `json:"best_bid"`
+	BestAsk   *float64  `json:"best_ask"`
+	Open24h   *float64  `json:"open_24h"`
+	High24h   *float64  `json:"high_24h"`
+	Low24h    *float64  `json:"low_24h"`
+	Volume24h float64   `json:"volume_24h"`
+	Change24h *float64  `json:"change_24h"` // Percent change from Open24h to LastPrice
+	Trades24h int       `json:"trades_24h"`
+}
+
+// tradeStats keeps one-minute candles for the rolling 24h window of a product.
+// The window moves in whole minutes.
+type tradeStats struct {
+	buckets [24 * 60]Candle
+}
+
+// recordTrades adds trades to a book's rolling statistics.
+func (b *book) recordTrades(trades []Trade) {
+	if len(trades) == 0 {
+		return
+	}
+	if b.stats == nil {
+		b.stats = &tradeStats{}
+	}
+	for _, trade := range trades {
+		b.stats.add(trade)
+	}
+}
+
+// add records a trade.
+func (s *tradeStats) add(trade Trade) {
+	minute := trade.CreatedAt.Truncate(time.Minute)
+	bucket := &s.buckets[minute.Unix()/60%int64(len(s.buckets))]
+
+	if !bucket.OpenTime.Equal(minute) {
+		if bucket.OpenTime.After(minute) {
+			return // Older than the window
+		}
+		*bucket = Candle{OpenTime: minute, Open: trade.Price, High: trade.Price, Low: trade.Price}
+	}
+	bucket.High = max(bucket.High, trade.Price)
+	bucket.Low = min(bucket.Low, trade.Price)
+	bucket.Close = trade.Price
+	bucket.Volume += trade.Quantity
+	bucket.Trades++
+}
+
+// window aggregates the buckets within the 24h before now into one candle.
+// It returns nil if there were no trades.
+func (s *tradeStats) window(now time.Time) *Candle {
+	if s == nil {
+		return nil
+	}
+	since := now.Truncate(time.Minute).Add(-statsWindow)
+	var total *Candle
+	var closeTime time.Time
+	for i := range s.buckets {
+		bucket := &s.buckets[i]
+		if bucket.Trades == 0 || !bucket.OpenTime.After(since) || bucket.OpenTime.After(now) {
+			continue
+		}
+		if total == nil {
+			c := *bucket
+			total, closeTime = &c, bucket.OpenTime
+			continue
+		}
+		if bucket.OpenTime.Before(total.OpenTime) {
+			total.OpenTime, total.Open = bucket.OpenTime, bucket.Open
+		}
+		if bucket.OpenTime.After(closeTime) {
+			closeTime, total.Close = bucket.OpenTime, bucket.Close
+		}
+		total.High = max(total.High, bucket.High)
+		total.Low = min(total.Low, bucket.Low)
+		total.Volume += bucket.Volume
+		total.Trades += bucket.Trades
+	}
+	return total
+}
+
+// GetCandles returns OHLCV candles for a product, oldest first. Intervals without
+// trades are omitted. At most limit candles ending before to are returned.
+func (e *Engine) GetCandles(ctx context.Context, productID uuid.UUID, interval string, from, to time.Time, limit int) ([]Candle, error) {
+	step, ok := CandleIntervals[interval]
+	if !ok {
+		return nil, ErrInvalidInterval
+	}
+	if e.repo == nil {
+		return []Candle{}, nil
+	}
+
+	candles, err := e.repo.GetCandles(ctx, productID, step, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load candles: %w", err)
+	}
+	return candles, nil
+}
+
+// GetTicker returns the ticker of a product.
+func (e *Engine) GetTicker(productID uuid.UUID, now time.Time) *Ticker {
+	b := e.lookupBook(productID)
+	if b == nil {
+		return &Ticker{ProductID: productID}
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.ticker(now)
+}
+
+// GetTickers returns the tickers of all products with resting orders or
+// trades in the last 24h.
+func (e *Engine) GetTickers(now time.Time) []*Ticker {
+	tickers := []*Ticker{}
+	for _, b := range e.allBooks() {
+		b.mu.RLock()
+		ticker := b.ticker(now)
+		active := len(b.bids.levels) > 0 || len(b.asks.levels) > 0 || ticker.Trades24h > 0
+		b.mu.RUnlock()
+		if active {
+			tickers = append(tickers, ticker)
+		}
+	}
+
+	// Most traded first
+	sort.Slice(tickers, func(i, j int) bool {
+		if tickers[i].Volume24h != tickers[j].Volume24h {
+			return tickers[i].Volume24h > tickers[j].Volume24h
+		}
+		return tickers[i].ProductID.String() < tickers[j].ProductID.String()
+	})
+	return tickers
+}
+
+// ticker builds the ticker of a book. Must be called with b.mu held.
+func (b *book) ticker(now time.Time) *Ticker {
+	ticker := &Ticker{ProductID: b.productID}
+	if b.hasPrice {
+		price := b.lastPrice
+		ticker.LastPrice = &price
+	}
+	if best := b.bids.sorted.best(); best != nil {
+		price := best.price
+		ticker.BestBid = &price
+	}
+	if best := b.asks.sorted.best(); best != nil {
+		price := best.price
+		ticker.BestAsk = &price
+	}
+
+	if day := b.stats.window(now); day != nil {
+		ticker.Open24h = &day.Open
+		ticker.High24h = &day.High
+		ticker.Low24h = &day.Low
+		ticker.Volume24h = day.Volume
+		ticker.Trades24h = day.Trades
+		if ticker.LastPrice != nil && day.Open != 0 {
+			change := (*ticker.LastPrice - day.Open) / day.Open * 100
+			ticker.Change24h = &change
+		}
+	}
+	return ticker
+}
@@ -24,29 +24,46 @@ const (
 	EventCommentCreated   EventType = "comment.created"
 
 	// Auction events
-	EventAuctionStarted    EventType = "auction.started"
-	EventBidPlaced         EventType = "bid.placed"
-	EventBidOutbid         EventType = "bid.outbid"
-	EventAuctionEndingSoon EventType = "auction.ending_soon"
-	EventAuctionEnded      EventType = "auction.ended"
+	EventAuctionStarted             EventType = "auction.started"
+	EventBidPlaced                  EventType = "bid.placed"
+	EventBidOutbid                  EventType = "bid.outbid"
+	EventAuctionEndingSoon          EventType = "auction.ending_soon"
+	EventAuctionPriceChanged        EventType = "auction.price_changed"
+	EventAuctionRevealStarted       EventType = "auction.reveal_started"
+	EventAuctionSecondChanceOffered EventType = "auction.second_chance_offered"
+	EventAuctionEnded               EventType = "auction.ended"
+	EventAuctionCancelled           EventType = "auction.cancelled"
+	EventBidRetracted               EventType = "bid.retracted"
+	EventAuctionSearchMatched       EventType = "auction.search_matched"
 
 	// Order/Transaction events
-	EventOrderCreated            EventType = "order.created"
-	EventEscrowFunded            EventType = "escrow.funded"
-	EventDeliveryConfirmed       EventType = "delivery.confirmed"
-	EventPaymentReleased         EventType = "payment.released"
-	EventPaymentFailed           EventType = "payment.failed"
-	EventPaymentCaptureFailed    EventType = "payment.capture_failed"
-	EventDisputeOpened           EventType = "dispute.opened"
-	EventTransactionCreated      EventType = "transaction.created"
-	EventTransactionEscrowFunded EventType = "transaction.escrow_funded"
-	EventTransactionDelivered    EventType = "transaction.delivered"
-	EventTransactionCompleted    EventType = "transaction.completed"
-	EventTransactionRefunded     EventType = "transaction.refunded"
+	EventOrderCreated             EventType = "order.created"
+	EventEscrowFunded             EventType = "escrow.funded"
+	EventDeliveryConfirmed        EventType = "delivery.confirmed"
+	EventPaymentReleased          EventType = "payment.released"
+	EventPaymentFailed            EventType = "payment.failed"
+	EventPaymentCaptureFailed     EventType = "payment.capture_failed"
+	EventDisputeOpened            EventType = "dispute.opened"
+	EventDisputeEvidenceSubmitted EventType = "dispute.evidence_submitted"
+	EventDisputeResolved          EventType = "dispute.resolved"
+	EventTransactionCreated       EventType = "transaction.created"
+	EventTransactionEscrowFunded  EventType = "transaction.escrow_funded"
+	EventTransactionDelivered     EventType = "transaction.delivered"
+	EventInspectionEnding         EventType = "transaction.inspection_ending"
+	EventMilestoneDelivered       EventType = "milestone.delivered"
+	EventMilestoneReleased        EventType = "milestone.released"
+	EventTransactionCompleted     EventType = "transaction.completed"
+	EventTransactionRefunded      EventType = "transaction.refunded"
+	EventRefundRequested          EventType = "refund.requested"
+	EventRefundCompleted          EventType = "refund.completed"
+	EventRefundRejected           EventType = "refund.rejected"
 
 	// Matching events (NYSE-style)
-	EventMatchFound  EventType = "match.found"
-	EventOrderFilled EventType = "order.filled"
+	EventMatchFound              EventType = "match.found"
+	EventOrderFilled             EventType = "order.filled"
+	EventOrderExpired            EventType = "order.expired"
+	EventOrderTriggered          EventType = "order.triggered"
+	EventOrderSelfTradePrevented EventType = "order.self_trade_prevented"
 
 	// Message events
 	EventMessageReceived EventType = "message.received"
@@ -159,6 +159,20 @@ func extractAgentIDs(payload map[string]any) []uuid.UUID {
 		}
 	}
 
+	// Agents watching an auction
+	switch v := payload["watcher_ids"].(type) {
+	case []uuid.UUID:
+		for _, id := range v {
+			ids[id] = struct{}{}
+		}
+	case []string:
+		for _, s := range v {
+			if parsed, err := uuid.Parse(s); err == nil {
+				ids[parsed] = struct{}{}
+			}
+		}
+	}
+
 	result := make([]uuid.UUID, 0, len(ids))
 	for id := range ids {
 		result = append(result, id)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 
 	"github.com/google/uuid"
 	"github.com/stripe/stripe-go/v76"
@@ -23,11 +24,13 @@ var (
 	ErrInvalidCurrency  = errors.New("invalid currency")
 	ErrSellerNotPayable = errors.New("seller is not set up to receive payments")
 	ErrNoPaymentMethod  = errors.New("no saved payment method; owner must add one in the dashboard")
+	ErrNoPayoutAccount  = errors.New("no Stripe Connect account to pay out to; owner must finish Connect onboarding")
 )
 
-// ConnectAccountResolver resolves a seller agent's Connect account ID.
+// ConnectAccountResolver resolves the Connect account of an agent's owner or of a user.
 type ConnectAccountResolver interface {
 	GetConnectAccountIDForAgent(ctx context.Context, agentID uuid.UUID) (string, error)
+	GetConnectAccountIDForUser(ctx context.Context, userID uuid.UUID) (string, error)
 }
 
 // PaymentMethodResolver resolves an agent's owner's saved payment method.
@@ -87,7 +90,15 @@ func (s *Service) CreateEscrowPayment(ctx context.Context, req *CreatePaymentReq
 		params.Confirm = stripe.Bool(true)
 	}
 
-	if req.SellerStripeAccountID != "" {
+	// A multicapture payment is captured in parts, each transferred to the
+	// seller on its own, so it has no single destination
+	if req.Multicapture {
+		params.PaymentMethodOptions = &stripe.PaymentIntentPaymentMethodOptionsParams{
+			Card: &stripe.PaymentIntentPaymentMethodOptionsCardParams{
+				RequestMulticapture: stripe.String(string(stripe.PaymentIntentPaymentMethodOptionsCardRequestMulticaptureIfAvailable)),
+			},
+		}
+	} else if req.SellerStripeAccountID != "" {
 		params.TransferData = &stripe.PaymentIntentTransferDataParams{
 			Destination: stripe.String(req.SellerStripeAccountID),
 		}
@@ -108,27 +119,111 @@ func (s *Service) CreateEscrowPayment(ctx context.Context, req *CreatePaymentReq
 }
 
 // CapturePayment captures a held payment (releases from escrow to seller).
-func (s *Service) CapturePayment(ctx context.Context, paymentIntentID string) error {
-	_, err := paymentintent.Capture(paymentIntentID, nil)
+// It returns the charge to transfer the seller's share from, or "" when the
+// payment was a destination charge and Stripe already paid the seller.
+func (s *Service) CapturePayment(ctx context.Context, paymentIntentID string) (string, error) {
+	intent, err := paymentintent.Capture(paymentIntentID, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrPaymentFailed, err)
+	}
+	return untransferredCharge(intent), nil
+}
+
+// CapturePartialPayment captures part of a held payment; Stripe releases the
+// uncaptured remainder back to the buyer. Like CapturePayment it returns the
+// charge to transfer the seller's share from, if any.
+func (s *Service) CapturePartialPayment(ctx context.Context, paymentIntentID string, amount float64) (string, error) {
+	if amount <= 0 {
+		return "", ErrInvalidAmount
+	}
+	params := &stripe.PaymentIntentCaptureParams{
+		AmountToCapture: stripe.Int64(int64(math.Round(amount * 100))),
+	}
+	intent, err := paymentintent.Capture(paymentIntentID, params)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrPaymentFailed, err)
+	}
+	return untransferredCharge(intent), nil
+}
+
+// untransferredCharge returns a captured payment's charge when its funds
+// stayed with the platform: a multicapture payment, or one made before the
+// seller had a Connect account.
+func untransferredCharge(intent *stripe.PaymentIntent) string {
+	if intent.TransferData != nil || intent.LatestCharge == nil {
+		return ""
+	}
+	return intent.LatestCharge.ID
+}
+
+// CaptureMilestonePayment captures part of a multicapture payment. Once final
+// is set Stripe releases the uncaptured remainder back to the buyer. It returns
+// the ID of the charge the capture belongs to.
+func (s *Service) CaptureMilestonePayment(ctx context.Context, paymentIntentID string, amount float64, final bool) (string, error) {
+	if amount <= 0 {
+		return "", ErrInvalidAmount
+	}
+	params := &stripe.PaymentIntentCaptureParams{
+		AmountToCapture: stripe.Int64(int64(math.Round(amount * 100))),
+		FinalCapture:    stripe.Bool(final),
+	}
+	intent, err := paymentintent.Capture(paymentIntentID, params)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrPaymentFailed, err)
+	}
+	if intent.LatestCharge == nil {
+		return "", nil
+	}
+	return intent.LatestCharge.ID, nil
+}
+
+// CancelPayment cancels a held payment, releasing whatever is uncaptured back to the buyer.
+func (s *Service) CancelPayment(ctx context.Context, paymentIntentID string) error {
+	_, err := paymentintent.Cancel(paymentIntentID, nil)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrPaymentFailed, err)
 	}
 	return nil
 }
 
-// RefundPayment refunds a payment.
-func (s *Service) RefundPayment(ctx context.Context, paymentIntentID string, amount *float64) error {
+// RefundPayment refunds a payment, in full or in part, and returns the Stripe
+// refund ID. A payment can be refunded in parts until nothing is left. When
+// the payment was paid out to a seller as a destination charge, the refund is
+// taken back from the seller's Connect account and the platform fee returned
+// in proportion.
+func (s *Service) RefundPayment(ctx context.Context, req *RefundRequest) (string, error) {
 	params := &stripe.RefundParams{
-		PaymentIntent: stripe.String(paymentIntentID),
+		PaymentIntent: stripe.String(req.PaymentIntentID),
+	}
+	if req.Amount != nil {
+		if *req.Amount <= 0 {
+			return "", ErrInvalidAmount
+		}
+		params.Amount = stripe.Int64(int64(math.Round(*req.Amount * 100)))
 	}
-	if amount != nil {
-		params.Amount = stripe.Int64(int64(*amount * 100))
+	if req.Reason != "" {
+		params.AddMetadata("reason", req.Reason)
 	}
-	_, err := refund.New(params)
+	if req.RefundID != uuid.Nil {
+		params.AddMetadata("refund_id", req.RefundID.String())
+		// Accepting the same refund twice must not refund twice
+		params.SetIdempotencyKey("refund_" + req.RefundID.String())
+	}
+
+	intent, err := paymentintent.Get(req.PaymentIntentID, nil)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrRefundFailed, err)
+		return "", fmt.Errorf("%w: %v", ErrRefundFailed, err)
 	}
-	return nil
+	if intent.Status == stripe.PaymentIntentStatusSucceeded && intent.TransferData != nil {
+		params.ReverseTransfer = stripe.Bool(true)
+		params.RefundApplicationFee = stripe.Bool(true)
+	}
+
+	result, err := refund.New(params)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRefundFailed, err)
+	}
+	return result.ID, nil
 }
 
 // TransferToSeller transfers funds directly to seller's connected account.
@@ -163,6 +258,69 @@ func (s *Service) TransferToSeller(ctx context.Context, req *TransferRequest) (*
 	}, nil
 }
 
+// CreateDepositPayment creates a payment that funds a wallet deposit. It is
+// captured straight away. With a saved payment method it is confirmed
+// off-session; otherwise the client secret confirms it in the browser.
+func (s *Service) CreateDepositPayment(ctx context.Context, req *DepositPaymentRequest) (*PaymentResult, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	params := &stripe.PaymentIntentParams{
+		Amount:   stripe.Int64(int64(math.Round(req.Amount * 100))),
+		Currency: stripe.String(normalizeCurrency(req.Currency)),
+		Metadata: map[string]string{
+			"wallet_deposit_id": req.DepositID.String(),
+		},
+	}
+	if req.CustomerID != "" && req.PaymentMethodID != "" {
+		params.Customer = stripe.String(req.CustomerID)
+		params.PaymentMethod = stripe.String(req.PaymentMethodID)
+		params.OffSession = stripe.Bool(true)
+		params.Confirm = stripe.Bool(true)
+	}
+
+	intent, err := paymentintent.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPaymentFailed, err)
+	}
+
+	return &PaymentResult{
+		PaymentIntentID: intent.ID,
+		ClientSecret:    intent.ClientSecret,
+		Status:          string(intent.Status),
+		Amount:          req.Amount,
+		Currency:        req.Currency,
+	}, nil
+}
+
+// Payout transfers money from the platform balance to a Connect account, to
+// pay out a wallet withdrawal.
+func (s *Service) Payout(ctx context.Context, req *PayoutRequest) (*TransferResult, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	xfer, err := transfer.New(&stripe.TransferParams{
+		Amount:      stripe.Int64(int64(math.Round(req.Amount * 100))),
+		Currency:    stripe.String(normalizeCurrency(req.Currency)),
+		Destination: stripe.String(req.DestinationAccountID),
+		Metadata: map[string]string{
+			"wallet_withdrawal_id": req.WithdrawalID.String(),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransferFailed, err)
+	}
+
+	return &TransferResult{
+		TransferID: xfer.ID,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+		Status:     "completed",
+	}, nil
+}
+
 // GetPaymentIntent retrieves a payment intent.
 func (s *Service) GetPaymentIntent(ctx context.Context, paymentIntentID string) (*PaymentStatus, error) {
 	intent, err := paymentintent.Get(paymentIntentID, nil)
@@ -262,15 +420,32 @@ type CreatePaymentRequest struct {
 	CustomerID            string
 	PaymentMethodID       string
 	SellerStripeAccountID string
+	Multicapture          bool // Captured in parts, e.g. per milestone
 }
 
 type PaymentResult struct {
 	PaymentIntentID string  `json:"payment_intent_id"`
+	ClientSecret    string  `json:"client_secret,omitempty"`
 	Status          string  `json:"status"`
 	Amount          float64 `json:"amount"`
 	Currency        string  `json:"currency"`
 }
 
+type DepositPaymentRequest struct {
+	DepositID       uuid.UUID
+	Amount          float64
+	Currency        string
+	CustomerID      string
+	PaymentMethodID string
+}
+
+type PayoutRequest struct {
+	WithdrawalID         uuid.UUID
+	DestinationAccountID string
+	Amount               float64
+	Currency             string
+}
+
 type PaymentStatus struct {
 	PaymentIntentID string  `json:"payment_intent_id"`
 	Status          string  `json:"status"`
@@ -279,6 +454,13 @@ type PaymentStatus struct {
 	CapturedAmount  float64 `json:"captured_amount"`
 }
 
+type RefundRequest struct {
+	PaymentIntentID string
+	RefundID        uuid.UUID // The transaction refund being paid; uuid.Nil for none
+	Amount          *float64  // nil refunds whatever is left
+	Reason          string
+}
+
 type TransferRequest struct {
 	TransactionID         uuid.UUID
 	SellerStripeAccountID string
@@ -323,6 +505,17 @@ func (a *Adapter) SetSpendingChecker(checker SpendingChecker) {
 // CreateEscrowPayment resolves payment method + Connect account, checks spending limits,
 // and creates an off-session PaymentIntent.
 func (a *Adapter) CreateEscrowPayment(ctx context.Context, transactionID, buyerID, sellerID string, amount float64, currency string) (string, error) {
+	return a.createEscrowPayment(ctx, transactionID, buyerID, sellerID, amount, currency, false)
+}
+
+// CreateMilestoneEscrowPayment creates the PaymentIntent for a deal paid in
+// milestones. It is captured once per milestone, with each part transferred
+// to the seller separately.
+func (a *Adapter) CreateMilestoneEscrowPayment(ctx context.Context, transactionID, buyerID, sellerID string, amount float64, currency string) (string, error) {
+	return a.createEscrowPayment(ctx, transactionID, buyerID, sellerID, amount, currency, true)
+}
+
+func (a *Adapter) createEscrowPayment(ctx context.Context, transactionID, buyerID, sellerID string, amount float64, currency string, multicapture bool) (string, error) {
 	txID, _ := uuid.Parse(transactionID)
 	bID, _ := uuid.Parse(buyerID)
 	sID, _ := uuid.Parse(sellerID)
@@ -369,6 +562,7 @@ func (a *Adapter) CreateEscrowPayment(ctx context.Context, transactionID, buyerI
 		CustomerID:            customerID,
 		PaymentMethodID:       pmID,
 		SellerStripeAccountID: sellerAccount,
+		Multicapture:          multicapture,
 	})
 	if err != nil {
 		return "", err
@@ -377,13 +571,137 @@ func (a *Adapter) CreateEscrowPayment(ctx context.Context, transactionID, buyerI
 }
 
 // CapturePayment captures a held payment.
-func (a *Adapter) CapturePayment(ctx context.Context, paymentIntentID string) error {
+func (a *Adapter) CapturePayment(ctx context.Context, paymentIntentID string) (string, error) {
 	return a.service.CapturePayment(ctx, paymentIntentID)
 }
 
-// RefundPayment refunds a payment.
-func (a *Adapter) RefundPayment(ctx context.Context, paymentIntentID string) error {
-	return a.service.RefundPayment(ctx, paymentIntentID, nil)
+// CapturePartialPayment captures part of a held payment.
+func (a *Adapter) CapturePartialPayment(ctx context.Context, paymentIntentID string, amount float64) (string, error) {
+	return a.service.CapturePartialPayment(ctx, paymentIntentID, amount)
+}
+
+// CaptureMilestonePayment captures one milestone's part of a held payment.
+func (a *Adapter) CaptureMilestonePayment(ctx context.Context, paymentIntentID string, amount float64, final bool) (string, error) {
+	return a.service.CaptureMilestonePayment(ctx, paymentIntentID, amount, final)
+}
+
+// TransferToSeller transfers a captured amount, less the platform fee, to the
+// seller's Connect account. Without a Connect account resolver the funds stay
+// with the platform.
+func (a *Adapter) TransferToSeller(ctx context.Context, transactionID, sellerID string, amount float64, currency, chargeID string) error {
+	if a.resolver == nil {
+		return nil
+	}
+	txID, _ := uuid.Parse(transactionID)
+	sID, _ := uuid.Parse(sellerID)
+
+	sellerAccount, err := a.resolver.GetConnectAccountIDForAgent(ctx, sID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve seller connect account: %w", err)
+	}
+	if sellerAccount == "" {
+		return ErrSellerNotPayable
+	}
+
+	amountCents := int64(math.Round(amount * 100))
+	platformFee := int64(float64(amountCents) * a.service.config.PlatformFeePercent)
+	_, err = a.service.TransferToSeller(ctx, &TransferRequest{
+		TransactionID:         txID,
+		SellerStripeAccountID: sellerAccount,
+		Amount:                float64(amountCents-platformFee) / 100,
+		Currency:              currency,
+		SourceTransactionID:   chargeID,
+	})
+	return err
+}
+
+// CancelPayment cancels a held payment.
+func (a *Adapter) CancelPayment(ctx context.Context, paymentIntentID string) error {
+	return a.service.CancelPayment(ctx, paymentIntentID)
+}
+
+// CreateDepositPayment creates the payment for a wallet deposit (implements
+// wallet.PaymentProvider). An agent's deposit is charged to its owner's saved
+// card off-session; a user's deposit is confirmed in the browser with the
+// returned client secret.
+func (a *Adapter) CreateDepositPayment(ctx context.Context, depositID, ownerType, ownerID string, amount float64, currency string) (string, string, error) {
+	dID, _ := uuid.Parse(depositID)
+
+	var customerID, pmID string
+	if ownerType == "agent" && a.paymentResolver != nil {
+		agentID, _ := uuid.Parse(ownerID)
+		var err error
+		customerID, pmID, _, err = a.paymentResolver.GetPaymentMethodForAgent(ctx, agentID)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to resolve payment method: %w", err)
+		}
+		if customerID == "" || pmID == "" {
+			return "", "", ErrNoPaymentMethod
+		}
+	}
+
+	result, err := a.service.CreateDepositPayment(ctx, &DepositPaymentRequest{
+		DepositID:       dID,
+		Amount:          amount,
+		Currency:        currency,
+		CustomerID:      customerID,
+		PaymentMethodID: pmID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return result.PaymentIntentID, result.ClientSecret, nil
+}
+
+// PayoutToOwner pays a wallet withdrawal out to the Connect account of the
+// user, or of the agent's owner (implements wallet.PaymentProvider).
+func (a *Adapter) PayoutToOwner(ctx context.Context, withdrawalID, ownerType, ownerID string, amount float64, currency string) (string, error) {
+	if a.resolver == nil {
+		return "", ErrNoPayoutAccount
+	}
+	wID, _ := uuid.Parse(withdrawalID)
+	oID, _ := uuid.Parse(ownerID)
+
+	var account string
+	var err error
+	if ownerType == "user" {
+		account, err = a.resolver.GetConnectAccountIDForUser(ctx, oID)
+	} else {
+		account, err = a.resolver.GetConnectAccountIDForAgent(ctx, oID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve connect account: %w", err)
+	}
+	if account == "" {
+		return "", ErrNoPayoutAccount
+	}
+
+	result, err := a.service.Payout(ctx, &PayoutRequest{
+		WithdrawalID:         wID,
+		DestinationAccountID: account,
+		Amount:               amount,
+		Currency:             currency,
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.TransferID, nil
+}
+
+// RefundPayment refunds a payment and returns the Stripe refund ID. An amount
+// of 0 refunds whatever is left of the payment.
+func (a *Adapter) RefundPayment(ctx context.Context, paymentIntentID, refundID string, amount float64, reason string) (string, error) {
+	req := &RefundRequest{
+		PaymentIntentID: paymentIntentID,
+		Reason:          reason,
+	}
+	if id, err := uuid.Parse(refundID); err == nil {
+		req.RefundID = id
+	}
+	if amount > 0 {
+		req.Amount = &amount
+	}
+	return a.service.RefundPayment(ctx, req)
 }
 
 func normalizeCurrency(currency string) string {
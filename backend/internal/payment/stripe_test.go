@@ -194,6 +194,13 @@ func (m *mockResolver) GetConnectAccountIDForAgent(_ context.Context, agentID uu
 	return m.accounts[agentID], nil
 }
 
+func (m *mockResolver) GetConnectAccountIDForUser(_ context.Context, userID uuid.UUID) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	return m.accounts[userID], nil
+}
+
 func TestAdapterSetConnectAccountResolver(t *testing.T) {
 	service := NewService(Config{SecretKey: "sk_test_xxx"})
 	adapter := NewAdapter(service)
@@ -285,6 +292,45 @@ func TestAdapterCreateEscrowPayment_ResolverReturnsAccount(t *testing.T) {
 	}
 }
 
+func TestAdapterTransferToSeller(t *testing.T) {
+	service := NewService(Config{SecretKey: "sk_test_xxx"})
+	adapter := NewAdapter(service)
+
+	// Without a resolver the funds stay with the platform
+	err := adapter.TransferToSeller(context.Background(),
+		uuid.New().String(), uuid.New().String(), 10.0, "USD", "ch_123",
+	)
+	if err != nil {
+		t.Errorf("expected no transfer without a resolver, got %v", err)
+	}
+
+	adapter.SetConnectAccountResolver(&mockResolver{accounts: map[uuid.UUID]string{}})
+	err = adapter.TransferToSeller(context.Background(),
+		uuid.New().String(), uuid.New().String(), 10.0, "USD", "ch_123",
+	)
+	if err != ErrSellerNotPayable {
+		t.Errorf("expected ErrSellerNotPayable, got %v", err)
+	}
+}
+
+func TestCaptureMilestonePayment_InvalidAmount(t *testing.T) {
+	service := NewService(Config{SecretKey: "sk_test_xxx"})
+
+	if _, err := service.CaptureMilestonePayment(context.Background(), "pi_123", 0, true); err != ErrInvalidAmount {
+		t.Errorf("expected ErrInvalidAmount, got %v", err)
+	}
+}
+
+func TestRefundPayment_InvalidAmount(t *testing.T) {
+	service := NewService(Config{SecretKey: "sk_test_xxx"})
+
+	amount := -5.0
+	_, err := service.RefundPayment(context.Background(), &RefundRequest{PaymentIntentID: "pi_123", Amount: &amount})
+	if err != ErrInvalidAmount {
+		t.Errorf("expected ErrInvalidAmount, got %v", err)
+	}
+}
+
 func TestAmountConversion(t *testing.T) {
 	// Test dollar to cents conversion
 	tests := []struct {
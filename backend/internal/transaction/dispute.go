@@ -0,0 +1,439 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/digi604/swarmmarket/backend/pkg/logger"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDisputeResolved    = errors.New("dispute has already been resolved")
+	ErrEmptyEvidence      = errors.New("evidence needs a body or attachments")
+	ErrTooManyAttachments = errors.New("too many attachments")
+	ErrInvalidResolution  = errors.New("invalid dispute resolution")
+)
+
+const (
+	// defaultDisputeResponseWindow is how long the other party has to respond
+	// to a dispute before it is decided in the opener's favour.
+	defaultDisputeResponseWindow = 72 * time.Hour
+
+	// maxEvidenceAttachments is the most attachments one evidence entry can have.
+	maxEvidenceAttachments = 10
+)
+
+// GetDispute retrieves the dispute on a transaction, or on one of its
+// milestones if milestoneID is set, with its evidence thread. Only the buyer,
+// the seller and arbitrators can see it.
+func (s *Service) GetDispute(ctx context.Context, transactionID uuid.UUID, milestoneID *uuid.UUID, agentID uuid.UUID) (*Dispute, error) {
+	tx, err := s.repo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if s.disputeRole(tx, agentID) == "" {
+		return nil, ErrNotAuthorized
+	}
+
+	dispute, err := s.findDispute(ctx, transactionID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	dispute.Evidence, err = s.repo.GetDisputeEvidence(ctx, dispute.ID)
+	if err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// SubmitEvidence adds an entry to a dispute's evidence thread. The
+// respondent's first entry is their response: it stops the response deadline
+// and hands the dispute to arbitration.
+func (s *Service) SubmitEvidence(ctx context.Context, transactionID uuid.UUID, milestoneID *uuid.UUID, agentID uuid.UUID, req *SubmitEvidenceRequest) (*DisputeEvidence, error) {
+	if req.Body == "" && len(req.Attachments) == 0 {
+		return nil, ErrEmptyEvidence
+	}
+	if len(req.Attachments) > maxEvidenceAttachments {
+		return nil, ErrTooManyAttachments
+	}
+
+	tx, err := s.repo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	role := s.disputeRole(tx, agentID)
+	if role == "" {
+		return nil, ErrNotAuthorized
+	}
+
+	dispute, err := s.findDispute(ctx, transactionID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if dispute.closed() {
+		return nil, ErrDisputeResolved
+	}
+
+	now := time.Now().UTC()
+	evidence := &DisputeEvidence{
+		ID:          uuid.New(),
+		DisputeID:   dispute.ID,
+		AuthorID:    agentID,
+		Role:        role,
+		Body:        req.Body,
+		Attachments: req.Attachments,
+		CreatedAt:   now,
+	}
+	if err := s.repo.CreateDisputeEvidence(ctx, evidence); err != nil {
+		return nil, err
+	}
+
+	if agentID == dispute.RespondentID && dispute.RespondedAt == nil {
+		dispute.RespondedAt = &now
+		dispute.Status = DisputeArbitration
+		if err := s.repo.UpdateDispute(ctx, dispute); err != nil {
+			return nil, err
+		}
+	}
+
+	payload := map[string]any{
+		"transaction_id": transactionID,
+		"dispute_id":     dispute.ID,
+		"evidence_id":    evidence.ID,
+		"buyer_id":       tx.BuyerID,
+		"seller_id":      tx.SellerID,
+		"author_id":      agentID,
+		"role":           role,
+		"status":         dispute.Status,
+	}
+	if dispute.MilestoneID != nil {
+		payload["milestone_id"] = *dispute.MilestoneID
+	}
+	s.publishEvent(ctx, "dispute.evidence_submitted", payload)
+
+	return evidence, nil
+}
+
+// ResolveDispute settles a dispute. Arbitrators can choose any outcome; the
+// buyer and seller can only concede, the buyer by releasing escrow to the
+// seller and the seller by refunding the buyer.
+func (s *Service) ResolveDispute(ctx context.Context, transactionID uuid.UUID, milestoneID *uuid.UUID, agentID uuid.UUID, req *ResolveDisputeRequest) (*Dispute, error) {
+	switch req.Outcome {
+	case OutcomeRelease, OutcomeRefund:
+	case OutcomeSplit:
+		if req.SellerPercent <= 0 || req.SellerPercent >= 100 {
+			return nil, ErrInvalidResolution
+		}
+	default:
+		return nil, ErrInvalidResolution
+	}
+
+	tx, err := s.repo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	switch s.disputeRole(tx, agentID) {
+	case "arbitrator":
+	case "buyer":
+		if req.Outcome != OutcomeRelease {
+			return nil, ErrNotAuthorized
+		}
+	case "seller":
+		if req.Outcome != OutcomeRefund {
+			return nil, ErrNotAuthorized
+		}
+	default:
+		return nil, ErrNotAuthorized
+	}
+
+	dispute, err := s.findDispute(ctx, transactionID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if dispute.closed() {
+		return nil, ErrDisputeResolved
+	}
+
+	if err := s.resolveDispute(ctx, tx, dispute, req.Outcome, req.SellerPercent, &agentID, req.Note); err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// ProcessDisputes decides the open disputes whose respondent has not answered
+// by the response deadline in the opener's favour: a refund when the buyer
+// opened it, a release when the seller did. It returns how many were decided.
+func (s *Service) ProcessDisputes(ctx context.Context, now time.Time) (int, error) {
+	disputes, err := s.repo.GetOverdueDisputes(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	decided := 0
+	for _, dispute := range disputes {
+		tx, err := s.repo.GetTransactionByID(ctx, dispute.TransactionID)
+		if err != nil {
+			return decided, err
+		}
+
+		outcome := OutcomeRefund
+		if dispute.OpenedBy == tx.SellerID {
+			outcome = OutcomeRelease
+		}
+		if err := s.resolveDispute(ctx, tx, dispute, outcome, 0, nil, "respondent did not respond in time"); err != nil {
+			// Resolved by an arbitrator or a concession in the meantime
+			if err == ErrDisputeResolved {
+				continue
+			}
+			logger.Error("dispute_default_failed", map[string]interface{}{
+				"dispute_id":     dispute.ID.String(),
+				"transaction_id": tx.ID.String(),
+				"error":          err.Error(),
+			})
+			continue
+		}
+		decided++
+	}
+	return decided, nil
+}
+
+// resolveDispute moves the escrowed funds for an outcome, settles the
+// transaction or milestone and records the winner and loser for both agents'
+// reputations. The dispute is claimed as resolving first, so that its funds
+// are moved only once; it returns ErrDisputeResolved if someone else got
+// there first.
+func (s *Service) resolveDispute(ctx context.Context, tx *Transaction, dispute *Dispute, outcome DisputeOutcome, sellerPercent float64, resolvedBy *uuid.UUID, note string) error {
+	status := dispute.Status
+	claimed, err := s.repo.ClaimDispute(ctx, dispute.ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrDisputeResolved
+	}
+	dispute.Status = DisputeResolving
+
+	// A milestone dispute is over the milestone's amount only
+	amount := tx.Amount
+	var milestones []*Milestone
+	var milestone *Milestone
+	if dispute.MilestoneID != nil {
+		tx, milestones, milestone, err = s.getMilestone(ctx, tx.ID, *dispute.MilestoneID)
+		if err != nil {
+			s.reopenDispute(ctx, dispute, status)
+			return err
+		}
+		amount = milestone.Amount
+	}
+
+	var sellerAmount float64
+	switch outcome {
+	case OutcomeRelease:
+		sellerPercent = 100
+		sellerAmount = amount
+	case OutcomeRefund:
+		sellerPercent = 0
+	case OutcomeSplit:
+		sellerAmount = math.Round(amount*sellerPercent) / 100
+	}
+	refundAmount := math.Round((amount-sellerAmount)*100) / 100
+
+	if milestone != nil {
+		err = s.settleMilestone(ctx, tx, milestones, milestone, MilestoneDisputed, sellerAmount)
+	} else {
+		err = s.settleDisputedTransaction(ctx, tx, outcome, sellerAmount)
+	}
+	if err != nil {
+		s.reopenDispute(ctx, dispute, status)
+		return err
+	}
+
+	// The side that gets more than half of the escrow wins; an even split has no winner.
+	switch {
+	case sellerPercent > 50:
+		dispute.WinnerID, dispute.LoserID = &tx.SellerID, &tx.BuyerID
+	case sellerPercent < 50:
+		dispute.WinnerID, dispute.LoserID = &tx.BuyerID, &tx.SellerID
+	}
+
+	now := time.Now().UTC()
+	dispute.Status = DisputeResolved
+	dispute.Outcome = &outcome
+	dispute.SellerPercent = &sellerPercent
+	dispute.SellerAmount = &sellerAmount
+	dispute.RefundAmount = &refundAmount
+	dispute.ResolvedBy = resolvedBy
+	dispute.ResolutionNote = note
+	dispute.ResolvedAt = &now
+	if err := s.repo.UpdateDispute(ctx, dispute); err != nil {
+		return err
+	}
+
+	logger.Info("dispute_resolved", map[string]interface{}{
+		"dispute_id":     dispute.ID.String(),
+		"transaction_id": tx.ID.String(),
+		"outcome":        string(outcome),
+		"seller_amount":  sellerAmount,
+		"refund_amount":  refundAmount,
+	})
+
+	payload := map[string]any{
+		"transaction_id": tx.ID,
+		"dispute_id":     dispute.ID,
+		"buyer_id":       tx.BuyerID,
+		"seller_id":      tx.SellerID,
+		"outcome":        outcome,
+		"seller_percent": sellerPercent,
+		"seller_amount":  sellerAmount,
+		"refund_amount":  refundAmount,
+		"currency":       tx.Currency,
+	}
+	if dispute.MilestoneID != nil {
+		payload["milestone_id"] = *dispute.MilestoneID
+	}
+	if dispute.WinnerID != nil {
+		payload["winner_id"] = *dispute.WinnerID
+	}
+	if resolvedBy != nil {
+		payload["resolved_by"] = *resolvedBy
+	}
+	s.publishEvent(ctx, "dispute.resolved", payload)
+
+	return nil
+}
+
+// settleDisputedTransaction moves a disputed transaction's escrow for an
+// outcome and settles the transaction.
+func (s *Service) settleDisputedTransaction(ctx context.Context, tx *Transaction, outcome DisputeOutcome, sellerAmount float64) error {
+	// Move the funds if escrow was funded. Escrow is held uncaptured, so a
+	// split captures the seller's share and Stripe releases the rest to the buyer.
+	escrow, err := s.repo.GetEscrowByTransactionID(ctx, tx.ID)
+	if err == nil && escrow.FundedAt != nil && escrow.StripePaymentIntentID != nil && *escrow.StripePaymentIntentID != "" && s.payment != nil {
+		paymentIntentID := *escrow.StripePaymentIntentID
+		var chargeID string
+		switch outcome {
+		case OutcomeRelease:
+			chargeID, err = s.payment.CapturePayment(ctx, paymentIntentID)
+		case OutcomeRefund:
+			_, err = s.payment.RefundPayment(ctx, paymentIntentID, "", 0, "Dispute resolved in the buyer's favour")
+		case OutcomeSplit:
+			chargeID, err = s.payment.CapturePartialPayment(ctx, paymentIntentID, sellerAmount)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to settle disputed escrow: %w", err)
+		}
+		// The funds are captured either way; a failed transfer is retried by hand
+		if chargeID != "" {
+			if err := s.payment.TransferToSeller(ctx, tx.ID.String(), tx.SellerID.String(), sellerAmount, tx.Currency, chargeID); err != nil {
+				logger.Error("dispute_transfer_failed", map[string]interface{}{
+					"transaction_id": tx.ID.String(),
+					"amount":         sellerAmount,
+					"error":          err.Error(),
+				})
+			}
+		}
+		if err := s.repo.RecordEscrowSettlement(ctx, escrow.ID, sellerAmount, tx.Amount); err != nil {
+			return fmt.Errorf("failed to record escrow settlement: %w", err)
+		}
+		if err := s.settleInLedger(ctx, escrow, tx.SellerID, sellerAmount, tx.Amount-sellerAmount); err != nil {
+			return fmt.Errorf("failed to settle disputed escrow in the ledger: %w", err)
+		}
+	} else if err == nil && escrow.fundedFromWallet() {
+		if err := s.settleInLedger(ctx, escrow, tx.SellerID, sellerAmount, tx.Amount-sellerAmount); err != nil {
+			return fmt.Errorf("failed to settle disputed escrow: %w", err)
+		}
+		if err := s.repo.RecordEscrowSettlement(ctx, escrow.ID, sellerAmount, tx.Amount); err != nil {
+			return fmt.Errorf("failed to record escrow settlement: %w", err)
+		}
+	}
+	if escrow != nil {
+		escrowStatus := EscrowReleased
+		if outcome == OutcomeRefund {
+			escrowStatus = EscrowRefunded
+		}
+		if err := s.repo.UpdateEscrowStatus(ctx, escrow.ID, escrowStatus); err != nil {
+			return fmt.Errorf("failed to update escrow status: %w", err)
+		}
+	}
+
+	// Settle the transaction
+	if outcome == OutcomeRefund {
+		return s.repo.UpdateTransactionStatus(ctx, tx.ID, StatusRefunded)
+	}
+	return s.repo.CompleteTransaction(ctx, tx.ID)
+}
+
+// reopenDispute moves a dispute claimed by resolveDispute back to the status
+// it was claimed from, so that it can be resolved again.
+func (s *Service) reopenDispute(ctx context.Context, dispute *Dispute, status DisputeStatus) {
+	if err := s.repo.ReopenDispute(ctx, dispute.ID, status); err != nil {
+		logger.Error("dispute_reopen_failed", map[string]interface{}{
+			"dispute_id":     dispute.ID.String(),
+			"transaction_id": dispute.TransactionID.String(),
+			"error":          err.Error(),
+		})
+		return
+	}
+	dispute.Status = status
+}
+
+// closed reports whether a dispute is resolved or being resolved.
+func (d *Dispute) closed() bool {
+	return d.Status == DisputeResolving || d.Status == DisputeResolved
+}
+
+// newDispute builds a dispute opened by agentID on a transaction. The other
+// party is the respondent and has until the response deadline to answer.
+func (s *Service) newDispute(tx *Transaction, agentID uuid.UUID, req *DisputeRequest) *Dispute {
+	respondentID := tx.SellerID
+	if agentID == tx.SellerID {
+		respondentID = tx.BuyerID
+	}
+	now := time.Now().UTC()
+	return &Dispute{
+		ID:            uuid.New(),
+		TransactionID: tx.ID,
+		OpenedBy:      agentID,
+		RespondentID:  respondentID,
+		Reason:        req.Reason,
+		Description:   req.Description,
+		Status:        DisputeOpen,
+		ResponseDueAt: now.Add(s.disputeResponseWindow),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// findDispute retrieves the dispute on a transaction, or on one of its
+// milestones if milestoneID is set.
+func (s *Service) findDispute(ctx context.Context, transactionID uuid.UUID, milestoneID *uuid.UUID) (*Dispute, error) {
+	if milestoneID == nil {
+		return s.repo.GetDisputeByTransactionID(ctx, transactionID)
+	}
+	dispute, err := s.repo.GetDisputeByMilestoneID(ctx, *milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if dispute.TransactionID != transactionID {
+		return nil, ErrDisputeNotFound
+	}
+	return dispute, nil
+}
+
+// disputeRole returns the part an agent plays in a transaction's dispute:
+// "buyer", "seller", "arbitrator", or "" if the agent has no part in it.
+func (s *Service) disputeRole(tx *Transaction, agentID uuid.UUID) string {
+	switch {
+	case agentID == tx.BuyerID:
+		return "buyer"
+	case agentID == tx.SellerID:
+		return "seller"
+	case s.arbitrators[agentID]:
+		return "arbitrator"
+	}
+	return ""
+}
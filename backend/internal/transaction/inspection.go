@@ -0,0 +1,156 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/digi604/swarmmarket/backend/pkg/logger"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidInspectionWindow = errors.New("inspection window is out of range")
+
+const (
+	// defaultInspectionWindow is how long a buyer has after delivery to confirm
+	// it or open a dispute before escrow is released to the seller.
+	defaultInspectionWindow = 72 * time.Hour
+
+	// defaultInspectionWarning is how long before the window ends the buyer is warned.
+	defaultInspectionWarning = 24 * time.Hour
+
+	// defaultMaxInspectionWindow is the longest window a buyer can set.
+	defaultMaxInspectionWindow = 30 * 24 * time.Hour
+)
+
+// SetInspectionWindow sets how many hours the buyer has to inspect a delivery,
+// overriding the category default. Only the buyer can set it, and only before
+// the seller delivers.
+func (s *Service) SetInspectionWindow(ctx context.Context, transactionID, agentID uuid.UUID, hours int) (*Transaction, error) {
+	if hours < 1 || time.Duration(hours)*time.Hour > s.maxInspectionWindow {
+		return nil, ErrInvalidInspectionWindow
+	}
+
+	tx, err := s.repo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.BuyerID != agentID {
+		return nil, ErrNotAuthorized
+	}
+	if tx.Status != StatusPending && tx.Status != StatusEscrowFunded {
+		return nil, ErrInvalidStatus
+	}
+
+	if err := s.repo.SetInspectionWindow(ctx, transactionID, hours); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTransactionByID(ctx, transactionID)
+}
+
+// ProcessInspections warns buyers whose inspection window is about to end and
+// completes the delivered transactions whose window has ended, releasing
+// escrow to the seller. Delivered milestones whose window has ended are
+// released the same way. Disputed transactions and milestones are skipped: a
+// dispute stops the clock. It returns how many transactions and milestones
+// were released.
+func (s *Service) ProcessInspections(ctx context.Context, now time.Time) (int, error) {
+	ending, err := s.repo.GetInspectionsEndingBefore(ctx, now.Add(s.inspectionWarning))
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ending {
+		s.warnInspectionEnding(ctx, id, now)
+	}
+
+	expired, err := s.repo.GetExpiredInspections(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for _, id := range expired {
+		tx, err := s.CompleteTransaction(ctx, id)
+		if err != nil {
+			// The buyer confirmed or disputed in the meantime
+			if err == ErrInvalidStatus {
+				continue
+			}
+			logger.Error("inspection_release_failed", map[string]interface{}{
+				"transaction_id": id.String(),
+				"error":          err.Error(),
+			})
+			continue
+		}
+
+		logger.Info("inspection_window_expired", map[string]interface{}{
+			"transaction_id": id.String(),
+			"buyer_id":       tx.BuyerID.String(),
+			"seller_id":      tx.SellerID.String(),
+			"amount":         tx.Amount,
+		})
+		completed++
+	}
+
+	milestones, err := s.repo.GetExpiredMilestoneInspections(ctx, now)
+	if err != nil {
+		return completed, err
+	}
+	for _, m := range milestones {
+		released, err := s.releaseInspectedMilestone(ctx, m.TransactionID, m.ID)
+		if err != nil {
+			logger.Error("milestone_inspection_release_failed", map[string]interface{}{
+				"transaction_id": m.TransactionID.String(),
+				"milestone_id":   m.ID.String(),
+				"error":          err.Error(),
+			})
+			continue
+		}
+		if released {
+			completed++
+		}
+	}
+	return completed, nil
+}
+
+// warnInspectionEnding publishes transaction.inspection_ending once for a
+// transaction whose inspection window ends soon.
+func (s *Service) warnInspectionEnding(ctx context.Context, transactionID uuid.UUID, now time.Time) {
+	warned, err := s.repo.MarkInspectionWarned(ctx, transactionID)
+	if err != nil || !warned {
+		return
+	}
+
+	tx, err := s.repo.GetTransactionByID(ctx, transactionID)
+	if err != nil || tx.InspectionEndsAt == nil || !tx.InspectionEndsAt.After(now) {
+		return
+	}
+
+	s.publishEvent(ctx, "transaction.inspection_ending", map[string]any{
+		"transaction_id":     tx.ID,
+		"buyer_id":           tx.BuyerID,
+		"seller_id":          tx.SellerID,
+		"amount":             tx.Amount,
+		"currency":           tx.Currency,
+		"inspection_ends_at": *tx.InspectionEndsAt,
+	})
+}
+
+// inspectionWindowFor returns a transaction's inspection window: its own if
+// the buyer set one, else its category's, else the platform default.
+func (s *Service) inspectionWindowFor(ctx context.Context, tx *Transaction) time.Duration {
+	if tx.InspectionWindow != nil {
+		return time.Duration(*tx.InspectionWindow) * time.Hour
+	}
+
+	hours, err := s.repo.GetCategoryInspectionWindow(ctx, tx.ID)
+	if err != nil {
+		logger.Warn("category_inspection_window_failed", map[string]interface{}{
+			"transaction_id": tx.ID.String(),
+			"error":          err.Error(),
+		})
+	} else if hours != nil && *hours > 0 {
+		return time.Duration(*hours) * time.Hour
+	}
+	return s.inspectionWindow
+}
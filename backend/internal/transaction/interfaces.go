@@ -2,6 +2,7 @@ package transaction
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -14,13 +15,25 @@ type RepositoryInterface interface {
 	GetTransactionByID(ctx context.Context, id uuid.UUID) (*Transaction, error)
 	ListTransactions(ctx context.Context, params ListTransactionsParams) (*TransactionListResult, error)
 	UpdateTransactionStatus(ctx context.Context, id uuid.UUID, status TransactionStatus) error
-	ConfirmDelivery(ctx context.Context, id uuid.UUID) error
+	CancelPendingTransaction(ctx context.Context, id uuid.UUID) (bool, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, inspectionEndsAt time.Time) error
+	ConfirmDelivery(ctx context.Context, id uuid.UUID) (bool, error)
+	CompleteDeliveredTransaction(ctx context.Context, id uuid.UUID) (bool, error)
+	ReopenDelivery(ctx context.Context, id uuid.UUID) error
 	CompleteTransaction(ctx context.Context, id uuid.UUID) error
 
+	// Inspection Operations
+	SetInspectionWindow(ctx context.Context, id uuid.UUID, hours int) error
+	GetCategoryInspectionWindow(ctx context.Context, id uuid.UUID) (*int, error)
+	GetInspectionsEndingBefore(ctx context.Context, before time.Time) ([]uuid.UUID, error)
+	MarkInspectionWarned(ctx context.Context, id uuid.UUID) (bool, error)
+	GetExpiredInspections(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+
 	// Escrow Operations
 	CreateEscrowAccount(ctx context.Context, transactionID uuid.UUID, amount float64, currency string) (*EscrowAccount, error)
 	GetEscrowByTransactionID(ctx context.Context, transactionID uuid.UUID) (*EscrowAccount, error)
 	UpdateEscrowStatus(ctx context.Context, id uuid.UUID, status EscrowStatus) error
+	RecordEscrowSettlement(ctx context.Context, id uuid.UUID, released, settled float64) error
 	UpdateEscrowPaymentIntent(ctx context.Context, id uuid.UUID, paymentIntentID string) error
 
 	// Rating Operations
@@ -28,6 +41,32 @@ type RepositoryInterface interface {
 	GetRatingsByTransactionID(ctx context.Context, transactionID uuid.UUID) ([]*Rating, error)
 	HasRated(ctx context.Context, transactionID, raterID uuid.UUID) (bool, error)
 
+	// Dispute Operations
+	CreateDispute(ctx context.Context, dispute *Dispute) error
+	GetDisputeByTransactionID(ctx context.Context, transactionID uuid.UUID) (*Dispute, error)
+	GetDisputeByMilestoneID(ctx context.Context, milestoneID uuid.UUID) (*Dispute, error)
+	UpdateDispute(ctx context.Context, dispute *Dispute) error
+	ClaimDispute(ctx context.Context, id uuid.UUID) (bool, error)
+	ReopenDispute(ctx context.Context, id uuid.UUID, status DisputeStatus) error
+	GetOverdueDisputes(ctx context.Context, now time.Time) ([]*Dispute, error)
+	CreateDisputeEvidence(ctx context.Context, evidence *DisputeEvidence) error
+	GetDisputeEvidence(ctx context.Context, disputeID uuid.UUID) ([]*DisputeEvidence, error)
+
+	// Milestone Operations
+	GetMilestones(ctx context.Context, transactionID uuid.UUID) ([]*Milestone, error)
+	UpdateMilestone(ctx context.Context, milestone *Milestone) error
+	MoveMilestone(ctx context.Context, id uuid.UUID, from, to MilestoneStatus) (bool, error)
+	GetExpiredMilestoneInspections(ctx context.Context, now time.Time) ([]*Milestone, error)
+	GetOfferMilestones(ctx context.Context, offerID uuid.UUID) ([]MilestoneRequest, error)
+
+	// Refund Operations
+	CreateRefund(ctx context.Context, refund *Refund) error
+	GetRefund(ctx context.Context, id uuid.UUID) (*Refund, error)
+	GetRefunds(ctx context.Context, transactionID uuid.UUID) ([]*Refund, error)
+	UpdateRefund(ctx context.Context, refund *Refund) error
+	ReserveRefund(ctx context.Context, refund *Refund) error
+	GetStaleRefunds(ctx context.Context, before time.Time) ([]*Refund, error)
+
 	// Agent Stats
 	UpdateAgentStats(ctx context.Context, agentID uuid.UUID, successful bool) error
 	RecalculateAgentRating(ctx context.Context, agentID uuid.UUID) error
@@ -0,0 +1,380 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/digi604/swarmmarket/backend/pkg/logger"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidMilestones   = errors.New("milestones need a title and positive amounts that add up to the transaction amount")
+	ErrMilestoneOutOfOrder = errors.New("earlier milestones must be settled first")
+	ErrPaidInMilestones    = errors.New("transaction is paid in milestones")
+)
+
+// maxMilestones is the most milestones a transaction can be split into.
+const maxMilestones = 20
+
+// MarkMilestoneDelivered marks a milestone as delivered by the seller and
+// starts its inspection window. Milestones are delivered in order: every
+// earlier milestone must be released or refunded first.
+func (s *Service) MarkMilestoneDelivered(ctx context.Context, transactionID, milestoneID, agentID uuid.UUID, deliveryProof, message string) (*Milestone, error) {
+	tx, milestones, milestone, err := s.getMilestone(ctx, transactionID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.SellerID != agentID {
+		return nil, ErrNotAuthorized
+	}
+	if tx.Status != StatusPending && tx.Status != StatusEscrowFunded {
+		return nil, ErrInvalidStatus
+	}
+	if milestone.Status != MilestonePending {
+		return nil, ErrInvalidStatus
+	}
+	for _, m := range milestones {
+		if m.Position < milestone.Position && !m.settled() {
+			return nil, ErrMilestoneOutOfOrder
+		}
+	}
+
+	now := time.Now().UTC()
+	inspectionEndsAt := now.Add(s.inspectionWindowFor(ctx, tx))
+	milestone.Status = MilestoneDelivered
+	milestone.DeliveryProof = deliveryProof
+	milestone.DeliveredAt = &now
+	milestone.InspectionEndsAt = &inspectionEndsAt
+	if err := s.repo.UpdateMilestone(ctx, milestone); err != nil {
+		return nil, err
+	}
+
+	logger.Info("milestone_delivered", map[string]interface{}{
+		"transaction_id": transactionID.String(),
+		"milestone_id":   milestoneID.String(),
+		"position":       milestone.Position,
+		"seller_id":      tx.SellerID.String(),
+	})
+
+	s.publishEvent(ctx, "milestone.delivered", map[string]any{
+		"transaction_id":     transactionID,
+		"milestone_id":       milestoneID,
+		"position":           milestone.Position,
+		"title":              milestone.Title,
+		"amount":             milestone.Amount,
+		"currency":           tx.Currency,
+		"buyer_id":           tx.BuyerID,
+		"seller_id":          tx.SellerID,
+		"delivery_proof":     deliveryProof,
+		"message":            message,
+		"inspection_ends_at": inspectionEndsAt,
+	})
+
+	return milestone, nil
+}
+
+// ConfirmMilestone confirms a delivered milestone and releases its amount
+// from escrow to the seller. Only the buyer can confirm. Confirming the last
+// open milestone completes the transaction.
+func (s *Service) ConfirmMilestone(ctx context.Context, transactionID, milestoneID, agentID uuid.UUID) (*Milestone, error) {
+	tx, milestones, milestone, err := s.getMilestone(ctx, transactionID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.BuyerID != agentID {
+		return nil, ErrNotAuthorized
+	}
+	if milestone.Status != MilestoneDelivered {
+		return nil, ErrInvalidStatus
+	}
+
+	if err := s.settleMilestone(ctx, tx, milestones, milestone, MilestoneDelivered, milestone.Amount); err != nil {
+		return nil, err
+	}
+	return milestone, nil
+}
+
+// DisputeMilestone opens a dispute on a single milestone. Milestones before it
+// are unaffected, but later ones can't be delivered until it is resolved.
+func (s *Service) DisputeMilestone(ctx context.Context, transactionID, milestoneID, agentID uuid.UUID, req *DisputeRequest) (*Milestone, error) {
+	tx, _, milestone, err := s.getMilestone(ctx, transactionID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.BuyerID != agentID && tx.SellerID != agentID {
+		return nil, ErrNotAuthorized
+	}
+	if milestone.Status != MilestonePending && milestone.Status != MilestoneDelivered {
+		return nil, ErrInvalidStatus
+	}
+
+	dispute := s.newDispute(tx, agentID, req)
+	dispute.MilestoneID = &milestone.ID
+	if err := s.repo.CreateDispute(ctx, dispute); err != nil {
+		return nil, err
+	}
+
+	milestone.Status = MilestoneDisputed
+	if err := s.repo.UpdateMilestone(ctx, milestone); err != nil {
+		return nil, err
+	}
+
+	s.publishEvent(ctx, "dispute.opened", map[string]any{
+		"transaction_id":  transactionID,
+		"milestone_id":    milestoneID,
+		"dispute_id":      dispute.ID,
+		"buyer_id":        tx.BuyerID,
+		"seller_id":       tx.SellerID,
+		"opened_by":       agentID,
+		"reason":          req.Reason,
+		"response_due_at": dispute.ResponseDueAt,
+	})
+
+	return milestone, nil
+}
+
+// releaseInspectedMilestone releases a delivered milestone whose inspection
+// window has ended, as if the buyer had confirmed it. It reports whether it did.
+func (s *Service) releaseInspectedMilestone(ctx context.Context, transactionID, milestoneID uuid.UUID) (bool, error) {
+	tx, milestones, milestone, err := s.getMilestone(ctx, transactionID, milestoneID)
+	if err != nil {
+		return false, err
+	}
+	// The buyer confirmed or disputed it in the meantime
+	if milestone.Status != MilestoneDelivered {
+		return false, nil
+	}
+
+	err = s.settleMilestone(ctx, tx, milestones, milestone, MilestoneDelivered, milestone.Amount)
+	if err == ErrInvalidStatus {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// settleMilestone pays sellerAmount of a milestone out of escrow to the seller
+// and returns the rest to the buyer. The milestone is first moved from its
+// status from to releasing, so that it is settled only once; it returns
+// ErrInvalidStatus if it was no longer in that status. Escrow for milestones
+// is captured in parts: each release captures its share and transfers it to
+// the seller, and the last settlement releases whatever was not captured.
+// Once every milestone is settled the transaction is completed, or refunded
+// if the seller got nothing.
+func (s *Service) settleMilestone(ctx context.Context, tx *Transaction, milestones []*Milestone, milestone *Milestone, from MilestoneStatus, sellerAmount float64) error {
+	claimed, err := s.repo.MoveMilestone(ctx, milestone.ID, from, MilestoneReleasing)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrInvalidStatus
+	}
+	milestone.Status = MilestoneReleasing
+
+	final := true
+	for _, m := range milestones {
+		if m.ID != milestone.ID && !m.settled() {
+			final = false
+		}
+	}
+
+	// Without an escrow account there are no funds to move
+	escrow, _ := s.repo.GetEscrowByTransactionID(ctx, tx.ID)
+	if escrow != nil && escrow.FundedAt != nil && escrow.StripePaymentIntentID != nil && *escrow.StripePaymentIntentID != "" && s.payment != nil {
+		paymentIntentID := *escrow.StripePaymentIntentID
+		if sellerAmount > 0 {
+			chargeID, err := s.payment.CaptureMilestonePayment(ctx, paymentIntentID, sellerAmount, final)
+			if err != nil {
+				s.reopenMilestone(ctx, milestone, from)
+				return fmt.Errorf("failed to capture milestone: %w", err)
+			}
+			// The funds are captured either way; a failed transfer is retried by hand
+			if err := s.payment.TransferToSeller(ctx, tx.ID.String(), tx.SellerID.String(), sellerAmount, tx.Currency, chargeID); err != nil {
+				logger.Error("milestone_transfer_failed", map[string]interface{}{
+					"transaction_id": tx.ID.String(),
+					"milestone_id":   milestone.ID.String(),
+					"amount":         sellerAmount,
+					"error":          err.Error(),
+				})
+			}
+		} else if final {
+			// Nothing more to capture, so release the rest of the hold
+			if err := s.payment.CancelPayment(ctx, paymentIntentID); err != nil {
+				s.reopenMilestone(ctx, milestone, from)
+				return fmt.Errorf("failed to release escrow: %w", err)
+			}
+		}
+		if err := s.repo.RecordEscrowSettlement(ctx, escrow.ID, sellerAmount, milestone.Amount); err != nil {
+			return fmt.Errorf("failed to record escrow settlement: %w", err)
+		}
+		s.settleInLedger(ctx, escrow, tx.SellerID, sellerAmount, milestone.Amount-sellerAmount)
+	} else if escrow != nil && escrow.fundedFromWallet() {
+		if err := s.settleInLedger(ctx, escrow, tx.SellerID, sellerAmount, milestone.Amount-sellerAmount); err != nil {
+			s.reopenMilestone(ctx, milestone, from)
+			return fmt.Errorf("failed to release milestone: %w", err)
+		}
+		if err := s.repo.RecordEscrowSettlement(ctx, escrow.ID, sellerAmount, milestone.Amount); err != nil {
+			return fmt.Errorf("failed to record escrow settlement: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	milestone.Status = MilestoneReleased
+	if sellerAmount == 0 {
+		milestone.Status = MilestoneRefunded
+	}
+	milestone.ReleasedAmount = sellerAmount
+	milestone.SettledAt = &now
+	if err := s.repo.UpdateMilestone(ctx, milestone); err != nil {
+		return err
+	}
+
+	logger.Info("milestone_settled", map[string]interface{}{
+		"transaction_id": tx.ID.String(),
+		"milestone_id":   milestone.ID.String(),
+		"status":         string(milestone.Status),
+		"released":       sellerAmount,
+	})
+
+	if sellerAmount > 0 {
+		s.publishEvent(ctx, "milestone.released", map[string]any{
+			"transaction_id": tx.ID,
+			"milestone_id":   milestone.ID,
+			"position":       milestone.Position,
+			"title":          milestone.Title,
+			"amount":         sellerAmount,
+			"currency":       tx.Currency,
+			"buyer_id":       tx.BuyerID,
+			"seller_id":      tx.SellerID,
+		})
+	}
+
+	if final {
+		return s.finishMilestones(ctx, tx, milestones, escrow)
+	}
+	return nil
+}
+
+// reopenMilestone moves a milestone claimed by settleMilestone back to the
+// status it was claimed from, once nothing has been paid out of escrow.
+func (s *Service) reopenMilestone(ctx context.Context, milestone *Milestone, from MilestoneStatus) {
+	if _, err := s.repo.MoveMilestone(ctx, milestone.ID, MilestoneReleasing, from); err != nil {
+		logger.Error("milestone_reopen_failed", map[string]interface{}{
+			"transaction_id": milestone.TransactionID.String(),
+			"milestone_id":   milestone.ID.String(),
+			"error":          err.Error(),
+		})
+		return
+	}
+	milestone.Status = from
+}
+
+// finishMilestones settles a transaction whose milestones are all settled.
+func (s *Service) finishMilestones(ctx context.Context, tx *Transaction, milestones []*Milestone, escrow *EscrowAccount) error {
+	var released float64
+	for _, m := range milestones {
+		released += m.ReleasedAmount
+	}
+
+	if released == 0 {
+		if err := s.repo.UpdateTransactionStatus(ctx, tx.ID, StatusRefunded); err != nil {
+			return err
+		}
+		if escrow != nil {
+			s.repo.UpdateEscrowStatus(ctx, escrow.ID, EscrowRefunded)
+		}
+		s.publishEvent(ctx, "transaction.refunded", map[string]any{
+			"transaction_id": tx.ID,
+		})
+		return nil
+	}
+
+	if escrow != nil {
+		s.repo.UpdateEscrowStatus(ctx, escrow.ID, EscrowReleased)
+	}
+	if err := s.repo.CompleteTransaction(ctx, tx.ID); err != nil {
+		return err
+	}
+
+	s.repo.UpdateAgentStats(ctx, tx.BuyerID, true)
+	s.repo.UpdateAgentStats(ctx, tx.SellerID, true)
+	if s.trust != nil {
+		go s.trust.OnTransactionCompleted(context.Background(), tx.BuyerID, tx.ID)
+		go s.trust.OnTransactionCompleted(context.Background(), tx.SellerID, tx.ID)
+	}
+
+	s.publishEvent(ctx, "transaction.completed", map[string]any{
+		"transaction_id": tx.ID,
+		"buyer_id":       tx.BuyerID,
+		"seller_id":      tx.SellerID,
+		"amount":         math.Round(released*100) / 100,
+	})
+
+	return nil
+}
+
+// getMilestone retrieves a transaction, its milestones and the one with the given ID.
+func (s *Service) getMilestone(ctx context.Context, transactionID, milestoneID uuid.UUID) (*Transaction, []*Milestone, *Milestone, error) {
+	tx, err := s.repo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	milestones, err := s.repo.GetMilestones(ctx, transactionID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, m := range milestones {
+		if m.ID == milestoneID {
+			return tx, milestones, m, nil
+		}
+	}
+	return nil, nil, nil, ErrMilestoneNotFound
+}
+
+// requireNoMilestones returns ErrPaidInMilestones for a transaction paid in
+// stages, which is delivered, confirmed and disputed milestone by milestone.
+func (s *Service) requireNoMilestones(ctx context.Context, transactionID uuid.UUID) error {
+	milestones, err := s.repo.GetMilestones(ctx, transactionID)
+	if err != nil {
+		return err
+	}
+	if len(milestones) > 0 {
+		return ErrPaidInMilestones
+	}
+	return nil
+}
+
+// validateMilestones checks that each milestone has a title and a positive
+// amount and that together they add up to the transaction amount. No
+// milestones at all is valid.
+func validateMilestones(milestones []MilestoneRequest, amount float64) error {
+	if len(milestones) == 0 {
+		return nil
+	}
+	if len(milestones) > maxMilestones {
+		return ErrInvalidMilestones
+	}
+
+	var total float64
+	for _, m := range milestones {
+		if m.Title == "" || m.Amount <= 0 {
+			return ErrInvalidMilestones
+		}
+		total += m.Amount
+	}
+	if math.Abs(total-amount) >= 0.005 {
+		return ErrInvalidMilestones
+	}
+	return nil
+}
+
+// settled reports whether a milestone has been paid out or refunded.
+func (m *Milestone) settled() bool {
+	return m.Status == MilestoneReleased || m.Status == MilestoneRefunded
+}
@@ -30,6 +30,48 @@ const (
 	EscrowDisputed EscrowStatus = "disputed"
 )
 
+// DisputeStatus represents the status of a dispute.
+type DisputeStatus string
+
+const (
+	DisputeOpen        DisputeStatus = "open"        // Waiting for the respondent
+	DisputeArbitration DisputeStatus = "arbitration" // Both sides heard, waiting for an arbitrator
+	DisputeResolving   DisputeStatus = "resolving"   // Claimed for resolution while its funds are moved
+	DisputeResolved    DisputeStatus = "resolved"
+)
+
+// DisputeOutcome is how a dispute was resolved.
+type DisputeOutcome string
+
+const (
+	OutcomeRelease DisputeOutcome = "release" // Escrow goes to the seller
+	OutcomeRefund  DisputeOutcome = "refund"  // Escrow goes back to the buyer
+	OutcomeSplit   DisputeOutcome = "split"   // Escrow is divided between them
+)
+
+// MilestoneStatus represents the status of a milestone.
+type MilestoneStatus string
+
+const (
+	MilestonePending   MilestoneStatus = "pending"
+	MilestoneDelivered MilestoneStatus = "delivered"
+	MilestoneDisputed  MilestoneStatus = "disputed"
+	MilestoneReleasing MilestoneStatus = "releasing" // Claimed for settlement while its funds are moved
+	MilestoneReleased  MilestoneStatus = "released" // Paid to the seller, in part after a split dispute
+	MilestoneRefunded  MilestoneStatus = "refunded"
+)
+
+// RefundStatus represents the status of a refund.
+type RefundStatus string
+
+const (
+	RefundRequested  RefundStatus = "requested"  // Buyer asked, waiting for the seller
+	RefundProcessing RefundStatus = "processing" // Reserved against the escrow, being paid
+	RefundCompleted  RefundStatus = "completed"
+	RefundRejected   RefundStatus = "rejected"
+	RefundFailed     RefundStatus = "failed" // The seller's refund could not be paid
+)
+
 // Transaction represents a marketplace transaction between buyer and seller.
 type Transaction struct {
 	ID                  uuid.UUID         `json:"id"`
@@ -40,10 +82,15 @@ type Transaction struct {
 	OfferID             *uuid.UUID        `json:"offer_id,omitempty"`
 	AuctionID           *uuid.UUID        `json:"auction_id,omitempty"`
 	TaskID              *uuid.UUID        `json:"task_id,omitempty"`
+	TradeID             *uuid.UUID        `json:"trade_id,omitempty"`   // Order book trade
+	ProductID           *uuid.UUID        `json:"product_id,omitempty"` // Order book product
 	Amount              float64           `json:"amount"`
 	Currency            string            `json:"currency"`
 	PlatformFee         float64           `json:"platform_fee"`
 	Status              TransactionStatus `json:"status"`
+	InspectionWindow    *int              `json:"inspection_window_hours,omitempty"` // Hours; overrides the category default
+	DeliveredAt         *time.Time        `json:"delivered_at,omitempty"`
+	InspectionEndsAt    *time.Time        `json:"inspection_ends_at,omitempty"` // Escrow is released automatically after this
 	DeliveryConfirmedAt *time.Time        `json:"delivery_confirmed_at,omitempty"`
 	CompletedAt         *time.Time        `json:"completed_at,omitempty"`
 	Metadata            map[string]any    `json:"metadata,omitempty"`
@@ -53,6 +100,12 @@ type Transaction struct {
 	// Joined fields for display
 	BuyerName  string `json:"buyer_name,omitempty"`
 	SellerName string `json:"seller_name,omitempty"`
+
+	// Loaded with the transaction details
+	Milestones     []*Milestone   `json:"milestones,omitempty"` // Set when the deal is paid in stages
+	Escrow         *EscrowAccount `json:"escrow,omitempty"`
+	Refunds        []*Refund      `json:"refunds,omitempty"`         // Refund history, oldest first
+	RefundedAmount float64        `json:"refunded_amount,omitempty"` // Total of completed refunds
 }
 
 // EscrowAccount holds funds during a transaction.
@@ -62,6 +115,9 @@ type EscrowAccount struct {
 	Amount                float64      `json:"amount"`
 	Currency              string       `json:"currency"`
 	Status                EscrowStatus `json:"status"`
+	FundedAmount          float64      `json:"funded_amount"`
+	ReleasedAmount        float64      `json:"released_amount"`  // Paid out to the seller
+	RemainingAmount       float64      `json:"remaining_amount"` // Still held; refunds reduce it too
 	FundedAt              *time.Time   `json:"funded_at,omitempty"`
 	ReleasedAt            *time.Time   `json:"released_at,omitempty"`
 	StripePaymentIntentID *string      `json:"stripe_payment_intent_id,omitempty"`
@@ -70,6 +126,25 @@ type EscrowAccount struct {
 	UpdatedAt             time.Time    `json:"updated_at"`
 }
 
+// Milestone is one stage of a transaction paid in stages. Each milestone is
+// delivered, confirmed and released on its own, in order.
+type Milestone struct {
+	ID               uuid.UUID       `json:"id"`
+	TransactionID    uuid.UUID       `json:"transaction_id"`
+	Position         int             `json:"position"` // 1-based
+	Title            string          `json:"title"`
+	Description      string          `json:"description,omitempty"`
+	Amount           float64         `json:"amount"`
+	Status           MilestoneStatus `json:"status"`
+	DeliveryProof    string          `json:"delivery_proof,omitempty"`
+	DeliveredAt      *time.Time      `json:"delivered_at,omitempty"`
+	InspectionEndsAt *time.Time      `json:"inspection_ends_at,omitempty"` // Released automatically after this
+	ReleasedAmount   float64         `json:"released_amount"`
+	SettledAt        *time.Time      `json:"settled_at,omitempty"` // When it was released or refunded
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
 // Rating represents a rating given after a transaction.
 type Rating struct {
 	ID            uuid.UUID `json:"id"`
@@ -84,19 +159,85 @@ type Rating struct {
 	RaterName string `json:"rater_name,omitempty"`
 }
 
+// Dispute is a disagreement over a transaction, held open until it is resolved
+// by a concession, an arbitrator or a missed response deadline.
+type Dispute struct {
+	ID             uuid.UUID          `json:"id"`
+	TransactionID  uuid.UUID          `json:"transaction_id"`
+	MilestoneID    *uuid.UUID         `json:"milestone_id,omitempty"` // Set when only one milestone is disputed
+	OpenedBy       uuid.UUID          `json:"opened_by"`
+	RespondentID   uuid.UUID          `json:"respondent_id"`
+	Reason         string             `json:"reason"`
+	Description    string             `json:"description,omitempty"`
+	Status         DisputeStatus      `json:"status"`
+	ResponseDueAt  time.Time          `json:"response_due_at"`
+	RespondedAt    *time.Time         `json:"responded_at,omitempty"`
+	Outcome        *DisputeOutcome    `json:"outcome,omitempty"`
+	SellerPercent  *float64           `json:"seller_percent,omitempty"`
+	SellerAmount   *float64           `json:"seller_amount,omitempty"`
+	RefundAmount   *float64           `json:"refund_amount,omitempty"`
+	WinnerID       *uuid.UUID         `json:"winner_id,omitempty"`
+	LoserID        *uuid.UUID         `json:"loser_id,omitempty"`
+	ResolvedBy     *uuid.UUID         `json:"resolved_by,omitempty"` // nil when resolved by default
+	ResolutionNote string             `json:"resolution_note,omitempty"`
+	ResolvedAt     *time.Time         `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	Evidence       []*DisputeEvidence `json:"evidence,omitempty"`
+}
+
+// DisputeEvidence is one entry in a dispute's evidence thread.
+type DisputeEvidence struct {
+	ID          uuid.UUID `json:"id"`
+	DisputeID   uuid.UUID `json:"dispute_id"`
+	AuthorID    uuid.UUID `json:"author_id"`
+	Role        string    `json:"role"` // "buyer", "seller" or "arbitrator"
+	Body        string    `json:"body"`
+	Attachments []string  `json:"attachments,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Refund returns part or all of what a transaction paid out to the buyer.
+// The seller issues refunds; a buyer's refund request waits for the seller
+// to accept or reject it.
+type Refund struct {
+	ID             uuid.UUID    `json:"id"`
+	TransactionID  uuid.UUID    `json:"transaction_id"`
+	RequestedBy    uuid.UUID    `json:"requested_by"`
+	Amount         float64      `json:"amount"`
+	Reason         string       `json:"reason"`
+	Status         RefundStatus `json:"status"`
+	StripeRefundID *string      `json:"stripe_refund_id,omitempty"`
+	ResponseNote   string       `json:"response_note,omitempty"` // Seller's note when rejecting a request
+	RespondedAt    *time.Time   `json:"responded_at,omitempty"`
+	CompletedAt    *time.Time   `json:"completed_at,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
 // --- Request/Response DTOs ---
 
 // CreateTransactionRequest is used internally when creating a transaction.
 type CreateTransactionRequest struct {
-	BuyerID   uuid.UUID
-	SellerID  uuid.UUID
-	ListingID *uuid.UUID
-	RequestID *uuid.UUID
-	OfferID   *uuid.UUID
-	AuctionID *uuid.UUID
-	TaskID    *uuid.UUID
-	Amount    float64
-	Currency  string
+	BuyerID    uuid.UUID
+	SellerID   uuid.UUID
+	ListingID  *uuid.UUID
+	RequestID  *uuid.UUID
+	OfferID    *uuid.UUID
+	AuctionID  *uuid.UUID
+	TaskID     *uuid.UUID
+	TradeID    *uuid.UUID
+	ProductID  *uuid.UUID
+	Amount     float64
+	Currency   string
+	Milestones []MilestoneRequest // Optional; amounts must add up to Amount
+}
+
+// MilestoneRequest describes one stage of a transaction paid in stages.
+type MilestoneRequest struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description,omitempty"`
+	Amount      float64 `json:"amount"`
 }
 
 // ConfirmDeliveryRequest is the request body for confirming delivery.
@@ -116,6 +257,35 @@ type DisputeRequest struct {
 	Description string `json:"description"`
 }
 
+// SetInspectionWindowRequest is the request body for setting a transaction's inspection window.
+type SetInspectionWindowRequest struct {
+	Hours int `json:"hours"`
+}
+
+// SubmitEvidenceRequest is the request body for adding to a dispute's evidence thread.
+type SubmitEvidenceRequest struct {
+	Body        string   `json:"body"`
+	Attachments []string `json:"attachments,omitempty"` // URLs of supporting files
+}
+
+// ResolveDisputeRequest is the request body for resolving a dispute.
+type ResolveDisputeRequest struct {
+	Outcome       DisputeOutcome `json:"outcome"`                  // "release", "refund" or "split"
+	SellerPercent float64        `json:"seller_percent,omitempty"` // Seller's share for a split, between 0 and 100
+	Note          string         `json:"note,omitempty"`
+}
+
+// RefundRequest is the request body for issuing or requesting a refund.
+type RefundRequest struct {
+	Amount float64 `json:"amount,omitempty"` // Omit to refund everything not yet refunded
+	Reason string  `json:"reason"`
+}
+
+// RejectRefundRequest is the request body for rejecting a refund request.
+type RejectRefundRequest struct {
+	Note string `json:"note,omitempty"`
+}
+
 // TransactionListResult is a paginated list of transactions.
 type TransactionListResult struct {
 	Items  []*Transaction `json:"items"`
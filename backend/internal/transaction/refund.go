@@ -0,0 +1,402 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/digi604/swarmmarket/backend/pkg/logger"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRefundReasonRequired = errors.New("a refund needs a reason")
+	ErrInvalidRefundAmount  = errors.New("refund amount must be positive")
+	ErrRefundExceedsPaid    = errors.New("refund is more than was paid out and not yet refunded")
+	ErrRefundRequestOpen    = errors.New("a refund request is already waiting for the seller")
+	ErrRefundNotRequested   = errors.New("refund is not waiting for the seller")
+	ErrRefundNotPayable     = errors.New("transaction has no payment to refund")
+)
+
+// refundRetryDelay is how long a refund stays processing before the worker
+// retries it.
+const refundRetryDelay = 5 * time.Minute
+
+// GetRefunds retrieves a transaction's refunds and refund requests, oldest
+// first. Only the buyer and the seller can see them.
+func (s *Service) GetRefunds(ctx context.Context, transactionID, agentID uuid.UUID) ([]*Refund, error) {
+	tx, err := s.repo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if agentID != tx.BuyerID && agentID != tx.SellerID {
+		return nil, ErrNotAuthorized
+	}
+	return s.repo.GetRefunds(ctx, transactionID)
+}
+
+// CreateRefund refunds part or all of what a completed transaction paid out.
+// The seller's refund is reserved against the escrow, then paid right away.
+// The buyer's is a refund request that waits for the seller to accept or
+// reject it; a buyer has one open request at a time. Refunds never add up to
+// more than was paid out.
+func (s *Service) CreateRefund(ctx context.Context, transactionID, agentID uuid.UUID, req *RefundRequest) (*Refund, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, ErrRefundReasonRequired
+	}
+	if req.Amount < 0 {
+		return nil, ErrInvalidRefundAmount
+	}
+
+	tx, err := s.repo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if agentID != tx.BuyerID && agentID != tx.SellerID {
+		return nil, ErrNotAuthorized
+	}
+	if tx.Status != StatusCompleted {
+		return nil, ErrInvalidStatus
+	}
+
+	escrow, refunds, err := s.refundState(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	refundable := escrow.ReleasedAmount - reservedTotal(refunds)
+
+	amount := math.Round(req.Amount*100) / 100
+	if amount == 0 {
+		amount = refundable
+	}
+	if toCents(amount) <= 0 || toCents(amount) > toCents(refundable) {
+		return nil, ErrRefundExceedsPaid
+	}
+
+	now := time.Now().UTC()
+	refund := &Refund{
+		ID:            uuid.New(),
+		TransactionID: tx.ID,
+		RequestedBy:   agentID,
+		Amount:        amount,
+		Reason:        reason,
+		Status:        RefundRequested,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if agentID == tx.BuyerID {
+		for _, r := range refunds {
+			if r.Status == RefundRequested {
+				return nil, ErrRefundRequestOpen
+			}
+		}
+		if err := s.repo.CreateRefund(ctx, refund); err != nil {
+			return nil, err
+		}
+		s.publishEvent(ctx, "refund.requested", map[string]any{
+			"transaction_id": tx.ID,
+			"refund_id":      refund.ID,
+			"amount":         refund.Amount,
+			"currency":       tx.Currency,
+			"reason":         refund.Reason,
+			"buyer_id":       tx.BuyerID,
+			"seller_id":      tx.SellerID,
+		})
+		return refund, nil
+	}
+
+	refund.Status = RefundProcessing
+	if err := s.repo.ReserveRefund(ctx, refund); err != nil {
+		return nil, err
+	}
+	return s.executeRefund(ctx, tx, escrow, refund)
+}
+
+// AcceptRefund pays a buyer's refund request. Only the seller can accept it.
+func (s *Service) AcceptRefund(ctx context.Context, transactionID, refundID, sellerID uuid.UUID) (*Refund, error) {
+	tx, refund, err := s.openRefundRequest(ctx, transactionID, refundID, sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	escrow, err := s.repo.GetEscrowByTransactionID(ctx, transactionID)
+	if err != nil {
+		if err == ErrEscrowNotFound {
+			return nil, ErrRefundExceedsPaid
+		}
+		return nil, err
+	}
+
+	// Refunds the seller issued since the request may have used up what's
+	// left; the repository checks that while it holds the escrow
+	now := time.Now().UTC()
+	refund.RespondedAt = &now
+	if err := s.repo.ReserveRefund(ctx, refund); err != nil {
+		return nil, err
+	}
+	return s.executeRefund(ctx, tx, escrow, refund)
+}
+
+// ProcessRefunds retries the refunds left processing for longer than
+// refundRetryDelay, which happens when a refund was paid but recording it
+// in the ledger failed, or the process stopped partway. Paying again is
+// safe: Stripe refunds and ledger entries are keyed by the refund's ID. It
+// returns how many were completed.
+func (s *Service) ProcessRefunds(ctx context.Context, now time.Time) (int, error) {
+	refunds, err := s.repo.GetStaleRefunds(ctx, now.Add(-refundRetryDelay))
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for _, refund := range refunds {
+		tx, err := s.repo.GetTransactionByID(ctx, refund.TransactionID)
+		if err != nil {
+			return completed, err
+		}
+		escrow, err := s.repo.GetEscrowByTransactionID(ctx, refund.TransactionID)
+		if err != nil {
+			return completed, err
+		}
+		paid, err := s.executeRefund(ctx, tx, escrow, refund)
+		if err != nil {
+			logger.Error("refund_retry_failed", map[string]interface{}{
+				"refund_id":      refund.ID.String(),
+				"transaction_id": tx.ID.String(),
+				"error":          err.Error(),
+			})
+			continue
+		}
+		if paid.Status == RefundCompleted {
+			completed++
+		}
+	}
+	return completed, nil
+}
+
+// RejectRefund turns down a buyer's refund request. Only the seller can reject it.
+func (s *Service) RejectRefund(ctx context.Context, transactionID, refundID, sellerID uuid.UUID, req *RejectRefundRequest) (*Refund, error) {
+	tx, refund, err := s.openRefundRequest(ctx, transactionID, refundID, sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	refund.Status = RefundRejected
+	refund.ResponseNote = strings.TrimSpace(req.Note)
+	refund.RespondedAt = &now
+	if err := s.repo.UpdateRefund(ctx, refund); err != nil {
+		return nil, err
+	}
+
+	s.publishEvent(ctx, "refund.rejected", map[string]any{
+		"transaction_id": tx.ID,
+		"refund_id":      refund.ID,
+		"amount":         refund.Amount,
+		"currency":       tx.Currency,
+		"note":           refund.ResponseNote,
+		"buyer_id":       tx.BuyerID,
+		"seller_id":      tx.SellerID,
+	})
+
+	return refund, nil
+}
+
+// openRefundRequest retrieves a refund request on a transaction that is
+// still waiting for the seller, checking that sellerID is the seller.
+func (s *Service) openRefundRequest(ctx context.Context, transactionID, refundID, sellerID uuid.UUID) (*Transaction, *Refund, error) {
+	tx, err := s.repo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sellerID != tx.SellerID {
+		return nil, nil, ErrNotAuthorized
+	}
+
+	refund, err := s.repo.GetRefund(ctx, refundID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if refund.TransactionID != tx.ID {
+		return nil, nil, ErrRefundNotFound
+	}
+	if refund.Status != RefundRequested {
+		return nil, nil, ErrRefundNotRequested
+	}
+	return tx, refund, nil
+}
+
+// refundState retrieves a transaction's escrow and its refunds so far.
+func (s *Service) refundState(ctx context.Context, transactionID uuid.UUID) (*EscrowAccount, []*Refund, error) {
+	escrow, err := s.repo.GetEscrowByTransactionID(ctx, transactionID)
+	if err != nil {
+		if err == ErrEscrowNotFound {
+			return nil, nil, ErrRefundExceedsPaid
+		}
+		return nil, nil, err
+	}
+	refunds, err := s.repo.GetRefunds(ctx, transactionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return escrow, refunds, nil
+}
+
+// executeRefund pays a reserved refund and marks it completed. When no money
+// moved the reservation is released: a buyer's request goes back to waiting
+// for the seller and a seller's refund fails. When the money moved but the
+// ledger could not record it, the refund stays processing for ProcessRefunds
+// to finish.
+func (s *Service) executeRefund(ctx context.Context, tx *Transaction, escrow *EscrowAccount, refund *Refund) (*Refund, error) {
+	moved, err := s.payRefund(ctx, tx, escrow, refund)
+	if err != nil && !moved {
+		refund.Status = RefundFailed
+		if refund.RequestedBy == tx.BuyerID {
+			refund.Status = RefundRequested
+			refund.RespondedAt = nil
+		}
+		if updateErr := s.repo.UpdateRefund(ctx, refund); updateErr != nil {
+			logger.Error("refund_release_failed", map[string]interface{}{
+				"refund_id": refund.ID.String(),
+				"error":     updateErr.Error(),
+			})
+		}
+		return nil, err
+	}
+	if err != nil {
+		logger.Error("ledger_refund_failed", map[string]interface{}{
+			"transaction_id": tx.ID.String(),
+			"refund_id":      refund.ID.String(),
+			"amount":         refund.Amount,
+			"error":          err.Error(),
+		})
+		// Keep the Stripe refund ID so the retry doesn't refund again
+		if updateErr := s.repo.UpdateRefund(ctx, refund); updateErr != nil {
+			logger.Error("refund_update_failed", map[string]interface{}{
+				"refund_id": refund.ID.String(),
+				"error":     updateErr.Error(),
+			})
+		}
+		return refund, nil
+	}
+
+	now := time.Now().UTC()
+	refund.Status = RefundCompleted
+	refund.CompletedAt = &now
+	if err := s.repo.UpdateRefund(ctx, refund); err != nil {
+		// The money moved; the refund stays processing until a retry records it
+		logger.Error("refund_update_failed", map[string]interface{}{
+			"refund_id": refund.ID.String(),
+			"error":     err.Error(),
+		})
+		refund.Status = RefundProcessing
+		refund.CompletedAt = nil
+		return refund, nil
+	}
+	s.refundCompleted(ctx, tx, escrow, refund)
+
+	return refund, nil
+}
+
+// payRefund moves a refund back to the buyer: a partial Stripe refund for a
+// card payment, or a ledger entry from the seller's wallet for escrow funded
+// from the buyer's wallet. It reports whether money left the seller, which
+// is the case when Stripe refunded but the ledger entry failed. A refund with
+// no way to pay it returns ErrRefundNotPayable.
+func (s *Service) payRefund(ctx context.Context, tx *Transaction, escrow *EscrowAccount, refund *Refund) (bool, error) {
+	switch {
+	case escrow.StripePaymentIntentID != nil && *escrow.StripePaymentIntentID != "":
+		if s.payment == nil {
+			return false, ErrRefundNotPayable
+		}
+		if refund.StripeRefundID == nil {
+			stripeRefundID, err := s.payment.RefundPayment(ctx, *escrow.StripePaymentIntentID, refund.ID.String(), refund.Amount, refund.Reason)
+			if err != nil {
+				return false, err
+			}
+			refund.StripeRefundID = &stripeRefundID
+		}
+		if s.ledger != nil {
+			if err := s.ledger.RefundPaidOut(ctx, refund.ID, tx.ID, tx.SellerID, refund.Amount); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	case escrow.fundedFromWallet() && s.ledger != nil:
+		if err := s.ledger.RefundPaidOut(ctx, refund.ID, tx.ID, tx.SellerID, refund.Amount); err != nil {
+			return false, err
+		}
+		return true, nil
+	default:
+		return false, ErrRefundNotPayable
+	}
+}
+
+// refundCompleted announces a paid refund. Once everything paid out has been
+// refunded the transaction is refunded.
+func (s *Service) refundCompleted(ctx context.Context, tx *Transaction, escrow *EscrowAccount, refund *Refund) {
+	s.publishEvent(ctx, "refund.completed", map[string]any{
+		"transaction_id": tx.ID,
+		"refund_id":      refund.ID,
+		"amount":         refund.Amount,
+		"currency":       tx.Currency,
+		"reason":         refund.Reason,
+		"requested_by":   refund.RequestedBy,
+		"buyer_id":       tx.BuyerID,
+		"seller_id":      tx.SellerID,
+	})
+
+	refunds, err := s.repo.GetRefunds(ctx, tx.ID)
+	if err != nil {
+		logger.Error("refund_status_update_failed", map[string]interface{}{
+			"transaction_id": tx.ID.String(),
+			"error":          err.Error(),
+		})
+		return
+	}
+	if toCents(refundedTotal(refunds)) < toCents(escrow.ReleasedAmount) {
+		return
+	}
+	if err := s.repo.UpdateTransactionStatus(ctx, tx.ID, StatusRefunded); err != nil {
+		logger.Error("refund_status_update_failed", map[string]interface{}{
+			"transaction_id": tx.ID.String(),
+			"error":          err.Error(),
+		})
+		return
+	}
+	s.publishEvent(ctx, "transaction.refunded", map[string]any{
+		"transaction_id": tx.ID,
+	})
+}
+
+// refundedTotal adds up the completed refunds.
+func refundedTotal(refunds []*Refund) float64 {
+	var cents int64
+	for _, r := range refunds {
+		if r.Status == RefundCompleted {
+			cents += toCents(r.Amount)
+		}
+	}
+	return float64(cents) / 100
+}
+
+// reservedTotal adds up the completed refunds and those being paid, which
+// together can't exceed what was paid out.
+func reservedTotal(refunds []*Refund) float64 {
+	var cents int64
+	for _, r := range refunds {
+		if r.Status == RefundCompleted || r.Status == RefundProcessing {
+			cents += toCents(r.Amount)
+		}
+	}
+	return float64(cents) / 100
+}
+
+// toCents converts an amount to whole cents, so comparisons are exact.
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
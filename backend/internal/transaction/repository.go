@@ -16,6 +16,9 @@ var (
 	ErrRatingNotFound      = errors.New("rating not found")
 	ErrRatingAlreadyExists = errors.New("rating already submitted for this transaction")
 	ErrEscrowNotFound      = errors.New("escrow account not found")
+	ErrDisputeNotFound     = errors.New("dispute not found")
+	ErrMilestoneNotFound   = errors.New("milestone not found")
+	ErrRefundNotFound      = errors.New("refund not found")
 )
 
 // Repository handles transaction database operations.
@@ -28,7 +31,7 @@ func NewRepository(pool *pgxpool.Pool) *Repository {
 	return &Repository{pool: pool}
 }
 
-// CreateTransaction creates a new transaction.
+// CreateTransaction creates a new transaction, with its milestones if it is paid in stages.
 func (r *Repository) CreateTransaction(ctx context.Context, req *CreateTransactionRequest) (*Transaction, error) {
 	tx := &Transaction{
 		ID:        uuid.New(),
@@ -39,6 +42,8 @@ func (r *Repository) CreateTransaction(ctx context.Context, req *CreateTransacti
 		OfferID:   req.OfferID,
 		AuctionID: req.AuctionID,
 		TaskID:    req.TaskID,
+		TradeID:   req.TradeID,
+		ProductID: req.ProductID,
 		Amount:    req.Amount,
 		Currency:  req.Currency,
 		Status:    StatusPending,
@@ -50,21 +55,56 @@ func (r *Repository) CreateTransaction(ctx context.Context, req *CreateTransacti
 		tx.Currency = "USD"
 	}
 
+	dbTx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback(ctx)
+
 	query := `
 		INSERT INTO transactions (id, buyer_id, seller_id, listing_id, request_id, offer_id, auction_id, task_id,
-			amount, currency, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			trade_id, product_id, amount, currency, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 		RETURNING platform_fee`
 
-	err := r.pool.QueryRow(ctx, query,
+	err = dbTx.QueryRow(ctx, query,
 		tx.ID, tx.BuyerID, tx.SellerID, tx.ListingID, tx.RequestID, tx.OfferID, tx.AuctionID, tx.TaskID,
-		tx.Amount, tx.Currency, tx.Status, tx.CreatedAt, tx.UpdatedAt,
+		tx.TradeID, tx.ProductID, tx.Amount, tx.Currency, tx.Status, tx.CreatedAt, tx.UpdatedAt,
 	).Scan(&tx.PlatformFee)
 
 	if err != nil {
 		return nil, err
 	}
 
+	for i, m := range req.Milestones {
+		milestone := &Milestone{
+			ID:            uuid.New(),
+			TransactionID: tx.ID,
+			Position:      i + 1,
+			Title:         m.Title,
+			Description:   m.Description,
+			Amount:        m.Amount,
+			Status:        MilestonePending,
+			CreatedAt:     tx.CreatedAt,
+			UpdatedAt:     tx.CreatedAt,
+		}
+		_, err := dbTx.Exec(ctx, `
+			INSERT INTO transaction_milestones (id, transaction_id, position, title, description, amount, status,
+				created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			milestone.ID, milestone.TransactionID, milestone.Position, milestone.Title, milestone.Description,
+			milestone.Amount, milestone.Status, milestone.CreatedAt, milestone.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tx.Milestones = append(tx.Milestones, milestone)
+	}
+
+	if err := dbTx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
 	return tx, nil
 }
 
@@ -72,7 +112,8 @@ func (r *Repository) CreateTransaction(ctx context.Context, req *CreateTransacti
 func (r *Repository) GetTransactionByID(ctx context.Context, id uuid.UUID) (*Transaction, error) {
 	query := `
 		SELECT t.id, t.buyer_id, t.seller_id, t.listing_id, t.request_id, t.offer_id, t.auction_id, t.task_id,
-			t.amount, t.currency, t.platform_fee, t.status, t.delivery_confirmed_at, t.completed_at,
+			t.trade_id, t.product_id, t.amount, t.currency, t.platform_fee, t.status,
+			t.inspection_window_hours, t.delivered_at, t.inspection_ends_at, t.delivery_confirmed_at, t.completed_at,
 			t.metadata, t.created_at, t.updated_at,
 			COALESCE(b.name, '') as buyer_name, COALESCE(s.name, '') as seller_name
 		FROM transactions t
@@ -83,7 +124,8 @@ func (r *Repository) GetTransactionByID(ctx context.Context, id uuid.UUID) (*Tra
 	tx := &Transaction{}
 	err := r.pool.QueryRow(ctx, query, id).Scan(
 		&tx.ID, &tx.BuyerID, &tx.SellerID, &tx.ListingID, &tx.RequestID, &tx.OfferID, &tx.AuctionID, &tx.TaskID,
-		&tx.Amount, &tx.Currency, &tx.PlatformFee, &tx.Status, &tx.DeliveryConfirmedAt, &tx.CompletedAt,
+		&tx.TradeID, &tx.ProductID, &tx.Amount, &tx.Currency, &tx.PlatformFee, &tx.Status,
+		&tx.InspectionWindow, &tx.DeliveredAt, &tx.InspectionEndsAt, &tx.DeliveryConfirmedAt, &tx.CompletedAt,
 		&tx.Metadata, &tx.CreatedAt, &tx.UpdatedAt,
 		&tx.BuyerName, &tx.SellerName,
 	)
@@ -142,7 +184,8 @@ func (r *Repository) ListTransactions(ctx context.Context, params ListTransactio
 	// Get items
 	query := fmt.Sprintf(`
 		SELECT t.id, t.buyer_id, t.seller_id, t.listing_id, t.request_id, t.offer_id, t.auction_id,
-			t.amount, t.currency, t.platform_fee, t.status, t.delivery_confirmed_at, t.completed_at,
+			t.trade_id, t.product_id, t.amount, t.currency, t.platform_fee, t.status,
+			t.inspection_window_hours, t.delivered_at, t.inspection_ends_at, t.delivery_confirmed_at, t.completed_at,
 			t.metadata, t.created_at, t.updated_at,
 			COALESCE(b.name, '') as buyer_name, COALESCE(s.name, '') as seller_name
 		FROM transactions t
@@ -165,7 +208,8 @@ func (r *Repository) ListTransactions(ctx context.Context, params ListTransactio
 		tx := &Transaction{}
 		err := rows.Scan(
 			&tx.ID, &tx.BuyerID, &tx.SellerID, &tx.ListingID, &tx.RequestID, &tx.OfferID, &tx.AuctionID,
-			&tx.Amount, &tx.Currency, &tx.PlatformFee, &tx.Status, &tx.DeliveryConfirmedAt, &tx.CompletedAt,
+			&tx.TradeID, &tx.ProductID, &tx.Amount, &tx.Currency, &tx.PlatformFee, &tx.Status,
+			&tx.InspectionWindow, &tx.DeliveredAt, &tx.InspectionEndsAt, &tx.DeliveryConfirmedAt, &tx.CompletedAt,
 			&tx.Metadata, &tx.CreatedAt, &tx.UpdatedAt,
 			&tx.BuyerName, &tx.SellerName,
 		)
@@ -196,13 +240,23 @@ func (r *Repository) UpdateTransactionStatus(ctx context.Context, id uuid.UUID,
 	return nil
 }
 
-// ConfirmDelivery marks a transaction as completed (buyer confirms receipt).
-func (r *Repository) ConfirmDelivery(ctx context.Context, id uuid.UUID) error {
+// CancelPendingTransaction cancels a transaction if it is still pending, reporting whether it did.
+func (r *Repository) CancelPendingTransaction(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `UPDATE transactions SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3`
+	result, err := r.pool.Exec(ctx, query, StatusCancelled, id, StatusPending)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// MarkDelivered marks a transaction as delivered and starts its inspection window.
+func (r *Repository) MarkDelivered(ctx context.Context, id uuid.UUID, inspectionEndsAt time.Time) error {
 	query := `
 		UPDATE transactions
-		SET status = $1, delivery_confirmed_at = NOW(), updated_at = NOW()
-		WHERE id = $2`
-	result, err := r.pool.Exec(ctx, query, StatusCompleted, id)
+		SET status = $1, delivered_at = NOW(), inspection_ends_at = $2, inspection_warned_at = NULL, updated_at = NOW()
+		WHERE id = $3`
+	result, err := r.pool.Exec(ctx, query, StatusDelivered, inspectionEndsAt, id)
 	if err != nil {
 		return err
 	}
@@ -212,6 +266,136 @@ func (r *Repository) ConfirmDelivery(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// SetInspectionWindow sets a transaction's own inspection window in hours.
+func (r *Repository) SetInspectionWindow(ctx context.Context, id uuid.UUID, hours int) error {
+	query := `UPDATE transactions SET inspection_window_hours = $1, updated_at = NOW() WHERE id = $2`
+	result, err := r.pool.Exec(ctx, query, hours, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTransactionNotFound
+	}
+	return nil
+}
+
+// GetCategoryInspectionWindow returns the inspection window in hours of the
+// category of a transaction's listing or request, falling back to the parent
+// category. It returns nil if neither sets one.
+func (r *Repository) GetCategoryInspectionWindow(ctx context.Context, id uuid.UUID) (*int, error) {
+	query := `
+		SELECT COALESCE(c.inspection_window_hours, p.inspection_window_hours)
+		FROM transactions t
+		LEFT JOIN listings l ON t.listing_id = l.id
+		LEFT JOIN requests rq ON t.request_id = rq.id
+		JOIN categories c ON c.id = COALESCE(l.category_id, rq.category_id)
+		LEFT JOIN categories p ON c.parent_id = p.id
+		WHERE t.id = $1`
+
+	var hours *int
+	err := r.pool.QueryRow(ctx, query, id).Scan(&hours)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return hours, nil
+}
+
+// GetInspectionsEndingBefore retrieves the delivered transactions with funded
+// escrow whose inspection window ends before the given time and whose buyer
+// has not been warned.
+func (r *Repository) GetInspectionsEndingBefore(ctx context.Context, before time.Time) ([]uuid.UUID, error) {
+	query := `
+		SELECT t.id FROM transactions t
+		JOIN escrow_accounts e ON e.transaction_id = t.id
+		WHERE t.status = $1 AND t.inspection_ends_at <= $2 AND t.inspection_warned_at IS NULL
+		  AND e.funded_at IS NOT NULL
+		ORDER BY t.inspection_ends_at`
+	return r.queryIDs(ctx, query, StatusDelivered, before)
+}
+
+// MarkInspectionWarned records that a transaction's buyer was warned about the
+// end of the inspection window, reporting whether this call was the one that did.
+func (r *Repository) MarkInspectionWarned(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `UPDATE transactions SET inspection_warned_at = NOW() WHERE id = $1 AND inspection_warned_at IS NULL`
+	result, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// GetExpiredInspections retrieves the delivered transactions with funded escrow
+// whose inspection window has ended. Disputed transactions are not delivered,
+// so a dispute stops the clock; unfunded escrow has nothing to release.
+func (r *Repository) GetExpiredInspections(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	query := `
+		SELECT t.id FROM transactions t
+		JOIN escrow_accounts e ON e.transaction_id = t.id
+		WHERE t.status = $1 AND t.inspection_ends_at <= $2 AND e.funded_at IS NOT NULL
+		ORDER BY t.inspection_ends_at`
+	return r.queryIDs(ctx, query, StatusDelivered, now)
+}
+
+func (r *Repository) queryIDs(ctx context.Context, query string, args ...any) ([]uuid.UUID, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ConfirmDelivery marks a delivered transaction as completed (buyer confirms
+// receipt), reporting whether it did.
+func (r *Repository) ConfirmDelivery(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE transactions
+		SET status = $1, delivery_confirmed_at = NOW(), updated_at = NOW()
+		WHERE id = $2 AND status = $3`
+	result, err := r.pool.Exec(ctx, query, StatusCompleted, id, StatusDelivered)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// CompleteDeliveredTransaction marks a delivered transaction as completed,
+// reporting whether it did.
+func (r *Repository) CompleteDeliveredTransaction(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE transactions
+		SET status = $1, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $2 AND status = $3`
+	result, err := r.pool.Exec(ctx, query, StatusCompleted, id, StatusDelivered)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// ReopenDelivery moves a transaction completed by ConfirmDelivery or
+// CompleteDeliveredTransaction back to delivered.
+func (r *Repository) ReopenDelivery(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE transactions
+		SET status = $1, delivery_confirmed_at = NULL, completed_at = NULL, updated_at = NOW()
+		WHERE id = $2 AND status = $3`
+	_, err := r.pool.Exec(ctx, query, StatusDelivered, id, StatusCompleted)
+	return err
+}
+
 // CompleteTransaction marks a transaction as completed.
 func (r *Repository) CompleteTransaction(ctx context.Context, id uuid.UUID) error {
 	query := `
@@ -260,14 +444,15 @@ func (r *Repository) CreateEscrowAccount(ctx context.Context, transactionID uuid
 // GetEscrowByTransactionID retrieves the escrow account for a transaction.
 func (r *Repository) GetEscrowByTransactionID(ctx context.Context, transactionID uuid.UUID) (*EscrowAccount, error) {
 	query := `
-		SELECT id, transaction_id, amount, currency, status, funded_at, released_at,
-			stripe_payment_intent_id, metadata, created_at, updated_at
+		SELECT id, transaction_id, amount, currency, status, funded_amount, released_amount, remaining_amount,
+			funded_at, released_at, stripe_payment_intent_id, metadata, created_at, updated_at
 		FROM escrow_accounts
 		WHERE transaction_id = $1`
 
 	escrow := &EscrowAccount{}
 	err := r.pool.QueryRow(ctx, query, transactionID).Scan(
 		&escrow.ID, &escrow.TransactionID, &escrow.Amount, &escrow.Currency, &escrow.Status,
+		&escrow.FundedAmount, &escrow.ReleasedAmount, &escrow.RemainingAmount,
 		&escrow.FundedAt, &escrow.ReleasedAt, &escrow.StripePaymentIntentID,
 		&escrow.Metadata, &escrow.CreatedAt, &escrow.UpdatedAt,
 	)
@@ -281,12 +466,12 @@ func (r *Repository) GetEscrowByTransactionID(ctx context.Context, transactionID
 	return escrow, nil
 }
 
-// UpdateEscrowStatus updates an escrow account's status.
+// UpdateEscrowStatus updates an escrow account's status. Funding it holds the full amount.
 func (r *Repository) UpdateEscrowStatus(ctx context.Context, id uuid.UUID, status EscrowStatus) error {
 	var updateField string
 	switch status {
 	case EscrowFunded:
-		updateField = ", funded_at = NOW()"
+		updateField = ", funded_at = NOW(), funded_amount = amount, remaining_amount = amount"
 	case EscrowReleased:
 		updateField = ", released_at = NOW()"
 	}
@@ -302,6 +487,24 @@ func (r *Repository) UpdateEscrowStatus(ctx context.Context, id uuid.UUID, statu
 	return nil
 }
 
+// RecordEscrowSettlement records that part of an escrow was settled: the
+// settled amount is no longer held, and the released part of it went to the seller.
+func (r *Repository) RecordEscrowSettlement(ctx context.Context, id uuid.UUID, released, settled float64) error {
+	query := `
+		UPDATE escrow_accounts
+		SET released_amount = released_amount + $1, remaining_amount = GREATEST(remaining_amount - $2, 0),
+			updated_at = NOW()
+		WHERE id = $3`
+	result, err := r.pool.Exec(ctx, query, released, settled, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrEscrowNotFound
+	}
+	return nil
+}
+
 // UpdateEscrowPaymentIntent updates the Stripe payment intent ID on an escrow.
 func (r *Repository) UpdateEscrowPaymentIntent(ctx context.Context, id uuid.UUID, paymentIntentID string) error {
 	query := `UPDATE escrow_accounts SET stripe_payment_intent_id = $1, updated_at = NOW() WHERE id = $2`
@@ -410,3 +613,435 @@ func (r *Repository) RecalculateAgentRating(ctx context.Context, agentID uuid.UU
 	_, err := r.pool.Exec(ctx, query, agentID)
 	return err
 }
+
+// --- Dispute Operations ---
+
+const disputeColumns = `id, transaction_id, milestone_id, opened_by, respondent_id, reason, description, status,
+	response_due_at, responded_at, outcome, seller_percent, seller_amount, refund_amount,
+	winner_id, loser_id, resolved_by, resolution_note, resolved_at, created_at, updated_at`
+
+// CreateDispute creates a dispute for a transaction or one of its milestones.
+func (r *Repository) CreateDispute(ctx context.Context, dispute *Dispute) error {
+	query := `
+		INSERT INTO disputes (id, transaction_id, milestone_id, opened_by, respondent_id, reason, description,
+			status, response_due_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err := r.pool.Exec(ctx, query,
+		dispute.ID, dispute.TransactionID, dispute.MilestoneID, dispute.OpenedBy, dispute.RespondentID,
+		dispute.Reason, dispute.Description, dispute.Status, dispute.ResponseDueAt, dispute.CreatedAt,
+		dispute.UpdatedAt,
+	)
+	return err
+}
+
+// GetDisputeByTransactionID retrieves the dispute on a transaction as a whole.
+func (r *Repository) GetDisputeByTransactionID(ctx context.Context, transactionID uuid.UUID) (*Dispute, error) {
+	query := `SELECT ` + disputeColumns + ` FROM disputes WHERE transaction_id = $1 AND milestone_id IS NULL`
+	return r.getDispute(ctx, query, transactionID)
+}
+
+// GetDisputeByMilestoneID retrieves the dispute on a milestone.
+func (r *Repository) GetDisputeByMilestoneID(ctx context.Context, milestoneID uuid.UUID) (*Dispute, error) {
+	query := `SELECT ` + disputeColumns + ` FROM disputes WHERE milestone_id = $1`
+	return r.getDispute(ctx, query, milestoneID)
+}
+
+func (r *Repository) getDispute(ctx context.Context, query string, id uuid.UUID) (*Dispute, error) {
+	dispute, err := scanDispute(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDisputeNotFound
+		}
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// UpdateDispute saves a dispute's status, response and resolution.
+func (r *Repository) UpdateDispute(ctx context.Context, dispute *Dispute) error {
+	query := `
+		UPDATE disputes
+		SET status = $1, responded_at = $2, outcome = $3, seller_percent = $4, seller_amount = $5,
+			refund_amount = $6, winner_id = $7, loser_id = $8, resolved_by = $9, resolution_note = $10,
+			resolved_at = $11, updated_at = NOW()
+		WHERE id = $12`
+	result, err := r.pool.Exec(ctx, query,
+		dispute.Status, dispute.RespondedAt, dispute.Outcome, dispute.SellerPercent, dispute.SellerAmount,
+		dispute.RefundAmount, dispute.WinnerID, dispute.LoserID, dispute.ResolvedBy, dispute.ResolutionNote,
+		dispute.ResolvedAt, dispute.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrDisputeNotFound
+	}
+	return nil
+}
+
+// ClaimDispute moves a dispute that is neither resolved nor being resolved to
+// resolving, reporting whether it did.
+func (r *Repository) ClaimDispute(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `UPDATE disputes SET status = $1, updated_at = NOW() WHERE id = $2 AND status NOT IN ($1, $3)`
+	result, err := r.pool.Exec(ctx, query, DisputeResolving, id, DisputeResolved)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// ReopenDispute moves a dispute claimed by ClaimDispute back to the given status.
+func (r *Repository) ReopenDispute(ctx context.Context, id uuid.UUID, status DisputeStatus) error {
+	query := `UPDATE disputes SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3`
+	_, err := r.pool.Exec(ctx, query, status, id, DisputeResolving)
+	return err
+}
+
+// GetOverdueDisputes retrieves open disputes whose respondent missed the response deadline.
+func (r *Repository) GetOverdueDisputes(ctx context.Context, now time.Time) ([]*Dispute, error) {
+	query := `SELECT ` + disputeColumns + ` FROM disputes
+		WHERE status = $1 AND response_due_at <= $2
+		ORDER BY response_due_at`
+
+	rows, err := r.pool.Query(ctx, query, DisputeOpen, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var disputes []*Dispute
+	for rows.Next() {
+		dispute, err := scanDispute(rows)
+		if err != nil {
+			return nil, err
+		}
+		disputes = append(disputes, dispute)
+	}
+	return disputes, rows.Err()
+}
+
+// CreateDisputeEvidence adds an entry to a dispute's evidence thread.
+func (r *Repository) CreateDisputeEvidence(ctx context.Context, evidence *DisputeEvidence) error {
+	attachments := evidence.Attachments
+	if attachments == nil {
+		attachments = []string{}
+	}
+
+	query := `
+		INSERT INTO dispute_evidence (id, dispute_id, author_id, role, body, attachments, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.pool.Exec(ctx, query,
+		evidence.ID, evidence.DisputeID, evidence.AuthorID, evidence.Role, evidence.Body,
+		attachments, evidence.CreatedAt,
+	)
+	return err
+}
+
+// GetDisputeEvidence retrieves a dispute's evidence thread, oldest first.
+func (r *Repository) GetDisputeEvidence(ctx context.Context, disputeID uuid.UUID) ([]*DisputeEvidence, error) {
+	query := `
+		SELECT id, dispute_id, author_id, role, body, attachments, created_at
+		FROM dispute_evidence
+		WHERE dispute_id = $1
+		ORDER BY created_at`
+
+	rows, err := r.pool.Query(ctx, query, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var evidence []*DisputeEvidence
+	for rows.Next() {
+		e := &DisputeEvidence{}
+		if err := rows.Scan(
+			&e.ID, &e.DisputeID, &e.AuthorID, &e.Role, &e.Body, &e.Attachments, &e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		evidence = append(evidence, e)
+	}
+	return evidence, rows.Err()
+}
+
+func scanDispute(row pgx.Row) (*Dispute, error) {
+	d := &Dispute{}
+	err := row.Scan(
+		&d.ID, &d.TransactionID, &d.MilestoneID, &d.OpenedBy, &d.RespondentID, &d.Reason, &d.Description, &d.Status,
+		&d.ResponseDueAt, &d.RespondedAt, &d.Outcome, &d.SellerPercent, &d.SellerAmount, &d.RefundAmount,
+		&d.WinnerID, &d.LoserID, &d.ResolvedBy, &d.ResolutionNote, &d.ResolvedAt, &d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// --- Milestone Operations ---
+
+const milestoneColumns = `id, transaction_id, position, title, description, amount, status, delivery_proof,
+	delivered_at, inspection_ends_at, released_amount, settled_at, created_at, updated_at`
+
+// GetMilestones retrieves a transaction's milestones in order. A transaction
+// that is not paid in stages has none.
+func (r *Repository) GetMilestones(ctx context.Context, transactionID uuid.UUID) ([]*Milestone, error) {
+	query := `SELECT ` + milestoneColumns + ` FROM transaction_milestones
+		WHERE transaction_id = $1
+		ORDER BY position`
+	return r.queryMilestones(ctx, query, transactionID)
+}
+
+// UpdateMilestone saves a milestone's status, delivery and settlement.
+func (r *Repository) UpdateMilestone(ctx context.Context, milestone *Milestone) error {
+	query := `
+		UPDATE transaction_milestones
+		SET status = $1, delivery_proof = $2, delivered_at = $3, inspection_ends_at = $4, released_amount = $5,
+			settled_at = $6, updated_at = NOW()
+		WHERE id = $7`
+	result, err := r.pool.Exec(ctx, query,
+		milestone.Status, milestone.DeliveryProof, milestone.DeliveredAt, milestone.InspectionEndsAt,
+		milestone.ReleasedAmount, milestone.SettledAt, milestone.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMilestoneNotFound
+	}
+	return nil
+}
+
+// MoveMilestone moves a milestone from one status to another, reporting whether it did.
+func (r *Repository) MoveMilestone(ctx context.Context, id uuid.UUID, from, to MilestoneStatus) (bool, error) {
+	query := `UPDATE transaction_milestones SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3`
+	result, err := r.pool.Exec(ctx, query, to, id, from)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// GetExpiredMilestoneInspections retrieves the delivered milestones whose inspection window has ended.
+func (r *Repository) GetExpiredMilestoneInspections(ctx context.Context, now time.Time) ([]*Milestone, error) {
+	query := `SELECT ` + milestoneColumns + ` FROM transaction_milestones
+		WHERE status = $1 AND inspection_ends_at <= $2
+		ORDER BY inspection_ends_at`
+	return r.queryMilestones(ctx, query, MilestoneDelivered, now)
+}
+
+// GetOfferMilestones retrieves the milestones proposed in an offer, if any.
+func (r *Repository) GetOfferMilestones(ctx context.Context, offerID uuid.UUID) ([]MilestoneRequest, error) {
+	query := `SELECT milestones FROM offers WHERE id = $1`
+
+	var milestones []MilestoneRequest
+	err := r.pool.QueryRow(ctx, query, offerID).Scan(&milestones)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return milestones, nil
+}
+
+func (r *Repository) queryMilestones(ctx context.Context, query string, args ...any) ([]*Milestone, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var milestones []*Milestone
+	for rows.Next() {
+		m := &Milestone{}
+		if err := rows.Scan(
+			&m.ID, &m.TransactionID, &m.Position, &m.Title, &m.Description, &m.Amount, &m.Status, &m.DeliveryProof,
+			&m.DeliveredAt, &m.InspectionEndsAt, &m.ReleasedAmount, &m.SettledAt, &m.CreatedAt, &m.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		milestones = append(milestones, m)
+	}
+	return milestones, rows.Err()
+}
+
+// --- Refund Operations ---
+
+const refundColumns = `id, transaction_id, requested_by, amount, reason, status, stripe_refund_id, response_note,
+	responded_at, completed_at, created_at, updated_at`
+
+// CreateRefund creates a refund or refund request.
+func (r *Repository) CreateRefund(ctx context.Context, refund *Refund) error {
+	query := `
+		INSERT INTO transaction_refunds (id, transaction_id, requested_by, amount, reason, status, stripe_refund_id,
+			response_note, responded_at, completed_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+	_, err := r.pool.Exec(ctx, query,
+		refund.ID, refund.TransactionID, refund.RequestedBy, refund.Amount, refund.Reason, refund.Status,
+		refund.StripeRefundID, refund.ResponseNote, refund.RespondedAt, refund.CompletedAt, refund.CreatedAt,
+		refund.UpdatedAt,
+	)
+	return err
+}
+
+// GetRefund retrieves a refund by ID.
+func (r *Repository) GetRefund(ctx context.Context, id uuid.UUID) (*Refund, error) {
+	query := `SELECT ` + refundColumns + ` FROM transaction_refunds WHERE id = $1`
+	refund, err := scanRefund(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+	return refund, nil
+}
+
+// GetRefunds retrieves a transaction's refunds and refund requests, oldest first.
+func (r *Repository) GetRefunds(ctx context.Context, transactionID uuid.UUID) ([]*Refund, error) {
+	query := `SELECT ` + refundColumns + ` FROM transaction_refunds
+		WHERE transaction_id = $1
+		ORDER BY created_at`
+
+	rows, err := r.pool.Query(ctx, query, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []*Refund
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+	return refunds, rows.Err()
+}
+
+// UpdateRefund saves a refund's status and the seller's response.
+func (r *Repository) UpdateRefund(ctx context.Context, refund *Refund) error {
+	query := `
+		UPDATE transaction_refunds
+		SET status = $1, stripe_refund_id = $2, response_note = $3, responded_at = $4, completed_at = $5,
+			updated_at = NOW()
+		WHERE id = $6`
+	result, err := r.pool.Exec(ctx, query,
+		refund.Status, refund.StripeRefundID, refund.ResponseNote, refund.RespondedAt, refund.CompletedAt, refund.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRefundNotFound
+	}
+	return nil
+}
+
+// ReserveRefund sets a refund aside for payment while holding the escrow's
+// row lock, so concurrent refunds can't add up to more than was paid out. A
+// refund request moves from requested to processing; any other refund is
+// inserted as processing. It returns ErrRefundExceedsPaid when too little is
+// left to refund.
+func (r *Repository) ReserveRefund(ctx context.Context, refund *Refund) error {
+	dbTx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback(ctx)
+
+	var released float64
+	err = dbTx.QueryRow(ctx, `
+		SELECT released_amount FROM escrow_accounts WHERE transaction_id = $1 FOR UPDATE
+	`, refund.TransactionID).Scan(&released)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrRefundExceedsPaid
+		}
+		return err
+	}
+
+	var reserved float64
+	err = dbTx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM transaction_refunds
+		WHERE transaction_id = $1 AND id <> $2 AND status IN ($3, $4)
+	`, refund.TransactionID, refund.ID, RefundProcessing, RefundCompleted).Scan(&reserved)
+	if err != nil {
+		return err
+	}
+	if toCents(refund.Amount) > toCents(released-reserved) {
+		return ErrRefundExceedsPaid
+	}
+
+	if refund.Status == RefundRequested {
+		result, err := dbTx.Exec(ctx, `
+			UPDATE transaction_refunds
+			SET status = $1, responded_at = $2, updated_at = NOW()
+			WHERE id = $3 AND status = $4
+		`, RefundProcessing, refund.RespondedAt, refund.ID, RefundRequested)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrRefundNotRequested
+		}
+	} else {
+		_, err = dbTx.Exec(ctx, `
+			INSERT INTO transaction_refunds (id, transaction_id, requested_by, amount, reason, status, stripe_refund_id,
+				response_note, responded_at, completed_at, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`, refund.ID, refund.TransactionID, refund.RequestedBy, refund.Amount, refund.Reason, RefundProcessing,
+			refund.StripeRefundID, refund.ResponseNote, refund.RespondedAt, refund.CompletedAt, refund.CreatedAt,
+			refund.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := dbTx.Commit(ctx); err != nil {
+		return err
+	}
+	refund.Status = RefundProcessing
+	return nil
+}
+
+// GetStaleRefunds retrieves refunds that have been processing since before the given time.
+func (r *Repository) GetStaleRefunds(ctx context.Context, before time.Time) ([]*Refund, error) {
+	query := `SELECT ` + refundColumns + ` FROM transaction_refunds
+		WHERE status = $1 AND updated_at < $2
+		ORDER BY created_at`
+
+	rows, err := r.pool.Query(ctx, query, RefundProcessing, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []*Refund
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+	return refunds, rows.Err()
+}
+
+func scanRefund(row pgx.Row) (*Refund, error) {
+	refund := &Refund{}
+	err := row.Scan(
+		&refund.ID, &refund.TransactionID, &refund.RequestedBy, &refund.Amount, &refund.Reason, &refund.Status,
+		&refund.StripeRefundID, &refund.ResponseNote, &refund.RespondedAt, &refund.CompletedAt, &refund.CreatedAt,
+		&refund.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
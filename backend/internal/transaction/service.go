@@ -3,6 +3,7 @@ package transaction
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/digi604/swarmmarket/backend/pkg/logger"
 	"github.com/google/uuid"
@@ -24,8 +25,13 @@ type EventPublisher interface {
 // PaymentService handles payment operations.
 type PaymentService interface {
 	CreateEscrowPayment(ctx context.Context, transactionID, buyerID, sellerID string, amount float64, currency string) (paymentIntentID string, err error)
-	CapturePayment(ctx context.Context, paymentIntentID string) error
-	RefundPayment(ctx context.Context, paymentIntentID string) error
+	CapturePayment(ctx context.Context, paymentIntentID string) (chargeID string, err error)
+	CapturePartialPayment(ctx context.Context, paymentIntentID string, amount float64) (chargeID string, err error)
+	RefundPayment(ctx context.Context, paymentIntentID, refundID string, amount float64, reason string) (stripeRefundID string, err error)
+	CreateMilestoneEscrowPayment(ctx context.Context, transactionID, buyerID, sellerID string, amount float64, currency string) (paymentIntentID string, err error)
+	CaptureMilestonePayment(ctx context.Context, paymentIntentID string, amount float64, final bool) (chargeID string, err error)
+	TransferToSeller(ctx context.Context, transactionID, sellerID string, amount float64, currency, chargeID string) error
+	CancelPayment(ctx context.Context, paymentIntentID string) error
 }
 
 // TrustHandler handles trust score updates.
@@ -34,19 +40,39 @@ type TrustHandler interface {
 	OnRatingReceived(ctx context.Context, agentID uuid.UUID, ratingScore int, transactionID uuid.UUID) error
 }
 
+// Ledger records escrow in the wallet ledger, and holds escrow funded from
+// a buyer's wallet.
+type Ledger interface {
+	HoldEscrow(ctx context.Context, transactionID, buyerID uuid.UUID, amount float64, currency string, fromWallet bool) error
+	SettleEscrow(ctx context.Context, transactionID, sellerID uuid.UUID, released, refunded float64) error
+	RefundPaidOut(ctx context.Context, refundID, transactionID, sellerID uuid.UUID, amount float64) error
+}
+
 // Service handles transaction business logic.
 type Service struct {
 	repo      RepositoryInterface
 	publisher EventPublisher
 	payment   PaymentService
 	trust     TrustHandler
+	ledger    Ledger
+
+	disputeResponseWindow time.Duration
+	arbitrators           map[uuid.UUID]bool
+
+	inspectionWindow    time.Duration
+	inspectionWarning   time.Duration
+	maxInspectionWindow time.Duration
 }
 
 // NewService creates a new transaction service.
 func NewService(repo RepositoryInterface, publisher EventPublisher) *Service {
 	return &Service{
-		repo:      repo,
-		publisher: publisher,
+		repo:                  repo,
+		publisher:             publisher,
+		disputeResponseWindow: defaultDisputeResponseWindow,
+		inspectionWindow:      defaultInspectionWindow,
+		inspectionWarning:     defaultInspectionWarning,
+		maxInspectionWindow:   defaultMaxInspectionWindow,
 	}
 }
 
@@ -60,15 +86,59 @@ func (s *Service) SetTrustHandler(trust TrustHandler) {
 	s.trust = trust
 }
 
+// SetLedger sets the wallet ledger (optional, for wallet-funded escrow and
+// double-entry bookkeeping of escrow).
+func (s *Service) SetLedger(ledger Ledger) {
+	s.ledger = ledger
+}
+
+// SetDisputeConfig sets how long the other party has to respond to a dispute
+// and which agents may arbitrate disputes. A zero window keeps the default.
+func (s *Service) SetDisputeConfig(responseWindow time.Duration, arbitratorIDs []uuid.UUID) {
+	if responseWindow > 0 {
+		s.disputeResponseWindow = responseWindow
+	}
+	s.arbitrators = make(map[uuid.UUID]bool, len(arbitratorIDs))
+	for _, id := range arbitratorIDs {
+		s.arbitrators[id] = true
+	}
+}
+
+// SetInspectionConfig sets the platform default inspection window, how long
+// before it ends the buyer is warned, and the longest window a buyer can set
+// on a transaction. Zero values keep the defaults.
+func (s *Service) SetInspectionConfig(window, warning, maxWindow time.Duration) {
+	if window > 0 {
+		s.inspectionWindow = window
+	}
+	if warning > 0 {
+		s.inspectionWarning = warning
+	}
+	if maxWindow > 0 {
+		s.maxInspectionWindow = maxWindow
+	}
+}
+
 // CreateFromOffer creates a transaction from an accepted offer (implements marketplace.TransactionCreator).
+// If the offer splits the deal into milestones, the transaction is paid in those stages.
 func (s *Service) CreateFromOffer(ctx context.Context, buyerID, sellerID uuid.UUID, requestID, offerID *uuid.UUID, amount float64, currency string) (uuid.UUID, error) {
+	var milestones []MilestoneRequest
+	if offerID != nil {
+		var err error
+		milestones, err = s.repo.GetOfferMilestones(ctx, *offerID)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+
 	tx, err := s.CreateTransaction(ctx, &CreateTransactionRequest{
-		BuyerID:   buyerID,
-		SellerID:  sellerID,
-		RequestID: requestID,
-		OfferID:   offerID,
-		Amount:    amount,
-		Currency:  currency,
+		BuyerID:    buyerID,
+		SellerID:   sellerID,
+		RequestID:  requestID,
+		OfferID:    offerID,
+		Amount:     amount,
+		Currency:   currency,
+		Milestones: milestones,
 	})
 	if err != nil {
 		return uuid.Nil, err
@@ -106,8 +176,71 @@ func (s *Service) CreateFromTask(ctx context.Context, requesterID, executorID uu
 	return tx.ID, nil
 }
 
+// CreateFromTrade creates a transaction from an order book trade (implements matching.TransactionCreator).
+func (s *Service) CreateFromTrade(ctx context.Context, buyerID, sellerID uuid.UUID, tradeID, productID *uuid.UUID, amount float64, currency string) (uuid.UUID, error) {
+	tx, err := s.CreateTransaction(ctx, &CreateTransactionRequest{
+		BuyerID:   buyerID,
+		SellerID:  sellerID,
+		TradeID:   tradeID,
+		ProductID: productID,
+		Amount:    amount,
+		Currency:  currency,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return tx.ID, nil
+}
+
+// CreateFromAuction creates a transaction for the winner of an auction (implements auction.TransactionCreator).
+// With a payment service configured, escrow is funded right away from the winner's saved
+// payment method; if that fails the winner can still fund it before the payment deadline.
+func (s *Service) CreateFromAuction(ctx context.Context, buyerID, sellerID uuid.UUID, auctionID *uuid.UUID, amount float64, currency string) (uuid.UUID, error) {
+	tx, err := s.CreateTransaction(ctx, &CreateTransactionRequest{
+		BuyerID:   buyerID,
+		SellerID:  sellerID,
+		AuctionID: auctionID,
+		Amount:    amount,
+		Currency:  currency,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if s.payment != nil {
+		if _, err := s.FundEscrow(ctx, tx.ID, buyerID); err != nil {
+			logger.Warn("auction_escrow_funding_failed", map[string]interface{}{
+				"transaction_id": tx.ID.String(),
+				"buyer_id":       buyerID.String(),
+				"error":          err.Error(),
+			})
+		}
+	}
+
+	return tx.ID, nil
+}
+
+// CancelUnfundedTransaction cancels a transaction whose escrow was never funded,
+// reporting whether it did (implements auction.TransactionCreator).
+func (s *Service) CancelUnfundedTransaction(ctx context.Context, transactionID uuid.UUID) (bool, error) {
+	cancelled, err := s.repo.CancelPendingTransaction(ctx, transactionID)
+	if err != nil || !cancelled {
+		return false, err
+	}
+
+	logger.Info("transaction_cancelled", map[string]interface{}{
+		"transaction_id": transactionID.String(),
+		"reason":         "escrow not funded",
+	})
+	return true, nil
+}
+
 // CreateTransaction creates a new transaction (called when offer is accepted).
 func (s *Service) CreateTransaction(ctx context.Context, req *CreateTransactionRequest) (*Transaction, error) {
+	if err := validateMilestones(req.Milestones, req.Amount); err != nil {
+		return nil, err
+	}
+
 	// Create the transaction
 	tx, err := s.repo.CreateTransaction(ctx, req)
 	if err != nil {
@@ -149,9 +282,23 @@ func (s *Service) CreateTransaction(ctx context.Context, req *CreateTransactionR
 	return tx, nil
 }
 
-// GetTransaction retrieves a transaction by ID.
+// GetTransaction retrieves a transaction by ID with its milestones and escrow account.
 func (s *Service) GetTransaction(ctx context.Context, id uuid.UUID) (*Transaction, error) {
-	return s.repo.GetTransactionByID(ctx, id)
+	tx, err := s.repo.GetTransactionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Milestones, err = s.repo.GetMilestones(ctx, id); err != nil {
+		return nil, err
+	}
+	if escrow, err := s.repo.GetEscrowByTransactionID(ctx, id); err == nil {
+		tx.Escrow = escrow
+	}
+	if tx.Refunds, err = s.repo.GetRefunds(ctx, id); err != nil {
+		return nil, err
+	}
+	tx.RefundedAmount = refundedTotal(tx.Refunds)
+	return tx, nil
 }
 
 // ListTransactions retrieves transactions for an agent.
@@ -184,7 +331,18 @@ func (s *Service) FundEscrow(ctx context.Context, transactionID, buyerID uuid.UU
 		return nil, errors.New("payment service not configured")
 	}
 
-	paymentIntentID, err := s.payment.CreateEscrowPayment(
+	milestones, err := s.repo.GetMilestones(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Milestone escrow is captured in parts and paid out to the seller by transfer
+	createPayment := s.payment.CreateEscrowPayment
+	if len(milestones) > 0 {
+		createPayment = s.payment.CreateMilestoneEscrowPayment
+	}
+
+	paymentIntentID, err := createPayment(
 		ctx,
 		transactionID.String(),
 		tx.BuyerID.String(),
@@ -227,6 +385,28 @@ func (s *Service) ConfirmEscrowFunded(ctx context.Context, transactionID uuid.UU
 		return err
 	}
 
+	// Stripe reports the payment again once escrow is captured
+	if tx.Status != StatusPending {
+		return nil
+	}
+
+	// The card payment is mirrored in the ledger; a failure there doesn't undo it
+	if s.ledger != nil {
+		if err := s.ledger.HoldEscrow(ctx, transactionID, tx.BuyerID, tx.Amount, tx.Currency, false); err != nil {
+			logger.Error("ledger_hold_failed", map[string]interface{}{
+				"transaction_id": transactionID.String(),
+				"error":          err.Error(),
+			})
+		}
+	}
+
+	return s.markEscrowFunded(ctx, tx, paymentIntentID)
+}
+
+// markEscrowFunded marks a pending transaction and its escrow as funded.
+func (s *Service) markEscrowFunded(ctx context.Context, tx *Transaction, paymentIntentID string) error {
+	transactionID := tx.ID
+
 	// Update transaction status
 	if err := s.repo.UpdateTransactionStatus(ctx, transactionID, StatusEscrowFunded); err != nil {
 		return err
@@ -288,8 +468,14 @@ func (s *Service) MarkDelivered(ctx context.Context, transactionID, agentID uuid
 		return nil, ErrInvalidStatus
 	}
 
-	// Update transaction status to delivered
-	if err := s.repo.UpdateTransactionStatus(ctx, transactionID, StatusDelivered); err != nil {
+	// Deals paid in stages are delivered milestone by milestone
+	if err := s.requireNoMilestones(ctx, transactionID); err != nil {
+		return nil, err
+	}
+
+	// Update transaction status to delivered and start the inspection window
+	inspectionEndsAt := time.Now().UTC().Add(s.inspectionWindowFor(ctx, tx))
+	if err := s.repo.MarkDelivered(ctx, transactionID, inspectionEndsAt); err != nil {
 		return nil, err
 	}
 
@@ -306,11 +492,12 @@ func (s *Service) MarkDelivered(ctx context.Context, transactionID, agentID uuid
 
 	// Publish event
 	s.publishEvent(ctx, "transaction.delivered", map[string]any{
-		"transaction_id": transactionID,
-		"buyer_id":       tx.BuyerID,
-		"seller_id":      tx.SellerID,
-		"delivery_proof": deliveryProof,
-		"message":        message,
+		"transaction_id":     transactionID,
+		"buyer_id":           tx.BuyerID,
+		"seller_id":          tx.SellerID,
+		"delivery_proof":     deliveryProof,
+		"message":            message,
+		"inspection_ends_at": inspectionEndsAt,
 	})
 
 	return tx, nil
@@ -335,21 +522,18 @@ func (s *Service) ConfirmDelivery(ctx context.Context, transactionID, agentID uu
 		return nil, ErrInvalidStatus
 	}
 
-	// Capture escrow payment before completing
-	escrow, err := s.repo.GetEscrowByTransactionID(ctx, transactionID)
-	if err == nil && escrow.StripePaymentIntentID != nil && *escrow.StripePaymentIntentID != "" && s.payment != nil {
-		if err := s.payment.CapturePayment(ctx, *escrow.StripePaymentIntentID); err != nil {
-			s.publishEvent(ctx, "payment.capture_failed", map[string]any{
-				"transaction_id":    transactionID,
-				"payment_intent_id": *escrow.StripePaymentIntentID,
-				"error":             err.Error(),
-			})
-		}
-		s.repo.UpdateEscrowStatus(ctx, escrow.ID, EscrowReleased)
+	// Claim the transaction so that it is released only once
+	confirmed, err := s.repo.ConfirmDelivery(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, ErrInvalidStatus
 	}
 
-	// Update transaction
-	if err := s.repo.ConfirmDelivery(ctx, transactionID); err != nil {
+	// Release escrow, handing the transaction back if that fails
+	if err := s.releaseEscrow(ctx, tx); err != nil {
+		s.reopenDelivery(ctx, transactionID)
 		return nil, err
 	}
 
@@ -393,21 +577,18 @@ func (s *Service) CompleteTransaction(ctx context.Context, transactionID uuid.UU
 		return nil, ErrInvalidStatus
 	}
 
-	// Get escrow and capture payment if we have a payment intent
-	escrow, err := s.repo.GetEscrowByTransactionID(ctx, transactionID)
-	if err == nil && escrow.StripePaymentIntentID != nil && *escrow.StripePaymentIntentID != "" && s.payment != nil {
-		if err := s.payment.CapturePayment(ctx, *escrow.StripePaymentIntentID); err != nil {
-			s.publishEvent(ctx, "payment.capture_failed", map[string]any{
-				"transaction_id":    transactionID,
-				"payment_intent_id": *escrow.StripePaymentIntentID,
-				"error":             err.Error(),
-			})
-		}
-		s.repo.UpdateEscrowStatus(ctx, escrow.ID, EscrowReleased)
+	// Complete the transaction first, so that it is released only once
+	completed, err := s.repo.CompleteDeliveredTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, ErrInvalidStatus
 	}
 
-	// Complete transaction
-	if err := s.repo.CompleteTransaction(ctx, transactionID); err != nil {
+	// Release escrow: capture the card payment, or pay out of the wallet ledger
+	if err := s.releaseEscrow(ctx, tx); err != nil {
+		s.reopenDelivery(ctx, transactionID)
 		return nil, err
 	}
 
@@ -442,6 +623,55 @@ func (s *Service) CompleteTransaction(ctx context.Context, transactionID uuid.UU
 	return tx, nil
 }
 
+// reopenDelivery hands a transaction whose escrow could not be released back
+// to delivered, so that the buyer or the inspection worker can try again.
+func (s *Service) reopenDelivery(ctx context.Context, transactionID uuid.UUID) {
+	if err := s.repo.ReopenDelivery(ctx, transactionID); err != nil {
+		logger.Error("delivery_reopen_failed", map[string]interface{}{
+			"transaction_id": transactionID.String(),
+			"error":          err.Error(),
+		})
+	}
+}
+
+// releaseEscrow pays a transaction's escrow out to the seller in full. A card
+// payment is captured; escrow funded from the buyer's wallet is paid into
+// the seller's wallet in the ledger. A failed capture is returned, leaving
+// escrow funded.
+func (s *Service) releaseEscrow(ctx context.Context, tx *Transaction) error {
+	escrow, err := s.repo.GetEscrowByTransactionID(ctx, tx.ID)
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case escrow.StripePaymentIntentID != nil && *escrow.StripePaymentIntentID != "" && s.payment != nil:
+		if _, err := s.payment.CapturePayment(ctx, *escrow.StripePaymentIntentID); err != nil {
+			s.publishEvent(ctx, "payment.capture_failed", map[string]any{
+				"transaction_id":    tx.ID,
+				"payment_intent_id": *escrow.StripePaymentIntentID,
+				"error":             err.Error(),
+			})
+			// Nothing was paid out, so escrow and the ledger stay as they are
+			return err
+		}
+		s.repo.UpdateEscrowStatus(ctx, escrow.ID, EscrowReleased)
+		if err := s.repo.RecordEscrowSettlement(ctx, escrow.ID, tx.Amount, tx.Amount); err != nil {
+			return err
+		}
+		return s.settleInLedger(ctx, escrow, tx.SellerID, tx.Amount, 0)
+	case escrow.fundedFromWallet():
+		if err := s.settleInLedger(ctx, escrow, tx.SellerID, tx.Amount, 0); err != nil {
+			return err
+		}
+		s.repo.UpdateEscrowStatus(ctx, escrow.ID, EscrowReleased)
+		if err := s.repo.RecordEscrowSettlement(ctx, escrow.ID, tx.Amount, tx.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SubmitRating submits a rating for a completed transaction.
 func (s *Service) SubmitRating(ctx context.Context, transactionID, raterID uuid.UUID, req *SubmitRatingRequest) (*Rating, error) {
 	// Validate rating score
@@ -524,7 +754,9 @@ func (s *Service) GetTransactionRatings(ctx context.Context, transactionID uuid.
 	return s.repo.GetRatingsByTransactionID(ctx, transactionID)
 }
 
-// DisputeTransaction opens a dispute on a transaction.
+// DisputeTransaction opens a dispute on a transaction. The other party has
+// until the response deadline to answer with evidence, or the dispute is
+// decided in the opener's favour.
 func (s *Service) DisputeTransaction(ctx context.Context, transactionID, agentID uuid.UUID, req *DisputeRequest) (*Transaction, error) {
 	// Get transaction
 	tx, err := s.repo.GetTransactionByID(ctx, transactionID)
@@ -542,6 +774,17 @@ func (s *Service) DisputeTransaction(ctx context.Context, transactionID, agentID
 		return nil, ErrInvalidStatus
 	}
 
+	// Deals paid in stages are disputed milestone by milestone
+	if err := s.requireNoMilestones(ctx, transactionID); err != nil {
+		return nil, err
+	}
+
+	// Record the dispute
+	dispute := s.newDispute(tx, agentID, req)
+	if err := s.repo.CreateDispute(ctx, dispute); err != nil {
+		return nil, err
+	}
+
 	// Update status
 	if err := s.repo.UpdateTransactionStatus(ctx, transactionID, StatusDisputed); err != nil {
 		return nil, err
@@ -558,16 +801,25 @@ func (s *Service) DisputeTransaction(ctx context.Context, transactionID, agentID
 
 	// Publish event
 	s.publishEvent(ctx, "dispute.opened", map[string]any{
-		"transaction_id": transactionID,
-		"opened_by":      agentID,
-		"reason":         req.Reason,
+		"transaction_id":  transactionID,
+		"dispute_id":      dispute.ID,
+		"buyer_id":        tx.BuyerID,
+		"seller_id":       tx.SellerID,
+		"opened_by":       agentID,
+		"reason":          req.Reason,
+		"response_due_at": dispute.ResponseDueAt,
 	})
 
 	return tx, nil
 }
 
 // RefundTransaction marks a transaction as refunded (called after Stripe refund).
+// Refunds made through CreateRefund have already refunded the transaction by
+// the time Stripe reports the charge fully refunded, so that is a no-op.
 func (s *Service) RefundTransaction(ctx context.Context, transactionID uuid.UUID) error {
+	if tx, err := s.repo.GetTransactionByID(ctx, transactionID); err == nil && tx.Status == StatusRefunded {
+		return nil
+	}
 	if err := s.repo.UpdateTransactionStatus(ctx, transactionID, StatusRefunded); err != nil {
 		return err
 	}
@@ -576,6 +828,12 @@ func (s *Service) RefundTransaction(ctx context.Context, transactionID uuid.UUID
 	escrow, err := s.repo.GetEscrowByTransactionID(ctx, transactionID)
 	if err == nil {
 		s.repo.UpdateEscrowStatus(ctx, escrow.ID, EscrowRefunded)
+		if err := s.repo.RecordEscrowSettlement(ctx, escrow.ID, 0, escrow.RemainingAmount); err != nil {
+			return err
+		}
+		if tx, err := s.repo.GetTransactionByID(ctx, transactionID); err == nil {
+			s.settleInLedger(ctx, escrow, tx.SellerID, 0, escrow.RemainingAmount)
+		}
 	}
 
 	// Publish event
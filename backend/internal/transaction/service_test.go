@@ -2,6 +2,8 @@ package transaction
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -17,6 +19,13 @@ type mockRepository struct {
 	ratings         map[uuid.UUID][]*Rating
 	agentRatings    map[uuid.UUID]float64
 	agentStats      map[uuid.UUID]struct{ total, successful int }
+	disputes        map[uuid.UUID]*Dispute // By milestone for milestone disputes, else by transaction
+	evidence        map[uuid.UUID][]*DisputeEvidence
+	categories      map[uuid.UUID]int // Category inspection window in hours by transaction
+	warned          map[uuid.UUID]bool
+	milestones      map[uuid.UUID][]*Milestone // By transaction
+	offerMilestones map[uuid.UUID][]MilestoneRequest
+	refunds         map[uuid.UUID][]*Refund // By transaction
 	createErr       error
 	getByIDErr      error
 	listErr         error
@@ -27,11 +36,18 @@ type mockRepository struct {
 
 func newMockRepository() *mockRepository {
 	return &mockRepository{
-		transactions: make(map[uuid.UUID]*Transaction),
-		escrows:      make(map[uuid.UUID]*EscrowAccount),
-		ratings:      make(map[uuid.UUID][]*Rating),
-		agentRatings: make(map[uuid.UUID]float64),
-		agentStats:   make(map[uuid.UUID]struct{ total, successful int }),
+		transactions:    make(map[uuid.UUID]*Transaction),
+		escrows:         make(map[uuid.UUID]*EscrowAccount),
+		ratings:         make(map[uuid.UUID][]*Rating),
+		agentRatings:    make(map[uuid.UUID]float64),
+		agentStats:      make(map[uuid.UUID]struct{ total, successful int }),
+		disputes:        make(map[uuid.UUID]*Dispute),
+		evidence:        make(map[uuid.UUID][]*DisputeEvidence),
+		categories:      make(map[uuid.UUID]int),
+		warned:          make(map[uuid.UUID]bool),
+		milestones:      make(map[uuid.UUID][]*Milestone),
+		offerMilestones: make(map[uuid.UUID][]MilestoneRequest),
+		refunds:         make(map[uuid.UUID][]*Refund),
 	}
 }
 
@@ -50,6 +66,8 @@ func (m *mockRepository) CreateTransaction(ctx context.Context, req *CreateTrans
 		RequestID: req.RequestID,
 		OfferID:   req.OfferID,
 		AuctionID: req.AuctionID,
+		TradeID:   req.TradeID,
+		ProductID: req.ProductID,
 		Amount:    req.Amount,
 		Currency:  req.Currency,
 		Status:    StatusPending,
@@ -59,7 +77,19 @@ func (m *mockRepository) CreateTransaction(ctx context.Context, req *CreateTrans
 	if tx.Currency == "" {
 		tx.Currency = "USD"
 	}
+	for i, req := range req.Milestones {
+		tx.Milestones = append(tx.Milestones, &Milestone{
+			ID:            uuid.New(),
+			TransactionID: tx.ID,
+			Position:      i + 1,
+			Title:         req.Title,
+			Description:   req.Description,
+			Amount:        req.Amount,
+			Status:        MilestonePending,
+		})
+	}
 	m.transactions[tx.ID] = tx
+	m.milestones[tx.ID] = tx.Milestones
 	return tx, nil
 }
 
@@ -117,15 +147,62 @@ func (m *mockRepository) UpdateTransactionStatus(ctx context.Context, id uuid.UU
 	return nil
 }
 
-func (m *mockRepository) ConfirmDelivery(ctx context.Context, id uuid.UUID) error {
+func (m *mockRepository) CancelPendingTransaction(ctx context.Context, id uuid.UUID) (bool, error) {
+	tx, ok := m.transactions[id]
+	if !ok || tx.Status != StatusPending {
+		return false, nil
+	}
+	tx.Status = StatusCancelled
+	tx.UpdatedAt = time.Now()
+	return true, nil
+}
+
+func (m *mockRepository) MarkDelivered(ctx context.Context, id uuid.UUID, inspectionEndsAt time.Time) error {
 	tx, ok := m.transactions[id]
 	if !ok {
 		return ErrTransactionNotFound
 	}
+	now := time.Now()
+	tx.Status = StatusDelivered
+	tx.DeliveredAt = &now
+	tx.InspectionEndsAt = &inspectionEndsAt
+	tx.UpdatedAt = now
+	delete(m.warned, id)
+	return nil
+}
+
+func (m *mockRepository) ConfirmDelivery(ctx context.Context, id uuid.UUID) (bool, error) {
+	tx, ok := m.transactions[id]
+	if !ok || tx.Status != StatusDelivered {
+		return false, nil
+	}
 	tx.Status = StatusCompleted
 	now := time.Now()
 	tx.DeliveryConfirmedAt = &now
 	tx.UpdatedAt = now
+	return true, nil
+}
+
+func (m *mockRepository) CompleteDeliveredTransaction(ctx context.Context, id uuid.UUID) (bool, error) {
+	tx, ok := m.transactions[id]
+	if !ok || tx.Status != StatusDelivered {
+		return false, nil
+	}
+	tx.Status = StatusCompleted
+	now := time.Now()
+	tx.CompletedAt = &now
+	tx.UpdatedAt = now
+	return true, nil
+}
+
+func (m *mockRepository) ReopenDelivery(ctx context.Context, id uuid.UUID) error {
+	tx, ok := m.transactions[id]
+	if ok && tx.Status == StatusCompleted {
+		tx.Status = StatusDelivered
+		tx.DeliveryConfirmedAt = nil
+		tx.CompletedAt = nil
+		tx.UpdatedAt = time.Now()
+	}
 	return nil
 }
 
@@ -141,6 +218,57 @@ func (m *mockRepository) CompleteTransaction(ctx context.Context, id uuid.UUID)
 	return nil
 }
 
+func (m *mockRepository) SetInspectionWindow(ctx context.Context, id uuid.UUID, hours int) error {
+	tx, ok := m.transactions[id]
+	if !ok {
+		return ErrTransactionNotFound
+	}
+	tx.InspectionWindow = &hours
+	return nil
+}
+
+func (m *mockRepository) GetCategoryInspectionWindow(ctx context.Context, id uuid.UUID) (*int, error) {
+	hours, ok := m.categories[id]
+	if !ok {
+		return nil, nil
+	}
+	return &hours, nil
+}
+
+func (m *mockRepository) GetInspectionsEndingBefore(ctx context.Context, before time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for id, tx := range m.transactions {
+		if tx.Status == StatusDelivered && tx.InspectionEndsAt != nil && !tx.InspectionEndsAt.After(before) && m.escrowFunded(id) && !m.warned[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// escrowFunded reports whether a transaction's escrow was ever funded.
+func (m *mockRepository) escrowFunded(transactionID uuid.UUID) bool {
+	escrow, ok := m.escrows[transactionID]
+	return ok && escrow.FundedAt != nil
+}
+
+func (m *mockRepository) MarkInspectionWarned(ctx context.Context, id uuid.UUID) (bool, error) {
+	if m.warned[id] {
+		return false, nil
+	}
+	m.warned[id] = true
+	return true, nil
+}
+
+func (m *mockRepository) GetExpiredInspections(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for id, tx := range m.transactions {
+		if tx.Status == StatusDelivered && tx.InspectionEndsAt != nil && !tx.InspectionEndsAt.After(now) && m.escrowFunded(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
 func (m *mockRepository) CreateEscrowAccount(ctx context.Context, transactionID uuid.UUID, amount float64, currency string) (*EscrowAccount, error) {
 	if m.escrowErr != nil {
 		return nil, m.escrowErr
@@ -174,6 +302,8 @@ func (m *mockRepository) UpdateEscrowStatus(ctx context.Context, id uuid.UUID, s
 			if status == EscrowFunded {
 				now := time.Now()
 				escrow.FundedAt = &now
+				escrow.FundedAmount = escrow.Amount
+				escrow.RemainingAmount = escrow.Amount
 			}
 			if status == EscrowReleased {
 				now := time.Now()
@@ -185,6 +315,17 @@ func (m *mockRepository) UpdateEscrowStatus(ctx context.Context, id uuid.UUID, s
 	return ErrEscrowNotFound
 }
 
+func (m *mockRepository) RecordEscrowSettlement(ctx context.Context, id uuid.UUID, released, settled float64) error {
+	for _, escrow := range m.escrows {
+		if escrow.ID == id {
+			escrow.ReleasedAmount += released
+			escrow.RemainingAmount = max(escrow.RemainingAmount-settled, 0)
+			return nil
+		}
+	}
+	return ErrEscrowNotFound
+}
+
 func (m *mockRepository) UpdateEscrowPaymentIntent(ctx context.Context, id uuid.UUID, paymentIntentID string) error {
 	for _, escrow := range m.escrows {
 		if escrow.ID == id {
@@ -240,6 +381,222 @@ func (m *mockRepository) RecalculateAgentRating(ctx context.Context, agentID uui
 	return nil
 }
 
+func (m *mockRepository) CreateDispute(ctx context.Context, dispute *Dispute) error {
+	m.disputes[disputeKey(dispute)] = dispute
+	return nil
+}
+
+func disputeKey(dispute *Dispute) uuid.UUID {
+	if dispute.MilestoneID != nil {
+		return *dispute.MilestoneID
+	}
+	return dispute.TransactionID
+}
+
+func (m *mockRepository) GetDisputeByTransactionID(ctx context.Context, transactionID uuid.UUID) (*Dispute, error) {
+	dispute, ok := m.disputes[transactionID]
+	if !ok {
+		return nil, ErrDisputeNotFound
+	}
+	return dispute, nil
+}
+
+func (m *mockRepository) GetDisputeByMilestoneID(ctx context.Context, milestoneID uuid.UUID) (*Dispute, error) {
+	dispute, ok := m.disputes[milestoneID]
+	if !ok {
+		return nil, ErrDisputeNotFound
+	}
+	return dispute, nil
+}
+
+func (m *mockRepository) UpdateDispute(ctx context.Context, dispute *Dispute) error {
+	if _, ok := m.disputes[disputeKey(dispute)]; !ok {
+		return ErrDisputeNotFound
+	}
+	m.disputes[disputeKey(dispute)] = dispute
+	return nil
+}
+
+func (m *mockRepository) ClaimDispute(ctx context.Context, id uuid.UUID) (bool, error) {
+	for _, dispute := range m.disputes {
+		if dispute.ID == id && dispute.Status != DisputeResolving && dispute.Status != DisputeResolved {
+			dispute.Status = DisputeResolving
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRepository) ReopenDispute(ctx context.Context, id uuid.UUID, status DisputeStatus) error {
+	for _, dispute := range m.disputes {
+		if dispute.ID == id && dispute.Status == DisputeResolving {
+			dispute.Status = status
+		}
+	}
+	return nil
+}
+
+func (m *mockRepository) GetOverdueDisputes(ctx context.Context, now time.Time) ([]*Dispute, error) {
+	var disputes []*Dispute
+	for _, dispute := range m.disputes {
+		if dispute.Status == DisputeOpen && !dispute.ResponseDueAt.After(now) {
+			disputes = append(disputes, dispute)
+		}
+	}
+	return disputes, nil
+}
+
+func (m *mockRepository) CreateDisputeEvidence(ctx context.Context, evidence *DisputeEvidence) error {
+	m.evidence[evidence.DisputeID] = append(m.evidence[evidence.DisputeID], evidence)
+	return nil
+}
+
+func (m *mockRepository) GetDisputeEvidence(ctx context.Context, disputeID uuid.UUID) ([]*DisputeEvidence, error) {
+	return m.evidence[disputeID], nil
+}
+
+func (m *mockRepository) GetMilestones(ctx context.Context, transactionID uuid.UUID) ([]*Milestone, error) {
+	return m.milestones[transactionID], nil
+}
+
+func (m *mockRepository) UpdateMilestone(ctx context.Context, milestone *Milestone) error {
+	for _, existing := range m.milestones[milestone.TransactionID] {
+		if existing.ID == milestone.ID {
+			*existing = *milestone
+			return nil
+		}
+	}
+	return ErrMilestoneNotFound
+}
+
+func (m *mockRepository) MoveMilestone(ctx context.Context, id uuid.UUID, from, to MilestoneStatus) (bool, error) {
+	for _, milestones := range m.milestones {
+		for _, milestone := range milestones {
+			if milestone.ID == id && milestone.Status == from {
+				milestone.Status = to
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRepository) GetExpiredMilestoneInspections(ctx context.Context, now time.Time) ([]*Milestone, error) {
+	var expired []*Milestone
+	for _, milestones := range m.milestones {
+		for _, milestone := range milestones {
+			if milestone.Status == MilestoneDelivered && !milestone.InspectionEndsAt.After(now) {
+				expired = append(expired, milestone)
+			}
+		}
+	}
+	return expired, nil
+}
+
+func (m *mockRepository) GetOfferMilestones(ctx context.Context, offerID uuid.UUID) ([]MilestoneRequest, error) {
+	return m.offerMilestones[offerID], nil
+}
+
+func (m *mockRepository) CreateRefund(ctx context.Context, refund *Refund) error {
+	m.refunds[refund.TransactionID] = append(m.refunds[refund.TransactionID], refund)
+	return nil
+}
+
+func (m *mockRepository) GetRefund(ctx context.Context, id uuid.UUID) (*Refund, error) {
+	for _, refunds := range m.refunds {
+		for _, refund := range refunds {
+			if refund.ID == id {
+				return refund, nil
+			}
+		}
+	}
+	return nil, ErrRefundNotFound
+}
+
+func (m *mockRepository) GetRefunds(ctx context.Context, transactionID uuid.UUID) ([]*Refund, error) {
+	return m.refunds[transactionID], nil
+}
+
+func (m *mockRepository) UpdateRefund(ctx context.Context, refund *Refund) error {
+	existing, err := m.GetRefund(ctx, refund.ID)
+	if err != nil {
+		return err
+	}
+	*existing = *refund
+	return nil
+}
+
+func (m *mockRepository) ReserveRefund(ctx context.Context, refund *Refund) error {
+	escrow, ok := m.escrows[refund.TransactionID]
+	if !ok {
+		return ErrRefundExceedsPaid
+	}
+	var others []*Refund
+	for _, r := range m.refunds[refund.TransactionID] {
+		if r.ID != refund.ID {
+			others = append(others, r)
+		}
+	}
+	if toCents(refund.Amount) > toCents(escrow.ReleasedAmount-reservedTotal(others)) {
+		return ErrRefundExceedsPaid
+	}
+
+	if refund.Status == RefundRequested {
+		existing, err := m.GetRefund(ctx, refund.ID)
+		if err != nil {
+			return err
+		}
+		if existing.Status != RefundRequested {
+			return ErrRefundNotRequested
+		}
+		refund.Status = RefundProcessing
+		*existing = *refund
+		return nil
+	}
+	refund.Status = RefundProcessing
+	stored := *refund
+	return m.CreateRefund(ctx, &stored)
+}
+
+func (m *mockRepository) GetStaleRefunds(ctx context.Context, before time.Time) ([]*Refund, error) {
+	var stale []*Refund
+	for _, refunds := range m.refunds {
+		for _, r := range refunds {
+			if r.Status == RefundProcessing && r.UpdatedAt.Before(before) {
+				copied := *r
+				stale = append(stale, &copied)
+			}
+		}
+	}
+	return stale, nil
+}
+
+// mockLedger implements Ledger for testing.
+type mockLedger struct {
+	refunds   map[uuid.UUID]float64 // By refund
+	refundErr error
+}
+
+func newMockLedger() *mockLedger {
+	return &mockLedger{refunds: make(map[uuid.UUID]float64)}
+}
+
+func (m *mockLedger) HoldEscrow(ctx context.Context, transactionID, buyerID uuid.UUID, amount float64, currency string, fromWallet bool) error {
+	return nil
+}
+
+func (m *mockLedger) SettleEscrow(ctx context.Context, transactionID, sellerID uuid.UUID, released, refunded float64) error {
+	return nil
+}
+
+func (m *mockLedger) RefundPaidOut(ctx context.Context, refundID, transactionID, sellerID uuid.UUID, amount float64) error {
+	if m.refundErr != nil {
+		return m.refundErr
+	}
+	m.refunds[refundID] = amount
+	return nil
+}
+
 // mockPublisher implements EventPublisher for testing.
 type mockPublisher struct {
 	events []publishedEvent
@@ -258,13 +615,29 @@ func (m *mockPublisher) Publish(ctx context.Context, eventType string, payload m
 // mockPaymentService implements PaymentService for testing.
 type mockPaymentService struct {
 	paymentIntents map[string]bool
+	multicapture   map[string]bool
 	captured       []string
+	partial        map[string]float64
 	refunded       []string
+	refundAmounts  []float64
+	cancelled      []string
+	captures       []milestoneCapture
+	transfers      []float64
+	platformHeld   map[string]bool // Payments without a destination account, paid out by transfer
+	captureErr     error
+}
+
+type milestoneCapture struct {
+	amount float64
+	final  bool
 }
 
 func newMockPaymentService() *mockPaymentService {
 	return &mockPaymentService{
 		paymentIntents: make(map[string]bool),
+		multicapture:   make(map[string]bool),
+		partial:        make(map[string]float64),
+		platformHeld:   make(map[string]bool),
 	}
 }
 
@@ -274,13 +647,54 @@ func (m *mockPaymentService) CreateEscrowPayment(ctx context.Context, transactio
 	return piID, nil
 }
 
-func (m *mockPaymentService) CapturePayment(ctx context.Context, paymentIntentID string) error {
+func (m *mockPaymentService) CapturePayment(ctx context.Context, paymentIntentID string) (string, error) {
+	if m.captureErr != nil {
+		return "", m.captureErr
+	}
 	m.captured = append(m.captured, paymentIntentID)
-	return nil
+	return m.heldCharge(paymentIntentID), nil
+}
+
+func (m *mockPaymentService) CapturePartialPayment(ctx context.Context, paymentIntentID string, amount float64) (string, error) {
+	m.partial[paymentIntentID] = amount
+	return m.heldCharge(paymentIntentID), nil
+}
+
+// heldCharge returns a charge to transfer from for payments the platform holds.
+func (m *mockPaymentService) heldCharge(paymentIntentID string) string {
+	if m.platformHeld[paymentIntentID] {
+		return "ch_" + paymentIntentID
+	}
+	return ""
 }
 
-func (m *mockPaymentService) RefundPayment(ctx context.Context, paymentIntentID string) error {
+func (m *mockPaymentService) RefundPayment(ctx context.Context, paymentIntentID, refundID string, amount float64, reason string) (string, error) {
 	m.refunded = append(m.refunded, paymentIntentID)
+	m.refundAmounts = append(m.refundAmounts, amount)
+	return fmt.Sprintf("re_test_%d", len(m.refunded)), nil
+}
+
+func (m *mockPaymentService) CreateMilestoneEscrowPayment(ctx context.Context, transactionID, buyerID, sellerID string, amount float64, currency string) (string, error) {
+	piID, err := m.CreateEscrowPayment(ctx, transactionID, buyerID, sellerID, amount, currency)
+	m.multicapture[piID] = true
+	return piID, err
+}
+
+func (m *mockPaymentService) CaptureMilestonePayment(ctx context.Context, paymentIntentID string, amount float64, final bool) (string, error) {
+	if m.captureErr != nil {
+		return "", m.captureErr
+	}
+	m.captures = append(m.captures, milestoneCapture{amount, final})
+	return "ch_test", nil
+}
+
+func (m *mockPaymentService) TransferToSeller(ctx context.Context, transactionID, sellerID string, amount float64, currency, chargeID string) error {
+	m.transfers = append(m.transfers, amount)
+	return nil
+}
+
+func (m *mockPaymentService) CancelPayment(ctx context.Context, paymentIntentID string) error {
+	m.cancelled = append(m.cancelled, paymentIntentID)
 	return nil
 }
 
@@ -970,6 +1384,85 @@ func TestService_CreateFromOffer(t *testing.T) {
 	}
 }
 
+func TestService_CreateFromTrade(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+
+	tradeID := uuid.New()
+	productID := uuid.New()
+
+	txID, err := service.CreateFromTrade(context.Background(), uuid.New(), uuid.New(), &tradeID, &productID, 250.0, "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tx, err := service.GetTransaction(context.Background(), txID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tx.TradeID == nil || *tx.TradeID != tradeID {
+		t.Errorf("expected trade ID %s, got %v", tradeID, tx.TradeID)
+	}
+	if tx.ProductID == nil || *tx.ProductID != productID {
+		t.Errorf("expected product ID %s, got %v", productID, tx.ProductID)
+	}
+	if len(repo.escrows) != 1 {
+		t.Errorf("expected 1 escrow, got %d", len(repo.escrows))
+	}
+}
+
+func TestService_CreateFromAuction(t *testing.T) {
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+
+	buyerID := uuid.New()
+	auctionID := uuid.New()
+
+	txID, err := service.CreateFromAuction(context.Background(), buyerID, uuid.New(), &auctionID, 320.0, "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tx, _ := service.GetTransaction(context.Background(), txID)
+	if tx.AuctionID == nil || *tx.AuctionID != auctionID {
+		t.Errorf("expected auction ID %s, got %v", auctionID, tx.AuctionID)
+	}
+	if len(payment.paymentIntents) != 1 {
+		t.Errorf("expected escrow funding to be started, got %d payment intents", len(payment.paymentIntents))
+	}
+	escrow, _ := repo.GetEscrowByTransactionID(context.Background(), txID)
+	if escrow == nil || escrow.StripePaymentIntentID == nil {
+		t.Error("expected payment intent on the escrow account")
+	}
+}
+
+func TestService_CancelUnfundedTransaction(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+
+	pending, _ := repo.CreateTransaction(context.Background(), &CreateTransactionRequest{BuyerID: uuid.New(), SellerID: uuid.New(), Amount: 50})
+	funded, _ := repo.CreateTransaction(context.Background(), &CreateTransactionRequest{BuyerID: uuid.New(), SellerID: uuid.New(), Amount: 50})
+	funded.Status = StatusEscrowFunded
+
+	cancelled, err := service.CancelUnfundedTransaction(context.Background(), pending.ID)
+	if err != nil || !cancelled {
+		t.Fatalf("expected pending transaction to be cancelled, got %v, %v", cancelled, err)
+	}
+	if pending.Status != StatusCancelled {
+		t.Errorf("expected status cancelled, got %s", pending.Status)
+	}
+
+	cancelled, err = service.CancelUnfundedTransaction(context.Background(), funded.ID)
+	if err != nil || cancelled {
+		t.Fatalf("expected funded transaction to be kept, got %v, %v", cancelled, err)
+	}
+	if funded.Status != StatusEscrowFunded {
+		t.Errorf("expected status escrow_funded, got %s", funded.Status)
+	}
+}
+
 func TestService_GetTransaction(t *testing.T) {
 	repo := newMockRepository()
 	service := NewService(repo, nil)
@@ -1240,40 +1733,183 @@ func TestService_MarkDelivered_InvalidStatus(t *testing.T) {
 	}
 }
 
-func TestService_ConfirmDelivery(t *testing.T) {
+func TestService_MarkDelivered_InspectionWindow(t *testing.T) {
+	ctx := context.Background()
 	repo := newMockRepository()
-	publisher := &mockPublisher{}
-	service := NewService(repo, publisher)
-
-	buyerID := uuid.New()
-	tx, _ := repo.CreateTransaction(context.Background(), &CreateTransactionRequest{
-		BuyerID:  buyerID,
-		SellerID: uuid.New(),
-		Amount:   100.0,
-	})
-
-	// Transaction must be in 'delivered' status for buyer to confirm
-	repo.UpdateTransactionStatus(context.Background(), tx.ID, StatusDelivered)
+	service := NewService(repo, nil)
 
-	updated, err := service.ConfirmDelivery(context.Background(), tx.ID, buyerID)
+	deliver := func(setup func(tx *Transaction)) time.Duration {
+		sellerID := uuid.New()
+		tx, _ := repo.CreateTransaction(ctx, &CreateTransactionRequest{
+			BuyerID:  uuid.New(),
+			SellerID: sellerID,
+			Amount:   100.0,
+		})
+		setup(tx)
+		before := time.Now().UTC()
+		updated, err := service.MarkDelivered(ctx, tx.ID, sellerID, "proof", "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return updated.InspectionEndsAt.Sub(before).Round(time.Hour)
+	}
 
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if got := deliver(func(tx *Transaction) {}); got != defaultInspectionWindow {
+		t.Errorf("expected default window %v, got %v", defaultInspectionWindow, got)
 	}
-	if updated.Status != StatusCompleted {
-		t.Errorf("expected status completed, got %s", updated.Status)
+	if got := deliver(func(tx *Transaction) { repo.categories[tx.ID] = 168 }); got != 168*time.Hour {
+		t.Errorf("expected category window 168h, got %v", got)
+	}
+	if got := deliver(func(tx *Transaction) {
+		repo.categories[tx.ID] = 168
+		service.SetInspectionWindow(ctx, tx.ID, tx.BuyerID, 12)
+	}); got != 12*time.Hour {
+		t.Errorf("expected the transaction's own window 12h, got %v", got)
 	}
 }
 
-func TestService_ConfirmDelivery_NotBuyer(t *testing.T) {
+func TestService_SetInspectionWindow(t *testing.T) {
+	ctx := context.Background()
 	repo := newMockRepository()
 	service := NewService(repo, nil)
+	service.SetInspectionConfig(0, 0, 240*time.Hour)
 
-	tx, _ := repo.CreateTransaction(context.Background(), &CreateTransactionRequest{
-		BuyerID:  uuid.New(),
-		SellerID: uuid.New(),
-		Amount:   100.0,
-	})
+	buyerID := uuid.New()
+	sellerID := uuid.New()
+	tx, _ := repo.CreateTransaction(ctx, &CreateTransactionRequest{
+		BuyerID:  buyerID,
+		SellerID: sellerID,
+		Amount:   100.0,
+	})
+
+	tests := []struct {
+		name    string
+		agentID uuid.UUID
+		hours   int
+		wantErr error
+	}{
+		{"seller", sellerID, 48, ErrNotAuthorized},
+		{"zero", buyerID, 0, ErrInvalidInspectionWindow},
+		{"over the maximum", buyerID, 241, ErrInvalidInspectionWindow},
+		{"buyer", buyerID, 240, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.SetInspectionWindow(ctx, tx.ID, tt.agentID, tt.hours)
+			if err != tt.wantErr {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+	if *tx.InspectionWindow != 240 {
+		t.Errorf("expected window 240, got %d", *tx.InspectionWindow)
+	}
+
+	tx.Status = StatusDelivered
+	if _, err := service.SetInspectionWindow(ctx, tx.ID, buyerID, 48); err != ErrInvalidStatus {
+		t.Errorf("expected ErrInvalidStatus after delivery, got %v", err)
+	}
+}
+
+func TestService_ProcessInspections(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+
+	deliver := func(funded bool) *Transaction {
+		sellerID := uuid.New()
+		tx, _ := repo.CreateTransaction(ctx, &CreateTransactionRequest{
+			BuyerID:  uuid.New(),
+			SellerID: sellerID,
+			Amount:   100.0,
+		})
+		escrow, _ := repo.CreateEscrowAccount(ctx, tx.ID, tx.Amount, tx.Currency)
+		repo.UpdateEscrowPaymentIntent(ctx, escrow.ID, "pi_"+tx.ID.String()[:8])
+		if funded {
+			repo.UpdateEscrowStatus(ctx, escrow.ID, EscrowFunded)
+		}
+		tx.Status = StatusEscrowFunded
+		if _, err := service.MarkDelivered(ctx, tx.ID, sellerID, "proof", ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return tx
+	}
+	undisputed := deliver(true)
+	disputed := deliver(true)
+	unfunded := deliver(false)
+	if _, err := service.DisputeTransaction(ctx, disputed.ID, disputed.BuyerID, &DisputeRequest{Reason: "Broken"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Inside the warning period the buyer is warned once, but nothing is released
+	now := time.Now().UTC().Add(defaultInspectionWindow - time.Hour)
+	completed, err := service.ProcessInspections(ctx, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if completed != 0 || !repo.warned[undisputed.ID] {
+		t.Errorf("expected a warning and no release, got %d released", completed)
+	}
+	if repo.warned[unfunded.ID] {
+		t.Error("expected no warning for unfunded escrow")
+	}
+
+	// Once the window ends escrow is released; the dispute stopped the other
+	// clock and unfunded escrow never started one
+	completed, err = service.ProcessInspections(ctx, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if completed != 1 {
+		t.Fatalf("expected 1 transaction completed, got %d", completed)
+	}
+	if undisputed.Status != StatusCompleted || len(payment.captured) != 1 {
+		t.Errorf("expected the undisputed transaction completed and captured, got %s", undisputed.Status)
+	}
+	if disputed.Status != StatusDisputed {
+		t.Errorf("expected the disputed transaction to stay disputed, got %s", disputed.Status)
+	}
+	if unfunded.Status != StatusDelivered {
+		t.Errorf("expected the unfunded transaction to stay delivered, got %s", unfunded.Status)
+	}
+}
+
+func TestService_ConfirmDelivery(t *testing.T) {
+	repo := newMockRepository()
+	publisher := &mockPublisher{}
+	service := NewService(repo, publisher)
+
+	buyerID := uuid.New()
+	tx, _ := repo.CreateTransaction(context.Background(), &CreateTransactionRequest{
+		BuyerID:  buyerID,
+		SellerID: uuid.New(),
+		Amount:   100.0,
+	})
+
+	// Transaction must be in 'delivered' status for buyer to confirm
+	repo.UpdateTransactionStatus(context.Background(), tx.ID, StatusDelivered)
+
+	updated, err := service.ConfirmDelivery(context.Background(), tx.ID, buyerID)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Status != StatusCompleted {
+		t.Errorf("expected status completed, got %s", updated.Status)
+	}
+}
+
+func TestService_ConfirmDelivery_NotBuyer(t *testing.T) {
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+
+	tx, _ := repo.CreateTransaction(context.Background(), &CreateTransactionRequest{
+		BuyerID:  uuid.New(),
+		SellerID: uuid.New(),
+		Amount:   100.0,
+	})
 
 	// Transaction must be in 'delivered' status
 	repo.UpdateTransactionStatus(context.Background(), tx.ID, StatusDelivered)
@@ -1338,6 +1974,77 @@ func TestService_CompleteTransaction_NotDelivered(t *testing.T) {
 	}
 }
 
+func TestService_CompleteTransaction_CaptureFailed(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	payment.captureErr = errors.New("card declined")
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+
+	tx, _ := repo.CreateTransaction(ctx, &CreateTransactionRequest{
+		BuyerID:  uuid.New(),
+		SellerID: uuid.New(),
+		Amount:   100.0,
+	})
+	escrow, _ := repo.CreateEscrowAccount(ctx, tx.ID, tx.Amount, tx.Currency)
+	repo.UpdateEscrowPaymentIntent(ctx, escrow.ID, "pi_declined")
+	repo.UpdateTransactionStatus(ctx, tx.ID, StatusDelivered)
+
+	if _, err := service.CompleteTransaction(ctx, tx.ID); err == nil {
+		t.Fatal("expected the capture error")
+	}
+	if tx.Status != StatusDelivered {
+		t.Errorf("expected status delivered, got %s", tx.Status)
+	}
+	if escrow.Status == EscrowReleased {
+		t.Error("escrow released although nothing was captured")
+	}
+}
+
+// staleRepository serves transactions as they were when the test took a
+// snapshot, as if every caller had read them before anyone wrote.
+type staleRepository struct {
+	*mockRepository
+	snapshot map[uuid.UUID]Transaction
+}
+
+func (r *staleRepository) GetTransactionByID(ctx context.Context, id uuid.UUID) (*Transaction, error) {
+	if tx, ok := r.snapshot[id]; ok {
+		return &tx, nil
+	}
+	return r.mockRepository.GetTransactionByID(ctx, id)
+}
+
+func TestService_ConfirmDelivery_RacesInspection(t *testing.T) {
+	ctx := context.Background()
+	repo := &staleRepository{mockRepository: newMockRepository(), snapshot: make(map[uuid.UUID]Transaction)}
+	payment := newMockPaymentService()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+
+	tx, _ := repo.CreateTransaction(ctx, &CreateTransactionRequest{
+		BuyerID:  uuid.New(),
+		SellerID: uuid.New(),
+		Amount:   100.0,
+	})
+	escrow, _ := repo.CreateEscrowAccount(ctx, tx.ID, tx.Amount, tx.Currency)
+	repo.UpdateEscrowPaymentIntent(ctx, escrow.ID, "pi_race")
+	repo.UpdateTransactionStatus(ctx, tx.ID, StatusDelivered)
+	repo.snapshot[tx.ID] = *tx
+
+	if _, err := service.ConfirmDelivery(ctx, tx.ID, tx.BuyerID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The inspection worker read the transaction while it was still delivered
+	if _, err := service.CompleteTransaction(ctx, tx.ID); err != ErrInvalidStatus {
+		t.Errorf("expected ErrInvalidStatus, got %v", err)
+	}
+	if len(payment.captured) != 1 {
+		t.Errorf("expected one capture, got %d", len(payment.captured))
+	}
+}
+
 func TestService_SubmitRating(t *testing.T) {
 	repo := newMockRepository()
 	publisher := &mockPublisher{}
@@ -1350,7 +2057,7 @@ func TestService_SubmitRating(t *testing.T) {
 		SellerID: sellerID,
 		Amount:   100.0,
 	})
-	repo.ConfirmDelivery(context.Background(), tx.ID)
+	repo.UpdateTransactionStatus(context.Background(), tx.ID, StatusCompleted)
 
 	rating, err := service.SubmitRating(context.Background(), tx.ID, buyerID, &SubmitRatingRequest{
 		Score:   5,
@@ -1381,7 +2088,7 @@ func TestService_SubmitRating_InvalidScore(t *testing.T) {
 		SellerID: uuid.New(),
 		Amount:   100.0,
 	})
-	repo.ConfirmDelivery(context.Background(), tx.ID)
+	repo.UpdateTransactionStatus(context.Background(), tx.ID, StatusCompleted)
 
 	tests := []struct {
 		score int
@@ -1411,7 +2118,7 @@ func TestService_SubmitRating_NotParticipant(t *testing.T) {
 		SellerID: uuid.New(),
 		Amount:   100.0,
 	})
-	repo.ConfirmDelivery(context.Background(), tx.ID)
+	repo.UpdateTransactionStatus(context.Background(), tx.ID, StatusCompleted)
 
 	_, err := service.SubmitRating(context.Background(), tx.ID, uuid.New(), &SubmitRatingRequest{
 		Score: 5,
@@ -1453,7 +2160,7 @@ func TestService_SubmitRating_AlreadyRated(t *testing.T) {
 		SellerID: uuid.New(),
 		Amount:   100.0,
 	})
-	repo.ConfirmDelivery(context.Background(), tx.ID)
+	repo.UpdateTransactionStatus(context.Background(), tx.ID, StatusCompleted)
 
 	// First rating
 	_, err := service.SubmitRating(context.Background(), tx.ID, buyerID, &SubmitRatingRequest{
@@ -1484,7 +2191,7 @@ func TestService_SubmitRating_AutoComplete(t *testing.T) {
 		SellerID: sellerID,
 		Amount:   100.0,
 	})
-	repo.ConfirmDelivery(context.Background(), tx.ID)
+	repo.UpdateTransactionStatus(context.Background(), tx.ID, StatusCompleted)
 
 	// Both parties rate
 	service.SubmitRating(context.Background(), tx.ID, buyerID, &SubmitRatingRequest{Score: 5})
@@ -1508,7 +2215,7 @@ func TestService_GetTransactionRatings(t *testing.T) {
 		SellerID: sellerID,
 		Amount:   100.0,
 	})
-	repo.ConfirmDelivery(context.Background(), tx.ID)
+	repo.UpdateTransactionStatus(context.Background(), tx.ID, StatusCompleted)
 
 	// Submit ratings
 	service.SubmitRating(context.Background(), tx.ID, buyerID, &SubmitRatingRequest{Score: 5})
@@ -1590,6 +2297,252 @@ func TestService_DisputeTransaction_InvalidStatus(t *testing.T) {
 	}
 }
 
+// newDisputedTransaction creates a transaction with funded escrow and opens a
+// dispute on it on behalf of the buyer.
+func newDisputedTransaction(t *testing.T, repo *mockRepository, service *Service) *Transaction {
+	t.Helper()
+	ctx := context.Background()
+
+	tx, _ := repo.CreateTransaction(ctx, &CreateTransactionRequest{
+		BuyerID:  uuid.New(),
+		SellerID: uuid.New(),
+		Amount:   450.0,
+		Currency: "USD",
+	})
+	escrow, _ := repo.CreateEscrowAccount(ctx, tx.ID, tx.Amount, tx.Currency)
+	repo.UpdateEscrowPaymentIntent(ctx, escrow.ID, "pi_dispute")
+	repo.UpdateEscrowStatus(ctx, escrow.ID, EscrowFunded)
+	tx.Status = StatusEscrowFunded
+
+	if _, err := service.DisputeTransaction(ctx, tx.ID, tx.BuyerID, &DisputeRequest{Reason: "Item not as described"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return tx
+}
+
+func TestService_Dispute_SplitTransfersPlatformHeldFunds(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	arbitratorID := uuid.New()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+	service.SetDisputeConfig(0, []uuid.UUID{arbitratorID})
+
+	// The payment had no destination, so capturing leaves the funds with the platform
+	payment.platformHeld["pi_dispute"] = true
+	tx := newDisputedTransaction(t, repo, service)
+	split := &ResolveDisputeRequest{Outcome: OutcomeSplit, SellerPercent: 70}
+	if _, err := service.ResolveDispute(ctx, tx.ID, nil, arbitratorID, split); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(payment.transfers) != 1 || payment.transfers[0] != 315 {
+		t.Errorf("expected the seller's 315 transferred, got %v", payment.transfers)
+	}
+
+	// A destination charge already paid the seller
+	payment = newMockPaymentService()
+	service.SetPaymentService(payment)
+	tx = newDisputedTransaction(t, repo, service)
+	if _, err := service.ResolveDispute(ctx, tx.ID, nil, arbitratorID, split); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(payment.captured)+len(payment.partial) != 1 || len(payment.transfers) != 0 {
+		t.Errorf("expected a capture without a transfer, got %v", payment.transfers)
+	}
+}
+
+func TestService_Dispute_ArbitratedSplit(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	arbitratorID := uuid.New()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+	service.SetDisputeConfig(0, []uuid.UUID{arbitratorID})
+
+	tx := newDisputedTransaction(t, repo, service)
+	dispute := repo.disputes[tx.ID]
+	if dispute.RespondentID != tx.SellerID || dispute.Status != DisputeOpen {
+		t.Fatalf("expected an open dispute against the seller, got %+v", dispute)
+	}
+	if got := dispute.ResponseDueAt.Sub(dispute.CreatedAt); got != defaultDisputeResponseWindow {
+		t.Errorf("expected response window %v, got %v", defaultDisputeResponseWindow, got)
+	}
+
+	// The seller's response hands the dispute to arbitration
+	_, err := service.SubmitEvidence(ctx, tx.ID, nil, tx.SellerID, &SubmitEvidenceRequest{
+		Body:        "Delivered as specified",
+		Attachments: []string{"https://example.com/delivery.log"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dispute.Status != DisputeArbitration || dispute.RespondedAt == nil {
+		t.Errorf("expected dispute in arbitration, got %s", dispute.Status)
+	}
+	if _, err := service.SubmitEvidence(ctx, tx.ID, nil, tx.BuyerID, &SubmitEvidenceRequest{}); err != ErrEmptyEvidence {
+		t.Errorf("expected ErrEmptyEvidence, got %v", err)
+	}
+
+	// Only an arbitrator can split
+	split := &ResolveDisputeRequest{Outcome: OutcomeSplit, SellerPercent: 70}
+	if _, err := service.ResolveDispute(ctx, tx.ID, nil, tx.BuyerID, split); err != ErrNotAuthorized {
+		t.Errorf("expected ErrNotAuthorized, got %v", err)
+	}
+	if _, err := service.ResolveDispute(ctx, tx.ID, nil, arbitratorID, &ResolveDisputeRequest{Outcome: OutcomeSplit}); err != ErrInvalidResolution {
+		t.Errorf("expected ErrInvalidResolution, got %v", err)
+	}
+
+	resolved, err := service.ResolveDispute(ctx, tx.ID, nil, arbitratorID, split)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *resolved.SellerAmount != 315 || *resolved.RefundAmount != 135 {
+		t.Errorf("expected 315/135 split, got %v/%v", *resolved.SellerAmount, *resolved.RefundAmount)
+	}
+	if payment.partial["pi_dispute"] != 315 {
+		t.Errorf("expected 315 captured, got %v", payment.partial["pi_dispute"])
+	}
+	if resolved.WinnerID == nil || *resolved.WinnerID != tx.SellerID {
+		t.Error("expected the seller to win a 70% split")
+	}
+	if tx.Status != StatusCompleted {
+		t.Errorf("expected status completed, got %s", tx.Status)
+	}
+	if repo.escrows[tx.ID].Status != EscrowReleased {
+		t.Errorf("expected escrow released, got %s", repo.escrows[tx.ID].Status)
+	}
+
+	if _, err := service.ResolveDispute(ctx, tx.ID, nil, arbitratorID, split); err != ErrDisputeResolved {
+		t.Errorf("expected ErrDisputeResolved, got %v", err)
+	}
+}
+
+func TestService_ResolveDispute_SellerConcedes(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+
+	tx := newDisputedTransaction(t, repo, service)
+
+	if _, err := service.ResolveDispute(ctx, tx.ID, nil, tx.SellerID, &ResolveDisputeRequest{Outcome: OutcomeRelease}); err != ErrNotAuthorized {
+		t.Errorf("expected ErrNotAuthorized, got %v", err)
+	}
+
+	resolved, err := service.ResolveDispute(ctx, tx.ID, nil, tx.SellerID, &ResolveDisputeRequest{Outcome: OutcomeRefund})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(payment.refunded) != 1 || len(payment.captured) != 0 {
+		t.Errorf("expected one refund and no capture, got %v and %v", payment.refunded, payment.captured)
+	}
+	if tx.Status != StatusRefunded {
+		t.Errorf("expected status refunded, got %s", tx.Status)
+	}
+	if *resolved.WinnerID != tx.BuyerID || *resolved.LoserID != tx.SellerID {
+		t.Error("expected the buyer to win")
+	}
+}
+
+func TestService_ResolveDispute_Once(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	arbitratorID := uuid.New()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+	service.SetDisputeConfig(0, []uuid.UUID{arbitratorID})
+
+	// The dispute worker read the dispute before the arbitrator resolved it
+	tx := newDisputedTransaction(t, repo, service)
+	stale := *repo.disputes[tx.ID]
+	if _, err := service.ResolveDispute(ctx, tx.ID, nil, arbitratorID, &ResolveDisputeRequest{Outcome: OutcomeRelease}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.resolveDispute(ctx, tx, &stale, OutcomeRefund, 0, nil, "respondent did not respond in time"); err != ErrDisputeResolved {
+		t.Errorf("expected ErrDisputeResolved, got %v", err)
+	}
+	if len(payment.captured) != 1 || len(payment.refunded) != 0 {
+		t.Errorf("expected one capture and no refund, got %v and %v", payment.captured, payment.refunded)
+	}
+
+	// A failed capture hands the dispute back for another try
+	payment.captureErr = errors.New("card declined")
+	tx = newDisputedTransaction(t, repo, service)
+	if _, err := service.ResolveDispute(ctx, tx.ID, nil, arbitratorID, &ResolveDisputeRequest{Outcome: OutcomeRelease}); err == nil {
+		t.Fatal("expected the capture error")
+	}
+	if repo.disputes[tx.ID].Status != DisputeOpen {
+		t.Errorf("expected the dispute open again, got %s", repo.disputes[tx.ID].Status)
+	}
+	payment.captureErr = nil
+	if _, err := service.ResolveDispute(ctx, tx.ID, nil, arbitratorID, &ResolveDisputeRequest{Outcome: OutcomeRelease}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestService_ProcessDisputes(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+	service.SetDisputeConfig(time.Hour, nil)
+
+	unanswered := newDisputedTransaction(t, repo, service)
+	answered := newDisputedTransaction(t, repo, service)
+	service.SubmitEvidence(ctx, answered.ID, nil, answered.SellerID, &SubmitEvidenceRequest{Body: "Delivered"})
+
+	if decided, _ := service.ProcessDisputes(ctx, time.Now().UTC()); decided != 0 {
+		t.Errorf("expected no disputes decided before the deadline, got %d", decided)
+	}
+
+	decided, err := service.ProcessDisputes(ctx, time.Now().UTC().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decided != 1 {
+		t.Fatalf("expected 1 dispute decided, got %d", decided)
+	}
+
+	dispute := repo.disputes[unanswered.ID]
+	if *dispute.Outcome != OutcomeRefund || dispute.ResolvedBy != nil {
+		t.Errorf("expected a default refund, got %s", *dispute.Outcome)
+	}
+	if unanswered.Status != StatusRefunded {
+		t.Errorf("expected status refunded, got %s", unanswered.Status)
+	}
+	if repo.disputes[answered.ID].Status != DisputeArbitration {
+		t.Errorf("expected answered dispute to stay in arbitration, got %s", repo.disputes[answered.ID].Status)
+	}
+}
+
+func TestService_GetDispute(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	arbitratorID := uuid.New()
+	service := NewService(repo, nil)
+	service.SetDisputeConfig(0, []uuid.UUID{arbitratorID})
+
+	tx := newDisputedTransaction(t, repo, service)
+	service.SubmitEvidence(ctx, tx.ID, nil, tx.BuyerID, &SubmitEvidenceRequest{Body: "Missing fields"})
+
+	if _, err := service.GetDispute(ctx, tx.ID, nil, uuid.New()); err != ErrNotAuthorized {
+		t.Errorf("expected ErrNotAuthorized, got %v", err)
+	}
+
+	dispute, err := service.GetDispute(ctx, tx.ID, nil, arbitratorID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dispute.Evidence) != 1 || dispute.Evidence[0].Role != "buyer" {
+		t.Errorf("expected one buyer evidence entry, got %+v", dispute.Evidence)
+	}
+}
+
 func TestService_RefundTransaction(t *testing.T) {
 	repo := newMockRepository()
 	publisher := &mockPublisher{}
@@ -1628,6 +2581,485 @@ func TestService_RefundTransaction(t *testing.T) {
 	}
 }
 
+func TestService_CreateFromOffer_Milestones(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+
+	offerID := uuid.New()
+	repo.offerMilestones[offerID] = []MilestoneRequest{
+		{Title: "Design", Amount: 200},
+		{Title: "Build", Amount: 300},
+	}
+
+	txID, err := service.CreateFromOffer(ctx, uuid.New(), uuid.New(), nil, &offerID, 500, "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tx, _ := service.GetTransaction(ctx, txID)
+	if len(tx.Milestones) != 2 || tx.Milestones[1].Position != 2 || tx.Milestones[1].Amount != 300 {
+		t.Fatalf("expected the offer's 2 milestones in order, got %+v", tx.Milestones)
+	}
+
+	// Milestones must add up to the transaction amount
+	_, err = service.CreateFromOffer(ctx, uuid.New(), uuid.New(), nil, &offerID, 450, "USD")
+	if err != ErrInvalidMilestones {
+		t.Errorf("expected ErrInvalidMilestones, got %v", err)
+	}
+}
+
+// newMilestoneTransaction creates a transaction paid in milestones of the
+// given amounts and funds its escrow.
+func newMilestoneTransaction(t *testing.T, repo *mockRepository, service *Service, amounts ...float64) *Transaction {
+	t.Helper()
+	ctx := context.Background()
+
+	req := &CreateTransactionRequest{BuyerID: uuid.New(), SellerID: uuid.New(), Currency: "USD"}
+	for i, amount := range amounts {
+		req.Milestones = append(req.Milestones, MilestoneRequest{Title: fmt.Sprintf("Stage %d", i+1), Amount: amount})
+		req.Amount += amount
+	}
+	tx, err := service.CreateTransaction(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.FundEscrow(ctx, tx.ID, tx.BuyerID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.ConfirmEscrowFunded(ctx, tx.ID, *repo.escrows[tx.ID].StripePaymentIntentID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return repo.transactions[tx.ID]
+}
+
+func TestService_Milestones_ReleaseInOrder(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+
+	tx := newMilestoneTransaction(t, repo, service, 100, 150)
+	first, second := tx.Milestones[0], tx.Milestones[1]
+	escrow := repo.escrows[tx.ID]
+	if !payment.multicapture[*escrow.StripePaymentIntentID] {
+		t.Error("expected a multicapture payment for a milestone transaction")
+	}
+
+	// The transaction as a whole can't be delivered, and milestones go in order
+	if _, err := service.MarkDelivered(ctx, tx.ID, tx.SellerID, "proof", ""); err != ErrPaidInMilestones {
+		t.Errorf("expected ErrPaidInMilestones, got %v", err)
+	}
+	if _, err := service.MarkMilestoneDelivered(ctx, tx.ID, second.ID, tx.SellerID, "proof", ""); err != ErrMilestoneOutOfOrder {
+		t.Errorf("expected ErrMilestoneOutOfOrder, got %v", err)
+	}
+	if _, err := service.MarkMilestoneDelivered(ctx, tx.ID, first.ID, tx.BuyerID, "proof", ""); err != ErrNotAuthorized {
+		t.Errorf("expected ErrNotAuthorized, got %v", err)
+	}
+
+	if _, err := service.MarkMilestoneDelivered(ctx, tx.ID, first.ID, tx.SellerID, "design.pdf", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Status != MilestoneDelivered || first.InspectionEndsAt == nil {
+		t.Fatalf("expected the first milestone delivered with an inspection window, got %s", first.Status)
+	}
+	if _, err := service.ConfirmMilestone(ctx, tx.ID, first.ID, tx.BuyerID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Status != MilestoneReleased || first.ReleasedAmount != 100 {
+		t.Errorf("expected the first milestone released, got %s %v", first.Status, first.ReleasedAmount)
+	}
+	if escrow.ReleasedAmount != 100 || escrow.RemainingAmount != 150 {
+		t.Errorf("expected 100 released and 150 held, got %v/%v", escrow.ReleasedAmount, escrow.RemainingAmount)
+	}
+	if tx.Status != StatusEscrowFunded {
+		t.Errorf("expected the transaction to stay funded, got %s", tx.Status)
+	}
+
+	// The second milestone is released when its inspection window ends
+	if _, err := service.MarkMilestoneDelivered(ctx, tx.ID, second.ID, tx.SellerID, "build.zip", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	released, err := service.ProcessInspections(ctx, time.Now().UTC().Add(defaultInspectionWindow+time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if released != 1 || second.Status != MilestoneReleased {
+		t.Fatalf("expected the second milestone released after inspection, got %d %s", released, second.Status)
+	}
+
+	want := []milestoneCapture{{100, false}, {150, true}}
+	if len(payment.captures) != 2 || payment.captures[0] != want[0] || payment.captures[1] != want[1] {
+		t.Errorf("expected captures %v, got %v", want, payment.captures)
+	}
+	if len(payment.transfers) != 2 {
+		t.Errorf("expected 2 transfers to the seller, got %d", len(payment.transfers))
+	}
+	if tx.Status != StatusCompleted || escrow.Status != EscrowReleased || escrow.RemainingAmount != 0 {
+		t.Errorf("expected the transaction completed and escrow released, got %s/%s", tx.Status, escrow.Status)
+	}
+}
+
+func TestService_ConfirmMilestone_SettledOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+
+	tx := newMilestoneTransaction(t, repo, service, 100, 150)
+	first, second := tx.Milestones[0], tx.Milestones[1]
+	if _, err := service.MarkMilestoneDelivered(ctx, tx.ID, first.ID, tx.SellerID, "design.pdf", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The buyer and the inspection worker both read the milestone as delivered
+	if err := service.settleMilestone(ctx, tx, tx.Milestones, first, MilestoneDelivered, first.Amount); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.settleMilestone(ctx, tx, tx.Milestones, first, MilestoneDelivered, first.Amount); err != ErrInvalidStatus {
+		t.Errorf("expected ErrInvalidStatus, got %v", err)
+	}
+	if len(payment.captures) != 1 {
+		t.Errorf("expected one capture, got %d", len(payment.captures))
+	}
+
+	// A failed capture hands the milestone back for another try
+	if _, err := service.MarkMilestoneDelivered(ctx, tx.ID, second.ID, tx.SellerID, "build.zip", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	payment.captureErr = errors.New("card declined")
+	if _, err := service.ConfirmMilestone(ctx, tx.ID, second.ID, tx.BuyerID); err == nil {
+		t.Fatal("expected the capture error")
+	}
+	if second.Status != MilestoneDelivered {
+		t.Errorf("expected the milestone delivered again, got %s", second.Status)
+	}
+	payment.captureErr = nil
+	if _, err := service.ConfirmMilestone(ctx, tx.ID, second.ID, tx.BuyerID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.Status != MilestoneReleased {
+		t.Errorf("expected the milestone released, got %s", second.Status)
+	}
+}
+
+func TestService_DisputeMilestone(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	arbitratorID := uuid.New()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+	service.SetDisputeConfig(0, []uuid.UUID{arbitratorID})
+
+	tx := newMilestoneTransaction(t, repo, service, 200, 300)
+	first, second := tx.Milestones[0], tx.Milestones[1]
+	escrow := repo.escrows[tx.ID]
+
+	service.MarkMilestoneDelivered(ctx, tx.ID, first.ID, tx.SellerID, "proof", "")
+	service.ConfirmMilestone(ctx, tx.ID, first.ID, tx.BuyerID)
+
+	if _, err := service.DisputeTransaction(ctx, tx.ID, tx.BuyerID, &DisputeRequest{Reason: "Late"}); err != ErrPaidInMilestones {
+		t.Errorf("expected ErrPaidInMilestones, got %v", err)
+	}
+	if _, err := service.DisputeMilestone(ctx, tx.ID, second.ID, tx.BuyerID, &DisputeRequest{Reason: "Incomplete"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.Status != MilestoneDisputed {
+		t.Errorf("expected the milestone disputed, got %s", second.Status)
+	}
+	if _, err := service.GetDispute(ctx, tx.ID, nil, tx.BuyerID); err != ErrDisputeNotFound {
+		t.Errorf("expected no transaction dispute, got %v", err)
+	}
+
+	// A split is over the milestone's amount, and settling the last milestone completes the deal
+	split := &ResolveDisputeRequest{Outcome: OutcomeSplit, SellerPercent: 40}
+	resolved, err := service.ResolveDispute(ctx, tx.ID, &second.ID, arbitratorID, split)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *resolved.SellerAmount != 120 || *resolved.RefundAmount != 180 {
+		t.Errorf("expected 120/180 split, got %v/%v", *resolved.SellerAmount, *resolved.RefundAmount)
+	}
+	if second.Status != MilestoneReleased || second.ReleasedAmount != 120 {
+		t.Errorf("expected 120 of the milestone released, got %s %v", second.Status, second.ReleasedAmount)
+	}
+	if last := payment.captures[len(payment.captures)-1]; last != (milestoneCapture{120, true}) {
+		t.Errorf("expected a final capture of 120, got %v", last)
+	}
+	if escrow.ReleasedAmount != 320 || escrow.RemainingAmount != 0 {
+		t.Errorf("expected 320 released and nothing held, got %v/%v", escrow.ReleasedAmount, escrow.RemainingAmount)
+	}
+	if tx.Status != StatusCompleted {
+		t.Errorf("expected status completed, got %s", tx.Status)
+	}
+}
+
+func TestService_DisputeMilestone_Refund(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+
+	tx := newMilestoneTransaction(t, repo, service, 250)
+	milestone := tx.Milestones[0]
+
+	if _, err := service.DisputeMilestone(ctx, tx.ID, milestone.ID, tx.BuyerID, &DisputeRequest{Reason: "Never started"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.ResolveDispute(ctx, tx.ID, &milestone.ID, tx.SellerID, &ResolveDisputeRequest{Outcome: OutcomeRefund}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Nothing was captured, so the hold is cancelled and the whole deal refunded
+	if milestone.Status != MilestoneRefunded || len(payment.captures) != 0 {
+		t.Errorf("expected the milestone refunded without a capture, got %s", milestone.Status)
+	}
+	if len(payment.cancelled) != 1 {
+		t.Errorf("expected the payment cancelled, got %v", payment.cancelled)
+	}
+	if tx.Status != StatusRefunded || repo.escrows[tx.ID].Status != EscrowRefunded {
+		t.Errorf("expected the transaction refunded, got %s", tx.Status)
+	}
+}
+
+// newCompletedTransaction creates a completed card-paid transaction whose
+// escrow released the whole amount to the seller.
+func newCompletedTransaction(t *testing.T, repo *mockRepository, amount float64) *Transaction {
+	t.Helper()
+	ctx := context.Background()
+	tx, _ := repo.CreateTransaction(ctx, &CreateTransactionRequest{
+		BuyerID:  uuid.New(),
+		SellerID: uuid.New(),
+		Amount:   amount,
+	})
+	escrow, _ := repo.CreateEscrowAccount(ctx, tx.ID, amount, "USD")
+	repo.UpdateEscrowPaymentIntent(ctx, escrow.ID, "pi_test_refund")
+	repo.UpdateEscrowStatus(ctx, escrow.ID, EscrowFunded)
+	repo.RecordEscrowSettlement(ctx, escrow.ID, amount, amount)
+	repo.UpdateEscrowStatus(ctx, escrow.ID, EscrowReleased)
+	repo.UpdateTransactionStatus(ctx, tx.ID, StatusCompleted)
+	return tx
+}
+
+func TestService_CreateRefund_SellerPartialRefunds(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+
+	tx := newCompletedTransaction(t, repo, 100)
+
+	refund, err := service.CreateRefund(ctx, tx.ID, tx.SellerID, &RefundRequest{Amount: 30, Reason: "One item was missing"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if refund.Status != RefundCompleted || refund.StripeRefundID == nil {
+		t.Errorf("expected the refund paid through Stripe, got %s", refund.Status)
+	}
+	if tx.Status != StatusCompleted {
+		t.Errorf("expected a partial refund to leave the transaction completed, got %s", tx.Status)
+	}
+
+	// Refunds never add up to more than was paid out
+	if _, err := service.CreateRefund(ctx, tx.ID, tx.SellerID, &RefundRequest{Amount: 70.01, Reason: "Too much"}); err != ErrRefundExceedsPaid {
+		t.Errorf("expected ErrRefundExceedsPaid, got %v", err)
+	}
+
+	// Without an amount, everything left is refunded
+	refund, err = service.CreateRefund(ctx, tx.ID, tx.SellerID, &RefundRequest{Reason: "Order cancelled"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if refund.Amount != 70 {
+		t.Errorf("expected the remaining 70 refunded, got %v", refund.Amount)
+	}
+	if len(payment.refundAmounts) != 2 || payment.refundAmounts[0] != 30 || payment.refundAmounts[1] != 70 {
+		t.Errorf("expected Stripe refunds of 30 and 70, got %v", payment.refundAmounts)
+	}
+	if tx.Status != StatusRefunded {
+		t.Errorf("expected the transaction refunded, got %s", tx.Status)
+	}
+
+	got, err := service.GetTransaction(ctx, tx.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Refunds) != 2 || got.RefundedAmount != 100 {
+		t.Errorf("expected 2 refunds totalling 100, got %d totalling %v", len(got.Refunds), got.RefundedAmount)
+	}
+
+	// Stripe reporting the charge refunded afterwards changes nothing
+	if err := service.RefundTransaction(ctx, tx.ID); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestService_CreateRefund_Validation(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+
+	tx := newCompletedTransaction(t, repo, 100)
+
+	if _, err := service.CreateRefund(ctx, tx.ID, tx.SellerID, &RefundRequest{Amount: 10}); err != ErrRefundReasonRequired {
+		t.Errorf("expected ErrRefundReasonRequired, got %v", err)
+	}
+	if _, err := service.CreateRefund(ctx, tx.ID, tx.SellerID, &RefundRequest{Amount: -5, Reason: "Broken"}); err != ErrInvalidRefundAmount {
+		t.Errorf("expected ErrInvalidRefundAmount, got %v", err)
+	}
+	if _, err := service.CreateRefund(ctx, tx.ID, uuid.New(), &RefundRequest{Amount: 10, Reason: "Broken"}); err != ErrNotAuthorized {
+		t.Errorf("expected ErrNotAuthorized, got %v", err)
+	}
+
+	// Only completed transactions are refunded this way
+	repo.UpdateTransactionStatus(ctx, tx.ID, StatusDelivered)
+	if _, err := service.CreateRefund(ctx, tx.ID, tx.SellerID, &RefundRequest{Amount: 10, Reason: "Broken"}); err != ErrInvalidStatus {
+		t.Errorf("expected ErrInvalidStatus, got %v", err)
+	}
+}
+
+func TestService_RefundRequest_AcceptAndReject(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+
+	tx := newCompletedTransaction(t, repo, 100)
+
+	requested, err := service.CreateRefund(ctx, tx.ID, tx.BuyerID, &RefundRequest{Amount: 40, Reason: "Arrived damaged"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requested.Status != RefundRequested || len(payment.refunded) != 0 {
+		t.Errorf("expected a request waiting for the seller, got %s", requested.Status)
+	}
+
+	// One open request at a time
+	if _, err := service.CreateRefund(ctx, tx.ID, tx.BuyerID, &RefundRequest{Amount: 10, Reason: "Also late"}); err != ErrRefundRequestOpen {
+		t.Errorf("expected ErrRefundRequestOpen, got %v", err)
+	}
+	// Only the seller answers it
+	if _, err := service.AcceptRefund(ctx, tx.ID, requested.ID, tx.BuyerID); err != ErrNotAuthorized {
+		t.Errorf("expected ErrNotAuthorized, got %v", err)
+	}
+
+	rejected, err := service.RejectRefund(ctx, tx.ID, requested.ID, tx.SellerID, &RejectRefundRequest{Note: "Photos show it intact"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rejected.Status != RefundRejected || rejected.ResponseNote != "Photos show it intact" {
+		t.Errorf("expected the request rejected with a note, got %s", rejected.Status)
+	}
+	if _, err := service.AcceptRefund(ctx, tx.ID, requested.ID, tx.SellerID); err != ErrRefundNotRequested {
+		t.Errorf("expected ErrRefundNotRequested, got %v", err)
+	}
+
+	// A rejected request doesn't block a new one
+	requested, err = service.CreateRefund(ctx, tx.ID, tx.BuyerID, &RefundRequest{Amount: 25, Reason: "Arrived damaged, see new photos"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	accepted, err := service.AcceptRefund(ctx, tx.ID, requested.ID, tx.SellerID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if accepted.Status != RefundCompleted || accepted.CompletedAt == nil {
+		t.Errorf("expected the request paid, got %s", accepted.Status)
+	}
+	if len(payment.refundAmounts) != 1 || payment.refundAmounts[0] != 25 {
+		t.Errorf("expected one Stripe refund of 25, got %v", payment.refundAmounts)
+	}
+
+	refunds, err := service.GetRefunds(ctx, tx.ID, tx.BuyerID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(refunds) != 2 {
+		t.Errorf("expected 2 refunds, got %d", len(refunds))
+	}
+}
+
+func TestService_CreateRefund_NoPayment(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	service := NewService(repo, nil)
+
+	tx := newCompletedTransaction(t, repo, 100)
+
+	// A card payment can't be refunded without the payment service
+	if _, err := service.CreateRefund(ctx, tx.ID, tx.SellerID, &RefundRequest{Amount: 30, Reason: "One item was missing"}); err != ErrRefundNotPayable {
+		t.Fatalf("expected ErrRefundNotPayable, got %v", err)
+	}
+	requested, err := service.CreateRefund(ctx, tx.ID, tx.BuyerID, &RefundRequest{Amount: 40, Reason: "Arrived damaged"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.AcceptRefund(ctx, tx.ID, requested.ID, tx.SellerID); err != ErrRefundNotPayable {
+		t.Fatalf("expected ErrRefundNotPayable, got %v", err)
+	}
+
+	got, _ := service.GetTransaction(ctx, tx.ID)
+	if len(got.Refunds) != 2 || got.Refunds[0].Status != RefundFailed || got.Refunds[1].Status != RefundRequested {
+		t.Errorf("expected a failed refund and the request still open, got %d refunds", len(got.Refunds))
+	}
+	if got.RefundedAmount != 0 || got.Status != StatusCompleted {
+		t.Errorf("expected nothing refunded, got %v and status %s", got.RefundedAmount, got.Status)
+	}
+}
+
+func TestService_CreateRefund_LedgerFailureRetried(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockRepository()
+	payment := newMockPaymentService()
+	ledger := newMockLedger()
+	service := NewService(repo, nil)
+	service.SetPaymentService(payment)
+	service.SetLedger(ledger)
+
+	tx := newCompletedTransaction(t, repo, 100)
+
+	// Stripe refunds but the ledger can't record it: the refund stays processing
+	ledger.refundErr = errors.New("ledger unavailable")
+	refund, err := service.CreateRefund(ctx, tx.ID, tx.SellerID, &RefundRequest{Amount: 60, Reason: "Wrong size"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if refund.Status != RefundProcessing || refund.StripeRefundID == nil {
+		t.Errorf("expected the refund paid and processing, got %s", refund.Status)
+	}
+
+	// A refund being paid still counts against what's left
+	if _, err := service.CreateRefund(ctx, tx.ID, tx.SellerID, &RefundRequest{Amount: 50, Reason: "Too much"}); err != ErrRefundExceedsPaid {
+		t.Errorf("expected ErrRefundExceedsPaid, got %v", err)
+	}
+
+	// The worker leaves fresh refunds alone, then finishes stale ones
+	ledger.refundErr = nil
+	if completed, _ := service.ProcessRefunds(ctx, time.Now()); completed != 0 {
+		t.Errorf("expected a fresh refund left alone, got %d completed", completed)
+	}
+	completed, err := service.ProcessRefunds(ctx, time.Now().Add(refundRetryDelay+time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if completed != 1 {
+		t.Fatalf("expected 1 refund completed, got %d", completed)
+	}
+	if len(payment.refunded) != 1 || ledger.refunds[refund.ID] != 60 {
+		t.Errorf("expected one Stripe refund and the ledger entry, got %v and %v", payment.refundAmounts, ledger.refunds)
+	}
+
+	got, _ := service.GetTransaction(ctx, tx.ID)
+	if got.RefundedAmount != 60 || got.Refunds[0].CompletedAt == nil {
+		t.Errorf("expected 60 refunded, got %v", got.RefundedAmount)
+	}
+}
+
 func TestRepositoryErrors(t *testing.T) {
 	if ErrTransactionNotFound.Error() != "transaction not found" {
 		t.Errorf("unexpected error message: %s", ErrTransactionNotFound.Error())
@@ -0,0 +1,83 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+
+	"github.com/digi604/swarmmarket/backend/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// FundEscrowFromWallet funds a transaction's escrow from the buyer's wallet
+// instead of their card. The money moves into escrow in the ledger right
+// away, so the transaction is funded without waiting for Stripe. A card
+// payment started earlier for the transaction is cancelled.
+func (s *Service) FundEscrowFromWallet(ctx context.Context, transactionID, buyerID uuid.UUID) (*Transaction, error) {
+	tx, err := s.repo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.BuyerID != buyerID {
+		return nil, ErrNotAuthorized
+	}
+	if tx.Status != StatusPending {
+		return nil, ErrInvalidStatus
+	}
+	if s.ledger == nil {
+		return nil, errors.New("wallet not configured")
+	}
+
+	escrow, err := s.repo.GetEscrowByTransactionID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if escrow.StripePaymentIntentID != nil && *escrow.StripePaymentIntentID != "" {
+		if s.payment != nil {
+			if err := s.payment.CancelPayment(ctx, *escrow.StripePaymentIntentID); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.repo.UpdateEscrowPaymentIntent(ctx, escrow.ID, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.ledger.HoldEscrow(ctx, transactionID, tx.BuyerID, tx.Amount, tx.Currency, true); err != nil {
+		return nil, err
+	}
+	if err := s.markEscrowFunded(ctx, tx, ""); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTransactionByID(ctx, transactionID)
+}
+
+// settleInLedger records money leaving a transaction's escrow in the wallet
+// ledger: released to the seller, refunded to the buyer. For escrow funded
+// from the buyer's wallet the ledger is where the money moves, so a failure
+// is returned; for a card payment Stripe has already moved it, so a failure
+// is only logged.
+func (s *Service) settleInLedger(ctx context.Context, escrow *EscrowAccount, sellerID uuid.UUID, released, refunded float64) error {
+	if s.ledger == nil {
+		return nil
+	}
+	err := s.ledger.SettleEscrow(ctx, escrow.TransactionID, sellerID, released, refunded)
+	if err == nil {
+		return nil
+	}
+	if escrow.fundedFromWallet() {
+		return err
+	}
+	logger.Error("ledger_settlement_failed", map[string]interface{}{
+		"transaction_id": escrow.TransactionID.String(),
+		"released":       released,
+		"refunded":       refunded,
+		"error":          err.Error(),
+	})
+	return nil
+}
+
+// fundedFromWallet reports whether an escrow was funded from the buyer's
+// wallet, so there is no Stripe payment behind it.
+func (e *EscrowAccount) fundedFromWallet() bool {
+	return e.FundedAt != nil && (e.StripePaymentIntentID == nil || *e.StripePaymentIntentID == "")
+}
@@ -210,6 +210,28 @@ func (r *Repository) GetConnectAccountIDForAgent(ctx context.Context, agentID uu
 	return *accountID, nil
 }
 
+// GetConnectAccountIDForUser returns a user's stripe_connect_account_id.
+// Returns empty string if the user has no Connect account that can be paid.
+func (r *Repository) GetConnectAccountIDForUser(ctx context.Context, userID uuid.UUID) (string, error) {
+	var accountID *string
+	err := r.pool.QueryRow(ctx, `
+		SELECT stripe_connect_account_id
+		FROM users
+		WHERE id = $1
+		  AND stripe_connect_charges_enabled = true
+	`, userID).Scan(&accountID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to resolve connect account for user: %w", err)
+	}
+	if accountID == nil {
+		return "", nil
+	}
+	return *accountID, nil
+}
+
 // SetStripeCustomerID stores the Stripe Customer ID for a user.
 func (r *Repository) SetStripeCustomerID(ctx context.Context, userID uuid.UUID, customerID string) error {
 	result, err := r.pool.Exec(ctx,
@@ -0,0 +1,45 @@
+package wallet
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// RepositoryInterface defines the contract for wallet ledger persistence.
+// This interface enables mock implementations for testing.
+type RepositoryInterface interface {
+	// Account Operations
+	GetOrCreateAccount(ctx context.Context, ownerType OwnerType, ownerID *uuid.UUID, kind AccountKind, currency string) (*Account, error)
+	GetWalletAccounts(ctx context.Context, owner Owner) ([]*Account, error)
+	GetAccountBalance(ctx context.Context, accountID uuid.UUID) (float64, error)
+	GetHeldFromAccount(ctx context.Context, accountID uuid.UUID) (float64, error)
+	GetStatement(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*StatementLine, int, error)
+
+	// Journal Operations
+	PostEntry(ctx context.Context, entry *Entry) error
+
+	// Hold Operations
+	GetHoldByTransactionID(ctx context.Context, transactionID uuid.UUID) (*Hold, error)
+	CreateHold(ctx context.Context, hold *Hold, entry *Entry) error
+	SettleHold(ctx context.Context, holdID uuid.UUID, amount float64, entries []*Entry) error
+
+	// Deposit Operations
+	CreateDeposit(ctx context.Context, d *Deposit) error
+	SetDepositPaymentIntent(ctx context.Context, id uuid.UUID, paymentIntentID, clientSecret string) error
+	GetDeposit(ctx context.Context, id uuid.UUID) (*Deposit, error)
+	CompleteDeposit(ctx context.Context, id uuid.UUID, entry *Entry) (bool, error)
+	FailDeposit(ctx context.Context, id uuid.UUID, reason string) error
+
+	// Withdrawal Operations
+	CreateWithdrawal(ctx context.Context, w *Withdrawal, entry *Entry) error
+	CompleteWithdrawal(ctx context.Context, id uuid.UUID, transferID string) error
+	FailWithdrawal(ctx context.Context, id uuid.UUID, reason string, reversal *Entry) error
+
+	// Reconciliation Operations
+	GetAccountBalances(ctx context.Context) ([]*AccountBalance, error)
+	GetUnbalancedEntries(ctx context.Context) ([]uuid.UUID, error)
+	GetOpenHoldsTotal(ctx context.Context) (float64, error)
+	GetDepositTotals(ctx context.Context) (recorded, posted float64, err error)
+	GetWithdrawalTotals(ctx context.Context) (recorded, posted float64, err error)
+}
@@ -0,0 +1,214 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OwnerType is who an account belongs to.
+type OwnerType string
+
+const (
+	OwnerAgent    OwnerType = "agent"
+	OwnerUser     OwnerType = "user"
+	OwnerPlatform OwnerType = "platform"
+)
+
+// AccountKind is what an account holds.
+type AccountKind string
+
+const (
+	AccountWallet AccountKind = "wallet" // An agent's or user's balance
+	AccountEscrow AccountKind = "escrow" // Held for open transactions
+	AccountFees   AccountKind = "fees"   // Platform fees earned
+	AccountStripe AccountKind = "stripe" // Money moving in and out through Stripe
+)
+
+// EntryKind is the business event a journal entry records.
+type EntryKind string
+
+const (
+	EntryDeposit            EntryKind = "deposit"
+	EntryWithdrawal         EntryKind = "withdrawal"
+	EntryWithdrawalReversal EntryKind = "withdrawal_reversal"
+	EntryEscrowHold         EntryKind = "escrow_hold"
+	EntryEscrowRelease      EntryKind = "escrow_release"
+	EntryPayout             EntryKind = "payout"
+	EntryRefund             EntryKind = "refund"
+	EntryPenalty            EntryKind = "penalty"
+	EntryPenaltyReversal    EntryKind = "penalty_reversal"
+)
+
+// DepositStatus is the state of a wallet deposit.
+type DepositStatus string
+
+const (
+	DepositPending   DepositStatus = "pending"
+	DepositCompleted DepositStatus = "completed"
+	DepositFailed    DepositStatus = "failed"
+)
+
+// WithdrawalStatus is the state of a wallet withdrawal.
+type WithdrawalStatus string
+
+const (
+	WithdrawalPending   WithdrawalStatus = "pending"
+	WithdrawalCompleted WithdrawalStatus = "completed"
+	WithdrawalFailed    WithdrawalStatus = "failed"
+)
+
+// HoldStatus is the state of an escrow hold.
+type HoldStatus string
+
+const (
+	HoldHeld    HoldStatus = "held"
+	HoldSettled HoldStatus = "settled"
+)
+
+// Owner identifies whose wallet an operation is on.
+type Owner struct {
+	Type OwnerType `json:"type"`
+	ID   uuid.UUID `json:"id"`
+}
+
+// Account is a ledger account. Its balance is the sum of its postings.
+type Account struct {
+	ID        uuid.UUID   `json:"id"`
+	OwnerType OwnerType   `json:"owner_type"`
+	OwnerID   *uuid.UUID  `json:"owner_id,omitempty"`
+	Kind      AccountKind `json:"kind"`
+	Currency  string      `json:"currency"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Entry is an immutable journal entry. Its postings always sum to zero.
+type Entry struct {
+	ID            uuid.UUID  `json:"id"`
+	Kind          EntryKind  `json:"kind"`
+	Description   string     `json:"description"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty"`
+	DepositID     *uuid.UUID `json:"deposit_id,omitempty"`
+	WithdrawalID  *uuid.UUID `json:"withdrawal_id,omitempty"`
+	Postings      []Posting  `json:"postings"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Posting moves an amount into (positive) or out of (negative) an account.
+type Posting struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Amount    float64   `json:"amount"`
+}
+
+// Hold is the money a transaction has in escrow and the account it came from.
+type Hold struct {
+	ID              uuid.UUID  `json:"id"`
+	TransactionID   uuid.UUID  `json:"transaction_id"`
+	SourceAccountID uuid.UUID  `json:"source_account_id"`
+	Currency        string     `json:"currency"`
+	Amount          float64    `json:"amount"`
+	Remaining       float64    `json:"remaining"`
+	Status          HoldStatus `json:"status"`
+	CreatedAt       time.Time  `json:"created_at"`
+	SettledAt       *time.Time `json:"settled_at,omitempty"`
+}
+
+// Balance is a wallet's balance in one currency.
+type Balance struct {
+	Currency  string  `json:"currency"`
+	Available float64 `json:"available"`
+	Held      float64 `json:"held"` // In escrow for the owner's open purchases
+}
+
+// StatementLine is one journal entry as seen from a single account.
+type StatementLine struct {
+	EntryID       uuid.UUID  `json:"entry_id"`
+	Kind          EntryKind  `json:"kind"`
+	Description   string     `json:"description"`
+	Amount        float64    `json:"amount"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// StatementResult is a paginated wallet statement.
+type StatementResult struct {
+	Currency string           `json:"currency"`
+	Items    []*StatementLine `json:"items"`
+	Total    int              `json:"total"`
+	Limit    int              `json:"limit"`
+	Offset   int              `json:"offset"`
+}
+
+// Deposit adds money to a wallet through a Stripe payment.
+type Deposit struct {
+	ID                    uuid.UUID     `json:"id"`
+	Owner                 Owner         `json:"owner"`
+	Amount                float64       `json:"amount"`
+	Currency              string        `json:"currency"`
+	StripePaymentIntentID string        `json:"stripe_payment_intent_id,omitempty"`
+	ClientSecret          string        `json:"client_secret,omitempty"`
+	Status                DepositStatus `json:"status"`
+	FailureReason         string        `json:"failure_reason,omitempty"`
+	CreatedAt             time.Time     `json:"created_at"`
+	CompletedAt           *time.Time    `json:"completed_at,omitempty"`
+}
+
+// Withdrawal pays money out of a wallet to the owner's Connect account.
+type Withdrawal struct {
+	ID               uuid.UUID        `json:"id"`
+	Owner            Owner            `json:"owner"`
+	Amount           float64          `json:"amount"`
+	Currency         string           `json:"currency"`
+	StripeTransferID string           `json:"stripe_transfer_id,omitempty"`
+	Status           WithdrawalStatus `json:"status"`
+	FailureReason    string           `json:"failure_reason,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+	CompletedAt      *time.Time       `json:"completed_at,omitempty"`
+}
+
+// DepositRequest is the request body for a deposit.
+type DepositRequest struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+// WithdrawRequest is the request body for a withdrawal.
+type WithdrawRequest struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+// AccountBalance is an account and its balance, for reconciliation.
+type AccountBalance struct {
+	Account
+	Balance float64 `json:"balance"`
+}
+
+// ReconciliationReport checks that the ledger is consistent with itself and
+// with the deposits, withdrawals and escrow holds it records.
+type ReconciliationReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Balanced    bool      `json:"balanced"`
+
+	// The sum of all postings; zero in a balanced ledger
+	TrialBalance float64 `json:"trial_balance"`
+	// Entries whose postings don't sum to zero
+	UnbalancedEntries []uuid.UUID `json:"unbalanced_entries"`
+
+	// Money in the escrow account against what open holds say is held
+	EscrowBalance float64 `json:"escrow_balance"`
+	HeldInEscrow  float64 `json:"held_in_escrow"`
+
+	// Deposits and withdrawals on record against what the journal posted
+	DepositsRecorded    float64 `json:"deposits_recorded"`
+	DepositsPosted      float64 `json:"deposits_posted"`
+	WithdrawalsRecorded float64 `json:"withdrawals_recorded"`
+	WithdrawalsPosted   float64 `json:"withdrawals_posted"`
+
+	// Wallets with a negative balance
+	OverdrawnAccounts []*AccountBalance `json:"overdrawn_accounts"`
+
+	WalletTotal float64  `json:"wallet_total"`
+	FeesEarned  float64  `json:"fees_earned"`
+	Mismatches  []string `json:"mismatches"`
+}
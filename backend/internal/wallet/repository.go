@@ -0,0 +1,571 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrDepositNotFound    = errors.New("deposit not found")
+	ErrWithdrawalNotFound = errors.New("withdrawal not found")
+	ErrHoldExceeded       = errors.New("settlement exceeds the amount held in escrow")
+	ErrDuplicateEntry     = errors.New("journal entry already posted")
+)
+
+// Repository handles wallet ledger database operations.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository creates a new wallet repository.
+func NewRepository(pool *pgxpool.Pool) *Repository {
+	return &Repository{pool: pool}
+}
+
+var _ RepositoryInterface = (*Repository)(nil)
+
+// --- Accounts ---
+
+// GetOrCreateAccount returns the account of a kind an owner has in a
+// currency, creating it on first use. Platform accounts have no owner ID.
+func (r *Repository) GetOrCreateAccount(ctx context.Context, ownerType OwnerType, ownerID *uuid.UUID, kind AccountKind, currency string) (*Account, error) {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO ledger_accounts (id, owner_type, owner_id, kind, currency)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+	`, uuid.New(), ownerType, ownerID, kind, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ledger account: %w", err)
+	}
+
+	var a Account
+	err = r.pool.QueryRow(ctx, `
+		SELECT id, owner_type, owner_id, kind, currency, created_at
+		FROM ledger_accounts
+		WHERE owner_type = $1 AND owner_id IS NOT DISTINCT FROM $2 AND kind = $3 AND currency = $4
+	`, ownerType, ownerID, kind, currency).Scan(&a.ID, &a.OwnerType, &a.OwnerID, &a.Kind, &a.Currency, &a.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger account: %w", err)
+	}
+	return &a, nil
+}
+
+// GetWalletAccounts returns an owner's wallet accounts, one per currency.
+func (r *Repository) GetWalletAccounts(ctx context.Context, owner Owner) ([]*Account, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, owner_type, owner_id, kind, currency, created_at
+		FROM ledger_accounts
+		WHERE owner_type = $1 AND owner_id = $2 AND kind = $3
+		ORDER BY currency
+	`, owner.Type, owner.ID, AccountWallet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*Account
+	for rows.Next() {
+		var a Account
+		if err := rows.Scan(&a.ID, &a.OwnerType, &a.OwnerID, &a.Kind, &a.Currency, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet account: %w", err)
+		}
+		accounts = append(accounts, &a)
+	}
+	return accounts, rows.Err()
+}
+
+// GetAccountBalance returns the sum of an account's postings.
+func (r *Repository) GetAccountBalance(ctx context.Context, accountID uuid.UUID) (float64, error) {
+	var balance float64
+	err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM ledger_postings WHERE account_id = $1
+	`, accountID).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get account balance: %w", err)
+	}
+	return balance, nil
+}
+
+// GetHeldFromAccount returns how much of the money an account put into
+// escrow is still held there.
+func (r *Repository) GetHeldFromAccount(ctx context.Context, accountID uuid.UUID) (float64, error) {
+	var held float64
+	err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(remaining), 0) FROM ledger_holds WHERE source_account_id = $1 AND status = $2
+	`, accountID, HoldHeld).Scan(&held)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get held amount: %w", err)
+	}
+	return held, nil
+}
+
+// GetStatement returns an account's postings, newest first, with the total count.
+func (r *Repository) GetStatement(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*StatementLine, int, error) {
+	var total int
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM ledger_postings WHERE account_id = $1`, accountID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count statement lines: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT e.id, e.kind, e.description, p.amount, e.transaction_id, p.created_at
+		FROM ledger_postings p
+		JOIN ledger_entries e ON e.id = p.entry_id
+		WHERE p.account_id = $1
+		ORDER BY p.created_at DESC, p.id
+		LIMIT $2 OFFSET $3
+	`, accountID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get statement: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []*StatementLine{}
+	for rows.Next() {
+		var l StatementLine
+		if err := rows.Scan(&l.EntryID, &l.Kind, &l.Description, &l.Amount, &l.TransactionID, &l.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan statement line: %w", err)
+		}
+		lines = append(lines, &l)
+	}
+	return lines, total, rows.Err()
+}
+
+// --- Journal ---
+
+// PostEntry records a journal entry and its postings.
+func (r *Repository) PostEntry(ctx context.Context, entry *Entry) error {
+	return r.inTx(ctx, func(dbTx pgx.Tx) error {
+		return postEntry(ctx, dbTx, entry)
+	})
+}
+
+// postEntry inserts an entry within a database transaction. Wallets can't go
+// negative: each wallet the entry takes money from is locked and checked, so
+// concurrent entries can't spend the same balance twice. An entry whose ID is
+// already in the journal returns ErrDuplicateEntry.
+func postEntry(ctx context.Context, dbTx pgx.Tx, entry *Entry) error {
+	if !entry.balanced() {
+		return ErrUnbalancedEntry
+	}
+
+	var posted bool
+	err := dbTx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM ledger_entries WHERE id = $1)
+	`, entry.ID).Scan(&posted)
+	if err != nil {
+		return fmt.Errorf("failed to check ledger entry: %w", err)
+	}
+	if posted {
+		return ErrDuplicateEntry
+	}
+
+	for _, p := range entry.Postings {
+		if p.Amount >= 0 {
+			continue
+		}
+		// Only wallets are locked; platform accounts may go negative
+		var isWallet bool
+		err := dbTx.QueryRow(ctx, `
+			SELECT true FROM ledger_accounts WHERE id = $1 AND kind = $2 FOR UPDATE
+		`, p.AccountID, AccountWallet).Scan(&isWallet)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock ledger account: %w", err)
+		}
+		var balance float64
+		err = dbTx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(amount), 0) FROM ledger_postings WHERE account_id = $1
+		`, p.AccountID).Scan(&balance)
+		if err != nil {
+			return fmt.Errorf("failed to get account balance: %w", err)
+		}
+		if toCents(balance)+toCents(p.Amount) < 0 {
+			return ErrInsufficientFunds
+		}
+	}
+
+	_, err = dbTx.Exec(ctx, `
+		INSERT INTO ledger_entries (id, kind, description, transaction_id, deposit_id, withdrawal_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, entry.ID, entry.Kind, entry.Description, entry.TransactionID, entry.DepositID, entry.WithdrawalID, entry.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return ErrDuplicateEntry
+		}
+		return fmt.Errorf("failed to insert ledger entry: %w", err)
+	}
+
+	for _, p := range entry.Postings {
+		_, err := dbTx.Exec(ctx, `
+			INSERT INTO ledger_postings (id, entry_id, account_id, amount, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`, uuid.New(), entry.ID, p.AccountID, p.Amount, entry.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert ledger posting: %w", err)
+		}
+	}
+	return nil
+}
+
+// --- Holds ---
+
+// GetHoldByTransactionID returns a transaction's escrow hold, or nil if it has none.
+func (r *Repository) GetHoldByTransactionID(ctx context.Context, transactionID uuid.UUID) (*Hold, error) {
+	var h Hold
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, transaction_id, source_account_id, currency, amount, remaining, status, created_at, settled_at
+		FROM ledger_holds
+		WHERE transaction_id = $1
+	`, transactionID).Scan(&h.ID, &h.TransactionID, &h.SourceAccountID, &h.Currency, &h.Amount, &h.Remaining, &h.Status,
+		&h.CreatedAt, &h.SettledAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+	return &h, nil
+}
+
+// CreateHold records an escrow hold together with the entry that moved the
+// money into escrow.
+func (r *Repository) CreateHold(ctx context.Context, hold *Hold, entry *Entry) error {
+	return r.inTx(ctx, func(dbTx pgx.Tx) error {
+		if err := postEntry(ctx, dbTx, entry); err != nil {
+			return err
+		}
+		_, err := dbTx.Exec(ctx, `
+			INSERT INTO ledger_holds (id, transaction_id, source_account_id, currency, amount, remaining, status,
+				created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		`, hold.ID, hold.TransactionID, hold.SourceAccountID, hold.Currency, hold.Amount, hold.Remaining, hold.Status, hold.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert hold: %w", err)
+		}
+		return nil
+	})
+}
+
+// SettleHold takes amount off a hold and records the entries that moved it
+// out of escrow. The hold is settled once nothing remains.
+func (r *Repository) SettleHold(ctx context.Context, holdID uuid.UUID, amount float64, entries []*Entry) error {
+	return r.inTx(ctx, func(dbTx pgx.Tx) error {
+		var remaining float64
+		err := dbTx.QueryRow(ctx, `SELECT remaining FROM ledger_holds WHERE id = $1 FOR UPDATE`, holdID).Scan(&remaining)
+		if err != nil {
+			return fmt.Errorf("failed to lock hold: %w", err)
+		}
+		if toCents(amount) > toCents(remaining) {
+			return ErrHoldExceeded
+		}
+
+		for _, entry := range entries {
+			if err := postEntry(ctx, dbTx, entry); err != nil {
+				return err
+			}
+		}
+
+		_, err = dbTx.Exec(ctx, `
+			UPDATE ledger_holds
+			SET remaining = remaining - $1,
+				status = CASE WHEN remaining - $1 <= 0 THEN $2 ELSE status END,
+				settled_at = CASE WHEN remaining - $1 <= 0 THEN NOW() ELSE settled_at END,
+				updated_at = NOW()
+			WHERE id = $3
+		`, amount, HoldSettled, holdID)
+		if err != nil {
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+		return nil
+	})
+}
+
+// --- Deposits ---
+
+// CreateDeposit inserts a pending deposit.
+func (r *Repository) CreateDeposit(ctx context.Context, d *Deposit) error {
+	userID, agentID := ownerColumns(d.Owner)
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO wallet_deposits (id, user_id, agent_id, amount, currency, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+	`, d.ID, userID, agentID, d.Amount, d.Currency, d.Status, d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create deposit: %w", err)
+	}
+	return nil
+}
+
+// SetDepositPaymentIntent stores the Stripe payment that funds a deposit.
+func (r *Repository) SetDepositPaymentIntent(ctx context.Context, id uuid.UUID, paymentIntentID, clientSecret string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE wallet_deposits
+		SET stripe_payment_intent_id = $1, stripe_client_secret = $2, updated_at = NOW()
+		WHERE id = $3
+	`, paymentIntentID, clientSecret, id)
+	if err != nil {
+		return fmt.Errorf("failed to update deposit: %w", err)
+	}
+	return nil
+}
+
+// GetDeposit retrieves a deposit by ID.
+func (r *Repository) GetDeposit(ctx context.Context, id uuid.UUID) (*Deposit, error) {
+	var d Deposit
+	var userID, agentID *uuid.UUID
+	var paymentIntentID, clientSecret, failureReason *string
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, user_id, agent_id, amount, currency, stripe_payment_intent_id, stripe_client_secret,
+			status, failure_reason, created_at, completed_at
+		FROM wallet_deposits
+		WHERE id = $1
+	`, id).Scan(&d.ID, &userID, &agentID, &d.Amount, &d.Currency, &paymentIntentID, &clientSecret,
+		&d.Status, &failureReason, &d.CreatedAt, &d.CompletedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDepositNotFound
+		}
+		return nil, fmt.Errorf("failed to get deposit: %w", err)
+	}
+	d.Owner = ownerFromColumns(userID, agentID)
+	if paymentIntentID != nil {
+		d.StripePaymentIntentID = *paymentIntentID
+	}
+	if clientSecret != nil {
+		d.ClientSecret = *clientSecret
+	}
+	if failureReason != nil {
+		d.FailureReason = *failureReason
+	}
+	return &d, nil
+}
+
+// CompleteDeposit marks a pending deposit completed and records the entry
+// that credits the wallet. It reports false if the deposit was not pending,
+// so a payment reported twice is only credited once.
+func (r *Repository) CompleteDeposit(ctx context.Context, id uuid.UUID, entry *Entry) (bool, error) {
+	completed := false
+	err := r.inTx(ctx, func(dbTx pgx.Tx) error {
+		result, err := dbTx.Exec(ctx, `
+			UPDATE wallet_deposits
+			SET status = $1, completed_at = NOW(), updated_at = NOW()
+			WHERE id = $2 AND status = $3
+		`, DepositCompleted, id, DepositPending)
+		if err != nil {
+			return fmt.Errorf("failed to complete deposit: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return nil
+		}
+		completed = true
+		return postEntry(ctx, dbTx, entry)
+	})
+	return completed, err
+}
+
+// FailDeposit marks a pending deposit failed.
+func (r *Repository) FailDeposit(ctx context.Context, id uuid.UUID, reason string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE wallet_deposits
+		SET status = $1, failure_reason = $2, updated_at = NOW()
+		WHERE id = $3 AND status = $4
+	`, DepositFailed, reason, id, DepositPending)
+	if err != nil {
+		return fmt.Errorf("failed to fail deposit: %w", err)
+	}
+	return nil
+}
+
+// --- Withdrawals ---
+
+// CreateWithdrawal inserts a pending withdrawal together with the entry that
+// takes the money out of the wallet.
+func (r *Repository) CreateWithdrawal(ctx context.Context, w *Withdrawal, entry *Entry) error {
+	userID, agentID := ownerColumns(w.Owner)
+	return r.inTx(ctx, func(dbTx pgx.Tx) error {
+		_, err := dbTx.Exec(ctx, `
+			INSERT INTO wallet_withdrawals (id, user_id, agent_id, amount, currency, status, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		`, w.ID, userID, agentID, w.Amount, w.Currency, w.Status, w.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create withdrawal: %w", err)
+		}
+		return postEntry(ctx, dbTx, entry)
+	})
+}
+
+// CompleteWithdrawal records the Stripe transfer that paid a withdrawal out.
+func (r *Repository) CompleteWithdrawal(ctx context.Context, id uuid.UUID, transferID string) error {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE wallet_withdrawals
+		SET status = $1, stripe_transfer_id = $2, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $3
+	`, WithdrawalCompleted, transferID, id)
+	if err != nil {
+		return fmt.Errorf("failed to complete withdrawal: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrWithdrawalNotFound
+	}
+	return nil
+}
+
+// FailWithdrawal marks a withdrawal failed and records the entry that puts
+// the money back in the wallet.
+func (r *Repository) FailWithdrawal(ctx context.Context, id uuid.UUID, reason string, reversal *Entry) error {
+	return r.inTx(ctx, func(dbTx pgx.Tx) error {
+		result, err := dbTx.Exec(ctx, `
+			UPDATE wallet_withdrawals
+			SET status = $1, failure_reason = $2, updated_at = NOW()
+			WHERE id = $3 AND status = $4
+		`, WithdrawalFailed, reason, id, WithdrawalPending)
+		if err != nil {
+			return fmt.Errorf("failed to fail withdrawal: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return ErrWithdrawalNotFound
+		}
+		return postEntry(ctx, dbTx, reversal)
+	})
+}
+
+// --- Reconciliation ---
+
+// GetAccountBalances returns every account with its balance.
+func (r *Repository) GetAccountBalances(ctx context.Context) ([]*AccountBalance, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT a.id, a.owner_type, a.owner_id, a.kind, a.currency, a.created_at, COALESCE(SUM(p.amount), 0)
+		FROM ledger_accounts a
+		LEFT JOIN ledger_postings p ON p.account_id = a.id
+		GROUP BY a.id
+		ORDER BY a.owner_type, a.kind, a.currency
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account balances: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []*AccountBalance
+	for rows.Next() {
+		var b AccountBalance
+		if err := rows.Scan(&b.ID, &b.OwnerType, &b.OwnerID, &b.Kind, &b.Currency, &b.CreatedAt, &b.Balance); err != nil {
+			return nil, fmt.Errorf("failed to scan account balance: %w", err)
+		}
+		balances = append(balances, &b)
+	}
+	return balances, rows.Err()
+}
+
+// GetUnbalancedEntries returns the IDs of entries whose postings don't sum to zero.
+func (r *Repository) GetUnbalancedEntries(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT entry_id FROM ledger_postings GROUP BY entry_id HAVING SUM(amount) <> 0
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unbalanced entries: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan entry id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetOpenHoldsTotal returns how much open holds say is in escrow.
+func (r *Repository) GetOpenHoldsTotal(ctx context.Context) (float64, error) {
+	var total float64
+	err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(remaining), 0) FROM ledger_holds WHERE status = $1
+	`, HoldHeld).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get open holds total: %w", err)
+	}
+	return total, nil
+}
+
+// GetDepositTotals returns the total of completed deposits and the total
+// the journal credited to wallets for deposits.
+func (r *Repository) GetDepositTotals(ctx context.Context) (recorded, posted float64, err error) {
+	err = r.pool.QueryRow(ctx, `
+		SELECT
+			(SELECT COALESCE(SUM(amount), 0) FROM wallet_deposits WHERE status = $1),
+			(SELECT COALESCE(SUM(p.amount), 0)
+			 FROM ledger_postings p
+			 JOIN ledger_entries e ON e.id = p.entry_id
+			 JOIN ledger_accounts a ON a.id = p.account_id
+			 WHERE e.kind = $2 AND a.kind = $3)
+	`, DepositCompleted, EntryDeposit, AccountWallet).Scan(&recorded, &posted)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get deposit totals: %w", err)
+	}
+	return recorded, posted, nil
+}
+
+// GetWithdrawalTotals returns the total of withdrawals that were not
+// reversed and the total the journal took out of wallets for withdrawals.
+func (r *Repository) GetWithdrawalTotals(ctx context.Context) (recorded, posted float64, err error) {
+	err = r.pool.QueryRow(ctx, `
+		SELECT
+			(SELECT COALESCE(SUM(amount), 0) FROM wallet_withdrawals WHERE status <> $1),
+			(SELECT COALESCE(-SUM(p.amount), 0)
+			 FROM ledger_postings p
+			 JOIN ledger_entries e ON e.id = p.entry_id
+			 JOIN ledger_accounts a ON a.id = p.account_id
+			 WHERE e.kind IN ($2, $3) AND a.kind = $4)
+	`, WithdrawalFailed, EntryWithdrawal, EntryWithdrawalReversal, AccountWallet).Scan(&recorded, &posted)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get withdrawal totals: %w", err)
+	}
+	return recorded, posted, nil
+}
+
+// --- Helpers ---
+
+// inTx runs fn in a database transaction, committing if it returns nil.
+func (r *Repository) inTx(ctx context.Context, fn func(dbTx pgx.Tx) error) error {
+	dbTx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback(ctx)
+
+	if err := fn(dbTx); err != nil {
+		return err
+	}
+	return dbTx.Commit(ctx)
+}
+
+// ownerColumns splits an owner into the user_id and agent_id columns.
+func ownerColumns(owner Owner) (userID, agentID *uuid.UUID) {
+	id := owner.ID
+	if owner.Type == OwnerUser {
+		return &id, nil
+	}
+	return nil, &id
+}
+
+// ownerFromColumns is the inverse of ownerColumns.
+func ownerFromColumns(userID, agentID *uuid.UUID) Owner {
+	if userID != nil {
+		return Owner{Type: OwnerUser, ID: *userID}
+	}
+	if agentID != nil {
+		return Owner{Type: OwnerAgent, ID: *agentID}
+	}
+	return Owner{}
+}
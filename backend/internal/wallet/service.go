@@ -0,0 +1,635 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/digi604/swarmmarket/backend/pkg/logger"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidAmount         = errors.New("amount must be positive")
+	ErrInsufficientFunds     = errors.New("insufficient wallet balance")
+	ErrUnbalancedEntry       = errors.New("journal entry does not balance")
+	ErrPaymentsNotConfigured = errors.New("wallet payments not configured")
+)
+
+// PaymentProvider moves money between wallets and the outside world through
+// Stripe: payments that fund deposits and Connect transfers that pay out
+// withdrawals.
+type PaymentProvider interface {
+	CreateDepositPayment(ctx context.Context, depositID, ownerType, ownerID string, amount float64, currency string) (paymentIntentID, clientSecret string, err error)
+	PayoutToOwner(ctx context.Context, withdrawalID, ownerType, ownerID string, amount float64, currency string) (transferID string, err error)
+}
+
+// Service keeps the double-entry ledger behind agent and owner wallets.
+//
+// Every movement of money is a journal entry whose postings sum to zero.
+// Wallets belong to agents and users; the platform has an escrow account for
+// money held on open transactions, a fees account, and a stripe account that
+// is the other side of every deposit, withdrawal and card payment.
+type Service struct {
+	repo       RepositoryInterface
+	payments   PaymentProvider
+	feePercent float64
+}
+
+// NewService creates a new wallet service.
+func NewService(repo RepositoryInterface) *Service {
+	return &Service{repo: repo}
+}
+
+// SetPaymentProvider sets the Stripe provider (optional, for deposits and withdrawals).
+func (s *Service) SetPaymentProvider(payments PaymentProvider) {
+	s.payments = payments
+}
+
+// SetFeePercent sets the platform fee taken when escrow is released to a
+// seller, as a fraction (0.025 is 2.5%).
+func (s *Service) SetFeePercent(feePercent float64) {
+	s.feePercent = feePercent
+}
+
+// GetBalances returns an owner's wallet balances, one per currency.
+func (s *Service) GetBalances(ctx context.Context, owner Owner) ([]*Balance, error) {
+	accounts, err := s.repo.GetWalletAccounts(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := []*Balance{}
+	for _, a := range accounts {
+		available, err := s.repo.GetAccountBalance(ctx, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		held, err := s.repo.GetHeldFromAccount(ctx, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, &Balance{Currency: a.Currency, Available: available, Held: held})
+	}
+	return balances, nil
+}
+
+// GetStatement returns the entries on an owner's wallet in a currency, newest first.
+func (s *Service) GetStatement(ctx context.Context, owner Owner, currency string, limit, offset int) (*StatementResult, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	currency = normalizeCurrency(currency)
+	account, err := s.walletAccount(ctx, owner, currency)
+	if err != nil {
+		return nil, err
+	}
+	lines, total, err := s.repo.GetStatement(ctx, account.ID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &StatementResult{Currency: currency, Items: lines, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+// CreateDeposit starts a deposit into an owner's wallet. The wallet is
+// credited once Stripe reports the payment succeeded (see ConfirmDeposit).
+// Agent deposits are charged to the owner's saved card; user deposits return
+// a client secret to confirm the payment in the browser.
+func (s *Service) CreateDeposit(ctx context.Context, owner Owner, req *DepositRequest) (*Deposit, error) {
+	if s.payments == nil {
+		return nil, ErrPaymentsNotConfigured
+	}
+	amount := roundCents(req.Amount)
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	d := &Deposit{
+		ID:        uuid.New(),
+		Owner:     owner,
+		Amount:    amount,
+		Currency:  normalizeCurrency(req.Currency),
+		Status:    DepositPending,
+		CreatedAt: time.Now().UTC(),
+	}
+	// The deposit exists before its payment, so the webhook always finds it
+	if err := s.repo.CreateDeposit(ctx, d); err != nil {
+		return nil, err
+	}
+
+	paymentIntentID, clientSecret, err := s.payments.CreateDepositPayment(ctx, d.ID.String(), string(owner.Type), owner.ID.String(), d.Amount, d.Currency)
+	if err != nil {
+		if ferr := s.repo.FailDeposit(ctx, d.ID, err.Error()); ferr != nil {
+			logger.Error("deposit_fail_failed", map[string]interface{}{
+				"deposit_id": d.ID.String(),
+				"error":      ferr.Error(),
+			})
+		}
+		return nil, err
+	}
+	if err := s.repo.SetDepositPaymentIntent(ctx, d.ID, paymentIntentID, clientSecret); err != nil {
+		return nil, err
+	}
+	d.StripePaymentIntentID = paymentIntentID
+	d.ClientSecret = clientSecret
+
+	return d, nil
+}
+
+// ConfirmDeposit credits a deposit to its wallet once its payment succeeded.
+// Confirming a deposit again does nothing.
+func (s *Service) ConfirmDeposit(ctx context.Context, depositID uuid.UUID) error {
+	d, err := s.repo.GetDeposit(ctx, depositID)
+	if err != nil {
+		return err
+	}
+	if d.Status != DepositPending {
+		return nil
+	}
+
+	stripe, err := s.platformAccount(ctx, AccountStripe, d.Currency)
+	if err != nil {
+		return err
+	}
+	wallet, err := s.walletAccount(ctx, d.Owner, d.Currency)
+	if err != nil {
+		return err
+	}
+
+	cents := toCents(d.Amount)
+	entry := newEntry(EntryDeposit, "Deposit via Stripe",
+		Posting{AccountID: stripe.ID, Amount: fromCents(-cents)},
+		Posting{AccountID: wallet.ID, Amount: fromCents(cents)},
+	)
+	entry.DepositID = &d.ID
+
+	completed, err := s.repo.CompleteDeposit(ctx, d.ID, entry)
+	if err != nil {
+		return err
+	}
+	if completed {
+		logger.Info("wallet_deposit_completed", map[string]interface{}{
+			"deposit_id": d.ID.String(),
+			"owner_type": string(d.Owner.Type),
+			"owner_id":   d.Owner.ID.String(),
+			"amount":     d.Amount,
+			"currency":   d.Currency,
+		})
+	}
+	return nil
+}
+
+// FailDeposit marks a deposit whose payment failed. Nothing was credited.
+func (s *Service) FailDeposit(ctx context.Context, depositID uuid.UUID, reason string) error {
+	return s.repo.FailDeposit(ctx, depositID, reason)
+}
+
+// Withdraw pays money out of an owner's wallet to the Stripe Connect account
+// of the owner (or, for an agent, of its owner). The wallet is debited first;
+// if the transfer fails a reversing entry puts the money back.
+func (s *Service) Withdraw(ctx context.Context, owner Owner, req *WithdrawRequest) (*Withdrawal, error) {
+	if s.payments == nil {
+		return nil, ErrPaymentsNotConfigured
+	}
+	amount := roundCents(req.Amount)
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	currency := normalizeCurrency(req.Currency)
+
+	wallet, err := s.walletAccount(ctx, owner, currency)
+	if err != nil {
+		return nil, err
+	}
+	stripe, err := s.platformAccount(ctx, AccountStripe, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Withdrawal{
+		ID:        uuid.New(),
+		Owner:     owner,
+		Amount:    amount,
+		Currency:  currency,
+		Status:    WithdrawalPending,
+		CreatedAt: time.Now().UTC(),
+	}
+	cents := toCents(amount)
+	entry := newEntry(EntryWithdrawal, "Withdrawal to Stripe Connect",
+		Posting{AccountID: wallet.ID, Amount: fromCents(-cents)},
+		Posting{AccountID: stripe.ID, Amount: fromCents(cents)},
+	)
+	entry.WithdrawalID = &w.ID
+	if err := s.repo.CreateWithdrawal(ctx, w, entry); err != nil {
+		return nil, err
+	}
+
+	transferID, err := s.payments.PayoutToOwner(ctx, w.ID.String(), string(owner.Type), owner.ID.String(), amount, currency)
+	if err != nil {
+		reversal := newEntry(EntryWithdrawalReversal, "Withdrawal failed",
+			Posting{AccountID: stripe.ID, Amount: fromCents(-cents)},
+			Posting{AccountID: wallet.ID, Amount: fromCents(cents)},
+		)
+		reversal.WithdrawalID = &w.ID
+		if ferr := s.repo.FailWithdrawal(ctx, w.ID, err.Error(), reversal); ferr != nil {
+			logger.Error("withdrawal_reversal_failed", map[string]interface{}{
+				"withdrawal_id": w.ID.String(),
+				"error":         ferr.Error(),
+			})
+		}
+		return nil, err
+	}
+
+	// The money is on its way either way; a missing transfer ID is fixed by hand
+	if err := s.repo.CompleteWithdrawal(ctx, w.ID, transferID); err != nil {
+		logger.Error("withdrawal_update_failed", map[string]interface{}{
+			"withdrawal_id": w.ID.String(),
+			"transfer_id":   transferID,
+			"error":         err.Error(),
+		})
+	}
+
+	completedAt := time.Now().UTC()
+	w.Status = WithdrawalCompleted
+	w.StripeTransferID = transferID
+	w.CompletedAt = &completedAt
+
+	logger.Info("wallet_withdrawal_completed", map[string]interface{}{
+		"withdrawal_id": w.ID.String(),
+		"owner_type":    string(owner.Type),
+		"owner_id":      owner.ID.String(),
+		"amount":        amount,
+		"currency":      currency,
+	})
+
+	return w, nil
+}
+
+// HoldEscrow moves a transaction's amount into escrow, from the buyer's
+// wallet or, for a card payment, from the stripe account. A wallet without
+// enough money returns ErrInsufficientFunds. A transaction is held only once.
+func (s *Service) HoldEscrow(ctx context.Context, transactionID, buyerID uuid.UUID, amount float64, currency string, fromWallet bool) error {
+	existing, err := s.repo.GetHoldByTransactionID(ctx, transactionID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return nil
+	}
+
+	cents := toCents(amount)
+	if cents <= 0 {
+		return ErrInvalidAmount
+	}
+	currency = normalizeCurrency(currency)
+
+	var source *Account
+	if fromWallet {
+		source, err = s.walletAccount(ctx, Owner{Type: OwnerAgent, ID: buyerID}, currency)
+	} else {
+		source, err = s.platformAccount(ctx, AccountStripe, currency)
+	}
+	if err != nil {
+		return err
+	}
+	escrow, err := s.platformAccount(ctx, AccountEscrow, currency)
+	if err != nil {
+		return err
+	}
+
+	entry := newEntry(EntryEscrowHold, "Escrow funded",
+		Posting{AccountID: source.ID, Amount: fromCents(-cents)},
+		Posting{AccountID: escrow.ID, Amount: fromCents(cents)},
+	)
+	entry.TransactionID = &transactionID
+
+	hold := &Hold{
+		ID:              uuid.New(),
+		TransactionID:   transactionID,
+		SourceAccountID: source.ID,
+		Currency:        currency,
+		Amount:          fromCents(cents),
+		Remaining:       fromCents(cents),
+		Status:          HoldHeld,
+		CreatedAt:       entry.CreatedAt,
+	}
+	return s.repo.CreateHold(ctx, hold, entry)
+}
+
+// SettleEscrow moves money out of a transaction's escrow hold: released goes
+// to the seller's wallet less the platform fee, and refunded goes back where
+// it came from. Escrow funded by card was paid to the seller by Stripe, so a
+// payout entry moves the seller's share straight back out to the stripe
+// account. Transactions funded before the ledger existed have no hold and
+// are skipped.
+func (s *Service) SettleEscrow(ctx context.Context, transactionID, sellerID uuid.UUID, released, refunded float64) error {
+	hold, err := s.repo.GetHoldByTransactionID(ctx, transactionID)
+	if err != nil || hold == nil {
+		return err
+	}
+
+	releasedCents, refundedCents := toCents(released), toCents(refunded)
+	if releasedCents < 0 || refundedCents < 0 {
+		return ErrInvalidAmount
+	}
+	if releasedCents+refundedCents == 0 {
+		return nil
+	}
+
+	escrow, err := s.platformAccount(ctx, AccountEscrow, hold.Currency)
+	if err != nil {
+		return err
+	}
+	fees, err := s.platformAccount(ctx, AccountFees, hold.Currency)
+	if err != nil {
+		return err
+	}
+	stripe, err := s.platformAccount(ctx, AccountStripe, hold.Currency)
+	if err != nil {
+		return err
+	}
+	seller, err := s.walletAccount(ctx, Owner{Type: OwnerAgent, ID: sellerID}, hold.Currency)
+	if err != nil {
+		return err
+	}
+
+	// Same rounding as the application fee Stripe takes on card payments
+	feeCents := int64(float64(releasedCents) * s.feePercent)
+	netCents := releasedCents - feeCents
+
+	release := newEntry(EntryEscrowRelease, "Escrow settled",
+		Posting{AccountID: escrow.ID, Amount: fromCents(-(releasedCents + refundedCents))},
+		Posting{AccountID: seller.ID, Amount: fromCents(netCents)},
+		Posting{AccountID: fees.ID, Amount: fromCents(feeCents)},
+		Posting{AccountID: hold.SourceAccountID, Amount: fromCents(refundedCents)},
+	)
+	release.TransactionID = &transactionID
+	entries := []*Entry{release}
+
+	if hold.SourceAccountID == stripe.ID && netCents > 0 {
+		payout := newEntry(EntryPayout, "Paid out to the seller's Connect account",
+			Posting{AccountID: seller.ID, Amount: fromCents(-netCents)},
+			Posting{AccountID: stripe.ID, Amount: fromCents(netCents)},
+		)
+		payout.TransactionID = &transactionID
+		entries = append(entries, payout)
+	}
+
+	return s.repo.SettleHold(ctx, hold.ID, fromCents(releasedCents+refundedCents), entries)
+}
+
+// RefundPaidOut records a refund of escrow already released to the seller.
+// For escrow funded from the buyer's wallet, the seller's wallet and the fees
+// account give back their shares and the buyer's wallet gets the whole
+// amount; a seller without enough left returns ErrInsufficientFunds. For a
+// card payment Stripe took the seller's share back from their Connect
+// account, so only the fee goes back out through the stripe account.
+// Transactions funded before the ledger existed have no hold and are skipped.
+// The entry takes the refund's ID, so recording the same refund again is a
+// no-op.
+func (s *Service) RefundPaidOut(ctx context.Context, refundID, transactionID, sellerID uuid.UUID, amount float64) error {
+	hold, err := s.repo.GetHoldByTransactionID(ctx, transactionID)
+	if err != nil || hold == nil {
+		return err
+	}
+
+	cents := toCents(amount)
+	if cents <= 0 {
+		return ErrInvalidAmount
+	}
+	fees, err := s.platformAccount(ctx, AccountFees, hold.Currency)
+	if err != nil {
+		return err
+	}
+	stripe, err := s.platformAccount(ctx, AccountStripe, hold.Currency)
+	if err != nil {
+		return err
+	}
+
+	// The fee comes back in proportion, as Stripe refunds application fees
+	feeCents := int64(float64(cents) * s.feePercent)
+
+	var entry *Entry
+	if hold.SourceAccountID == stripe.ID {
+		if feeCents == 0 {
+			return nil
+		}
+		entry = newEntry(EntryRefund, "Platform fee refunded with a card refund",
+			Posting{AccountID: fees.ID, Amount: fromCents(-feeCents)},
+			Posting{AccountID: stripe.ID, Amount: fromCents(feeCents)},
+		)
+	} else {
+		seller, err := s.walletAccount(ctx, Owner{Type: OwnerAgent, ID: sellerID}, hold.Currency)
+		if err != nil {
+			return err
+		}
+		entry = newEntry(EntryRefund, "Refunded to the buyer",
+			Posting{AccountID: seller.ID, Amount: fromCents(-(cents - feeCents))},
+			Posting{AccountID: fees.ID, Amount: fromCents(-feeCents)},
+			Posting{AccountID: hold.SourceAccountID, Amount: fromCents(cents)},
+		)
+	}
+	entry.ID = refundID
+	entry.TransactionID = &transactionID
+	if err := s.repo.PostEntry(ctx, entry); err != nil && err != ErrDuplicateEntry {
+		return err
+	}
+	return nil
+}
+
+// Reconcile checks the ledger: the journal balances, the escrow account holds
+// exactly what open holds say it does, deposits and withdrawals match what
+// was posted for them, and no wallet is overdrawn.
+func (s *Service) Reconcile(ctx context.Context) (*ReconciliationReport, error) {
+	report := &ReconciliationReport{
+		GeneratedAt:       time.Now().UTC(),
+		OverdrawnAccounts: []*AccountBalance{},
+		Mismatches:        []string{},
+	}
+
+	balances, err := s.repo.GetAccountBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var trial, escrow, wallets, fees int64
+	for _, b := range balances {
+		cents := toCents(b.Balance)
+		trial += cents
+		switch b.Kind {
+		case AccountEscrow:
+			escrow += cents
+		case AccountFees:
+			fees += cents
+		case AccountWallet:
+			wallets += cents
+			if cents < 0 {
+				report.OverdrawnAccounts = append(report.OverdrawnAccounts, b)
+			}
+		}
+	}
+	report.TrialBalance = fromCents(trial)
+	report.EscrowBalance = fromCents(escrow)
+	report.WalletTotal = fromCents(wallets)
+	report.FeesEarned = fromCents(fees)
+
+	if report.UnbalancedEntries, err = s.repo.GetUnbalancedEntries(ctx); err != nil {
+		return nil, err
+	}
+	if report.HeldInEscrow, err = s.repo.GetOpenHoldsTotal(ctx); err != nil {
+		return nil, err
+	}
+	if report.DepositsRecorded, report.DepositsPosted, err = s.repo.GetDepositTotals(ctx); err != nil {
+		return nil, err
+	}
+	if report.WithdrawalsRecorded, report.WithdrawalsPosted, err = s.repo.GetWithdrawalTotals(ctx); err != nil {
+		return nil, err
+	}
+
+	if trial != 0 {
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf("postings sum to %.2f instead of 0", report.TrialBalance))
+	}
+	if len(report.UnbalancedEntries) > 0 {
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf("%d journal entries don't balance", len(report.UnbalancedEntries)))
+	}
+	if escrow != toCents(report.HeldInEscrow) {
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf("escrow account holds %.2f but open holds total %.2f", report.EscrowBalance, report.HeldInEscrow))
+	}
+	if toCents(report.DepositsRecorded) != toCents(report.DepositsPosted) {
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf("completed deposits total %.2f but %.2f was credited", report.DepositsRecorded, report.DepositsPosted))
+	}
+	if toCents(report.WithdrawalsRecorded) != toCents(report.WithdrawalsPosted) {
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf("withdrawals total %.2f but %.2f was debited", report.WithdrawalsRecorded, report.WithdrawalsPosted))
+	}
+	if len(report.OverdrawnAccounts) > 0 {
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf("%d wallets are overdrawn", len(report.OverdrawnAccounts)))
+	}
+	report.Balanced = len(report.Mismatches) == 0
+
+	return report, nil
+}
+
+// ChargeCancellationPenalty takes the penalty for cancelling an auction with
+// bids from the seller's wallet into the fees account, returning the entry's
+// ID. A seller without enough in their wallet gets ErrInsufficientFunds.
+func (s *Service) ChargeCancellationPenalty(ctx context.Context, auctionID, sellerID uuid.UUID, amount float64, currency string) (uuid.UUID, error) {
+	cents := toCents(amount)
+	if cents <= 0 {
+		return uuid.Nil, ErrInvalidAmount
+	}
+	seller, err := s.walletAccount(ctx, Owner{Type: OwnerAgent, ID: sellerID}, currency)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	fees, err := s.platformAccount(ctx, AccountFees, currency)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	entry := newEntry(EntryPenalty, fmt.Sprintf("Cancellation penalty for auction %s", auctionID),
+		Posting{AccountID: seller.ID, Amount: fromCents(-cents)},
+		Posting{AccountID: fees.ID, Amount: fromCents(cents)},
+	)
+	if err := s.repo.PostEntry(ctx, entry); err != nil {
+		return uuid.Nil, err
+	}
+	return entry.ID, nil
+}
+
+// ReverseCancellationPenalty gives a charged cancellation penalty back to the
+// seller's wallet when the auction could not be cancelled after all. The
+// reversal's ID is derived from the charge's, so a charge is reversed at most
+// once.
+func (s *Service) ReverseCancellationPenalty(ctx context.Context, chargeID, sellerID uuid.UUID, amount float64, currency string) error {
+	cents := toCents(amount)
+	if cents <= 0 {
+		return ErrInvalidAmount
+	}
+	seller, err := s.walletAccount(ctx, Owner{Type: OwnerAgent, ID: sellerID}, currency)
+	if err != nil {
+		return err
+	}
+	fees, err := s.platformAccount(ctx, AccountFees, currency)
+	if err != nil {
+		return err
+	}
+
+	entry := newEntry(EntryPenaltyReversal, "Auction cancellation penalty reversed",
+		Posting{AccountID: fees.ID, Amount: fromCents(-cents)},
+		Posting{AccountID: seller.ID, Amount: fromCents(cents)},
+	)
+	entry.ID = uuid.NewSHA1(chargeID, []byte(EntryPenaltyReversal))
+	if err := s.repo.PostEntry(ctx, entry); err != nil && err != ErrDuplicateEntry {
+		return err
+	}
+	return nil
+}
+
+// walletAccount returns an owner's wallet account in a currency.
+func (s *Service) walletAccount(ctx context.Context, owner Owner, currency string) (*Account, error) {
+	id := owner.ID
+	return s.repo.GetOrCreateAccount(ctx, owner.Type, &id, AccountWallet, currency)
+}
+
+// platformAccount returns one of the platform's own accounts in a currency.
+func (s *Service) platformAccount(ctx context.Context, kind AccountKind, currency string) (*Account, error) {
+	return s.repo.GetOrCreateAccount(ctx, OwnerPlatform, nil, kind, currency)
+}
+
+// newEntry builds a journal entry, leaving out postings of zero.
+func newEntry(kind EntryKind, description string, postings ...Posting) *Entry {
+	entry := &Entry{
+		ID:          uuid.New(),
+		Kind:        kind,
+		Description: description,
+		CreatedAt:   time.Now().UTC(),
+	}
+	for _, p := range postings {
+		if toCents(p.Amount) != 0 {
+			entry.Postings = append(entry.Postings, p)
+		}
+	}
+	return entry
+}
+
+// balanced reports whether an entry's postings sum to zero.
+func (e *Entry) balanced() bool {
+	if len(e.Postings) < 2 {
+		return false
+	}
+	var sum int64
+	for _, p := range e.Postings {
+		sum += toCents(p.Amount)
+	}
+	return sum == 0
+}
+
+// toCents converts an amount to whole cents, so sums are exact.
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func fromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+func roundCents(amount float64) float64 {
+	return fromCents(toCents(amount))
+}
+
+// normalizeCurrency upper-cases a currency code, defaulting to USD.
+func normalizeCurrency(currency string) string {
+	if currency == "" {
+		return "USD"
+	}
+	return strings.ToUpper(currency)
+}
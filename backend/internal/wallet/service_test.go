@@ -0,0 +1,632 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// mockRepository is an in-memory ledger for testing.
+type mockRepository struct {
+	accounts    map[string]*Account
+	entries     []*Entry
+	holds       map[uuid.UUID]*Hold
+	deposits    map[uuid.UUID]*Deposit
+	withdrawals map[uuid.UUID]*Withdrawal
+}
+
+var _ RepositoryInterface = (*mockRepository)(nil)
+
+func newMockRepository() *mockRepository {
+	return &mockRepository{
+		accounts:    make(map[string]*Account),
+		holds:       make(map[uuid.UUID]*Hold),
+		deposits:    make(map[uuid.UUID]*Deposit),
+		withdrawals: make(map[uuid.UUID]*Withdrawal),
+	}
+}
+
+func (m *mockRepository) GetOrCreateAccount(ctx context.Context, ownerType OwnerType, ownerID *uuid.UUID, kind AccountKind, currency string) (*Account, error) {
+	key := string(ownerType) + "/" + string(kind) + "/" + currency
+	if ownerID != nil {
+		key += "/" + ownerID.String()
+	}
+	if a, ok := m.accounts[key]; ok {
+		return a, nil
+	}
+	a := &Account{ID: uuid.New(), OwnerType: ownerType, OwnerID: ownerID, Kind: kind, Currency: currency, CreatedAt: time.Now()}
+	m.accounts[key] = a
+	return a, nil
+}
+
+func (m *mockRepository) GetWalletAccounts(ctx context.Context, owner Owner) ([]*Account, error) {
+	var accounts []*Account
+	for _, a := range m.accounts {
+		if a.Kind == AccountWallet && a.OwnerType == owner.Type && a.OwnerID != nil && *a.OwnerID == owner.ID {
+			accounts = append(accounts, a)
+		}
+	}
+	return accounts, nil
+}
+
+func (m *mockRepository) balanceCents(accountID uuid.UUID) int64 {
+	var cents int64
+	for _, e := range m.entries {
+		for _, p := range e.Postings {
+			if p.AccountID == accountID {
+				cents += toCents(p.Amount)
+			}
+		}
+	}
+	return cents
+}
+
+func (m *mockRepository) GetAccountBalance(ctx context.Context, accountID uuid.UUID) (float64, error) {
+	return fromCents(m.balanceCents(accountID)), nil
+}
+
+func (m *mockRepository) GetHeldFromAccount(ctx context.Context, accountID uuid.UUID) (float64, error) {
+	var held float64
+	for _, h := range m.holds {
+		if h.SourceAccountID == accountID && h.Status == HoldHeld {
+			held += h.Remaining
+		}
+	}
+	return held, nil
+}
+
+func (m *mockRepository) GetStatement(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*StatementLine, int, error) {
+	lines := []*StatementLine{}
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		e := m.entries[i]
+		for _, p := range e.Postings {
+			if p.AccountID == accountID {
+				lines = append(lines, &StatementLine{EntryID: e.ID, Kind: e.Kind, Description: e.Description, Amount: p.Amount, TransactionID: e.TransactionID, CreatedAt: e.CreatedAt})
+			}
+		}
+	}
+	total := len(lines)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return lines[offset:end], total, nil
+}
+
+func (m *mockRepository) isWallet(accountID uuid.UUID) bool {
+	for _, a := range m.accounts {
+		if a.ID == accountID {
+			return a.Kind == AccountWallet
+		}
+	}
+	return false
+}
+
+func (m *mockRepository) PostEntry(ctx context.Context, entry *Entry) error {
+	if !entry.balanced() {
+		return ErrUnbalancedEntry
+	}
+	for _, e := range m.entries {
+		if e.ID == entry.ID {
+			return ErrDuplicateEntry
+		}
+	}
+	for _, p := range entry.Postings {
+		if p.Amount < 0 && m.isWallet(p.AccountID) && m.balanceCents(p.AccountID)+toCents(p.Amount) < 0 {
+			return ErrInsufficientFunds
+		}
+	}
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func (m *mockRepository) GetHoldByTransactionID(ctx context.Context, transactionID uuid.UUID) (*Hold, error) {
+	return m.holds[transactionID], nil
+}
+
+func (m *mockRepository) CreateHold(ctx context.Context, hold *Hold, entry *Entry) error {
+	if err := m.PostEntry(ctx, entry); err != nil {
+		return err
+	}
+	m.holds[hold.TransactionID] = hold
+	return nil
+}
+
+func (m *mockRepository) SettleHold(ctx context.Context, holdID uuid.UUID, amount float64, entries []*Entry) error {
+	for _, h := range m.holds {
+		if h.ID != holdID {
+			continue
+		}
+		if toCents(amount) > toCents(h.Remaining) {
+			return ErrHoldExceeded
+		}
+		for _, e := range entries {
+			if err := m.PostEntry(ctx, e); err != nil {
+				return err
+			}
+		}
+		h.Remaining = fromCents(toCents(h.Remaining) - toCents(amount))
+		if h.Remaining == 0 {
+			h.Status = HoldSettled
+		}
+		return nil
+	}
+	return errors.New("hold not found")
+}
+
+func (m *mockRepository) CreateDeposit(ctx context.Context, d *Deposit) error {
+	m.deposits[d.ID] = d
+	return nil
+}
+
+func (m *mockRepository) SetDepositPaymentIntent(ctx context.Context, id uuid.UUID, paymentIntentID, clientSecret string) error {
+	m.deposits[id].StripePaymentIntentID = paymentIntentID
+	return nil
+}
+
+func (m *mockRepository) GetDeposit(ctx context.Context, id uuid.UUID) (*Deposit, error) {
+	d, ok := m.deposits[id]
+	if !ok {
+		return nil, ErrDepositNotFound
+	}
+	copied := *d
+	return &copied, nil
+}
+
+func (m *mockRepository) CompleteDeposit(ctx context.Context, id uuid.UUID, entry *Entry) (bool, error) {
+	d := m.deposits[id]
+	if d.Status != DepositPending {
+		return false, nil
+	}
+	if err := m.PostEntry(ctx, entry); err != nil {
+		return false, err
+	}
+	d.Status = DepositCompleted
+	return true, nil
+}
+
+func (m *mockRepository) FailDeposit(ctx context.Context, id uuid.UUID, reason string) error {
+	if d, ok := m.deposits[id]; ok && d.Status == DepositPending {
+		d.Status = DepositFailed
+		d.FailureReason = reason
+	}
+	return nil
+}
+
+func (m *mockRepository) CreateWithdrawal(ctx context.Context, w *Withdrawal, entry *Entry) error {
+	if err := m.PostEntry(ctx, entry); err != nil {
+		return err
+	}
+	m.withdrawals[w.ID] = w
+	return nil
+}
+
+func (m *mockRepository) CompleteWithdrawal(ctx context.Context, id uuid.UUID, transferID string) error {
+	m.withdrawals[id].Status = WithdrawalCompleted
+	m.withdrawals[id].StripeTransferID = transferID
+	return nil
+}
+
+func (m *mockRepository) FailWithdrawal(ctx context.Context, id uuid.UUID, reason string, reversal *Entry) error {
+	if err := m.PostEntry(ctx, reversal); err != nil {
+		return err
+	}
+	m.withdrawals[id].Status = WithdrawalFailed
+	m.withdrawals[id].FailureReason = reason
+	return nil
+}
+
+func (m *mockRepository) GetAccountBalances(ctx context.Context) ([]*AccountBalance, error) {
+	var balances []*AccountBalance
+	for _, a := range m.accounts {
+		balances = append(balances, &AccountBalance{Account: *a, Balance: fromCents(m.balanceCents(a.ID))})
+	}
+	return balances, nil
+}
+
+func (m *mockRepository) GetUnbalancedEntries(ctx context.Context) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+	for _, e := range m.entries {
+		if !e.balanced() {
+			ids = append(ids, e.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (m *mockRepository) GetOpenHoldsTotal(ctx context.Context) (float64, error) {
+	var total int64
+	for _, h := range m.holds {
+		if h.Status == HoldHeld {
+			total += toCents(h.Remaining)
+		}
+	}
+	return fromCents(total), nil
+}
+
+func (m *mockRepository) GetDepositTotals(ctx context.Context) (float64, float64, error) {
+	var recorded, posted int64
+	for _, d := range m.deposits {
+		if d.Status == DepositCompleted {
+			recorded += toCents(d.Amount)
+		}
+	}
+	for _, e := range m.entries {
+		if e.Kind == EntryDeposit {
+			posted += toCents(e.Postings[1].Amount)
+		}
+	}
+	return fromCents(recorded), fromCents(posted), nil
+}
+
+func (m *mockRepository) GetWithdrawalTotals(ctx context.Context) (float64, float64, error) {
+	var recorded, posted int64
+	for _, w := range m.withdrawals {
+		if w.Status != WithdrawalFailed {
+			recorded += toCents(w.Amount)
+		}
+	}
+	for _, e := range m.entries {
+		switch e.Kind {
+		case EntryWithdrawal:
+			posted += toCents(e.Postings[1].Amount)
+		case EntryWithdrawalReversal:
+			posted -= toCents(e.Postings[1].Amount)
+		}
+	}
+	return fromCents(recorded), fromCents(posted), nil
+}
+
+// mockPayments is a Stripe provider that succeeds unless told to fail.
+type mockPayments struct {
+	payoutErr error
+}
+
+func (p *mockPayments) CreateDepositPayment(ctx context.Context, depositID, ownerType, ownerID string, amount float64, currency string) (string, string, error) {
+	return "pi_" + depositID, "secret", nil
+}
+
+func (p *mockPayments) PayoutToOwner(ctx context.Context, withdrawalID, ownerType, ownerID string, amount float64, currency string) (string, error) {
+	if p.payoutErr != nil {
+		return "", p.payoutErr
+	}
+	return "tr_" + withdrawalID, nil
+}
+
+func newTestService() (*Service, *mockRepository, *mockPayments) {
+	repo := newMockRepository()
+	payments := &mockPayments{}
+	svc := NewService(repo)
+	svc.SetPaymentProvider(payments)
+	svc.SetFeePercent(0.025)
+	return svc, repo, payments
+}
+
+// fund deposits an amount into an agent's wallet and confirms it.
+func fund(t *testing.T, svc *Service, agentID uuid.UUID, amount float64) {
+	t.Helper()
+	ctx := context.Background()
+	d, err := svc.CreateDeposit(ctx, Owner{Type: OwnerAgent, ID: agentID}, &DepositRequest{Amount: amount})
+	if err != nil {
+		t.Fatalf("CreateDeposit failed: %v", err)
+	}
+	if err := svc.ConfirmDeposit(ctx, d.ID); err != nil {
+		t.Fatalf("ConfirmDeposit failed: %v", err)
+	}
+}
+
+func available(t *testing.T, svc *Service, owner Owner) float64 {
+	t.Helper()
+	balances, err := svc.GetBalances(context.Background(), owner)
+	if err != nil {
+		t.Fatalf("GetBalances failed: %v", err)
+	}
+	var total float64
+	for _, b := range balances {
+		total += b.Available
+	}
+	return total
+}
+
+func TestDeposit_ConfirmIsIdempotent(t *testing.T) {
+	svc, _, _ := newTestService()
+	ctx := context.Background()
+	owner := Owner{Type: OwnerAgent, ID: uuid.New()}
+
+	d, err := svc.CreateDeposit(ctx, owner, &DepositRequest{Amount: 50.004, Currency: "usd"})
+	if err != nil {
+		t.Fatalf("CreateDeposit failed: %v", err)
+	}
+	if d.Amount != 50 || d.Currency != "USD" || d.StripePaymentIntentID == "" {
+		t.Errorf("unexpected deposit: %+v", d)
+	}
+	if got := available(t, svc, owner); got != 0 {
+		t.Errorf("expected nothing credited before payment, got %.2f", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := svc.ConfirmDeposit(ctx, d.ID); err != nil {
+			t.Fatalf("ConfirmDeposit failed: %v", err)
+		}
+	}
+	if got := available(t, svc, owner); got != 50 {
+		t.Errorf("expected 50.00 available, got %.2f", got)
+	}
+}
+
+func TestDeposit_InvalidAmount(t *testing.T) {
+	svc, _, _ := newTestService()
+	_, err := svc.CreateDeposit(context.Background(), Owner{Type: OwnerUser, ID: uuid.New()}, &DepositRequest{Amount: 0.001})
+	if err != ErrInvalidAmount {
+		t.Errorf("expected ErrInvalidAmount, got %v", err)
+	}
+}
+
+func TestDeposit_PaymentsNotConfigured(t *testing.T) {
+	svc := NewService(newMockRepository())
+	_, err := svc.CreateDeposit(context.Background(), Owner{Type: OwnerUser, ID: uuid.New()}, &DepositRequest{Amount: 10})
+	if err != ErrPaymentsNotConfigured {
+		t.Errorf("expected ErrPaymentsNotConfigured, got %v", err)
+	}
+}
+
+func TestHoldEscrow_InsufficientFunds(t *testing.T) {
+	svc, _, _ := newTestService()
+	buyerID := uuid.New()
+	fund(t, svc, buyerID, 30)
+
+	err := svc.HoldEscrow(context.Background(), uuid.New(), buyerID, 30.01, "USD", true)
+	if err != ErrInsufficientFunds {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if got := available(t, svc, Owner{Type: OwnerAgent, ID: buyerID}); got != 30 {
+		t.Errorf("expected balance untouched at 30.00, got %.2f", got)
+	}
+}
+
+func TestEscrow_WalletFundedReleaseAndRefund(t *testing.T) {
+	svc, _, _ := newTestService()
+	ctx := context.Background()
+	buyer := Owner{Type: OwnerAgent, ID: uuid.New()}
+	seller := Owner{Type: OwnerAgent, ID: uuid.New()}
+	txID := uuid.New()
+	fund(t, svc, buyer.ID, 100)
+
+	if err := svc.HoldEscrow(ctx, txID, buyer.ID, 80, "USD", true); err != nil {
+		t.Fatalf("HoldEscrow failed: %v", err)
+	}
+	// A second hold for the same transaction does nothing
+	if err := svc.HoldEscrow(ctx, txID, buyer.ID, 80, "USD", true); err != nil {
+		t.Fatalf("repeat HoldEscrow failed: %v", err)
+	}
+	balances, _ := svc.GetBalances(ctx, buyer)
+	if balances[0].Available != 20 || balances[0].Held != 80 {
+		t.Errorf("expected 20.00 available and 80.00 held, got %+v", balances[0])
+	}
+
+	// Split outcome: 60 to the seller, 20 back to the buyer
+	if err := svc.SettleEscrow(ctx, txID, seller.ID, 60, 20); err != nil {
+		t.Fatalf("SettleEscrow failed: %v", err)
+	}
+	if got := available(t, svc, buyer); got != 40 {
+		t.Errorf("expected buyer to have 40.00, got %.2f", got)
+	}
+	if got := available(t, svc, seller); got != 58.5 {
+		t.Errorf("expected seller to have 58.50 after the 2.5%% fee, got %.2f", got)
+	}
+
+	// Nothing is left to settle
+	if err := svc.SettleEscrow(ctx, txID, seller.ID, 1, 0); err != ErrHoldExceeded {
+		t.Errorf("expected ErrHoldExceeded, got %v", err)
+	}
+
+	report, err := svc.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if !report.Balanced {
+		t.Errorf("expected a balanced ledger, got mismatches %v", report.Mismatches)
+	}
+	if report.FeesEarned != 1.5 || report.EscrowBalance != 0 {
+		t.Errorf("expected 1.50 fees and empty escrow, got %+v", report)
+	}
+}
+
+func TestEscrow_CardFundedPaysOutSeller(t *testing.T) {
+	svc, repo, _ := newTestService()
+	ctx := context.Background()
+	seller := Owner{Type: OwnerAgent, ID: uuid.New()}
+	txID := uuid.New()
+
+	if err := svc.HoldEscrow(ctx, txID, uuid.New(), 200, "USD", false); err != nil {
+		t.Fatalf("HoldEscrow failed: %v", err)
+	}
+	if err := svc.SettleEscrow(ctx, txID, seller.ID, 200, 0); err != nil {
+		t.Fatalf("SettleEscrow failed: %v", err)
+	}
+
+	// Stripe paid the seller directly, so their wallet ends where it started
+	if got := available(t, svc, seller); got != 0 {
+		t.Errorf("expected seller wallet at 0.00, got %.2f", got)
+	}
+	last := repo.entries[len(repo.entries)-1]
+	if last.Kind != EntryPayout || last.Postings[1].Amount != 195 {
+		t.Errorf("expected a 195.00 payout entry, got %+v", last)
+	}
+
+	report, _ := svc.Reconcile(ctx)
+	if !report.Balanced || report.FeesEarned != 5 {
+		t.Errorf("expected balanced ledger with 5.00 fees, got %+v", report)
+	}
+}
+
+func TestSettleEscrow_NoHold(t *testing.T) {
+	svc, repo, _ := newTestService()
+	if err := svc.SettleEscrow(context.Background(), uuid.New(), uuid.New(), 10, 0); err != nil {
+		t.Errorf("expected transactions without a hold to be skipped, got %v", err)
+	}
+	if len(repo.entries) != 0 {
+		t.Errorf("expected no entries, got %d", len(repo.entries))
+	}
+}
+
+func TestChargeCancellationPenalty(t *testing.T) {
+	svc, _, _ := newTestService()
+	ctx := context.Background()
+	seller := Owner{Type: OwnerAgent, ID: uuid.New()}
+	fund(t, svc, seller.ID, 30)
+
+	chargeID, err := svc.ChargeCancellationPenalty(ctx, uuid.New(), seller.ID, 20, "USD")
+	if err != nil {
+		t.Fatalf("ChargeCancellationPenalty failed: %v", err)
+	}
+	if got := available(t, svc, seller); got != 10 {
+		t.Errorf("expected seller to have 10.00, got %.2f", got)
+	}
+
+	if _, err := svc.ChargeCancellationPenalty(ctx, uuid.New(), seller.ID, 20, "USD"); err != ErrInsufficientFunds {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	report, _ := svc.Reconcile(ctx)
+	if !report.Balanced || report.FeesEarned != 20 {
+		t.Errorf("expected balanced ledger with 20.00 fees, got %+v", report)
+	}
+
+	// A charge is reversed once, however often it is asked to be
+	for i := 0; i < 2; i++ {
+		if err := svc.ReverseCancellationPenalty(ctx, chargeID, seller.ID, 20, "USD"); err != nil {
+			t.Fatalf("ReverseCancellationPenalty failed: %v", err)
+		}
+	}
+	if got := available(t, svc, seller); got != 30 {
+		t.Errorf("expected seller to have 30.00 back, got %.2f", got)
+	}
+	report, _ = svc.Reconcile(ctx)
+	if !report.Balanced || report.FeesEarned != 0 {
+		t.Errorf("expected balanced ledger with no fees, got %+v", report)
+	}
+}
+
+func TestRefundPaidOut_WalletFunded(t *testing.T) {
+	svc, _, _ := newTestService()
+	ctx := context.Background()
+	buyer := Owner{Type: OwnerAgent, ID: uuid.New()}
+	seller := Owner{Type: OwnerAgent, ID: uuid.New()}
+	txID := uuid.New()
+	fund(t, svc, buyer.ID, 100)
+
+	if err := svc.HoldEscrow(ctx, txID, buyer.ID, 100, "USD", true); err != nil {
+		t.Fatalf("HoldEscrow failed: %v", err)
+	}
+	if err := svc.SettleEscrow(ctx, txID, seller.ID, 100, 0); err != nil {
+		t.Fatalf("SettleEscrow failed: %v", err)
+	}
+
+	// The seller gives back 39.00 and the fee account 1.00
+	refundID := uuid.New()
+	if err := svc.RefundPaidOut(ctx, refundID, txID, seller.ID, 40); err != nil {
+		t.Fatalf("RefundPaidOut failed: %v", err)
+	}
+	// Recording the same refund again changes nothing
+	if err := svc.RefundPaidOut(ctx, refundID, txID, seller.ID, 40); err != nil {
+		t.Fatalf("RefundPaidOut retry failed: %v", err)
+	}
+	if got := available(t, svc, buyer); got != 40 {
+		t.Errorf("expected buyer to have 40.00, got %.2f", got)
+	}
+	if got := available(t, svc, seller); got != 58.5 {
+		t.Errorf("expected seller to have 58.50, got %.2f", got)
+	}
+
+	// The seller no longer has enough for the rest
+	if err := svc.RefundPaidOut(ctx, uuid.New(), txID, seller.ID, 70); err != ErrInsufficientFunds {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	report, _ := svc.Reconcile(ctx)
+	if !report.Balanced || report.FeesEarned != 1.5 {
+		t.Errorf("expected balanced ledger with 1.50 fees, got %+v", report)
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	svc, repo, payments := newTestService()
+	ctx := context.Background()
+	owner := Owner{Type: OwnerAgent, ID: uuid.New()}
+	fund(t, svc, owner.ID, 100)
+
+	if _, err := svc.Withdraw(ctx, owner, &WithdrawRequest{Amount: 150}); err != ErrInsufficientFunds {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	w, err := svc.Withdraw(ctx, owner, &WithdrawRequest{Amount: 40})
+	if err != nil {
+		t.Fatalf("Withdraw failed: %v", err)
+	}
+	if w.Status != WithdrawalCompleted || w.StripeTransferID == "" {
+		t.Errorf("unexpected withdrawal: %+v", w)
+	}
+	if got := available(t, svc, owner); got != 60 {
+		t.Errorf("expected 60.00 left, got %.2f", got)
+	}
+
+	// A failed transfer puts the money back
+	payments.payoutErr = errors.New("no connect account")
+	if _, err := svc.Withdraw(ctx, owner, &WithdrawRequest{Amount: 60}); err == nil {
+		t.Fatal("expected the payout error")
+	}
+	if got := available(t, svc, owner); got != 60 {
+		t.Errorf("expected 60.00 after reversal, got %.2f", got)
+	}
+	if last := repo.entries[len(repo.entries)-1]; last.Kind != EntryWithdrawalReversal {
+		t.Errorf("expected a reversal entry, got %s", last.Kind)
+	}
+
+	report, _ := svc.Reconcile(ctx)
+	if !report.Balanced {
+		t.Errorf("expected a balanced ledger, got mismatches %v", report.Mismatches)
+	}
+}
+
+func TestReconcile_FlagsMismatches(t *testing.T) {
+	svc, repo, _ := newTestService()
+	ctx := context.Background()
+	txID := uuid.New()
+	if err := svc.HoldEscrow(ctx, txID, uuid.New(), 25, "USD", false); err != nil {
+		t.Fatalf("HoldEscrow failed: %v", err)
+	}
+
+	// The hold says less is in escrow than the account holds
+	repo.holds[txID].Remaining = 20
+	report, err := svc.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if report.Balanced || len(report.Mismatches) != 1 {
+		t.Errorf("expected one escrow mismatch, got %v", report.Mismatches)
+	}
+}
+
+func TestNewEntry_DropsZeroPostings(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	entry := newEntry(EntryEscrowRelease, "test",
+		Posting{AccountID: a, Amount: -10},
+		Posting{AccountID: b, Amount: 10},
+		Posting{AccountID: uuid.New(), Amount: 0},
+	)
+	if len(entry.Postings) != 2 || !entry.balanced() {
+		t.Errorf("expected two balanced postings, got %+v", entry.Postings)
+	}
+
+	unbalanced := newEntry(EntryDeposit, "test", Posting{AccountID: a, Amount: -10}, Posting{AccountID: b, Amount: 9.99})
+	if unbalanced.balanced() {
+		t.Error("expected entry not to balance")
+	}
+}
@@ -10,7 +10,10 @@ import (
 
 	"github.com/digi604/swarmmarket/backend/internal/auction"
 	"github.com/digi604/swarmmarket/backend/internal/email"
+	"github.com/digi604/swarmmarket/backend/internal/matching"
 	"github.com/digi604/swarmmarket/backend/internal/notification"
+	"github.com/digi604/swarmmarket/backend/internal/transaction"
+	"github.com/digi604/swarmmarket/backend/internal/wallet"
 	"github.com/digi604/swarmmarket/backend/pkg/logger"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
@@ -22,7 +25,10 @@ type Config struct {
 	WebhookRepo         *notification.Repository
 	AuctionService      *auction.Service
 	AuctionRepo         *auction.Repository
+	TransactionService  *transaction.Service
+	WalletService       *wallet.Service
 	EmailService        *email.Service
+	MatchingEngine      *matching.Engine
 	RedisClient         *redis.Client
 }
 
@@ -32,7 +38,10 @@ type Worker struct {
 	webhookRepo         *notification.Repository
 	auctionService      *auction.Service
 	auctionRepo         *auction.Repository
+	transactionService  *transaction.Service
+	walletService       *wallet.Service
 	emailService        *email.Service
+	matchingEngine      *matching.Engine
 	redis               *redis.Client
 }
 
@@ -43,7 +52,10 @@ func New(cfg Config) *Worker {
 		webhookRepo:         cfg.WebhookRepo,
 		auctionService:      cfg.AuctionService,
 		auctionRepo:         cfg.AuctionRepo,
+		transactionService:  cfg.TransactionService,
+		walletService:       cfg.WalletService,
 		emailService:        cfg.EmailService,
+		matchingEngine:      cfg.MatchingEngine,
 		redis:               cfg.RedisClient,
 	}
 }
@@ -56,6 +68,18 @@ func (w *Worker) Run(ctx context.Context) error {
 	// Start auction scheduler
 	go w.processAuctions(ctx)
 
+	// Start Dutch auction price ticker
+	go w.processDutchAuctions(ctx)
+
+	// Start order book expiry sweeper
+	go w.processOrderBook(ctx)
+
+	// Start dispute and inspection deadline sweeper
+	go w.processTransactions(ctx)
+
+	// Start wallet ledger reconciliation
+	go w.reconcileLedger(ctx)
+
 	// Start webhook delivery worker
 	go w.deliverWebhooks(ctx)
 
@@ -79,9 +103,15 @@ func (w *Worker) consumeEvents(ctx context.Context) {
 		"events:comment.created",
 		"events:auction.started",
 		"events:auction.ending_soon",
+		"events:auction.price_changed",
+		"events:auction.reveal_started",
+		"events:auction.second_chance_offered",
+		"events:auction.search_matched",
 		"events:bid.placed",
 		"events:bid.outbid",
 		"events:auction.ended",
+		"events:auction.cancelled",
+		"events:bid.retracted",
 		"events:order.created",
 		"events:escrow.funded",
 		"events:delivery.confirmed",
@@ -91,11 +121,22 @@ func (w *Worker) consumeEvents(ctx context.Context) {
 		"events:transaction.created",
 		"events:transaction.escrow_funded",
 		"events:transaction.delivered",
+		"events:transaction.inspection_ending",
+		"events:milestone.delivered",
+		"events:milestone.released",
 		"events:transaction.completed",
 		"events:transaction.refunded",
+		"events:refund.requested",
+		"events:refund.completed",
+		"events:refund.rejected",
 		"events:rating.submitted",
 		"events:dispute.opened",
+		"events:dispute.evidence_submitted",
+		"events:dispute.resolved",
 		"events:match.found",
+		"events:order.expired",
+		"events:order.triggered",
+		"events:order.self_trade_prevented",
 		"events:message.received",
 		"events:agent.registered",
 		"events:agent.claimed",
@@ -332,10 +373,43 @@ func (w *Worker) processAuctions(ctx context.Context) {
 			w.startScheduledAuctions(ctx)
 			w.notifyAuctionsEndingSoon(ctx)
 			w.endExpiredAuctions(ctx)
+			w.processSealedAuctions(ctx)
+			w.processSettlements(ctx)
 		}
 	}
 }
 
+// processDutchAuctions steps Dutch auction prices down on their schedule.
+func (w *Worker) processDutchAuctions(ctx context.Context) {
+	if w.auctionService == nil {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.tickDutchAuctions(ctx)
+		}
+	}
+}
+
+// tickDutchAuctions lowers the prices of Dutch auctions that are due; the
+// auction service publishes auction.price_changed for each of them.
+func (w *Worker) tickDutchAuctions(ctx context.Context) {
+	changed, err := w.auctionService.TickDutchAuctions(ctx, time.Now().UTC())
+	if err != nil {
+		log.Printf("Worker: Failed to tick Dutch auctions: %v", err)
+	}
+	if changed > 0 {
+		log.Printf("Worker: Lowered the price of %d Dutch auctions", changed)
+	}
+}
+
 // notifyAuctionsEndingSoon publishes auction.ending_soon for auctions approaching their end time.
 func (w *Worker) notifyAuctionsEndingSoon(ctx context.Context) {
 	status := auction.AuctionStatusActive
@@ -376,13 +450,20 @@ func (w *Worker) notifyAuctionsEndingSoon(ctx context.Context) {
 		}
 
 		if w.notificationService != nil {
-			_ = w.notificationService.Publish(ctx, "auction.ending_soon", map[string]any{
+			payload := map[string]any{
 				"auction_id":   auc.ID,
 				"seller_id":    auc.SellerID,
 				"auction_type": auc.AuctionType,
 				"title":        auc.Title,
 				"ends_at":      auc.EndsAt,
-			})
+			}
+			// Agents watching the auction are notified too
+			if watchers, err := w.auctionRepo.GetAuctionWatcherIDs(ctx, auc.ID); err != nil {
+				log.Printf("Worker: Failed to get watchers of auction %s: %v", auc.ID, err)
+			} else if len(watchers) > 0 {
+				payload["watcher_ids"] = watchers
+			}
+			_ = w.notificationService.Publish(ctx, "auction.ending_soon", payload)
 		}
 	}
 }
@@ -421,50 +502,165 @@ func (w *Worker) startScheduledAuctions(ctx context.Context) {
 				"ends_at":        auc.EndsAt,
 			})
 		}
+		if w.auctionService != nil {
+			w.auctionService.NotifySavedSearches(ctx, auc)
+		}
+	}
+}
+
+// processSealedAuctions opens the reveal window of commit-reveal auctions that
+// have ended and settles Vickrey and revealed auctions.
+func (w *Worker) processSealedAuctions(ctx context.Context) {
+	if w.auctionService == nil {
+		return
+	}
+	processed, err := w.auctionService.ProcessSealedAuctions(ctx, time.Now().UTC())
+	if err != nil {
+		log.Printf("Worker: Failed to process sealed auctions: %v", err)
+	}
+	if processed > 0 {
+		log.Printf("Worker: Closed or settled %d sealed auctions", processed)
 	}
 }
 
 // endExpiredAuctions finds and ends auctions past their end time.
 func (w *Worker) endExpiredAuctions(ctx context.Context) {
-	// Search for active auctions that have ended
-	status := auction.AuctionStatusActive
-	result, err := w.auctionRepo.SearchAuctions(ctx, auction.SearchAuctionsParams{
-		Status: &status,
-		Limit:  100,
-	})
+	if w.auctionService == nil {
+		return
+	}
+	ended, err := w.auctionService.EndExpiredAuctions(ctx, time.Now().UTC())
+	if err != nil {
+		log.Printf("Worker: Failed to end expired auctions: %v", err)
+	}
+	if ended > 0 {
+		log.Printf("Worker: Ended %d expired auctions", ended)
+	}
+}
+
+// processSettlements cancels the transactions of auction winners that did not
+// fund escrow in time and passes the auction on to the next bidder.
+func (w *Worker) processSettlements(ctx context.Context) {
+	if w.auctionService == nil {
+		return
+	}
+	processed, err := w.auctionService.ProcessSettlements(ctx, time.Now().UTC())
 	if err != nil {
-		log.Printf("Worker: Failed to search auctions: %v", err)
+		log.Printf("Worker: Failed to process auction settlements: %v", err)
+	}
+	if processed > 0 {
+		log.Printf("Worker: Processed %d auction settlements", processed)
+	}
+}
+
+// processOrderBook expires GTD orders once their expiry has passed.
+func (w *Worker) processOrderBook(ctx context.Context) {
+	if w.matchingEngine == nil {
 		return
 	}
 
-	now := time.Now().UTC()
-	for _, auc := range result.Auctions {
-		if auc.EndsAt.Before(now) {
-			// End the auction
-			log.Printf("Worker: Ending expired auction %s", auc.ID)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.expireOrders(ctx)
+		}
+	}
+}
 
-			// Get highest bid
-			highestBid, err := w.auctionRepo.GetHighestBid(ctx, auc.ID)
+// expireOrders removes expired GTD orders from the book; the engine's
+// expiry handler publishes order.expired for each of them.
+func (w *Worker) expireOrders(ctx context.Context) {
+	expired, err := w.matchingEngine.ExpireOrders(ctx, time.Now().UTC())
+	if err != nil {
+		log.Printf("Worker: Failed to expire orders: %v", err)
+	}
+	if expired > 0 {
+		log.Printf("Worker: Expired %d GTD orders", expired)
+	}
+}
+
+// processTransactions decides disputes whose respondent missed the response
+// deadline, releases escrow for deliveries whose inspection window ended and
+// finishes refunds left processing.
+func (w *Worker) processTransactions(ctx context.Context) {
+	if w.transactionService == nil {
+		return
+	}
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.decideOverdueDisputes(ctx)
+			w.releaseInspectedEscrow(ctx)
+			w.finishStuckRefunds(ctx)
+		}
+	}
+}
+
+// decideOverdueDisputes decides disputes in the opener's favour once the response deadline passes.
+func (w *Worker) decideOverdueDisputes(ctx context.Context) {
+	decided, err := w.transactionService.ProcessDisputes(ctx, time.Now().UTC())
+	if err != nil {
+		log.Printf("Worker: Failed to process disputes: %v", err)
+	}
+	if decided > 0 {
+		log.Printf("Worker: Decided %d overdue disputes", decided)
+	}
+}
+
+// releaseInspectedEscrow warns buyers whose inspection window is ending and
+// releases the transactions and milestones whose window has ended.
+func (w *Worker) releaseInspectedEscrow(ctx context.Context) {
+	completed, err := w.transactionService.ProcessInspections(ctx, time.Now().UTC())
+	if err != nil {
+		log.Printf("Worker: Failed to process inspection windows: %v", err)
+	}
+	if completed > 0 {
+		log.Printf("Worker: Released escrow for %d transactions and milestones after inspection", completed)
+	}
+}
+
+// finishStuckRefunds retries refunds that were paid but not fully recorded.
+func (w *Worker) finishStuckRefunds(ctx context.Context) {
+	completed, err := w.transactionService.ProcessRefunds(ctx, time.Now().UTC())
+	if err != nil {
+		log.Printf("Worker: Failed to process refunds: %v", err)
+	}
+	if completed > 0 {
+		log.Printf("Worker: Completed %d stuck refunds", completed)
+	}
+}
+
+// reconcileLedger checks the wallet ledger every hour and logs any mismatch.
+func (w *Worker) reconcileLedger(ctx context.Context) {
+	if w.walletService == nil {
+		return
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := w.walletService.Reconcile(ctx)
 			if err != nil {
-				log.Printf("Worker: Failed to get highest bid: %v", err)
+				log.Printf("Worker: Failed to reconcile wallet ledger: %v", err)
 				continue
 			}
-
-			if highestBid != nil {
-				// Check reserve price
-				metReserve := true
-				if auc.ReservePrice != nil && highestBid.Amount < *auc.ReservePrice {
-					metReserve = false
-				}
-
-				if metReserve {
-					w.auctionRepo.SetAuctionWinner(ctx, auc.ID, highestBid.ID, highestBid.BidderID)
-					w.auctionRepo.UpdateBidStatus(ctx, highestBid.ID, auction.BidStatusWon)
-				} else {
-					w.auctionRepo.UpdateAuctionStatus(ctx, auc.ID, auction.AuctionStatusEnded)
-				}
-			} else {
-				w.auctionRepo.UpdateAuctionStatus(ctx, auc.ID, auction.AuctionStatusEnded)
+			for _, mismatch := range report.Mismatches {
+				log.Printf("Worker: Wallet ledger mismatch: %s", mismatch)
 			}
 		}
 	}
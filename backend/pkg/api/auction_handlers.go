@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/digi604/swarmmarket/backend/internal/auction"
 	"github.com/digi604/swarmmarket/backend/internal/common"
+	"github.com/digi604/swarmmarket/backend/internal/wallet"
 	"github.com/digi604/swarmmarket/backend/pkg/middleware"
 )
 
@@ -49,7 +50,7 @@ func (h *AuctionHandler) CreateAuction(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		switch err {
 		case auction.ErrInvalidAuctionType:
-			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction type - must be english, dutch, sealed, or continuous"))
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction type - must be english, dutch, sealed, vickrey, continuous, or bundle"))
 		default:
 			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
 		}
@@ -144,7 +145,15 @@ func (h *AuctionHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Amount <= 0 {
+	// Commit-reveal bids send no amount until the reveal
+	switch {
+	case req.Commitment != nil:
+	case req.MaxAmount != nil:
+		if *req.MaxAmount <= 0 {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("max_amount must be positive"))
+			return
+		}
+	case req.Amount <= 0:
 		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("amount must be positive"))
 		return
 	}
@@ -162,6 +171,12 @@ func (h *AuctionHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
 			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("bid amount is too low"))
 		case auction.ErrCannotBidOnOwnAuction:
 			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("cannot bid on your own auction"))
+		case auction.ErrProxyNotSupported:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("max_amount is only supported on english auctions"))
+		case auction.ErrCommitmentRequired, auction.ErrUnexpectedCommitment, auction.ErrInvalidCommitment, auction.ErrInvalidQuantity, auction.ErrInvalidBundle:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+		case auction.ErrAlreadyCommitted:
+			common.WriteError(w, http.StatusConflict, common.ErrConflict(err.Error()))
 		default:
 			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to place bid"))
 		}
@@ -171,6 +186,150 @@ func (h *AuctionHandler) PlaceBid(w http.ResponseWriter, r *http.Request) {
 	common.WriteJSON(w, http.StatusCreated, bid)
 }
 
+// BuyNow handles POST /auctions/{id}/buy-now - buy an auction at its buy-now price.
+func (h *AuctionHandler) BuyNow(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	auctionID, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction id"))
+		return
+	}
+
+	bid, err := h.service.BuyNow(r.Context(), auctionID, agent.ID)
+	if err != nil {
+		switch err {
+		case auction.ErrAuctionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("auction not found"))
+		case auction.ErrAuctionNotActive:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("auction is not active"))
+		case auction.ErrAuctionEnded:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("auction has ended"))
+		case auction.ErrBuyNowNotAvailable:
+			common.WriteError(w, http.StatusConflict, common.ErrConflict("buy-now is not available on this auction"))
+		case auction.ErrCannotBidOnOwnAuction:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("cannot buy your own auction"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to buy auction"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusCreated, bid)
+}
+
+// AcceptSecondChance handles POST /auctions/{id}/second-chance/accept - buy an auction through a second-chance offer.
+func (h *AuctionHandler) AcceptSecondChance(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	auctionID, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction id"))
+		return
+	}
+
+	settlement, err := h.service.AcceptSecondChance(r.Context(), auctionID, agent.ID)
+	if err != nil {
+		switch err {
+		case auction.ErrAuctionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("auction not found"))
+		case auction.ErrNoSecondChanceOffer:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("no open second-chance offer"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to accept offer"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, settlement)
+}
+
+// DeclineSecondChance handles POST /auctions/{id}/second-chance/decline - decline a second-chance offer.
+func (h *AuctionHandler) DeclineSecondChance(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	auctionID, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction id"))
+		return
+	}
+
+	if err := h.service.DeclineSecondChance(r.Context(), auctionID, agent.ID); err != nil {
+		switch err {
+		case auction.ErrAuctionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("auction not found"))
+		case auction.ErrNoSecondChanceOffer:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("no open second-chance offer"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to decline offer"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]string{"status": "declined"})
+}
+
+// RevealBid handles POST /auctions/{id}/reveal - reveal a commit-reveal bid.
+func (h *AuctionHandler) RevealBid(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	auctionID, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction id"))
+		return
+	}
+
+	var req auction.RevealBidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	if req.Amount <= 0 || req.Nonce == "" {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("amount and nonce are required"))
+		return
+	}
+
+	bid, err := h.service.RevealBid(r.Context(), auctionID, agent.ID, &req)
+	if err != nil {
+		switch err {
+		case auction.ErrAuctionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("auction not found"))
+		case auction.ErrBidNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("no committed bid to reveal"))
+		case auction.ErrUnexpectedCommitment, auction.ErrRevealNotOpen, auction.ErrCommitmentMismatch:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+		case auction.ErrBidTooLow:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("bid amount is too low"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to reveal bid"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, bid)
+}
+
 // GetBids handles GET /auctions/{id}/bids - get bids for an auction.
 func (h *AuctionHandler) GetBids(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
@@ -234,3 +393,311 @@ func (h *AuctionHandler) EndAuction(w http.ResponseWriter, r *http.Request) {
 
 	common.WriteJSON(w, http.StatusOK, auc)
 }
+
+// UpdateAuction handles PATCH /auctions/{id} - edit a scheduled auction.
+func (h *AuctionHandler) UpdateAuction(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	auctionID, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction id"))
+		return
+	}
+
+	var req auction.UpdateAuctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	if req.Title != nil && *req.Title == "" {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("title cannot be empty"))
+		return
+	}
+
+	auc, err := h.service.UpdateAuction(r.Context(), auctionID, agent.ID, &req)
+	if err != nil {
+		switch err {
+		case auction.ErrAuctionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("auction not found"))
+		case auction.ErrNotAuthorized:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("only the seller can edit the auction"))
+		case auction.ErrAuctionNotScheduled:
+			common.WriteError(w, http.StatusConflict, common.ErrConflict("only scheduled auctions can be edited"))
+		default:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, auc)
+}
+
+// CancelAuction handles POST /auctions/{id}/cancel - cancel an auction.
+func (h *AuctionHandler) CancelAuction(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	auctionID, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction id"))
+		return
+	}
+
+	var req auction.CancelAuctionRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+			return
+		}
+	}
+
+	auc, err := h.service.CancelAuction(r.Context(), auctionID, agent.ID, &req)
+	if err != nil {
+		switch err {
+		case auction.ErrAuctionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("auction not found"))
+		case auction.ErrNotAuthorized:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("only the seller can cancel the auction"))
+		case auction.ErrAuctionNotActive:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("auction is not scheduled or active"))
+		case auction.ErrAuctionHasBids:
+			common.WriteError(w, http.StatusConflict, common.ErrConflict("auction has bids - set accept_penalty to cancel it"))
+		case wallet.ErrInsufficientFunds:
+			common.WriteError(w, http.StatusPaymentRequired, common.ErrInsufficientFunds("wallet balance is too low for the cancellation penalty"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to cancel auction"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, auc)
+}
+
+// RetractBid handles POST /auctions/{id}/bids/{bidId}/retract - retract a bid.
+func (h *AuctionHandler) RetractBid(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	auctionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction id"))
+		return
+	}
+	bidID, err := uuid.Parse(chi.URLParam(r, "bidId"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid bid id"))
+		return
+	}
+
+	bid, err := h.service.RetractBid(r.Context(), auctionID, bidID, agent.ID)
+	if err != nil {
+		switch err {
+		case auction.ErrAuctionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("auction not found"))
+		case auction.ErrBidNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("bid not found"))
+		case auction.ErrNotAuthorized:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("only the bidder can retract the bid"))
+		case auction.ErrAuctionNotActive:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("auction is not active"))
+		case auction.ErrAuctionEnded:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("auction has ended"))
+		case auction.ErrBidNotRetractable:
+			common.WriteError(w, http.StatusConflict, common.ErrConflict("bid cannot be retracted once it has outbid another bid"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to retract bid"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, bid)
+}
+
+// GetAuctionHistory handles GET /auctions/{id}/history - get the audit history of an auction.
+func (h *AuctionHandler) GetAuctionHistory(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	auctionID, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction id"))
+		return
+	}
+
+	history, err := h.service.GetAuctionHistory(r.Context(), auctionID)
+	if err != nil {
+		if err == auction.ErrAuctionNotFound {
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("auction not found"))
+			return
+		}
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to get auction history"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]any{
+		"history": history,
+		"total":   len(history),
+	})
+}
+
+// WatchAuction handles POST /auctions/{id}/watch - add an auction to the agent's watchlist.
+func (h *AuctionHandler) WatchAuction(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	auctionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction id"))
+		return
+	}
+
+	if err := h.service.WatchAuction(r.Context(), auctionID, agent.ID); err != nil {
+		switch err {
+		case auction.ErrAuctionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("auction not found"))
+		case auction.ErrAuctionEnded:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("auction has ended"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to watch auction"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]any{
+		"auction_id": auctionID,
+		"watching":   true,
+	})
+}
+
+// UnwatchAuction handles DELETE /auctions/{id}/watch - remove an auction from the agent's watchlist.
+func (h *AuctionHandler) UnwatchAuction(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	auctionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction id"))
+		return
+	}
+
+	if err := h.service.UnwatchAuction(r.Context(), auctionID, agent.ID); err != nil {
+		if err == auction.ErrNotWatching {
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("auction is not on the watchlist"))
+			return
+		}
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to unwatch auction"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWatchlist handles GET /auctions/watchlist - list the auctions the agent is watching.
+func (h *AuctionHandler) GetWatchlist(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	result, err := h.service.GetWatchlist(r.Context(), agent.ID, parseIntParam(r, "limit", 20), parseIntParam(r, "offset", 0))
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to get watchlist"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, result)
+}
+
+// CreateSavedSearch handles POST /auctions/saved-searches - save an auction search to be alerted about.
+func (h *AuctionHandler) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	var req auction.CreateSavedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	search, err := h.service.CreateSavedSearch(r.Context(), agent.ID, &req)
+	if err != nil {
+		switch err {
+		case auction.ErrInvalidAuctionType:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid auction type"))
+		case auction.ErrTooManySavedSearches:
+			common.WriteError(w, http.StatusConflict, common.ErrConflict("too many saved searches, delete one first"))
+		default:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusCreated, search)
+}
+
+// GetSavedSearches handles GET /auctions/saved-searches - list the agent's saved searches.
+func (h *AuctionHandler) GetSavedSearches(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	searches, err := h.service.GetSavedSearches(r.Context(), agent.ID)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to get saved searches"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]any{
+		"saved_searches": searches,
+		"total":          len(searches),
+	})
+}
+
+// DeleteSavedSearch handles DELETE /auctions/saved-searches/{searchId} - delete a saved search.
+func (h *AuctionHandler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	searchID, err := uuid.Parse(chi.URLParam(r, "searchId"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid saved search id"))
+		return
+	}
+
+	if err := h.service.DeleteSavedSearch(r.Context(), searchID, agent.ID); err != nil {
+		if err == auction.ErrSavedSearchNotFound {
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("saved search not found"))
+			return
+		}
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to delete saved search"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/digi604/swarmmarket/backend/internal/common"
 	"github.com/digi604/swarmmarket/backend/internal/transaction"
+	"github.com/digi604/swarmmarket/backend/internal/wallet"
 	"github.com/digi604/swarmmarket/backend/pkg/middleware"
 )
 
@@ -17,11 +18,23 @@ type TransactionService interface {
 	GetTransaction(ctx context.Context, id uuid.UUID) (*transaction.Transaction, error)
 	ListTransactions(ctx context.Context, params transaction.ListTransactionsParams) (*transaction.TransactionListResult, error)
 	FundEscrow(ctx context.Context, transactionID, buyerID uuid.UUID) (*transaction.EscrowFundingResult, error)
+	FundEscrowFromWallet(ctx context.Context, transactionID, buyerID uuid.UUID) (*transaction.Transaction, error)
 	MarkDelivered(ctx context.Context, transactionID, sellerID uuid.UUID, deliveryProof, message string) (*transaction.Transaction, error)
+	SetInspectionWindow(ctx context.Context, transactionID, buyerID uuid.UUID, hours int) (*transaction.Transaction, error)
 	ConfirmDelivery(ctx context.Context, transactionID, buyerID uuid.UUID) (*transaction.Transaction, error)
 	SubmitRating(ctx context.Context, transactionID, raterID uuid.UUID, req *transaction.SubmitRatingRequest) (*transaction.Rating, error)
 	GetTransactionRatings(ctx context.Context, transactionID uuid.UUID) ([]*transaction.Rating, error)
 	DisputeTransaction(ctx context.Context, transactionID, agentID uuid.UUID, req *transaction.DisputeRequest) (*transaction.Transaction, error)
+	GetDispute(ctx context.Context, transactionID uuid.UUID, milestoneID *uuid.UUID, agentID uuid.UUID) (*transaction.Dispute, error)
+	SubmitEvidence(ctx context.Context, transactionID uuid.UUID, milestoneID *uuid.UUID, agentID uuid.UUID, req *transaction.SubmitEvidenceRequest) (*transaction.DisputeEvidence, error)
+	ResolveDispute(ctx context.Context, transactionID uuid.UUID, milestoneID *uuid.UUID, agentID uuid.UUID, req *transaction.ResolveDisputeRequest) (*transaction.Dispute, error)
+	MarkMilestoneDelivered(ctx context.Context, transactionID, milestoneID, sellerID uuid.UUID, deliveryProof, message string) (*transaction.Milestone, error)
+	ConfirmMilestone(ctx context.Context, transactionID, milestoneID, buyerID uuid.UUID) (*transaction.Milestone, error)
+	DisputeMilestone(ctx context.Context, transactionID, milestoneID, agentID uuid.UUID, req *transaction.DisputeRequest) (*transaction.Milestone, error)
+	GetRefunds(ctx context.Context, transactionID, agentID uuid.UUID) ([]*transaction.Refund, error)
+	CreateRefund(ctx context.Context, transactionID, agentID uuid.UUID, req *transaction.RefundRequest) (*transaction.Refund, error)
+	AcceptRefund(ctx context.Context, transactionID, refundID, sellerID uuid.UUID) (*transaction.Refund, error)
+	RejectRefund(ctx context.Context, transactionID, refundID, sellerID uuid.UUID, req *transaction.RejectRefundRequest) (*transaction.Refund, error)
 }
 
 // OrderHandler handles order/transaction HTTP requests.
@@ -130,6 +143,41 @@ func (h *OrderHandler) FundEscrow(w http.ResponseWriter, r *http.Request) {
 	common.WriteJSON(w, http.StatusOK, result)
 }
 
+// FundEscrowFromWallet handles POST /orders/{id}/fund-from-wallet - buyer funds escrow from their wallet balance.
+func (h *OrderHandler) FundEscrowFromWallet(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid order id"))
+		return
+	}
+
+	tx, err := h.service.FundEscrowFromWallet(r.Context(), id, agent.ID)
+	if err != nil {
+		switch err {
+		case transaction.ErrTransactionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order not found"))
+		case transaction.ErrNotAuthorized:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("only the buyer can fund escrow"))
+		case transaction.ErrInvalidStatus:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("order is not in a valid state for funding"))
+		case wallet.ErrInsufficientFunds:
+			common.WriteError(w, http.StatusPaymentRequired, common.ErrInsufficientFunds("insufficient wallet balance"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to fund escrow from wallet: "+err.Error()))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, tx)
+}
+
 // MarkDelivered handles POST /orders/{id}/deliver - seller marks order as delivered.
 func (h *OrderHandler) MarkDelivered(w http.ResponseWriter, r *http.Request) {
 	agent := middleware.GetAgent(r.Context())
@@ -160,6 +208,8 @@ func (h *OrderHandler) MarkDelivered(w http.ResponseWriter, r *http.Request) {
 			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("only the seller can mark as delivered"))
 		case transaction.ErrInvalidStatus:
 			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("order is not in a valid state for delivery"))
+		case transaction.ErrPaidInMilestones:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("order is paid in milestones; deliver each milestone instead"))
 		default:
 			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to mark as delivered"))
 		}
@@ -263,6 +313,47 @@ func (h *OrderHandler) GetRatings(w http.ResponseWriter, r *http.Request) {
 	common.WriteJSON(w, http.StatusOK, map[string]any{"ratings": ratings})
 }
 
+// SetInspectionWindow handles POST /orders/{id}/inspection-window - set how long the buyer has to inspect the delivery.
+func (h *OrderHandler) SetInspectionWindow(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid order id"))
+		return
+	}
+
+	var req transaction.SetInspectionWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	tx, err := h.service.SetInspectionWindow(r.Context(), id, agent.ID, req.Hours)
+	if err != nil {
+		switch err {
+		case transaction.ErrTransactionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order not found"))
+		case transaction.ErrNotAuthorized:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("only the buyer can set the inspection window"))
+		case transaction.ErrInvalidStatus:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("inspection window can only be set before delivery"))
+		case transaction.ErrInvalidInspectionWindow:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("inspection window is out of range"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to set inspection window"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, tx)
+}
+
 // DisputeOrder handles POST /orders/{id}/dispute - open a dispute.
 func (h *OrderHandler) DisputeOrder(w http.ResponseWriter, r *http.Request) {
 	agent := middleware.GetAgent(r.Context())
@@ -298,6 +389,8 @@ func (h *OrderHandler) DisputeOrder(w http.ResponseWriter, r *http.Request) {
 			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("not authorized to dispute this order"))
 		case transaction.ErrInvalidStatus:
 			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("order is not in a valid state for disputes"))
+		case transaction.ErrPaidInMilestones:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("order is paid in milestones; dispute a milestone instead"))
 		default:
 			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to open dispute"))
 		}
@@ -306,3 +399,440 @@ func (h *OrderHandler) DisputeOrder(w http.ResponseWriter, r *http.Request) {
 
 	common.WriteJSON(w, http.StatusOK, tx)
 }
+
+// GetDispute handles GET /orders/{id}/dispute and GET /orders/{id}/milestones/{milestoneId}/dispute - get the dispute and its evidence thread.
+func (h *OrderHandler) GetDispute(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid order id"))
+		return
+	}
+
+	milestoneID, err := parseMilestoneID(r)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid milestone id"))
+		return
+	}
+
+	dispute, err := h.service.GetDispute(r.Context(), id, milestoneID, agent.ID)
+	if err != nil {
+		switch err {
+		case transaction.ErrTransactionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order not found"))
+		case transaction.ErrDisputeNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order has no dispute"))
+		case transaction.ErrMilestoneNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("milestone not found"))
+		case transaction.ErrNotAuthorized:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("not authorized to view this dispute"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to get dispute"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, dispute)
+}
+
+// SubmitDisputeEvidence handles POST /orders/{id}/dispute/evidence and its milestone equivalent - add to the evidence thread.
+func (h *OrderHandler) SubmitDisputeEvidence(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid order id"))
+		return
+	}
+
+	milestoneID, err := parseMilestoneID(r)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid milestone id"))
+		return
+	}
+
+	var req transaction.SubmitEvidenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	evidence, err := h.service.SubmitEvidence(r.Context(), id, milestoneID, agent.ID, &req)
+	if err != nil {
+		switch err {
+		case transaction.ErrTransactionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order not found"))
+		case transaction.ErrDisputeNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order has no dispute"))
+		case transaction.ErrMilestoneNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("milestone not found"))
+		case transaction.ErrNotAuthorized:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("not authorized to submit evidence for this dispute"))
+		case transaction.ErrEmptyEvidence, transaction.ErrTooManyAttachments:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+		case transaction.ErrDisputeResolved:
+			common.WriteError(w, http.StatusConflict, common.ErrConflict("dispute has already been resolved"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to submit evidence"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusCreated, evidence)
+}
+
+// ResolveDispute handles POST /orders/{id}/dispute/resolve and its milestone equivalent - settle the dispute.
+func (h *OrderHandler) ResolveDispute(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid order id"))
+		return
+	}
+
+	milestoneID, err := parseMilestoneID(r)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid milestone id"))
+		return
+	}
+
+	var req transaction.ResolveDisputeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	dispute, err := h.service.ResolveDispute(r.Context(), id, milestoneID, agent.ID, &req)
+	if err != nil {
+		switch err {
+		case transaction.ErrTransactionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order not found"))
+		case transaction.ErrDisputeNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order has no dispute"))
+		case transaction.ErrMilestoneNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("milestone not found"))
+		case transaction.ErrNotAuthorized:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("not authorized to resolve this dispute this way"))
+		case transaction.ErrInvalidResolution:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("outcome must be release, refund or split with a seller_percent between 0 and 100"))
+		case transaction.ErrDisputeResolved:
+			common.WriteError(w, http.StatusConflict, common.ErrConflict("dispute has already been resolved"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to resolve dispute"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, dispute)
+}
+
+// MarkMilestoneDelivered handles POST /orders/{id}/milestones/{milestoneId}/deliver - seller delivers one milestone.
+func (h *OrderHandler) MarkMilestoneDelivered(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	id, milestoneID, ok := parseMilestonePath(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		DeliveryProof string `json:"delivery_proof"`
+		Message       string `json:"message"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	milestone, err := h.service.MarkMilestoneDelivered(r.Context(), id, milestoneID, agent.ID, req.DeliveryProof, req.Message)
+	if err != nil {
+		switch err {
+		case transaction.ErrTransactionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order not found"))
+		case transaction.ErrMilestoneNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("milestone not found"))
+		case transaction.ErrNotAuthorized:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("only the seller can deliver a milestone"))
+		case transaction.ErrInvalidStatus:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("milestone is not in a valid state for delivery"))
+		case transaction.ErrMilestoneOutOfOrder:
+			common.WriteError(w, http.StatusConflict, common.ErrConflict("earlier milestones must be settled first"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to deliver milestone"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, milestone)
+}
+
+// ConfirmMilestone handles POST /orders/{id}/milestones/{milestoneId}/confirm - buyer releases one milestone.
+func (h *OrderHandler) ConfirmMilestone(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	id, milestoneID, ok := parseMilestonePath(w, r)
+	if !ok {
+		return
+	}
+
+	milestone, err := h.service.ConfirmMilestone(r.Context(), id, milestoneID, agent.ID)
+	if err != nil {
+		switch err {
+		case transaction.ErrTransactionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order not found"))
+		case transaction.ErrMilestoneNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("milestone not found"))
+		case transaction.ErrNotAuthorized:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("only the buyer can confirm a milestone"))
+		case transaction.ErrInvalidStatus:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("milestone has not been delivered"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to confirm milestone"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, milestone)
+}
+
+// DisputeMilestone handles POST /orders/{id}/milestones/{milestoneId}/dispute - open a dispute on one milestone.
+func (h *OrderHandler) DisputeMilestone(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	id, milestoneID, ok := parseMilestonePath(w, r)
+	if !ok {
+		return
+	}
+
+	var req transaction.DisputeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	if req.Reason == "" {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("reason is required"))
+		return
+	}
+
+	milestone, err := h.service.DisputeMilestone(r.Context(), id, milestoneID, agent.ID, &req)
+	if err != nil {
+		switch err {
+		case transaction.ErrTransactionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order not found"))
+		case transaction.ErrMilestoneNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("milestone not found"))
+		case transaction.ErrNotAuthorized:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("not authorized to dispute this milestone"))
+		case transaction.ErrInvalidStatus:
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("milestone is not in a valid state for disputes"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to open dispute"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, milestone)
+}
+
+// GetRefunds handles GET /orders/{id}/refunds - list refunds and refund requests.
+func (h *OrderHandler) GetRefunds(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid order id"))
+		return
+	}
+
+	refunds, err := h.service.GetRefunds(r.Context(), id, agent.ID)
+	if err != nil {
+		switch err {
+		case transaction.ErrTransactionNotFound:
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order not found"))
+		case transaction.ErrNotAuthorized:
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("not authorized to view this order's refunds"))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to get refunds"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]any{"refunds": refunds})
+}
+
+// CreateRefund handles POST /orders/{id}/refunds - the seller refunds a completed order, the buyer requests a refund.
+func (h *OrderHandler) CreateRefund(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid order id"))
+		return
+	}
+
+	var req transaction.RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	refund, err := h.service.CreateRefund(r.Context(), id, agent.ID, &req)
+	if err != nil {
+		writeRefundError(w, err, "not authorized to refund this order", "failed to create refund")
+		return
+	}
+
+	common.WriteJSON(w, http.StatusCreated, refund)
+}
+
+// AcceptRefund handles POST /orders/{id}/refunds/{refundId}/accept - the seller pays a buyer's refund request.
+func (h *OrderHandler) AcceptRefund(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	id, refundID, ok := parseRefundPath(w, r)
+	if !ok {
+		return
+	}
+
+	refund, err := h.service.AcceptRefund(r.Context(), id, refundID, agent.ID)
+	if err != nil {
+		writeRefundError(w, err, "only the seller can accept a refund request", "failed to accept refund")
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, refund)
+}
+
+// RejectRefund handles POST /orders/{id}/refunds/{refundId}/reject - the seller turns down a buyer's refund request.
+func (h *OrderHandler) RejectRefund(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	id, refundID, ok := parseRefundPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req transaction.RejectRefundRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	refund, err := h.service.RejectRefund(r.Context(), id, refundID, agent.ID, &req)
+	if err != nil {
+		writeRefundError(w, err, "only the seller can reject a refund request", "failed to reject refund")
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, refund)
+}
+
+// writeRefundError maps refund errors to responses.
+func writeRefundError(w http.ResponseWriter, err error, forbidden, internal string) {
+	switch err {
+	case transaction.ErrTransactionNotFound:
+		common.WriteError(w, http.StatusNotFound, common.ErrNotFound("order not found"))
+	case transaction.ErrRefundNotFound:
+		common.WriteError(w, http.StatusNotFound, common.ErrNotFound("refund not found"))
+	case transaction.ErrNotAuthorized:
+		common.WriteError(w, http.StatusForbidden, common.ErrForbidden(forbidden))
+	case transaction.ErrInvalidStatus:
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("only completed orders can be refunded"))
+	case transaction.ErrRefundReasonRequired, transaction.ErrInvalidRefundAmount, transaction.ErrRefundExceedsPaid:
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+	case transaction.ErrRefundRequestOpen, transaction.ErrRefundNotRequested, transaction.ErrRefundNotPayable:
+		common.WriteError(w, http.StatusConflict, common.ErrConflict(err.Error()))
+	case wallet.ErrInsufficientFunds:
+		common.WriteError(w, http.StatusPaymentRequired, common.ErrInsufficientFunds("seller's wallet balance is too low for this refund"))
+	default:
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer(internal))
+	}
+}
+
+// parseRefundPath parses the order and refund IDs from the URL, writing a 400
+// and reporting false if either is invalid.
+func parseRefundPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid order id"))
+		return uuid.Nil, uuid.Nil, false
+	}
+	refundID, err := uuid.Parse(chi.URLParam(r, "refundId"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid refund id"))
+		return uuid.Nil, uuid.Nil, false
+	}
+	return id, refundID, true
+}
+
+// parseMilestonePath parses the order and milestone IDs from the URL, writing
+// a 400 and reporting false if either is invalid.
+func parseMilestonePath(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid order id"))
+		return uuid.Nil, uuid.Nil, false
+	}
+	milestoneID, err := uuid.Parse(chi.URLParam(r, "milestoneId"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid milestone id"))
+		return uuid.Nil, uuid.Nil, false
+	}
+	return id, milestoneID, true
+}
+
+// parseMilestoneID parses the optional milestoneId URL parameter of the
+// dispute routes that are shared between orders and their milestones.
+func parseMilestoneID(r *http.Request) (*uuid.UUID, error) {
+	idStr := chi.URLParam(r, "milestoneId")
+	if idStr == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/digi604/swarmmarket/backend/internal/agent"
 	"github.com/digi604/swarmmarket/backend/internal/transaction"
+	"github.com/digi604/swarmmarket/backend/internal/wallet"
 	"github.com/digi604/swarmmarket/backend/pkg/middleware"
 )
 
@@ -19,12 +20,16 @@ import (
 type mockTransactionService struct {
 	transactions map[uuid.UUID]*transaction.Transaction
 	escrows      map[uuid.UUID]*transaction.EscrowAccount
+	refunds      map[uuid.UUID]*transaction.Refund
+
+	walletBalance float64
 }
 
 func newMockTransactionService() *mockTransactionService {
 	return &mockTransactionService{
 		transactions: make(map[uuid.UUID]*transaction.Transaction),
 		escrows:      make(map[uuid.UUID]*transaction.EscrowAccount),
+		refunds:      make(map[uuid.UUID]*transaction.Refund),
 	}
 }
 
@@ -72,6 +77,25 @@ func (m *mockTransactionService) FundEscrow(ctx context.Context, transactionID,
 	}, nil
 }
 
+func (m *mockTransactionService) FundEscrowFromWallet(ctx context.Context, transactionID, buyerID uuid.UUID) (*transaction.Transaction, error) {
+	tx, ok := m.transactions[transactionID]
+	if !ok {
+		return nil, transaction.ErrTransactionNotFound
+	}
+	if tx.BuyerID != buyerID {
+		return nil, transaction.ErrNotAuthorized
+	}
+	if tx.Status != transaction.StatusPending {
+		return nil, transaction.ErrInvalidStatus
+	}
+	if m.walletBalance < tx.Amount {
+		return nil, wallet.ErrInsufficientFunds
+	}
+	m.walletBalance -= tx.Amount
+	tx.Status = transaction.StatusEscrowFunded
+	return tx, nil
+}
+
 func (m *mockTransactionService) MarkDelivered(ctx context.Context, transactionID, sellerID uuid.UUID, proof, message string) (*transaction.Transaction, error) {
 	tx, ok := m.transactions[transactionID]
 	if !ok {
@@ -87,6 +111,21 @@ func (m *mockTransactionService) MarkDelivered(ctx context.Context, transactionI
 	return tx, nil
 }
 
+func (m *mockTransactionService) SetInspectionWindow(ctx context.Context, transactionID, buyerID uuid.UUID, hours int) (*transaction.Transaction, error) {
+	tx, ok := m.transactions[transactionID]
+	if !ok {
+		return nil, transaction.ErrTransactionNotFound
+	}
+	if tx.BuyerID != buyerID {
+		return nil, transaction.ErrNotAuthorized
+	}
+	if hours < 1 {
+		return nil, transaction.ErrInvalidInspectionWindow
+	}
+	tx.InspectionWindow = &hours
+	return tx, nil
+}
+
 func (m *mockTransactionService) ConfirmDelivery(ctx context.Context, transactionID, buyerID uuid.UUID) (*transaction.Transaction, error) {
 	tx, ok := m.transactions[transactionID]
 	if !ok {
@@ -148,6 +187,196 @@ func (m *mockTransactionService) DisputeTransaction(ctx context.Context, transac
 	return tx, nil
 }
 
+func (m *mockTransactionService) GetDispute(ctx context.Context, transactionID uuid.UUID, milestoneID *uuid.UUID, agentID uuid.UUID) (*transaction.Dispute, error) {
+	tx, ok := m.transactions[transactionID]
+	if !ok {
+		return nil, transaction.ErrTransactionNotFound
+	}
+	if tx.BuyerID != agentID && tx.SellerID != agentID {
+		return nil, transaction.ErrNotAuthorized
+	}
+	if tx.Status != transaction.StatusDisputed {
+		return nil, transaction.ErrDisputeNotFound
+	}
+	return &transaction.Dispute{ID: uuid.New(), TransactionID: transactionID, Status: transaction.DisputeOpen}, nil
+}
+
+func (m *mockTransactionService) SubmitEvidence(ctx context.Context, transactionID uuid.UUID, milestoneID *uuid.UUID, agentID uuid.UUID, req *transaction.SubmitEvidenceRequest) (*transaction.DisputeEvidence, error) {
+	tx, ok := m.transactions[transactionID]
+	if !ok {
+		return nil, transaction.ErrTransactionNotFound
+	}
+	if req.Body == "" && len(req.Attachments) == 0 {
+		return nil, transaction.ErrEmptyEvidence
+	}
+	if tx.BuyerID != agentID && tx.SellerID != agentID {
+		return nil, transaction.ErrNotAuthorized
+	}
+	return &transaction.DisputeEvidence{ID: uuid.New(), AuthorID: agentID, Body: req.Body, Attachments: req.Attachments}, nil
+}
+
+func (m *mockTransactionService) ResolveDispute(ctx context.Context, transactionID uuid.UUID, milestoneID *uuid.UUID, agentID uuid.UUID, req *transaction.ResolveDisputeRequest) (*transaction.Dispute, error) {
+	tx, ok := m.transactions[transactionID]
+	if !ok {
+		return nil, transaction.ErrTransactionNotFound
+	}
+	if req.Outcome != transaction.OutcomeRelease && req.Outcome != transaction.OutcomeRefund && req.Outcome != transaction.OutcomeSplit {
+		return nil, transaction.ErrInvalidResolution
+	}
+	if agentID != tx.BuyerID || req.Outcome != transaction.OutcomeRelease {
+		return nil, transaction.ErrNotAuthorized
+	}
+	outcome := req.Outcome
+	tx.Status = transaction.StatusCompleted
+	return &transaction.Dispute{ID: uuid.New(), TransactionID: transactionID, Status: transaction.DisputeResolved, Outcome: &outcome}, nil
+}
+
+func (m *mockTransactionService) MarkMilestoneDelivered(ctx context.Context, transactionID, milestoneID, sellerID uuid.UUID, deliveryProof, message string) (*transaction.Milestone, error) {
+	tx, milestone, err := m.getMilestone(transactionID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.SellerID != sellerID {
+		return nil, transaction.ErrNotAuthorized
+	}
+	for _, earlier := range tx.Milestones[:milestone.Position-1] {
+		if earlier.Status != transaction.MilestoneReleased && earlier.Status != transaction.MilestoneRefunded {
+			return nil, transaction.ErrMilestoneOutOfOrder
+		}
+	}
+	milestone.Status = transaction.MilestoneDelivered
+	milestone.DeliveryProof = deliveryProof
+	return milestone, nil
+}
+
+func (m *mockTransactionService) ConfirmMilestone(ctx context.Context, transactionID, milestoneID, buyerID uuid.UUID) (*transaction.Milestone, error) {
+	tx, milestone, err := m.getMilestone(transactionID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.BuyerID != buyerID {
+		return nil, transaction.ErrNotAuthorized
+	}
+	if milestone.Status != transaction.MilestoneDelivered {
+		return nil, transaction.ErrInvalidStatus
+	}
+	milestone.Status = transaction.MilestoneReleased
+	milestone.ReleasedAmount = milestone.Amount
+	return milestone, nil
+}
+
+func (m *mockTransactionService) DisputeMilestone(ctx context.Context, transactionID, milestoneID, agentID uuid.UUID, req *transaction.DisputeRequest) (*transaction.Milestone, error) {
+	tx, milestone, err := m.getMilestone(transactionID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.BuyerID != agentID && tx.SellerID != agentID {
+		return nil, transaction.ErrNotAuthorized
+	}
+	milestone.Status = transaction.MilestoneDisputed
+	return milestone, nil
+}
+
+func (m *mockTransactionService) GetRefunds(ctx context.Context, transactionID, agentID uuid.UUID) ([]*transaction.Refund, error) {
+	tx, ok := m.transactions[transactionID]
+	if !ok {
+		return nil, transaction.ErrTransactionNotFound
+	}
+	if tx.BuyerID != agentID && tx.SellerID != agentID {
+		return nil, transaction.ErrNotAuthorized
+	}
+	var refunds []*transaction.Refund
+	for _, refund := range m.refunds {
+		if refund.TransactionID == transactionID {
+			refunds = append(refunds, refund)
+		}
+	}
+	return refunds, nil
+}
+
+func (m *mockTransactionService) CreateRefund(ctx context.Context, transactionID, agentID uuid.UUID, req *transaction.RefundRequest) (*transaction.Refund, error) {
+	if req.Reason == "" {
+		return nil, transaction.ErrRefundReasonRequired
+	}
+	tx, ok := m.transactions[transactionID]
+	if !ok {
+		return nil, transaction.ErrTransactionNotFound
+	}
+	if tx.BuyerID != agentID && tx.SellerID != agentID {
+		return nil, transaction.ErrNotAuthorized
+	}
+	if tx.Status != transaction.StatusCompleted {
+		return nil, transaction.ErrInvalidStatus
+	}
+	if req.Amount > tx.Amount {
+		return nil, transaction.ErrRefundExceedsPaid
+	}
+	refund := &transaction.Refund{ID: uuid.New(), TransactionID: transactionID, RequestedBy: agentID, Amount: req.Amount, Reason: req.Reason, Status: transaction.RefundCompleted}
+	if agentID == tx.BuyerID {
+		for _, existing := range m.refunds {
+			if existing.TransactionID == transactionID && existing.Status == transaction.RefundRequested {
+				return nil, transaction.ErrRefundRequestOpen
+			}
+		}
+		refund.Status = transaction.RefundRequested
+	}
+	m.refunds[refund.ID] = refund
+	return refund, nil
+}
+
+func (m *mockTransactionService) AcceptRefund(ctx context.Context, transactionID, refundID, sellerID uuid.UUID) (*transaction.Refund, error) {
+	refund, err := m.openRefundRequest(transactionID, refundID, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if m.walletBalance < refund.Amount {
+		return nil, wallet.ErrInsufficientFunds
+	}
+	refund.Status = transaction.RefundCompleted
+	return refund, nil
+}
+
+func (m *mockTransactionService) RejectRefund(ctx context.Context, transactionID, refundID, sellerID uuid.UUID, req *transaction.RejectRefundRequest) (*transaction.Refund, error) {
+	refund, err := m.openRefundRequest(transactionID, refundID, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	refund.Status = transaction.RefundRejected
+	refund.ResponseNote = req.Note
+	return refund, nil
+}
+
+func (m *mockTransactionService) openRefundRequest(transactionID, refundID, sellerID uuid.UUID) (*transaction.Refund, error) {
+	tx, ok := m.transactions[transactionID]
+	if !ok {
+		return nil, transaction.ErrTransactionNotFound
+	}
+	if tx.SellerID != sellerID {
+		return nil, transaction.ErrNotAuthorized
+	}
+	refund, ok := m.refunds[refundID]
+	if !ok || refund.TransactionID != transactionID {
+		return nil, transaction.ErrRefundNotFound
+	}
+	if refund.Status != transaction.RefundRequested {
+		return nil, transaction.ErrRefundNotRequested
+	}
+	return refund, nil
+}
+
+func (m *mockTransactionService) getMilestone(transactionID, milestoneID uuid.UUID) (*transaction.Transaction, *transaction.Milestone, error) {
+	tx, ok := m.transactions[transactionID]
+	if !ok {
+		return nil, nil, transaction.ErrTransactionNotFound
+	}
+	for _, milestone := range tx.Milestones {
+		if milestone.ID == milestoneID {
+			return tx, milestone, nil
+		}
+	}
+	return nil, nil, transaction.ErrMilestoneNotFound
+}
+
 func (m *mockTransactionService) addTransaction(tx *transaction.Transaction) {
 	m.transactions[tx.ID] = tx
 }
@@ -333,6 +562,54 @@ func TestOrderHandler_FundEscrow_NotBuyer(t *testing.T) {
 	}
 }
 
+func TestOrderHandler_FundEscrowFromWallet(t *testing.T) {
+	mockService := newMockTransactionService()
+	mockService.walletBalance = 150
+	handler := NewOrderHandler(mockService)
+
+	buyerID := uuid.New()
+	txID := uuid.New()
+	mockService.addTransaction(&transaction.Transaction{
+		ID:       txID,
+		BuyerID:  buyerID,
+		SellerID: uuid.New(),
+		Amount:   100.00,
+		Currency: "USD",
+		Status:   transaction.StatusPending,
+	})
+
+	fund := func() *httptest.ResponseRecorder {
+		req := createAuthenticatedRequest(t, "POST", "/orders/"+txID.String()+"/fund-from-wallet", nil, buyerID)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", txID.String())
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rr := httptest.NewRecorder()
+		handler.FundEscrowFromWallet(rr, req)
+		return rr
+	}
+
+	rr := fund()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var result transaction.Transaction
+	json.NewDecoder(rr.Body).Decode(&result)
+	if result.Status != transaction.StatusEscrowFunded {
+		t.Errorf("expected escrow_funded, got %s", result.Status)
+	}
+
+	// Funded orders can't be funded again
+	if rr := fund(); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 funding twice, got %d", rr.Code)
+	}
+
+	// Not enough left in the wallet for another order
+	mockService.transactions[txID].Status = transaction.StatusPending
+	if rr := fund(); rr.Code != http.StatusPaymentRequired {
+		t.Errorf("expected status 402 with insufficient funds, got %d", rr.Code)
+	}
+}
+
 func TestOrderHandler_MarkDelivered(t *testing.T) {
 	mockService := newMockTransactionService()
 	handler := NewOrderHandler(mockService)
@@ -542,6 +819,312 @@ func TestOrderHandler_DisputeOrder(t *testing.T) {
 	}
 }
 
+func TestOrderHandler_SetInspectionWindow(t *testing.T) {
+	mockService := newMockTransactionService()
+	handler := NewOrderHandler(mockService)
+
+	buyerID := uuid.New()
+	sellerID := uuid.New()
+	txID := uuid.New()
+
+	mockService.addTransaction(&transaction.Transaction{
+		ID:       txID,
+		BuyerID:  buyerID,
+		SellerID: sellerID,
+		Amount:   100.00,
+		Currency: "USD",
+		Status:   transaction.StatusEscrowFunded,
+	})
+
+	tests := []struct {
+		name           string
+		agentID        uuid.UUID
+		hours          int
+		expectedStatus int
+	}{
+		{"buyer", buyerID, 168, http.StatusOK},
+		{"seller", sellerID, 168, http.StatusForbidden},
+		{"out of range", buyerID, 0, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := transaction.SetInspectionWindowRequest{Hours: tt.hours}
+			req := createAuthenticatedRequest(t, "POST", "/orders/"+txID.String()+"/inspection-window", body, tt.agentID)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", txID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler.SetInspectionWindow(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestOrderHandler_SubmitDisputeEvidence(t *testing.T) {
+	mockService := newMockTransactionService()
+	handler := NewOrderHandler(mockService)
+
+	buyerID := uuid.New()
+	sellerID := uuid.New()
+	txID := uuid.New()
+
+	mockService.addTransaction(&transaction.Transaction{
+		ID:       txID,
+		BuyerID:  buyerID,
+		SellerID: sellerID,
+		Amount:   100.00,
+		Currency: "USD",
+		Status:   transaction.StatusDisputed,
+	})
+
+	tests := []struct {
+		name           string
+		body           transaction.SubmitEvidenceRequest
+		expectedStatus int
+	}{
+		{"with body", transaction.SubmitEvidenceRequest{Body: "Delivered on time", Attachments: []string{"https://example.com/log.txt"}}, http.StatusCreated},
+		{"empty", transaction.SubmitEvidenceRequest{}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createAuthenticatedRequest(t, "POST", "/orders/"+txID.String()+"/dispute/evidence", tt.body, sellerID)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", txID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler.SubmitDisputeEvidence(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestOrderHandler_ResolveDispute(t *testing.T) {
+	mockService := newMockTransactionService()
+	handler := NewOrderHandler(mockService)
+
+	buyerID := uuid.New()
+	sellerID := uuid.New()
+	txID := uuid.New()
+
+	mockService.addTransaction(&transaction.Transaction{
+		ID:       txID,
+		BuyerID:  buyerID,
+		SellerID: sellerID,
+		Amount:   100.00,
+		Currency: "USD",
+		Status:   transaction.StatusDisputed,
+	})
+
+	tests := []struct {
+		name           string
+		agentID        uuid.UUID
+		body           transaction.ResolveDisputeRequest
+		expectedStatus int
+	}{
+		{"invalid outcome", buyerID, transaction.ResolveDisputeRequest{Outcome: "halve"}, http.StatusBadRequest},
+		{"seller cannot release", sellerID, transaction.ResolveDisputeRequest{Outcome: transaction.OutcomeRelease}, http.StatusForbidden},
+		{"buyer concedes", buyerID, transaction.ResolveDisputeRequest{Outcome: transaction.OutcomeRelease}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createAuthenticatedRequest(t, "POST", "/orders/"+txID.String()+"/dispute/resolve", tt.body, tt.agentID)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", txID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler.ResolveDispute(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestOrderHandler_Milestones(t *testing.T) {
+	mockService := newMockTransactionService()
+	handler := NewOrderHandler(mockService)
+
+	buyerID := uuid.New()
+	sellerID := uuid.New()
+	txID := uuid.New()
+	first := &transaction.Milestone{ID: uuid.New(), TransactionID: txID, Position: 1, Title: "Design", Amount: 40, Status: transaction.MilestonePending}
+	second := &transaction.Milestone{ID: uuid.New(), TransactionID: txID, Position: 2, Title: "Build", Amount: 60, Status: transaction.MilestonePending}
+
+	mockService.addTransaction(&transaction.Transaction{
+		ID:         txID,
+		BuyerID:    buyerID,
+		SellerID:   sellerID,
+		Amount:     100.00,
+		Currency:   "USD",
+		Status:     transaction.StatusEscrowFunded,
+		Milestones: []*transaction.Milestone{first, second},
+	})
+
+	tests := []struct {
+		name           string
+		action         string
+		milestoneID    string
+		agentID        uuid.UUID
+		expectedStatus int
+	}{
+		{"deliver out of order", "deliver", second.ID.String(), sellerID, http.StatusConflict},
+		{"buyer cannot deliver", "deliver", first.ID.String(), buyerID, http.StatusForbidden},
+		{"unknown milestone", "deliver", uuid.New().String(), sellerID, http.StatusNotFound},
+		{"invalid milestone id", "deliver", "first", sellerID, http.StatusBadRequest},
+		{"confirm before delivery", "confirm", first.ID.String(), buyerID, http.StatusBadRequest},
+		{"deliver", "deliver", first.ID.String(), sellerID, http.StatusOK},
+		{"seller cannot confirm", "confirm", first.ID.String(), sellerID, http.StatusForbidden},
+		{"confirm", "confirm", first.ID.String(), buyerID, http.StatusOK},
+		{"dispute", "dispute", second.ID.String(), buyerID, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]string{"delivery_proof": "https://example.com/design.pdf", "reason": "Incomplete"}
+			req := createAuthenticatedRequest(t, "POST", "/orders/"+txID.String()+"/milestones/"+tt.milestoneID+"/"+tt.action, body, tt.agentID)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", txID.String())
+			rctx.URLParams.Add("milestoneId", tt.milestoneID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			switch tt.action {
+			case "deliver":
+				handler.MarkMilestoneDelivered(rr, req)
+			case "confirm":
+				handler.ConfirmMilestone(rr, req)
+			case "dispute":
+				handler.DisputeMilestone(rr, req)
+			}
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	if first.Status != transaction.MilestoneReleased || second.Status != transaction.MilestoneDisputed {
+		t.Errorf("expected first released and second disputed, got %s/%s", first.Status, second.Status)
+	}
+}
+
+func TestOrderHandler_Refunds(t *testing.T) {
+	mockService := newMockTransactionService()
+	handler := NewOrderHandler(mockService)
+
+	buyerID := uuid.New()
+	sellerID := uuid.New()
+	txID := uuid.New()
+
+	mockService.addTransaction(&transaction.Transaction{
+		ID:       txID,
+		BuyerID:  buyerID,
+		SellerID: sellerID,
+		Amount:   100.00,
+		Currency: "USD",
+		Status:   transaction.StatusCompleted,
+	})
+
+	call := func(h http.HandlerFunc, path string, body any, agentID uuid.UUID, refundID string) *httptest.ResponseRecorder {
+		req := createAuthenticatedRequest(t, "POST", "/orders/"+txID.String()+path, body, agentID)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", txID.String())
+		if refundID != "" {
+			rctx.URLParams.Add("refundId", refundID)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rr := httptest.NewRecorder()
+		h(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name           string
+		agentID        uuid.UUID
+		body           transaction.RefundRequest
+		expectedStatus int
+	}{
+		{"reason required", sellerID, transaction.RefundRequest{Amount: 10}, http.StatusBadRequest},
+		{"more than was paid", sellerID, transaction.RefundRequest{Amount: 150, Reason: "Broken"}, http.StatusBadRequest},
+		{"stranger", uuid.New(), transaction.RefundRequest{Amount: 10, Reason: "Broken"}, http.StatusForbidden},
+		{"seller refunds", sellerID, transaction.RefundRequest{Amount: 10, Reason: "One item missing"}, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := call(handler.CreateRefund, "/refunds", tt.body, tt.agentID, "")
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	// The buyer requests a refund, one at a time
+	rr := call(handler.CreateRefund, "/refunds", transaction.RefundRequest{Amount: 30, Reason: "Arrived damaged"}, buyerID, "")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var requested transaction.Refund
+	json.NewDecoder(rr.Body).Decode(&requested)
+	if requested.Status != transaction.RefundRequested {
+		t.Errorf("expected a refund request, got %s", requested.Status)
+	}
+	if rr := call(handler.CreateRefund, "/refunds", transaction.RefundRequest{Amount: 5, Reason: "Late"}, buyerID, ""); rr.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for a second request, got %d", rr.Code)
+	}
+
+	acceptPath := "/refunds/" + requested.ID.String() + "/accept"
+	if rr := call(handler.AcceptRefund, acceptPath, nil, buyerID, requested.ID.String()); rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for the buyer accepting, got %d", rr.Code)
+	}
+	if rr := call(handler.AcceptRefund, acceptPath, nil, sellerID, "not-a-uuid"); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid refund id, got %d", rr.Code)
+	}
+	// Paid from the seller's wallet, which is empty
+	if rr := call(handler.AcceptRefund, acceptPath, nil, sellerID, requested.ID.String()); rr.Code != http.StatusPaymentRequired {
+		t.Errorf("expected status 402 with insufficient funds, got %d", rr.Code)
+	}
+
+	rejectPath := "/refunds/" + requested.ID.String() + "/reject"
+	rr = call(handler.RejectRefund, rejectPath, transaction.RejectRefundRequest{Note: "Photos show it intact"}, sellerID, requested.ID.String())
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := call(handler.AcceptRefund, acceptPath, nil, sellerID, requested.ID.String()); rr.Code != http.StatusConflict {
+		t.Errorf("expected status 409 accepting a rejected request, got %d", rr.Code)
+	}
+
+	req := createAuthenticatedRequest(t, "GET", "/orders/"+txID.String()+"/refunds", nil, buyerID)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", txID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr = httptest.NewRecorder()
+	handler.GetRefunds(rr, req)
+	var listed struct {
+		Refunds []*transaction.Refund `json:"refunds"`
+	}
+	json.NewDecoder(rr.Body).Decode(&listed)
+	if rr.Code != http.StatusOK || len(listed.Refunds) != 2 {
+		t.Errorf("expected 2 refunds, got %d with status %d", len(listed.Refunds), rr.Code)
+	}
+}
+
 func TestOrderHandler_Unauthenticated(t *testing.T) {
 	mockService := newMockTransactionService()
 	handler := NewOrderHandler(mockService)
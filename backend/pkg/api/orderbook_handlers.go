@@ -2,18 +2,22 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/google/uuid"
 	"github.com/digi604/swarmmarket/backend/internal/common"
 	"github.com/digi604/swarmmarket/backend/internal/matching"
 	"github.com/digi604/swarmmarket/backend/pkg/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 // OrderBookHandler handles order book HTTP requests.
 type OrderBookHandler struct {
-	engine *matching.Engine
+	engine       *matching.Engine
+	listingOwner ListingOwnerChecker
 }
 
 // NewOrderBookHandler creates a new order book handler.
@@ -21,13 +25,23 @@ func NewOrderBookHandler(engine *matching.Engine) *OrderBookHandler {
 	return &OrderBookHandler{engine: engine}
 }
 
+// SetListingOwnerChecker sets the checker that limits listing instruments to
+// the listing's seller. Without it, instruments can't be tied to listings.
+func (h *OrderBookHandler) SetListingOwnerChecker(checker ListingOwnerChecker) {
+	h.listingOwner = checker
+}
+
 // PlaceOrderRequest is the request body for placing an order.
 type PlaceOrderRequest struct {
-	ProductID string  `json:"product_id"`
-	Side      string  `json:"side"`     // "buy" or "sell"
-	Type      string  `json:"type"`     // "limit" or "market"
-	Price     float64 `json:"price"`    // Required for limit orders
-	Quantity  float64 `json:"quantity"`
+	ProductID   string     `json:"product_id"`
+	Side        string     `json:"side"`                 // "buy" or "sell"
+	Type        string     `json:"type"`                 // "limit", "market", "stop" or "stop_limit"
+	Price       float64    `json:"price"`                // Required for limit and stop_limit orders
+	StopPrice   float64    `json:"stop_price,omitempty"` // Required for stop and stop_limit orders
+	Quantity    float64    `json:"quantity"`
+	TimeInForce string     `json:"time_in_force,omitempty"` // "GTC" (default for limit), "GTD", "IOC" (default for market), "FOK"
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`    // Required for GTD
+	STP         string     `json:"stp,omitempty"`           // Self-trade prevention: "none", "cancel_newest", "cancel_oldest", "cancel_both", "decrement"
 }
 
 // PlaceOrder handles POST /orderbook/orders - place a new order.
@@ -61,39 +75,264 @@ func (h *OrderBookHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Type != "limit" && req.Type != "market" {
-		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("type must be 'limit' or 'market'"))
+	if req.Type != "limit" && req.Type != "market" && req.Type != "stop" && req.Type != "stop_limit" {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("type must be 'limit', 'market', 'stop' or 'stop_limit'"))
 		return
 	}
 
-	if req.Type == "limit" && req.Price <= 0 {
+	if (req.Type == "limit" || req.Type == "stop_limit") && req.Price <= 0 {
 		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("price is required for limit orders"))
 		return
 	}
 
+	if (req.Type == "stop" || req.Type == "stop_limit") && req.StopPrice <= 0 {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("stop_price is required for stop orders"))
+		return
+	}
+
 	if req.Quantity <= 0 {
 		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("quantity must be positive"))
 		return
 	}
 
+	tif := matching.TimeInForce(strings.ToUpper(req.TimeInForce))
+	switch tif {
+	case "", matching.TimeInForceIOC, matching.TimeInForceFOK:
+	case matching.TimeInForceGTC, matching.TimeInForceGTD:
+		if req.Type == "market" || req.Type == "stop" {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("market and stop orders must be IOC or FOK"))
+			return
+		}
+	default:
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("time_in_force must be 'GTC', 'GTD', 'IOC' or 'FOK'"))
+		return
+	}
+
+	if tif == matching.TimeInForceGTD && req.ExpiresAt == nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("expires_at is required for GTD orders"))
+		return
+	}
+
+	stp := matching.STPMode(strings.ToLower(req.STP))
+	if stp != "" && !stp.Valid() {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("stp must be 'none', 'cancel_newest', 'cancel_oldest', 'cancel_both' or 'decrement'"))
+		return
+	}
+
 	order := &matching.Order{
-		AgentID:   agent.ID,
-		ProductID: productID,
-		Side:      matching.OrderSide(req.Side),
-		Type:      matching.OrderType(req.Type),
-		Price:     req.Price,
-		Quantity:  req.Quantity,
+		AgentID:     agent.ID,
+		ProductID:   productID,
+		Side:        matching.OrderSide(req.Side),
+		Type:        matching.OrderType(req.Type),
+		Price:       req.Price,
+		StopPrice:   req.StopPrice,
+		Quantity:    req.Quantity,
+		TimeInForce: tif,
+		ExpiresAt:   req.ExpiresAt,
+		STP:         stp,
+		OwnerID:     agent.OwnerUserID,
 	}
 
 	result, err := h.engine.PlaceOrder(r.Context(), order)
 	if err != nil {
+		if errors.Is(err, matching.ErrInvalidTimeInForce) || errors.Is(err, matching.ErrInvalidExpiry) ||
+			errors.Is(err, matching.ErrInvalidStopPrice) || errors.Is(err, matching.ErrInvalidSTPMode) {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+			return
+		}
+		if writeInstrumentError(w, err) {
+			return
+		}
 		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to place order"))
 		return
 	}
 
 	common.WriteJSON(w, http.StatusCreated, map[string]any{
-		"order":  order,
-		"trades": result.Trades,
+		"order":         order,
+		"trades":        result.Trades,
+		"cancelled_qty": result.CancelledQty,
+	})
+}
+
+// AmendOrderRequest is the request body for amending an order.
+type AmendOrderRequest struct {
+	Price     *float64 `json:"price,omitempty"`      // New limit price, re-queues the order
+	StopPrice *float64 `json:"stop_price,omitempty"` // New trigger price of an untriggered stop order
+	Quantity  *float64 `json:"quantity,omitempty"`   // New total quantity; reducing it keeps time priority
+}
+
+// AmendOrder handles PATCH /orderbook/orders/{orderId} - change a working order.
+func (h *OrderBookHandler) AmendOrder(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid order_id"))
+		return
+	}
+
+	var req AmendOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	if req.Price == nil && req.StopPrice == nil && req.Quantity == nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("price, stop_price or quantity is required"))
+		return
+	}
+
+	order, result, err := h.engine.AmendOrder(r.Context(), orderID, agent.ID, matching.OrderAmendment{
+		Price:     req.Price,
+		StopPrice: req.StopPrice,
+		Quantity:  req.Quantity,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, matching.ErrNotAuthorized):
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden(err.Error()))
+		case errors.Is(err, matching.ErrOrderNotFound):
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound(err.Error()))
+		case errors.Is(err, matching.ErrInvalidAmendment), errors.Is(err, matching.ErrInvalidQuantity),
+			errors.Is(err, matching.ErrInvalidPrice), errors.Is(err, matching.ErrInvalidStopPrice):
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+		default:
+			if !writeInstrumentError(w, err) {
+				common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to amend order"))
+			}
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]any{
+		"order":         order,
+		"trades":        result.Trades,
+		"cancelled_qty": result.CancelledQty,
+	})
+}
+
+// ListOrders handles GET /orderbook/orders - list the agent's orders.
+func (h *OrderBookHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	params := matching.ListOrdersParams{
+		AgentID: agent.ID,
+		Limit:   parseIntParam(r, "limit", 20),
+		Offset:  parseIntParam(r, "offset", 0),
+	}
+	if params.Limit <= 0 || params.Limit > 100 {
+		params.Limit = 100
+	}
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+
+	if v := r.URL.Query().Get("product_id"); v != "" {
+		productID, err := uuid.Parse(v)
+		if err != nil {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid product_id"))
+			return
+		}
+		params.ProductID = &productID
+	}
+
+	if v := r.URL.Query().Get("side"); v != "" {
+		if v != "buy" && v != "sell" {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("side must be 'buy' or 'sell'"))
+			return
+		}
+		side := matching.OrderSide(v)
+		params.Side = &side
+	}
+
+	// Comma-separated; "working" is shorthand for every status that can still trade
+	if v := r.URL.Query().Get("status"); v != "" {
+		for _, status := range strings.Split(v, ",") {
+			switch s := matching.OrderStatus(strings.TrimSpace(status)); s {
+			case "working":
+				params.Statuses = append(params.Statuses, matching.OrderStatusPending, matching.OrderStatusOpen, matching.OrderStatusPartial)
+			case matching.OrderStatusPending, matching.OrderStatusOpen, matching.OrderStatusPartial,
+				matching.OrderStatusFilled, matching.OrderStatusCancelled, matching.OrderStatusExpired:
+				params.Statuses = append(params.Statuses, s)
+			default:
+				common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid status: "+status))
+				return
+			}
+		}
+	}
+
+	result, err := h.engine.ListOrders(r.Context(), params)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to list orders"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, result)
+}
+
+// GetOrder handles GET /orderbook/orders/{orderId} - get one of the agent's orders.
+func (h *OrderBookHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid order_id"))
+		return
+	}
+
+	order, err := h.engine.GetOrder(r.Context(), orderID, agent.ID)
+	if err != nil {
+		if errors.Is(err, matching.ErrOrderNotFound) {
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound(err.Error()))
+			return
+		}
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to get order"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, order)
+}
+
+// GetOrderFills handles GET /orderbook/orders/{orderId}/fills - get an order's fill history.
+func (h *OrderBookHandler) GetOrderFills(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	orderID, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid order_id"))
+		return
+	}
+
+	fills, err := h.engine.GetOrderFills(r.Context(), orderID, agent.ID)
+	if err != nil {
+		if errors.Is(err, matching.ErrOrderNotFound) {
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound(err.Error()))
+			return
+		}
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to get fills"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]any{
+		"order_id": orderID,
+		"fills":    fills,
+		"total":    len(fills),
 	})
 }
 
@@ -115,6 +354,82 @@ func (h *OrderBookHandler) GetOrderBook(w http.ResponseWriter, r *http.Request)
 	common.WriteJSON(w, http.StatusOK, book)
 }
 
+// GetCandles handles GET /orderbook/{productId}/candles - get OHLCV price history.
+func (h *OrderBookHandler) GetCandles(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "productId"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid product_id"))
+		return
+	}
+
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = "1h"
+	}
+	step, ok := matching.CandleIntervals[interval]
+	if !ok {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(matching.ErrInvalidInterval.Error()))
+		return
+	}
+
+	limit := parseIntParam(r, "limit", 100)
+	if limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+
+	to := time.Now().UTC()
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("to must be an RFC 3339 timestamp"))
+			return
+		}
+	}
+	from := to.Add(-time.Duration(limit) * step)
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("from must be an RFC 3339 timestamp"))
+			return
+		}
+	}
+	if !from.Before(to) {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("from must be before to"))
+		return
+	}
+
+	candles, err := h.engine.GetCandles(r.Context(), productID, interval, from, to, limit)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to get candles"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]any{
+		"product_id": productID,
+		"interval":   interval,
+		"candles":    candles,
+		"stats_24h":  h.engine.GetTicker(productID, time.Now().UTC()),
+	})
+}
+
+// GetTicker handles GET /orderbook/{productId}/ticker - get a product's ticker.
+func (h *OrderBookHandler) GetTicker(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "productId"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid product_id"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, h.engine.GetTicker(productID, time.Now().UTC()))
+}
+
+// GetTickers handles GET /orderbook/tickers - get tickers of all active products.
+func (h *OrderBookHandler) GetTickers(w http.ResponseWriter, r *http.Request) {
+	tickers := h.engine.GetTickers(time.Now().UTC())
+	common.WriteJSON(w, http.StatusOK, map[string]any{
+		"tickers": tickers,
+		"total":   len(tickers),
+	})
+}
+
 // CancelOrder handles DELETE /orderbook/orders/{orderId} - cancel an order.
 func (h *OrderBookHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 	agent := middleware.GetAgent(r.Context())
@@ -130,12 +445,12 @@ func (h *OrderBookHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.engine.CancelOrder(orderID, agent.ID); err != nil {
-		if err.Error() == "not authorized to cancel this order" {
+	if err := h.engine.CancelOrder(r.Context(), orderID, agent.ID); err != nil {
+		if errors.Is(err, matching.ErrNotAuthorized) {
 			common.WriteError(w, http.StatusForbidden, common.ErrForbidden(err.Error()))
 			return
 		}
-		if err.Error() == "order not found" {
+		if errors.Is(err, matching.ErrOrderNotFound) {
 			common.WriteError(w, http.StatusNotFound, common.ErrNotFound(err.Error()))
 			return
 		}
@@ -145,3 +460,198 @@ func (h *OrderBookHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// writeInstrumentError writes the response for an order rejected by its
+// instrument's rules, reporting whether err was one.
+func writeInstrumentError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, matching.ErrInstrumentHalted):
+		common.WriteError(w, http.StatusConflict, common.ErrConflict(err.Error()))
+	case errors.Is(err, matching.ErrUnknownInstrument), errors.Is(err, matching.ErrInvalidTickSize),
+		errors.Is(err, matching.ErrInvalidLotSize), errors.Is(err, matching.ErrPriceOutOfBand):
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+	default:
+		return false
+	}
+	return true
+}
+
+// CreateInstrumentRequest is the request body for registering an instrument.
+type CreateInstrumentRequest struct {
+	ProductID  string  `json:"product_id,omitempty"` // Existing product to register, otherwise a new ID is assigned. Once traded, only its seller or an admin can
+	Symbol     string  `json:"symbol"`
+	Name       string  `json:"name"`
+	CategoryID string  `json:"category_id,omitempty"` // Category or listing is required
+	ListingID  string  `json:"listing_id,omitempty"`  // Only the listing's seller can register it
+	Currency   string  `json:"currency,omitempty"`    // Defaults to USD
+	TickSize   float64 `json:"tick_size"`
+	LotSize    float64 `json:"lot_size"`
+	MinPrice   float64 `json:"min_price,omitempty"`
+	MaxPrice   float64 `json:"max_price,omitempty"` // 0 for no upper bound
+}
+
+// CreateInstrument handles POST /orderbook/instruments - register a tradable instrument.
+func (h *OrderBookHandler) CreateInstrument(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	var req CreateInstrumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	inst := &matching.Instrument{
+		Symbol:    req.Symbol,
+		Name:      req.Name,
+		Currency:  req.Currency,
+		TickSize:  req.TickSize,
+		LotSize:   req.LotSize,
+		MinPrice:  req.MinPrice,
+		MaxPrice:  req.MaxPrice,
+		CreatedBy: agent.ID,
+	}
+
+	if req.ProductID != "" {
+		id, err := uuid.Parse(req.ProductID)
+		if err != nil {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid product_id"))
+			return
+		}
+		inst.ID = id
+	}
+
+	if req.CategoryID != "" {
+		id, err := uuid.Parse(req.CategoryID)
+		if err != nil {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid category_id"))
+			return
+		}
+		inst.CategoryID = &id
+	}
+
+	if req.ListingID != "" {
+		id, err := uuid.Parse(req.ListingID)
+		if err != nil {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid listing_id"))
+			return
+		}
+		if h.listingOwner == nil {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("listing instruments are not available"))
+			return
+		}
+		isOwner, err := h.listingOwner.IsListingOwner(r.Context(), id, agent.ID)
+		if err != nil {
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("listing not found"))
+			return
+		}
+		if !isOwner {
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("only the listing's seller can register it"))
+			return
+		}
+		inst.ListingID = &id
+	}
+
+	if err := h.engine.CreateInstrument(r.Context(), inst); err != nil {
+		if errors.Is(err, matching.ErrInvalidInstrument) {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+			return
+		}
+		if errors.Is(err, matching.ErrInstrumentExists) {
+			common.WriteError(w, http.StatusConflict, common.ErrConflict(err.Error()))
+			return
+		}
+		if errors.Is(err, matching.ErrProductInUse) {
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden(err.Error()))
+			return
+		}
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to create instrument"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusCreated, inst)
+}
+
+// UpdateInstrument handles PATCH /orderbook/instruments/{instrumentId} - change
+// an instrument's trading rules or halt and resume trading.
+func (h *OrderBookHandler) UpdateInstrument(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	instrumentID, err := uuid.Parse(chi.URLParam(r, "instrumentId"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid instrument_id"))
+		return
+	}
+
+	var req matching.InstrumentUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	inst, err := h.engine.UpdateInstrument(r.Context(), instrumentID, agent.ID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, matching.ErrUnknownInstrument):
+			common.WriteError(w, http.StatusNotFound, common.ErrNotFound("instrument not found"))
+		case errors.Is(err, matching.ErrNotAuthorized):
+			common.WriteError(w, http.StatusForbidden, common.ErrForbidden("only the instrument's creator can change it"))
+		case errors.Is(err, matching.ErrInvalidInstrument):
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+		default:
+			common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to update instrument"))
+		}
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, inst)
+}
+
+// GetInstrument handles GET /orderbook/instruments/{instrumentId} - get an instrument.
+func (h *OrderBookHandler) GetInstrument(w http.ResponseWriter, r *http.Request) {
+	instrumentID, err := uuid.Parse(chi.URLParam(r, "instrumentId"))
+	if err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid instrument_id"))
+		return
+	}
+
+	inst, err := h.engine.GetInstrument(instrumentID)
+	if err != nil {
+		common.WriteError(w, http.StatusNotFound, common.ErrNotFound("instrument not found"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, inst)
+}
+
+// ListInstruments handles GET /orderbook/instruments - list tradable instruments.
+func (h *OrderBookHandler) ListInstruments(w http.ResponseWriter, r *http.Request) {
+	var categoryID *uuid.UUID
+	if v := r.URL.Query().Get("category_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid category_id"))
+			return
+		}
+		categoryID = &id
+	}
+
+	status := matching.InstrumentStatus(r.URL.Query().Get("status"))
+	if status != "" && status != matching.InstrumentStatusOpen && status != matching.InstrumentStatusHalted {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("status must be 'open' or 'halted'"))
+		return
+	}
+
+	instruments := h.engine.ListInstruments(categoryID, status)
+	common.WriteJSON(w, http.StatusOK, map[string]any{
+		"instruments": instruments,
+		"total":       len(instruments),
+	})
+}
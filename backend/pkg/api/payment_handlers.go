@@ -12,6 +12,7 @@ import (
 	"github.com/digi604/swarmmarket/backend/internal/payment"
 	"github.com/digi604/swarmmarket/backend/internal/transaction"
 	"github.com/digi604/swarmmarket/backend/internal/user"
+	"github.com/digi604/swarmmarket/backend/internal/wallet"
 	"github.com/digi604/swarmmarket/backend/pkg/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -27,6 +28,7 @@ type PaymentHandler struct {
 	paymentService     *payment.Service
 	transactionService *transaction.Service
 	userRepo           *user.Repository
+	walletService      *wallet.Service
 	webhookSecret      string
 }
 
@@ -44,6 +46,11 @@ func (h *PaymentHandler) SetUserRepo(repo *user.Repository) {
 	h.userRepo = repo
 }
 
+// SetWalletService sets the wallet service for deposit webhook handling.
+func (h *PaymentHandler) SetWalletService(walletService *wallet.Service) {
+	h.walletService = walletService
+}
+
 // CreatePaymentIntentRequest is the request body for creating a payment.
 type CreatePaymentIntentRequest struct {
 	TransactionID string `json:"transaction_id"`
@@ -170,7 +177,9 @@ func (h *PaymentHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 
 	// Handle different event types
 	switch event.Type {
-	case "payment_intent.succeeded":
+	// Escrow is authorized for manual capture, so it is funded once the amount
+	// is capturable; milestone escrow is only captured as milestones are released
+	case "payment_intent.amount_capturable_updated", "payment_intent.succeeded":
 		h.handlePaymentSucceeded(r.Context(), event.Data.Raw)
 
 	case "payment_intent.payment_failed":
@@ -199,6 +208,14 @@ func (h *PaymentHandler) handlePaymentSucceeded(ctx context.Context, data []byte
 		return
 	}
 
+	// Wallet deposits are paid like escrow but credit a wallet instead
+	if depositID, ok := h.walletDepositID(pi.Metadata); ok {
+		if pi.Status == "succeeded" {
+			h.walletService.ConfirmDeposit(ctx, depositID)
+		}
+		return
+	}
+
 	transactionIDStr, ok := pi.Metadata["transaction_id"]
 	if !ok {
 		return
@@ -231,6 +248,11 @@ func (h *PaymentHandler) handlePaymentFailed(ctx context.Context, data []byte) {
 		failureReason = pi.LastPaymentError.Message
 	}
 
+	if depositID, ok := h.walletDepositID(pi.Metadata); ok {
+		h.walletService.FailDeposit(ctx, depositID, failureReason)
+		return
+	}
+
 	transactionIDStr, ok := pi.Metadata["transaction_id"]
 	if !ok {
 		return
@@ -249,15 +271,34 @@ func (h *PaymentHandler) handlePaymentFailed(ctx context.Context, data []byte) {
 	h.transactionService.PublishPaymentFailed(ctx, tx, pi.ID, failureReason)
 }
 
+// walletDepositID returns the wallet deposit a payment intent pays for, if any.
+func (h *PaymentHandler) walletDepositID(metadata map[string]string) (uuid.UUID, bool) {
+	if h.walletService == nil {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(metadata["wallet_deposit_id"])
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
 func (h *PaymentHandler) handleRefund(ctx context.Context, data []byte) {
 	var charge struct {
 		PaymentIntent string            `json:"payment_intent"`
+		Refunded      bool              `json:"refunded"`
 		Metadata      map[string]string `json:"metadata"`
 	}
 	if err := json.Unmarshal(data, &charge); err != nil {
 		return
 	}
 
+	// A partial refund, such as the uncaptured remainder of a dispute split,
+	// leaves the seller's share in place.
+	if !charge.Refunded {
+		return
+	}
+
 	transactionIDStr, ok := charge.Metadata["transaction_id"]
 	if !ok {
 		return
@@ -21,6 +21,7 @@ import (
 	"github.com/digi604/swarmmarket/backend/internal/transaction"
 	"github.com/digi604/swarmmarket/backend/internal/trust"
 	"github.com/digi604/swarmmarket/backend/internal/user"
+	"github.com/digi604/swarmmarket/backend/internal/wallet"
 	"github.com/digi604/swarmmarket/backend/pkg/middleware"
 	"github.com/digi604/swarmmarket/backend/pkg/websocket"
 	"github.com/go-chi/chi/v5"
@@ -57,6 +58,7 @@ type RouterConfig struct {
 	PaymentService      *payment.Service
 	TrustService        *trust.Service
 	SpendingService     *spending.Service
+	WalletService       *wallet.Service
 	TaskService         *task.Service
 	MessagingService    *messaging.Service
 	WebhookRepo         *notification.Repository
@@ -115,6 +117,9 @@ func NewRouter(cfg RouterConfig) *chi.Mux {
 	auctionHandler := NewAuctionHandler(cfg.AuctionService)
 	webhookHandler := NewWebhookHandler(cfg.WebhookRepo)
 	orderBookHandler := NewOrderBookHandler(cfg.MatchingEngine)
+	if cfg.MarketplaceService != nil {
+		orderBookHandler.SetListingOwnerChecker(cfg.MarketplaceService)
+	}
 	var paymentHandler *PaymentHandler
 	if cfg.PaymentService != nil {
 		paymentHandler = NewPaymentHandler(cfg.PaymentService, cfg.TransactionService, cfg.Config.Stripe.WebhookSecret)
@@ -123,6 +128,15 @@ func NewRouter(cfg RouterConfig) *chi.Mux {
 		}
 	}
 
+	// Wallet handler (optional - only if WalletService is configured)
+	var walletHandler *WalletHandler
+	if cfg.WalletService != nil {
+		walletHandler = NewWalletHandler(cfg.WalletService, cfg.Config.Wallet.Auditors())
+		if paymentHandler != nil {
+			paymentHandler.SetWalletService(cfg.WalletService)
+		}
+	}
+
 	// Trust handler (optional - only if TrustService is configured)
 	var trustHandler *TrustHandler
 	if cfg.TrustService != nil {
@@ -256,6 +270,16 @@ func NewRouter(cfg RouterConfig) *chi.Mux {
 					})
 				}
 
+				// Wallet
+				if walletHandler != nil {
+					r.Route("/wallet", func(r chi.Router) {
+						r.Get("/", walletHandler.GetBalances)
+						r.Get("/statement", walletHandler.GetStatement)
+						r.Post("/deposits", walletHandler.CreateDeposit)
+						r.Post("/withdrawals", walletHandler.Withdraw)
+					})
+				}
+
 				// Connect routes (Stripe Connect Express onboarding)
 				if cfg.ConnectService != nil && cfg.UserRepo != nil {
 					connectHandler := NewConnectHandler(cfg.ConnectService, cfg.UserRepo)
@@ -335,9 +359,23 @@ func NewRouter(cfg RouterConfig) *chi.Mux {
 			r.Use(optionalAuth)
 			r.Get("/", auctionHandler.SearchAuctions)
 			r.With(authMiddleware).Post("/", auctionHandler.CreateAuction)
+			r.With(authMiddleware).Get("/watchlist", auctionHandler.GetWatchlist)
+			r.With(authMiddleware).Get("/saved-searches", auctionHandler.GetSavedSearches)
+			r.With(authMiddleware).Post("/saved-searches", auctionHandler.CreateSavedSearch)
+			r.With(authMiddleware).Delete("/saved-searches/{searchId}", auctionHandler.DeleteSavedSearch)
 			r.Get("/{id}", auctionHandler.GetAuction)
+			r.With(authMiddleware).Patch("/{id}", auctionHandler.UpdateAuction)
+			r.With(authMiddleware).Post("/{id}/cancel", auctionHandler.CancelAuction)
+			r.Get("/{id}/history", auctionHandler.GetAuctionHistory)
+			r.With(authMiddleware).Post("/{id}/watch", auctionHandler.WatchAuction)
+			r.With(authMiddleware).Delete("/{id}/watch", auctionHandler.UnwatchAuction)
 			r.With(authMiddleware).Post("/{id}/bid", auctionHandler.PlaceBid)
+			r.With(authMiddleware).Post("/{id}/reveal", auctionHandler.RevealBid)
+			r.With(authMiddleware).Post("/{id}/buy-now", auctionHandler.BuyNow)
+			r.With(authMiddleware).Post("/{id}/second-chance/accept", auctionHandler.AcceptSecondChance)
+			r.With(authMiddleware).Post("/{id}/second-chance/decline", auctionHandler.DeclineSecondChance)
 			r.Get("/{id}/bids", auctionHandler.GetBids)
+			r.With(authMiddleware).Post("/{id}/bids/{bidId}/retract", auctionHandler.RetractBid)
 			r.With(authMiddleware).Post("/{id}/end", auctionHandler.EndAuction)
 
 			// Images
@@ -356,15 +394,42 @@ func NewRouter(cfg RouterConfig) *chi.Mux {
 			r.Get("/", orderHandler.ListOrders)
 			r.Get("/{id}", orderHandler.GetOrder)
 			r.Post("/{id}/fund", orderHandler.FundEscrow)
+			r.Post("/{id}/fund-from-wallet", orderHandler.FundEscrowFromWallet)
+			r.Post("/{id}/inspection-window", orderHandler.SetInspectionWindow)
 			r.Post("/{id}/deliver", orderHandler.MarkDelivered)
 			r.Post("/{id}/confirm", orderHandler.ConfirmDelivery)
 			r.Post("/{id}/rating", orderHandler.SubmitRating)
 			r.Get("/{id}/ratings", orderHandler.GetRatings)
 			r.Post("/{id}/dispute", orderHandler.DisputeOrder)
+			r.Get("/{id}/dispute", orderHandler.GetDispute)
+			r.Post("/{id}/dispute/evidence", orderHandler.SubmitDisputeEvidence)
+			r.Post("/{id}/dispute/resolve", orderHandler.ResolveDispute)
+			r.Post("/{id}/milestones/{milestoneId}/deliver", orderHandler.MarkMilestoneDelivered)
+			r.Post("/{id}/milestones/{milestoneId}/confirm", orderHandler.ConfirmMilestone)
+			r.Post("/{id}/milestones/{milestoneId}/dispute", orderHandler.DisputeMilestone)
+			r.Get("/{id}/milestones/{milestoneId}/dispute", orderHandler.GetDispute)
+			r.Post("/{id}/milestones/{milestoneId}/dispute/evidence", orderHandler.SubmitDisputeEvidence)
+			r.Post("/{id}/milestones/{milestoneId}/dispute/resolve", orderHandler.ResolveDispute)
+			r.Get("/{id}/refunds", orderHandler.GetRefunds)
+			r.Post("/{id}/refunds", orderHandler.CreateRefund)
+			r.Post("/{id}/refunds/{refundId}/accept", orderHandler.AcceptRefund)
+			r.Post("/{id}/refunds/{refundId}/reject", orderHandler.RejectRefund)
 		}
 		r.Route("/orders", orderRoutes)
 		r.Route("/transactions", orderRoutes)
 
+		// Wallet routes (if wallet ledger is configured)
+		if walletHandler != nil {
+			r.Route("/wallet", func(r chi.Router) {
+				r.Use(authMiddleware)
+				r.Get("/", walletHandler.GetBalances)
+				r.Get("/statement", walletHandler.GetStatement)
+				r.Post("/deposits", walletHandler.CreateDeposit)
+				r.Post("/withdrawals", walletHandler.Withdraw)
+				r.Get("/reconciliation", walletHandler.GetReconciliation)
+			})
+		}
+
 		r.Route("/webhooks", func(r chi.Router) {
 			r.Use(authMiddleware)
 			r.Post("/", webhookHandler.CreateWebhook)
@@ -374,8 +439,19 @@ func NewRouter(cfg RouterConfig) *chi.Mux {
 
 		// Order book (NYSE-style matching)
 		r.Route("/orderbook", func(r chi.Router) {
+			r.Get("/tickers", orderBookHandler.GetTickers)
+			r.Get("/instruments", orderBookHandler.ListInstruments)
+			r.Get("/instruments/{instrumentId}", orderBookHandler.GetInstrument)
+			r.With(authMiddleware).Post("/instruments", orderBookHandler.CreateInstrument)
+			r.With(authMiddleware).Patch("/instruments/{instrumentId}", orderBookHandler.UpdateInstrument)
 			r.Get("/{productId}", orderBookHandler.GetOrderBook)
+			r.Get("/{productId}/candles", orderBookHandler.GetCandles)
+			r.Get("/{productId}/ticker", orderBookHandler.GetTicker)
 			r.With(authMiddleware).Post("/orders", orderBookHandler.PlaceOrder)
+			r.With(authMiddleware).Get("/orders", orderBookHandler.ListOrders)
+			r.With(authMiddleware).Get("/orders/{orderId}", orderBookHandler.GetOrder)
+			r.With(authMiddleware).Get("/orders/{orderId}/fills", orderBookHandler.GetOrderFills)
+			r.With(authMiddleware).Patch("/orders/{orderId}", orderBookHandler.AmendOrder)
 			r.With(authMiddleware).Delete("/orders/{orderId}", orderBookHandler.CancelOrder)
 		})
 
@@ -505,6 +581,7 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
 				"/api/v1/transactions": "Transaction management",
 				"/api/v1/capabilities": "Agent capabilities",
 				"/api/v1/webhooks":     "Webhook management",
+				"/api/v1/wallet":       "Wallet balances, deposits & withdrawals",
 				"/api/v1/trust":        "Trust & verification",
 				"/api/v1/dashboard":    "Human dashboard (Clerk auth)",
 			},
@@ -579,8 +656,22 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
   ├── /api/v1/auctions      Auctions
   │   ├── GET  /                 Search auctions
   │   ├── POST /                 Create auction
+  │   ├── GET  /watchlist        Auctions you are watching
+  │   ├── GET  /saved-searches   List saved searches
+  │   ├── POST /saved-searches   Save a search, alerted when a match starts
+  │   ├── DELETE /saved-searches/{searchId}  Delete saved search
   │   ├── GET  /{id}             Get auction details
+  │   ├── PATCH /{id}            Edit scheduled auction (seller)
+  │   ├── POST /{id}/cancel      Cancel auction (seller)
+  │   ├── GET  /{id}/history     Auction audit history
+  │   ├── POST /{id}/watch       Watch auction
+  │   ├── DELETE /{id}/watch     Stop watching auction
   │   ├── POST /{id}/bid         Place bid
+  │   ├── POST /{id}/bids/{bidId}/retract  Retract bid
+  │   ├── POST /{id}/reveal      Reveal sealed bid
+  │   ├── POST /{id}/buy-now     Buy at the buy-now price
+  │   ├── POST /{id}/second-chance/accept   Accept a second-chance offer
+  │   ├── POST /{id}/second-chance/decline  Decline a second-chance offer
   │   ├── GET  /{id}/images      Get images
   │   ├── POST /{id}/images      Upload image
   │   └── DELETE /{id}/images/{imageId}  Delete image
@@ -589,9 +680,21 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
   │   ├── GET  /                 List transactions
   │   ├── GET  /{id}             Transaction details
   │   ├── POST /{id}/fund        Fund escrow (buyer)
+  │   ├── POST /{id}/fund-from-wallet  Fund escrow from wallet (buyer)
+  │   ├── POST /{id}/inspection-window  Set inspection window (buyer)
   │   ├── POST /{id}/deliver     Mark delivered (seller)
   │   ├── POST /{id}/confirm     Confirm delivery (buyer)
-  │   └── POST /{id}/dispute     Raise dispute
+  │   ├── POST /{id}/milestones/{milestoneId}/deliver  Deliver milestone (seller)
+  │   ├── POST /{id}/milestones/{milestoneId}/confirm  Confirm milestone (buyer)
+  │   ├── POST /{id}/milestones/{milestoneId}/dispute  Dispute milestone
+  │   ├── POST /{id}/dispute     Raise dispute
+  │   ├── GET  /{id}/dispute     Dispute and evidence
+  │   ├── POST /{id}/dispute/evidence  Submit evidence
+  │   ├── POST /{id}/dispute/resolve   Resolve dispute
+  │   ├── GET  /{id}/refunds     Refunds and refund requests
+  │   ├── POST /{id}/refunds     Refund (seller) or request a refund (buyer)
+  │   ├── POST /{id}/refunds/{refundId}/accept  Accept refund request (seller)
+  │   └── POST /{id}/refunds/{refundId}/reject  Reject refund request (seller)
   │
   ├── /api/v1/capabilities  Agent capabilities
   │   ├── GET  /                 Search capabilities
@@ -603,6 +706,13 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
   │   ├── POST /                 Register webhook
   │   └── DELETE /{id}           Delete webhook
   │
+  ├── /api/v1/wallet        Wallet ledger
+  │   ├── GET  /                 Balances
+  │   ├── GET  /statement        Statement
+  │   ├── POST /deposits         Deposit via Stripe
+  │   ├── POST /withdrawals      Withdraw to Stripe Connect
+  │   └── GET  /reconciliation   Ledger reconciliation (auditors)
+  │
   ├── /api/v1/trust         Trust & verification
   │   ├── GET  /                 Trust score breakdown
   │   └── POST /verify/twitter/* Twitter verification
@@ -612,6 +722,7 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
       ├── GET  /agents           Owned agents
       ├── POST /agents/claim     Claim agent
       ├── /payment-methods/*     Payment methods
+      ├── /wallet/*              Wallet
       └── /agents/{id}/spending-limits  Spending limits
 
   Docs: https://github.com/digi604/swarmmarket
@@ -704,8 +815,22 @@ func serveHTMLDocs(w http.ResponseWriter) {
   ├── <a href="/api/v1/auctions">/api/v1/auctions</a>      Auctions
   │   ├── GET  /                 Search auctions
   │   ├── POST /                 Create auction
+  │   ├── GET  /watchlist        Auctions you are watching
+  │   ├── GET  /saved-searches   List saved searches
+  │   ├── POST /saved-searches   Save a search, alerted when a match starts
+  │   ├── DELETE /saved-searches/{searchId}  Delete saved search
   │   ├── GET  /{id}             Get auction details
+  │   ├── PATCH /{id}            Edit scheduled auction (seller)
+  │   ├── POST /{id}/cancel      Cancel auction (seller)
+  │   ├── GET  /{id}/history     Auction audit history
+  │   ├── POST /{id}/watch       Watch auction
+  │   ├── DELETE /{id}/watch     Stop watching auction
   │   ├── POST /{id}/bid         Place bid
+  │   ├── POST /{id}/bids/{bidId}/retract  Retract bid
+  │   ├── POST /{id}/reveal      Reveal sealed bid
+  │   ├── POST /{id}/buy-now     Buy at the buy-now price
+  │   ├── POST /{id}/second-chance/accept   Accept a second-chance offer
+  │   ├── POST /{id}/second-chance/decline  Decline a second-chance offer
   │   ├── GET  /{id}/images      Get images
   │   ├── POST /{id}/images      Upload image
   │   └── DELETE /{id}/images/{imageId}  Delete image
@@ -714,9 +839,21 @@ func serveHTMLDocs(w http.ResponseWriter) {
   │   ├── GET  /                 List transactions
   │   ├── GET  /{id}             Transaction details
   │   ├── POST /{id}/fund        Fund escrow (buyer)
+  │   ├── POST /{id}/fund-from-wallet  Fund escrow from wallet (buyer)
+  │   ├── POST /{id}/inspection-window  Set inspection window (buyer)
   │   ├── POST /{id}/deliver     Mark delivered (seller)
   │   ├── POST /{id}/confirm     Confirm delivery (buyer)
+  │   ├── POST /{id}/milestones/{milestoneId}/deliver  Deliver milestone (seller)
+  │   ├── POST /{id}/milestones/{milestoneId}/confirm  Confirm milestone (buyer)
+  │   ├── POST /{id}/milestones/{milestoneId}/dispute  Dispute milestone
   │   ├── POST /{id}/dispute     Raise dispute
+  │   ├── GET  /{id}/dispute     Dispute and evidence
+  │   ├── POST /{id}/dispute/evidence  Submit evidence
+  │   ├── POST /{id}/dispute/resolve   Resolve dispute
+  │   ├── GET  /{id}/refunds     Refunds and refund requests
+  │   ├── POST /{id}/refunds     Refund (seller) or request a refund (buyer)
+  │   ├── POST /{id}/refunds/{refundId}/accept  Accept refund request (seller)
+  │   ├── POST /{id}/refunds/{refundId}/reject  Reject refund request (seller)
   │   └── POST /{id}/rate        Rate transaction
   │
   ├── <a href="/api/v1/capabilities">/api/v1/capabilities</a>  Agent capabilities
@@ -730,6 +867,13 @@ func serveHTMLDocs(w http.ResponseWriter) {
   │   ├── POST /                 Register webhook
   │   └── DELETE /{id}           Delete webhook
   │
+  ├── /api/v1/wallet        Wallet ledger
+  │   ├── GET  /                 Balances
+  │   ├── GET  /statement        Statement
+  │   ├── POST /deposits         Deposit via Stripe
+  │   ├── POST /withdrawals      Withdraw to Stripe Connect
+  │   └── GET  /reconciliation   Ledger reconciliation (auditors)
+  │
   ├── /api/v1/trust         Trust & verification
   │   ├── GET  /                 Trust score breakdown
   │   ├── GET  /verifications    List verifications
@@ -993,6 +1137,30 @@ Response:
 Use the ` + "`client_secret`" + ` to complete payment via Stripe.js or redirect to Stripe Checkout.
 Once payment succeeds, transaction status becomes ` + "`escrow_funded`" + `.
 
+### Paying from your wallet
+
+Money deposited into your wallet can fund escrow without a card payment:
+
+` + "```bash" + `
+# Deposit (charged to your owner's saved card)
+curl -X POST https://api.swarmmarket.ai/api/v1/wallet/deposits \
+  -H "X-API-Key: BUYER_API_KEY" \
+  -H "Content-Type: application/json" \
+  -d '{"amount": 50.00, "currency": "USD"}'
+
+# Check your balance
+curl https://api.swarmmarket.ai/api/v1/wallet \
+  -H "X-API-Key: BUYER_API_KEY"
+
+# Fund escrow from the wallet
+curl -X POST https://api.swarmmarket.ai/api/v1/transactions/{id}/fund-from-wallet \
+  -H "X-API-Key: BUYER_API_KEY"
+` + "```" + `
+
+The transaction is ` + "`escrow_funded`" + ` immediately. If the wallet doesn't hold enough, you get ` + "`402`" + ` with ` + "`INSUFFICIENT_FUNDS`" + `.
+When escrow is released, the seller's share (less the platform fee) lands in the seller's wallet; refunds go back to yours.
+Withdraw with ` + "`POST /api/v1/wallet/withdrawals`" + ` to your owner's Stripe Connect account.
+
 ### As a Seller: Deliver and get paid
 
 ` + "```bash" + `
@@ -1014,6 +1182,31 @@ curl -X POST https://api.swarmmarket.ai/api/v1/transactions/{id}/confirm \
   -H "X-API-Key: BUYER_API_KEY"
 ` + "```" + `
 
+### Refunds after completion
+
+A completed transaction can be refunded in part, as many times as needed, up to what was paid out.
+The seller refunds right away; the buyer asks, and the seller accepts or rejects.
+
+` + "```bash" + `
+# Seller refunds part of the payment (omit amount to refund everything left)
+curl -X POST https://api.swarmmarket.ai/api/v1/transactions/{id}/refunds \
+  -H "X-API-Key: SELLER_API_KEY" \
+  -H "Content-Type: application/json" \
+  -d '{"amount": 10.00, "reason": "One record was missing"}'
+
+# Buyer requests a refund
+curl -X POST https://api.swarmmarket.ai/api/v1/transactions/{id}/refunds \
+  -H "X-API-Key: BUYER_API_KEY" \
+  -H "Content-Type: application/json" \
+  -d '{"amount": 25.00, "reason": "Half the data was stale"}'
+
+# Seller accepts (or POST .../reject with {"note": "..."})
+curl -X POST https://api.swarmmarket.ai/api/v1/transactions/{id}/refunds/{refundId}/accept \
+  -H "X-API-Key: SELLER_API_KEY"
+` + "```" + `
+
+A buyer has one open request at a time. Once everything paid out has been refunded, the transaction is ` + "`refunded`" + `.
+
 ---
 
 ## Webhooks 🔔
@@ -1238,15 +1431,35 @@ Supported formats: JPEG, PNG, GIF, WebP. Max size: 10MB.
 | /api/v1/transactions | GET | ✅ | List your transactions |
 | /api/v1/transactions/{id} | GET | ✅ | Get transaction details |
 | /api/v1/transactions/{id}/fund | POST | ✅ | Fund escrow (buyer pays) |
+| /api/v1/transactions/{id}/fund-from-wallet | POST | ✅ | Fund escrow from your wallet (buyer) |
+| /api/v1/transactions/{id}/inspection-window | POST | ✅ | Set inspection window (buyer) |
 | /api/v1/transactions/{id}/deliver | POST | ✅ | Mark as delivered (seller) |
 | /api/v1/transactions/{id}/confirm | POST | ✅ | Confirm delivery (buyer) |
 | /api/v1/transactions/{id}/dispute | POST | ✅ | Raise dispute |
+| /api/v1/transactions/{id}/dispute | GET | ✅ | Get dispute and evidence |
+| /api/v1/transactions/{id}/dispute/evidence | POST | ✅ | Submit dispute evidence |
+| /api/v1/transactions/{id}/dispute/resolve | POST | ✅ | Resolve dispute (arbitrator, or concede) |
+| /api/v1/transactions/{id}/milestones/{milestoneId}/deliver | POST | ✅ | Deliver milestone (seller) |
+| /api/v1/transactions/{id}/milestones/{milestoneId}/confirm | POST | ✅ | Confirm milestone, releasing its amount (buyer) |
+| /api/v1/transactions/{id}/milestones/{milestoneId}/dispute | POST | ✅ | Dispute milestone |
+| /api/v1/transactions/{id}/milestones/{milestoneId}/dispute | GET | ✅ | Get milestone dispute and evidence |
+| /api/v1/transactions/{id}/milestones/{milestoneId}/dispute/evidence | POST | ✅ | Submit milestone dispute evidence |
+| /api/v1/transactions/{id}/milestones/{milestoneId}/dispute/resolve | POST | ✅ | Resolve milestone dispute |
+| /api/v1/transactions/{id}/refunds | GET | ✅ | List refunds and refund requests |
+| /api/v1/transactions/{id}/refunds | POST | ✅ | Refund (seller) or request a refund (buyer) |
+| /api/v1/transactions/{id}/refunds/{refundId}/accept | POST | ✅ | Accept refund request (seller) |
+| /api/v1/transactions/{id}/refunds/{refundId}/reject | POST | ✅ | Reject refund request (seller) |
 | /api/v1/capabilities | GET | ❌ | Search capabilities |
 | /api/v1/capabilities | POST | ✅ | Register capability |
 | /api/v1/capabilities/{id} | GET | ❌ | Get capability details |
 | /api/v1/webhooks | GET | ✅ | List your webhooks |
 | /api/v1/webhooks | POST | ✅ | Register webhook |
 | /api/v1/webhooks/{id} | DELETE | ✅ | Delete webhook |
+| /api/v1/wallet | GET | ✅ | Wallet balances |
+| /api/v1/wallet/statement | GET | ✅ | Wallet statement |
+| /api/v1/wallet/deposits | POST | ✅ | Deposit into wallet |
+| /api/v1/wallet/withdrawals | POST | ✅ | Withdraw from wallet |
+| /api/v1/wallet/reconciliation | GET | ✅ | Ledger reconciliation (auditors) |
 | /api/v1/agents/me/avatar | POST | ✅ | Upload agent avatar |
 | /api/v1/listings/{id}/images | GET | ❌ | Get listing images |
 | /api/v1/listings/{id}/images | POST | ✅ | Upload listing image |
@@ -1331,6 +1544,9 @@ func skillJSONHandler(w http.ResponseWriter, r *http.Request) {
     "submit_offer": {"method": "POST", "path": "/api/v1/requests/{id}/offers", "auth": true},
     "auctions": {"method": "GET", "path": "/api/v1/auctions", "auth": false},
     "place_bid": {"method": "POST", "path": "/api/v1/auctions/{id}/bid", "auth": true},
+    "wallet": {"method": "GET", "path": "/api/v1/wallet", "auth": true},
+    "fund_from_wallet": {"method": "POST", "path": "/api/v1/transactions/{id}/fund-from-wallet", "auth": true},
+    "refunds": {"method": "POST", "path": "/api/v1/transactions/{id}/refunds", "auth": true},
     "upload_avatar": {"method": "POST", "path": "/api/v1/agents/me/avatar", "auth": true, "content_type": "multipart/form-data"},
     "listing_images": {"method": "GET", "path": "/api/v1/listings/{id}/images", "auth": false},
     "upload_listing_image": {"method": "POST", "path": "/api/v1/listings/{id}/images", "auth": true, "content_type": "multipart/form-data"},
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/digi604/swarmmarket/backend/internal/common"
+	"github.com/digi604/swarmmarket/backend/internal/payment"
+	"github.com/digi604/swarmmarket/backend/internal/wallet"
+	"github.com/digi604/swarmmarket/backend/pkg/middleware"
+	"github.com/google/uuid"
+)
+
+// WalletHandler handles wallet balances, deposits and withdrawals for agents
+// and dashboard users.
+type WalletHandler struct {
+	walletService *wallet.Service
+	auditors      map[uuid.UUID]bool
+}
+
+// NewWalletHandler creates a new wallet handler. Auditors are the agents
+// allowed to read the reconciliation report.
+func NewWalletHandler(walletService *wallet.Service, auditorIDs []uuid.UUID) *WalletHandler {
+	auditors := make(map[uuid.UUID]bool, len(auditorIDs))
+	for _, id := range auditorIDs {
+		auditors[id] = true
+	}
+	return &WalletHandler{
+		walletService: walletService,
+		auditors:      auditors,
+	}
+}
+
+// owner returns whose wallet the request is for: the authenticated agent, or
+// the dashboard user.
+func (h *WalletHandler) owner(r *http.Request) (wallet.Owner, bool) {
+	if agent := middleware.GetAgent(r.Context()); agent != nil {
+		return wallet.Owner{Type: wallet.OwnerAgent, ID: agent.ID}, true
+	}
+	if usr := middleware.GetUser(r.Context()); usr != nil {
+		return wallet.Owner{Type: wallet.OwnerUser, ID: usr.ID}, true
+	}
+	return wallet.Owner{}, false
+}
+
+// GetBalances handles GET /wallet - returns wallet balances per currency.
+func (h *WalletHandler) GetBalances(w http.ResponseWriter, r *http.Request) {
+	owner, ok := h.owner(r)
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	balances, err := h.walletService.GetBalances(r.Context(), owner)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to get wallet balances"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]any{"balances": balances})
+}
+
+// GetStatement handles GET /wallet/statement - returns wallet entries, newest first.
+func (h *WalletHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	owner, ok := h.owner(r)
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	result, err := h.walletService.GetStatement(r.Context(), owner,
+		r.URL.Query().Get("currency"),
+		parseIntParam(r, "limit", 20),
+		parseIntParam(r, "offset", 0),
+	)
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to get wallet statement"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, result)
+}
+
+// CreateDeposit handles POST /wallet/deposits - adds money to the wallet through Stripe.
+func (h *WalletHandler) CreateDeposit(w http.ResponseWriter, r *http.Request) {
+	owner, ok := h.owner(r)
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	var req wallet.DepositRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	deposit, err := h.walletService.CreateDeposit(r.Context(), owner, &req)
+	if err != nil {
+		h.writeMovementError(w, err, "failed to create deposit")
+		return
+	}
+
+	common.WriteJSON(w, http.StatusCreated, deposit)
+}
+
+// Withdraw handles POST /wallet/withdrawals - pays wallet money out to Stripe Connect.
+func (h *WalletHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
+	owner, ok := h.owner(r)
+	if !ok {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+
+	var req wallet.WithdrawRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest("invalid request body"))
+		return
+	}
+
+	withdrawal, err := h.walletService.Withdraw(r.Context(), owner, &req)
+	if err != nil {
+		h.writeMovementError(w, err, "failed to withdraw")
+		return
+	}
+
+	common.WriteJSON(w, http.StatusCreated, withdrawal)
+}
+
+// GetReconciliation handles GET /wallet/reconciliation - auditors check the ledger.
+func (h *WalletHandler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	agent := middleware.GetAgent(r.Context())
+	if agent == nil {
+		common.WriteError(w, http.StatusUnauthorized, common.ErrUnauthorized("not authenticated"))
+		return
+	}
+	if !h.auditors[agent.ID] {
+		common.WriteError(w, http.StatusForbidden, common.ErrForbidden("only wallet auditors can read the reconciliation report"))
+		return
+	}
+
+	report, err := h.walletService.Reconcile(r.Context())
+	if err != nil {
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer("failed to reconcile ledger"))
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, report)
+}
+
+// writeMovementError maps deposit and withdrawal errors to responses.
+func (h *WalletHandler) writeMovementError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, wallet.ErrInvalidAmount):
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		common.WriteError(w, http.StatusPaymentRequired, common.ErrInsufficientFunds(err.Error()))
+	case errors.Is(err, wallet.ErrPaymentsNotConfigured):
+		common.WriteError(w, http.StatusServiceUnavailable, common.ErrServiceUnavailable(err.Error()))
+	case errors.Is(err, payment.ErrNoPaymentMethod), errors.Is(err, payment.ErrNoPayoutAccount):
+		common.WriteError(w, http.StatusBadRequest, common.ErrBadRequest(err.Error()))
+	default:
+		common.WriteError(w, http.StatusInternalServerError, common.ErrInternalServer(msg))
+	}
+}
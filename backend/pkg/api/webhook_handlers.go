@@ -63,35 +63,52 @@ func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
 
 	// Validate event types
 	validEvents := map[string]bool{
-		"request.created":           true,
-		"request.updated":           true,
-		"offer.received":            true,
-		"offer.accepted":            true,
-		"offer.rejected":            true,
-		"listing.created":           true,
-		"listing.updated":           true,
-		"listing.purchased":         true,
-		"comment.created":           true,
-		"auction.started":           true,
-		"bid.placed":                true,
-		"bid.outbid":                true,
-		"auction.ending_soon":       true,
-		"auction.ended":             true,
-		"order.created":             true,
-		"escrow.funded":             true,
-		"delivery.confirmed":        true,
-		"payment.released":          true,
-		"payment.failed":            true,
-		"payment.capture_failed":    true,
-		"dispute.opened":            true,
-		"match.found":               true,
-		"order.filled":              true,
-		"transaction.created":       true,
-		"transaction.escrow_funded": true,
-		"transaction.delivered":     true,
-		"transaction.completed":     true,
-		"transaction.refunded":      true,
-		"rating.submitted":          true,
+		"request.created":               true,
+		"request.updated":               true,
+		"offer.received":                true,
+		"offer.accepted":                true,
+		"offer.rejected":                true,
+		"listing.created":               true,
+		"listing.updated":               true,
+		"listing.purchased":             true,
+		"comment.created":               true,
+		"auction.started":               true,
+		"bid.placed":                    true,
+		"bid.outbid":                    true,
+		"auction.ending_soon":           true,
+		"auction.price_changed":         true,
+		"auction.reveal_started":        true,
+		"auction.second_chance_offered": true,
+		"auction.ended":                 true,
+		"auction.cancelled":             true,
+		"bid.retracted":                 true,
+		"auction.search_matched":        true,
+		"order.created":                 true,
+		"escrow.funded":                 true,
+		"delivery.confirmed":            true,
+		"payment.released":              true,
+		"payment.failed":                true,
+		"payment.capture_failed":        true,
+		"dispute.opened":                true,
+		"dispute.evidence_submitted":    true,
+		"dispute.resolved":              true,
+		"match.found":                   true,
+		"order.filled":                  true,
+		"order.expired":                 true,
+		"order.triggered":               true,
+		"order.self_trade_prevented":    true,
+		"transaction.created":           true,
+		"transaction.escrow_funded":     true,
+		"transaction.delivered":         true,
+		"transaction.inspection_ending": true,
+		"milestone.delivered":           true,
+		"milestone.released":            true,
+		"transaction.completed":         true,
+		"transaction.refunded":          true,
+		"refund.requested":              true,
+		"refund.completed":              true,
+		"refund.rejected":               true,
+		"rating.submitted":              true,
 	}
 
 	for _, event := range req.Events {
@@ -0,0 +1,233 @@
+package websocket
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digi604/swarmmarket/backend/internal/auction"
+	"github.com/digi604/swarmmarket/backend/internal/notification"
+	"github.com/google/uuid"
+)
+
+const auctionTopicPrefix = "auction"
+
+// AuctionSource looks up the current state of auctions (implemented by auction.Service).
+type AuctionSource interface {
+	GetAuction(ctx context.Context, id uuid.UUID) (*auction.Auction, error)
+}
+
+// AuctionFeed publishes the public activity of auctions to hub topics:
+//
+//	auction.<auction_id>  room state on subscribe, then bids, price drops,
+//	                      anti-sniping extensions and the close
+//
+// Every message carries the auction's state after the change and the server
+// time, so bots can schedule bids against the server clock. Sealed auctions
+// only ever show their bid count. Like the market feed, every room has a
+// sequence number that increases by one with each message.
+type AuctionFeed struct {
+	hub    *Hub
+	source AuctionSource
+	mu     sync.Mutex
+	rooms  map[uuid.UUID]*auctionRoom
+}
+
+// auctionRoom is what the feed remembers of an auction between messages:
+// its state after the last message, which subscribers get as the snapshot.
+type auctionRoom struct {
+	seq     uint64
+	auction *auction.Auction
+}
+
+// NewAuctionFeed creates an auction feed and registers its topics on the hub.
+// Pass it to BridgeAuctionEvents to feed it auction events.
+func NewAuctionFeed(hub *Hub, source AuctionSource) *AuctionFeed {
+	f := &AuctionFeed{
+		hub:    hub,
+		source: source,
+		rooms:  make(map[uuid.UUID]*auctionRoom),
+	}
+	hub.HandleTopics(auctionTopicPrefix, f.snapshot)
+	hub.LoadTopics(auctionTopicPrefix, f.load)
+	return f
+}
+
+// HandleEvent publishes an auction event to the auction's room. Events for
+// auctions without a room are skipped, as are private ones such as outbid
+// notices and second-chance offers. A room lives from its first subscriber
+// until the auction closes, so its state is current for later subscribers.
+func (f *AuctionFeed) HandleEvent(ctx context.Context, event notification.Event) {
+	var msgType string
+	switch event.Type {
+	case notification.EventBidPlaced:
+		msgType = "auction.bid"
+	case notification.EventBidRetracted:
+		msgType = "auction.bid_retracted"
+	case notification.EventAuctionPriceChanged:
+		msgType = "auction.price"
+	case notification.EventAuctionStarted:
+		msgType = "auction.started"
+	case notification.EventAuctionRevealStarted:
+		msgType = "auction.reveal"
+	case notification.EventAuctionEnded, notification.EventAuctionCancelled:
+		msgType = "auction.closed"
+	default:
+		return
+	}
+
+	id, _ := event.Payload["auction_id"].(string)
+	auctionID, err := uuid.Parse(id)
+	if err != nil {
+		return
+	}
+	topic := topicName(auctionTopicPrefix, auctionID)
+	f.mu.Lock()
+	_, ok := f.rooms[auctionID]
+	f.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	a, err := f.source.GetAuction(ctx, auctionID)
+	if err != nil {
+		return
+	}
+	// A Dutch auction's winning bid arrives after it has closed
+	if msgType == "auction.bid" && a.Status == auction.AuctionStatusEnded {
+		return
+	}
+
+	f.mu.Lock()
+	room, ok := f.rooms[auctionID]
+	if !ok {
+		f.mu.Unlock()
+		return // Closed meanwhile
+	}
+	var messages []Message
+
+	room.seq++
+	payload := roomState(a, room.seq)
+	switch msgType {
+	case "auction.bid":
+		if !sealedBids(a) {
+			payload["bidder_id"] = event.Payload["bidder_id"]
+			payload["amount"] = event.Payload["amount"]
+		}
+	case "auction.closed":
+		payload["winner_id"] = a.WinnerID
+		if a.Status == auction.AuctionStatusEnded {
+			payload["final_price"] = a.CurrentPrice
+		}
+	}
+	messages = append(messages, Message{Type: msgType, Payload: payload})
+
+	// Anti-sniping moved the end time
+	if a.EndsAt.After(room.auction.EndsAt) && msgType == "auction.bid" {
+		room.seq++
+		messages = append(messages, Message{Type: "auction.extended", Payload: roomState(a, room.seq)})
+	}
+	room.auction = a
+
+	if msgType == "auction.closed" {
+		delete(f.rooms, auctionID)
+	}
+	f.mu.Unlock()
+
+	// Publish outside the feed lock, see MarketFeed.HandleBookUpdate
+	for _, msg := range messages {
+		f.hub.Publish(topic, msg)
+	}
+}
+
+// load opens the room of an auction before its first subscriber joins,
+// reading the auction outside the hub lock. Open rooms are kept current by
+// HandleEvent and need no reading.
+func (f *AuctionFeed) load(ctx context.Context, topic string) error {
+	auctionID, err := auctionTopicID(topic)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	_, ok := f.rooms[auctionID]
+	f.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	a, err := f.source.GetAuction(ctx, auctionID)
+	if err != nil {
+		return ErrUnknownTopic
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.rooms[auctionID]; !ok {
+		f.rooms[auctionID] = &auctionRoom{auction: a}
+	}
+	return nil
+}
+
+// snapshot returns the current state of an auction room from memory, as it
+// is taken under the hub lock. A closed auction gets no more messages, so its
+// room is dropped once served.
+func (f *AuctionFeed) snapshot(topic string) (*Message, error) {
+	auctionID, err := auctionTopicID(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	room, ok := f.rooms[auctionID]
+	if !ok {
+		return nil, ErrUnknownTopic
+	}
+	a := room.auction
+	if a.Status == auction.AuctionStatusEnded || a.Status == auction.AuctionStatusCancelled {
+		delete(f.rooms, auctionID)
+	}
+	return &Message{Type: "auction.snapshot", Payload: roomState(a, room.seq)}, nil
+}
+
+// auctionTopicID parses the auction ID of an auction.<auction_id> topic.
+func auctionTopicID(topic string) (uuid.UUID, error) {
+	_, id, _ := strings.Cut(topic, ".")
+	auctionID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, ErrUnknownTopic
+	}
+	return auctionID, nil
+}
+
+// roomState returns the public state of an auction at the room's sequence number.
+func roomState(a *auction.Auction, seq uint64) map[string]any {
+	state := map[string]any{
+		"auction_id":   a.ID,
+		"seq":          seq,
+		"server_time":  time.Now().UTC().Format(time.RFC3339Nano),
+		"auction_type": a.AuctionType,
+		"status":       a.Status,
+		"bid_count":    a.BidCount,
+		"currency":     a.Currency,
+		"starts_at":    a.StartsAt,
+		"ends_at":      a.EndsAt,
+	}
+	if !sealedBids(a) {
+		state["current_price"] = a.CurrentPrice
+	}
+	if a.NextPriceAt != nil {
+		state["next_price_at"] = a.NextPriceAt
+	}
+	if a.RevealEndsAt != nil {
+		state["reveal_ends_at"] = a.RevealEndsAt
+	}
+	return state
+}
+
+// sealedBids reports whether the auction's bids are hidden until it ends.
+func sealedBids(a *auction.Auction) bool {
+	return a.AuctionType == auction.AuctionTypeSealed || a.AuctionType == auction.AuctionTypeVickrey || a.AuctionType == auction.AuctionTypeBundle
+}
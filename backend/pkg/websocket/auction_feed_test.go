@@ -0,0 +1,185 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/digi604/swarmmarket/backend/internal/auction"
+	"github.com/digi604/swarmmarket/backend/internal/notification"
+	"github.com/google/uuid"
+)
+
+type mockAuctionSource struct {
+	auctions map[uuid.UUID]*auction.Auction
+}
+
+func (m *mockAuctionSource) GetAuction(ctx context.Context, id uuid.UUID) (*auction.Auction, error) {
+	a, ok := m.auctions[id]
+	if !ok {
+		return nil, auction.ErrAuctionNotFound
+	}
+	copy := *a
+	return &copy, nil
+}
+
+func newTestAuctionFeed(auctions ...*auction.Auction) (*Hub, *AuctionFeed, *mockAuctionSource) {
+	hub := NewHub()
+	source := &mockAuctionSource{auctions: make(map[uuid.UUID]*auction.Auction)}
+	for _, a := range auctions {
+		source.auctions[a.ID] = a
+	}
+	return hub, NewAuctionFeed(hub, source), source
+}
+
+func auctionEvent(eventType notification.EventType, payload map[string]any) notification.Event {
+	return notification.Event{ID: uuid.New(), Type: eventType, Payload: payload, CreatedAt: time.Now().UTC()}
+}
+
+func TestAuctionFeedBidAndExtension(t *testing.T) {
+	price := 100.0
+	a := &auction.Auction{
+		ID:           uuid.New(),
+		AuctionType:  auction.AuctionTypeEnglish,
+		Status:       auction.AuctionStatusActive,
+		CurrentPrice: &price,
+		EndsAt:       time.Now().Add(30 * time.Second),
+	}
+	hub, feed, source := newTestAuctionFeed(a)
+	ctx := context.Background()
+
+	client := newTestClient(hub)
+	if err := hub.subscribe(client, "auction."+a.ID.String()); err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	snapshot := receive(t, client)
+	if snapshot.Type != "auction.snapshot" || snapshot.Payload["seq"] != 0.0 || snapshot.Payload["current_price"] != 100.0 {
+		t.Fatalf("snapshot = %+v, want auction.snapshot at seq 0 and price 100", snapshot)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, snapshot.Payload["server_time"].(string)); err != nil {
+		t.Errorf("server_time = %v, want an RFC 3339 time", snapshot.Payload["server_time"])
+	}
+
+	// A late bid raises the price and extends the auction
+	bidderID := uuid.New()
+	newPrice := 120.0
+	stored := source.auctions[a.ID]
+	stored.CurrentPrice = &newPrice
+	stored.BidCount = 1
+	stored.EndsAt = stored.EndsAt.Add(time.Minute)
+	feed.HandleEvent(ctx, auctionEvent(notification.EventBidPlaced, map[string]any{
+		"auction_id": a.ID.String(),
+		"bidder_id":  bidderID.String(),
+		"amount":     120.0,
+	}))
+
+	bid := receive(t, client)
+	if bid.Type != "auction.bid" || bid.Payload["seq"] != 1.0 || bid.Payload["amount"] != 120.0 || bid.Payload["bid_count"] != 1.0 {
+		t.Fatalf("bid = %+v, want auction.bid at seq 1 for 120", bid)
+	}
+	extended := receive(t, client)
+	if extended.Type != "auction.extended" || extended.Payload["seq"] != 2.0 {
+		t.Fatalf("extended = %+v, want auction.extended at seq 2", extended)
+	}
+
+	// Private events are not broadcast
+	feed.HandleEvent(ctx, auctionEvent(notification.EventBidOutbid, map[string]any{"auction_id": a.ID.String()}))
+	if len(client.send) != 0 {
+		t.Error("expected no message for an outbid notice")
+	}
+
+	stored.Status = auction.AuctionStatusEnded
+	stored.WinnerID = &bidderID
+	feed.HandleEvent(ctx, auctionEvent(notification.EventAuctionEnded, map[string]any{"auction_id": a.ID.String()}))
+	closed := receive(t, client)
+	if closed.Type != "auction.closed" || closed.Payload["final_price"] != 120.0 || closed.Payload["winner_id"] != bidderID.String() {
+		t.Errorf("closed = %+v, want auction.closed won by the bidder at 120", closed)
+	}
+}
+
+func TestAuctionFeedSealedShowsBidCountOnly(t *testing.T) {
+	price := 50.0
+	a := &auction.Auction{
+		ID:           uuid.New(),
+		AuctionType:  auction.AuctionTypeSealed,
+		Status:       auction.AuctionStatusActive,
+		CurrentPrice: &price,
+		EndsAt:       time.Now().Add(time.Hour),
+	}
+	hub, feed, source := newTestAuctionFeed(a)
+
+	client := newTestClient(hub)
+	if err := hub.subscribe(client, "auction."+a.ID.String()); err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	if snapshot := receive(t, client); snapshot.Payload["current_price"] != nil {
+		t.Errorf("snapshot price = %v, want none for a sealed auction", snapshot.Payload["current_price"])
+	}
+
+	source.auctions[a.ID].BidCount = 1
+	feed.HandleEvent(context.Background(), auctionEvent(notification.EventBidPlaced, map[string]any{
+		"auction_id": a.ID.String(),
+		"bidder_id":  uuid.New().String(),
+		"amount":     75.0,
+	}))
+	bid := receive(t, client)
+	if bid.Payload["bid_count"] != 1.0 {
+		t.Errorf("bid_count = %v, want 1", bid.Payload["bid_count"])
+	}
+	for _, key := range []string{"amount", "bidder_id", "current_price"} {
+		if _, ok := bid.Payload[key]; ok {
+			t.Errorf("sealed bid message has %s", key)
+		}
+	}
+}
+
+func TestAuctionFeedRoomStaysCurrent(t *testing.T) {
+	price := 100.0
+	a := &auction.Auction{
+		ID:           uuid.New(),
+		AuctionType:  auction.AuctionTypeEnglish,
+		Status:       auction.AuctionStatusActive,
+		CurrentPrice: &price,
+		EndsAt:       time.Now().Add(time.Hour),
+	}
+	hub, feed, source := newTestAuctionFeed(a)
+	topic := "auction." + a.ID.String()
+
+	first := newTestClient(hub)
+	if err := hub.subscribe(first, topic); err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	receive(t, first)
+	hub.unsubscribe(first, topic)
+
+	// Bids keep the room current with nobody watching
+	newPrice := 130.0
+	source.auctions[a.ID].CurrentPrice = &newPrice
+	feed.HandleEvent(context.Background(), auctionEvent(notification.EventBidPlaced, map[string]any{
+		"auction_id": a.ID.String(),
+		"bidder_id":  uuid.New().String(),
+		"amount":     130.0,
+	}))
+
+	// The next subscriber is served from memory
+	delete(source.auctions, a.ID)
+	second := newTestClient(hub)
+	if err := hub.subscribe(second, topic); err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	snapshot := receive(t, second)
+	if snapshot.Payload["seq"] != 1.0 || snapshot.Payload["current_price"] != 130.0 {
+		t.Errorf("snapshot = %+v, want seq 1 at price 130", snapshot)
+	}
+}
+
+func TestAuctionFeedUnknownTopic(t *testing.T) {
+	_, feed, _ := newTestAuctionFeed()
+
+	if _, err := feed.snapshot("auction.not-a-uuid"); err != ErrUnknownTopic {
+		t.Errorf("snapshot() error = %v, want ErrUnknownTopic", err)
+	}
+	if _, err := feed.snapshot("auction." + uuid.New().String()); err != ErrUnknownTopic {
+		t.Errorf("snapshot() of missing auction error = %v, want ErrUnknownTopic", err)
+	}
+}
@@ -3,7 +3,9 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,6 +25,14 @@ const (
 
 	// Maximum message size allowed from peer.
 	maxMessageSize = 512
+
+	// Time allowed to load a topic before subscribing to it.
+	topicLoadTimeout = 5 * time.Second
+)
+
+var (
+	ErrUnknownTopic   = errors.New("unknown topic")
+	ErrSendBufferFull = errors.New("send buffer full")
 )
 
 // Message represents a WebSocket message.
@@ -37,8 +47,18 @@ type Client struct {
 	conn    *websocket.Conn
 	send    chan []byte
 	agentID uuid.UUID
+	topics  map[string]bool // Guarded by hub.mu
 }
 
+// SnapshotFunc returns the message a client receives first when it subscribes
+// to a topic, or ErrUnknownTopic if the topic does not exist.
+type SnapshotFunc func(topic string) (*Message, error)
+
+// LoadFunc prepares a topic for subscribers, e.g. by reading its state from
+// the database. It runs before the hub lock is taken, so the topic's
+// SnapshotFunc can serve the snapshot from memory.
+type LoadFunc func(ctx context.Context, topic string) error
+
 // Hub maintains the set of active clients and broadcasts messages.
 type Hub struct {
 	// Registered clients by agent ID
@@ -53,6 +73,15 @@ type Hub struct {
 	// Unregister requests from clients
 	unregister chan *Client
 
+	// Subscribed clients by topic
+	topics map[string]map[*Client]bool
+
+	// Snapshot sources by topic prefix (the part before the first '.')
+	sources map[string]SnapshotFunc
+
+	// Topic loaders by topic prefix, for sources that need one
+	loaders map[string]LoadFunc
+
 	// Mutex for thread-safe client access
 	mu sync.RWMutex
 }
@@ -70,6 +99,9 @@ func NewHub() *Hub {
 		broadcast:  make(chan *AgentMessage, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		topics:     make(map[string]map[*Client]bool),
+		sources:    make(map[string]SnapshotFunc),
+		loaders:    make(map[string]LoadFunc),
 	}
 }
 
@@ -83,6 +115,7 @@ func (h *Hub) Run(ctx context.Context) {
 			h.mu.Lock()
 			// Close existing connection if any
 			if existing, ok := h.clients[client.agentID]; ok {
+				h.unsubscribeAll(existing)
 				close(existing.send)
 				existing.conn.Close()
 			}
@@ -92,7 +125,8 @@ func (h *Hub) Run(ctx context.Context) {
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client.agentID]; ok {
+			if existing, ok := h.clients[client.agentID]; ok && existing == client {
+				h.unsubscribeAll(client)
 				delete(h.clients, client.agentID)
 				close(client.send)
 			}
@@ -128,6 +162,133 @@ func (h *Hub) SendToAgent(agentID uuid.UUID, message Message) error {
 	return nil
 }
 
+// HandleTopics registers the snapshot source for all topics with the given
+// prefix, e.g. "book" for "book.<product_id>". Clients can only subscribe to
+// topics that have a source.
+func (h *Hub) HandleTopics(prefix string, snapshot SnapshotFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sources[prefix] = snapshot
+}
+
+// LoadTopics registers the loader for all topics with the given prefix. It is
+// called before every subscription to such a topic.
+func (h *Hub) LoadTopics(prefix string, load LoadFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.loaders[prefix] = load
+}
+
+// Publish sends a message to every client subscribed to a topic.
+// Clients whose buffer is full miss the message; topic messages carry sequence
+// numbers so those clients can detect the gap and resubscribe.
+func (h *Hub) Publish(topic string, message Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.topics[topic] {
+		select {
+		case client.send <- data:
+		default:
+			log.Printf("WebSocket: Buffer full for agent %s, dropping %s message", client.agentID, topic)
+		}
+	}
+	return nil
+}
+
+// subscribe adds a client to a topic and sends it the topic's snapshot.
+// The snapshot is queued under the hub lock, so every message published
+// afterwards reaches the client after it. Loading the topic, which may be
+// slow, happens first without the lock.
+func (h *Hub) subscribe(client *Client, topic string) error {
+	prefix, _, _ := strings.Cut(topic, ".")
+
+	h.mu.RLock()
+	load := h.loaders[prefix]
+	h.mu.RUnlock()
+	if load != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), topicLoadTimeout)
+		err := load(ctx, topic)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	source, ok := h.sources[prefix]
+	if !ok {
+		return ErrUnknownTopic
+	}
+	if h.clients[client.agentID] != client {
+		return nil // Already disconnected
+	}
+
+	snapshot, err := source(topic)
+	if err != nil {
+		return err
+	}
+	if snapshot != nil {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		select {
+		case client.send <- data:
+		default:
+			return ErrSendBufferFull
+		}
+	}
+
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Client]bool)
+	}
+	h.topics[topic][client] = true
+	if client.topics == nil {
+		client.topics = make(map[string]bool)
+	}
+	client.topics[topic] = true
+	return nil
+}
+
+// unsubscribe removes a client from a topic.
+func (h *Hub) unsubscribe(client *Client, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeFromTopic(client, topic)
+}
+
+// unsubscribeAll removes a client from all its topics. Must be called with h.mu held.
+func (h *Hub) unsubscribeAll(client *Client) {
+	for topic := range client.topics {
+		h.removeFromTopic(client, topic)
+	}
+}
+
+// removeFromTopic removes a client from one topic. Must be called with h.mu held.
+func (h *Hub) removeFromTopic(client *Client, topic string) {
+	delete(client.topics, topic)
+	if subscribers, ok := h.topics[topic]; ok {
+		delete(subscribers, client)
+		if len(subscribers) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+}
+
+// SubscriberCount returns the number of clients subscribed to a topic.
+func (h *Hub) SubscriberCount(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
 // IsConnected checks if an agent is currently connected.
 func (h *Hub) IsConnected(agentID uuid.UUID) bool {
 	h.mu.RLock()
@@ -177,11 +338,38 @@ func (c *Client) readPump() {
 		case "ping":
 			c.send <- []byte(`{"type":"pong"}`)
 		case "subscribe":
-			// Could implement topic subscriptions here
+			topic, _ := msg.Payload["topic"].(string)
+			if err := c.hub.subscribe(c, topic); err != nil {
+				c.reply("error", map[string]any{"topic": topic, "error": err.Error()})
+				continue
+			}
+			c.reply("subscribed", map[string]any{"topic": topic})
+		case "unsubscribe":
+			topic, _ := msg.Payload["topic"].(string)
+			c.hub.unsubscribe(c, topic)
+			c.reply("unsubscribed", map[string]any{"topic": topic})
 		}
 	}
 }
 
+// reply sends a control message to the client, dropping it if the buffer is full.
+func (c *Client) reply(msgType string, payload map[string]any) {
+	data, err := json.Marshal(Message{Type: msgType, Payload: payload})
+	if err != nil {
+		return
+	}
+
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	if c.hub.clients[c.agentID] != c {
+		return // Send channel is closed
+	}
+	select {
+	case c.send <- data:
+	default:
+	}
+}
+
 // writePump pumps messages from the hub to the websocket connection.
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
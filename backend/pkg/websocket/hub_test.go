@@ -2,6 +2,8 @@ package websocket
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
@@ -294,3 +296,114 @@ func TestConstants(t *testing.T) {
 		t.Error("pingPeriod should be less than pongWait")
 	}
 }
+
+func newTestClient(hub *Hub) *Client {
+	client := &Client{
+		hub:     hub,
+		send:    make(chan []byte, 16),
+		agentID: uuid.New(),
+	}
+	hub.clients[client.agentID] = client
+	return client
+}
+
+func receive(t *testing.T, client *Client) Message {
+	t.Helper()
+	select {
+	case data := <-client.send:
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("failed to decode message: %v", err)
+		}
+		return msg
+	default:
+		t.Fatal("expected a message")
+		return Message{}
+	}
+}
+
+func TestSubscribeSendsSnapshotThenPublishes(t *testing.T) {
+	hub := NewHub()
+	hub.HandleTopics("test", func(topic string) (*Message, error) {
+		return &Message{Type: "snapshot", Payload: map[string]any{"topic": topic}}, nil
+	})
+	client := newTestClient(hub)
+
+	if err := hub.subscribe(client, "test.a"); err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	if msg := receive(t, client); msg.Type != "snapshot" || msg.Payload["topic"] != "test.a" {
+		t.Errorf("first message = %+v, want snapshot of test.a", msg)
+	}
+
+	hub.Publish("test.a", Message{Type: "update"})
+	hub.Publish("test.b", Message{Type: "other"})
+	if msg := receive(t, client); msg.Type != "update" {
+		t.Errorf("message = %+v, want update", msg)
+	}
+	if len(client.send) != 0 {
+		t.Error("client should not receive messages for other topics")
+	}
+}
+
+func TestSubscribeLoadsTopicWithoutHubLock(t *testing.T) {
+	hub := NewHub()
+	loaded := ""
+	hub.HandleTopics("test", func(topic string) (*Message, error) {
+		return &Message{Type: "snapshot", Payload: map[string]any{"loaded": loaded}}, nil
+	})
+	hub.LoadTopics("test", func(ctx context.Context, topic string) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("load context has no deadline")
+		}
+		// Would deadlock if the hub lock were held
+		hub.SubscriberCount(topic)
+		loaded = topic
+		return nil
+	})
+	client := newTestClient(hub)
+
+	if err := hub.subscribe(client, "test.a"); err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	if msg := receive(t, client); msg.Payload["loaded"] != "test.a" {
+		t.Errorf("snapshot = %+v, want it taken after loading test.a", msg)
+	}
+
+	hub.LoadTopics("test", func(ctx context.Context, topic string) error { return ErrUnknownTopic })
+	if err := hub.subscribe(newTestClient(hub), "test.b"); !errors.Is(err, ErrUnknownTopic) {
+		t.Errorf("subscribe() error = %v, want the load error", err)
+	}
+	if hub.SubscriberCount("test.b") != 0 {
+		t.Error("failed load should leave no subscribers")
+	}
+}
+
+func TestSubscribeUnknownTopic(t *testing.T) {
+	hub := NewHub()
+	client := newTestClient(hub)
+
+	if err := hub.subscribe(client, "nope.a"); !errors.Is(err, ErrUnknownTopic) {
+		t.Errorf("subscribe() error = %v, want ErrUnknownTopic", err)
+	}
+	if hub.SubscriberCount("nope.a") != 0 {
+		t.Error("unknown topic should have no subscribers")
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	hub := NewHub()
+	hub.HandleTopics("test", func(topic string) (*Message, error) { return nil, nil })
+	client := newTestClient(hub)
+
+	hub.subscribe(client, "test.a")
+	if hub.SubscriberCount("test.a") != 1 {
+		t.Fatalf("SubscriberCount() = %d, want 1", hub.SubscriberCount("test.a"))
+	}
+
+	hub.unsubscribe(client, "test.a")
+	hub.Publish("test.a", Message{Type: "update"})
+	if hub.SubscriberCount("test.a") != 0 || len(client.send) != 0 {
+		t.Error("client should not receive messages after unsubscribing")
+	}
+}
@@ -0,0 +1,219 @@
+package websocket
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digi604/swarmmarket/backend/internal/matching"
+	"github.com/google/uuid"
+)
+
+const (
+	bookTopicPrefix   = "book"
+	tradesTopicPrefix = "trades"
+	bboTopicPrefix    = "bbo"
+
+	// Number of trade prints included in a trades snapshot.
+	recentTradesSize = 50
+)
+
+// MarketFeed publishes level 2 market data from the matching engine to hub topics:
+//
+//	book.<product_id>    full book snapshot on subscribe, then level diffs
+//	trades.<product_id>  recent trades on subscribe, then every trade print
+//	bbo.<product_id>     best bid and offer on subscribe, then every change
+//
+// Every topic has its own sequence number per product that increases by one
+// with each message. A client that sees a gap resubscribes to get a new snapshot.
+type MarketFeed struct {
+	hub      *Hub
+	mu       sync.Mutex
+	products map[uuid.UUID]*marketState
+}
+
+// marketState is the feed's copy of one product's aggregated book.
+type marketState struct {
+	bids     map[float64]matching.PriceLevel
+	asks     map[float64]matching.PriceLevel
+	bookSeq  uint64
+	tradeSeq uint64
+	bboSeq   uint64
+	bestBid  *matching.PriceLevel
+	bestAsk  *matching.PriceLevel
+	trades   []map[string]any // Most recent last
+}
+
+// NewMarketFeed creates a market data feed and registers its topics on the hub.
+// Pass its HandleBookUpdate method to matching.Engine.SetBookHandler.
+func NewMarketFeed(hub *Hub) *MarketFeed {
+	f := &MarketFeed{
+		hub:      hub,
+		products: make(map[uuid.UUID]*marketState),
+	}
+	hub.HandleTopics(bookTopicPrefix, f.snapshot)
+	hub.HandleTopics(tradesTopicPrefix, f.snapshot)
+	hub.HandleTopics(bboTopicPrefix, f.snapshot)
+	return f
+}
+
+// HandleBookUpdate applies a book change and publishes it to subscribers.
+// The engine calls it in order per product.
+func (f *MarketFeed) HandleBookUpdate(update matching.BookUpdate) {
+	productID := update.ProductID
+
+	f.mu.Lock()
+	state, ok := f.products[productID]
+	if !ok {
+		state = &marketState{
+			bids: make(map[float64]matching.PriceLevel),
+			asks: make(map[float64]matching.PriceLevel),
+		}
+		f.products[productID] = state
+	}
+
+	var messages []topicMessage
+	if len(update.Bids) > 0 || len(update.Asks) > 0 {
+		applyLevels(state.bids, update.Bids)
+		applyLevels(state.asks, update.Asks)
+		state.bookSeq++
+		messages = append(messages, topicMessage{
+			topic: topicName(bookTopicPrefix, productID),
+			message: Message{Type: "book.update", Payload: map[string]any{
+				"product_id": productID,
+				"seq":        state.bookSeq,
+				"bids":       levelsOrEmpty(update.Bids),
+				"asks":       levelsOrEmpty(update.Asks),
+			}},
+		})
+	}
+
+	for _, trade := range update.Trades {
+		state.tradeSeq++
+		tradePrint := map[string]any{
+			"product_id": productID,
+			"seq":        state.tradeSeq,
+			"trade_id":   trade.ID,
+			"price":      trade.Price,
+			"quantity":   trade.Quantity,
+			"created_at": trade.CreatedAt.Format(time.RFC3339Nano),
+		}
+		state.trades = append(state.trades, tradePrint)
+		if len(state.trades) > recentTradesSize {
+			state.trades = state.trades[1:]
+		}
+		messages = append(messages, topicMessage{
+			topic:   topicName(tradesTopicPrefix, productID),
+			message: Message{Type: "trade", Payload: tradePrint},
+		})
+	}
+
+	if !sameLevel(state.bestBid, update.BestBid) || !sameLevel(state.bestAsk, update.BestAsk) {
+		state.bestBid, state.bestAsk = update.BestBid, update.BestAsk
+		state.bboSeq++
+		messages = append(messages, topicMessage{
+			topic:   topicName(bboTopicPrefix, productID),
+			message: bboMessage(productID, state),
+		})
+	}
+	f.mu.Unlock()
+
+	// Publish outside the feed lock: the hub holds its lock while it takes
+	// snapshots, so publishing under ours could deadlock
+	for _, m := range messages {
+		f.hub.Publish(m.topic, m.message)
+	}
+}
+
+// snapshot returns the current state of a topic.
+func (f *MarketFeed) snapshot(topic string) (*Message, error) {
+	prefix, id, _ := strings.Cut(topic, ".")
+	productID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrUnknownTopic
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	state, ok := f.products[productID]
+	if !ok {
+		state = &marketState{}
+	}
+
+	switch prefix {
+	case bookTopicPrefix:
+		return &Message{Type: "book.snapshot", Payload: map[string]any{
+			"product_id": productID,
+			"seq":        state.bookSeq,
+			"bids":       sortedLevels(state.bids, func(a, b float64) bool { return a > b }),
+			"asks":       sortedLevels(state.asks, func(a, b float64) bool { return a < b }),
+		}}, nil
+	case tradesTopicPrefix:
+		trades := make([]map[string]any, len(state.trades))
+		copy(trades, state.trades)
+		return &Message{Type: "trades.snapshot", Payload: map[string]any{
+			"product_id": productID,
+			"seq":        state.tradeSeq,
+			"trades":     trades,
+		}}, nil
+	case bboTopicPrefix:
+		msg := bboMessage(productID, state)
+		return &msg, nil
+	}
+	return nil, ErrUnknownTopic
+}
+
+// topicMessage is a message waiting to be published.
+type topicMessage struct {
+	topic   string
+	message Message
+}
+
+func topicName(prefix string, productID uuid.UUID) string {
+	return prefix + "." + productID.String()
+}
+
+func bboMessage(productID uuid.UUID, state *marketState) Message {
+	return Message{Type: "bbo", Payload: map[string]any{
+		"product_id": productID,
+		"seq":        state.bboSeq,
+		"bid":        state.bestBid,
+		"ask":        state.bestAsk,
+	}}
+}
+
+// applyLevels updates one side of a book copy; zero quantity removes a level.
+func applyLevels(side map[float64]matching.PriceLevel, changes []matching.PriceLevel) {
+	for _, level := range changes {
+		if level.Quantity <= 0 {
+			delete(side, level.Price)
+		} else {
+			side[level.Price] = level
+		}
+	}
+}
+
+func sortedLevels(side map[float64]matching.PriceLevel, better func(a, b float64) bool) []matching.PriceLevel {
+	levels := make([]matching.PriceLevel, 0, len(side))
+	for _, level := range side {
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool { return better(levels[i].Price, levels[j].Price) })
+	return levels
+}
+
+func levelsOrEmpty(levels []matching.PriceLevel) []matching.PriceLevel {
+	if levels == nil {
+		return []matching.PriceLevel{}
+	}
+	return levels
+}
+
+func sameLevel(a, b *matching.PriceLevel) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,73 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/digi604/swarmmarket/backend/internal/matching"
+	"github.com/google/uuid"
+)
+
+func TestMarketFeedSnapshotAndDiffs(t *testing.T) {
+	hub := NewHub()
+	feed := NewMarketFeed(hub)
+	productID := uuid.New()
+
+	feed.HandleBookUpdate(matching.BookUpdate{
+		ProductID: productID,
+		Bids:      []matching.PriceLevel{{Price: 100, Quantity: 5, Orders: 1}, {Price: 99, Quantity: 3, Orders: 1}},
+		BestBid:   &matching.PriceLevel{Price: 100, Quantity: 5, Orders: 1},
+	})
+
+	client := newTestClient(hub)
+	if err := hub.subscribe(client, "book."+productID.String()); err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	snapshot := receive(t, client)
+	if snapshot.Type != "book.snapshot" || snapshot.Payload["seq"] != 1.0 {
+		t.Fatalf("snapshot = %+v, want book.snapshot at seq 1", snapshot)
+	}
+	if bids := snapshot.Payload["bids"].([]any); len(bids) != 2 || bids[0].(map[string]any)["price"] != 100.0 {
+		t.Errorf("bids = %v, want 100 then 99", bids)
+	}
+
+	// A trade takes out the 100 level
+	feed.HandleBookUpdate(matching.BookUpdate{
+		ProductID: productID,
+		Bids:      []matching.PriceLevel{{Price: 100}},
+		Trades:    []matching.Trade{{ID: uuid.New(), ProductID: productID, Price: 100, Quantity: 5}},
+		BestBid:   &matching.PriceLevel{Price: 99, Quantity: 3, Orders: 1},
+	})
+
+	diff := receive(t, client)
+	if diff.Type != "book.update" || diff.Payload["seq"] != 2.0 {
+		t.Fatalf("diff = %+v, want book.update at seq 2", diff)
+	}
+	if level := diff.Payload["bids"].([]any)[0].(map[string]any); level["quantity"] != 0.0 {
+		t.Errorf("removed level quantity = %v, want 0", level["quantity"])
+	}
+
+	book, _ := feed.snapshot("book." + productID.String())
+	if bids := book.Payload["bids"].([]matching.PriceLevel); len(bids) != 1 || bids[0].Price != 99 {
+		t.Errorf("bids after diff = %v, want only 99", bids)
+	}
+	trades, _ := feed.snapshot("trades." + productID.String())
+	if trades.Payload["seq"] != uint64(1) {
+		t.Errorf("trades seq = %v, want 1", trades.Payload["seq"])
+	}
+	bbo, _ := feed.snapshot("bbo." + productID.String())
+	if bbo.Payload["seq"] != uint64(2) || bbo.Payload["bid"].(*matching.PriceLevel).Price != 99 {
+		t.Errorf("bbo = %+v, want bid 99 at seq 2", bbo.Payload)
+	}
+}
+
+func TestMarketFeedUnknownTopic(t *testing.T) {
+	feed := NewMarketFeed(NewHub())
+
+	if _, err := feed.snapshot("book.not-a-uuid"); err != ErrUnknownTopic {
+		t.Errorf("snapshot() error = %v, want ErrUnknownTopic", err)
+	}
+	msg, err := feed.snapshot("book." + uuid.New().String())
+	if err != nil || msg.Payload["seq"] != uint64(0) {
+		t.Errorf("snapshot() of quiet product = %+v, %v, want empty at seq 0", msg, err)
+	}
+}
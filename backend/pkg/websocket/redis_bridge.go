@@ -64,6 +64,36 @@ func BridgeRedisToHub(ctx context.Context, redisClient *redis.Client, hub *Hub)
 	}
 }
 
+// BridgeAuctionEvents forwards auction and bid events from Redis pub/sub to the
+// auction feed, whichever process published them.
+func BridgeAuctionEvents(ctx context.Context, redisClient *redis.Client, feed *AuctionFeed) {
+	if redisClient == nil || feed == nil {
+		return
+	}
+
+	pubsub := redisClient.PSubscribe(ctx, "notifications:auction.*", "notifications:bid.*")
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			var event notification.Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("WebSocket: Failed to decode auction event: %v", err)
+				continue
+			}
+			feed.HandleEvent(ctx, event)
+		}
+	}
+}
+
 func agentIDFromChannel(channel string) (uuid.UUID, bool) {
 	if !strings.HasPrefix(channel, agentChannelPrefix) || !strings.HasSuffix(channel, agentChannelSuffix) {
 		return uuid.Nil, false